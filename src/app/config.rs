@@ -8,10 +8,13 @@ use std::fs;
 use std::path::{Path, PathBuf};
 
 use anyhow::{Context, Result};
-use directories::ProjectDirs;
 use serde::{Deserialize, Serialize};
 use thiserror::Error;
 
+use super::paths;
+use crate::types::{DeepLink, PeerHandle};
+use crate::utils::Keywords;
+
 /// Configuration errors.
 #[derive(Error, Debug)]
 pub enum ConfigError {
@@ -52,6 +55,13 @@ pub struct Config {
 
     /// Logging settings
     pub logging: LoggingConfig,
+
+    /// Words that make a message stand out
+    pub highlights: HighlightConfig,
+
+    /// Shell commands run when messages arrive (see
+    /// [`hooks`](super::hooks))
+    pub hooks: Vec<Hook>,
 }
 
 /// General application settings.
@@ -120,13 +130,22 @@ pub struct LayoutConfig {
 
     /// Whether to show the info pane
     pub show_info_pane: bool,
+
+    /// Group the chat list under Pinned / Unread / All chats headers
+    pub chat_list_sections: bool,
+
+    /// Below this many columns, show the chat list or the conversation
+    /// alone, with Tab flipping between them, and hide the info pane
+    /// (0 never collapses)
+    pub collapse_below: u16,
 }
 
 /// Appearance configuration.
 #[derive(Debug, Clone, Serialize, Deserialize)]
 #[serde(default)]
 pub struct AppearanceConfig {
-    /// Show user avatars
+    /// Show initials avatars, in each chat's Telegram peer color, in the
+    /// chat list and info pane
     pub show_avatars: bool,
 
     /// Show status bar
@@ -140,6 +159,24 @@ pub struct AppearanceConfig {
 
     /// Maximum length of message preview in chat list
     pub message_preview_length: usize,
+
+    /// Number of lines the chat list gives each message preview (1-3)
+    pub message_preview_lines: usize,
+
+    /// Show messages of only 1-3 emoji enlarged, on a centered line of
+    /// their own
+    pub big_emoji: bool,
+
+    /// Show a minimap of the loaded history down the right edge of the
+    /// conversation, marking the unread part, mentions, media and days
+    pub minimap: bool,
+    /// Widest a message is drawn, in columns, so long messages stay
+    /// readable on wide terminals (0 for the full pane)
+    pub max_message_width: u16,
+
+    /// Seconds within which a sender's messages share one header, the
+    /// later ones sitting closer beneath it (0 shows every header)
+    pub group_messages_secs: u64,
 }
 
 /// Behavior configuration.
@@ -157,6 +194,68 @@ pub struct BehaviorConfig {
 
     /// Emoji style: "unicode" or "ascii"
     pub emoji_style: String,
+
+    /// Directory attachments are saved to with `s`
+    pub download_directory: PathBuf,
+
+    /// What opens at startup: "chats" (the chat list only), "last" (the
+    /// chat open when Ithil last quit), "saved" (Saved Messages), or a
+    /// chat's @username or ID; see [`StartupView`]
+    pub startup_view: String,
+
+    /// Program and arguments `/listen` plays voice messages with; the file
+    /// is added at the end
+    pub voice_player: String,
+
+    /// Send attached images as photos, which Telegram compresses; when off
+    /// they go as files in their original quality. `Alt+C` switches it for
+    /// one attachment
+    pub compress_images: bool,
+}
+
+/// What opens once the chat list has loaded.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum StartupView {
+    /// Just the chat list
+    ChatList,
+    /// The chat that was open when Ithil last quit
+    LastChat,
+    /// The user's own chat
+    SavedMessages,
+    /// A chat by ID
+    Chat(i64),
+    /// A user or public chat by username or phone number
+    Handle(PeerHandle),
+    /// A `t.me` or `tg://` link, at its message if it names one
+    Link(DeepLink),
+}
+
+impl StartupView {
+    /// Parses a `startup_view` setting or `--chat` argument. Returns `None`
+    /// if it is neither a keyword, a chat ID, a username or phone number,
+    /// nor a link.
+    #[must_use]
+    pub fn parse(value: &str) -> Option<Self> {
+        let value = value.trim();
+        match value.to_lowercase().as_str() {
+            "" | "chats" | "list" => Some(Self::ChatList),
+            "last" => Some(Self::LastChat),
+            "saved" | "me" => Some(Self::SavedMessages),
+            _ => PeerHandle::parse(value)
+                .map(Self::Handle)
+                .or_else(|| DeepLink::parse(value).map(Self::Link))
+                .or_else(|| value.parse().ok().map(Self::Chat)),
+        }
+    }
+}
+
+impl BehaviorConfig {
+    /// Returns what opens at startup, or the chat list if `startup_view`
+    /// can't be parsed.
+    #[must_use]
+    pub fn startup_view(&self) -> StartupView {
+        StartupView::parse(&self.startup_view).unwrap_or(StartupView::ChatList)
+    }
 }
 
 /// Keyboard configuration.
@@ -168,11 +267,20 @@ pub struct KeyboardConfig {
 
     /// Custom key bindings
     pub custom_bindings: HashMap<String, String>,
+
+    /// Key that starts a leader sequence: "space", a single character, or
+    /// empty to turn sequences off
+    pub leader: String,
+
+    /// Leader sequences: space-separated keys typed after the leader (e.g.
+    /// "m a"), mapped to an action name or a slash command
+    pub leader_bindings: HashMap<String, String>,
 }
 
 /// Notification configuration.
 #[derive(Debug, Clone, Serialize, Deserialize)]
 #[serde(default)]
+#[allow(clippy::struct_excessive_bools)]
 pub struct NotificationConfig {
     /// Enable notifications
     pub enabled: bool,
@@ -185,6 +293,76 @@ pub struct NotificationConfig {
 
     /// List of muted chat IDs
     pub muted_chats: Vec<i64>,
+
+    /// Show the unread count and open chat in the terminal window title
+    pub terminal_title: bool,
+
+    /// Ring the terminal bell when a message arrives
+    pub bell: bool,
+
+    /// Flash the status bar when a message arrives
+    pub flash: bool,
+
+    /// Chats the bell and flash are limited to, chosen with `/alert`
+    /// (every un-muted chat if empty)
+    pub alert_chats: Vec<i64>,
+}
+
+impl NotificationConfig {
+    /// Adds a chat to those the bell and flash are limited to, or takes it
+    /// off. Returns `true` if it's on the list now.
+    pub fn toggle_alert_chat(&mut self, chat_id: i64) -> bool {
+        if let Some(pos) = self.alert_chats.iter().position(|&id| id == chat_id) {
+            self.alert_chats.remove(pos);
+            false
+        } else {
+            self.alert_chats.push(chat_id);
+            true
+        }
+    }
+}
+
+/// Words that make a message stand out.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+#[serde(default)]
+pub struct HighlightConfig {
+    /// Words or phrases to look for in incoming messages, ignoring case; a
+    /// trailing `*` matches any ending, and `/.../` is a regular expression
+    /// (see [`Keywords`])
+    pub words: Keywords,
+
+    /// Notify about matching messages even in muted chats
+    pub notify: bool,
+}
+
+/// A shell command run when a message arrives.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+#[serde(default)]
+pub struct Hook {
+    /// What sets it off
+    pub on: HookTrigger,
+
+    /// Chats it's limited to, by ID, title or @username (all if empty)
+    pub chats: Vec<String>,
+
+    /// Keywords a `keyword` hook looks for (see [`Keywords`])
+    pub keywords: Keywords,
+
+    /// Command run through the shell
+    pub command: String,
+}
+
+/// What sets off a [`Hook`]. Only messages from others count.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum HookTrigger {
+    /// Any new message
+    #[default]
+    Message,
+    /// A message that mentions the user or replies to them
+    Mention,
+    /// A message containing one of the hook's keywords
+    Keyword,
 }
 
 /// Privacy configuration.
@@ -195,17 +373,78 @@ pub struct NotificationConfig {
 #[serde(default)]
 #[allow(clippy::struct_excessive_bools)]
 pub struct PrivacyConfig {
-    /// Show online status to others
+    /// Show online status to others; when off, never report online
+    /// ("appear offline")
     pub show_online_status: bool,
 
+    /// Seconds without input before reporting offline (0 disables)
+    pub idle_timeout_secs: u64,
+
     /// Send read receipts
     pub show_read_receipts: bool,
 
     /// Show typing indicator
     pub show_typing: bool,
 
-    /// Stealth mode (disables read receipts and typing indicators)
+    /// Stealth mode (disables read receipts, typing indicators, and online status)
     pub stealth_mode: bool,
+
+    /// In stealth mode, hide chat list previews until revealed
+    pub blur_previews: bool,
+
+    /// Hashed lock-screen passphrase (empty until one is set)
+    pub lock_passphrase_hash: String,
+
+    /// Seconds without input before locking the screen (0 disables)
+    pub auto_lock_secs: u64,
+
+    /// Chats whose bookmarks, local pins and media are stored encrypted
+    /// with a key from the lock-screen passphrase (toggled with `/encrypt`)
+    pub encrypted_chats: Vec<i64>,
+}
+
+impl PrivacyConfig {
+    /// Returns `true` if read receipts may be sent.
+    #[must_use]
+    pub const fn sends_read_receipts(&self) -> bool {
+        self.show_read_receipts && !self.stealth_mode
+    }
+
+    /// Returns `true` if typing indicators may be sent.
+    #[must_use]
+    pub const fn sends_typing(&self) -> bool {
+        self.show_typing && !self.stealth_mode
+    }
+
+    /// Returns `true` if the account may be reported as online.
+    #[must_use]
+    pub const fn reports_online(&self) -> bool {
+        self.show_online_status && !self.stealth_mode
+    }
+
+    /// Returns `true` if chat list previews should be hidden.
+    #[must_use]
+    pub const fn hides_previews(&self) -> bool {
+        self.stealth_mode && self.blur_previews
+    }
+
+    /// Returns `true` if a chat's local data is kept encrypted.
+    #[must_use]
+    pub fn is_encrypted(&self, chat_id: i64) -> bool {
+        self.encrypted_chats.contains(&chat_id)
+    }
+
+    /// Marks a chat's local data to be kept encrypted, or unmarks it.
+    /// Returns `true` if it's marked now.
+    pub fn toggle_encrypted_chat(&mut self, chat_id: i64) -> bool {
+        if let Some(pos) = self.encrypted_chats.iter().position(|&id| id == chat_id) {
+            self.encrypted_chats.remove(pos);
+            false
+        } else {
+            self.encrypted_chats.push(chat_id);
+            true
+        }
+    }
 }
 
 /// Cache configuration.
@@ -215,6 +454,11 @@ pub struct CacheConfig {
     /// Maximum messages to cache per chat
     pub max_messages_per_chat: usize,
 
+    /// Rough memory budget for cached messages in MiB; beyond it the
+    /// histories of the chats viewed least recently are dropped (0 for no
+    /// limit)
+    pub max_memory_mb: usize,
+
     /// Maximum media file size in bytes
     pub max_media_size: u64,
 
@@ -222,6 +466,20 @@ pub struct CacheConfig {
     pub media_directory: PathBuf,
 }
 
+impl CacheConfig {
+    /// Returns the memory budget for cached messages in bytes.
+    #[must_use]
+    pub const fn memory_budget(&self) -> usize {
+        self.max_memory_mb.saturating_mul(1024 * 1024)
+    }
+
+    /// Returns the directory `/export` writes to, next to the media cache.
+    #[must_use]
+    pub fn exports_directory(&self) -> PathBuf {
+        self.media_directory.with_file_name("exports")
+    }
+}
+
 /// Logging configuration.
 #[derive(Debug, Clone, Serialize, Deserialize)]
 #[serde(default)]
@@ -231,6 +489,9 @@ pub struct LoggingConfig {
 
     /// Path to log file
     pub file: PathBuf,
+
+    /// Enables debugging aids for bug reports, such as `/dump`
+    pub debug: bool,
 }
 
 // Default implementations
@@ -246,15 +507,15 @@ impl Default for AppConfig {
 
 impl Default for TelegramConfig {
     fn default() -> Self {
-        let config_dir = get_config_dir();
+        let state_dir = paths::state_dir();
         Self {
             use_default_credentials: true,
             api_id: String::new(),
             api_hash: String::new(),
             // Use .session extension for grammers SQLite session format
             // Different from Go version's session.json to avoid conflicts
-            session_file: config_dir.join("ithil.session"),
-            database_directory: config_dir.join("tdlib"),
+            session_file: state_dir.join(paths::SESSION_FILE),
+            database_directory: state_dir.join("tdlib"),
         }
     }
 }
@@ -278,6 +539,8 @@ impl Default for LayoutConfig {
             conversation_width: 50,
             info_width: 25,
             show_info_pane: false,
+            chat_list_sections: false,
+            collapse_below: 80,
         }
     }
 }
@@ -290,6 +553,11 @@ impl Default for AppearanceConfig {
             date_format: "12h".to_string(),
             relative_timestamps: true,
             message_preview_length: 50,
+            message_preview_lines: 1,
+            big_emoji: true,
+            minimap: false,
+            max_message_width: 80,
+            group_messages_secs: 300,
         }
     }
 }
@@ -301,6 +569,10 @@ impl Default for BehaviorConfig {
             auto_download_limit: 5_242_880, // 5MB
             mark_read_on_scroll: true,
             emoji_style: "unicode".to_string(),
+            download_directory: paths::downloads_dir(),
+            startup_view: "chats".to_string(),
+            voice_player: "mpv --no-video --really-quiet".to_string(),
+            compress_images: true,
         }
     }
 }
@@ -310,6 +582,21 @@ impl Default for KeyboardConfig {
         Self {
             vim_mode: true,
             custom_bindings: HashMap::new(),
+            leader: "space".to_string(),
+            leader_bindings: [
+                ("m a", "mark_all_as_read"),
+                ("m r", "mark_as_read"),
+                ("g", "quick_switch"),
+                ("i", "show_inbox"),
+                ("d", "jump_to_date"),
+                ("s", "toggle_stealth"),
+                ("l", "lock"),
+                ("p", "/permissions"),
+                ("e", "/export"),
+            ]
+            .into_iter()
+            .map(|(keys, target)| (keys.to_string(), target.to_string()))
+            .collect(),
         }
     }
 }
@@ -321,6 +608,10 @@ impl Default for NotificationConfig {
             sound: true,
             desktop: true,
             muted_chats: Vec::new(),
+            terminal_title: true,
+            bell: false,
+            flash: false,
+            alert_chats: Vec::new(),
         }
     }
 }
@@ -329,18 +620,24 @@ impl Default for PrivacyConfig {
     fn default() -> Self {
         Self {
             show_online_status: true,
+            idle_timeout_secs: 300,
             show_read_receipts: true,
             show_typing: true,
             stealth_mode: false,
+            blur_previews: false,
+            lock_passphrase_hash: String::new(),
+            auto_lock_secs: 0,
+            encrypted_chats: Vec::new(),
         }
     }
 }
 
 impl Default for CacheConfig {
     fn default() -> Self {
-        let cache_dir = get_cache_dir();
+        let cache_dir = paths::cache_dir();
         Self {
             max_messages_per_chat: 1000,
+            max_memory_mb: 64,
             max_media_size: 104_857_600, // 100MB
             media_directory: cache_dir.join("media"),
         }
@@ -349,10 +646,10 @@ impl Default for CacheConfig {
 
 impl Default for LoggingConfig {
     fn default() -> Self {
-        let config_dir = get_config_dir();
         Self {
             level: "info".to_string(),
-            file: config_dir.join("ithil.log"),
+            file: paths::state_dir().join(paths::LOG_FILE),
+            debug: false,
         }
     }
 }
@@ -363,8 +660,9 @@ impl Config {
     /// Search order:
     /// 1. Specified path (if provided)
     /// 2. `./config.yaml`
-    /// 3. `~/.config/ithil/config.yaml`
-    /// 4. `~/.ithil.yaml`
+    /// 3. `config.yaml` in the config directory (see [`paths::config_dir`])
+    /// 4. `~/.config/ithil/config.yaml`, where older versions looked
+    /// 5. `~/.ithil.yaml`
     ///
     /// If no config file is found, returns the default configuration.
     ///
@@ -400,16 +698,38 @@ impl Config {
         Ok(Self::default())
     }
 
+    /// Returns the config file [`Config::load`] would read, if any.
+    ///
+    /// An explicit `path` is returned (tilde-expanded) only if it exists.
+    #[must_use]
+    pub fn find_file(path: Option<&Path>) -> Option<PathBuf> {
+        match path {
+            Some(p) => Some(expand_tilde(p)).filter(|p| p.exists()),
+            None => Self::config_search_paths().into_iter().find(|p| p.exists()),
+        }
+    }
+
+    /// Returns where settings are saved when no config file was found:
+    /// `config.yaml` in the config directory (see [`paths::config_dir`]).
+    #[must_use]
+    pub fn default_file() -> PathBuf {
+        paths::config_file()
+    }
+
     /// Get the list of paths to search for config files.
     fn config_search_paths() -> Vec<PathBuf> {
-        let mut paths = vec![PathBuf::from("config.yaml")];
+        let mut found = vec![PathBuf::from(paths::CONFIG_FILE), paths::config_file()];
 
         if let Some(home) = dirs::home_dir() {
-            paths.push(home.join(".config").join("ithil").join("config.yaml"));
-            paths.push(home.join(".ithil.yaml"));
+            // Where older versions looked on every platform
+            let legacy = home.join(".config").join("ithil").join(paths::CONFIG_FILE);
+            if !found.contains(&legacy) {
+                found.push(legacy);
+            }
+            found.push(home.join(".ithil.yaml"));
         }
 
-        paths
+        found
     }
 
     /// Load configuration from a specific file.
@@ -486,14 +806,57 @@ impl Config {
             )));
         }
 
+        if let Some(e) = self.highlights.words.error() {
+            return Err(ConfigError::ValidationError(format!(
+                "Bad highlight word {e}"
+            )));
+        }
+
+        for (i, hook) in self.hooks.iter().enumerate() {
+            if hook.command.trim().is_empty() {
+                return Err(ConfigError::ValidationError(format!(
+                    "Hook {} has no command",
+                    i + 1
+                )));
+            }
+            if hook.on == HookTrigger::Keyword && hook.keywords.is_blank() {
+                return Err(ConfigError::ValidationError(format!(
+                    "Hook {} is for keywords but lists none",
+                    i + 1
+                )));
+            }
+            if let Some(e) = hook.keywords.error() {
+                return Err(ConfigError::ValidationError(format!(
+                    "Hook {} has a bad keyword {e}",
+                    i + 1
+                )));
+            }
+        }
+
+        if StartupView::parse(&self.ui.behavior.startup_view).is_none() {
+            return Err(ConfigError::ValidationError(format!(
+                "Unknown startup view \"{}\" (use chats, last, saved, or a chat's @username or ID)",
+                self.ui.behavior.startup_view
+            )));
+        }
+
         Ok(())
     }
 
+    /// Forgets which of the logged-in account's chats are muted, alerted or
+    /// encrypted. Another account's chats have other IDs.
+    pub fn forget_account(&mut self) {
+        self.notifications.muted_chats.clear();
+        self.notifications.alert_chats.clear();
+        self.privacy.encrypted_chats.clear();
+    }
+
     /// Expand tilde in all path fields.
-    fn expand_paths(&mut self) {
+    pub(super) fn expand_paths(&mut self) {
         self.telegram.session_file = expand_tilde(&self.telegram.session_file);
         self.telegram.database_directory = expand_tilde(&self.telegram.database_directory);
         self.cache.media_directory = expand_tilde(&self.cache.media_directory);
+        self.ui.behavior.download_directory = expand_tilde(&self.ui.behavior.download_directory);
         self.logging.file = expand_tilde(&self.logging.file);
     }
 
@@ -519,32 +882,6 @@ impl Config {
     }
 }
 
-/// Get the default config directory for Ithil.
-fn get_config_dir() -> PathBuf {
-    ProjectDirs::from("", "", "ithil").map_or_else(
-        || {
-            dirs::home_dir().map_or_else(
-                || PathBuf::from(".config/ithil"),
-                |h| h.join(".config").join("ithil"),
-            )
-        },
-        |dirs| dirs.config_dir().to_path_buf(),
-    )
-}
-
-/// Get the default cache directory for Ithil.
-fn get_cache_dir() -> PathBuf {
-    ProjectDirs::from("", "", "ithil").map_or_else(
-        || {
-            dirs::home_dir().map_or_else(
-                || PathBuf::from(".cache/ithil"),
-                |h| h.join(".cache").join("ithil"),
-            )
-        },
-        |dirs| dirs.cache_dir().to_path_buf(),
-    )
-}
-
 /// Expand tilde (~) to home directory in a path.
 fn expand_tilde(path: &Path) -> PathBuf {
     let path_str = path.to_string_lossy();
@@ -583,6 +920,26 @@ mod tests {
         assert!(result.is_err());
     }
 
+    #[test]
+    fn test_config_validation_hooks() {
+        let mut config: Config = serde_yaml::from_str(
+            "hooks:\n  - on: keyword\n    keywords: [deploy]\n    \
+             command: notify-send \"$ITHIL_TEXT\"\n",
+        )
+        .unwrap();
+        assert_eq!(config.hooks[0].on, HookTrigger::Keyword);
+        assert!(config.validate().is_ok());
+
+        config.hooks[0].keywords = Keywords::new(["/[unclosed/"]);
+        assert!(config.validate().is_err());
+        config.hooks[0].keywords = Keywords::default();
+        assert!(config.validate().is_err());
+        config.hooks[0].on = HookTrigger::Mention;
+        assert!(config.validate().is_ok());
+        config.hooks[0].command = " ".to_string();
+        assert!(config.validate().is_err());
+    }
+
     #[test]
     fn test_config_validation_custom_credentials() {
         let mut config = Config::default();
@@ -593,6 +950,37 @@ mod tests {
         assert!(result.is_err());
     }
 
+    #[test]
+    fn startup_view_takes_a_keyword_an_id_or_a_handle() {
+        assert_eq!(StartupView::parse("Last"), Some(StartupView::LastChat));
+        assert_eq!(
+            StartupView::parse("saved"),
+            Some(StartupView::SavedMessages)
+        );
+        assert_eq!(
+            StartupView::parse("-1001234"),
+            Some(StartupView::Chat(-1_001_234))
+        );
+        assert_eq!(
+            StartupView::parse("@frodo_b"),
+            Some(StartupView::Handle(PeerHandle::Username(
+                "frodo_b".to_string()
+            )))
+        );
+        assert_eq!(
+            StartupView::parse("+44 20 7946 0000"),
+            Some(StartupView::Handle(PeerHandle::Phone(
+                "442079460000".to_string()
+            )))
+        );
+        assert_eq!(StartupView::parse("somewhere"), None);
+
+        let mut config = Config::default();
+        assert_eq!(config.ui.behavior.startup_view(), StartupView::ChatList);
+        config.ui.behavior.startup_view = "somewhere".to_string();
+        assert!(config.validate().is_err());
+    }
+
     #[test]
     fn test_expand_tilde() {
         let path = Path::new("~/test/path");
@@ -608,4 +996,38 @@ mod tests {
     fn notification_desktop_defaults_on() {
         assert!(NotificationConfig::default().desktop);
     }
+
+    #[test]
+    fn stealth_mode_overrides_privacy_flags() {
+        let mut privacy = PrivacyConfig::default();
+        assert!(privacy.sends_read_receipts());
+        assert!(privacy.sends_typing());
+        assert!(privacy.reports_online());
+
+        privacy.stealth_mode = true;
+        assert!(!privacy.sends_read_receipts());
+        assert!(!privacy.sends_typing());
+        assert!(!privacy.reports_online());
+        assert!(!privacy.hides_previews());
+
+        privacy.blur_previews = true;
+        assert!(privacy.hides_previews());
+    }
+
+    #[test]
+    fn forgetting_the_account_keeps_device_settings() {
+        let mut config = Config::default();
+        config.notifications.muted_chats = vec![7];
+        config.notifications.alert_chats = vec![8];
+        config.privacy.encrypted_chats = vec![9];
+        config.privacy.lock_passphrase_hash = "hash".to_string();
+        config.ui.keyboard.vim_mode = true;
+
+        config.forget_account();
+        assert!(config.notifications.muted_chats.is_empty());
+        assert!(config.notifications.alert_chats.is_empty());
+        assert!(config.privacy.encrypted_chats.is_empty());
+        assert_eq!(config.privacy.lock_passphrase_hash, "hash");
+        assert!(config.ui.keyboard.vim_mode);
+    }
 }
@@ -0,0 +1,115 @@
+//! Passphrase-keyed encryption for the files Ithil writes to disk: session
+//! archives and encrypted chats' data.
+//!
+//! Data is encrypted with AES-256-CTR and then authenticated with
+//! HMAC-SHA256 over the caller's header and the ciphertext, with both keys
+//! derived from the passphrase by PBKDF2-HMAC-SHA256.
+
+use aes::Aes256;
+use ctr::cipher::{KeyIvInit, StreamCipher};
+use hmac::{Hmac, Mac};
+use sha2::Sha256;
+
+/// Length of the random IV each encryption needs.
+pub const IV_LEN: usize = 16;
+
+/// Length of the tag appended to the ciphertext.
+pub const TAG_LEN: usize = 32;
+
+type Aes256Ctr = ctr::Ctr128BE<Aes256>;
+type HmacSha256 = Hmac<Sha256>;
+
+/// Encryption and authentication keys derived from a passphrase.
+pub struct Cipher {
+    enc_key: [u8; 32],
+    mac_key: [u8; 32],
+}
+
+impl std::fmt::Debug for Cipher {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        f.debug_struct("Cipher").finish_non_exhaustive()
+    }
+}
+
+impl Cipher {
+    /// Derives the keys from a passphrase.
+    #[must_use]
+    pub fn derive(passphrase: &str, salt: &[u8], iterations: u32) -> Self {
+        let mut keys = [0u8; 64];
+        pbkdf2::pbkdf2_hmac::<Sha256>(passphrase.as_bytes(), salt, iterations, &mut keys);
+        let mut enc_key = [0u8; 32];
+        let mut mac_key = [0u8; 32];
+        enc_key.copy_from_slice(&keys[..32]);
+        mac_key.copy_from_slice(&keys[32..]);
+        Self { enc_key, mac_key }
+    }
+
+    /// Encrypts `plain`, returning the ciphertext followed by a tag that
+    /// also covers `header`, which must hold `iv`.
+    #[must_use]
+    pub fn encrypt(&self, iv: &[u8; IV_LEN], header: &[u8], plain: &[u8]) -> Vec<u8> {
+        let mut out = plain.to_vec();
+        Aes256Ctr::new((&self.enc_key).into(), iv.into()).apply_keystream(&mut out);
+        let tag = self.mac(header, &out).finalize().into_bytes();
+        out.extend_from_slice(&tag);
+        out
+    }
+
+    /// Checks the tag and decrypts what [`Cipher::encrypt`] returned, or
+    /// returns `None` if the key is wrong or anything was changed.
+    #[must_use]
+    pub fn decrypt(&self, iv: &[u8; IV_LEN], header: &[u8], sealed: &[u8]) -> Option<Vec<u8>> {
+        let (ciphertext, tag) = sealed.split_at(sealed.len().checked_sub(TAG_LEN)?);
+        self.mac(header, ciphertext).verify_slice(tag).ok()?;
+        let mut out = ciphertext.to_vec();
+        Aes256Ctr::new((&self.enc_key).into(), iv.into()).apply_keystream(&mut out);
+        Some(out)
+    }
+
+    fn mac(&self, header: &[u8], ciphertext: &[u8]) -> HmacSha256 {
+        let mut mac =
+            <HmacSha256 as Mac>::new_from_slice(&self.mac_key).expect("HMAC takes any key length");
+        mac.update(header);
+        mac.update(ciphertext);
+        mac
+    }
+}
+
+/// Returns `N` random bytes.
+///
+/// # Errors
+///
+/// Returns an error if the operating system's random number generator is
+/// unavailable.
+pub fn random<const N: usize>() -> anyhow::Result<[u8; N]> {
+    let mut bytes = [0u8; N];
+    getrandom::getrandom(&mut bytes)
+        .map_err(|e| anyhow::anyhow!("OS random number generator unavailable: {e}"))?;
+    Ok(bytes)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn opens_only_with_the_same_key_and_header() {
+        let cipher = Cipher::derive("hunter2", b"salt", 1000);
+        let iv = [9u8; IV_LEN];
+        let sealed = cipher.encrypt(&iv, b"header", b"attack at dawn");
+        assert_eq!(sealed.len(), b"attack at dawn".len() + TAG_LEN);
+        assert_eq!(
+            cipher.decrypt(&iv, b"header", &sealed).as_deref(),
+            Some(&b"attack at dawn"[..])
+        );
+
+        assert!(Cipher::derive("hunter3", b"salt", 1000)
+            .decrypt(&iv, b"header", &sealed)
+            .is_none());
+        assert!(cipher.decrypt(&iv, b"headex", &sealed).is_none());
+        let mut tampered = sealed.clone();
+        tampered[0] ^= 1;
+        assert!(cipher.decrypt(&iv, b"header", &tampered).is_none());
+        assert!(cipher.decrypt(&iv, b"header", &sealed[..4]).is_none());
+    }
+}
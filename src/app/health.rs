@@ -0,0 +1,218 @@
+//! Checks Ithil's files at startup and sets damaged ones aside.
+//!
+//! A session database cut short by a crash or a full disk would otherwise
+//! fail deep inside the Telegram client, and a garbled state file would be
+//! misread. Before anything opens them, [`check`] makes sure the session's
+//! directory is usable and that each file looks like what it should be.
+//! Damaged files are renamed with a `.corrupt-<timestamp>` suffix rather
+//! than deleted, so nothing is lost; a session set aside just means logging
+//! in again.
+
+use std::fs;
+use std::io::{self, Read};
+use std::path::{Path, PathBuf};
+
+use chrono::Local;
+
+use super::state;
+use super::storage;
+use super::Config;
+
+/// The bytes every SQLite database starts with.
+const SQLITE_MAGIC: &[u8; 16] = b"SQLite format 3\0";
+
+/// Size of the SQLite database header.
+const SQLITE_HEADER_LEN: usize = 100;
+
+/// A damaged file that was set aside.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Repair {
+    /// What the file was for
+    pub kind: RepairedFile,
+    /// Where the file was
+    pub path: PathBuf,
+    /// Where it was moved to
+    pub moved_to: PathBuf,
+    /// What was wrong with it
+    pub reason: String,
+}
+
+/// Which of Ithil's files a [`Repair`] concerns.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum RepairedFile {
+    /// The login session; the user has to log in again
+    Session,
+    /// The remembered last open chat
+    LastChat,
+}
+
+/// Checks the session's directory and files, setting aside any that are
+/// damaged. Returns what was set aside.
+///
+/// # Errors
+///
+/// Returns an error if the session's directory can't be created or written
+/// to, or a damaged file can't be moved.
+pub fn check(config: &Config) -> io::Result<Vec<Repair>> {
+    let session = &config.telegram.session_file;
+    if let Some(dir) = session.parent().filter(|d| !d.as_os_str().is_empty()) {
+        check_writable(dir)?;
+    }
+
+    let stamp = Local::now().format("%Y%m%d-%H%M%S").to_string();
+    let mut repairs = Vec::new();
+
+    if let Some(reason) = session_problem(session) {
+        // The journal files belong to the damaged database and go with it
+        for path in storage::session_files(session) {
+            if path.exists() {
+                let moved_to = quarantine(&path, &stamp)?;
+                if path == *session {
+                    repairs.push(Repair {
+                        kind: RepairedFile::Session,
+                        path,
+                        moved_to,
+                        reason: reason.clone(),
+                    });
+                }
+            }
+        }
+    }
+
+    let last_chat = state::last_chat_file(config);
+    if last_chat.is_file() && state::load_last_chat(&last_chat).is_none() {
+        repairs.push(Repair {
+            kind: RepairedFile::LastChat,
+            moved_to: quarantine(&last_chat, &stamp)?,
+            path: last_chat,
+            reason: "not a chat ID".to_string(),
+        });
+    }
+
+    Ok(repairs)
+}
+
+/// Makes sure `dir` exists and files can be created in it.
+fn check_writable(dir: &Path) -> io::Result<()> {
+    fs::create_dir_all(dir)?;
+    let probe = dir.join(format!(".ithil-probe-{}", std::process::id()));
+    fs::write(&probe, b"")
+        .map_err(|e| io::Error::new(e.kind(), format!("{} isn't writable: {e}", dir.display())))?;
+    fs::remove_file(&probe)
+}
+
+/// Returns what's wrong with the session database, if anything.
+///
+/// A missing or empty file is fine: it's a session that hasn't logged in.
+/// Otherwise the file has to carry a SQLite header whose page size divides
+/// the file.
+fn session_problem(path: &Path) -> Option<String> {
+    let meta = fs::metadata(path).ok()?;
+    if !meta.is_file() {
+        return Some("not a file".to_string());
+    }
+    if meta.len() == 0 {
+        return None;
+    }
+
+    let mut header = [0; SQLITE_HEADER_LEN];
+    let read = fs::File::open(path).and_then(|mut file| file.read_exact(&mut header));
+    if let Err(e) = read {
+        return Some(if e.kind() == io::ErrorKind::UnexpectedEof {
+            "cut short".to_string()
+        } else {
+            format!("unreadable ({e})")
+        });
+    }
+    if &header[..SQLITE_MAGIC.len()] != SQLITE_MAGIC {
+        return Some("not a session database".to_string());
+    }
+
+    // A page size of 1 stands for 65536
+    let page_size = match u16::from_be_bytes([header[16], header[17]]) {
+        1 => 65_536,
+        size => u64::from(size),
+    };
+    if !(512..=65_536).contains(&page_size) || !page_size.is_power_of_two() {
+        return Some("damaged header".to_string());
+    }
+    if meta.len() % page_size != 0 {
+        return Some("cut short".to_string());
+    }
+    None
+}
+
+/// Renames `path` to `<path>.corrupt-<stamp>`, numbering it if that's
+/// taken, and returns the new path.
+fn quarantine(path: &Path, stamp: &str) -> io::Result<PathBuf> {
+    let moved_to = (1..)
+        .map(|n| {
+            let mut name = path.as_os_str().to_owned();
+            name.push(format!(".corrupt-{stamp}"));
+            if n > 1 {
+                name.push(format!("-{n}"));
+            }
+            PathBuf::from(name)
+        })
+        .find(|candidate| !candidate.exists())
+        .expect("some numbered name is free");
+    fs::rename(path, &moved_to)?;
+    Ok(moved_to)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn sqlite_file(pages: usize) -> Vec<u8> {
+        let mut bytes = vec![0; 4096 * pages];
+        bytes[..16].copy_from_slice(SQLITE_MAGIC);
+        bytes[16..18].copy_from_slice(&4096u16.to_be_bytes());
+        bytes
+    }
+
+    #[test]
+    fn damaged_files_are_set_aside_and_sound_ones_kept() {
+        let base = std::env::temp_dir().join(format!("ithil_health_test_{}", std::process::id()));
+        let mut config = Config::default();
+        config.telegram.session_file = base.join("ithil.session");
+        let session = config.telegram.session_file.clone();
+        let last_chat = state::last_chat_file(&config);
+
+        // Nothing there yet is fine
+        assert_eq!(check(&config).unwrap(), Vec::new());
+
+        fs::write(&session, sqlite_file(2)).unwrap();
+        fs::write(&last_chat, "42").unwrap();
+        assert_eq!(check(&config).unwrap(), Vec::new());
+        assert!(session.exists());
+
+        // A database cut mid-page goes, with its journal
+        let mut truncated = sqlite_file(2);
+        truncated.truncate(5000);
+        fs::write(&session, truncated).unwrap();
+        let wal = base.join("ithil.session-wal");
+        fs::write(&wal, b"journal").unwrap();
+        fs::write(&last_chat, "garbage").unwrap();
+
+        let repairs = check(&config).unwrap();
+        assert_eq!(repairs.len(), 2);
+        assert_eq!(repairs[0].kind, RepairedFile::Session);
+        assert_eq!(repairs[0].reason, "cut short");
+        assert!(repairs[0].moved_to.exists());
+        assert!(repairs[0]
+            .moved_to
+            .to_string_lossy()
+            .contains("ithil.session.corrupt-"));
+        assert_eq!(repairs[1].kind, RepairedFile::LastChat);
+        assert!(!session.exists());
+        assert!(!wal.exists());
+        assert!(!last_chat.exists());
+
+        fs::write(&session, [b'x'; 4096]).unwrap();
+        let repairs = check(&config).unwrap();
+        assert_eq!(repairs[0].reason, "not a session database");
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+}
@@ -0,0 +1,229 @@
+//! Shell commands run when messages arrive, for automations and alerts.
+//!
+//! Each hook in the config's `hooks` list says what sets it off (any new
+//! message, a mention of the user, or one of its keywords, which work as
+//! highlight words do and may be `/regular expressions/`), which chats it
+//! is limited to, if any, and a command. The command runs through the shell
+//! without holding up the app, with the message described in environment
+//! variables:
+//!
+//! | Variable           | Holds                                  |
+//! |--------------------|----------------------------------------|
+//! | `ITHIL_EVENT`      | `message`, `mention` or `keyword`      |
+//! | `ITHIL_CHAT_ID`    | The chat's ID                          |
+//! | `ITHIL_CHAT`       | The chat's title                       |
+//! | `ITHIL_SENDER_ID`  | The sender's user ID                   |
+//! | `ITHIL_SENDER`     | The sender's name                      |
+//! | `ITHIL_MESSAGE_ID` | The message's ID                       |
+//! | `ITHIL_DATE`       | When it was sent (RFC 3339)            |
+//! | `ITHIL_TEXT`       | Its text, or an attachment's caption   |
+//! | `ITHIL_KEYWORD`    | The keyword found (`keyword` hooks)    |
+//!
+//! The whole message is also written to the command's stdin as JSON. Only
+//! messages from others set hooks off. While the screen is locked, commands
+//! get the message without its text or attachment, so a hook that shows a
+//! notification doesn't put it on screen.
+//!
+//! ```yaml
+//! hooks:
+//!   - on: keyword
+//!     keywords: ["deploy failed", outage, '/disk \d+% full/']
+//!     chats: [Ops]
+//!     command: notify-send -u critical "$ITHIL_CHAT" "$ITHIL_TEXT"
+//! ```
+
+use tracing::{debug, warn};
+
+use super::config::{Hook, HookTrigger};
+use crate::types::{Chat, Message, MessageContent};
+
+/// Returns the hooks `message` in `chat` sets off, each with the keyword
+/// it was found by, for keyword hooks.
+#[must_use]
+pub fn triggered<'a>(
+    hooks: &'a [Hook],
+    chat: &Chat,
+    message: &Message,
+) -> Vec<(&'a Hook, Option<&'a str>)> {
+    if message.is_outgoing {
+        return Vec::new();
+    }
+    let text = message.content.text_or_caption();
+    hooks
+        .iter()
+        .filter(|hook| in_chats(hook, chat))
+        .filter_map(|hook| match hook.on {
+            HookTrigger::Message => Some((hook, None)),
+            HookTrigger::Mention => message.mentions_me.then_some((hook, None)),
+            HookTrigger::Keyword => hook.keywords.find(text).map(|k| (hook, Some(k))),
+        })
+        .collect()
+}
+
+/// Returns `true` if the hook covers `chat`: it lists no chats, or names
+/// this one by ID, title or username.
+fn in_chats(hook: &Hook, chat: &Chat) -> bool {
+    hook.chats.is_empty()
+        || hook.chats.iter().any(|entry| {
+            let entry = entry.trim();
+            entry == chat.id.to_string()
+                || entry.eq_ignore_ascii_case(&chat.title)
+                || (!chat.username.is_empty()
+                    && entry
+                        .strip_prefix('@')
+                        .is_some_and(|name| name.eq_ignore_ascii_case(&chat.username)))
+        })
+}
+
+/// Returns the environment a hook's command runs with.
+#[must_use]
+pub fn environment(
+    hook: &Hook,
+    keyword: Option<&str>,
+    chat: &Chat,
+    message: &Message,
+    sender: &str,
+) -> Vec<(&'static str, String)> {
+    let event = match hook.on {
+        HookTrigger::Message => "message",
+        HookTrigger::Mention => "mention",
+        HookTrigger::Keyword => "keyword",
+    };
+    let mut env = vec![
+        ("ITHIL_EVENT", event.to_string()),
+        ("ITHIL_CHAT_ID", chat.id.to_string()),
+        ("ITHIL_CHAT", chat.title.clone()),
+        ("ITHIL_SENDER_ID", message.sender_id.to_string()),
+        ("ITHIL_SENDER", sender.to_string()),
+        ("ITHIL_MESSAGE_ID", message.id.to_string()),
+        ("ITHIL_DATE", message.date.to_rfc3339()),
+        ("ITHIL_TEXT", message.content.text_or_caption().to_string()),
+    ];
+    if let Some(keyword) = keyword {
+        env.push(("ITHIL_KEYWORD", keyword.to_string()));
+    }
+    env
+}
+
+/// Returns the message as a hook's command sees it: without its content
+/// while the screen is `locked`.
+#[must_use]
+pub fn shown(message: &Message, locked: bool) -> Message {
+    if locked {
+        Message {
+            content: MessageContent::default(),
+            ..message.clone()
+        }
+    } else {
+        message.clone()
+    }
+}
+
+/// Runs the hooks `message` sets off, written by `sender`. Keywords are
+/// found in the whole message even while `locked`. A command that can't be
+/// started is logged rather than shown.
+pub fn run(hooks: &[Hook], chat: &Chat, message: &Message, sender: &str, locked: bool) {
+    let fired = triggered(hooks, chat, message);
+    if fired.is_empty() {
+        return;
+    }
+    let message = &shown(message, locked);
+    let json = serde_json::to_vec(message).unwrap_or_default();
+    for (hook, keyword) in fired {
+        debug!("Running hook for message {} in {}", message.id, chat.id);
+        let env = environment(hook, keyword, chat, message, sender);
+        if let Err(e) = crate::platform::run_shell(&hook.command, env, json.clone()) {
+            warn!("Hook \"{}\" failed to start: {}", hook.command, e);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::utils::Keywords;
+
+    fn hook(on: HookTrigger, chats: &[&str], keywords: &[&str]) -> Hook {
+        Hook {
+            on,
+            chats: chats.iter().map(ToString::to_string).collect(),
+            keywords: Keywords::new(keywords.iter().copied()),
+            command: "true".to_string(),
+        }
+    }
+
+    fn incoming(text: &str) -> Message {
+        Message {
+            id: 9,
+            chat_id: 5,
+            sender_id: 42,
+            content: MessageContent {
+                text: text.to_string(),
+                ..MessageContent::default()
+            },
+            ..Message::default()
+        }
+    }
+
+    #[test]
+    fn hooks_fire_on_their_trigger_in_their_chats() {
+        let ops = Chat {
+            id: 5,
+            title: "Ops".to_string(),
+            username: "ops_team".to_string(),
+            ..Chat::default()
+        };
+        let hooks = [
+            hook(HookTrigger::Message, &[], &[]),
+            hook(HookTrigger::Message, &["Home", "7"], &[]),
+            hook(HookTrigger::Keyword, &["@OPS_TEAM"], &["", "Deploy Failed"]),
+            hook(HookTrigger::Keyword, &[], &[r"/fail(ed|ing) \w+/"]),
+            hook(HookTrigger::Mention, &["5"], &[]),
+        ];
+
+        let fired = triggered(&hooks, &ops, &incoming("the deploy failed again"));
+        assert_eq!(fired.len(), 3);
+        assert!(std::ptr::eq(fired[0].0, &hooks[0]));
+        assert_eq!(fired[1].1, Some("Deploy Failed"));
+        assert_eq!(fired[2].1, Some(r"/fail(ed|ing) \w+/"));
+
+        let mut mention = incoming("ping");
+        mention.mentions_me = true;
+        assert_eq!(triggered(&hooks, &ops, &mention).len(), 2);
+
+        // The user's own messages never count
+        let mut mine = incoming("deploy failed");
+        mine.is_outgoing = true;
+        assert!(triggered(&hooks, &ops, &mine).is_empty());
+    }
+
+    #[test]
+    fn the_command_learns_about_the_message_from_its_environment() {
+        let chat = Chat {
+            id: 5,
+            title: "Ops".to_string(),
+            ..Chat::default()
+        };
+        let mut message = incoming("");
+        message.content.caption = "outage graph".to_string();
+        let hook = hook(HookTrigger::Keyword, &[], &["outage"]);
+
+        let env = environment(&hook, Some("outage"), &chat, &message, "Alice");
+        let var = |name: &str| {
+            env.iter()
+                .find(|(key, _)| *key == name)
+                .map(|(_, value)| value.as_str())
+        };
+        assert_eq!(var("ITHIL_EVENT"), Some("keyword"));
+        assert_eq!(var("ITHIL_CHAT"), Some("Ops"));
+        assert_eq!(var("ITHIL_SENDER"), Some("Alice"));
+        assert_eq!(var("ITHIL_TEXT"), Some("outage graph"));
+        assert_eq!(var("ITHIL_KEYWORD"), Some("outage"));
+
+        // Locked, the command hears of the message but not what it says
+        let locked = shown(&message, true);
+        let env = environment(&hook, Some("outage"), &chat, &locked, "Alice");
+        assert!(env.contains(&("ITHIL_TEXT", String::new())));
+        assert!(env.contains(&("ITHIL_SENDER", "Alice".to_string())));
+    }
+}
@@ -3,10 +3,26 @@
 //! This module provides:
 //! - Configuration loading and management
 //! - Default API credentials handling
+//! - Platform-specific config, state and cache directories
+//! - Session export and import
+//! - Encrypting local data of the chats marked sensitive
+//! - Measuring and clearing local data
+//! - Remembering the last open chat between runs
+//! - Checking the session files at startup and setting damaged ones aside
+//! - Running user-defined hooks when messages arrive
 //! - Application state management
 
 mod config;
 mod credentials;
+mod crypto;
+pub mod health;
+pub mod hooks;
+pub mod paths;
+mod session;
+pub mod state;
+pub mod storage;
+pub mod vault;
 
-pub use config::{Config, NotificationConfig};
+pub use config::{Config, Hook, HookTrigger, NotificationConfig, StartupView};
 pub use credentials::Credentials;
+pub use session::{export_session, import_session, ImportedSession};
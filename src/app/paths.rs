@@ -0,0 +1,408 @@
+//! Where Ithil keeps its files.
+//!
+//! - Config (`config.yaml`): `$XDG_CONFIG_HOME/ithil`
+//! - State (session, logs): `$XDG_STATE_HOME/ithil`
+//! - Cache (downloaded media, exports): `$XDG_CACHE_HOME/ithil`
+//! - Saved attachments: the user's downloads directory
+//!
+//! An `XDG_*` variable is honored on every platform when set to an absolute
+//! path. Otherwise the platform's usual location is used: the XDG defaults
+//! on Linux, `~/Library/Application Support` and `~/Library/Caches` on macOS,
+//! and `%APPDATA%` / `%LOCALAPPDATA%` on Windows.
+//!
+//! Older versions kept everything in the config directory (and on macOS read
+//! the config from `~/.config/ithil` as well). [`migrate_legacy_files`] moves
+//! the session and config to their new homes on first run.
+
+use std::ffi::OsString;
+use std::fs;
+use std::io;
+use std::path::{Path, PathBuf};
+use std::sync::{Mutex, PoisonError};
+
+use directories::ProjectDirs;
+
+use super::config::Config;
+
+/// Application name used in directory names.
+const APP_NAME: &str = "ithil";
+
+/// Session file name.
+pub const SESSION_FILE: &str = "ithil.session";
+
+/// Log file name.
+pub const LOG_FILE: &str = "ithil.log";
+
+/// Config file name.
+pub const CONFIG_FILE: &str = "config.yaml";
+
+/// Suffixes of files SQLite keeps next to a session database.
+pub(crate) const SESSION_SIDECARS: [&str; 3] = ["", "-wal", "-shm"];
+
+/// Returns the directory for the config file.
+#[must_use]
+pub fn config_dir() -> PathBuf {
+    resolve("XDG_CONFIG_HOME", ".config", |d| d.config_dir())
+}
+
+/// Returns the directory for state that should survive restarts but isn't
+/// configuration: the login session and logs.
+#[must_use]
+pub fn state_dir() -> PathBuf {
+    // Only Linux has a dedicated state directory; elsewhere local app data
+    resolve("XDG_STATE_HOME", ".local/state", |d| {
+        d.state_dir().unwrap_or(d.data_local_dir())
+    })
+}
+
+/// Returns the directory for files that can be re-downloaded or rebuilt.
+#[must_use]
+pub fn cache_dir() -> PathBuf {
+    resolve("XDG_CACHE_HOME", ".cache", |d| d.cache_dir())
+}
+
+/// The private directory [`runtime_dir`] made in the temp directory, if it
+/// had to.
+static RUNTIME_FALLBACK: Mutex<Option<PathBuf>> = Mutex::new(None);
+
+/// Returns a directory only the current user can use, for short-lived files
+/// such as editor drafts.
+///
+/// This is `$XDG_RUNTIME_DIR` when set, which belongs to the user alone.
+/// Otherwise the system temp directory is shared with other users, so a
+/// directory of its own is made in it once per process with
+/// [`create_private_dir`]; [`remove_runtime_dir`] removes it on quitting.
+///
+/// # Errors
+///
+/// Returns an error if that directory can't be made.
+pub fn runtime_dir() -> io::Result<PathBuf> {
+    if let Some(dir) = xdg_dir(std::env::var_os("XDG_RUNTIME_DIR")) {
+        return Ok(dir);
+    }
+    let mut fallback = RUNTIME_FALLBACK
+        .lock()
+        .unwrap_or_else(PoisonError::into_inner);
+    if let Some(dir) = fallback.as_ref().filter(|dir| dir.is_dir()) {
+        return Ok(dir.clone());
+    }
+    let dir = create_private_dir(&std::env::temp_dir(), APP_NAME)?;
+    *fallback = Some(dir.clone());
+    Ok(dir)
+}
+
+/// Removes the directory [`runtime_dir`] made in the temp directory, and
+/// everything in it, if it made one.
+pub fn remove_runtime_dir() {
+    let made = RUNTIME_FALLBACK
+        .lock()
+        .unwrap_or_else(PoisonError::into_inner)
+        .take();
+    if let Some(dir) = made {
+        if let Err(e) = fs::remove_dir_all(&dir) {
+            tracing::warn!("Failed to remove {}: {}", dir.display(), e);
+        }
+    }
+}
+
+/// Makes a new directory in `parent` that only the current user can use,
+/// named `prefix` and a random suffix, and returns it.
+///
+/// The directory never existed before, so no one else can have made it
+/// first, opened it up or left anything in it.
+///
+/// # Errors
+///
+/// Returns an error if no random suffix can be had or the directory can't
+/// be made.
+pub fn create_private_dir(parent: &Path, prefix: &str) -> io::Result<PathBuf> {
+    let mut builder = fs::DirBuilder::new();
+    #[cfg(unix)]
+    {
+        use std::os::unix::fs::DirBuilderExt;
+        builder.mode(0o700);
+    }
+    // A clash is all but impossible, unless someone is guessing
+    for _ in 0..8 {
+        let suffix: [u8; 8] = super::crypto::random().map_err(io::Error::other)?;
+        let dir = parent.join(format!("{prefix}-{}", crate::utils::to_hex(&suffix)));
+        match builder.create(&dir) {
+            Ok(()) => return Ok(dir),
+            Err(e) if e.kind() == io::ErrorKind::AlreadyExists => {},
+            Err(e) => return Err(e),
+        }
+    }
+    Err(io::Error::new(
+        io::ErrorKind::AlreadyExists,
+        format!(
+            "No free name for a private directory in {}",
+            parent.display()
+        ),
+    ))
+}
+
+/// Creates or truncates a file that only the current user can read, and
+/// opens it for writing.
+///
+/// A file already there is narrowed to the user before it is truncated,
+/// since the mode asked for only applies to a new one.
+///
+/// # Errors
+///
+/// Returns an error if the file can't be opened or its permissions set.
+pub fn create_private_file(path: &Path) -> io::Result<fs::File> {
+    let mut options = fs::OpenOptions::new();
+    options.write(true).create(true);
+    #[cfg(unix)]
+    {
+        use std::os::unix::fs::OpenOptionsExt;
+        options.mode(0o600);
+    }
+    let file = options.open(path)?;
+    #[cfg(unix)]
+    {
+        use std::os::unix::fs::PermissionsExt;
+        file.set_permissions(fs::Permissions::from_mode(0o600))?;
+    }
+    file.set_len(0)?;
+    Ok(file)
+}
+
+/// Returns the user's downloads directory, where attachments are saved.
+///
+/// Falls back to `~/Downloads` where the platform doesn't name one.
+#[must_use]
+pub fn downloads_dir() -> PathBuf {
+    dirs::download_dir()
+        .or_else(|| dirs::home_dir().map(|h| h.join("Downloads")))
+        .unwrap_or_else(|| PathBuf::from("Downloads"))
+}
+
+/// Returns the default config file path.
+#[must_use]
+pub fn config_file() -> PathBuf {
+    config_dir().join(CONFIG_FILE)
+}
+
+/// Returns the config directory older versions used on every platform.
+fn legacy_config_dir() -> Option<PathBuf> {
+    dirs::home_dir().map(|h| h.join(".config").join(APP_NAME))
+}
+
+/// Moves files left in their old locations by earlier versions.
+///
+/// Only default locations are migrated: a session path set in the config is
+/// left alone, and nothing is moved over an existing file. Old logs stay
+/// where they are.
+/// Returns the `(from, to)` pairs that were moved.
+///
+/// # Errors
+///
+/// Returns an error if a file exists in an old location but cannot be moved.
+pub fn migrate_legacy_files(config: &Config) -> io::Result<Vec<(PathBuf, PathBuf)>> {
+    let mut old_dirs: Vec<PathBuf> = legacy_config_dir().into_iter().collect();
+    let platform_config = config_dir();
+    if !old_dirs.contains(&platform_config) {
+        old_dirs.push(platform_config);
+    }
+
+    let mut moved = Vec::new();
+
+    if config.telegram.session_file == state_dir().join(SESSION_FILE) {
+        for dir in &old_dirs {
+            moved.extend(move_session(dir, &config.telegram.session_file)?);
+        }
+    }
+
+    // macOS used to read the config from ~/.config/ithil too
+    if let Some(old) = legacy_config_dir() {
+        moved.extend(move_if_absent(&old.join(CONFIG_FILE), &config_file())?);
+    }
+
+    Ok(moved)
+}
+
+/// Resolves an app directory from an `XDG_*` variable, the platform
+/// default, or `~/<fallback>/ithil`.
+fn resolve(var: &str, fallback: &str, platform: impl Fn(&ProjectDirs) -> &Path) -> PathBuf {
+    if let Some(base) = xdg_dir(std::env::var_os(var)) {
+        return base.join(APP_NAME);
+    }
+    ProjectDirs::from("", "", APP_NAME).map_or_else(
+        || {
+            dirs::home_dir().map_or_else(
+                || Path::new(fallback).join(APP_NAME),
+                |h| h.join(fallback).join(APP_NAME),
+            )
+        },
+        |dirs| platform(&dirs).to_path_buf(),
+    )
+}
+
+/// Interprets an `XDG_*` value; the spec says relative paths are ignored.
+fn xdg_dir(value: Option<OsString>) -> Option<PathBuf> {
+    value.map(PathBuf::from).filter(|p| p.is_absolute())
+}
+
+/// Moves the session in `dir` to `to` together with its SQLite sidecars,
+/// only if `dir` has a session and nothing of one is at `to` yet. A
+/// write-ahead log must never end up next to another database, which
+/// SQLite would replay it into.
+///
+/// Returns the pairs that were moved.
+fn move_session(dir: &Path, to: &Path) -> io::Result<Vec<(PathBuf, PathBuf)>> {
+    let from = dir.join(SESSION_FILE);
+    let name = |suffix: &str| format!("{SESSION_FILE}{suffix}");
+    let taken = SESSION_SIDECARS
+        .iter()
+        .any(|suffix| to.with_file_name(name(suffix)).exists());
+    if from == to || !from.is_file() || taken {
+        return Ok(Vec::new());
+    }
+    let mut moved = Vec::new();
+    for suffix in SESSION_SIDECARS {
+        let file = name(suffix);
+        moved.extend(move_if_absent(&dir.join(&file), &to.with_file_name(&file))?);
+    }
+    Ok(moved)
+}
+
+/// Moves `from` to `to` if `from` exists and `to` doesn't.
+///
+/// Returns the pair if the file was moved.
+fn move_if_absent(from: &Path, to: &Path) -> io::Result<Option<(PathBuf, PathBuf)>> {
+    if from == to || !from.is_file() || to.exists() {
+        return Ok(None);
+    }
+    if let Some(parent) = to.parent() {
+        fs::create_dir_all(parent)?;
+    }
+    // rename fails across filesystems; copy and delete instead
+    if fs::rename(from, to).is_err() {
+        fs::copy(from, to)?;
+        fs::remove_file(from)?;
+    }
+    Ok(Some((from.to_path_buf(), to.to_path_buf())))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn relative_xdg_values_are_ignored() {
+        assert_eq!(
+            xdg_dir(Some("/home/u/.state".into())),
+            Some(PathBuf::from("/home/u/.state"))
+        );
+        assert_eq!(xdg_dir(Some("relative/dir".into())), None);
+        assert_eq!(xdg_dir(Some(OsString::new())), None);
+        assert_eq!(xdg_dir(None), None);
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn private_files_are_closed_to_others_even_if_they_were_open() {
+        use std::io::Write;
+        use std::os::unix::fs::PermissionsExt;
+
+        let parent =
+            std::env::temp_dir().join(format!("ithil_private_file_test_{}", std::process::id()));
+        fs::create_dir_all(&parent).unwrap();
+        let path = parent.join("updates.jsonl");
+        fs::write(&path, "an older, longer recording").unwrap();
+        fs::set_permissions(&path, fs::Permissions::from_mode(0o644)).unwrap();
+
+        create_private_file(&path)
+            .unwrap()
+            .write_all(b"new")
+            .unwrap();
+        assert_eq!(fs::read(&path).unwrap(), b"new");
+        let mode = fs::metadata(&path).unwrap().permissions().mode();
+        assert_eq!(mode & 0o777, 0o600);
+
+        fs::remove_dir_all(&parent).unwrap();
+    }
+
+    #[test]
+    fn private_dirs_are_new_and_closed_to_others() {
+        let parent =
+            std::env::temp_dir().join(format!("ithil_private_dir_test_{}", std::process::id()));
+        fs::create_dir_all(&parent).unwrap();
+
+        let first = create_private_dir(&parent, "ithil").unwrap();
+        let second = create_private_dir(&parent, "ithil").unwrap();
+        assert_ne!(first, second);
+        assert!(first
+            .file_name()
+            .unwrap()
+            .to_string_lossy()
+            .starts_with("ithil-"));
+        #[cfg(unix)]
+        {
+            use std::os::unix::fs::PermissionsExt;
+            let mode = fs::metadata(&first).unwrap().permissions().mode();
+            assert_eq!(mode & 0o777, 0o700);
+        }
+
+        fs::remove_dir_all(&parent).unwrap();
+    }
+
+    #[test]
+    fn moves_only_when_destination_is_free() {
+        let base = std::env::temp_dir().join(format!("ithil_paths_test_{}", std::process::id()));
+        let from = base.join("old").join("ithil.session");
+        let to = base.join("new").join("ithil.session");
+        fs::create_dir_all(from.parent().unwrap()).unwrap();
+        fs::write(&from, b"session").unwrap();
+
+        assert_eq!(
+            move_if_absent(&from, &to).unwrap(),
+            Some((from.clone(), to.clone()))
+        );
+        assert!(!from.exists());
+        assert_eq!(fs::read(&to).unwrap(), b"session");
+
+        // An existing destination is never overwritten
+        fs::write(&from, b"stale").unwrap();
+        assert_eq!(move_if_absent(&from, &to).unwrap(), None);
+        assert_eq!(fs::read(&to).unwrap(), b"session");
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+
+    #[test]
+    fn a_session_moves_with_its_sidecars_or_not_at_all() {
+        let base =
+            std::env::temp_dir().join(format!("ithil_paths_session_test_{}", std::process::id()));
+        let old = base.join("old");
+        let to = base.join("new").join(SESSION_FILE);
+        fs::create_dir_all(&old).unwrap();
+        fs::create_dir_all(to.parent().unwrap()).unwrap();
+        let wal = format!("{SESSION_FILE}-wal");
+
+        // A session already in place keeps its own state; the old log stays
+        fs::write(&to, b"current").unwrap();
+        fs::write(old.join(SESSION_FILE), b"old").unwrap();
+        fs::write(old.join(&wal), b"old log").unwrap();
+        assert_eq!(move_session(&old, &to).unwrap(), Vec::new());
+        assert!(!to.with_file_name(&wal).exists());
+        assert!(old.join(&wal).exists());
+
+        // With no session in place, the log goes along with the database
+        fs::remove_file(&to).unwrap();
+        let moved = move_session(&old, &to).unwrap();
+        assert_eq!(moved.len(), 2);
+        assert_eq!(fs::read(&to).unwrap(), b"old");
+        assert_eq!(fs::read(to.with_file_name(&wal)).unwrap(), b"old log");
+
+        // A leftover log alone is never joined to a database
+        fs::write(old.join(&wal), b"stray log").unwrap();
+        fs::remove_file(&to).unwrap();
+        fs::write(old.join(SESSION_FILE), b"older").unwrap();
+        assert_eq!(move_session(&old, &to).unwrap(), Vec::new());
+        assert_eq!(fs::read(to.with_file_name(&wal)).unwrap(), b"old log");
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+}
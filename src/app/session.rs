@@ -0,0 +1,502 @@
+//! Exporting and importing the login session between machines.
+//!
+//! `ithil session export` bundles the session file, the config file and the
+//! bookmarks, local pins, last open chat, reactions used, recent forward
+//! destinations and aliases kept next to the session into one archive,
+//! encrypted with a key derived from a passphrase.
+//! Importing it on another machine restores them all, so there is no need to
+//! log in again.
+//!
+//! Archive layout: `MAGIC`, a version byte, the PBKDF2 iteration count (u32,
+//! big-endian), the salt, the IV, then the entries encrypted as described in
+//! [`super::crypto`], with the header authenticated too. Each entry is a u16
+//! name length, the name, a u64 data length and the data.
+
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use anyhow::{bail, Context, Result};
+
+use super::config::Config;
+use super::crypto::{self, Cipher, IV_LEN};
+use super::{paths, state, storage};
+
+/// Marks the start of a session archive.
+const MAGIC: &[u8; 8] = b"ITHILSES";
+
+/// Archive format version.
+const VERSION: u8 = 1;
+
+/// PBKDF2 iterations for new archives.
+///
+/// Higher than for the lock screen: an archive can be attacked offline.
+const ITERATIONS: u32 = 600_000;
+
+/// Most PBKDF2 iterations an archive may ask for, so a crafted header
+/// can't keep an import busy for hours.
+const MAX_ITERATIONS: u32 = 10 * ITERATIONS;
+
+/// Salt length in bytes.
+const SALT_LEN: usize = 16;
+
+/// Length of everything before the ciphertext.
+const HEADER_LEN: usize = MAGIC.len() + 1 + 4 + SALT_LEN + IV_LEN;
+
+/// Entry holding the session file.
+const SESSION_ENTRY: &str = "session";
+
+/// Entry holding the session's write-ahead log, which holds its latest
+/// changes until SQLite folds them into the session file.
+const SESSION_WAL_ENTRY: &str = "session-wal";
+
+/// Entry holding the config file.
+const CONFIG_ENTRY: &str = "config.yaml";
+
+/// Where an imported archive was written.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct ImportedSession {
+    /// Session file that was written
+    pub session_file: PathBuf,
+    /// Config file that was written, if the archive had one
+    pub config_file: Option<PathBuf>,
+}
+
+/// Writes the session, config and saved state to an encrypted archive at
+/// `out`.
+///
+/// `config_file` is the file the config was loaded from; without one the
+/// config is left out. State files that don't exist yet are left out too.
+/// The session's write-ahead log goes with it, so changes Ithil hasn't
+/// folded into the session file yet aren't lost; the copy is only certain
+/// to be consistent while Ithil isn't running.
+///
+/// # Errors
+///
+/// Returns an error if there is no session file (never logged in), a file
+/// cannot be read, or the archive cannot be written.
+pub fn export_session(
+    config: &Config,
+    config_file: Option<&Path>,
+    passphrase: &str,
+    out: &Path,
+) -> Result<()> {
+    let session_path = &config.telegram.session_file;
+    let session = fs::read(session_path).with_context(|| {
+        format!(
+            "No session to export at {} (log in first)",
+            session_path.display()
+        )
+    })?;
+
+    let mut entries = vec![(SESSION_ENTRY.to_string(), session)];
+    let [_, wal, _] = storage::session_files(session_path);
+    if let Some(content) = read_if_exists(&wal)? {
+        entries.push((SESSION_WAL_ENTRY.to_string(), content));
+    }
+    if let Some(path) = config_file {
+        let content = fs::read(path)
+            .with_context(|| format!("Failed to read config file: {}", path.display()))?;
+        entries.push((CONFIG_ENTRY.to_string(), content));
+    }
+    for name in state::FILES {
+        if let Some(content) = read_if_exists(&session_path.with_file_name(name))? {
+            entries.push((name.to_string(), content));
+        }
+    }
+
+    // It holds the auth key, so only the user may read it
+    write_private(out, &seal(&entries, passphrase, ITERATIONS)?)
+}
+
+/// Restores a session archive made by [`export_session`].
+///
+/// The config (if the archive has one) is written to `config_file`, with the
+/// paths it sets replaced by those of `local`, the config in effect on this
+/// machine. The session is written to `local`'s session path, with the saved
+/// state next to it. Existing files are only replaced when `force` is set;
+/// nothing is written if any would be. The session's SQLite sidecars count as
+/// part of it: stale ones are removed, so SQLite can't replay an old log into
+/// the imported session.
+///
+/// # Errors
+///
+/// Returns an error if the archive cannot be read, the passphrase is wrong,
+/// the archive is damaged, a file would be overwritten without `force`, or a
+/// file cannot be written.
+pub fn import_session(
+    archive: &Path,
+    passphrase: &str,
+    config_file: &Path,
+    local: &Config,
+    force: bool,
+) -> Result<ImportedSession> {
+    let data =
+        fs::read(archive).with_context(|| format!("Failed to read {}", archive.display()))?;
+    let mut entries = open(&data, passphrase)?;
+
+    let take_entry = |entries: &mut Vec<(String, Vec<u8>)>, name: &str| {
+        entries
+            .iter()
+            .position(|(n, _)| n == name)
+            .map(|i| entries.swap_remove(i).1)
+    };
+    let Some(session) = take_entry(&mut entries, SESSION_ENTRY) else {
+        bail!("Archive has no session");
+    };
+    let wal = take_entry(&mut entries, SESSION_WAL_ENTRY);
+    // The archived config's paths are the other machine's, so the session
+    // goes where this machine keeps it
+    let config_content = take_entry(&mut entries, CONFIG_ENTRY)
+        .map(|content| rebase_config(&content, local))
+        .transpose()?;
+    let session_file = local.telegram.session_file.clone();
+    // Only the known state files, so an entry's name can't point elsewhere
+    let state_files: Vec<(PathBuf, Vec<u8>)> = state::FILES
+        .iter()
+        .filter_map(|name| {
+            let content = take_entry(&mut entries, name)?;
+            Some((session_file.with_file_name(name), content))
+        })
+        .collect();
+
+    let sidecars = storage::session_files(&session_file);
+    if !force {
+        let mut existing: Vec<&Path> = sidecars.iter().map(PathBuf::as_path).collect();
+        if config_content.is_some() {
+            existing.push(config_file);
+        }
+        existing.extend(state_files.iter().map(|(path, _)| path.as_path()));
+        if let Some(path) = existing.into_iter().find(|p| p.exists()) {
+            bail!(
+                "{} already exists; use --force to replace it",
+                path.display()
+            );
+        }
+    }
+
+    let config_file = match config_content {
+        Some(content) => {
+            write_private(config_file, &content)?;
+            Some(config_file.to_path_buf())
+        },
+        None => None,
+    };
+    storage::remove_all(&sidecars)
+        .with_context(|| format!("Failed to replace {}", session_file.display()))?;
+    write_private(&session_file, &session)?;
+    if let Some(wal) = wal {
+        let [_, wal_file, _] = &sidecars;
+        write_private(wal_file, &wal)?;
+    }
+    for (path, content) in &state_files {
+        write_private(path, content)?;
+    }
+
+    Ok(ImportedSession {
+        session_file,
+        config_file,
+    })
+}
+
+/// Replaces the paths an archived config sets with `local`'s.
+///
+/// [`Config::save`] writes them expanded, so they name the home directory,
+/// and perhaps the platform, of the machine the archive was made on.
+/// Returns the content to write, which is the archived one unless a path
+/// was replaced.
+fn rebase_config(content: &[u8], local: &Config) -> Result<Vec<u8>> {
+    let text = std::str::from_utf8(content).context("Archived config is not UTF-8")?;
+    serde_yaml::from_str::<Config>(text).context("Failed to parse archived config")?;
+    let mut value: serde_yaml::Value =
+        serde_yaml::from_str(text).context("Failed to parse archived config")?;
+
+    let paths: [(&[&str], &Path); 5] = [
+        (&["telegram", "session_file"], &local.telegram.session_file),
+        (
+            &["telegram", "database_directory"],
+            &local.telegram.database_directory,
+        ),
+        (&["cache", "media_directory"], &local.cache.media_directory),
+        (
+            &["ui", "behavior", "download_directory"],
+            &local.ui.behavior.download_directory,
+        ),
+        (&["logging", "file"], &local.logging.file),
+    ];
+    let mut rebased = false;
+    for (keys, path) in paths {
+        rebased |= replace_path(&mut value, keys, path).is_some();
+    }
+
+    if !rebased {
+        return Ok(content.to_vec());
+    }
+    Ok(serde_yaml::to_string(&value)
+        .context("Failed to serialize configuration")?
+        .into_bytes())
+}
+
+/// Sets the value at `keys` to `path`, if the config sets it at all.
+fn replace_path(value: &mut serde_yaml::Value, keys: &[&str], path: &Path) -> Option<()> {
+    let mut node = value;
+    for key in keys {
+        node = node.get_mut(*key)?;
+    }
+    *node = serde_yaml::Value::String(path.to_string_lossy().into_owned());
+    Some(())
+}
+
+/// Reads a file, or returns `None` if there is none.
+fn read_if_exists(path: &Path) -> Result<Option<Vec<u8>>> {
+    match fs::read(path) {
+        Ok(content) => Ok(Some(content)),
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(None),
+        Err(e) => Err(e).with_context(|| format!("Failed to read {}", path.display())),
+    }
+}
+
+/// Encrypts `entries` into an archive.
+fn seal(entries: &[(String, Vec<u8>)], passphrase: &str, iterations: u32) -> Result<Vec<u8>> {
+    let mut plain = Vec::new();
+    for (name, data) in entries {
+        let name_len = u16::try_from(name.len()).context("Entry name too long")?;
+        plain.extend_from_slice(&name_len.to_be_bytes());
+        plain.extend_from_slice(name.as_bytes());
+        plain.extend_from_slice(&(data.len() as u64).to_be_bytes());
+        plain.extend_from_slice(data);
+    }
+
+    let salt: [u8; SALT_LEN] = crypto::random()?;
+    let iv: [u8; IV_LEN] = crypto::random()?;
+
+    let mut out = Vec::with_capacity(HEADER_LEN + plain.len() + crypto::TAG_LEN);
+    out.extend_from_slice(MAGIC);
+    out.push(VERSION);
+    out.extend_from_slice(&iterations.to_be_bytes());
+    out.extend_from_slice(&salt);
+    out.extend_from_slice(&iv);
+
+    // The header is authenticated along with the entries
+    let sealed = Cipher::derive(passphrase, &salt, iterations).encrypt(&iv, &out, &plain);
+    out.extend_from_slice(&sealed);
+    Ok(out)
+}
+
+/// Decrypts an archive into its entries.
+fn open(data: &[u8], passphrase: &str) -> Result<Vec<(String, Vec<u8>)>> {
+    if data.len() < HEADER_LEN || !data.starts_with(MAGIC) {
+        bail!("Not an Ithil session archive");
+    }
+    let (header, ciphertext) = data.split_at(HEADER_LEN);
+    let version = header[MAGIC.len()];
+    if version != VERSION {
+        bail!("Unsupported session archive version {version}");
+    }
+    let mut rest = &header[MAGIC.len() + 1..];
+    let iterations = u32::from_be_bytes(take_array(&mut rest)?);
+    let salt: [u8; SALT_LEN] = take_array(&mut rest)?;
+    let iv: [u8; IV_LEN] = take_array(&mut rest)?;
+    if iterations == 0 || iterations > MAX_ITERATIONS {
+        bail!("Damaged session archive");
+    }
+
+    let plain = Cipher::derive(passphrase, &salt, iterations)
+        .decrypt(&iv, header, ciphertext)
+        .context("Wrong passphrase or damaged archive")?;
+
+    let mut entries = Vec::new();
+    let mut rest = plain.as_slice();
+    while !rest.is_empty() {
+        let name_len = usize::from(u16::from_be_bytes(take_array(&mut rest)?));
+        let name = String::from_utf8(take(&mut rest, name_len)?.to_vec())
+            .context("Damaged session archive")?;
+        let data_len = usize::try_from(u64::from_be_bytes(take_array(&mut rest)?))
+            .context("Damaged session archive")?;
+        entries.push((name, take(&mut rest, data_len)?.to_vec()));
+    }
+    Ok(entries)
+}
+
+/// Splits `len` bytes off the front of `rest`.
+fn take<'a>(rest: &mut &'a [u8], len: usize) -> Result<&'a [u8]> {
+    if rest.len() < len {
+        bail!("Damaged session archive");
+    }
+    let (head, tail) = rest.split_at(len);
+    *rest = tail;
+    Ok(head)
+}
+
+/// Splits a fixed-size array off the front of `rest`.
+fn take_array<const N: usize>(rest: &mut &[u8]) -> Result<[u8; N]> {
+    let mut out = [0u8; N];
+    out.copy_from_slice(take(rest, N)?);
+    Ok(out)
+}
+
+/// Writes a file readable only by the current user, creating its directory.
+pub(super) fn write_private(path: &Path, data: &[u8]) -> Result<()> {
+    if let Some(parent) = path.parent() {
+        fs::create_dir_all(parent)
+            .with_context(|| format!("Failed to create directory: {}", parent.display()))?;
+    }
+
+    let mut file = paths::create_private_file(path)
+        .with_context(|| format!("Failed to write {}", path.display()))?;
+    std::io::Write::write_all(&mut file, data)
+        .with_context(|| format!("Failed to write {}", path.display()))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn entries() -> Vec<(String, Vec<u8>)> {
+        vec![
+            (SESSION_ENTRY.to_string(), vec![0, 1, 2, 255]),
+            (CONFIG_ENTRY.to_string(), b"ui:\n  theme: light\n".to_vec()),
+        ]
+    }
+
+    #[test]
+    fn round_trips_with_the_right_passphrase() {
+        let archive = seal(&entries(), "hunter2", 1000).unwrap();
+        assert!(archive.starts_with(MAGIC));
+        assert_eq!(open(&archive, "hunter2").unwrap(), entries());
+    }
+
+    #[test]
+    fn rejects_wrong_passphrase_and_tampering() {
+        let mut archive = seal(&entries(), "hunter2", 1000).unwrap();
+        assert!(open(&archive, "hunter3").is_err());
+
+        // Lowering the iteration count in the header must not go unnoticed
+        archive[MAGIC.len() + 4] ^= 1;
+        assert!(open(&archive, "hunter2").is_err());
+    }
+
+    #[test]
+    fn refuses_huge_iteration_counts_before_deriving() {
+        let mut archive = seal(&entries(), "hunter2", 1000).unwrap();
+        archive[MAGIC.len() + 1..MAGIC.len() + 5].copy_from_slice(&u32::MAX.to_be_bytes());
+        let started = std::time::Instant::now();
+        assert!(open(&archive, "hunter2").is_err());
+        assert!(started.elapsed() < std::time::Duration::from_secs(5));
+    }
+
+    /// Returns a config keeping the session at `session_file`.
+    fn local_config(session_file: &Path) -> Config {
+        let mut config = Config::default();
+        config.telegram.session_file = session_file.to_path_buf();
+        config
+    }
+
+    #[test]
+    fn imports_saved_state_next_to_the_session() {
+        let base = std::env::temp_dir().join(format!("ithil_session_test_{}", std::process::id()));
+        let session_file = base.join("data").join("ithil.session");
+        let config = format!("telegram:\n  session_file: {}\n", session_file.display());
+        let entries = vec![
+            (SESSION_ENTRY.to_string(), vec![1, 2, 3]),
+            (CONFIG_ENTRY.to_string(), config.into_bytes()),
+            (state::BOOKMARKS_FILE.to_string(), b"[]".to_vec()),
+            ("../elsewhere".to_string(), b"x".to_vec()),
+        ];
+        let archive = base.join("ithil.session.enc");
+        write_private(&archive, &seal(&entries, "pw", 1000).unwrap()).unwrap();
+
+        let config_file = base.join("config.yaml");
+        let local = local_config(&session_file);
+        import_session(&archive, "pw", &config_file, &local, false).unwrap();
+        let bookmarks = session_file.with_file_name(state::BOOKMARKS_FILE);
+        assert_eq!(fs::read(&bookmarks).unwrap(), b"[]");
+        assert!(!session_file.with_file_name(state::LOCAL_PINS_FILE).exists());
+        assert!(!base.join("elsewhere").exists());
+
+        // The state counts as something that would be overwritten
+        fs::remove_file(&session_file).unwrap();
+        fs::remove_file(&config_file).unwrap();
+        let err = import_session(&archive, "pw", &config_file, &local, false).unwrap_err();
+        assert!(err.to_string().contains(state::BOOKMARKS_FILE));
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+
+    #[test]
+    fn the_session_is_imported_with_its_log_and_without_stale_sidecars() {
+        let base =
+            std::env::temp_dir().join(format!("ithil_session_wal_test_{}", std::process::id()));
+        let session_file = base.join("ithil.session");
+        let [_, wal, shm] = storage::session_files(&session_file);
+        let config = format!("telegram:\n  session_file: {}\n", session_file.display());
+        let entries = vec![
+            (SESSION_ENTRY.to_string(), vec![1, 2, 3]),
+            (SESSION_WAL_ENTRY.to_string(), vec![4, 5]),
+            (CONFIG_ENTRY.to_string(), config.into_bytes()),
+        ];
+        let archive = base.join("ithil.session.enc");
+        write_private(&archive, &seal(&entries, "pw", 1000).unwrap()).unwrap();
+        let config_file = base.join("config.yaml");
+        let local = local_config(&session_file);
+
+        // A sidecar left behind by an old session counts as the session
+        write_private(&shm, b"old").unwrap();
+        let err = import_session(&archive, "pw", &config_file, &local, false).unwrap_err();
+        assert!(err.to_string().contains("ithil.session-shm"));
+
+        import_session(&archive, "pw", &config_file, &local, true).unwrap();
+        assert_eq!(fs::read(&session_file).unwrap(), [1, 2, 3]);
+        assert_eq!(fs::read(&wal).unwrap(), [4, 5]);
+        assert!(!shm.exists());
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+
+    #[test]
+    fn another_machines_paths_are_replaced_by_this_ones() {
+        let base =
+            std::env::temp_dir().join(format!("ithil_session_paths_test_{}", std::process::id()));
+        let foreign = Path::new("/home/someone-else/.local/share/ithil");
+        let config = format!(
+            "telegram:\n  session_file: {}\n  database_directory: {}\nui:\n  theme: nord\n",
+            foreign.join("ithil.session").display(),
+            foreign.join("tdlib").display(),
+        );
+        let entries = vec![
+            (SESSION_ENTRY.to_string(), vec![1, 2, 3]),
+            (CONFIG_ENTRY.to_string(), config.into_bytes()),
+            (state::LAST_CHAT_FILE.to_string(), b"42".to_vec()),
+        ];
+        let archive = base.join("ithil.session.enc");
+        write_private(&archive, &seal(&entries, "pw", 1000).unwrap()).unwrap();
+
+        let session_file = base.join("data").join("ithil.session");
+        let local = local_config(&session_file);
+        let config_file = base.join("config.yaml");
+        let imported = import_session(&archive, "pw", &config_file, &local, false).unwrap();
+        assert_eq!(imported.session_file, session_file);
+        assert_eq!(fs::read(&session_file).unwrap(), [1, 2, 3]);
+        assert_eq!(
+            fs::read(session_file.with_file_name(state::LAST_CHAT_FILE)).unwrap(),
+            b"42"
+        );
+
+        let written = Config::load(Some(&config_file)).unwrap();
+        assert_eq!(written.telegram.session_file, session_file);
+        assert_eq!(
+            written.telegram.database_directory,
+            local.telegram.database_directory
+        );
+        assert_eq!(written.ui.theme, "nord");
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+
+    #[test]
+    fn rejects_truncated_or_foreign_files() {
+        let archive = seal(&entries(), "pw", 1000).unwrap();
+        assert!(open(&archive[..archive.len() - 1], "pw").is_err());
+        assert!(open(&archive[..HEADER_LEN - 1], "pw").is_err());
+        assert!(open(b"not an archive at all, just some text", "pw").is_err());
+    }
+}
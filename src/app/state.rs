@@ -0,0 +1,640 @@
+//! State kept between runs that isn't configuration: the chat that was
+//! open when Ithil quit, for `startup_view: last`, the messages
+//! bookmarked with `b`, those pinned locally with `P`, the reactions
+//! sent and pinned for the reaction picker's quick row, the chats
+//! forwarded to most recently, and the aliases set with `/alias`.
+//!
+//! It lives next to the session file, so a custom session path keeps its
+//! own. Bookmarks and local pins from chats marked with `/encrypt` are
+//! stored with their sender and excerpt sealed by the [`Vault`].
+
+use std::collections::HashMap;
+use std::fs;
+use std::io;
+use std::path::{Path, PathBuf};
+
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+
+use super::vault::Vault;
+use super::Config;
+
+/// Name of the file holding the last open chat's ID.
+pub const LAST_CHAT_FILE: &str = "last_chat";
+
+/// Name of the file holding the bookmarks.
+pub const BOOKMARKS_FILE: &str = "bookmarks.json";
+
+/// Name of the file holding the messages pinned on this device.
+pub const LOCAL_PINS_FILE: &str = "local_pins.json";
+
+/// Name of the file holding the reactions sent and pinned.
+pub const REACTIONS_FILE: &str = "reactions.json";
+
+/// Name of the file holding the recent forward destinations.
+pub const FORWARD_TARGETS_FILE: &str = "forward_targets.json";
+
+/// Name of the file holding the aliases of chats and users.
+pub const ALIASES_FILE: &str = "aliases.json";
+
+/// Names of every file kept here, which travel with the session in
+/// exports.
+pub const FILES: [&str; 6] = [
+    LAST_CHAT_FILE,
+    BOOKMARKS_FILE,
+    LOCAL_PINS_FILE,
+    REACTIONS_FILE,
+    FORWARD_TARGETS_FILE,
+    ALIASES_FILE,
+];
+
+/// Maximum number of recent forward destinations remembered.
+pub const MAX_FORWARD_TARGETS: usize = 10;
+
+/// Number of reactions in the reaction picker's quick row, one per digit key.
+pub const QUICK_REACTIONS: usize = 9;
+
+/// Shown in place of an excerpt that is sealed with a key not at hand.
+pub const SEALED_EXCERPT: &str = "Encrypted; unlock with the passphrase it was saved under";
+
+/// A message saved with `b` to come back to, kept on this device only.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct Bookmark {
+    /// Chat the message is in
+    pub chat_id: i64,
+    /// The message's ID
+    pub message_id: i64,
+    /// The chat's name when bookmarked
+    pub chat: String,
+    /// Who wrote it
+    pub sender: String,
+    /// The start of the message
+    pub excerpt: String,
+    /// When it was sent
+    pub date: DateTime<Utc>,
+    /// Sender and excerpt sealed by the vault, kept while they can't be
+    /// opened
+    #[serde(default, skip_serializing_if = "String::is_empty")]
+    pub sealed: String,
+}
+
+/// A message pinned with `P` on this device only, apart from the chat's
+/// Telegram pins, which need the right to pin.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct LocalPin {
+    /// Chat the message is in
+    pub chat_id: i64,
+    /// The message's ID
+    pub message_id: i64,
+    /// Who wrote it
+    pub sender: String,
+    /// The start of the message
+    pub excerpt: String,
+    /// When it was sent
+    pub date: DateTime<Utc>,
+    /// Sender and excerpt sealed by the vault, kept while they can't be
+    /// opened
+    #[serde(default, skip_serializing_if = "String::is_empty")]
+    pub sealed: String,
+}
+
+/// The reactions sent from this device, for the reaction picker's quick
+/// row.
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(default)]
+pub struct ReactionHistory {
+    /// How many times each reaction was sent
+    pub counts: HashMap<String, u32>,
+    /// Reactions pinned to the front of the quick row, in pinning order
+    pub favorites: Vec<String>,
+}
+
+impl ReactionHistory {
+    /// Counts a use of `reaction`.
+    pub fn remember(&mut self, reaction: &str) {
+        *self.counts.entry(reaction.to_string()).or_default() += 1;
+    }
+
+    /// Pins `reaction` to the quick row, or unpins it. Returns `true` if
+    /// it's pinned now.
+    pub fn toggle_favorite(&mut self, reaction: &str) -> bool {
+        if let Some(pos) = self.favorites.iter().position(|r| r == reaction) {
+            self.favorites.remove(pos);
+            false
+        } else {
+            self.favorites.push(reaction.to_string());
+            true
+        }
+    }
+
+    /// Returns the quick row: pinned reactions, then the most used ones, up
+    /// to [`QUICK_REACTIONS`].
+    #[must_use]
+    pub fn quick(&self) -> Vec<String> {
+        let mut used: Vec<(&String, &u32)> = self
+            .counts
+            .iter()
+            .filter(|(r, _)| !self.favorites.contains(r))
+            .collect();
+        // Ties in a stable order, so the row doesn't shuffle between opens
+        used.sort_by(|a, b| b.1.cmp(a.1).then_with(|| a.0.cmp(b.0)));
+        self.favorites
+            .iter()
+            .chain(used.into_iter().map(|(r, _)| r))
+            .take(QUICK_REACTIONS)
+            .cloned()
+            .collect()
+    }
+}
+
+/// Local display names for chats and users, keyed by ID, shown in place of
+/// their real names.
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(transparent)]
+pub struct Aliases(HashMap<i64, String>);
+
+impl Aliases {
+    /// Returns the alias for a chat or user, if one is set.
+    ///
+    /// Blank aliases are treated as unset, so clearing an entry in the file
+    /// falls back to the real name.
+    #[must_use]
+    pub fn get(&self, id: i64) -> Option<&str> {
+        self.0.get(&id).map(|a| a.trim()).filter(|a| !a.is_empty())
+    }
+
+    /// Sets or clears the alias for a chat or user.
+    ///
+    /// Passing an empty (or whitespace-only) name removes the alias.
+    pub fn set(&mut self, id: i64, name: &str) {
+        let name = name.trim();
+        if name.is_empty() {
+            self.0.remove(&id);
+        } else {
+            self.0.insert(id, name.to_string());
+        }
+    }
+
+    /// Returns every alias by ID.
+    #[must_use]
+    pub const fn as_map(&self) -> &HashMap<i64, String> {
+        &self.0
+    }
+}
+
+/// Returns where each of the [`FILES`] is kept, whether it exists or not.
+#[must_use]
+pub fn files(config: &Config) -> Vec<PathBuf> {
+    FILES
+        .iter()
+        .map(|name| config.telegram.session_file.with_file_name(name))
+        .collect()
+}
+
+/// Returns where the last open chat is remembered.
+#[must_use]
+pub fn last_chat_file(config: &Config) -> PathBuf {
+    config.telegram.session_file.with_file_name(LAST_CHAT_FILE)
+}
+
+/// Returns the chat that was open when Ithil last quit, if any.
+#[must_use]
+pub fn load_last_chat(path: &Path) -> Option<i64> {
+    fs::read_to_string(path).ok()?.trim().parse().ok()
+}
+
+/// Remembers the open chat.
+///
+/// # Errors
+///
+/// Returns an error if the file can't be written.
+pub fn save_last_chat(path: &Path, chat_id: i64) -> io::Result<()> {
+    if let Some(parent) = path.parent() {
+        fs::create_dir_all(parent)?;
+    }
+    fs::write(path, chat_id.to_string())
+}
+
+/// Returns where bookmarks are kept.
+#[must_use]
+pub fn bookmarks_file(config: &Config) -> PathBuf {
+    config.telegram.session_file.with_file_name(BOOKMARKS_FILE)
+}
+
+/// Returns the bookmarks, newest first, opening sealed ones with `vault`.
+/// A missing or unreadable file means there are none.
+#[must_use]
+pub fn load_bookmarks(path: &Path, vault: Option<&Vault>) -> Vec<Bookmark> {
+    let mut bookmarks: Vec<Bookmark> = fs::read_to_string(path)
+        .ok()
+        .and_then(|json| serde_json::from_str(&json).ok())
+        .unwrap_or_default();
+    for b in &mut bookmarks {
+        open_entry(&mut b.sealed, &mut b.sender, &mut b.excerpt, vault);
+    }
+    bookmarks
+}
+
+/// Saves the bookmarks, sealing those from `encrypted_chats` with `vault`.
+///
+/// # Errors
+///
+/// Returns an error if the file can't be written.
+pub fn save_bookmarks(
+    path: &Path,
+    bookmarks: &[Bookmark],
+    vault: Option<&Vault>,
+    encrypted_chats: &[i64],
+) -> io::Result<()> {
+    let mut bookmarks = bookmarks.to_vec();
+    for b in &mut bookmarks {
+        let encrypted = encrypted_chats.contains(&b.chat_id);
+        seal_entry(
+            &mut b.sealed,
+            &mut b.sender,
+            &mut b.excerpt,
+            encrypted,
+            vault,
+        )?;
+    }
+    write_json(path, &bookmarks)
+}
+
+/// Returns where local pins are kept.
+#[must_use]
+pub fn local_pins_file(config: &Config) -> PathBuf {
+    config.telegram.session_file.with_file_name(LOCAL_PINS_FILE)
+}
+
+/// Returns the local pins of every chat, newest pin first, opening sealed
+/// ones with `vault`. A missing or unreadable file means there are none.
+#[must_use]
+pub fn load_local_pins(path: &Path, vault: Option<&Vault>) -> Vec<LocalPin> {
+    let mut pins: Vec<LocalPin> = fs::read_to_string(path)
+        .ok()
+        .and_then(|json| serde_json::from_str(&json).ok())
+        .unwrap_or_default();
+    for p in &mut pins {
+        open_entry(&mut p.sealed, &mut p.sender, &mut p.excerpt, vault);
+    }
+    pins
+}
+
+/// Saves the local pins, sealing those from `encrypted_chats` with `vault`.
+///
+/// # Errors
+///
+/// Returns an error if the file can't be written.
+pub fn save_local_pins(
+    path: &Path,
+    pins: &[LocalPin],
+    vault: Option<&Vault>,
+    encrypted_chats: &[i64],
+) -> io::Result<()> {
+    let mut pins = pins.to_vec();
+    for p in &mut pins {
+        let encrypted = encrypted_chats.contains(&p.chat_id);
+        seal_entry(
+            &mut p.sealed,
+            &mut p.sender,
+            &mut p.excerpt,
+            encrypted,
+            vault,
+        )?;
+    }
+    write_json(path, &pins)
+}
+
+/// Returns where the reactions sent and pinned are kept.
+#[must_use]
+pub fn reactions_file(config: &Config) -> PathBuf {
+    config.telegram.session_file.with_file_name(REACTIONS_FILE)
+}
+
+/// Returns the reactions sent and pinned. A missing or unreadable file
+/// means there are none.
+#[must_use]
+pub fn load_reactions(path: &Path) -> ReactionHistory {
+    fs::read_to_string(path)
+        .ok()
+        .and_then(|json| serde_json::from_str(&json).ok())
+        .unwrap_or_default()
+}
+
+/// Saves the reactions sent and pinned.
+///
+/// # Errors
+///
+/// Returns an error if the file can't be written.
+pub fn save_reactions(path: &Path, reactions: &ReactionHistory) -> io::Result<()> {
+    write_json(path, reactions)
+}
+
+/// Returns where the recent forward destinations are kept.
+#[must_use]
+pub fn forward_targets_file(config: &Config) -> PathBuf {
+    config
+        .telegram
+        .session_file
+        .with_file_name(FORWARD_TARGETS_FILE)
+}
+
+/// Returns the recent forward destinations, most recent first. A missing or
+/// unreadable file means there are none.
+#[must_use]
+pub fn load_forward_targets(path: &Path) -> Vec<i64> {
+    fs::read_to_string(path)
+        .ok()
+        .and_then(|json| serde_json::from_str(&json).ok())
+        .unwrap_or_default()
+}
+
+/// Moves `ids` to the front of the recent forward destinations kept at
+/// `path`.
+///
+/// Duplicates are removed and the list is capped at
+/// [`MAX_FORWARD_TARGETS`] entries.
+///
+/// # Errors
+///
+/// Returns an error if the file can't be written.
+pub fn remember_forward_targets(path: &Path, ids: &[i64]) -> io::Result<()> {
+    let mut targets: Vec<i64> = Vec::with_capacity(MAX_FORWARD_TARGETS);
+    for id in ids.iter().copied().chain(load_forward_targets(path)) {
+        if !targets.contains(&id) {
+            targets.push(id);
+        }
+    }
+    targets.truncate(MAX_FORWARD_TARGETS);
+    write_json(path, &targets)
+}
+
+/// Returns where the aliases are kept.
+#[must_use]
+pub fn aliases_file(config: &Config) -> PathBuf {
+    config.telegram.session_file.with_file_name(ALIASES_FILE)
+}
+
+/// Returns the aliases. A missing or unreadable file means there are none.
+#[must_use]
+pub fn load_aliases(path: &Path) -> Aliases {
+    fs::read_to_string(path)
+        .ok()
+        .and_then(|json| serde_json::from_str(&json).ok())
+        .unwrap_or_default()
+}
+
+/// Saves the aliases.
+///
+/// # Errors
+///
+/// Returns an error if the file can't be written.
+pub fn save_aliases(path: &Path, aliases: &Aliases) -> io::Result<()> {
+    write_json(path, aliases)
+}
+
+/// Replaces a sealed sender and excerpt with what `vault` opens them to,
+/// or with a placeholder while they can't be opened.
+fn open_entry(
+    sealed: &mut String,
+    sender: &mut String,
+    excerpt: &mut String,
+    vault: Option<&Vault>,
+) {
+    if sealed.is_empty() {
+        return;
+    }
+    let opened = vault
+        .and_then(|v| v.open_text(sealed))
+        .and_then(|json| serde_json::from_str::<(String, String)>(&json).ok());
+    if let Some((s, e)) = opened {
+        *sender = s;
+        *excerpt = e;
+        sealed.clear();
+    } else {
+        sender.clear();
+        *excerpt = SEALED_EXCERPT.to_string();
+    }
+}
+
+/// Prepares an entry for writing: the sender and excerpt of an encrypted
+/// chat are sealed with `vault`, or left out without one, and an entry
+/// that couldn't be opened keeps its sealed form.
+fn seal_entry(
+    sealed: &mut String,
+    sender: &mut String,
+    excerpt: &mut String,
+    encrypted: bool,
+    vault: Option<&Vault>,
+) -> io::Result<()> {
+    if sealed.is_empty() && encrypted {
+        if let Some(vault) = vault {
+            let json = serde_json::to_string(&(&*sender, &*excerpt))?;
+            *sealed = vault.seal_text(&json).map_err(io::Error::other)?;
+        }
+    }
+    if !sealed.is_empty() || encrypted {
+        sender.clear();
+        excerpt.clear();
+    }
+    Ok(())
+}
+
+fn write_json<T: Serialize + ?Sized>(path: &Path, value: &T) -> io::Result<()> {
+    if let Some(parent) = path.parent() {
+        fs::create_dir_all(parent)?;
+    }
+    let json = serde_json::to_string_pretty(value)?;
+    fs::write(path, json)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn remembers_the_last_chat() {
+        let base = std::env::temp_dir().join(format!("ithil_state_test_{}", std::process::id()));
+        let path = base.join(LAST_CHAT_FILE);
+
+        assert_eq!(load_last_chat(&path), None);
+        save_last_chat(&path, -1_001_234).unwrap();
+        assert_eq!(load_last_chat(&path), Some(-1_001_234));
+        save_last_chat(&path, 42).unwrap();
+        assert_eq!(load_last_chat(&path), Some(42));
+
+        fs::write(&path, "not a chat").unwrap();
+        assert_eq!(load_last_chat(&path), None);
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+
+    #[test]
+    fn keeps_bookmarks() {
+        let base =
+            std::env::temp_dir().join(format!("ithil_bookmarks_test_{}", std::process::id()));
+        let path = base.join(BOOKMARKS_FILE);
+        assert_eq!(load_bookmarks(&path, None), Vec::new());
+
+        let bookmark = Bookmark {
+            chat_id: -1_001_234,
+            message_id: 77,
+            chat: "Ops".to_string(),
+            sender: "Alice".to_string(),
+            excerpt: "The runbook is here".to_string(),
+            date: Utc::now(),
+            sealed: String::new(),
+        };
+        save_bookmarks(&path, &[bookmark.clone()], None, &[]).unwrap();
+        assert_eq!(load_bookmarks(&path, None), vec![bookmark]);
+
+        fs::write(&path, "not json").unwrap();
+        assert_eq!(load_bookmarks(&path, None), Vec::new());
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+
+    #[test]
+    fn keeps_local_pins() {
+        let base =
+            std::env::temp_dir().join(format!("ithil_local_pins_test_{}", std::process::id()));
+        let path = base.join(LOCAL_PINS_FILE);
+        assert_eq!(load_local_pins(&path, None), Vec::new());
+
+        let pin = LocalPin {
+            chat_id: -1_001_234,
+            message_id: 77,
+            sender: "Ops Bot".to_string(),
+            excerpt: "Deploy window is Tuesday".to_string(),
+            date: Utc::now(),
+            sealed: String::new(),
+        };
+        save_local_pins(&path, &[pin.clone()], None, &[]).unwrap();
+        assert_eq!(load_local_pins(&path, None), vec![pin]);
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+
+    #[test]
+    fn quick_reactions_put_favorites_before_the_most_used() {
+        let mut reactions = ReactionHistory::default();
+        for reaction in ["a", "b", "b", "c", "c", "c"] {
+            reactions.remember(reaction);
+        }
+        assert_eq!(reactions.quick(), vec!["c", "b", "a"]);
+
+        assert!(reactions.toggle_favorite("a"));
+        assert!(reactions.toggle_favorite("z"));
+        assert_eq!(reactions.quick(), vec!["a", "z", "c", "b"]);
+
+        assert!(!reactions.toggle_favorite("a"));
+        assert_eq!(reactions.quick(), vec!["z", "c", "b", "a"]);
+
+        for i in 0..20 {
+            reactions.remember(&i.to_string());
+        }
+        assert_eq!(reactions.quick().len(), QUICK_REACTIONS);
+    }
+
+    #[test]
+    fn keeps_reactions() {
+        let base =
+            std::env::temp_dir().join(format!("ithil_reactions_test_{}", std::process::id()));
+        let path = base.join(REACTIONS_FILE);
+        assert_eq!(load_reactions(&path), ReactionHistory::default());
+
+        let mut reactions = ReactionHistory::default();
+        reactions.remember("\u{1f44d}");
+        reactions.toggle_favorite("\u{1f525}");
+        save_reactions(&path, &reactions).unwrap();
+        assert_eq!(load_reactions(&path), reactions);
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+
+    #[test]
+    fn forward_targets_are_recent_first_and_capped() {
+        let base = std::env::temp_dir().join(format!("ithil_forward_test_{}", std::process::id()));
+        let path = base.join(FORWARD_TARGETS_FILE);
+        assert!(load_forward_targets(&path).is_empty());
+
+        remember_forward_targets(&path, &[1, 2]).unwrap();
+        remember_forward_targets(&path, &[3, 1, 3]).unwrap();
+        assert_eq!(load_forward_targets(&path), vec![3, 1, 2]);
+
+        let many: Vec<i64> = (10..30).collect();
+        remember_forward_targets(&path, &many).unwrap();
+        let targets = load_forward_targets(&path);
+        assert_eq!(targets.len(), MAX_FORWARD_TARGETS);
+        assert_eq!(targets[0], 10);
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+
+    #[test]
+    fn alias_set_and_clear() {
+        let mut aliases = Aliases::default();
+        assert!(aliases.get(42).is_none());
+
+        aliases.set(42, "  Mom ");
+        assert_eq!(aliases.get(42), Some("Mom"));
+
+        aliases.set(42, "");
+        assert!(aliases.get(42).is_none());
+        assert!(aliases.as_map().is_empty());
+    }
+
+    #[test]
+    fn keeps_aliases() {
+        let base = std::env::temp_dir().join(format!("ithil_aliases_test_{}", std::process::id()));
+        let path = base.join(ALIASES_FILE);
+        assert_eq!(load_aliases(&path), Aliases::default());
+
+        let mut aliases = Aliases::default();
+        aliases.set(-100_123, "Work");
+        aliases.set(42, "Mom");
+        save_aliases(&path, &aliases).unwrap();
+        assert_eq!(load_aliases(&path), aliases);
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+
+    #[test]
+    fn encrypted_chats_are_stored_sealed() {
+        let base =
+            std::env::temp_dir().join(format!("ithil_sealed_state_test_{}", std::process::id()));
+        let path = base.join(LOCAL_PINS_FILE);
+        let vault = Vault::derive("hunter2", "hash");
+        let date = Utc::now();
+        let pin = |chat_id| LocalPin {
+            chat_id,
+            message_id: 5,
+            sender: "Alice".to_string(),
+            excerpt: "The door code is 4711".to_string(),
+            date,
+            sealed: String::new(),
+        };
+
+        save_local_pins(&path, &[pin(1), pin(2)], Some(&vault), &[2]).unwrap();
+        let raw = fs::read_to_string(&path).unwrap();
+        assert_eq!(raw.matches("4711").count(), 1);
+        assert_eq!(load_local_pins(&path, Some(&vault)), vec![pin(1), pin(2)]);
+
+        // Without the key, or with another, the sealed pin is a placeholder
+        // that keeps its sealed form when saved again
+        let other = Vault::derive("hunter3", "hash");
+        for key in [None, Some(&other)] {
+            let pins = load_local_pins(&path, key);
+            assert_eq!(pins[1].excerpt, SEALED_EXCERPT);
+            assert!(pins[1].sender.is_empty());
+            save_local_pins(&path, &pins, key, &[2]).unwrap();
+        }
+        assert_eq!(load_local_pins(&path, Some(&vault)), vec![pin(1), pin(2)]);
+
+        // Without the key, a new pin from an encrypted chat is left blank
+        save_local_pins(&path, &[pin(2)], None, &[2]).unwrap();
+        assert!(!fs::read_to_string(&path).unwrap().contains("4711"));
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+}
@@ -0,0 +1,145 @@
+//! Ithil's local data, how much disk it takes, and clearing it (`/storage`).
+//!
+//! Nearly everything here can be rebuilt: media is downloaded again when
+//! opened, exports can be redone, and a removed session only means logging
+//! in again. Bookmarks and local pins are the exception; they are kept on
+//! this device only.
+
+use std::fmt;
+use std::fs;
+use std::io;
+use std::path::{Path, PathBuf};
+
+use super::paths;
+
+/// A kind of local data that can be cleared on its own.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]
+pub enum LocalData {
+    /// The login session; clearing it signs this device out
+    Session,
+    /// Messages held in memory
+    Messages,
+    /// Downloaded media files
+    Media,
+    /// Conversations saved with `/export`
+    Exports,
+    /// Bookmarks, local pins, aliases, the last open chat and the reactions
+    /// used
+    Saved,
+}
+
+impl LocalData {
+    /// Every kind, in display order.
+    pub const ALL: [Self; 5] = [
+        Self::Session,
+        Self::Messages,
+        Self::Media,
+        Self::Exports,
+        Self::Saved,
+    ];
+
+    /// What clearing it means for the user.
+    #[must_use]
+    pub const fn description(self) -> &'static str {
+        match self {
+            Self::Session => "You'll have to log in again next time",
+            Self::Messages => "Reloaded from Telegram when chats are opened",
+            Self::Media => "Downloaded again when opened",
+            Self::Exports => "Files saved with /export",
+            Self::Saved => "Kept only here; gone for good once cleared",
+        }
+    }
+}
+
+impl fmt::Display for LocalData {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Session => write!(f, "Session"),
+            Self::Messages => write!(f, "Message cache"),
+            Self::Media => write!(f, "Media"),
+            Self::Exports => write!(f, "Exports"),
+            Self::Saved => write!(f, "Bookmarks, pins and aliases"),
+        }
+    }
+}
+
+/// Returns the size in bytes of a file, or of everything under a directory
+/// (0 if it doesn't exist).
+#[must_use]
+pub fn disk_usage(path: &Path) -> u64 {
+    let Ok(meta) = fs::symlink_metadata(path) else {
+        return 0;
+    };
+    if !meta.is_dir() {
+        return meta.len();
+    }
+    fs::read_dir(path).map_or(0, |entries| {
+        entries
+            .filter_map(Result::ok)
+            .map(|entry| disk_usage(&entry.path()))
+            .sum()
+    })
+}
+
+/// Returns the session file and the files SQLite keeps next to it: its
+/// write-ahead log and shared-memory index, in that order.
+#[must_use]
+pub fn session_files(session_file: &Path) -> [PathBuf; 3] {
+    paths::SESSION_SIDECARS.map(|suffix| {
+        let mut name = session_file.as_os_str().to_owned();
+        name.push(suffix);
+        PathBuf::from(name)
+    })
+}
+
+/// Deletes `paths`, files or directories, skipping those that don't exist.
+/// Returns the bytes freed.
+///
+/// # Errors
+///
+/// Returns the first error met; paths before it are already deleted.
+pub fn remove_all(paths: &[PathBuf]) -> io::Result<u64> {
+    let mut freed = 0;
+    for path in paths {
+        let Ok(meta) = fs::symlink_metadata(path) else {
+            continue;
+        };
+        let size = disk_usage(path);
+        if meta.is_dir() {
+            fs::remove_dir_all(path)?;
+        } else {
+            fs::remove_file(path)?;
+        }
+        freed += size;
+    }
+    Ok(freed)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn measures_and_removes_files_and_directories() {
+        let base = std::env::temp_dir().join(format!("ithil_storage_test_{}", std::process::id()));
+        let media = base.join("media");
+        fs::create_dir_all(media.join("chat")).unwrap();
+        fs::write(media.join("a.jpg"), [0; 100]).unwrap();
+        fs::write(media.join("chat").join("b.mp4"), [0; 50]).unwrap();
+        let session = base.join("ithil.session");
+        fs::write(&session, [0; 10]).unwrap();
+
+        assert_eq!(disk_usage(&media), 150);
+        assert_eq!(disk_usage(&base.join("missing")), 0);
+
+        let files = session_files(&session);
+        assert_eq!(files[1], base.join("ithil.session-wal"));
+        assert_eq!(remove_all(&files).unwrap(), 10);
+        assert!(!session.exists());
+
+        assert_eq!(remove_all(&[media.clone()]).unwrap(), 150);
+        assert!(!media.exists());
+
+        fs::remove_dir_all(&base).unwrap();
+    }
+}
@@ -0,0 +1,357 @@
+//! Encrypting what Ithil keeps on disk about the chats marked sensitive
+//! with `/encrypt`.
+//!
+//! The key is derived from the lock-screen passphrase each time the screen
+//! is unlocked and only ever held in memory, so Ithil starts locked while
+//! any chat is marked. The sender and excerpt of their bookmarks and
+//! local pins are stored sealed (see [`super::state`]). Their media is
+//! downloaded into a scratch directory made fresh for them under the
+//! runtime directory, since viewers need a plain file, and a sealed copy
+//! is kept in the media cache to restore from next time. If no such
+//! directory can be made, nothing is decrypted. The scratch directory is
+//! removed on locking and on quitting.
+//!
+//! Sealed data layout: `MAGIC`, a version byte, the IV, then the data
+//! encrypted as described in [`super::crypto`], with the header
+//! authenticated too.
+
+use std::fs;
+use std::path::{Path, PathBuf};
+use std::sync::{Mutex, PoisonError};
+
+use anyhow::{bail, Context, Result};
+
+use super::crypto::{self, Cipher, IV_LEN};
+use super::session::write_private;
+
+/// Marks the start of sealed data.
+const MAGIC: &[u8; 8] = b"ITHILVLT";
+
+/// Sealed data format version.
+const VERSION: u8 = 1;
+
+/// PBKDF2 iterations, as for the lock-screen passphrase hash.
+const ITERATIONS: u32 = 100_000;
+
+/// Prefixed to the salt, so the key never equals the stored passphrase
+/// hash.
+const SALT_PREFIX: &[u8] = b"ithil-vault:";
+
+/// Length of everything before the ciphertext.
+const HEADER_LEN: usize = MAGIC.len() + 1 + IV_LEN;
+
+/// Directory in the media cache holding sealed copies of media.
+const SEALED_MEDIA_DIR: &str = "sealed";
+
+/// Extension of a sealed media file.
+const SEALED_EXTENSION: &str = "sealed";
+
+/// The key for encrypted chats' local data.
+#[derive(Debug)]
+pub struct Vault {
+    cipher: Cipher,
+}
+
+impl Vault {
+    /// Derives the key from the lock-screen passphrase, salted with its
+    /// stored hash. That hash has a random salt of its own, so each
+    /// passphrase set gets a new key without another salt to keep.
+    #[must_use]
+    pub fn derive(passphrase: &str, passphrase_hash: &str) -> Self {
+        let salt = [SALT_PREFIX, passphrase_hash.as_bytes()].concat();
+        Self {
+            cipher: Cipher::derive(passphrase, &salt, ITERATIONS),
+        }
+    }
+
+    /// Encrypts `plain`.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if no random IV can be had.
+    pub fn seal(&self, plain: &[u8]) -> Result<Vec<u8>> {
+        let iv: [u8; IV_LEN] = crypto::random()?;
+        let mut out = Vec::with_capacity(HEADER_LEN + plain.len() + crypto::TAG_LEN);
+        out.extend_from_slice(MAGIC);
+        out.push(VERSION);
+        out.extend_from_slice(&iv);
+        let sealed = self.cipher.encrypt(&iv, &out, plain);
+        out.extend_from_slice(&sealed);
+        Ok(out)
+    }
+
+    /// Decrypts data sealed with [`Vault::seal`].
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the data isn't sealed, was sealed with another
+    /// key (an earlier passphrase), or was tampered with.
+    pub fn open(&self, data: &[u8]) -> Result<Vec<u8>> {
+        if data.len() < HEADER_LEN || !data.starts_with(MAGIC) {
+            bail!("Not sealed data");
+        }
+        let (header, ciphertext) = data.split_at(HEADER_LEN);
+        let version = header[MAGIC.len()];
+        if version != VERSION {
+            bail!("Unsupported sealed data version {version}");
+        }
+        let mut iv = [0u8; IV_LEN];
+        iv.copy_from_slice(&header[MAGIC.len() + 1..]);
+        self.cipher
+            .decrypt(&iv, header, ciphertext)
+            .context("Sealed with another passphrase, or damaged")
+    }
+
+    /// Encrypts `text` into hex, for storing in a text file.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if no random IV can be had.
+    pub fn seal_text(&self, text: &str) -> Result<String> {
+        self.seal(text.as_bytes())
+            .map(|sealed| crate::utils::to_hex(&sealed))
+    }
+
+    /// Decrypts text sealed with [`Vault::seal_text`], or returns `None` if
+    /// it can't be.
+    #[must_use]
+    pub fn open_text(&self, hex: &str) -> Option<String> {
+        let sealed = crate::utils::from_hex(hex)?;
+        String::from_utf8(self.open(&sealed).ok()?).ok()
+    }
+
+    /// Keeps a sealed copy of a message's downloaded media in the media
+    /// cache, under the file's own name.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the file can't be read or the copy written.
+    pub fn seal_media(
+        &self,
+        media_dir: &Path,
+        chat_id: i64,
+        message_id: i64,
+        file: &Path,
+    ) -> Result<()> {
+        let name = file
+            .file_name()
+            .with_context(|| format!("Not a file: {}", file.display()))?;
+        let plain = fs::read(file).with_context(|| format!("Failed to read {}", file.display()))?;
+        let mut sealed_name = name.to_os_string();
+        sealed_name.push(format!(".{SEALED_EXTENSION}"));
+        let dir = sealed_media_dir(media_dir, chat_id, message_id);
+        write_private(&dir.join(sealed_name), &self.seal(&plain)?)
+    }
+
+    /// Decrypts a message's sealed media into `scratch`, returning where
+    /// it is, or `None` if there's no sealed copy this key opens.
+    #[must_use]
+    pub fn restore_media(
+        &self,
+        media_dir: &Path,
+        chat_id: i64,
+        message_id: i64,
+        scratch: &Path,
+    ) -> Option<PathBuf> {
+        let sealed = fs::read_dir(sealed_media_dir(media_dir, chat_id, message_id))
+            .ok()?
+            .flatten()
+            .map(|entry| entry.path())
+            .find(|path| path.extension().map_or(false, |e| e == SEALED_EXTENSION))?;
+        let plain = self.open(&fs::read(&sealed).ok()?).ok()?;
+        let out = scratch.join(sealed.file_stem()?);
+        write_private(&out, &plain).ok()?;
+        Some(out)
+    }
+
+    /// Seals the media downloaded from a chat into the media cache before
+    /// it was encrypted, removing the plain files. Returns how many were
+    /// sealed.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if a file can't be sealed or removed; those
+    /// sealed before it stay sealed.
+    pub fn seal_chat_media(&self, media_dir: &Path, chat_id: i64) -> Result<usize> {
+        let Ok(entries) = fs::read_dir(media_dir) else {
+            return Ok(0);
+        };
+        let mut sealed = 0;
+        for path in entries.flatten().map(|entry| entry.path()) {
+            let owner = path
+                .file_name()
+                .and_then(|n| n.to_str())
+                .filter(|n| !n.ends_with(".part"))
+                .and_then(media_owner);
+            let Some((chat, message_id)) = owner else {
+                continue;
+            };
+            if chat != chat_id || !path.is_file() {
+                continue;
+            }
+            self.seal_media(media_dir, chat_id, message_id, &path)?;
+            fs::remove_file(&path)
+                .with_context(|| format!("Failed to remove {}", path.display()))?;
+            sealed += 1;
+        }
+        Ok(sealed)
+    }
+}
+
+/// Returns the chat and message a file in the media cache came from, going
+/// by the names downloads are given: `photo_<chat>_<msg>.jpg`,
+/// `<chat>_<msg>_<name>` and the like.
+fn media_owner(name: &str) -> Option<(i64, i64)> {
+    let rest = ["photo_", "media_", "file_"]
+        .iter()
+        .find_map(|prefix| name.strip_prefix(prefix))
+        .unwrap_or(name);
+    let (chat, rest) = rest.split_once('_')?;
+    let end = rest
+        .find(|c: char| !c.is_ascii_digit())
+        .unwrap_or(rest.len());
+    Some((chat.parse().ok()?, rest[..end].parse().ok()?))
+}
+
+/// Returns where a message's sealed media is kept.
+#[must_use]
+pub fn sealed_media_dir(media_dir: &Path, chat_id: i64, message_id: i64) -> PathBuf {
+    media_dir
+        .join(SEALED_MEDIA_DIR)
+        .join(format!("{chat_id}_{message_id}"))
+}
+
+/// The scratch directory made for this process, if one has been.
+static SCRATCH_DIR: Mutex<Option<PathBuf>> = Mutex::new(None);
+
+/// Returns the scratch directory encrypted chats' media is downloaded to
+/// and decrypted into. It is made fresh under a random name, readable only
+/// by the current user, so nothing anyone else prepared can be used in its
+/// place. It is removed with [`clear_scratch_dir`] on locking and quitting.
+///
+/// # Errors
+///
+/// Returns an error if there is no private runtime directory to make it in,
+/// or it can't be made; nothing should then be decrypted.
+pub fn scratch_dir() -> std::io::Result<PathBuf> {
+    let mut scratch = SCRATCH_DIR.lock().unwrap_or_else(PoisonError::into_inner);
+    let made = scratch
+        .as_ref()
+        .filter(|dir| fs::symlink_metadata(dir).is_ok_and(|meta| meta.file_type().is_dir()));
+    if let Some(dir) = made {
+        return Ok(dir.clone());
+    }
+    let dir = super::paths::create_private_dir(&super::paths::runtime_dir()?, "ithil-media")?;
+    *scratch = Some(dir.clone());
+    Ok(dir)
+}
+
+/// Removes the scratch directory and everything decrypted into it.
+pub fn clear_scratch_dir() {
+    let made = SCRATCH_DIR
+        .lock()
+        .unwrap_or_else(PoisonError::into_inner)
+        .take();
+    if let Some(dir) = made {
+        if let Err(e) = fs::remove_dir_all(&dir) {
+            tracing::warn!("Failed to clear {}: {}", dir.display(), e);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn opens_only_what_the_same_passphrase_sealed() {
+        let vault = Vault::derive("hunter2", "hash");
+        let sealed = vault.seal(b"meet at noon").unwrap();
+        assert!(!sealed.windows(b"noon".len()).any(|w| w == b"noon"));
+        assert_eq!(vault.open(&sealed).unwrap(), b"meet at noon");
+
+        assert!(Vault::derive("hunter3", "hash").open(&sealed).is_err());
+        assert!(Vault::derive("hunter2", "other hash")
+            .open(&sealed)
+            .is_err());
+        let mut tampered = sealed.clone();
+        *tampered.last_mut().unwrap() ^= 1;
+        assert!(vault.open(&tampered).is_err());
+        assert!(vault.open(b"meet at noon").is_err());
+
+        let text = vault.seal_text("Alice: the code is 1234").unwrap();
+        assert_eq!(
+            vault.open_text(&text).as_deref(),
+            Some("Alice: the code is 1234")
+        );
+        assert_eq!(vault.open_text("not hex"), None);
+    }
+
+    #[test]
+    fn media_is_sealed_in_the_cache_and_restored_to_scratch() {
+        let base = std::env::temp_dir().join(format!("ithil_vault_test_{}", std::process::id()));
+        let media = base.join("media");
+        let scratch = base.join("scratch");
+        fs::create_dir_all(&scratch).unwrap();
+        let photo = scratch.join("photo_7_9.jpg");
+        fs::write(&photo, b"jpeg bytes").unwrap();
+
+        let vault = Vault::derive("hunter2", "hash");
+        vault.seal_media(&media, 7, 9, &photo).unwrap();
+        let sealed = sealed_media_dir(&media, 7, 9).join("photo_7_9.jpg.sealed");
+        assert_ne!(fs::read(&sealed).unwrap(), b"jpeg bytes");
+
+        fs::remove_file(&photo).unwrap();
+        assert_eq!(
+            vault.restore_media(&media, 7, 9, &scratch),
+            Some(photo.clone())
+        );
+        assert_eq!(fs::read(&photo).unwrap(), b"jpeg bytes");
+
+        // Another key, or another message, finds nothing
+        let other = Vault::derive("hunter3", "hash");
+        assert_eq!(other.restore_media(&media, 7, 9, &scratch), None);
+        assert_eq!(vault.restore_media(&media, 7, 10, &scratch), None);
+        fs::remove_dir_all(&base).unwrap();
+    }
+
+    #[test]
+    fn the_scratch_directory_is_made_fresh_after_clearing() {
+        let first = scratch_dir().unwrap();
+        assert_eq!(scratch_dir().unwrap(), first);
+        clear_scratch_dir();
+        assert!(!first.exists());
+
+        // A directory put back at the old name is never used
+        fs::create_dir_all(&first).unwrap();
+        let second = scratch_dir().unwrap();
+        assert_ne!(second, first);
+        clear_scratch_dir();
+        fs::remove_dir_all(&first).unwrap();
+    }
+
+    #[test]
+    fn a_chats_cached_media_is_sealed_when_it_is_encrypted() {
+        assert_eq!(media_owner("photo_-1001_42.jpg"), Some((-1001, 42)));
+        assert_eq!(media_owner("7_9_report.pdf"), Some((7, 9)));
+        assert_eq!(media_owner("file_7_10.ogg"), Some((7, 10)));
+        assert_eq!(media_owner("notes.txt"), None);
+
+        let media =
+            std::env::temp_dir().join(format!("ithil_vault_media_test_{}", std::process::id()));
+        fs::create_dir_all(&media).unwrap();
+        for name in ["photo_7_9.jpg", "7_10_report.pdf", "photo_8_9.jpg"] {
+            fs::write(media.join(name), name).unwrap();
+        }
+
+        let vault = Vault::derive("hunter2", "hash");
+        assert_eq!(vault.seal_chat_media(&media, 7).unwrap(), 2);
+        assert!(!media.join("photo_7_9.jpg").exists());
+        assert!(!media.join("7_10_report.pdf").exists());
+        assert!(media.join("photo_8_9.jpg").exists());
+        assert!(sealed_media_dir(&media, 7, 10)
+            .join("7_10_report.pdf.sealed")
+            .is_file());
+        fs::remove_dir_all(&media).unwrap();
+    }
+}
@@ -7,17 +7,65 @@
 //!
 //! - Uses `RwLock` to allow multiple concurrent readers
 //! - Messages are stored per-chat with a configurable limit (FIFO eviction)
+//! - An optional memory budget drops whole histories of the chats viewed
+//!   least recently once cached messages outgrow it; the chats themselves
+//!   stay, so the chat list is unaffected
 //! - All operations return cloned data to avoid lock contention
 //! - Provides a `SharedCache` type alias for `Arc<Cache>` convenience
+//! - Tracks group participants with a TTL so unknown senders are looked up
+//!   once and refreshed occasionally, not on every message
+//! - Keeps sent messages under a local ID until Telegram confirms them,
+//!   then swaps in the server's copy (see [`Cache::begin_send`])
 
 // The significant_drop_tightening lint gives false positives for our use case
 // where we need to hold the lock for the entire operation duration.
 #![allow(clippy::significant_drop_tightening)]
 
 use std::collections::HashMap;
+use std::sync::atomic::{AtomicI64, AtomicU64, AtomicUsize, Ordering};
 use std::sync::{Arc, RwLock};
+use std::time::{Duration, Instant};
 
-use crate::types::{Chat, Message, User};
+use crate::types::{Chat, ChatType, Message, User};
+
+/// How long a group participant's user info is trusted before it is
+/// looked up again.
+pub const PARTICIPANTS_TTL: Duration = Duration::from_secs(30 * 60);
+
+/// Rough number of bytes a cached message takes up: the struct itself
+/// plus its text.
+fn message_size(message: &Message) -> usize {
+    std::mem::size_of::<Message>() + message.content.text.len() + message.content.caption.len()
+}
+
+/// Counters describing what the cache holds and has dropped.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub struct CacheMetrics {
+    /// Chats cached
+    pub chats: usize,
+    /// Chats with a cached message history
+    pub histories: usize,
+    /// Messages cached across all chats
+    pub messages: usize,
+    /// Rough size of the cached messages in bytes
+    pub message_bytes: usize,
+    /// Memory budget for messages in bytes (0 for none)
+    pub budget_bytes: usize,
+    /// Histories dropped to stay within the budget
+    pub evicted_histories: u64,
+    /// Old messages dropped to stay within the per-chat limit
+    pub trimmed_messages: u64,
+}
+
+/// A sent message Telegram hasn't confirmed yet.
+#[derive(Debug, Clone, Copy)]
+struct PendingSend {
+    chat_id: i64,
+    /// ID the optimistic copy is cached under
+    local_id: i64,
+    /// ID Telegram gave the message, once known
+    server_id: Option<i64>,
+}
 
 /// A thread-safe cache for storing Telegram data.
 ///
@@ -55,8 +103,23 @@ pub struct Cache {
     messages: RwLock<HashMap<i64, Vec<Message>>>,
     /// User storage: `user_id` -> `User`
     users: RwLock<HashMap<i64, User>>,
+    /// Group participants: `chat_id` -> `user_id` -> when last looked up
+    participants: RwLock<HashMap<i64, HashMap<i64, Instant>>>,
+    /// Unconfirmed sends: `random_id` -> where the optimistic copy is
+    pending_sends: RwLock<HashMap<i64, PendingSend>>,
+    /// Next local ID for an optimistic copy
+    next_local_id: AtomicI64,
+    /// When each chat was last viewed, as a tick of `view_clock`
+    last_viewed: RwLock<HashMap<i64, u64>>,
+    view_clock: AtomicU64,
+    /// Rough size of all cached messages, kept in step with `messages`
+    message_bytes: AtomicUsize,
+    evicted_histories: AtomicU64,
+    trimmed_messages: AtomicU64,
     /// Maximum number of messages to store per chat
     max_messages_per_chat: usize,
+    /// Memory budget for messages in bytes (0 for none)
+    memory_budget: usize,
 }
 
 impl Cache {
@@ -80,10 +143,38 @@ impl Cache {
             chats: RwLock::new(HashMap::new()),
             messages: RwLock::new(HashMap::new()),
             users: RwLock::new(HashMap::new()),
+            participants: RwLock::new(HashMap::new()),
+            pending_sends: RwLock::new(HashMap::new()),
+            next_local_id: AtomicI64::new(Message::FIRST_LOCAL_ID),
+            last_viewed: RwLock::new(HashMap::new()),
+            view_clock: AtomicU64::new(0),
+            message_bytes: AtomicUsize::new(0),
+            evicted_histories: AtomicU64::new(0),
+            trimmed_messages: AtomicU64::new(0),
             max_messages_per_chat,
+            memory_budget: 0,
         }
     }
 
+    /// Caps the rough size of cached messages at `bytes` (0 for no cap).
+    ///
+    /// Beyond it, the histories of the chats viewed least recently (see
+    /// [`mark_viewed`](Self::mark_viewed)) are dropped whole, never the one
+    /// just added to.
+    ///
+    /// # Examples
+    ///
+    /// ```
+    /// use ithil::cache::Cache;
+    ///
+    /// let cache = Cache::new(1000).with_memory_budget(64 * 1024 * 1024);
+    /// ```
+    #[must_use]
+    pub const fn with_memory_budget(mut self, bytes: usize) -> Self {
+        self.memory_budget = bytes;
+        self
+    }
+
     // ========================================================================
     // Chat Methods
     // ========================================================================
@@ -144,10 +235,20 @@ impl Cache {
     /// Panics if the internal lock is poisoned (another thread panicked while holding it).
     pub fn remove_chat(&self, id: i64) {
         self.chats.write().expect("chats lock poisoned").remove(&id);
-        self.messages
+        let removed = self
+            .messages
             .write()
             .expect("messages lock poisoned")
             .remove(&id);
+        self.forget_bytes(removed.iter().flatten());
+        self.last_viewed
+            .write()
+            .expect("last viewed lock poisoned")
+            .remove(&id);
+        self.participants
+            .write()
+            .expect("participants lock poisoned")
+            .remove(&id);
     }
 
     // ========================================================================
@@ -174,7 +275,8 @@ impl Cache {
 
     /// Adds a message to a chat's message list.
     ///
-    /// If the message limit is exceeded, the oldest messages are removed.
+    /// If the message limit is exceeded, the oldest messages are removed,
+    /// and if the memory budget is, other chats' histories are dropped.
     /// Messages are inserted in sorted order by ID (assumed to be chronological).
     ///
     /// # Panics
@@ -189,19 +291,78 @@ impl Cache {
             .binary_search_by_key(&message.id, |m| m.id)
             .unwrap_or_else(|pos| pos);
 
+        self.message_bytes
+            .fetch_add(message_size(&message), Ordering::Relaxed);
+
         // Check if message already exists
         if insert_pos < chat_messages.len() && chat_messages[insert_pos].id == message.id {
             // Update existing message
-            chat_messages[insert_pos] = message;
+            let old = std::mem::replace(&mut chat_messages[insert_pos], message);
+            self.forget_bytes(std::iter::once(&old));
         } else {
             // Insert new message
             chat_messages.insert(insert_pos, message);
 
             // Enforce message limit (remove from the beginning - oldest messages)
-            while chat_messages.len() > self.max_messages_per_chat {
-                chat_messages.remove(0);
+            let excess = chat_messages
+                .len()
+                .saturating_sub(self.max_messages_per_chat);
+            if excess > 0 {
+                let trimmed: Vec<Message> = chat_messages.drain(..excess).collect();
+                self.forget_bytes(&trimmed);
+                self.trimmed_messages
+                    .fetch_add(trimmed.len() as u64, Ordering::Relaxed);
             }
         }
+
+        self.evict_for_budget(&mut messages, chat_id);
+    }
+
+    /// Drops the histories of the chats viewed least recently until cached
+    /// messages fit the memory budget, sparing `keep`.
+    fn evict_for_budget(&self, messages: &mut HashMap<i64, Vec<Message>>, keep: i64) {
+        if self.memory_budget == 0 {
+            return;
+        }
+        let last_viewed = self.last_viewed.read().expect("last viewed lock poisoned");
+        while self.message_bytes.load(Ordering::Relaxed) > self.memory_budget {
+            // Chats never viewed (tick 0) go first
+            let Some(victim) = messages
+                .keys()
+                .filter(|&&id| id != keep)
+                .min_by_key(|id| last_viewed.get(id).copied().unwrap_or(0))
+                .copied()
+            else {
+                break;
+            };
+            self.forget_bytes(messages.remove(&victim).iter().flatten());
+            self.evicted_histories.fetch_add(1, Ordering::Relaxed);
+        }
+    }
+
+    /// Subtracts removed messages from the running size, stopping at zero
+    /// rather than wrapping if the count ever drifted low.
+    fn forget_bytes<'a>(&self, removed: impl IntoIterator<Item = &'a Message>) {
+        let bytes: usize = removed.into_iter().map(message_size).sum();
+        let _ = self
+            .message_bytes
+            .fetch_update(Ordering::Relaxed, Ordering::Relaxed, |total| {
+                Some(total.saturating_sub(bytes))
+            });
+    }
+
+    /// Records that a chat was just viewed, so its history is the last to
+    /// be dropped for the memory budget.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the internal lock is poisoned (another thread panicked while holding it).
+    pub fn mark_viewed(&self, chat_id: i64) {
+        let tick = self.view_clock.fetch_add(1, Ordering::Relaxed) + 1;
+        self.last_viewed
+            .write()
+            .expect("last viewed lock poisoned")
+            .insert(chat_id, tick);
     }
 
     /// Updates an existing message in the cache.
@@ -217,7 +378,11 @@ impl Cache {
 
         // Find and update the message
         if let Some(existing) = chat_messages.iter_mut().find(|m| m.id == message.id) {
-            *existing = message;
+            self.message_bytes
+                .fetch_add(message_size(&message), Ordering::Relaxed);
+            let old = std::mem::replace(existing, message);
+            self.forget_bytes(std::iter::once(&old));
+            self.evict_for_budget(&mut messages, chat_id);
         } else {
             // Message not found, add it
             drop(messages);
@@ -233,10 +398,41 @@ impl Cache {
     pub fn delete_message(&self, chat_id: i64, message_id: i64) {
         let mut messages = self.messages.write().expect("messages lock poisoned");
         if let Some(chat_messages) = messages.get_mut(&chat_id) {
-            chat_messages.retain(|m| m.id != message_id);
+            if let Ok(pos) = chat_messages.binary_search_by_key(&message_id, |m| m.id) {
+                let removed = chat_messages.remove(pos);
+                self.forget_bytes(std::iter::once(&removed));
+            }
         }
     }
 
+    /// Finds the private chat or basic group whose cached messages include
+    /// `message_id`.
+    ///
+    /// These chats share one account-wide message numbering, so deletions
+    /// made on other devices name only the message. Channels and
+    /// supergroups number their own messages and are skipped.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the internal lock is poisoned (another thread panicked while holding it).
+    #[must_use]
+    pub fn find_message_chat(&self, message_id: i64) -> Option<i64> {
+        let chats = self.chats.read().expect("chats lock poisoned");
+        let messages = self.messages.read().expect("messages lock poisoned");
+        messages
+            .iter()
+            .find(|(chat_id, chat_messages)| {
+                let own_numbering = chats.get(chat_id).is_some_and(|c| {
+                    matches!(c.chat_type, ChatType::Channel | ChatType::Supergroup)
+                });
+                !own_numbering
+                    && chat_messages
+                        .binary_search_by_key(&message_id, |m| m.id)
+                        .is_ok()
+            })
+            .map(|(chat_id, _)| *chat_id)
+    }
+
     /// Returns the number of cached messages for a chat.
     ///
     /// # Panics
@@ -309,10 +505,209 @@ impl Cache {
         self.users.write().expect("users lock poisoned").remove(&id);
     }
 
+    // ========================================================================
+    // Participant Methods
+    // ========================================================================
+
+    /// Returns which of a group's senders need looking up.
+    ///
+    /// A sender needs it if their user info isn't cached at all, or if it was
+    /// last looked up for this chat more than [`PARTICIPANTS_TTL`] before
+    /// `now`. Senders already cached but never seen in this chat are recorded
+    /// as fresh instead. The result is deduplicated.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the internal lock is poisoned (another thread panicked while holding it).
+    #[must_use]
+    pub fn participants_to_resolve(
+        &self,
+        chat_id: i64,
+        user_ids: impl IntoIterator<Item = i64>,
+        now: Instant,
+    ) -> Vec<i64> {
+        let users = self.users.read().expect("users lock poisoned");
+        let mut participants = self
+            .participants
+            .write()
+            .expect("participants lock poisoned");
+        let seen = participants.entry(chat_id).or_default();
+
+        let mut stale = Vec::new();
+        for user_id in user_ids {
+            if stale.contains(&user_id) {
+                continue;
+            }
+            let expired = match seen.get(&user_id) {
+                Some(at) => now.saturating_duration_since(*at) > PARTICIPANTS_TTL,
+                None if users.contains_key(&user_id) => {
+                    seen.insert(user_id, now);
+                    false
+                },
+                None => true,
+            };
+            if expired {
+                stale.push(user_id);
+            }
+        }
+        stale
+    }
+
+    /// Records that a group's participants were looked up at `now`.
+    ///
+    /// Callers mark participants before the lookup finishes so concurrent
+    /// loads don't ask for the same users twice; a failed lookup is retried
+    /// once the TTL runs out.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the internal lock is poisoned (another thread panicked while holding it).
+    pub fn mark_participants(&self, chat_id: i64, user_ids: &[i64], now: Instant) {
+        let mut participants = self
+            .participants
+            .write()
+            .expect("participants lock poisoned");
+        let seen = participants.entry(chat_id).or_default();
+        for &user_id in user_ids {
+            seen.insert(user_id, now);
+        }
+    }
+
+    /// Forgets that a group's participants were looked up, so the next
+    /// load asks for them again.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the internal lock is poisoned (another thread panicked while holding it).
+    pub fn forget_participants(&self, chat_id: i64, user_ids: &[i64]) {
+        let mut participants = self
+            .participants
+            .write()
+            .expect("participants lock poisoned");
+        if let Some(seen) = participants.get_mut(&chat_id) {
+            for user_id in user_ids {
+                seen.remove(user_id);
+            }
+        }
+    }
+
+    // ========================================================================
+    // Pending Send Methods
+    // ========================================================================
+
+    /// Caches an optimistic copy of a message being sent, keyed by the
+    /// `random_id` the send uses, and returns it.
+    ///
+    /// The copy gets a local ID (see [`Message::is_pending`]) until
+    /// [`settle_send`](Self::settle_send) swaps in the server's message.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the internal lock is poisoned (another thread panicked while holding it).
+    pub fn begin_send(&self, random_id: i64, mut message: Message) -> Message {
+        message.id = self.next_local_id.fetch_add(1, Ordering::Relaxed);
+        self.pending_sends
+            .write()
+            .expect("pending sends lock poisoned")
+            .insert(
+                random_id,
+                PendingSend {
+                    chat_id: message.chat_id,
+                    local_id: message.id,
+                    server_id: None,
+                },
+            );
+        self.add_message(message.chat_id, message.clone());
+        message
+    }
+
+    /// Records the ID Telegram gave the send with `random_id`, from its
+    /// reply or an `updateMessageID`.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the internal lock is poisoned (another thread panicked while holding it).
+    pub fn confirm_send(&self, random_id: i64, server_id: i64) {
+        if let Some(pending) = self
+            .pending_sends
+            .write()
+            .expect("pending sends lock poisoned")
+            .get_mut(&random_id)
+        {
+            pending.server_id = Some(server_id);
+        }
+    }
+
+    /// Caches the server's copy of a message and, if it confirms a pending
+    /// send, drops the optimistic copy.
+    ///
+    /// Returns the local ID that was replaced. The server's copy can arrive
+    /// from the send's reply or the update stream, in either order; only the
+    /// first settles the send, later ones just refresh the cached message.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the internal lock is poisoned (another thread panicked while holding it).
+    pub fn settle_send(&self, chat_id: i64, message: Message) -> Option<i64> {
+        let mut pending_sends = self
+            .pending_sends
+            .write()
+            .expect("pending sends lock poisoned");
+        let random_id = pending_sends
+            .iter()
+            .find(|(_, p)| p.chat_id == chat_id && p.server_id == Some(message.id))
+            .map(|(random_id, _)| *random_id);
+        let local_id = random_id
+            .and_then(|random_id| pending_sends.remove(&random_id))
+            .map(|p| p.local_id);
+        drop(pending_sends);
+
+        if let Some(local_id) = local_id {
+            self.delete_message(chat_id, local_id);
+        }
+        self.add_message(chat_id, message);
+        local_id
+    }
+
+    /// Forgets a send that failed, dropping its optimistic copy.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the internal lock is poisoned (another thread panicked while holding it).
+    pub fn abandon_send(&self, random_id: i64) {
+        let pending = self
+            .pending_sends
+            .write()
+            .expect("pending sends lock poisoned")
+            .remove(&random_id);
+        if let Some(pending) = pending {
+            self.delete_message(pending.chat_id, pending.local_id);
+        }
+    }
+
     // ========================================================================
     // General Methods
     // ========================================================================
 
+    /// Drops every stored message history, keeping chats and users, and
+    /// returns the bytes freed. Histories are loaded again when chats are
+    /// opened.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the internal lock is poisoned (another thread panicked while holding it).
+    pub fn clear_messages(&self) -> usize {
+        self.messages
+            .write()
+            .expect("messages lock poisoned")
+            .clear();
+        self.last_viewed
+            .write()
+            .expect("last viewed lock poisoned")
+            .clear();
+        self.message_bytes.swap(0, Ordering::Relaxed)
+    }
+
     /// Clears all data from the cache.
     ///
     /// # Panics
@@ -324,7 +719,20 @@ impl Cache {
             .write()
             .expect("messages lock poisoned")
             .clear();
+        self.message_bytes.store(0, Ordering::Relaxed);
+        self.last_viewed
+            .write()
+            .expect("last viewed lock poisoned")
+            .clear();
         self.users.write().expect("users lock poisoned").clear();
+        self.participants
+            .write()
+            .expect("participants lock poisoned")
+            .clear();
+        self.pending_sends
+            .write()
+            .expect("pending sends lock poisoned")
+            .clear();
     }
 
     /// Returns the total number of cached items (chats + users + messages).
@@ -364,6 +772,26 @@ impl Cache {
             .sum();
         (chats_count, users_count, messages_count)
     }
+
+    /// Returns what the cache holds and has dropped, for diagnostics.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the internal lock is poisoned (another thread panicked while holding it).
+    #[must_use]
+    pub fn metrics(&self) -> CacheMetrics {
+        let chats = self.chats.read().expect("chats lock poisoned").len();
+        let messages = self.messages.read().expect("messages lock poisoned");
+        CacheMetrics {
+            chats,
+            histories: messages.values().filter(|m| !m.is_empty()).count(),
+            messages: messages.values().map(Vec::len).sum(),
+            message_bytes: self.message_bytes.load(Ordering::Relaxed),
+            budget_bytes: self.memory_budget,
+            evicted_histories: self.evicted_histories.load(Ordering::Relaxed),
+            trimmed_messages: self.trimmed_messages.load(Ordering::Relaxed),
+        }
+    }
 }
 
 impl Default for Cache {
@@ -399,7 +827,7 @@ pub fn new_shared_cache(max_messages_per_chat: usize) -> SharedCache {
 #[cfg(test)]
 mod tests {
     use super::*;
-    use crate::types::{ChatType, MessageContent, MessageType};
+    use crate::types::{MessageContent, MessageType};
 
     fn create_test_user(id: i64, name: &str) -> User {
         User {
@@ -618,6 +1046,22 @@ mod tests {
             assert_eq!(cache.message_count(1), 1);
         }
 
+        #[test]
+        fn finds_chat_of_message_outside_channels() {
+            let cache = Cache::new(100);
+            cache.set_chat(create_test_chat(1, "Alice"));
+            cache.set_chat(Chat {
+                chat_type: ChatType::Supergroup,
+                ..create_test_chat(2, "Club")
+            });
+            cache.add_message(1, create_test_message(7, 1, "Hello"));
+            cache.add_message(2, create_test_message(9, 2, "Hi all"));
+
+            assert_eq!(cache.find_message_chat(7), Some(1));
+            // Supergroups number their own messages
+            assert_eq!(cache.find_message_chat(9), None);
+        }
+
         #[test]
         fn message_limit_enforcement() {
             let cache = Cache::new(3); // Limit to 3 messages
@@ -666,6 +1110,152 @@ mod tests {
         }
     }
 
+    mod participant_cache_tests {
+        use super::*;
+
+        #[test]
+        fn unknown_senders_need_resolving_once() {
+            let cache = Cache::new(100);
+            cache.set_user(create_test_user(1, "Alice"));
+            let now = Instant::now();
+
+            assert_eq!(
+                cache.participants_to_resolve(10, [1, 2, 3, 2], now),
+                vec![2, 3]
+            );
+
+            cache.mark_participants(10, &[2, 3], now);
+            assert!(cache.participants_to_resolve(10, [1, 2, 3], now).is_empty());
+
+            // A failed lookup is forgotten and asked for again
+            cache.forget_participants(10, &[3]);
+            assert_eq!(cache.participants_to_resolve(10, [1, 2, 3], now), vec![3]);
+        }
+
+        #[test]
+        fn participants_expire_after_ttl() {
+            let cache = Cache::new(100);
+            let start = Instant::now();
+            cache.set_user(create_test_user(1, "Alice"));
+            cache.mark_participants(10, &[1], start);
+
+            let later = start + PARTICIPANTS_TTL + Duration::from_secs(1);
+            assert_eq!(cache.participants_to_resolve(10, [1], later), vec![1]);
+            // Tracked per chat
+            assert!(cache.participants_to_resolve(20, [1], later).is_empty());
+        }
+    }
+
+    mod pending_send_tests {
+        use super::*;
+
+        #[test]
+        fn server_copy_replaces_the_optimistic_one() {
+            let cache = Cache::new(100);
+            let local = cache.begin_send(77, create_test_message(0, 1, "hi"));
+            assert!(local.is_pending());
+            assert_eq!(cache.get_messages(1).len(), 1);
+
+            cache.confirm_send(77, 500);
+            assert_eq!(
+                cache.settle_send(1, create_test_message(500, 1, "hi")),
+                Some(local.id)
+            );
+            let ids: Vec<i64> = cache.get_messages(1).iter().map(|m| m.id).collect();
+            assert_eq!(ids, vec![500]);
+
+            // The second copy (reply or update, whichever is later) settles nothing
+            assert_eq!(
+                cache.settle_send(1, create_test_message(500, 1, "hi")),
+                None
+            );
+            assert_eq!(cache.get_messages(1).len(), 1);
+        }
+
+        #[test]
+        fn failed_send_drops_the_optimistic_copy() {
+            let cache = Cache::new(100);
+            cache.add_message(1, create_test_message(10, 1, "before"));
+            cache.begin_send(77, create_test_message(0, 1, "lost"));
+            cache.abandon_send(77);
+
+            let ids: Vec<i64> = cache.get_messages(1).iter().map(|m| m.id).collect();
+            assert_eq!(ids, vec![10]);
+        }
+    }
+
+    mod memory_budget_tests {
+        use super::*;
+
+        #[test]
+        fn least_recently_viewed_history_goes_first() {
+            let one = message_size(&create_test_message(0, 0, "x"));
+            let cache = Cache::new(100).with_memory_budget(one * 4);
+            cache.mark_viewed(1);
+            cache.mark_viewed(2);
+            cache.mark_viewed(1);
+            for (id, chat_id) in [(1, 1), (2, 1), (3, 2), (4, 2)] {
+                cache.add_message(chat_id, create_test_message(id, chat_id, "x"));
+            }
+
+            // Chat 3 was just added to, so it stays; chat 2 was viewed longer
+            // ago than chat 1
+            cache.add_message(3, create_test_message(5, 3, "x"));
+            assert_eq!(cache.message_count(2), 0);
+            assert_eq!(cache.message_count(1), 2);
+            assert_eq!(cache.message_count(3), 1);
+
+            let metrics = cache.metrics();
+            assert_eq!(metrics.evicted_histories, 1);
+            assert_eq!(metrics.messages, 3);
+            assert_eq!(metrics.message_bytes, one * 3);
+        }
+
+        #[test]
+        fn per_chat_limit_counts_trimmed_messages() {
+            let cache = Cache::new(2);
+            for id in 1..=5 {
+                cache.add_message(1, create_test_message(id, 1, "x"));
+            }
+            cache.delete_message(1, 5);
+
+            let metrics = cache.metrics();
+            assert_eq!(metrics.trimmed_messages, 3);
+            assert_eq!(metrics.messages, 1);
+            assert_eq!(
+                metrics.message_bytes,
+                message_size(&create_test_message(4, 1, "x"))
+            );
+        }
+
+        #[test]
+        fn edits_keep_the_size_in_step() {
+            let cache = Cache::new(100);
+            cache.add_message(1, create_test_message(1, 1, "x"));
+            let longer = create_test_message(1, 1, &"x".repeat(500));
+            let size = message_size(&longer);
+            cache.update_message(1, longer);
+            assert_eq!(cache.metrics().message_bytes, size);
+
+            cache.delete_message(1, 1);
+            assert_eq!(cache.metrics().message_bytes, 0);
+        }
+
+        #[test]
+        fn clearing_messages_keeps_chats() {
+            let cache = Cache::new(100);
+            cache.set_chat(create_test_chat(1, "Chat"));
+            let message = create_test_message(1, 1, "Hello");
+            let size = message_size(&message);
+            cache.add_message(1, message);
+
+            assert_eq!(cache.clear_messages(), size);
+            assert_eq!(cache.message_count(1), 0);
+            assert!(cache.get_chat(1).is_some());
+            assert_eq!(cache.metrics().message_bytes, 0);
+        }
+    }
+
     mod general_cache_tests {
         use super::*;
 
@@ -57,6 +57,7 @@
 
 pub mod app;
 pub mod cache;
+pub mod platform;
 pub mod telegram;
 pub mod types;
 pub mod ui;
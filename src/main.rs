@@ -5,21 +5,25 @@
 #![allow(clippy::large_futures)]
 
 use std::io;
-use std::path::PathBuf;
+use std::path::{Path, PathBuf};
 use std::sync::Arc;
 
-use anyhow::{Context, Result};
-use clap::Parser;
+use anyhow::{bail, Context, Result};
+use clap::{Parser, Subcommand};
 use tokio::sync::mpsc;
 use tracing::{error, info, Level};
 use tracing_appender::rolling::{RollingFileAppender, Rotation};
 use tracing_subscriber::{fmt, layer::SubscriberExt, util::SubscriberInitExt, EnvFilter};
 
-use ithil::app::{Config, Credentials};
-use ithil::cache::new_shared_cache;
-use ithil::telegram::TelegramClient;
+use ithil::app::{health, state, Config, Credentials, StartupView};
+use ithil::cache::Cache;
+use ithil::telegram::replay::{self, Recording, ReplayTelegram};
+use ithil::telegram::{TelegramClient, UpdateHub};
+use ithil::types::DeepLink;
 use ithil::ui::App;
 
+type Terminal = ratatui::Terminal<ratatui::backend::CrosstermBackend<io::Stdout>>;
+
 /// Ithil - A Terminal User Interface for Telegram
 #[derive(Parser, Debug)]
 #[command(name = "ithil")]
@@ -32,14 +36,68 @@ struct Cli {
     /// Enable debug logging
     #[arg(short, long)]
     debug: bool,
+
+    /// Save every Telegram update to FILE, for playing back with --replay
+    #[arg(long, value_name = "FILE", conflicts_with = "replay")]
+    record: Option<PathBuf>,
+
+    /// Play back updates saved with --record instead of connecting to Telegram
+    #[arg(long, value_name = "FILE")]
+    replay: Option<PathBuf>,
+
+    /// Chat to open at startup: @username, phone number, chat ID, "saved"
+    /// for Saved Messages, or "last" (overrides `startup_view`)
+    #[arg(long, value_name = "CHAT", conflicts_with = "link")]
+    chat: Option<String>,
+
+    /// A t.me or tg:// link to open at startup, at its message if it names
+    /// one
+    #[arg(value_name = "LINK")]
+    link: Option<String>,
+
+    #[command(subcommand)]
+    command: Option<Command>,
+}
+
+#[derive(Subcommand, Debug)]
+enum Command {
+    /// Move your login to another machine
+    Session {
+        #[command(subcommand)]
+        action: SessionCommand,
+    },
+}
+
+#[derive(Subcommand, Debug)]
+enum SessionCommand {
+    /// Write the session and config to a passphrase-protected archive
+    Export {
+        /// Archive to create
+        file: PathBuf,
+    },
+    /// Restore a session archive made by `session export`
+    Import {
+        /// Archive to restore
+        file: PathBuf,
+
+        /// Replace an existing session and config
+        #[arg(long)]
+        force: bool,
+    },
 }
 
 #[tokio::main]
 async fn main() -> Result<()> {
     let cli = Cli::parse();
 
+    if let Some(Command::Session { action }) = cli.command {
+        return run_session_command(action, cli.config.as_deref());
+    }
+
     // Load configuration
     let config = Config::load(cli.config.as_deref()).context("Failed to load configuration")?;
+    // Settings are saved back where they came from
+    let config_file = Config::find_file(cli.config.as_deref()).unwrap_or_else(Config::default_file);
 
     // Validate configuration
     config.validate().context("Invalid configuration")?;
@@ -58,8 +116,148 @@ async fn main() -> Result<()> {
         .ensure_directories()
         .context("Failed to create application directories")?;
 
+    // Move files left behind by versions that kept everything in one place
+    match ithil::app::paths::migrate_legacy_files(&config) {
+        Ok(moved) => {
+            for (from, to) in moved {
+                info!("Moved {} to {}", from.display(), to.display());
+            }
+        },
+        Err(e) => error!("Failed to move files to their new locations: {e}"),
+    }
+
+    // Check arguments before taking over the terminal so errors are visible
+    let startup_view = match (cli.chat.as_deref(), cli.link.as_deref()) {
+        (Some(chat), _) => Some(StartupView::parse(chat).with_context(|| {
+            format!("Not a chat: {chat} (use @username, +phone, an ID, saved or last)")
+        })?),
+        (None, Some(link)) => Some(
+            DeepLink::parse(link)
+                .map(StartupView::Link)
+                .with_context(|| format!("Not a Telegram link: {link}"))?,
+        ),
+        (None, None) => None,
+    };
+
+    let record_file = cli
+        .record
+        .as_deref()
+        .map(|path| {
+            // It holds every message received, so only the user may read it
+            ithil::app::paths::create_private_file(path)
+                .with_context(|| format!("Failed to create recording: {}", path.display()))
+        })
+        .transpose()?;
+    let recording = cli
+        .replay
+        .as_deref()
+        .map(|path| {
+            Recording::load(path)
+                .with_context(|| format!("Failed to load recording: {}", path.display()))
+        })
+        .transpose()?;
+
+    // A damaged session would fail deep inside the client; set it aside so
+    // the user can log in again instead. Playback doesn't touch it.
+    let repairs = if recording.is_none() {
+        let repairs = health::check(&config).context("Failed to check the session files")?;
+        for repair in &repairs {
+            error!(
+                "Moved damaged {} ({}) to {}",
+                repair.path.display(),
+                repair.reason,
+                repair.moved_to.display()
+            );
+        }
+        repairs
+    } else {
+        Vec::new()
+    };
+
     // Run the TUI application
-    run_app(config).await
+    run_app(
+        config,
+        config_file,
+        startup_view,
+        repairs,
+        record_file,
+        recording,
+    )
+    .await
+}
+
+/// Run `ithil session export` or `ithil session import`
+fn run_session_command(action: SessionCommand, config_path: Option<&Path>) -> Result<()> {
+    match action {
+        SessionCommand::Export { file } => {
+            let config = Config::load(config_path).context("Failed to load configuration")?;
+            // The session may still be where an older version left it
+            ithil::app::paths::migrate_legacy_files(&config)
+                .context("Failed to move files to their new locations")?;
+            let passphrase = read_passphrase("Passphrase: ")?;
+            if passphrase.is_empty() {
+                bail!("A passphrase is required");
+            }
+            if read_passphrase("Repeat passphrase: ")? != passphrase {
+                bail!("Passphrases don't match");
+            }
+            ithil::app::export_session(
+                &config,
+                Config::find_file(config_path).as_deref(),
+                &passphrase,
+                &file,
+            )?;
+            println!("Session exported to {}", file.display());
+            println!("Keep it safe: anyone with the passphrase can use your account.");
+        },
+        SessionCommand::Import { file, force } => {
+            let passphrase = read_passphrase("Passphrase: ")?;
+            let target = config_path.map_or_else(Config::default_file, Path::to_path_buf);
+            // The config being replaced, if any, says where this machine
+            // keeps the session
+            let local = Config::load(Config::find_file(Some(&target)).as_deref())
+                .context("Failed to load configuration")?;
+            let imported = ithil::app::import_session(&file, &passphrase, &target, &local, force)?;
+            if let Some(config_file) = imported.config_file {
+                println!("Config restored to {}", config_file.display());
+            }
+            println!("Session restored to {}", imported.session_file.display());
+        },
+    }
+    Ok(())
+}
+
+/// Prompt for a passphrase on the terminal without echoing it
+fn read_passphrase(prompt: &str) -> Result<String> {
+    use crossterm::event::{self, Event, KeyCode, KeyEventKind, KeyModifiers};
+    use std::io::Write as _;
+
+    eprint!("{prompt}");
+    io::stderr().flush()?;
+
+    crossterm::terminal::enable_raw_mode().context("Failed to enable raw mode")?;
+    let mut input = String::new();
+    let result = loop {
+        match event::read() {
+            Ok(Event::Key(key)) if key.kind == KeyEventKind::Press => match key.code {
+                KeyCode::Enter => break Ok(()),
+                KeyCode::Char('c') if key.modifiers.contains(KeyModifiers::CONTROL) => {
+                    break Err(anyhow::anyhow!("Cancelled"));
+                },
+                KeyCode::Backspace => {
+                    input.pop();
+                },
+                KeyCode::Char(c) => input.push(c),
+                _ => {},
+            },
+            Ok(_) => {},
+            Err(e) => break Err(e.into()),
+        }
+    };
+    crossterm::terminal::disable_raw_mode().context("Failed to disable raw mode")?;
+    eprintln!();
+
+    result.map(|()| input)
 }
 
 /// Set up tracing/logging infrastructure
@@ -111,7 +309,16 @@ fn setup_logging(config: &Config, debug: bool) -> Result<()> {
 }
 
 /// Run the main TUI application
-async fn run_app(config: Config) -> Result<()> {
+///
+/// With a recording, plays it back instead of connecting to Telegram.
+async fn run_app(
+    config: Config,
+    config_file: PathBuf,
+    startup_view: Option<StartupView>,
+    repairs: Vec<health::Repair>,
+    record_file: Option<std::fs::File>,
+    recording: Option<Recording>,
+) -> Result<()> {
     // Set up terminal
     crossterm::terminal::enable_raw_mode().context("Failed to enable raw mode")?;
 
@@ -125,10 +332,60 @@ async fn run_app(config: Config) -> Result<()> {
     .context("Failed to set up terminal")?;
 
     let backend = ratatui::backend::CrosstermBackend::new(stdout);
-    let mut terminal = ratatui::Terminal::new(backend).context("Failed to create terminal")?;
+    let mut terminal = Terminal::new(backend).context("Failed to create terminal")?;
+
+    let (app, result) = match recording {
+        Some(recording) => {
+            run_replay(&mut terminal, config, config_file, startup_view, recording).await
+        },
+        None => {
+            run_live(
+                &mut terminal,
+                config,
+                config_file,
+                startup_view,
+                &repairs,
+                record_file,
+            )
+            .await
+        },
+    };
+
+    // Hand the window title back to the shell
+    if app.config.notifications.terminal_title {
+        ithil::utils::reset_terminal_title();
+    }
+
+    // Restore terminal
+    crossterm::terminal::disable_raw_mode().context("Failed to disable raw mode")?;
+
+    crossterm::execute!(
+        terminal.backend_mut(),
+        crossterm::terminal::LeaveAlternateScreen,
+        crossterm::event::DisableMouseCapture,
+        crossterm::event::DisableFocusChange
+    )
+    .context("Failed to restore terminal")?;
+
+    terminal.show_cursor().context("Failed to show cursor")?;
 
+    result
+}
+
+/// Run the app against Telegram, optionally recording its updates
+async fn run_live(
+    terminal: &mut Terminal,
+    config: Config,
+    config_file: PathBuf,
+    startup_view: Option<StartupView>,
+    repairs: &[health::Repair],
+    record_file: Option<std::fs::File>,
+) -> (App, Result<()>) {
     // Create shared cache
-    let cache = new_shared_cache(config.cache.max_messages_per_chat);
+    let cache = Arc::new(
+        Cache::new(config.cache.max_messages_per_chat)
+            .with_memory_budget(config.cache.memory_budget()),
+    );
 
     // Get API credentials
     let credentials = Credentials::from_config(&config);
@@ -146,13 +403,24 @@ async fn run_app(config: Config) -> Result<()> {
         cache.clone(),
     ));
 
-    // Create update channel for streaming Telegram updates to the UI
+    // Stream Telegram updates through a hub, so the UI and the recorder
+    // each get every update
     let (update_tx, update_rx) = mpsc::channel(100);
     telegram.set_update_channel(update_tx).await;
+    let hub = UpdateHub::new();
+    if let Some(file) = record_file {
+        replay::spawn_recorder(file, cache.clone(), hub.subscribe("recorder"));
+    }
 
     // Create the app
     let mut app = App::new(config, telegram.clone(), cache);
-    app.set_update_receiver(update_rx);
+    app.set_config_file(config_file);
+    app.set_update_receiver(hub.subscribe("app"));
+    if let Some(view) = startup_view {
+        app.set_startup_view(view);
+    }
+    app.report_repairs(repairs);
+    hub.spawn(update_rx);
 
     // Spawn Telegram connection in background so UI can render
     let telegram_for_connect = telegram.clone();
@@ -168,28 +436,58 @@ async fn run_app(config: Config) -> Result<()> {
 
     // Run the async event loop with connection happening in background
     let result = app
-        .run_async_with_connection(&mut terminal, connect_handle)
+        .run_async_with_connection(terminal, connect_handle)
         .await;
 
+    // Remember the open chat for `startup_view: last`
+    if let Some(chat_id) = app.get_selected_chat_id() {
+        if let Err(e) = state::save_last_chat(&state::last_chat_file(&app.config), chat_id) {
+            error!("Failed to remember the open chat: {e}");
+        }
+    }
+
     // Disconnect from Telegram gracefully
     if telegram.is_connected().await {
+        // Don't linger as "online" until the status times out
+        if let Err(e) = telegram.set_online(false).await {
+            info!("Could not report offline status: {e}");
+        }
         if let Err(e) = telegram.disconnect().await {
             error!("Error disconnecting from Telegram: {e}");
         }
     }
 
-    // Restore terminal
-    crossterm::terminal::disable_raw_mode().context("Failed to disable raw mode")?;
+    (app, result)
+}
 
-    crossterm::execute!(
-        terminal.backend_mut(),
-        crossterm::terminal::LeaveAlternateScreen,
-        crossterm::event::DisableMouseCapture,
-        crossterm::event::DisableFocusChange
-    )
-    .context("Failed to restore terminal")?;
+/// Run the app against a recording, without touching the network or session
+async fn run_replay(
+    terminal: &mut Terminal,
+    config: Config,
+    config_file: PathBuf,
+    startup_view: Option<StartupView>,
+    recording: Recording,
+) -> (App, Result<()>) {
+    info!("Replaying {} recorded updates", recording.updates.len());
+
+    let cache = Arc::new(
+        Cache::new(config.cache.max_messages_per_chat)
+            .with_memory_budget(config.cache.memory_budget()),
+    );
+    let (update_tx, update_rx) = mpsc::channel(100);
+    let telegram = Arc::new(ReplayTelegram::new(recording, cache.clone(), update_tx));
 
-    terminal.show_cursor().context("Failed to show cursor")?;
+    let mut app = App::new(config, telegram, cache);
+    app.set_config_file(config_file);
+    app.set_update_receiver(update_rx);
+    if let Some(view) = startup_view {
+        app.set_startup_view(view);
+    }
 
-    result
+    // There is nothing to connect to; the app goes straight to the chat list
+    let connect_handle = tokio::spawn(async { Ok(()) });
+    let result = app
+        .run_async_with_connection(terminal, connect_handle)
+        .await;
+    (app, result)
 }
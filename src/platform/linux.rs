@@ -0,0 +1,68 @@
+//! Linux and other Unix desktops: `xdg-open`, Wayland/X11 clipboard tools,
+//! and `notify-send`.
+
+use std::ffi::OsStr;
+
+use super::Invocation;
+
+/// Opens a file or URL with `xdg-open`.
+pub(super) fn open(target: &OsStr) -> Invocation {
+    Invocation::new("xdg-open", [target])
+}
+
+/// Runs a command line with `sh -c`, as on any Unix, macOS included.
+pub(super) fn shell(command: &str) -> Invocation {
+    Invocation::new("sh", ["-c", command])
+}
+
+/// Clipboard tools to try, best first.
+///
+/// `wl-copy` only works under Wayland; `xclip` and `xsel` cover X11 (and
+/// XWayland when `wl-copy` isn't installed).
+pub(super) fn clipboard(text: &str, wayland: bool) -> Vec<Invocation> {
+    let mut candidates = Vec::with_capacity(3);
+    if wayland {
+        candidates.push(Invocation::new::<_, &str>("wl-copy", []));
+    }
+    candidates.push(Invocation::new("xclip", ["-selection", "clipboard"]));
+    candidates.push(Invocation::new("xsel", ["--clipboard", "--input"]));
+    candidates
+        .into_iter()
+        .map(|c| c.input(text.as_bytes().to_vec()))
+        .collect()
+}
+
+/// Shows a notification with `notify-send`.
+pub(super) fn notification(title: &str, body: &str, sound: bool) -> Invocation {
+    let mut args = vec!["--app-name=Ithil".to_string()];
+    if sound {
+        // Freedesktop sound theme name; servers without sound support ignore it
+        args.push("--hint=string:sound-name:message-new-instant".to_string());
+    }
+    // "--" so a body starting with "-" isn't taken for an option
+    args.extend(["--".to_string(), title.to_string(), body.to_string()]);
+    Invocation::new("notify-send", args)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn prefers_wl_copy_under_wayland() {
+        let programs =
+            |wayland| -> Vec<&str> { clipboard("x", wayland).iter().map(|c| c.program).collect() };
+        assert_eq!(programs(true), ["wl-copy", "xclip", "xsel"]);
+        assert_eq!(programs(false), ["xclip", "xsel"]);
+        assert!(clipboard("hé", false)
+            .iter()
+            .all(|c| c.input.as_deref() == Some("hé".as_bytes())));
+    }
+
+    #[test]
+    fn notification_body_cannot_become_an_option() {
+        let n = notification("Ithil", "-u critical", false);
+        assert_eq!(n.args.last().unwrap(), "-u critical");
+        assert_eq!(n.args[n.args.len() - 3], "--");
+    }
+}
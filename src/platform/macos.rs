@@ -0,0 +1,57 @@
+//! macOS: `open`, `pbcopy`, and Notification Center through `osascript`.
+
+use std::ffi::OsStr;
+
+use super::Invocation;
+
+/// Opens a file or URL with `open`.
+pub(super) fn open(target: &OsStr) -> Invocation {
+    Invocation::new("open", [target])
+}
+
+/// Copies with `pbcopy`.
+pub(super) fn clipboard(text: &str) -> Vec<Invocation> {
+    let mut pbcopy = Invocation::new::<_, &str>("pbcopy", []).input(text.as_bytes().to_vec());
+    // pbcopy reads stdin in the locale's encoding, which may not be UTF-8
+    pbcopy.env.push(("LANG", "en_US.UTF-8".to_string()));
+    vec![pbcopy]
+}
+
+/// Shows a notification with AppleScript's `display notification`.
+///
+/// Title and body are passed as script arguments rather than spliced into
+/// the script, so quotes in a message can't break out of the string.
+pub(super) fn notification(title: &str, body: &str, sound: bool) -> Invocation {
+    let display = if sound {
+        "display notification (item 2 of argv) with title (item 1 of argv) sound name \"default\""
+    } else {
+        "display notification (item 2 of argv) with title (item 1 of argv)"
+    };
+    Invocation::new(
+        "osascript",
+        [
+            "-e",
+            "on run argv",
+            "-e",
+            display,
+            "-e",
+            "end run",
+            title,
+            body,
+        ],
+    )
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn notification_passes_text_as_arguments() {
+        let n = notification("Ithil", "Bob: \"hi\" & bye", true);
+        assert_eq!(n.program, "osascript");
+        assert_eq!(n.args.last().unwrap(), "Bob: \"hi\" & bye");
+        assert!(n.args[3].to_string_lossy().contains("sound name"));
+        assert!(!n.args[3].to_string_lossy().contains("Bob"));
+    }
+}
@@ -0,0 +1,307 @@
+//! Desktop integration that differs between operating systems.
+//!
+//! Opening files and links, the clipboard, system notifications, user
+//! hooks and playing voice messages all shell out to whatever the OS
+//! provides. Each OS module only builds the commands; this module picks the
+//! one for the current platform and runs it, so the rest of the app never
+//! needs a `cfg(target_os)` of its own.
+
+// Every OS module is built everywhere so its tests run on any machine
+#[cfg_attr(any(target_os = "macos", target_os = "windows"), allow(dead_code))]
+mod linux;
+#[cfg_attr(not(target_os = "macos"), allow(dead_code))]
+mod macos;
+#[cfg_attr(not(target_os = "windows"), allow(dead_code))]
+mod windows;
+
+use std::ffi::{OsStr, OsString};
+use std::io::{self, Write};
+use std::path::Path;
+use std::process::{Child, Command, Stdio};
+
+use tracing::debug;
+
+/// A command that performs one integration.
+#[derive(Debug, Clone, PartialEq, Eq)]
+struct Invocation {
+    /// Program to run, looked up on `PATH`
+    program: &'static str,
+    /// Arguments
+    args: Vec<OsString>,
+    /// Extra environment variables
+    env: Vec<(&'static str, String)>,
+    /// Bytes written to the program's stdin
+    input: Option<Vec<u8>>,
+}
+
+impl Invocation {
+    fn new<I, S>(program: &'static str, args: I) -> Self
+    where
+        I: IntoIterator<Item = S>,
+        S: Into<OsString>,
+    {
+        Self {
+            program,
+            args: args.into_iter().map(Into::into).collect(),
+            env: Vec::new(),
+            input: None,
+        }
+    }
+
+    fn input(mut self, input: Vec<u8>) -> Self {
+        self.input = Some(input);
+        self
+    }
+
+    /// Starts the program. Its input, if any, is left to [`feed`].
+    fn spawn(&self) -> io::Result<Child> {
+        let mut command = Command::new(self.program);
+        command
+            .args(&self.args)
+            .envs(self.env.iter().map(|(k, v)| (k, v)))
+            .stdin(if self.input.is_some() {
+                Stdio::piped()
+            } else {
+                Stdio::null()
+            })
+            .stdout(Stdio::null())
+            .stderr(Stdio::null());
+
+        #[cfg(target_os = "windows")]
+        {
+            use std::os::windows::process::CommandExt;
+            // Don't flash a console window
+            const CREATE_NO_WINDOW: u32 = 0x0800_0000;
+            command.creation_flags(CREATE_NO_WINDOW);
+        }
+
+        command.spawn()
+    }
+
+    /// Starts the program without waiting for it to finish.
+    fn spawn_detached(&self) -> io::Result<()> {
+        let mut child = self.spawn()?;
+        let input = self.input.clone();
+        // Feed it and reap it in the background, so a program slow to read
+        // its input doesn't hold up the app and none lingers as a zombie
+        std::thread::spawn(move || {
+            if let Err(e) = feed(&mut child, input.as_deref()) {
+                debug!("Couldn't write to a helper's input: {e}");
+            }
+            child.wait()
+        });
+        Ok(())
+    }
+
+    /// Runs the program to completion, failing if it exits unsuccessfully.
+    fn run(&self) -> io::Result<()> {
+        let mut child = self.spawn()?;
+        let fed = feed(&mut child, self.input.as_deref());
+        // Wait even if the input didn't go through, so it's reaped
+        let status = child.wait()?;
+        fed?;
+        if status.success() {
+            Ok(())
+        } else {
+            Err(io::Error::other(format!(
+                "{} exited with {status}",
+                self.program
+            )))
+        }
+    }
+}
+
+/// Writes `input` to the child's stdin and closes it. A program that exits
+/// without reading all of it is not an error.
+fn feed(child: &mut Child, input: Option<&[u8]>) -> io::Result<()> {
+    let (Some(input), Some(mut stdin)) = (input, child.stdin.take()) else {
+        return Ok(());
+    };
+    match stdin.write_all(input) {
+        Err(e) if e.kind() == io::ErrorKind::BrokenPipe => Ok(()),
+        result => result,
+    }
+}
+
+/// Opens a file or URL with the system's default application.
+///
+/// # Errors
+///
+/// Returns an error if the opener cannot be started.
+pub fn open(target: impl AsRef<OsStr>) -> io::Result<()> {
+    let target = target.as_ref();
+
+    #[cfg(target_os = "macos")]
+    let invocation = macos::open(target);
+    #[cfg(target_os = "windows")]
+    let invocation = windows::open(target);
+    #[cfg(not(any(target_os = "macos", target_os = "windows")))]
+    let invocation = linux::open(target);
+
+    invocation.spawn_detached()
+}
+
+/// Puts `text` on the system clipboard.
+///
+/// Over SSH, or when no clipboard tool is installed, the text is sent to the
+/// terminal with OSC 52 instead, which most terminals forward to the local
+/// clipboard.
+///
+/// # Errors
+///
+/// Returns an error only if writing the OSC 52 sequence fails.
+pub fn copy_to_clipboard(text: &str) -> io::Result<()> {
+    let remote =
+        std::env::var_os("SSH_TTY").is_some() || std::env::var_os("SSH_CONNECTION").is_some();
+
+    if !remote {
+        #[cfg(target_os = "macos")]
+        let candidates = macos::clipboard(text);
+        #[cfg(target_os = "windows")]
+        let candidates = windows::clipboard(text);
+        #[cfg(not(any(target_os = "macos", target_os = "windows")))]
+        let candidates = linux::clipboard(text, std::env::var_os("WAYLAND_DISPLAY").is_some());
+
+        for candidate in candidates {
+            match candidate.run() {
+                Ok(()) => return Ok(()),
+                Err(e) => debug!("Clipboard via {} failed: {}", candidate.program, e),
+            }
+        }
+    }
+
+    let mut stdout = io::stdout();
+    stdout.write_all(osc52_sequence(text).as_bytes())?;
+    stdout.flush()
+}
+
+/// Shows a notification through the OS notification center.
+///
+/// `body` must already be sanitized; it is passed as a separate argument or
+/// environment variable, never spliced into a script.
+///
+/// # Errors
+///
+/// Returns an error if the notifier cannot be started.
+pub fn system_notification(title: &str, body: &str, sound: bool) -> io::Result<()> {
+    #[cfg(target_os = "macos")]
+    let invocation = macos::notification(title, body, sound);
+    #[cfg(target_os = "windows")]
+    let invocation = windows::notification(title, body, sound);
+    #[cfg(not(any(target_os = "macos", target_os = "windows")))]
+    let invocation = linux::notification(title, body, sound);
+
+    invocation.spawn_detached()
+}
+
+/// Runs a user's command line through the shell without waiting for it,
+/// with extra environment variables and `input` on its stdin.
+///
+/// # Errors
+///
+/// Returns an error if the shell cannot be started.
+pub fn run_shell(
+    command: &str,
+    env: Vec<(&'static str, String)>,
+    input: Vec<u8>,
+) -> io::Result<()> {
+    #[cfg(target_os = "windows")]
+    let mut invocation = windows::shell(command);
+    #[cfg(not(target_os = "windows"))]
+    let mut invocation = linux::shell(command);
+
+    invocation.env = env;
+    invocation.input(input).spawn_detached()
+}
+
+/// Starts an audio player on `file` and returns it, to be waited on or
+/// stopped. `player` is the program and its arguments, split on
+/// whitespace; the file goes last.
+///
+/// # Errors
+///
+/// Returns an error if `player` is empty or can't be started.
+pub fn play(player: &str, file: &Path) -> io::Result<Child> {
+    let mut words = player.split_whitespace();
+    let program = words
+        .next()
+        .ok_or_else(|| io::Error::new(io::ErrorKind::InvalidInput, "no player is set"))?;
+    let mut command = Command::new(program);
+    command
+        .args(words)
+        .arg(file)
+        .stdin(Stdio::null())
+        .stdout(Stdio::null())
+        .stderr(Stdio::null());
+
+    #[cfg(target_os = "windows")]
+    {
+        use std::os::windows::process::CommandExt;
+        // Don't flash a console window
+        const CREATE_NO_WINDOW: u32 = 0x0800_0000;
+        command.creation_flags(CREATE_NO_WINDOW);
+    }
+
+    command.spawn()
+}
+
+/// Builds the OSC 52 sequence that sets the clipboard to `text`.
+fn osc52_sequence(text: &str) -> String {
+    format!("\x1b]52;c;{}\x07", base64(text.as_bytes()))
+}
+
+/// Standard base64 with padding.
+fn base64(bytes: &[u8]) -> String {
+    const ALPHABET: &[u8; 64] = b"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+
+    let mut out = String::with_capacity(bytes.len().div_ceil(3) * 4);
+    for chunk in bytes.chunks(3) {
+        let b = [
+            chunk[0],
+            chunk.get(1).copied().unwrap_or(0),
+            chunk.get(2).copied().unwrap_or(0),
+        ];
+        let n = (u32::from(b[0]) << 16) | (u32::from(b[1]) << 8) | u32::from(b[2]);
+        for i in 0..4 {
+            if i <= chunk.len() {
+                out.push(char::from(ALPHABET[(n >> (18 - 6 * i)) as usize & 63]));
+            } else {
+                out.push('=');
+            }
+        }
+    }
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn base64_pads_partial_chunks() {
+        assert_eq!(base64(b""), "");
+        assert_eq!(base64(b"f"), "Zg==");
+        assert_eq!(base64(b"fo"), "Zm8=");
+        assert_eq!(base64(b"foo"), "Zm9v");
+        assert_eq!(base64("héllo".as_bytes()), "aMOpbGxv");
+    }
+
+    #[test]
+    fn osc52_wraps_encoded_text() {
+        assert_eq!(osc52_sequence("hi"), "\x1b]52;c;aGk=\x07");
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn a_program_that_ignores_its_input_still_runs() {
+        let input = vec![b'x'; 1 << 20];
+        assert!(Invocation::new("sh", ["-c", "exit 0"])
+            .input(input.clone())
+            .run()
+            .is_ok());
+        assert!(Invocation::new("sh", ["-c", "exit 3"])
+            .input(input)
+            .run()
+            .is_err());
+    }
+}
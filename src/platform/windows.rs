@@ -0,0 +1,92 @@
+//! Windows: the default-program handler, `clip.exe`, and toast notifications
+//! through PowerShell.
+
+use std::ffi::OsStr;
+
+use super::Invocation;
+
+/// Shows a toast from the title and body in `ITHIL_TITLE` / `ITHIL_BODY`.
+///
+/// Toasts need a registered app ID; PowerShell's own is always present. The
+/// text is XML-escaped inside the script, so it never needs quoting here.
+const TOAST_SCRIPT: &str = "\
+$m = [Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime]
+$x = [Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime]::new()
+$t = [Security.SecurityElement]::Escape($env:ITHIL_TITLE)
+$b = [Security.SecurityElement]::Escape($env:ITHIL_BODY)
+$a = if ($env:ITHIL_SILENT) { \"<audio silent='true'/>\" } else { '' }
+$x.LoadXml(\"<toast><visual><binding template='ToastGeneric'><text>$t</text><text>$b</text></binding></visual>$a</toast>\")
+$id = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\\WindowsPowerShell\\v1.0\\powershell.exe'
+$m::CreateToastNotifier($id).Show([Windows.UI.Notifications.ToastNotification]::new($x))";
+
+/// Opens a file or URL with its default program.
+///
+/// `start` would go through `cmd`, which mangles URLs containing `&`;
+/// the URL handler takes the target as-is.
+pub(super) fn open(target: &OsStr) -> Invocation {
+    Invocation::new(
+        "rundll32",
+        [OsStr::new("url.dll,FileProtocolHandler"), target],
+    )
+}
+
+/// Runs a command line with `cmd /C`.
+pub(super) fn shell(command: &str) -> Invocation {
+    Invocation::new("cmd", ["/C", command])
+}
+
+/// Copies with `clip.exe`.
+pub(super) fn clipboard(text: &str) -> Vec<Invocation> {
+    vec![Invocation::new::<_, &str>("clip", []).input(utf16_with_bom(text))]
+}
+
+/// Shows a toast notification.
+pub(super) fn notification(title: &str, body: &str, sound: bool) -> Invocation {
+    let mut toast = Invocation::new(
+        "powershell",
+        [
+            "-NoProfile",
+            "-NonInteractive",
+            "-ExecutionPolicy",
+            "Bypass",
+            "-Command",
+            TOAST_SCRIPT,
+        ],
+    );
+    toast.env.push(("ITHIL_TITLE", title.to_string()));
+    toast.env.push(("ITHIL_BODY", body.to_string()));
+    if !sound {
+        toast.env.push(("ITHIL_SILENT", "1".to_string()));
+    }
+    toast
+}
+
+/// Encodes text the way `clip.exe` reads Unicode: UTF-16LE with a BOM.
+///
+/// Without the BOM it uses the console code page and mangles non-ASCII text.
+fn utf16_with_bom(text: &str) -> Vec<u8> {
+    std::iter::once(0xFEFF)
+        .chain(text.encode_utf16())
+        .flat_map(u16::to_le_bytes)
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn clip_input_is_utf16le_with_bom() {
+        assert_eq!(utf16_with_bom("hé"), [0xFF, 0xFE, b'h', 0, 0xE9, 0]);
+    }
+
+    #[test]
+    fn toast_text_goes_through_environment() {
+        let n = notification("Ithil", "Bob: <b>hi</b>", false);
+        assert!(!n.args.iter().any(|a| a.to_string_lossy().contains("Bob")));
+        assert!(n
+            .env
+            .contains(&("ITHIL_BODY", "Bob: <b>hi</b>".to_string())));
+        assert!(n.env.iter().any(|(k, _)| *k == "ITHIL_SILENT"));
+    }
+}
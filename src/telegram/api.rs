@@ -0,0 +1,596 @@
+//! The Telegram operations the UI depends on, as a trait.
+//!
+//! [`App`](crate::ui::App) talks to Telegram only through [`TelegramApi`], so
+//! tests can drive it with an in-memory backend instead of a live
+//! connection. [`TelegramClient`] is the real implementation.
+//!
+//! The operations are grouped into services: [`AuthService`],
+//! [`DialogService`], [`MessageService`], [`MediaService`] and
+//! [`UpdateService`]. [`TelegramApi`] is all of them together and is what the
+//! app holds; a backend implements each service, and code that needs only
+//! one can take `&dyn MessageService` and the like.
+//!
+//! Methods return boxed futures rather than using `async fn` so the traits
+//! can be used as `Arc<dyn TelegramApi>`.
+
+use std::future::Future;
+use std::path::{Path, PathBuf};
+use std::pin::Pin;
+use std::sync::Arc;
+
+use chrono::{DateTime, Utc};
+
+use super::client::TelegramClient;
+use super::error::TelegramError;
+use crate::types::{
+    AuthState, Chat, ChatPermissions, Message, PeerHandle, PollVoters, ReportReason, SearchFilter,
+    SendAsPeer, User,
+};
+
+/// A boxed, sendable future borrowing from the backend.
+pub type BoxFuture<'a, T> = Pin<Box<dyn Future<Output = T> + Send + 'a>>;
+
+/// Result of a Telegram operation.
+pub type ApiResult<'a, T> = BoxFuture<'a, Result<T, TelegramError>>;
+
+/// Logging in, and the logged-in user.
+pub trait AuthService: Send + Sync {
+    /// Returns the current authentication state.
+    fn get_auth_state(&self) -> BoxFuture<'_, AuthState>;
+
+    /// Asks Telegram to send a login code to `phone`.
+    fn request_login_code<'a>(&'a self, phone: &'a str) -> ApiResult<'a, ()>;
+
+    /// Signs in with the code sent to the phone.
+    fn sign_in<'a>(&'a self, code: &'a str) -> ApiResult<'a, ()>;
+
+    /// Completes sign-in with the two-factor password.
+    fn check_password<'a>(&'a self, password: &'a str) -> ApiResult<'a, ()>;
+
+    /// Fetches the logged-in user.
+    fn get_me(&self) -> ApiResult<'_, User>;
+
+    /// Logs out of Telegram and deletes the session, back to waiting for a
+    /// phone number.
+    fn log_out(&self) -> ApiResult<'_, ()>;
+}
+
+/// Chats as a whole: the dialog list, chat settings, members, reports
+/// and read state.
+pub trait DialogService: Send + Sync {
+    /// Fetches (and caches) every dialog.
+    fn get_dialogs(&self) -> ApiResult<'_, Vec<Chat>>;
+
+    /// Finds a user or public chat by username or phone number, even if it
+    /// isn't in the dialogs, and caches it so messages can be sent to it.
+    fn resolve_chat<'a>(&'a self, handle: &'a PeerHandle) -> ApiResult<'a, Chat>;
+
+    /// Searches Telegram for up to `limit` users and public chats matching
+    /// `query`, the user's own first. Nothing is cached; open a result with
+    /// `resolve_chat`.
+    fn search_global_chats<'a>(&'a self, query: &'a str, limit: usize) -> ApiResult<'a, Vec<Chat>>;
+
+    /// Mutes or unmutes a chat indefinitely.
+    fn mute_chat(&self, chat_id: i64, mute: bool) -> ApiResult<'_, ()>;
+
+    /// Mutes a chat for `duration`.
+    fn mute_chat_for(&self, chat_id: i64, duration: chrono::Duration) -> ApiResult<'_, ()>;
+
+    /// Moves a chat into the Archive folder, or back out of it.
+    fn archive_chat(&self, chat_id: i64, archive: bool) -> ApiResult<'_, ()>;
+
+    /// Joins a public channel or supergroup found with `resolve_chat`.
+    fn join_chat(&self, chat_id: i64) -> ApiResult<'_, ()>;
+
+    /// Leaves a group or channel.
+    fn leave_chat(&self, chat_id: i64) -> ApiResult<'_, ()>;
+
+    /// Sets a chat's auto-delete timer in seconds (0 turns it off).
+    fn set_auto_delete(&self, chat_id: i64, period: i32) -> ApiResult<'_, ()>;
+
+    /// Fetches a channel's discussion group, or a discussion group's channel.
+    fn get_linked_chat(&self, chat_id: i64) -> ApiResult<'_, Option<i64>>;
+
+    /// Returns a chat's t.me link, or its primary invite link if it has no
+    /// public username (`None` if there is neither, or it isn't visible).
+    fn get_invite_link(&self, chat_id: i64) -> ApiResult<'_, Option<String>>;
+
+    /// Fetches the identities the user can post as in a chat (empty where
+    /// they can only post as themselves).
+    fn get_send_as(&self, chat_id: i64) -> ApiResult<'_, Vec<SendAsPeer>>;
+
+    /// Lists up to `limit` members of a group or channel.
+    fn get_members(&self, chat_id: i64, limit: usize) -> ApiResult<'_, Vec<User>>;
+
+    /// Sets what members of a group may do by default.
+    fn set_chat_permissions(&self, chat_id: i64, permissions: ChatPermissions)
+        -> ApiResult<'_, ()>;
+
+    /// Reports a chat to Telegram's moderators.
+    fn report_chat(&self, chat_id: i64, reason: ReportReason) -> ApiResult<'_, ()>;
+
+    /// Marks every message in a chat as read.
+    fn mark_as_read(&self, chat_id: i64) -> ApiResult<'_, ()>;
+
+    /// Marks several chats as read, pacing the requests, and returns how
+    /// many were marked. `progress` is told the count after each chat.
+    fn mark_chats_as_read<'a>(
+        &'a self,
+        chat_ids: &'a [i64],
+        progress: &'a (dyn Fn(usize) + Sync),
+    ) -> ApiResult<'a, usize>;
+}
+
+/// Reading, searching, sending and changing messages, polls included.
+pub trait MessageService: Send + Sync {
+    /// Fetches up to `limit` messages older than `offset_id`, newest first.
+    fn get_messages(
+        &self,
+        chat_id: i64,
+        limit: usize,
+        offset_id: Option<i64>,
+    ) -> ApiResult<'_, Vec<Message>>;
+
+    /// Fetches up to `limit` messages sent before `date`, newest first.
+    fn get_messages_before_date(
+        &self,
+        chat_id: i64,
+        limit: usize,
+        date: DateTime<Utc>,
+    ) -> ApiResult<'_, Vec<Message>>;
+
+    /// Searches one chat, or all chats when `chat_id` is `None`, for up to
+    /// `limit` messages, newest first.
+    fn search_messages<'a>(
+        &'a self,
+        chat_id: Option<i64>,
+        query: &'a str,
+        filter: Option<SearchFilter>,
+        limit: usize,
+    ) -> ApiResult<'a, Vec<Message>>;
+
+    /// Sends a text message, as the identity `send_as` if given.
+    fn send_message<'a>(
+        &'a self,
+        chat_id: i64,
+        text: &'a str,
+        reply_to: Option<i64>,
+        send_as: Option<i64>,
+    ) -> ApiResult<'a, Message>;
+
+    /// Replaces the text of a sent message.
+    fn edit_message<'a>(
+        &'a self,
+        chat_id: i64,
+        message_id: i64,
+        new_text: &'a str,
+    ) -> ApiResult<'a, Message>;
+
+    /// Reacts to a message, replacing the user's earlier reaction, or takes
+    /// it back if `reaction` is `None`. Returns the message as reacted to.
+    fn send_reaction<'a>(
+        &'a self,
+        chat_id: i64,
+        message_id: i64,
+        reaction: Option<&'a str>,
+    ) -> ApiResult<'a, Message>;
+
+    /// Deletes messages, for everyone if `revoke` is set.
+    fn delete_messages<'a>(
+        &'a self,
+        chat_id: i64,
+        message_ids: &'a [i64],
+        revoke: bool,
+    ) -> ApiResult<'a, ()>;
+
+    /// Forwards messages with attribution to the original sender.
+    fn forward_messages<'a>(
+        &'a self,
+        from_chat_id: i64,
+        to_chat_id: i64,
+        message_ids: &'a [i64],
+    ) -> ApiResult<'a, Vec<Message>>;
+
+    /// Forwards messages as if freshly sent.
+    fn forward_messages_without_author<'a>(
+        &'a self,
+        from_chat_id: i64,
+        to_chat_id: i64,
+        message_ids: &'a [i64],
+    ) -> ApiResult<'a, ()>;
+
+    /// Tells the chat the user is typing.
+    fn send_typing(&self, chat_id: i64) -> ApiResult<'_, ()>;
+
+    /// Marks a voice or video message as played.
+    fn mark_played(&self, chat_id: i64, message_id: i64) -> ApiResult<'_, ()>;
+
+    /// Votes in a poll; an empty `options` retracts the vote.
+    fn vote_poll<'a>(
+        &'a self,
+        chat_id: i64,
+        message_id: i64,
+        options: &'a [Vec<u8>],
+    ) -> ApiResult<'a, ()>;
+
+    /// Fetches a page of the users who chose a poll option.
+    fn get_poll_voters<'a>(
+        &'a self,
+        chat_id: i64,
+        message_id: i64,
+        option: &'a [u8],
+        offset: Option<&'a str>,
+        limit: usize,
+    ) -> ApiResult<'a, PollVoters>;
+
+    /// Reports messages to Telegram's moderators.
+    fn report_messages<'a>(
+        &'a self,
+        chat_id: i64,
+        message_ids: &'a [i64],
+        reason: ReportReason,
+    ) -> ApiResult<'a, ()>;
+}
+
+/// Uploading and downloading files.
+pub trait MediaService: Send + Sync {
+    /// Sends a file with an optional caption; an image goes as a photo if
+    /// `as_photo` is set.
+    fn send_file<'a>(
+        &'a self,
+        chat_id: i64,
+        text: &'a str,
+        path: &'a Path,
+        as_photo: bool,
+        reply_to: Option<i64>,
+    ) -> ApiResult<'a, Message>;
+
+    /// Sends images as one album with `text` as its caption, returning its
+    /// messages.
+    fn send_album<'a>(
+        &'a self,
+        chat_id: i64,
+        text: &'a str,
+        paths: &'a [PathBuf],
+        reply_to: Option<i64>,
+    ) -> ApiResult<'a, Vec<Message>>;
+
+    /// Downloads a message's media unless it already is, returning where
+    /// the file is.
+    fn fetch_media<'a>(
+        &'a self,
+        message: &'a Message,
+        download_dir: &'a Path,
+    ) -> ApiResult<'a, PathBuf>;
+
+    /// Downloads a message's media in the background, reporting progress as
+    /// updates.
+    fn spawn_media_download(
+        self: Arc<Self>,
+        message: Message,
+        download_dir: PathBuf,
+        save_to: Option<PathBuf>,
+    );
+}
+
+/// Presence, and the stream of updates from Telegram.
+pub trait UpdateService: Send + Sync {
+    /// Reports the user as online or offline.
+    fn set_online(&self, online: bool) -> ApiResult<'_, ()>;
+
+    /// Returns `true` while [`Self::run_update_loop`] is running.
+    fn is_update_loop_running(&self) -> bool;
+
+    /// Streams updates to the UI channel until disconnected.
+    fn run_update_loop(&self) -> ApiResult<'_, ()>;
+}
+
+/// Telegram operations used by the UI: every service together.
+///
+/// Implementations keep the shared cache up to date the way
+/// [`TelegramClient`] does: fetched dialogs and sent messages are cached, and
+/// marking a chat read clears its unread count. Anything implementing all
+/// five services is a `TelegramApi`.
+pub trait TelegramApi:
+    AuthService + DialogService + MessageService + MediaService + UpdateService
+{
+}
+
+impl<T> TelegramApi for T where
+    T: AuthService + DialogService + MessageService + MediaService + UpdateService + ?Sized
+{
+}
+
+impl AuthService for TelegramClient {
+    fn get_auth_state(&self) -> BoxFuture<'_, AuthState> {
+        Box::pin(Self::get_auth_state(self))
+    }
+
+    fn request_login_code<'a>(&'a self, phone: &'a str) -> ApiResult<'a, ()> {
+        Box::pin(Self::request_login_code(self, phone))
+    }
+
+    fn sign_in<'a>(&'a self, code: &'a str) -> ApiResult<'a, ()> {
+        Box::pin(Self::sign_in(self, code))
+    }
+
+    fn check_password<'a>(&'a self, password: &'a str) -> ApiResult<'a, ()> {
+        Box::pin(Self::check_password(self, password))
+    }
+
+    fn get_me(&self) -> ApiResult<'_, User> {
+        Box::pin(Self::get_me(self))
+    }
+
+    fn log_out(&self) -> ApiResult<'_, ()> {
+        Box::pin(Self::log_out(self))
+    }
+}
+
+impl DialogService for TelegramClient {
+    fn get_dialogs(&self) -> ApiResult<'_, Vec<Chat>> {
+        Box::pin(Self::get_dialogs(self))
+    }
+
+    fn resolve_chat<'a>(&'a self, handle: &'a PeerHandle) -> ApiResult<'a, Chat> {
+        Box::pin(Self::resolve_chat(self, handle))
+    }
+
+    fn search_global_chats<'a>(&'a self, query: &'a str, limit: usize) -> ApiResult<'a, Vec<Chat>> {
+        Box::pin(Self::search_global_chats(self, query, limit))
+    }
+
+    fn mute_chat(&self, chat_id: i64, mute: bool) -> ApiResult<'_, ()> {
+        Box::pin(Self::mute_chat(self, chat_id, mute))
+    }
+
+    fn mute_chat_for(&self, chat_id: i64, duration: chrono::Duration) -> ApiResult<'_, ()> {
+        Box::pin(Self::mute_chat_for(self, chat_id, duration))
+    }
+
+    fn archive_chat(&self, chat_id: i64, archive: bool) -> ApiResult<'_, ()> {
+        Box::pin(Self::archive_chat(self, chat_id, archive))
+    }
+
+    fn join_chat(&self, chat_id: i64) -> ApiResult<'_, ()> {
+        Box::pin(Self::join_chat(self, chat_id))
+    }
+
+    fn leave_chat(&self, chat_id: i64) -> ApiResult<'_, ()> {
+        Box::pin(Self::leave_chat(self, chat_id))
+    }
+
+    fn set_auto_delete(&self, chat_id: i64, period: i32) -> ApiResult<'_, ()> {
+        Box::pin(Self::set_auto_delete(self, chat_id, period))
+    }
+
+    fn get_linked_chat(&self, chat_id: i64) -> ApiResult<'_, Option<i64>> {
+        Box::pin(Self::get_linked_chat(self, chat_id))
+    }
+
+    fn get_invite_link(&self, chat_id: i64) -> ApiResult<'_, Option<String>> {
+        Box::pin(Self::get_invite_link(self, chat_id))
+    }
+
+    fn get_send_as(&self, chat_id: i64) -> ApiResult<'_, Vec<SendAsPeer>> {
+        Box::pin(Self::get_send_as(self, chat_id))
+    }
+
+    fn get_members(&self, chat_id: i64, limit: usize) -> ApiResult<'_, Vec<User>> {
+        Box::pin(Self::get_members(self, chat_id, limit))
+    }
+
+    fn set_chat_permissions(
+        &self,
+        chat_id: i64,
+        permissions: ChatPermissions,
+    ) -> ApiResult<'_, ()> {
+        Box::pin(Self::set_chat_permissions(self, chat_id, permissions))
+    }
+
+    fn report_chat(&self, chat_id: i64, reason: ReportReason) -> ApiResult<'_, ()> {
+        Box::pin(Self::report_chat(self, chat_id, reason))
+    }
+
+    fn mark_as_read(&self, chat_id: i64) -> ApiResult<'_, ()> {
+        Box::pin(Self::mark_as_read(self, chat_id))
+    }
+
+    fn mark_chats_as_read<'a>(
+        &'a self,
+        chat_ids: &'a [i64],
+        progress: &'a (dyn Fn(usize) + Sync),
+    ) -> ApiResult<'a, usize> {
+        Box::pin(Self::mark_chats_as_read(self, chat_ids, progress))
+    }
+}
+
+impl MessageService for TelegramClient {
+    fn get_messages(
+        &self,
+        chat_id: i64,
+        limit: usize,
+        offset_id: Option<i64>,
+    ) -> ApiResult<'_, Vec<Message>> {
+        Box::pin(Self::get_messages(self, chat_id, limit, offset_id))
+    }
+
+    fn get_messages_before_date(
+        &self,
+        chat_id: i64,
+        limit: usize,
+        date: DateTime<Utc>,
+    ) -> ApiResult<'_, Vec<Message>> {
+        Box::pin(Self::get_messages_before_date(self, chat_id, limit, date))
+    }
+
+    fn search_messages<'a>(
+        &'a self,
+        chat_id: Option<i64>,
+        query: &'a str,
+        filter: Option<SearchFilter>,
+        limit: usize,
+    ) -> ApiResult<'a, Vec<Message>> {
+        Box::pin(Self::search_messages(self, chat_id, query, filter, limit))
+    }
+
+    fn send_message<'a>(
+        &'a self,
+        chat_id: i64,
+        text: &'a str,
+        reply_to: Option<i64>,
+        send_as: Option<i64>,
+    ) -> ApiResult<'a, Message> {
+        Box::pin(Self::send_message(self, chat_id, text, reply_to, send_as))
+    }
+
+    fn edit_message<'a>(
+        &'a self,
+        chat_id: i64,
+        message_id: i64,
+        new_text: &'a str,
+    ) -> ApiResult<'a, Message> {
+        Box::pin(Self::edit_message(self, chat_id, message_id, new_text))
+    }
+
+    fn send_reaction<'a>(
+        &'a self,
+        chat_id: i64,
+        message_id: i64,
+        reaction: Option<&'a str>,
+    ) -> ApiResult<'a, Message> {
+        Box::pin(Self::send_reaction(self, chat_id, message_id, reaction))
+    }
+
+    fn delete_messages<'a>(
+        &'a self,
+        chat_id: i64,
+        message_ids: &'a [i64],
+        revoke: bool,
+    ) -> ApiResult<'a, ()> {
+        Box::pin(Self::delete_messages(self, chat_id, message_ids, revoke))
+    }
+
+    fn forward_messages<'a>(
+        &'a self,
+        from_chat_id: i64,
+        to_chat_id: i64,
+        message_ids: &'a [i64],
+    ) -> ApiResult<'a, Vec<Message>> {
+        Box::pin(Self::forward_messages(
+            self,
+            from_chat_id,
+            to_chat_id,
+            message_ids,
+        ))
+    }
+
+    fn forward_messages_without_author<'a>(
+        &'a self,
+        from_chat_id: i64,
+        to_chat_id: i64,
+        message_ids: &'a [i64],
+    ) -> ApiResult<'a, ()> {
+        Box::pin(Self::forward_messages_without_author(
+            self,
+            from_chat_id,
+            to_chat_id,
+            message_ids,
+        ))
+    }
+
+    fn send_typing(&self, chat_id: i64) -> ApiResult<'_, ()> {
+        Box::pin(Self::send_typing(self, chat_id))
+    }
+
+    fn mark_played(&self, chat_id: i64, message_id: i64) -> ApiResult<'_, ()> {
+        Box::pin(Self::mark_played(self, chat_id, message_id))
+    }
+
+    fn vote_poll<'a>(
+        &'a self,
+        chat_id: i64,
+        message_id: i64,
+        options: &'a [Vec<u8>],
+    ) -> ApiResult<'a, ()> {
+        Box::pin(Self::vote_poll(self, chat_id, message_id, options))
+    }
+
+    fn get_poll_voters<'a>(
+        &'a self,
+        chat_id: i64,
+        message_id: i64,
+        option: &'a [u8],
+        offset: Option<&'a str>,
+        limit: usize,
+    ) -> ApiResult<'a, PollVoters> {
+        Box::pin(Self::get_poll_voters(
+            self, chat_id, message_id, option, offset, limit,
+        ))
+    }
+
+    fn report_messages<'a>(
+        &'a self,
+        chat_id: i64,
+        message_ids: &'a [i64],
+        reason: ReportReason,
+    ) -> ApiResult<'a, ()> {
+        Box::pin(Self::report_messages(self, chat_id, message_ids, reason))
+    }
+}
+
+impl MediaService for TelegramClient {
+    fn send_file<'a>(
+        &'a self,
+        chat_id: i64,
+        text: &'a str,
+        path: &'a Path,
+        as_photo: bool,
+        reply_to: Option<i64>,
+    ) -> ApiResult<'a, Message> {
+        Box::pin(Self::send_file(
+            self, chat_id, text, path, as_photo, reply_to,
+        ))
+    }
+
+    fn send_album<'a>(
+        &'a self,
+        chat_id: i64,
+        text: &'a str,
+        paths: &'a [PathBuf],
+        reply_to: Option<i64>,
+    ) -> ApiResult<'a, Vec<Message>> {
+        Box::pin(Self::send_album(self, chat_id, text, paths, reply_to))
+    }
+
+    fn fetch_media<'a>(
+        &'a self,
+        message: &'a Message,
+        download_dir: &'a Path,
+    ) -> ApiResult<'a, PathBuf> {
+        Box::pin(Self::download_media_if_needed(self, message, download_dir))
+    }
+
+    fn spawn_media_download(
+        self: Arc<Self>,
+        message: Message,
+        download_dir: PathBuf,
+        save_to: Option<PathBuf>,
+    ) {
+        Self::spawn_media_download(&self, message, download_dir, save_to);
+    }
+}
+
+impl UpdateService for TelegramClient {
+    fn set_online(&self, online: bool) -> ApiResult<'_, ()> {
+        Box::pin(Self::set_online(self, online))
+    }
+
+    fn is_update_loop_running(&self) -> bool {
+        Self::is_update_loop_running(self)
+    }
+
+    fn run_update_loop(&self) -> ApiResult<'_, ()> {
+        Box::pin(Self::run_update_loop(self))
+    }
+}
@@ -5,6 +5,12 @@
 //! 2. Sign in with the received code
 //! 3. Provide 2FA password if required
 //! 4. Sign up if the phone number is not registered
+//!
+//! Requesting a code and signing in with it are retried a few times when
+//! the network fails, and a failed sign-in keeps the login code's token so
+//! the user can simply try again.
+
+use std::time::Duration;
 
 use grammers_client::SignInError;
 use tracing::{debug, info, warn};
@@ -13,6 +19,28 @@ use super::client::TelegramClient;
 use super::error::TelegramError;
 use crate::types::{AuthState, User, UserStatus};
 
+/// How many times a step of the login is tried before a network failure
+/// is reported.
+const AUTH_ATTEMPTS: u32 = 3;
+
+/// Returns how long to wait before trying a login step again after
+/// `error` on attempt number `attempt` (starting at 1), or `None` if the
+/// error should go to the user.
+///
+/// Only network failures and timeouts are retried, after one and then two
+/// seconds. A flood wait is left to the user, who is shown how long it is.
+fn auth_retry_delay(attempt: u32, error: &TelegramError) -> Option<Duration> {
+    if attempt >= AUTH_ATTEMPTS {
+        return None;
+    }
+    match error {
+        TelegramError::Network(_) | TelegramError::Timeout => {
+            Some(Duration::from_secs(1 << (attempt - 1)))
+        },
+        _ => None,
+    }
+}
+
 impl TelegramClient {
     /// Requests a login code for the given phone number.
     ///
@@ -28,7 +56,8 @@ impl TelegramClient {
     /// Returns an error if:
     /// - The client is not connected
     /// - The phone number is invalid
-    /// - Network error occurs
+    /// - The network still fails after a few tries
+    /// - Telegram asks to wait before sending another code
     ///
     /// # Examples
     ///
@@ -49,10 +78,24 @@ impl TelegramClient {
         );
 
         // request_login_code takes phone and api_hash
-        let token = client
-            .request_login_code(phone, self.api_hash())
-            .await
-            .map_err(TelegramError::from)?;
+        let mut attempt = 1;
+        let token = loop {
+            match client.request_login_code(phone, self.api_hash()).await {
+                Ok(token) => break token,
+                Err(e) => {
+                    let e = TelegramError::from(e);
+                    let Some(delay) = auth_retry_delay(attempt, &e) else {
+                        return Err(e);
+                    };
+                    warn!(
+                        "Requesting login code failed ({}); retrying in {:?}",
+                        e, delay
+                    );
+                    tokio::time::sleep(delay).await;
+                    attempt += 1;
+                },
+            }
+        };
 
         // Store the token for use in sign_in
         self.set_login_token(token).await;
@@ -77,6 +120,8 @@ impl TelegramClient {
     /// - The client is not connected
     /// - No login code was requested (call `request_login_code` first)
     /// - The code is invalid
+    /// - The network still fails after a few tries; the code
+    ///   can be tried again
     /// - 2FA password is required (auth state changes to `WaitPassword`)
     /// - Sign up is required (auth state changes to `WaitRegistration`)
     ///
@@ -103,37 +148,49 @@ impl TelegramClient {
 
         info!("Attempting to sign in with verification code");
 
-        match client.sign_in(&token, code).await {
-            Ok(user) => {
-                let name = user.first_name().unwrap_or("Unknown");
-                info!("Signed in successfully as: {}", name);
-                self.set_auth_state(AuthState::Ready).await;
+        let mut attempt = 1;
+        loop {
+            match client.sign_in(&token, code).await {
+                Ok(user) => {
+                    let name = user.first_name().unwrap_or("Unknown");
+                    info!("Signed in successfully as: {}", name);
+                    self.set_auth_state(AuthState::Ready).await;
 
-                Ok(())
-            },
-            Err(SignInError::PasswordRequired(password_token)) => {
-                info!("2FA password required");
-                self.set_password_token(password_token).await;
-                self.set_auth_state(AuthState::WaitPassword).await;
-                Err(TelegramError::PasswordRequired)
-            },
-            Err(SignInError::SignUpRequired) => {
-                info!("Sign up required - phone number not registered");
-                // Store the login token back for sign_up
-                self.set_login_token(token).await;
-                self.set_auth_state(AuthState::WaitRegistration).await;
-                Err(TelegramError::SignUpRequired)
-            },
-            Err(SignInError::InvalidCode) => {
-                warn!("Invalid verification code");
-                // Store the token back so user can retry
-                self.set_login_token(token).await;
-                Err(TelegramError::InvalidCode)
-            },
-            Err(e) => {
-                warn!("Sign in failed: {}", e);
-                Err(e.into())
-            },
+                    return Ok(());
+                },
+                Err(SignInError::PasswordRequired(password_token)) => {
+                    info!("2FA password required");
+                    self.set_password_token(password_token).await;
+                    self.set_auth_state(AuthState::WaitPassword).await;
+                    return Err(TelegramError::PasswordRequired);
+                },
+                Err(SignInError::SignUpRequired) => {
+                    info!("Sign up required - phone number not registered");
+                    // Store the login token back for sign_up
+                    self.set_login_token(token).await;
+                    self.set_auth_state(AuthState::WaitRegistration).await;
+                    return Err(TelegramError::SignUpRequired);
+                },
+                Err(SignInError::InvalidCode) => {
+                    warn!("Invalid verification code");
+                    // Store the token back so user can retry
+                    self.set_login_token(token).await;
+                    return Err(TelegramError::InvalidCode);
+                },
+                Err(e) => {
+                    let e = TelegramError::from(e);
+                    if let Some(delay) = auth_retry_delay(attempt, &e) {
+                        warn!("Sign in failed ({}); retrying in {:?}", e, delay);
+                        tokio::time::sleep(delay).await;
+                        attempt += 1;
+                        continue;
+                    }
+                    warn!("Sign in failed: {}", e);
+                    // The code is still good; keep the token for another try
+                    self.set_login_token(token).await;
+                    return Err(e);
+                },
+            }
         }
     }
 
@@ -252,26 +309,36 @@ impl TelegramClient {
 
     /// Logs out and clears the session.
     ///
-    /// After calling this, the user will need to re-authenticate.
+    /// The connection is closed first, so nothing writes the old session
+    /// back, then the session file and its SQLite sidecars are removed and a
+    /// fresh session is opened. After calling this, the user will need to
+    /// re-authenticate.
     ///
     /// # Errors
     ///
-    /// Returns an error if the client is not connected.
+    /// Returns an error if the client is not connected, or the fresh
+    /// session can't be opened.
     pub async fn log_out(&self) -> Result<(), TelegramError> {
         let client = self.client().await?;
 
         info!("Logging out...");
 
         client.sign_out().await.map_err(TelegramError::from)?;
+        drop(client);
+        self.disconnect().await?;
 
-        // Clear the session file
-        let session_path = self.session_path();
-        if std::path::Path::new(session_path).exists() {
-            if let Err(e) = std::fs::remove_file(session_path) {
-                warn!("Failed to remove session file: {}", e);
+        let session_file = std::path::Path::new(self.session_path());
+        for path in crate::app::storage::session_files(session_file) {
+            match std::fs::remove_file(&path) {
+                Err(e) if e.kind() != std::io::ErrorKind::NotFound => {
+                    warn!("Failed to remove {}: {}", path.display(), e);
+                },
+                _ => {},
             }
         }
 
+        self.forget_my_id();
+        self.connect().await?;
         self.set_auth_state(AuthState::WaitPhoneNumber).await;
         info!("Logged out successfully");
 
@@ -301,6 +368,19 @@ pub(crate) fn grammers_user_to_user(user: &grammers_client::peer::User) -> User
 mod tests {
     use super::*;
 
+    #[test]
+    fn test_only_network_failures_are_retried_and_not_forever() {
+        let network = TelegramError::Network("connection reset".into());
+        assert_eq!(auth_retry_delay(1, &network), Some(Duration::from_secs(1)));
+        assert_eq!(
+            auth_retry_delay(2, &TelegramError::Timeout),
+            Some(Duration::from_secs(2))
+        );
+        assert_eq!(auth_retry_delay(AUTH_ATTEMPTS, &network), None);
+        assert_eq!(auth_retry_delay(1, &TelegramError::InvalidCode), None);
+        assert_eq!(auth_retry_delay(1, &TelegramError::FloodWait(30)), None);
+    }
+
     #[test]
     fn test_grammers_user_conversion_defaults() {
         // We can't easily create a grammers User for testing without mocking,
@@ -5,17 +5,51 @@
 //! - Searching chats
 //! - Pinning/unpinning chats
 //! - Muting/unmuting chats
+//! - Setting the auto-delete timer
+//! - Listing the identities the user can post as
+//! - Listing a group's members
+//! - Finding users and public chats by username or phone number
 //! - Archiving/unarchiving chats
 //! - Marking chats as read
 
-use grammers_client::peer::{Dialog, Peer as GrammersPeer};
-use grammers_client::tl;
-use grammers_session::types::PeerRef;
-use tracing::{debug, info};
+use std::collections::{HashMap, HashSet};
+use std::future::Future;
+use std::time::Duration;
+
+use grammers_client::peer::{Dialog, Peer as GrammersPeer, User as GrammersUser};
+use grammers_client::{tl, Client};
+use grammers_session::types::{PeerAuth, PeerId, PeerKind, PeerRef};
+use tracing::{debug, info, warn};
 
 use super::client::TelegramClient;
 use super::error::TelegramError;
-use crate::types::{Chat, ChatType, Message, UserStatus};
+use super::participants::tl_user_to_user;
+use crate::types::{
+    CallInfo, CallOutcome, Chat, ChatPermissions, ChatType, ForwardInfo, ForwardOrigin, Location,
+    Message, PeerHandle, ReportReason, SendAsPeer, ServiceAction, UserStatus,
+};
+
+/// Chats marked read between pauses by [`TelegramClient::mark_chats_as_read`].
+const MARK_READ_BATCH: usize = 20;
+
+/// Pause between batches of [`TelegramClient::mark_chats_as_read`], to stay
+/// clear of Telegram's flood limits.
+const MARK_READ_PAUSE: Duration = Duration::from_secs(1);
+
+/// Longest flood wait sat out while marking chats read; a longer one stops
+/// the run instead of leaving it hanging for minutes.
+const MAX_FLOOD_WAIT: Duration = Duration::from_secs(30);
+
+/// Most dialogs looked through for a chat whose access hash went stale,
+/// once asking for the chat itself has failed.
+const MAX_REFRESH_DIALOGS: usize = 200;
+
+/// Errors meaning no one has the username or phone number looked up.
+const HANDLE_NOT_FOUND_CODES: [&str; 3] = [
+    "USERNAME_NOT_OCCUPIED",
+    "USERNAME_INVALID",
+    "PHONE_NOT_OCCUPIED",
+];
 
 impl TelegramClient {
     /// Fetches all dialogs (chats) from Telegram.
@@ -95,6 +129,86 @@ impl TelegramClient {
         Ok(results)
     }
 
+    /// Searches Telegram for up to `limit` users and public chats matching
+    /// `query` (`contacts.search`), including ones not in the dialogs.
+    ///
+    /// Nothing is cached: the results are candidates, and one is only
+    /// added once it's opened with [`Self::resolve_chat`].
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected or not authorized.
+    pub async fn search_global_chats(
+        &self,
+        query: &str,
+        limit: usize,
+    ) -> Result<Vec<Chat>, TelegramError> {
+        let client = self.require_authorized().await?;
+
+        let tl::enums::contacts::Found::Found(found) = client
+            .invoke(&tl::functions::contacts::Search {
+                q: query.to_string(),
+                limit: i32::try_from(limit).unwrap_or(i32::MAX),
+            })
+            .await
+            .map_err(TelegramError::from)?;
+
+        let users = found.users.iter().filter_map(|raw| {
+            let tl::enums::User::User(u) = raw else {
+                return None;
+            };
+            let user = tl_user_to_user(raw)?;
+            Some((user.id, user_to_chat(&user, u.access_hash.unwrap_or(0))))
+        });
+        let chats = found.chats.iter().filter_map(|raw| match raw {
+            tl::enums::Chat::Chat(c) => Some(Chat {
+                id: c.id,
+                chat_type: ChatType::Group,
+                title: c.title.clone(),
+                member_count: c.participants_count,
+                ..Chat::default()
+            }),
+            tl::enums::Chat::Channel(c) => Some(Chat {
+                id: c.id,
+                chat_type: if c.broadcast {
+                    ChatType::Channel
+                } else {
+                    ChatType::Supergroup
+                },
+                title: c.title.clone(),
+                username: c.username.clone().unwrap_or_default(),
+                member_count: c.participants_count.unwrap_or(0),
+                access_hash: c.access_hash.unwrap_or(0),
+                ..Chat::default()
+            }),
+            _ => None,
+        });
+        let mut known: HashMap<i64, Chat> =
+            users.chain(chats.map(|chat| (chat.id, chat))).collect();
+
+        // The user's own contacts and chats come before everyone else
+        let results: Vec<Chat> = found
+            .my_results
+            .iter()
+            .chain(&found.results)
+            .filter_map(|peer| {
+                let id = match peer {
+                    tl::enums::Peer::User(u) => u.user_id,
+                    tl::enums::Peer::Chat(c) => c.chat_id,
+                    tl::enums::Peer::Channel(c) => c.channel_id,
+                };
+                known.remove(&id)
+            })
+            .collect();
+
+        debug!(
+            "Global search for '{}' found {} chats",
+            query,
+            results.len()
+        );
+        Ok(results)
+    }
+
     /// Pins or unpins a chat.
     ///
     /// # Arguments
@@ -147,18 +261,47 @@ impl TelegramClient {
     /// Returns an error if the client is not connected, not authorized,
     /// or the chat is not found.
     pub async fn mute_chat(&self, chat_id: i64, mute: bool) -> Result<(), TelegramError> {
+        // Mute for a very long time or unmute
+        let mute_until = if mute { i32::MAX } else { 0 };
+        self.set_mute_until(chat_id, mute_until).await
+    }
+
+    /// Mutes a chat for a limited time.
+    ///
+    /// Telegram unmutes the chat on its own once `duration` has elapsed.
+    ///
+    /// # Arguments
+    ///
+    /// * `chat_id` - ID of the chat to mute
+    /// * `duration` - How long to mute for
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// or the chat is not found.
+    pub async fn mute_chat_for(
+        &self,
+        chat_id: i64,
+        duration: chrono::Duration,
+    ) -> Result<(), TelegramError> {
+        let until = (chrono::Utc::now() + duration).timestamp();
+        let mute_until = i32::try_from(until).unwrap_or(i32::MAX);
+        self.set_mute_until(chat_id, mute_until).await
+    }
+
+    /// Applies a `mute_until` unix timestamp to a chat (0 unmutes).
+    async fn set_mute_until(&self, chat_id: i64, mute_until: i32) -> Result<(), TelegramError> {
         let client = self.require_authorized().await?;
         let peer_ref = self.get_peer_ref(chat_id).await?;
+        let mute = mute_until != 0;
 
         info!(
-            "{} chat {}",
+            "{} chat {} (until {})",
             if mute { "Muting" } else { "Unmuting" },
-            chat_id
+            chat_id,
+            mute_until
         );
 
-        // Mute for a very long time (1 year in seconds) or unmute
-        let mute_until = if mute { i32::MAX } else { 0 };
-
         client
             .invoke(&tl::functions::account::UpdateNotifySettings {
                 peer: tl::enums::InputNotifyPeer::Peer(tl::types::InputNotifyPeer {
@@ -188,7 +331,292 @@ impl TelegramClient {
         Ok(())
     }
 
-    /// Archives or unarchives a chat.
+    /// Sets what members of a group may do by default.
+    ///
+    /// Needs the right to ban users (see [`Chat::can_edit_permissions`]).
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// the chat is not found, or the user lacks the right.
+    pub async fn set_chat_permissions(
+        &self,
+        chat_id: i64,
+        permissions: ChatPermissions,
+    ) -> Result<(), TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        info!("Setting default permissions for chat {}", chat_id);
+
+        client
+            .invoke(&tl::functions::messages::EditChatDefaultBannedRights {
+                peer: tl::enums::InputPeer::from(peer_ref),
+                banned_rights: permissions_to_banned_rights(permissions),
+            })
+            .await
+            .map_err(TelegramError::from)?;
+
+        // Update cache
+        if let Some(mut chat) = self.cache().get_chat(chat_id) {
+            chat.default_permissions = Some(permissions);
+            self.cache().set_chat(chat);
+        }
+
+        Ok(())
+    }
+
+    /// Reports a chat, group, or channel to Telegram's moderators.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// or the chat is not found.
+    pub async fn report_chat(
+        &self,
+        chat_id: i64,
+        reason: ReportReason,
+    ) -> Result<(), TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        info!("Reporting chat {} for {}", chat_id, reason);
+
+        client
+            .invoke(&tl::functions::account::ReportPeer {
+                peer: tl::enums::InputPeer::from(peer_ref),
+                reason: report_reason_to_tl(reason),
+                message: String::new(),
+            })
+            .await
+            .map_err(TelegramError::from)?;
+
+        Ok(())
+    }
+
+    /// Fetches a channel's discussion group (or a discussion group's
+    /// channel) and records it on the cached chat.
+    ///
+    /// Returns `None` for chats without one, including private chats and
+    /// basic groups.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// or the chat is not found.
+    pub async fn get_linked_chat(&self, chat_id: i64) -> Result<Option<i64>, TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+        if !matches!(peer_ref.id.kind(), PeerKind::Channel) {
+            return Ok(None);
+        }
+
+        let tl::enums::messages::ChatFull::Full(full) = client
+            .invoke(&tl::functions::channels::GetFullChannel {
+                channel: tl::types::InputChannel {
+                    channel_id: peer_ref.id.bare_id(),
+                    access_hash: peer_ref.auth.hash(),
+                }
+                .into(),
+            })
+            .await
+            .map_err(TelegramError::from)?;
+        let linked = match full.full_chat {
+            tl::enums::ChatFull::ChannelFull(channel) => channel.linked_chat_id,
+            tl::enums::ChatFull::Full(_) => None,
+        };
+
+        if let Some(mut chat) = self.cache().get_chat(chat_id) {
+            chat.linked_chat_id = linked.unwrap_or(0);
+            self.cache().set_chat(chat);
+        }
+        Ok(linked)
+    }
+
+    /// Returns a chat's t.me link, or its primary invite link if it has
+    /// no public username.
+    ///
+    /// Only admins see a private group's invite link; for others, and for
+    /// private chats with users who have no username, this returns `None`.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// or the chat is not found.
+    pub async fn get_invite_link(&self, chat_id: i64) -> Result<Option<String>, TelegramError> {
+        if let Some(chat) = self.cache().get_chat(chat_id) {
+            if !chat.username.is_empty() {
+                return Ok(Some(format!("https://t.me/{}", chat.username)));
+            }
+        }
+
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+        let full = match peer_ref.id.kind() {
+            PeerKind::Channel => {
+                client
+                    .invoke(&tl::functions::channels::GetFullChannel {
+                        channel: tl::types::InputChannel {
+                            channel_id: peer_ref.id.bare_id(),
+                            access_hash: peer_ref.auth.hash(),
+                        }
+                        .into(),
+                    })
+                    .await
+            },
+            PeerKind::Chat => {
+                client
+                    .invoke(&tl::functions::messages::GetFullChat {
+                        chat_id: peer_ref.id.bare_id(),
+                    })
+                    .await
+            },
+            PeerKind::User | PeerKind::UserSelf => return Ok(None),
+        }
+        .map_err(TelegramError::from)?;
+
+        let tl::enums::messages::ChatFull::Full(full) = full;
+        let invite = match full.full_chat {
+            tl::enums::ChatFull::ChannelFull(channel) => channel.exported_invite,
+            tl::enums::ChatFull::Full(chat) => chat.exported_invite,
+        };
+        Ok(match invite {
+            Some(tl::enums::ExportedChatInvite::ChatInviteExported(invite)) => Some(invite.link),
+            _ => None,
+        })
+    }
+
+    /// Fetches the identities the user can post as in a supergroup or
+    /// channel (`channels.getSendAs`).
+    ///
+    /// Besides the user, these are channels they own and, for anonymous
+    /// admins, the group itself. Other chats have none, so this returns an
+    /// empty list for them.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// or the chat is not found.
+    pub async fn get_send_as(&self, chat_id: i64) -> Result<Vec<SendAsPeer>, TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+        if !matches!(peer_ref.id.kind(), PeerKind::Channel) {
+            return Ok(Vec::new());
+        }
+
+        let tl::enums::channels::SendAsPeers::Peers(result) = client
+            .invoke(&tl::functions::channels::GetSendAs {
+                for_paid_reactions: false,
+                peer: tl::enums::InputPeer::from(peer_ref),
+            })
+            .await
+            .map_err(TelegramError::from)?;
+
+        let chats = result.chats.iter().filter_map(|chat| match chat {
+            tl::enums::Chat::Chat(c) => Some((c.id, c.title.clone(), false)),
+            tl::enums::Chat::Channel(c) => Some((c.id, c.title.clone(), false)),
+            _ => None,
+        });
+        let users = result.users.iter().filter_map(|user| match user {
+            tl::enums::User::User(u) => {
+                let name = [u.first_name.as_deref(), u.last_name.as_deref()]
+                    .into_iter()
+                    .flatten()
+                    .collect::<Vec<_>>()
+                    .join(" ");
+                Some((u.id, name, u.is_self))
+            },
+            tl::enums::User::Empty(_) => None,
+        });
+        let known: HashMap<i64, (String, bool)> = chats
+            .chain(users)
+            .map(|(id, name, is_self)| (id, (name, is_self)))
+            .collect();
+
+        Ok(result
+            .peers
+            .into_iter()
+            .map(|tl::enums::SendAsPeer::Peer(option)| {
+                let id = match option.peer {
+                    tl::enums::Peer::User(u) => u.user_id,
+                    tl::enums::Peer::Chat(c) => c.chat_id,
+                    tl::enums::Peer::Channel(c) => c.channel_id,
+                };
+                let (name, is_self) = known.get(&id).cloned().unwrap_or_default();
+                SendAsPeer {
+                    id,
+                    name,
+                    is_self,
+                    premium_required: option.premium_required,
+                }
+            })
+            .collect())
+    }
+
+    /// Lists up to `limit` members of a group or channel, caching each
+    /// one as a user.
+    ///
+    /// Channels only show their members to admins; Telegram refuses the
+    /// request otherwise.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// the chat is not found, or the member list is hidden.
+    pub async fn get_members(
+        &self,
+        chat_id: i64,
+        limit: usize,
+    ) -> Result<Vec<crate::types::User>, TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        let mut participants = client.iter_participants(peer_ref).limit(limit);
+        let mut members = Vec::new();
+        while let Some(participant) = participants.next().await.map_err(TelegramError::from)? {
+            let user = grammers_user_to_user(&participant.user);
+            self.cache().set_user(user.clone());
+            members.push(user);
+        }
+        debug!(chat_id, count = members.len(), "Loaded members");
+        Ok(members)
+    }
+
+    /// Sets a chat's auto-delete timer.
+    ///
+    /// New messages are deleted for everyone `period` seconds after they are
+    /// sent; 0 turns the timer off. In groups and channels this needs the
+    /// right to delete messages (see [`Chat::can_set_auto_delete`]).
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// the chat is not found, or the user lacks the right.
+    pub async fn set_auto_delete(&self, chat_id: i64, period: i32) -> Result<(), TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        info!("Setting auto-delete for chat {} to {}s", chat_id, period);
+
+        client
+            .invoke(&tl::functions::messages::SetHistoryTtl {
+                peer: tl::enums::InputPeer::from(peer_ref),
+                period,
+            })
+            .await
+            .map_err(TelegramError::from)?;
+
+        // Update cache
+        if let Some(mut chat) = self.cache().get_chat(chat_id) {
+            chat.auto_delete_period = period;
+            self.cache().set_chat(chat);
+        }
+
+        Ok(())
+    }
+
+    /// Archives or unarchives a chat. An archived chat is dropped from the
+    /// cache, as the dialog list only holds the main folder.
     ///
     /// # Arguments
     ///
@@ -223,6 +651,90 @@ impl TelegramClient {
             .await
             .map_err(TelegramError::from)?;
 
+        // Archived dialogs aren't part of the main list
+        if archive {
+            self.cache().remove_chat(chat_id);
+        }
+
+        Ok(())
+    }
+
+    /// Joins a public channel or supergroup, usually one found with
+    /// [`Self::resolve_chat`].
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// the chat is not found, or it isn't a channel or supergroup.
+    pub async fn join_chat(&self, chat_id: i64) -> Result<(), TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+        if !matches!(peer_ref.id.kind(), PeerKind::Channel) {
+            return Err(TelegramError::Refused {
+                code: "PEER_ID_INVALID".to_string(),
+                message: "Only channels and supergroups can be joined",
+            });
+        }
+
+        info!("Joining chat {}", chat_id);
+
+        client
+            .invoke(&tl::functions::channels::JoinChannel {
+                channel: tl::types::InputChannel {
+                    channel_id: peer_ref.id.bare_id(),
+                    access_hash: peer_ref.auth.hash(),
+                }
+                .into(),
+            })
+            .await
+            .map_err(TelegramError::from)?;
+
+        Ok(())
+    }
+
+    /// Leaves a group or channel and drops it from the cache.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// the chat is not found, or it is a private chat.
+    pub async fn leave_chat(&self, chat_id: i64) -> Result<(), TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        info!("Leaving chat {}", chat_id);
+
+        match peer_ref.id.kind() {
+            PeerKind::Channel => {
+                client
+                    .invoke(&tl::functions::channels::LeaveChannel {
+                        channel: tl::types::InputChannel {
+                            channel_id: peer_ref.id.bare_id(),
+                            access_hash: peer_ref.auth.hash(),
+                        }
+                        .into(),
+                    })
+                    .await
+            },
+            PeerKind::Chat => {
+                client
+                    .invoke(&tl::functions::messages::DeleteChatUser {
+                        revoke_history: false,
+                        chat_id: peer_ref.id.bare_id(),
+                        user_id: tl::enums::InputUser::UserSelf,
+                    })
+                    .await
+            },
+            PeerKind::User | PeerKind::UserSelf => {
+                return Err(TelegramError::Refused {
+                    code: "PEER_ID_INVALID".to_string(),
+                    message: "Only groups and channels can be left",
+                })
+            },
+        }
+        .map_err(TelegramError::from)?;
+
+        self.cache().remove_chat(chat_id);
         Ok(())
     }
 
@@ -248,19 +760,180 @@ impl TelegramClient {
             .await
             .map_err(TelegramError::from)?;
 
-        // Update cache
+        self.clear_unread(chat_id);
+        Ok(())
+    }
+
+    /// Marks several chats as read, returning how many were marked.
+    ///
+    /// Chats are marked in batches with a pause in between. A short flood
+    /// wait is sat out and the chat retried; a longer one stops the run, as
+    /// does a chat that can't be marked. The chats left over keep their
+    /// unread counts. The pauses add up, so callers should run this in the
+    /// background; `progress` is told how many are marked after each chat.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected or not authorized,
+    /// or the dialogs can't be fetched.
+    pub async fn mark_chats_as_read(
+        &self,
+        chat_ids: &[i64],
+        progress: &(dyn Fn(usize) + Sync),
+    ) -> Result<usize, TelegramError> {
+        let client = self.require_authorized().await?;
+
+        info!("Marking {} chats as read", chat_ids.len());
+
+        // One pass over the dialogs resolves every peer, instead of one pass
+        // per chat
+        let wanted: HashSet<i64> = chat_ids.iter().copied().collect();
+        let mut peers = Vec::with_capacity(wanted.len());
+        let mut dialogs = client.iter_dialogs();
+        while let Some(dialog) = dialogs.next().await.map_err(TelegramError::from)? {
+            let peer = dialog.peer();
+            let chat_id = peer.id().bare_id();
+            if wanted.contains(&chat_id) {
+                if let Some(peer_ref) = peer.to_ref().await {
+                    peers.push((chat_id, peer_ref));
+                }
+                if peers.len() == wanted.len() {
+                    break;
+                }
+            }
+        }
+
+        let mut marked = 0;
+        for (i, batch) in peers.chunks(MARK_READ_BATCH).enumerate() {
+            if i > 0 {
+                tokio::time::sleep(MARK_READ_PAUSE).await;
+            }
+            for &(chat_id, peer_ref) in batch {
+                loop {
+                    match client
+                        .mark_as_read(peer_ref)
+                        .await
+                        .map_err(TelegramError::from)
+                    {
+                        Ok(()) => {
+                            self.clear_unread(chat_id);
+                            marked += 1;
+                            progress(marked);
+                            break;
+                        },
+                        Err(e) => {
+                            let Some(delay) = flood_wait_delay(&e) else {
+                                warn!("Stopped marking chats read at chat {}: {}", chat_id, e);
+                                return Ok(marked);
+                            };
+                            warn!("Flood wait while marking chats read; pausing {:?}", delay);
+                            tokio::time::sleep(delay).await;
+                        },
+                    }
+                }
+            }
+        }
+
+        Ok(marked)
+    }
+
+    /// Clears a cached chat's unread count, mentions and missed calls.
+    fn clear_unread(&self, chat_id: i64) {
         if let Some(mut chat) = self.cache().get_chat(chat_id) {
             chat.unread_count = 0;
+            chat.missed_calls = 0;
+            chat.unread_mentions = 0;
             self.cache().set_chat(chat);
         }
+    }
 
-        Ok(())
+    /// Finds a user or public chat by username or phone number, so a
+    /// conversation can start even though it isn't in the dialogs yet.
+    ///
+    /// The chat (and, for a person, the user) is cached unless it already
+    /// is, and its peer kept for later calls; Telegram adds it to the dialogs once a message is
+    /// sent. Phone numbers only resolve if the owner's privacy settings
+    /// allow it.
+    ///
+    /// # Errors
+    ///
+    /// Returns [`TelegramError::HandleNotFound`] if no one has the username
+    /// or phone number, or an error if the client is not connected or not
+    /// authorized.
+    pub async fn resolve_chat(&self, handle: &PeerHandle) -> Result<Chat, TelegramError> {
+        let client = self.require_authorized().await?;
+        let not_found = || TelegramError::HandleNotFound(handle.to_string());
+        let map_err = |err: grammers_client::InvocationError| match TelegramError::from(err) {
+            TelegramError::Api(code) if HANDLE_NOT_FOUND_CODES.contains(&code.as_str()) => {
+                not_found()
+            },
+            err => err,
+        };
+
+        let (chat, peer_ref, user) = match handle {
+            PeerHandle::Username(name) => {
+                let peer = client
+                    .resolve_username(name)
+                    .await
+                    .map_err(map_err)?
+                    .ok_or_else(not_found)?;
+                let peer_ref = peer.to_ref().await.ok_or_else(not_found)?;
+                let chat = peer_to_chat(&peer, peer_ref.auth.hash());
+                (chat, peer_ref, grammers_peer_to_user(&peer))
+            },
+            PeerHandle::Phone(digits) => {
+                let tl::enums::contacts::ResolvedPeer::Peer(resolved) = client
+                    .invoke(&tl::functions::contacts::ResolvePhone {
+                        phone: digits.clone(),
+                    })
+                    .await
+                    .map_err(map_err)?;
+                let tl::enums::Peer::User(target) = resolved.peer else {
+                    return Err(not_found());
+                };
+                let (user, access_hash) = resolved
+                    .users
+                    .iter()
+                    .find_map(|raw| match raw {
+                        tl::enums::User::User(u) if u.id == target.user_id => {
+                            Some((tl_user_to_user(raw)?, u.access_hash.unwrap_or(0)))
+                        },
+                        _ => None,
+                    })
+                    .ok_or_else(not_found)?;
+                let peer_ref = PeerRef {
+                    id: PeerId::user(user.id),
+                    auth: PeerAuth::from_hash(access_hash),
+                };
+                (user_to_chat(&user, access_hash), peer_ref, Some(user))
+            },
+        };
+
+        debug!("Resolved {} to chat {}", handle, chat.id);
+        self.resolved_peers()
+            .write()
+            .await
+            .insert(chat.id, peer_ref);
+        if let Some(user) = user {
+            self.cache().set_user(user);
+        }
+        if self.cache().get_chat(chat.id).is_none() {
+            self.cache().set_chat(chat.clone());
+        }
+        Ok(chat)
     }
 
     /// Resolves a chat ID to a `PeerRef` for API calls.
     ///
-    /// First checks the session cache, then fetches dialogs if not found.
+    /// Uses a peer found by [`Self::resolve_chat`] or refreshed by
+    /// [`Self::refresh_peer_ref`] if there is one, and
+    /// otherwise walks the dialogs and uses the access hash the session has
+    /// stored for the peer. If Telegram later rejects that hash, see
+    /// [`Self::refresh_peer_ref`].
     pub(crate) async fn get_peer_ref(&self, chat_id: i64) -> Result<PeerRef, TelegramError> {
+        if let Some(peer_ref) = self.resolved_peers().read().await.get(&chat_id).copied() {
+            return Ok(peer_ref);
+        }
         let client = self.client().await?;
 
         // Try to resolve from the session
@@ -281,6 +954,131 @@ impl TelegramClient {
 
         Err(TelegramError::ChatNotFound(chat_id))
     }
+
+    /// Re-fetches a chat's peer info after Telegram rejected its access hash.
+    ///
+    /// Supergroup and channel access hashes can go stale (for example after
+    /// the session was restored or the user left and rejoined). This asks
+    /// for the one chat: a channel or supergroup by its cached hash, then
+    /// anything with a username by that. Only if both fail does it look
+    /// through the newest [`MAX_REFRESH_DIALOGS`] dialogs. The fresh peer is
+    /// kept for later calls and its hash stored in the cached chat.
+    pub(crate) async fn refresh_peer_ref(&self, chat_id: i64) -> Result<PeerRef, TelegramError> {
+        let client = self.client().await?;
+
+        info!("Refreshing access hash for chat {}", chat_id);
+
+        let cached = self.cache().get_chat(chat_id);
+        let mut peer_ref = None;
+        if let Some(chat) = cached
+            .as_ref()
+            .filter(|c| matches!(c.chat_type, ChatType::Channel | ChatType::Supergroup))
+        {
+            peer_ref = fetch_channel_ref(&client, chat).await;
+        }
+        if let Some(name) = cached
+            .as_ref()
+            .map(|c| c.username.as_str())
+            .filter(|name| peer_ref.is_none() && !name.is_empty())
+        {
+            peer_ref = match client.resolve_username(name).await {
+                Ok(Some(peer)) if peer.id().bare_id() == chat_id => peer.to_ref().await,
+                Ok(_) => None,
+                Err(e) => {
+                    debug!("Resolving @{} for chat {} failed: {}", name, chat_id, e);
+                    None
+                },
+            };
+        }
+        if peer_ref.is_none() {
+            let mut dialogs = client.iter_dialogs();
+            for _ in 0..MAX_REFRESH_DIALOGS {
+                let Some(dialog) = dialogs.next().await.map_err(TelegramError::from)? else {
+                    break;
+                };
+                if dialog.peer().id().bare_id() == chat_id {
+                    if cached.is_none() {
+                        self.cache().set_chat(dialog_to_chat(&dialog));
+                    }
+                    peer_ref = Some(dialog.peer_ref());
+                    break;
+                }
+            }
+        }
+        let peer_ref = peer_ref.ok_or(TelegramError::ChatNotFound(chat_id))?;
+
+        if let Some(mut chat) = self.cache().get_chat(chat_id) {
+            chat.access_hash = peer_ref.auth.hash();
+            self.cache().set_chat(chat);
+        }
+        self.resolved_peers()
+            .write()
+            .await
+            .insert(chat_id, peer_ref);
+        Ok(peer_ref)
+    }
+
+    /// Runs `op` against a chat's peer, retrying once with a refreshed
+    /// access hash if Telegram reports the peer as invalid.
+    pub(crate) async fn with_peer_retry<T, F, Fut>(
+        &self,
+        chat_id: i64,
+        op: F,
+    ) -> Result<T, TelegramError>
+    where
+        F: Fn(PeerRef) -> Fut,
+        Fut: Future<Output = Result<T, TelegramError>>,
+    {
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+        match op(peer_ref).await {
+            Err(TelegramError::PeerInvalid(reason)) => {
+                warn!("Chat {} rejected ({}), retrying", chat_id, reason);
+                let peer_ref = self.refresh_peer_ref(chat_id).await?;
+                op(peer_ref).await
+            },
+            result => result,
+        }
+    }
+}
+
+/// Asks Telegram for a channel or supergroup by its cached access hash
+/// (`channels.getChannels`), returning its peer with the hash Telegram has
+/// now, or `None` if that fails.
+async fn fetch_channel_ref(client: &Client, chat: &Chat) -> Option<PeerRef> {
+    let result = client
+        .invoke(&tl::functions::channels::GetChannels {
+            id: vec![tl::types::InputChannel {
+                channel_id: chat.id,
+                access_hash: chat.access_hash,
+            }
+            .into()],
+        })
+        .await;
+    let chats = match result {
+        Ok(tl::enums::messages::Chats::Chats(found)) => found.chats,
+        Ok(tl::enums::messages::Chats::Slice(found)) => found.chats,
+        Err(e) => {
+            debug!("Looking up channel {} failed: {}", chat.id, e);
+            return None;
+        },
+    };
+    chats.into_iter().find_map(|raw| match raw {
+        tl::enums::Chat::Channel(c) if c.id == chat.id && !c.min => Some(PeerRef {
+            id: PeerId::channel(c.id),
+            auth: PeerAuth::from_hash(c.access_hash?),
+        }),
+        _ => None,
+    })
+}
+
+/// Returns how long to wait before retrying after `error`, if it is a flood
+/// wait short enough to sit out.
+fn flood_wait_delay(error: &TelegramError) -> Option<Duration> {
+    let TelegramError::FloodWait(secs) = error else {
+        return None;
+    };
+    let delay = Duration::from_secs(u64::try_from(*secs).unwrap_or(1));
+    (delay <= MAX_FLOOD_WAIT).then_some(delay)
 }
 
 /// Converts a grammers Dialog to our Chat type.
@@ -292,24 +1090,65 @@ fn dialog_to_chat(dialog: &Dialog) -> Chat {
         .map(grammers_message_to_message);
 
     // Extract dialog-specific info from raw
-    let (unread_count, is_pinned, draft_message) = extract_dialog_info(&dialog.raw);
+    let (
+        unread_count,
+        unread_mentions,
+        is_pinned,
+        draft_message,
+        last_read_inbox_id,
+        auto_delete_period,
+    ) = extract_dialog_info(&dialog.raw);
+
+    // Only the last message is at hand, so at most one missed call shows
+    let missed_calls = i32::from(
+        unread_count > 0
+            && last_message
+                .as_ref()
+                .and_then(|m| m.content.call.as_ref())
+                .is_some_and(CallInfo::is_missed),
+    );
 
     // Get peer_ref for access_hash
-    let peer_ref = dialog.peer_ref();
-    let access_hash = peer_ref.auth.hash();
+    let access_hash = dialog.peer_ref().auth.hash();
+
+    Chat {
+        last_message: last_message.map(Box::new),
+        unread_count,
+        is_pinned,
+        is_muted: dialog_is_muted(&dialog.raw),
+        auto_delete_period,
+        missed_calls,
+        unread_mentions,
+        draft_message,
+        last_read_inbox_id,
+        ..peer_to_chat(peer, access_hash)
+    }
+}
 
+/// Builds a chat with no history from a grammers Peer, for peers that
+/// aren't (or aren't yet) in the dialogs.
+fn peer_to_chat(peer: &GrammersPeer, access_hash: i64) -> Chat {
     Chat {
         id: peer.id().bare_id(),
         chat_type: grammers_peer_type(peer),
         title: peer.name().unwrap_or("").to_string(),
         username: peer.username().map(ToString::to_string).unwrap_or_default(),
         photo_id: String::new(), // Photo handling requires additional work
-        last_message: last_message.map(Box::new),
-        unread_count,
-        is_pinned,
+        last_message: None,
+        unread_count: 0,
+        is_pinned: false,
         pin_order: 0,
-        is_muted: false, // Would need to check notification settings
-        draft_message,
+        is_muted: false,
+        auto_delete_period: 0,
+        can_set_auto_delete: can_set_auto_delete(peer),
+        default_permissions: default_permissions(peer),
+        can_edit_permissions: can_edit_permissions(peer),
+        is_read_only: is_read_only(peer),
+        linked_chat_id: 0,
+        member_count: member_count(peer),
+        missed_calls: 0,
+        unread_mentions: 0,
+        draft_message: String::new(),
         last_read_inbox_id: 0,
         last_read_outbox_id: 0,
         access_hash,
@@ -319,8 +1158,23 @@ fn dialog_to_chat(dialog: &Dialog) -> Chat {
     }
 }
 
-/// Extracts dialog-specific information from raw dialog data.
-fn extract_dialog_info(raw: &tl::enums::Dialog) -> (i32, bool, String) {
+/// Builds the private chat with a user found by phone number.
+fn user_to_chat(user: &crate::types::User, access_hash: i64) -> Chat {
+    Chat {
+        id: user.id,
+        chat_type: ChatType::Private,
+        title: user.get_display_name(),
+        username: user.username.clone(),
+        access_hash,
+        user_status: user.status,
+        ..Chat::default()
+    }
+}
+
+/// Extracts dialog-specific information from raw dialog data: unread count,
+/// unread mentions, pinned flag, draft text, the last read incoming message
+/// ID, and the auto-delete period.
+fn extract_dialog_info(raw: &tl::enums::Dialog) -> (i32, i32, bool, String, i64, i32) {
     match raw {
         tl::enums::Dialog::Dialog(d) => {
             let draft = d
@@ -335,12 +1189,217 @@ fn extract_dialog_info(raw: &tl::enums::Dialog) -> (i32, bool, String) {
                 })
                 .unwrap_or_default();
 
-            (d.unread_count, d.pinned, draft)
+            (
+                d.unread_count,
+                d.unread_mentions_count,
+                d.pinned,
+                draft,
+                i64::from(d.read_inbox_max_id),
+                d.ttl_period.unwrap_or(0),
+            )
+        },
+        tl::enums::Dialog::Folder(_) => (0, 0, false, String::new(), 0, 0),
+    }
+}
+
+/// Returns whether a dialog's notifications are muted right now.
+fn dialog_is_muted(raw: &tl::enums::Dialog) -> bool {
+    let tl::enums::Dialog::Dialog(d) = raw else {
+        return false;
+    };
+    let tl::enums::PeerNotifySettings::Settings(settings) = &d.notify_settings;
+    settings
+        .mute_until
+        .is_some_and(|until| i64::from(until) > chrono::Utc::now().timestamp())
+}
+
+/// Returns how many members a group or subscribers a channel has, if
+/// Telegram sent the count (0 otherwise).
+fn member_count(peer: &GrammersPeer) -> i32 {
+    match peer {
+        GrammersPeer::User(_) => 0,
+        GrammersPeer::Group(group) => match &group.raw {
+            tl::enums::Chat::Chat(chat) => chat.participants_count,
+            tl::enums::Chat::Channel(channel) => channel.participants_count.unwrap_or(0),
+            _ => 0,
+        },
+        GrammersPeer::Channel(channel) => channel.raw.participants_count.unwrap_or(0),
+    }
+}
+
+/// Converts a report reason to its TL form.
+const fn report_reason_to_tl(reason: ReportReason) -> tl::enums::ReportReason {
+    match reason {
+        ReportReason::Spam => tl::enums::ReportReason::InputReportReasonSpam,
+        ReportReason::Violence => tl::enums::ReportReason::InputReportReasonViolence,
+        ReportReason::Fake => tl::enums::ReportReason::InputReportReasonFake,
+        ReportReason::Other => tl::enums::ReportReason::InputReportReasonOther,
+    }
+}
+
+/// Returns whether the user may set the auto-delete timer in a chat.
+///
+/// Anyone can in a private chat; groups and channels need the creator or an
+/// admin allowed to delete messages.
+fn can_set_auto_delete(peer: &GrammersPeer) -> bool {
+    let can_delete = |rights: Option<&tl::enums::ChatAdminRights>| {
+        let Some(tl::enums::ChatAdminRights::Rights(r)) = rights else {
+            return false;
+        };
+        r.delete_messages
+    };
+
+    match peer {
+        GrammersPeer::User(_) => true,
+        GrammersPeer::Group(group) => match &group.raw {
+            tl::enums::Chat::Chat(chat) => chat.creator || can_delete(chat.admin_rights.as_ref()),
+            tl::enums::Chat::Channel(channel) => {
+                channel.creator || can_delete(channel.admin_rights.as_ref())
+            },
+            _ => false,
+        },
+        GrammersPeer::Channel(channel) => {
+            channel.raw.creator || can_delete(channel.raw.admin_rights.as_ref())
         },
-        tl::enums::Dialog::Folder(_) => (0, false, String::new()),
     }
 }
 
+/// Returns a group's default member permissions, or `None` for private
+/// chats and broadcast channels.
+fn default_permissions(peer: &GrammersPeer) -> Option<ChatPermissions> {
+    let rights = match peer {
+        GrammersPeer::User(_) => return None,
+        GrammersPeer::Group(group) => match &group.raw {
+            tl::enums::Chat::Chat(chat) => chat.default_banned_rights.as_ref(),
+            tl::enums::Chat::Channel(channel) => channel.default_banned_rights.as_ref(),
+            _ => return None,
+        },
+        GrammersPeer::Channel(channel) => {
+            if grammers_peer_type(peer) != ChatType::Supergroup {
+                return None;
+            }
+            channel.raw.default_banned_rights.as_ref()
+        },
+    };
+    Some(rights.map_or_else(ChatPermissions::default, permissions_from_banned_rights))
+}
+
+/// Returns whether the user may change a group's default permissions: the
+/// creator or an admin allowed to ban users.
+fn can_edit_permissions(peer: &GrammersPeer) -> bool {
+    let can_ban = |rights: Option<&tl::enums::ChatAdminRights>| {
+        let Some(tl::enums::ChatAdminRights::Rights(r)) = rights else {
+            return false;
+        };
+        r.ban_users
+    };
+
+    match peer {
+        GrammersPeer::User(_) => false,
+        GrammersPeer::Group(group) => match &group.raw {
+            tl::enums::Chat::Chat(chat) => chat.creator || can_ban(chat.admin_rights.as_ref()),
+            tl::enums::Chat::Channel(channel) => {
+                channel.creator || can_ban(channel.admin_rights.as_ref())
+            },
+            _ => false,
+        },
+        GrammersPeer::Channel(channel) => {
+            grammers_peer_type(peer) == ChatType::Supergroup
+                && (channel.raw.creator || can_ban(channel.raw.admin_rights.as_ref()))
+        },
+    }
+}
+
+/// Returns whether the user can't send messages in a chat: a broadcast
+/// channel they can't post in, or a group that has left them or bans
+/// sending. The creator and admins are never restricted.
+fn is_read_only(peer: &GrammersPeer) -> bool {
+    let bans_sending = |rights: Option<&tl::enums::ChatBannedRights>| {
+        rights.is_some_and(|r| !permissions_from_banned_rights(r).send_messages)
+    };
+
+    match peer {
+        GrammersPeer::User(_) => false,
+        GrammersPeer::Group(group) => match &group.raw {
+            tl::enums::Chat::Chat(chat) => {
+                chat.left
+                    || chat.deactivated
+                    || (!chat.creator
+                        && chat.admin_rights.is_none()
+                        && bans_sending(chat.default_banned_rights.as_ref()))
+            },
+            tl::enums::Chat::Channel(channel) => {
+                !channel.creator
+                    && channel.admin_rights.is_none()
+                    && (bans_sending(channel.banned_rights.as_ref())
+                        || bans_sending(channel.default_banned_rights.as_ref()))
+            },
+            _ => true,
+        },
+        GrammersPeer::Channel(channel) => {
+            let raw = &channel.raw;
+            if raw.creator {
+                return false;
+            }
+            if grammers_peer_type(peer) == ChatType::Supergroup {
+                raw.admin_rights.is_none()
+                    && (bans_sending(raw.banned_rights.as_ref())
+                        || bans_sending(raw.default_banned_rights.as_ref()))
+            } else {
+                let rights = raw.admin_rights.as_ref();
+                !matches!(rights, Some(tl::enums::ChatAdminRights::Rights(r)) if r.post_messages)
+            }
+        },
+    }
+}
+
+/// Converts Telegram's banned-rights flags (what members may *not* do) to
+/// permissions.
+pub(super) const fn permissions_from_banned_rights(
+    rights: &tl::enums::ChatBannedRights,
+) -> ChatPermissions {
+    let tl::enums::ChatBannedRights::Rights(r) = rights;
+    ChatPermissions {
+        send_messages: !(r.send_messages || r.send_plain),
+        send_media: !r.send_media,
+        add_members: !r.invite_users,
+        pin_messages: !r.pin_messages,
+        change_info: !r.change_info,
+    }
+}
+
+/// Converts permissions to Telegram's banned-rights flags.
+///
+/// Telegram splits media into finer flags (stickers, polls, voice notes and
+/// so on); they all follow `send_media`, and managing forum topics follows
+/// `change_info`.
+const fn permissions_to_banned_rights(permissions: ChatPermissions) -> tl::enums::ChatBannedRights {
+    let no_media = !permissions.send_media;
+    tl::enums::ChatBannedRights::Rights(tl::types::ChatBannedRights {
+        view_messages: false,
+        send_messages: !permissions.send_messages,
+        send_media: no_media,
+        send_stickers: no_media,
+        send_gifs: no_media,
+        send_games: no_media,
+        send_inline: no_media,
+        embed_links: no_media,
+        send_polls: no_media,
+        change_info: !permissions.change_info,
+        invite_users: !permissions.add_members,
+        pin_messages: !permissions.pin_messages,
+        manage_topics: !permissions.change_info,
+        send_photos: no_media,
+        send_videos: no_media,
+        send_roundvideos: no_media,
+        send_audios: no_media,
+        send_voices: no_media,
+        send_docs: no_media,
+        send_plain: !permissions.send_messages,
+        until_date: 0,
+    })
+}
+
 /// Maps grammers Peer to our `ChatType`.
 fn grammers_peer_type(peer: &GrammersPeer) -> ChatType {
     use grammers_session::types::ChannelKind;
@@ -517,6 +1576,53 @@ pub(crate) fn grammers_message_to_message(msg: &grammers_client::message::Messag
         )
     };
 
+    // Media grammers doesn't model would otherwise come through blank; the
+    // description goes in the caption, with any text kept alongside
+    let (content_type, caption) = describe_unmodelled_media(msg).unwrap_or((content_type, caption));
+
+    let poll = match msg.media() {
+        Some(grammers_client::media::Media::Poll(poll)) => {
+            Some(super::polls::tl_poll_to_poll(&poll.raw, &poll.raw_results))
+        },
+        _ => None,
+    };
+
+    // Calls and group events arrive as service messages with no media or
+    // text
+    let call = match msg.action() {
+        Some(tl::enums::MessageAction::PhoneCall(call)) => {
+            Some(phone_call_to_call_info(call, msg.outgoing()))
+        },
+        _ => None,
+    };
+    let service = msg
+        .action()
+        .and_then(|action| tl_action_to_service_action(action, sender_id));
+    let content_type = if call.is_some() {
+        MessageType::Call
+    } else if service.is_some() {
+        MessageType::Service
+    } else {
+        content_type
+    };
+
+    // Live locations move by editing the message, so each edit brings the
+    // new position here too
+    let location = match msg.media() {
+        Some(grammers_client::media::Media::Geo(geo)) => Some(Location {
+            latitude: geo.latitude(),
+            longitude: geo.longitude(),
+            ..Default::default()
+        }),
+        Some(grammers_client::media::Media::GeoLive(live)) => Some(Location {
+            latitude: live.latitude(),
+            longitude: live.longitude(),
+            live_period: live.period(),
+            heading: live.heading(),
+        }),
+        _ => None,
+    };
+
     // Use the public date() method which returns DateTime<Utc>
     let date = msg.date();
 
@@ -533,24 +1639,230 @@ pub(crate) fn grammers_message_to_message(msg: &grammers_client::message::Messag
             caption,
             entities: Vec::new(), // Would need to convert entities
             media,
-            location: None,
+            location,
             contact: None,
-            poll: None,
+            poll,
             sticker: None,
             animation: None,
             document: None,
+            call,
+            service,
         },
         date,
         edit_date,
         is_outgoing: msg.outgoing(),
+        mentions_me: msg.mentioned(),
+        media_unread: match &msg.raw {
+            tl::enums::Message::Message(raw) => raw.media_unread,
+            _ => false,
+        },
         is_channel_post: msg.post(),
         is_pinned: msg.pinned(),
         is_edited: edit_date.is_some(),
         is_forwarded: msg.forward_header().is_some(),
         reply_to_message_id: msg.reply_to_message_id().map_or(0, i64::from),
-        forward_info: None, // Would need to convert forward info
+        forward_info: match &msg.raw {
+            tl::enums::Message::Message(raw) => {
+                raw.fwd_from.as_ref().map(fwd_header_to_forward_info)
+            },
+            _ => None,
+        },
         views: msg.view_count().unwrap_or(0),
         media_album_id: msg.grouped_id().unwrap_or(0),
+        reactions: match &msg.raw {
+            tl::enums::Message::Message(raw) => raw
+                .reactions
+                .as_ref()
+                .map(super::updates::message_reactions)
+                .unwrap_or_default(),
+            _ => Vec::new(),
+        },
+        effect: match &msg.raw {
+            tl::enums::Message::Message(raw) => raw.effect,
+            _ => None,
+        },
+    }
+}
+
+/// Converts a raw forward header to our `ForwardInfo`.
+///
+/// Channel posts name the channel and post; forwards saved to Saved Messages
+/// name the chat they were saved from. Other forwards only name the sender.
+fn fwd_header_to_forward_info(header: &tl::enums::MessageFwdHeader) -> ForwardInfo {
+    let tl::enums::MessageFwdHeader::Header(h) = header;
+    let mut info = ForwardInfo {
+        date: chrono::DateTime::from_timestamp(i64::from(h.date), 0).unwrap_or_default(),
+        author_signature: h.post_author.clone().unwrap_or_default(),
+        from_name: h.from_name.clone().unwrap_or_default(),
+        ..ForwardInfo::default()
+    };
+    match &h.from_id {
+        Some(tl::enums::Peer::User(u)) => {
+            info.origin = ForwardOrigin::User;
+            info.from_user_id = u.user_id;
+        },
+        Some(tl::enums::Peer::Channel(c)) => {
+            info.origin = ForwardOrigin::Channel;
+            info.from_chat_id = c.channel_id;
+            info.message_id = h.channel_post.map_or(0, i64::from);
+        },
+        Some(tl::enums::Peer::Chat(c)) => {
+            info.origin = ForwardOrigin::Chat;
+            info.from_chat_id = c.chat_id;
+        },
+        None => info.origin = ForwardOrigin::HiddenUser,
+    }
+    if let (0, Some(peer), Some(id)) = (info.message_id, &h.saved_from_peer, h.saved_from_msg_id) {
+        info.from_chat_id = match peer {
+            tl::enums::Peer::User(u) => u.user_id,
+            tl::enums::Peer::Chat(c) => c.chat_id,
+            tl::enums::Peer::Channel(c) => c.channel_id,
+        };
+        info.message_id = i64::from(id);
+    }
+    info
+}
+
+/// Describes invoices, giveaways, and stories from the raw message, for
+/// the placeholder shown in their place.
+fn describe_unmodelled_media(
+    msg: &grammers_client::message::Message,
+) -> Option<(crate::types::MessageType, String)> {
+    use crate::types::MessageType;
+
+    let tl::enums::Message::Message(raw) = &msg.raw else {
+        return None;
+    };
+    let until = |date: i32| {
+        chrono::DateTime::from_timestamp(i64::from(date), 0)
+            .map(|t| t.format("%b %-d").to_string())
+            .unwrap_or_default()
+    };
+
+    match raw.media.as_ref()? {
+        tl::enums::MessageMedia::Invoice(invoice) => {
+            let price = format_price(invoice.total_amount, &invoice.currency);
+            let status = if invoice.receipt_msg_id.is_some() {
+                "paid"
+            } else {
+                "unpaid"
+            };
+            Some((
+                MessageType::Invoice,
+                format!("Invoice: {} \u{2014} {price}, {status}", invoice.title),
+            ))
+        },
+        tl::enums::MessageMedia::Giveaway(giveaway) => {
+            let prize = giveaway_prize(
+                giveaway.quantity,
+                giveaway.months,
+                giveaway.stars,
+                giveaway.prize_description.as_deref(),
+            );
+            Some((
+                MessageType::Giveaway,
+                format!("Giveaway: {prize}, ends {}", until(giveaway.until_date)),
+            ))
+        },
+        tl::enums::MessageMedia::GiveawayResults(results) => {
+            let prize = giveaway_prize(
+                results.winners_count,
+                results.months,
+                results.stars,
+                results.prize_description.as_deref(),
+            );
+            Some((MessageType::Giveaway, format!("Giveaway results: {prize}")))
+        },
+        tl::enums::MessageMedia::Story(story) => {
+            let what = if story.via_mention {
+                "Story mention"
+            } else {
+                "Story"
+            };
+            Some((MessageType::Story, format!("{what} #{}", story.id)))
+        },
+        _ => None,
+    }
+}
+
+/// Describes a giveaway's prize for `quantity` winners.
+fn giveaway_prize(
+    quantity: i32,
+    months: Option<i32>,
+    stars: Option<i64>,
+    description: Option<&str>,
+) -> String {
+    let prize = match (months, stars) {
+        (Some(months), _) => format!("{quantity} \u{00d7} Telegram Premium ({months} months)"),
+        (None, Some(stars)) => format!("{stars} Stars for {quantity} winners"),
+        (None, None) => format!("{quantity} winners"),
+    };
+    match description {
+        Some(extra) if !extra.is_empty() => format!("{prize} + {extra}"),
+        _ => prize,
+    }
+}
+
+/// Formats an amount in a currency's smallest unit, e.g. `999, "USD"` as
+/// `"9.99 USD"`.
+fn format_price(amount: i64, currency: &str) -> String {
+    // Currencies without a minor unit, including Telegram Stars
+    const WHOLE_UNITS: [&str; 6] = ["XTR", "JPY", "KRW", "VND", "CLP", "ISK"];
+
+    if WHOLE_UNITS.contains(&currency) {
+        return format!("{amount} {currency}");
+    }
+    let sign = if amount < 0 { "-" } else { "" };
+    let amount = amount.unsigned_abs();
+    format!("{sign}{}.{:02} {currency}", amount / 100, amount % 100)
+}
+
+/// Converts a service message's action to a group event, if it is one we
+/// show. Adding or removing oneself is joining or leaving.
+fn tl_action_to_service_action(
+    action: &tl::enums::MessageAction,
+    sender_id: i64,
+) -> Option<ServiceAction> {
+    use tl::enums::MessageAction as A;
+
+    Some(match action {
+        A::ChatCreate(a) => ServiceAction::Created(a.title.clone()),
+        A::ChannelCreate(a) => ServiceAction::Created(a.title.clone()),
+        A::ChatJoinedByLink(_) | A::ChatJoinedByRequest => ServiceAction::Joined,
+        A::ChatAddUser(a) if a.users == [sender_id] => ServiceAction::Joined,
+        A::ChatAddUser(a) => ServiceAction::MembersAdded(a.users.clone()),
+        A::ChatDeleteUser(a) if a.user_id == sender_id => ServiceAction::Left,
+        A::ChatDeleteUser(a) => ServiceAction::MemberRemoved(a.user_id),
+        A::ChatEditTitle(a) => ServiceAction::TitleChanged(a.title.clone()),
+        A::ChatEditPhoto(_) => ServiceAction::PhotoChanged,
+        A::ChatDeletePhoto => ServiceAction::PhotoRemoved,
+        A::PinMessage => ServiceAction::MessagePinned,
+        A::GroupCall(a) => a.duration.map_or(
+            ServiceAction::VideoChatStarted,
+            ServiceAction::VideoChatEnded,
+        ),
+        _ => return None,
+    })
+}
+
+/// Converts a call service message's action to a call log entry.
+fn phone_call_to_call_info(
+    call: &tl::types::MessageActionPhoneCall,
+    is_outgoing: bool,
+) -> CallInfo {
+    let duration = call.duration.unwrap_or(0);
+    let outcome = match call.reason {
+        Some(tl::enums::PhoneCallDiscardReason::Missed) => CallOutcome::Missed,
+        Some(tl::enums::PhoneCallDiscardReason::Busy) => CallOutcome::Declined,
+        // Hanging up before answering declines the call
+        Some(tl::enums::PhoneCallDiscardReason::Hangup) if duration == 0 => CallOutcome::Declined,
+        _ => CallOutcome::Ended,
+    };
+    CallInfo {
+        is_outgoing,
+        is_video: call.video,
+        duration,
+        outcome,
     }
 }
 
@@ -559,30 +1871,33 @@ pub(crate) fn grammers_message_to_message(msg: &grammers_client::message::Messag
 /// Returns None for groups/channels since they aren't users.
 pub(crate) fn grammers_peer_to_user(peer: &GrammersPeer) -> Option<crate::types::User> {
     match peer {
-        GrammersPeer::User(user) => {
-            // Use first_name and last_name from grammers User
-            let first_name = user.first_name().unwrap_or("").to_string();
-            let last_name = user.last_name().unwrap_or("").to_string();
-
-            Some(crate::types::User {
-                id: user.id().bare_id(),
-                first_name,
-                last_name,
-                username: user.username().map(ToString::to_string).unwrap_or_default(),
-                phone_number: String::new(), // Not available from peer
-                profile_photo_id: String::new(),
-                status: UserStatus::Offline, // Would need separate query
-                is_bot: user.is_bot(),
-                is_contact: false, // Not available from peer
-                is_mutual_contact: false,
-                is_verified: user.verified(),
-                is_premium: user.is_premium(),
-            })
-        },
+        GrammersPeer::User(user) => Some(grammers_user_to_user(user)),
         _ => None,
     }
 }
 
+/// Converts a grammers User to our User type.
+pub(crate) fn grammers_user_to_user(user: &GrammersUser) -> crate::types::User {
+    // Use first_name and last_name from grammers User
+    let first_name = user.first_name().unwrap_or("").to_string();
+    let last_name = user.last_name().unwrap_or("").to_string();
+
+    crate::types::User {
+        id: user.id().bare_id(),
+        first_name,
+        last_name,
+        username: user.username().map(ToString::to_string).unwrap_or_default(),
+        phone_number: String::new(), // Not available from peer
+        profile_photo_id: String::new(),
+        status: UserStatus::Offline, // Would need separate query
+        is_bot: user.is_bot(),
+        is_contact: false, // Not available from peer
+        is_mutual_contact: false,
+        is_verified: user.verified(),
+        is_premium: user.is_premium(),
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -596,4 +1911,41 @@ mod tests {
         assert_eq!(format!("{}", ChatType::Supergroup), "Supergroup");
         assert_eq!(format!("{}", ChatType::Channel), "Channel");
     }
+
+    #[test]
+    fn permissions_round_trip_through_banned_rights() {
+        let permissions = ChatPermissions {
+            send_messages: true,
+            send_media: false,
+            add_members: false,
+            pin_messages: true,
+            change_info: false,
+        };
+        let rights = permissions_to_banned_rights(permissions);
+
+        let tl::enums::ChatBannedRights::Rights(r) = &rights;
+        assert!(r.send_stickers && r.send_polls && !r.send_plain);
+        assert_eq!(permissions_from_banned_rights(&rights), permissions);
+    }
+
+    #[test]
+    fn prices_use_the_currency_minor_unit() {
+        assert_eq!(format_price(999, "USD"), "9.99 USD");
+        assert_eq!(format_price(1_200, "EUR"), "12.00 EUR");
+        assert_eq!(format_price(500, "XTR"), "500 XTR");
+        assert_eq!(
+            giveaway_prize(3, Some(6), None, Some("a mug")),
+            "3 \u{00d7} Telegram Premium (6 months) + a mug"
+        );
+    }
+
+    #[test]
+    fn only_short_flood_waits_are_sat_out() {
+        assert_eq!(
+            flood_wait_delay(&TelegramError::FloodWait(5)),
+            Some(Duration::from_secs(5))
+        );
+        assert_eq!(flood_wait_delay(&TelegramError::FloodWait(300)), None);
+        assert_eq!(flood_wait_delay(&TelegramError::Timeout), None);
+    }
 }
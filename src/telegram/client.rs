@@ -3,19 +3,22 @@
 //! This module provides the [`TelegramClient`] struct which wraps grammers
 //! to provide a high-level interface for Telegram operations.
 
-use std::sync::atomic::{AtomicBool, Ordering};
+use std::collections::HashMap;
+use std::sync::atomic::{AtomicBool, AtomicI64, Ordering};
 use std::sync::Arc;
 
 use grammers_client::client::{LoginToken, PasswordToken, UpdateStream, UpdatesConfiguration};
 use grammers_client::sender::SenderPoolFatHandle;
 use grammers_client::{Client, SenderPool};
 use grammers_session::storages::SqliteSession;
+use grammers_session::types::PeerRef;
 use grammers_session::updates::UpdatesLike;
-use tokio::sync::{mpsc, RwLock};
+use tokio::sync::{mpsc, Mutex, RwLock};
 use tokio::task::JoinHandle;
 use tracing::{debug, info};
 
 use super::error::TelegramError;
+use super::participants::SenderQueue;
 use crate::cache::SharedCache;
 use crate::types::{AuthState, Update};
 
@@ -76,6 +79,16 @@ pub struct TelegramClient {
 
     /// Receiver for raw updates from the sender pool
     updates_receiver: Arc<RwLock<Option<mpsc::UnboundedReceiver<UpdatesLike>>>>,
+
+    /// Unknown message senders waiting to be looked up in one batch
+    sender_queue: Arc<Mutex<SenderQueue>>,
+
+    /// Peers found by username or phone number that aren't in the dialogs
+    /// (yet), and peers refreshed after a stale access hash, by chat ID
+    resolved_peers: Arc<RwLock<HashMap<i64, PeerRef>>>,
+
+    /// The logged-in user's ID once looked up, or 0
+    my_id: Arc<AtomicI64>,
 }
 
 impl TelegramClient {
@@ -122,6 +135,9 @@ impl TelegramClient {
             pool_task: Arc::new(RwLock::new(None)),
             pool_handle: Arc::new(RwLock::new(None)),
             updates_receiver: Arc::new(RwLock::new(None)),
+            sender_queue: Arc::new(Mutex::new(SenderQueue::default())),
+            resolved_peers: Arc::new(RwLock::new(HashMap::new())),
+            my_id: Arc::new(AtomicI64::new(0)),
         }
     }
 
@@ -294,6 +310,16 @@ impl TelegramClient {
         &self.cache
     }
 
+    /// Gets the peers resolved by username or phone number.
+    pub(crate) const fn resolved_peers(&self) -> &Arc<RwLock<HashMap<i64, PeerRef>>> {
+        &self.resolved_peers
+    }
+
+    /// Gets the queue of senders waiting to be looked up.
+    pub(crate) const fn sender_queue(&self) -> &Arc<Mutex<SenderQueue>> {
+        &self.sender_queue
+    }
+
     /// Gets the API hash (needed for auth methods).
     pub(crate) fn api_hash(&self) -> &str {
         &self.api_hash
@@ -339,6 +365,24 @@ impl TelegramClient {
         self.password_token.write().await.take()
     }
 
+    /// Internal: Gets the logged-in user's ID, looking it up the first
+    /// time.
+    pub(crate) async fn my_id(&self) -> Option<i64> {
+        match self.my_id.load(Ordering::Relaxed) {
+            0 => {
+                let id = self.get_me().await.ok()?.id;
+                self.my_id.store(id, Ordering::Relaxed);
+                Some(id)
+            },
+            id => Some(id),
+        }
+    }
+
+    /// Internal: Forgets the logged-in user's ID, on logging out.
+    pub(crate) fn forget_my_id(&self) {
+        self.my_id.store(0, Ordering::Relaxed);
+    }
+
     /// Internal: Gets the update sender channel.
     pub(crate) async fn get_update_sender(&self) -> Option<mpsc::Sender<Update>> {
         self.update_tx.read().await.clone()
@@ -402,6 +446,9 @@ impl Clone for TelegramClient {
             pool_task: Arc::clone(&self.pool_task),
             pool_handle: Arc::clone(&self.pool_handle),
             updates_receiver: Arc::clone(&self.updates_receiver),
+            sender_queue: Arc::clone(&self.sender_queue),
+            resolved_peers: Arc::clone(&self.resolved_peers),
+            my_id: Arc::clone(&self.my_id),
         }
     }
 }
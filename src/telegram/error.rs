@@ -2,6 +2,11 @@
 //!
 //! This module provides a unified error type for all Telegram-related operations,
 //! converting from grammers library errors into a more ergonomic interface.
+//!
+//! Telegram reports failures as terse names such as `CHAT_WRITE_FORBIDDEN`.
+//! The ones a user can understand and act on are translated into
+//! [`TelegramError::Refused`], whose message says what happened; anything
+//! else is kept as [`TelegramError::Api`].
 
 use thiserror::Error;
 
@@ -29,6 +34,10 @@ pub enum TelegramError {
     #[error("Invalid phone number: {0}")]
     InvalidPhoneNumber(String),
 
+    /// Telegram has banned the phone number, so it can't sign in.
+    #[error("This phone number is banned from Telegram")]
+    PhoneNumberBanned,
+
     /// The verification code entered is incorrect.
     #[error("Invalid verification code")]
     InvalidCode,
@@ -61,6 +70,19 @@ pub enum TelegramError {
     #[error("Telegram API error: {0}")]
     Api(String),
 
+    /// Telegram refused the request for a reason worth telling the user.
+    #[error("{message}")]
+    Refused {
+        /// Telegram's name for the error, such as `CHAT_WRITE_FORBIDDEN`
+        code: String,
+        /// What the error means, in plain words
+        message: &'static str,
+    },
+
+    /// Slow mode is on and the user must wait before sending again.
+    #[error("Slow mode is on: you can send again in {0} seconds")]
+    SlowMode(i32),
+
     /// Flood wait error - too many requests.
     ///
     /// The client should wait the specified number of seconds before retrying.
@@ -75,14 +97,45 @@ pub enum TelegramError {
     #[error("Chat not found: {0}")]
     ChatNotFound(i64),
 
+    /// No account or public chat has this username or phone number (shown
+    /// as typed, e.g. `@name`).
+    #[error("No one on Telegram goes by {0}")]
+    HandleNotFound(String),
+
+    /// Telegram rejected the peer, usually because the access hash is stale.
+    ///
+    /// Re-fetching the dialog yields a fresh access hash.
+    #[error("Invalid peer: {0}")]
+    PeerInvalid(String),
+
     /// The requested message was not found.
     #[error("Message not found: {0}")]
     MessageNotFound(i64),
 
+    /// The message is too old to edit.
+    ///
+    /// Telegram only accepts edits for 48 hours after a message is sent.
+    #[error("Messages can only be edited within 48 hours of sending")]
+    EditTimeExpired,
+
+    /// The edit left the message as it was.
+    #[error("Nothing changed")]
+    MessageNotModified,
+
+    /// The message was sent by someone else.
+    #[error("This message can't be edited")]
+    MessageNotEditable,
+
     /// The message does not contain any media.
     #[error("Message {0} does not contain media")]
     NoMedia(i64),
 
+    /// The file reference used to download media has expired.
+    ///
+    /// Re-fetching the message yields a fresh reference.
+    #[error("File reference expired")]
+    FileReferenceExpired,
+
     /// The message does not contain a photo.
     #[error("Message {0} is not a photo")]
     NotAPhoto(i64),
@@ -109,6 +162,13 @@ impl TelegramError {
         matches!(self, Self::Network(_) | Self::Timeout | Self::FloodWait(_))
     }
 
+    /// Returns `true` if this error means the user can't send messages in
+    /// the chat at all, so the composer should be disabled.
+    #[must_use]
+    pub fn makes_chat_read_only(&self) -> bool {
+        matches!(self, Self::Refused { code, .. } if READ_ONLY_CODES.contains(&code.as_str()))
+    }
+
     /// Returns `true` if this error requires user action to resolve.
     ///
     /// Authentication-related errors require user input.
@@ -118,6 +178,7 @@ impl TelegramError {
             self,
             Self::AuthRequired
                 | Self::InvalidPhoneNumber(_)
+                | Self::PhoneNumberBanned
                 | Self::InvalidCode
                 | Self::InvalidPassword
                 | Self::SignUpRequired
@@ -142,12 +203,19 @@ impl From<grammers_client::InvocationError> for TelegramError {
                         }
                     }
                 }
+                if let Some(seconds) = error_message
+                    .strip_prefix("SLOWMODE_WAIT_")
+                    .and_then(|s| s.parse::<i32>().ok())
+                {
+                    return Self::SlowMode(seconds);
+                }
 
                 // Handle specific error codes
                 match error_message {
-                    "PHONE_NUMBER_INVALID" => {
-                        Self::InvalidPhoneNumber("Phone number format is invalid".into())
-                    },
+                    "PHONE_NUMBER_INVALID" => Self::InvalidPhoneNumber(
+                        "Telegram doesn't know it; check the country code".into(),
+                    ),
+                    "PHONE_NUMBER_BANNED" => Self::PhoneNumberBanned,
                     "PHONE_CODE_INVALID" | "PHONE_CODE_EXPIRED" | "PHONE_CODE_EMPTY" => {
                         Self::InvalidCode
                     },
@@ -156,7 +224,17 @@ impl From<grammers_client::InvocationError> for TelegramError {
                     "AUTH_KEY_UNREGISTERED" | "USER_DEACTIVATED" | "USER_DEACTIVATED_BAN" => {
                         Self::AuthRequired
                     },
-                    _ => Self::Api(error_message.to_string()),
+                    "CHANNEL_INVALID" | "PEER_ID_INVALID" | "CHAT_ID_INVALID" => {
+                        Self::PeerInvalid(error_message.to_string())
+                    },
+                    "MESSAGE_EDIT_TIME_EXPIRED" => Self::EditTimeExpired,
+                    "MESSAGE_NOT_MODIFIED" => Self::MessageNotModified,
+                    "MESSAGE_AUTHOR_REQUIRED" => Self::MessageNotEditable,
+                    // Also FILE_REFERENCE_<n>_EXPIRED for multi-media messages
+                    name if name.starts_with("FILE_REFERENCE_") && name.ends_with("_EXPIRED") => {
+                        Self::FileReferenceExpired
+                    },
+                    name => refused(name).unwrap_or_else(|| Self::Api(name.to_string())),
                 }
             },
             InvocationError::Io(io_err) => Self::Network(io_err.to_string()),
@@ -173,6 +251,84 @@ impl From<grammers_client::InvocationError> for TelegramError {
     }
 }
 
+/// Errors after which the user can't send messages in the chat.
+const READ_ONLY_CODES: [&str; 5] = [
+    "CHAT_WRITE_FORBIDDEN",
+    "CHAT_SEND_PLAIN_FORBIDDEN",
+    "USER_BANNED_IN_CHANNEL",
+    "CHAT_RESTRICTED",
+    "CHANNEL_PRIVATE",
+];
+
+/// Plain-words explanations of the Telegram errors users run into.
+const REFUSALS: [(&str, &str); 18] = [
+    (
+        "CHAT_WRITE_FORBIDDEN",
+        "You can't send messages in this chat",
+    ),
+    (
+        "CHAT_SEND_PLAIN_FORBIDDEN",
+        "Text messages aren't allowed in this chat",
+    ),
+    (
+        "USER_BANNED_IN_CHANNEL",
+        "Your account is limited: you can't send messages in groups and channels",
+    ),
+    ("CHAT_RESTRICTED", "This chat is restricted"),
+    (
+        "CHANNEL_PRIVATE",
+        "This channel is private, or you were removed from it",
+    ),
+    (
+        "PEER_FLOOD",
+        "Telegram has limited your account for messaging too many people; try again later",
+    ),
+    (
+        "USER_IS_BLOCKED",
+        "You can't message this user: one of you has blocked the other",
+    ),
+    ("YOU_BLOCKED_USER", "Unblock this user to message them"),
+    (
+        "USER_PRIVACY_RESTRICTED",
+        "This user's privacy settings don't allow that",
+    ),
+    ("INPUT_USER_DEACTIVATED", "This account has been deleted"),
+    ("CHAT_ADMIN_REQUIRED", "Only admins can do that"),
+    (
+        "CHAT_FORWARDS_RESTRICTED",
+        "This chat doesn't allow forwarding its messages",
+    ),
+    (
+        "MESSAGE_DELETE_FORBIDDEN",
+        "This message can't be deleted for everyone",
+    ),
+    ("MESSAGE_TOO_LONG", "The message is too long"),
+    ("MEDIA_CAPTION_TOO_LONG", "The caption is too long"),
+    ("MESSAGE_EMPTY", "The message is empty"),
+    ("PREMIUM_ACCOUNT_REQUIRED", "That needs Telegram Premium"),
+    (
+        "SEND_AS_PEER_INVALID",
+        "You can't post as that identity here any more",
+    ),
+];
+
+/// Translates a Telegram error name the user can act on.
+fn refused(code: &str) -> Option<TelegramError> {
+    let message = REFUSALS
+        .iter()
+        .find(|(name, _)| *name == code)
+        .map(|(_, message)| *message)
+        // CHAT_SEND_PHOTOS_FORBIDDEN, CHAT_SEND_VOICES_FORBIDDEN and so on
+        .or_else(|| {
+            (code.starts_with("CHAT_SEND_") && code.ends_with("_FORBIDDEN"))
+                .then_some("This chat doesn't allow that kind of message")
+        })?;
+    Some(TelegramError::Refused {
+        code: code.to_string(),
+        message,
+    })
+}
+
 impl From<grammers_client::SignInError> for TelegramError {
     fn from(err: grammers_client::SignInError) -> Self {
         use grammers_client::SignInError;
@@ -207,6 +363,23 @@ mod tests {
         assert!(!TelegramError::AuthRequired.is_recoverable());
     }
 
+    #[test]
+    fn test_refusals_are_explained() {
+        let err = refused("CHAT_WRITE_FORBIDDEN").unwrap();
+        assert_eq!(err.to_string(), "You can't send messages in this chat");
+        assert!(err.makes_chat_read_only());
+
+        let err = refused("CHAT_SEND_VOICES_FORBIDDEN").unwrap();
+        assert_eq!(
+            err.to_string(),
+            "This chat doesn't allow that kind of message"
+        );
+        assert!(!err.makes_chat_read_only());
+
+        assert!(!refused("PEER_FLOOD").unwrap().makes_chat_read_only());
+        assert!(refused("SOMETHING_NEW").is_none());
+    }
+
     #[test]
     fn test_requires_user_action() {
         assert!(TelegramError::AuthRequired.requires_user_action());
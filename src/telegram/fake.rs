@@ -0,0 +1,1121 @@
+//! In-memory [`TelegramApi`](super::TelegramApi) for driving the UI in tests.
+//!
+//! [`FakeTelegram`] keeps chats and history in memory, walks through the
+//! same login states as the real client (phone, code, optional password),
+//! records every call that would change something on Telegram, and can push
+//! incoming messages through the update channel.
+
+use std::collections::HashMap;
+use std::future::ready;
+use std::path::{Path, PathBuf};
+use std::sync::{Arc, Mutex};
+
+use chrono::{DateTime, Utc};
+use tokio::sync::mpsc;
+
+use super::api::{
+    ApiResult, AuthService, BoxFuture, DialogService, MediaService, MessageService, UpdateService,
+};
+use super::error::TelegramError;
+use crate::cache::SharedCache;
+use crate::types::{
+    AuthState, Chat, ChatPermissions, Message, PeerHandle, PollVoters, ReportReason, SearchFilter,
+    SendAsPeer, Update, UpdateData, UpdateType, User,
+};
+
+/// The login code the fake accepts.
+pub const LOGIN_CODE: &str = "12345";
+
+/// A call that would have changed something on Telegram.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum Call {
+    /// A text message was sent, as `send_as` if set
+    SendMessage {
+        chat_id: i64,
+        text: String,
+        reply_to: Option<i64>,
+        send_as: Option<i64>,
+    },
+    /// A file was sent, an image as a photo if `as_photo`
+    SendFile {
+        chat_id: i64,
+        caption: String,
+        path: PathBuf,
+        as_photo: bool,
+    },
+    /// Images were sent as an album
+    SendAlbum {
+        chat_id: i64,
+        caption: String,
+        paths: Vec<PathBuf>,
+    },
+    /// A message was edited
+    EditMessage {
+        chat_id: i64,
+        message_id: i64,
+        text: String,
+    },
+    /// Messages were deleted
+    DeleteMessages { chat_id: i64, message_ids: Vec<i64> },
+    /// A message was reacted to; no reaction means it was taken back
+    React {
+        chat_id: i64,
+        message_id: i64,
+        reaction: Option<String>,
+    },
+    /// Messages were forwarded, with or without the original author
+    Forward {
+        from_chat_id: i64,
+        to_chat_id: i64,
+        message_ids: Vec<i64>,
+        drop_author: bool,
+    },
+    /// A typing notification was sent
+    SendTyping(i64),
+    /// A voice or video message was marked as played
+    MarkPlayed { chat_id: i64, message_id: i64 },
+    /// A chat was muted or unmuted
+    Mute { chat_id: i64, mute: bool },
+    /// A chat was archived or unarchived
+    Archive { chat_id: i64, archive: bool },
+    /// A group or channel was left
+    Leave(i64),
+    /// A public channel or supergroup was joined
+    Join(i64),
+    /// A chat's auto-delete timer was set
+    SetAutoDelete { chat_id: i64, period: i32 },
+    /// A poll vote was cast; no options means it was retracted
+    Vote {
+        chat_id: i64,
+        message_id: i64,
+        options: Vec<Vec<u8>>,
+    },
+    /// A group's default permissions were set
+    SetPermissions {
+        chat_id: i64,
+        permissions: ChatPermissions,
+    },
+    /// A chat was reported
+    ReportChat { chat_id: i64, reason: ReportReason },
+    /// Messages were reported
+    ReportMessages {
+        chat_id: i64,
+        message_ids: Vec<i64>,
+        reason: ReportReason,
+    },
+    /// A chat was marked read
+    MarkAsRead(i64),
+    /// Presence was reported
+    SetOnline(bool),
+    /// The user logged out
+    LogOut,
+    /// A media download was started
+    Download {
+        chat_id: i64,
+        message_id: i64,
+        save_to: Option<PathBuf>,
+    },
+    /// A message's media was fetched to play it
+    Fetch { chat_id: i64, message_id: i64 },
+}
+
+#[derive(Debug)]
+struct State {
+    auth: AuthState,
+    password: Option<String>,
+    chats: Vec<Chat>,
+    /// History per chat, oldest first
+    history: HashMap<i64, Vec<Message>>,
+    next_message_id: i64,
+    /// Voters per poll option identifier
+    voters: HashMap<Vec<u8>, Vec<User>>,
+    /// The logged-in user
+    me: User,
+    /// Identities the user can post as, per chat
+    send_as: HashMap<i64, Vec<SendAsPeer>>,
+    members: HashMap<i64, Vec<User>>,
+    /// Chats not in the dialogs that can be found by username or phone
+    strangers: HashMap<PeerHandle, Chat>,
+    calls: Vec<Call>,
+}
+
+/// In-memory Telegram backend.
+pub struct FakeTelegram {
+    cache: SharedCache,
+    state: Mutex<State>,
+    update_tx: Mutex<Option<mpsc::Sender<Update>>>,
+    /// Chats whose text messages wait until their hold is released
+    holds: Mutex<HashMap<i64, Arc<tokio::sync::Mutex<()>>>>,
+}
+
+impl FakeTelegram {
+    /// Creates a backend waiting for a phone number, with no chats.
+    #[must_use]
+    pub fn new(cache: SharedCache) -> Self {
+        Self {
+            cache,
+            state: Mutex::new(State {
+                auth: AuthState::WaitPhoneNumber,
+                password: None,
+                chats: Vec::new(),
+                history: HashMap::new(),
+                next_message_id: 1000,
+                voters: HashMap::new(),
+                me: User {
+                    id: 1,
+                    first_name: "Me".to_string(),
+                    ..Default::default()
+                },
+                send_as: HashMap::new(),
+                members: HashMap::new(),
+                strangers: HashMap::new(),
+                calls: Vec::new(),
+            }),
+            update_tx: Mutex::new(None),
+            holds: Mutex::new(HashMap::new()),
+        }
+    }
+
+    /// Starts out logged in.
+    #[must_use]
+    pub fn logged_in(self) -> Self {
+        self.state().auth = AuthState::Ready;
+        self
+    }
+
+    /// Requires a two-factor password after the login code.
+    #[must_use]
+    pub fn with_password(self, password: &str) -> Self {
+        self.state().password = Some(password.to_string());
+        self
+    }
+
+    /// Adds a chat with existing history (oldest first).
+    #[must_use]
+    pub fn with_chat(self, chat: Chat, history: Vec<Message>) -> Self {
+        {
+            let mut state = self.state();
+            state.history.insert(chat.id, history);
+            state.chats.push(chat);
+        }
+        self
+    }
+
+    /// Sets who voted for the poll option identified by `option`.
+    #[must_use]
+    pub fn with_voters(self, option: &[u8], users: Vec<User>) -> Self {
+        self.state().voters.insert(option.to_vec(), users);
+        self
+    }
+
+    /// Sets the logged-in user.
+    #[must_use]
+    pub fn with_me(self, user: User) -> Self {
+        self.state().me = user;
+        self
+    }
+
+    /// Sets the identities the user can post as in a chat.
+    #[must_use]
+    pub fn with_send_as(self, chat_id: i64, options: Vec<SendAsPeer>) -> Self {
+        self.state().send_as.insert(chat_id, options);
+        self
+    }
+
+    /// Sets the members listed for a group or channel.
+    #[must_use]
+    pub fn with_members(self, chat_id: i64, members: Vec<User>) -> Self {
+        self.state().members.insert(chat_id, members);
+        self
+    }
+
+    /// Adds a chat with existing history (oldest first) that isn't in the
+    /// dialogs but can be found by `handle`; it joins the dialogs once a
+    /// message is sent to it or it is joined.
+    #[must_use]
+    pub fn with_stranger(self, handle: PeerHandle, chat: Chat, history: Vec<Message>) -> Self {
+        {
+            let mut state = self.state();
+            state.history.insert(chat.id, history);
+            state.strangers.insert(handle, chat);
+        }
+        self
+    }
+
+    /// Sets the channel incoming messages are sent to.
+    pub fn set_update_channel(&self, tx: mpsc::Sender<Update>) {
+        *self.update_tx.lock().unwrap() = Some(tx);
+    }
+
+    /// Holds text messages sent to `chat_id` until the returned guard is
+    /// dropped, like a slow connection to that chat.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the chat is already held.
+    #[must_use]
+    pub fn hold_sends(&self, chat_id: i64) -> tokio::sync::OwnedMutexGuard<()> {
+        let hold = Arc::clone(self.holds.lock().unwrap().entry(chat_id).or_default());
+        hold.try_lock_owned().expect("chat already held")
+    }
+
+    /// Returns the calls made so far.
+    #[must_use]
+    pub fn calls(&self) -> Vec<Call> {
+        self.state().calls.clone()
+    }
+
+    /// Returns a chat's history, oldest first.
+    #[must_use]
+    pub fn history(&self, chat_id: i64) -> Vec<Message> {
+        self.state()
+            .history
+            .get(&chat_id)
+            .cloned()
+            .unwrap_or_default()
+    }
+
+    /// Delivers a message from `sender_id`, updating the cache the way the
+    /// real update loop does, and sends a `NewMessage` update.
+    ///
+    /// # Panics
+    ///
+    /// Panics if no update channel is set or it is closed.
+    pub async fn receive(&self, chat_id: i64, sender_id: i64, text: &str) -> Message {
+        let message = self.append(chat_id, sender_id, text, false);
+
+        self.cache.add_message(chat_id, message.clone());
+        if let Some(mut chat) = self.cache.get_chat(chat_id) {
+            chat.has_new_message = true;
+            chat.unread_count += 1;
+            chat.last_message = Some(Box::new(message.clone()));
+            self.cache.set_chat(chat);
+        }
+
+        let tx = self.update_tx.lock().unwrap().clone();
+        tx.expect("no update channel")
+            .send(Update {
+                update_type: UpdateType::NewMessage,
+                chat_id,
+                message: Some(Box::new(message.clone())),
+                data: UpdateData::None,
+            })
+            .await
+            .expect("update channel closed");
+        message
+    }
+
+    /// Delivers the update stream's copy of a message the user sent, the
+    /// way the real update loop does.
+    ///
+    /// # Panics
+    ///
+    /// Panics if the message isn't in the chat's history, or no update
+    /// channel is set or it is closed.
+    pub async fn echo(&self, chat_id: i64, message_id: i64) {
+        let message = self
+            .history(chat_id)
+            .into_iter()
+            .find(|m| m.id == message_id)
+            .expect("no such message");
+        let replaced = self.cache.settle_send(chat_id, message.clone());
+
+        let tx = self.update_tx.lock().unwrap().clone();
+        tx.expect("no update channel")
+            .send(Update {
+                update_type: UpdateType::NewMessage,
+                chat_id,
+                message: Some(Box::new(message)),
+                data: replaced.map_or(UpdateData::None, UpdateData::Integer),
+            })
+            .await
+            .expect("update channel closed");
+    }
+
+    /// Reads `chat_id` up to `max_id` as if on another device, updating
+    /// the cache the way the real update loop does, and sends a
+    /// `ChatReadInbox` update.
+    ///
+    /// # Panics
+    ///
+    /// Panics if no update channel is set or it is closed.
+    pub async fn read_elsewhere(&self, chat_id: i64, max_id: i64) {
+        let unread = self
+            .history(chat_id)
+            .iter()
+            .filter(|m| !m.is_outgoing && m.id > max_id)
+            .count();
+        if let Some(mut chat) = self.cache.get_chat(chat_id) {
+            chat.last_read_inbox_id = chat.last_read_inbox_id.max(max_id);
+            chat.unread_count = i32::try_from(unread).unwrap_or(i32::MAX);
+            if unread == 0 {
+                chat.has_new_message = false;
+            }
+            self.cache.set_chat(chat);
+        }
+
+        let tx = self.update_tx.lock().unwrap().clone();
+        tx.expect("no update channel")
+            .send(Update {
+                update_type: UpdateType::ChatReadInbox,
+                chat_id,
+                message: None,
+                data: UpdateData::Integer(max_id),
+            })
+            .await
+            .expect("update channel closed");
+    }
+
+    fn state(&self) -> std::sync::MutexGuard<'_, State> {
+        self.state.lock().unwrap()
+    }
+
+    fn require_ready(&self) -> Result<(), TelegramError> {
+        if self.state().auth == AuthState::Ready {
+            Ok(())
+        } else {
+            Err(TelegramError::AuthRequired)
+        }
+    }
+
+    fn record(&self, call: Call) {
+        self.state().calls.push(call);
+    }
+
+    /// Adds a message to a chat's history.
+    fn append(&self, chat_id: i64, sender_id: i64, text: &str, outgoing: bool) -> Message {
+        let mut state = self.state();
+        state.next_message_id += 1;
+        let mut message = Message {
+            id: state.next_message_id,
+            chat_id,
+            sender_id,
+            date: Utc::now(),
+            is_outgoing: outgoing,
+            ..Default::default()
+        };
+        message.content.text = text.to_string();
+        state
+            .history
+            .entry(chat_id)
+            .or_default()
+            .push(message.clone());
+        message
+    }
+
+    /// Sends a message as the user and caches it like the real client: an
+    /// optimistic copy first, replaced once the send is confirmed.
+    fn send(&self, chat_id: i64, text: &str) -> Result<Message, TelegramError> {
+        self.require_ready()?;
+        if !self.state().history.contains_key(&chat_id) {
+            return Err(TelegramError::ChatNotFound(chat_id));
+        }
+        {
+            // The first message turns a resolved stranger into a dialog
+            let mut state = self.state();
+            if !state.chats.iter().any(|c| c.id == chat_id) {
+                let stranger = state.strangers.values().find(|c| c.id == chat_id).cloned();
+                state.chats.extend(stranger);
+            }
+        }
+        let random_id = self.state().next_message_id;
+        let mut optimistic = Message {
+            chat_id,
+            is_outgoing: true,
+            ..Default::default()
+        };
+        optimistic.content.text = text.to_string();
+        self.cache.begin_send(random_id, optimistic);
+
+        let message = self.append(chat_id, 0, text, true);
+        self.cache.confirm_send(random_id, message.id);
+        self.cache.settle_send(chat_id, message.clone());
+        Ok(message)
+    }
+
+    /// Returns up to `limit` of the newest messages matching `keep`.
+    fn page(
+        &self,
+        chat_id: i64,
+        limit: usize,
+        keep: impl Fn(&Message) -> bool,
+    ) -> Result<Vec<Message>, TelegramError> {
+        self.require_ready()?;
+        let state = self.state();
+        let history = state
+            .history
+            .get(&chat_id)
+            .ok_or(TelegramError::ChatNotFound(chat_id))?;
+        let matching: Vec<Message> = history.iter().filter(|m| keep(m)).cloned().collect();
+        // Newest first, like the real client
+        Ok(matching.into_iter().rev().take(limit).collect())
+    }
+
+    fn forward(
+        &self,
+        from_chat_id: i64,
+        to_chat_id: i64,
+        message_ids: &[i64],
+        drop_author: bool,
+    ) -> Result<Vec<Message>, TelegramError> {
+        self.require_ready()?;
+        self.record(Call::Forward {
+            from_chat_id,
+            to_chat_id,
+            message_ids: message_ids.to_vec(),
+            drop_author,
+        });
+        let originals = self.history(from_chat_id);
+        message_ids
+            .iter()
+            .map(|id| {
+                let original = originals
+                    .iter()
+                    .find(|m| m.id == *id)
+                    .ok_or(TelegramError::MessageNotFound(*id))?;
+                let mut copy = self.send(to_chat_id, &original.content.text)?;
+                copy.is_forwarded = !drop_author;
+                Ok(copy)
+            })
+            .collect()
+    }
+}
+
+impl AuthService for FakeTelegram {
+    fn get_auth_state(&self) -> BoxFuture<'_, AuthState> {
+        Box::pin(ready(self.state().auth))
+    }
+
+    fn request_login_code<'a>(&'a self, phone: &'a str) -> ApiResult<'a, ()> {
+        let result = if phone.trim().is_empty() {
+            Err(TelegramError::InvalidPhoneNumber(phone.to_string()))
+        } else {
+            self.state().auth = AuthState::WaitCode;
+            Ok(())
+        };
+        Box::pin(ready(result))
+    }
+
+    fn sign_in<'a>(&'a self, code: &'a str) -> ApiResult<'a, ()> {
+        let mut state = self.state();
+        let result = if code != LOGIN_CODE {
+            Err(TelegramError::InvalidCode)
+        } else if state.password.is_some() {
+            state.auth = AuthState::WaitPassword;
+            Err(TelegramError::PasswordRequired)
+        } else {
+            state.auth = AuthState::Ready;
+            Ok(())
+        };
+        Box::pin(ready(result))
+    }
+
+    fn check_password<'a>(&'a self, password: &'a str) -> ApiResult<'a, ()> {
+        let mut state = self.state();
+        let result = if state.password.as_deref() == Some(password) {
+            state.auth = AuthState::Ready;
+            Ok(())
+        } else {
+            Err(TelegramError::InvalidPassword)
+        };
+        Box::pin(ready(result))
+    }
+
+    fn get_me(&self) -> ApiResult<'_, User> {
+        let result = self.require_ready().map(|()| self.state().me.clone());
+        Box::pin(ready(result))
+    }
+
+    fn log_out(&self) -> ApiResult<'_, ()> {
+        let result = self.require_ready().map(|()| {
+            self.state().auth = AuthState::WaitPhoneNumber;
+            self.record(Call::LogOut);
+        });
+        Box::pin(ready(result))
+    }
+}
+
+impl DialogService for FakeTelegram {
+    fn get_dialogs(&self) -> ApiResult<'_, Vec<Chat>> {
+        let result = self.require_ready().map(|()| {
+            let chats = self.state().chats.clone();
+            for chat in &chats {
+                self.cache.set_chat(chat.clone());
+            }
+            chats
+        });
+        Box::pin(ready(result))
+    }
+
+    fn resolve_chat<'a>(&'a self, handle: &'a PeerHandle) -> ApiResult<'a, Chat> {
+        let result = self.require_ready().and_then(|()| {
+            let mut state = self.state();
+            let chat = state
+                .strangers
+                .get(handle)
+                .or_else(|| {
+                    let PeerHandle::Username(name) = handle else {
+                        return None;
+                    };
+                    state.chats.iter().find(|c| c.username == *name)
+                })
+                .cloned()
+                .ok_or_else(|| TelegramError::HandleNotFound(handle.to_string()))?;
+            state.history.entry(chat.id).or_default();
+            if self.cache.get_chat(chat.id).is_none() {
+                self.cache.set_chat(chat.clone());
+            }
+            Ok(chat)
+        });
+        Box::pin(ready(result))
+    }
+
+    fn search_global_chats<'a>(&'a self, query: &'a str, limit: usize) -> ApiResult<'a, Vec<Chat>> {
+        let result = self.require_ready().map(|()| {
+            let state = self.state();
+            let query = query.to_lowercase();
+            let mut strangers: Vec<&Chat> = state.strangers.values().collect();
+            strangers.sort_by_key(|c| c.id);
+            state
+                .chats
+                .iter()
+                .chain(strangers)
+                .filter(|c| {
+                    c.title.to_lowercase().contains(&query)
+                        || c.username.to_lowercase().contains(&query)
+                })
+                .take(limit)
+                .cloned()
+                .collect()
+        });
+        Box::pin(ready(result))
+    }
+
+    fn mute_chat(&self, chat_id: i64, mute: bool) -> ApiResult<'_, ()> {
+        self.record(Call::Mute { chat_id, mute });
+        if let Some(mut chat) = self.cache.get_chat(chat_id) {
+            chat.is_muted = mute;
+            self.cache.set_chat(chat);
+        }
+        Box::pin(ready(Ok(())))
+    }
+
+    fn mute_chat_for(&self, chat_id: i64, _duration: chrono::Duration) -> ApiResult<'_, ()> {
+        self.mute_chat(chat_id, true)
+    }
+
+    fn archive_chat(&self, chat_id: i64, archive: bool) -> ApiResult<'_, ()> {
+        let result = self.require_ready().map(|()| {
+            self.record(Call::Archive { chat_id, archive });
+            if archive {
+                self.state().chats.retain(|c| c.id != chat_id);
+                self.cache.remove_chat(chat_id);
+            }
+        });
+        Box::pin(ready(result))
+    }
+
+    fn join_chat(&self, chat_id: i64) -> ApiResult<'_, ()> {
+        let result = self.require_ready().and_then(|()| {
+            let mut state = self.state();
+            if !state.chats.iter().any(|c| c.id == chat_id) {
+                let stranger = state
+                    .strangers
+                    .values()
+                    .find(|c| c.id == chat_id)
+                    .cloned()
+                    .ok_or(TelegramError::ChatNotFound(chat_id))?;
+                state.chats.push(stranger);
+            }
+            drop(state);
+            self.record(Call::Join(chat_id));
+            Ok(())
+        });
+        Box::pin(ready(result))
+    }
+
+    fn leave_chat(&self, chat_id: i64) -> ApiResult<'_, ()> {
+        let result = self.require_ready().and_then(|()| {
+            let mut state = self.state();
+            let before = state.chats.len();
+            state.chats.retain(|c| c.id != chat_id);
+            if state.chats.len() == before {
+                return Err(TelegramError::ChatNotFound(chat_id));
+            }
+            drop(state);
+            self.record(Call::Leave(chat_id));
+            self.cache.remove_chat(chat_id);
+            Ok(())
+        });
+        Box::pin(ready(result))
+    }
+
+    fn set_auto_delete(&self, chat_id: i64, period: i32) -> ApiResult<'_, ()> {
+        let result = self.require_ready().map(|()| {
+            self.record(Call::SetAutoDelete { chat_id, period });
+            if let Some(mut chat) = self.cache.get_chat(chat_id) {
+                chat.auto_delete_period = period;
+                self.cache.set_chat(chat);
+            }
+        });
+        Box::pin(ready(result))
+    }
+
+    fn get_linked_chat(&self, chat_id: i64) -> ApiResult<'_, Option<i64>> {
+        let result = self.require_ready().and_then(|()| {
+            let state = self.state();
+            let chat = state
+                .chats
+                .iter()
+                .find(|c| c.id == chat_id)
+                .ok_or(TelegramError::ChatNotFound(chat_id))?;
+            Ok((chat.linked_chat_id != 0).then_some(chat.linked_chat_id))
+        });
+        Box::pin(ready(result))
+    }
+
+    fn get_invite_link(&self, chat_id: i64) -> ApiResult<'_, Option<String>> {
+        let result = self.require_ready().and_then(|()| {
+            let state = self.state();
+            let chat = state
+                .chats
+                .iter()
+                .find(|c| c.id == chat_id)
+                .ok_or(TelegramError::ChatNotFound(chat_id))?;
+            Ok((!chat.username.is_empty()).then(|| format!("https://t.me/{}", chat.username)))
+        });
+        Box::pin(ready(result))
+    }
+
+    fn get_send_as(&self, chat_id: i64) -> ApiResult<'_, Vec<SendAsPeer>> {
+        let result = self.require_ready().map(|()| {
+            self.state()
+                .send_as
+                .get(&chat_id)
+                .cloned()
+                .unwrap_or_default()
+        });
+        Box::pin(ready(result))
+    }
+
+    fn get_members(&self, chat_id: i64, limit: usize) -> ApiResult<'_, Vec<User>> {
+        let result = self.require_ready().map(|()| {
+            let state = self.state();
+            let members = state.members.get(&chat_id).cloned().unwrap_or_default();
+            members.into_iter().take(limit).collect()
+        });
+        Box::pin(ready(result))
+    }
+
+    fn set_chat_permissions(
+        &self,
+        chat_id: i64,
+        permissions: ChatPermissions,
+    ) -> ApiResult<'_, ()> {
+        let result = self.require_ready().map(|()| {
+            self.record(Call::SetPermissions {
+                chat_id,
+                permissions,
+            });
+            if let Some(mut chat) = self.cache.get_chat(chat_id) {
+                chat.default_permissions = Some(permissions);
+                self.cache.set_chat(chat);
+            }
+        });
+        Box::pin(ready(result))
+    }
+
+    fn report_chat(&self, chat_id: i64, reason: ReportReason) -> ApiResult<'_, ()> {
+        let result = self
+            .require_ready()
+            .map(|()| self.record(Call::ReportChat { chat_id, reason }));
+        Box::pin(ready(result))
+    }
+
+    fn mark_as_read(&self, chat_id: i64) -> ApiResult<'_, ()> {
+        self.record(Call::MarkAsRead(chat_id));
+        if let Some(mut chat) = self.cache.get_chat(chat_id) {
+            chat.unread_count = 0;
+            chat.missed_calls = 0;
+            chat.unread_mentions = 0;
+            self.cache.set_chat(chat);
+        }
+        Box::pin(ready(Ok(())))
+    }
+
+    fn mark_chats_as_read<'a>(
+        &'a self,
+        chat_ids: &'a [i64],
+        progress: &'a (dyn Fn(usize) + Sync),
+    ) -> ApiResult<'a, usize> {
+        Box::pin(async move {
+            for (i, &chat_id) in chat_ids.iter().enumerate() {
+                self.mark_as_read(chat_id).await?;
+                progress(i + 1);
+            }
+            Ok(chat_ids.len())
+        })
+    }
+}
+
+impl MessageService for FakeTelegram {
+    fn get_messages(
+        &self,
+        chat_id: i64,
+        limit: usize,
+        offset_id: Option<i64>,
+    ) -> ApiResult<'_, Vec<Message>> {
+        let result = self.page(chat_id, limit, |m| offset_id.map_or(true, |id| m.id < id));
+        Box::pin(ready(result))
+    }
+
+    fn get_messages_before_date(
+        &self,
+        chat_id: i64,
+        limit: usize,
+        date: DateTime<Utc>,
+    ) -> ApiResult<'_, Vec<Message>> {
+        Box::pin(ready(self.page(chat_id, limit, |m| m.date < date)))
+    }
+
+    fn search_messages<'a>(
+        &'a self,
+        chat_id: Option<i64>,
+        query: &'a str,
+        filter: Option<SearchFilter>,
+        limit: usize,
+    ) -> ApiResult<'a, Vec<Message>> {
+        let result = self.require_ready().and_then(|()| {
+            let state = self.state();
+            if let Some(chat_id) = chat_id {
+                if !state.history.contains_key(&chat_id) {
+                    return Err(TelegramError::ChatNotFound(chat_id));
+                }
+            }
+            let query = query.to_lowercase();
+            let mut found: Vec<Message> = state
+                .history
+                .iter()
+                .filter(|(id, _)| chat_id.map_or(true, |chat_id| **id == chat_id))
+                .flat_map(|(_, history)| history.iter())
+                .filter(|m| m.content.text.to_lowercase().contains(&query))
+                .filter(|m| filter.map_or(true, |f| f.matches(m)))
+                .cloned()
+                .collect();
+            found.sort_by_key(|m| std::cmp::Reverse(m.date));
+            found.truncate(limit);
+            Ok(found)
+        });
+        Box::pin(ready(result))
+    }
+
+    fn send_message<'a>(
+        &'a self,
+        chat_id: i64,
+        text: &'a str,
+        reply_to: Option<i64>,
+        send_as: Option<i64>,
+    ) -> ApiResult<'a, Message> {
+        let hold = self.holds.lock().unwrap().get(&chat_id).cloned();
+        Box::pin(async move {
+            if let Some(hold) = hold {
+                drop(hold.lock().await);
+            }
+            self.send(chat_id, text).map(|mut message| {
+                message.reply_to_message_id = reply_to.unwrap_or(0);
+                self.record(Call::SendMessage {
+                    chat_id,
+                    text: text.to_string(),
+                    reply_to,
+                    send_as,
+                });
+                message
+            })
+        })
+    }
+
+    fn edit_message<'a>(
+        &'a self,
+        chat_id: i64,
+        message_id: i64,
+        new_text: &'a str,
+    ) -> ApiResult<'a, Message> {
+        let result = self.require_ready().and_then(|()| {
+            let mut state = self.state();
+            let message = state
+                .history
+                .get_mut(&chat_id)
+                .and_then(|h| h.iter_mut().find(|m| m.id == message_id))
+                .ok_or(TelegramError::MessageNotFound(message_id))?;
+            if !message.is_outgoing {
+                return Err(TelegramError::MessageNotEditable);
+            }
+            if !message.can_edit(Utc::now()) {
+                return Err(TelegramError::EditTimeExpired);
+            }
+            message.content.text = new_text.to_string();
+            message.is_edited = true;
+            message.edit_date = Some(Utc::now());
+            let message = message.clone();
+            state.calls.push(Call::EditMessage {
+                chat_id,
+                message_id,
+                text: new_text.to_string(),
+            });
+            drop(state);
+            self.cache.update_message(chat_id, message.clone());
+            Ok(message)
+        });
+        Box::pin(ready(result))
+    }
+
+    fn send_reaction<'a>(
+        &'a self,
+        chat_id: i64,
+        message_id: i64,
+        reaction: Option<&'a str>,
+    ) -> ApiResult<'a, Message> {
+        let result = self.require_ready().and_then(|()| {
+            let mut state = self.state();
+            let message = state
+                .history
+                .get_mut(&chat_id)
+                .and_then(|h| h.iter_mut().find(|m| m.id == message_id))
+                .ok_or(TelegramError::MessageNotFound(message_id))?;
+            message.set_my_reaction(reaction);
+            let message = message.clone();
+            state.calls.push(Call::React {
+                chat_id,
+                message_id,
+                reaction: reaction.map(ToString::to_string),
+            });
+            drop(state);
+            self.cache.update_message(chat_id, message.clone());
+            Ok(message)
+        });
+        Box::pin(ready(result))
+    }
+
+    fn delete_messages<'a>(
+        &'a self,
+        chat_id: i64,
+        message_ids: &'a [i64],
+        _revoke: bool,
+    ) -> ApiResult<'a, ()> {
+        let result = self.require_ready().map(|()| {
+            let mut state = self.state();
+            if let Some(history) = state.history.get_mut(&chat_id) {
+                history.retain(|m| !message_ids.contains(&m.id));
+            }
+            state.calls.push(Call::DeleteMessages {
+                chat_id,
+                message_ids: message_ids.to_vec(),
+            });
+        });
+        Box::pin(ready(result))
+    }
+
+    fn forward_messages<'a>(
+        &'a self,
+        from_chat_id: i64,
+        to_chat_id: i64,
+        message_ids: &'a [i64],
+    ) -> ApiResult<'a, Vec<Message>> {
+        Box::pin(ready(self.forward(
+            from_chat_id,
+            to_chat_id,
+            message_ids,
+            false,
+        )))
+    }
+
+    fn forward_messages_without_author<'a>(
+        &'a self,
+        from_chat_id: i64,
+        to_chat_id: i64,
+        message_ids: &'a [i64],
+    ) -> ApiResult<'a, ()> {
+        let result = self
+            .forward(from_chat_id, to_chat_id, message_ids, true)
+            .map(drop);
+        Box::pin(ready(result))
+    }
+
+    fn send_typing(&self, chat_id: i64) -> ApiResult<'_, ()> {
+        self.record(Call::SendTyping(chat_id));
+        Box::pin(ready(Ok(())))
+    }
+
+    fn mark_played(&self, chat_id: i64, message_id: i64) -> ApiResult<'_, ()> {
+        let result = self.require_ready().map(|()| {
+            self.record(Call::MarkPlayed {
+                chat_id,
+                message_id,
+            });
+        });
+        Box::pin(ready(result))
+    }
+
+    fn vote_poll<'a>(
+        &'a self,
+        chat_id: i64,
+        message_id: i64,
+        options: &'a [Vec<u8>],
+    ) -> ApiResult<'a, ()> {
+        let result = self.require_ready().map(|()| {
+            self.record(Call::Vote {
+                chat_id,
+                message_id,
+                options: options.to_vec(),
+            });
+            let cached = self
+                .cache
+                .get_messages(chat_id)
+                .into_iter()
+                .find(|m| m.id == message_id);
+            if let Some(mut message) = cached {
+                if let Some(poll) = message.content.poll.as_mut() {
+                    let chosen: Vec<usize> = poll
+                        .options
+                        .iter()
+                        .enumerate()
+                        .filter(|(_, o)| options.contains(&o.option))
+                        .map(|(i, _)| i)
+                        .collect();
+                    poll.apply_vote(&chosen);
+                    self.cache.update_message(chat_id, message);
+                }
+            }
+        });
+        Box::pin(ready(result))
+    }
+
+    /// Pages are `limit` voters long; the offset is the index of the next
+    /// voter.
+    fn get_poll_voters<'a>(
+        &'a self,
+        _chat_id: i64,
+        _message_id: i64,
+        option: &'a [u8],
+        offset: Option<&'a str>,
+        limit: usize,
+    ) -> ApiResult<'a, PollVoters> {
+        let result = self.require_ready().map(|()| {
+            let all = self.state().voters.get(option).cloned().unwrap_or_default();
+            let start = offset.and_then(|o| o.parse().ok()).unwrap_or(0);
+            let end = (start + limit).min(all.len());
+            PollVoters {
+                users: all.get(start..end).unwrap_or_default().to_vec(),
+                total: i32::try_from(all.len()).unwrap_or(i32::MAX),
+                next_offset: (end < all.len()).then(|| end.to_string()),
+            }
+        });
+        Box::pin(ready(result))
+    }
+
+    fn report_messages<'a>(
+        &'a self,
+        chat_id: i64,
+        message_ids: &'a [i64],
+        reason: ReportReason,
+    ) -> ApiResult<'a, ()> {
+        let result = self.require_ready().map(|()| {
+            self.record(Call::ReportMessages {
+                chat_id,
+                message_ids: message_ids.to_vec(),
+                reason,
+            });
+        });
+        Box::pin(ready(result))
+    }
+}
+
+impl MediaService for FakeTelegram {
+    fn send_file<'a>(
+        &'a self,
+        chat_id: i64,
+        text: &'a str,
+        path: &'a Path,
+        as_photo: bool,
+        _reply_to: Option<i64>,
+    ) -> ApiResult<'a, Message> {
+        let result = self.send(chat_id, text).map(|message| {
+            self.record(Call::SendFile {
+                chat_id,
+                caption: text.to_string(),
+                path: path.to_path_buf(),
+                as_photo,
+            });
+            message
+        });
+        Box::pin(ready(result))
+    }
+
+    fn send_album<'a>(
+        &'a self,
+        chat_id: i64,
+        text: &'a str,
+        paths: &'a [PathBuf],
+        _reply_to: Option<i64>,
+    ) -> ApiResult<'a, Vec<Message>> {
+        // The caption travels with the first item, as on Telegram
+        let result = paths
+            .iter()
+            .enumerate()
+            .map(|(i, _)| self.send(chat_id, if i == 0 { text } else { "" }))
+            .collect::<Result<Vec<_>, _>>()
+            .map(|messages| {
+                self.record(Call::SendAlbum {
+                    chat_id,
+                    caption: text.to_string(),
+                    paths: paths.to_vec(),
+                });
+                messages
+            });
+        Box::pin(ready(result))
+    }
+
+    fn fetch_media<'a>(
+        &'a self,
+        message: &'a Message,
+        download_dir: &'a Path,
+    ) -> ApiResult<'a, PathBuf> {
+        let result = self.require_ready().map(|()| {
+            self.record(Call::Fetch {
+                chat_id: message.chat_id,
+                message_id: message.id,
+            });
+            download_dir.join(format!("{}_{}", message.chat_id, message.id))
+        });
+        Box::pin(ready(result))
+    }
+
+    fn spawn_media_download(
+        self: Arc<Self>,
+        message: Message,
+        _download_dir: PathBuf,
+        save_to: Option<PathBuf>,
+    ) {
+        self.record(Call::Download {
+            chat_id: message.chat_id,
+            message_id: message.id,
+            save_to,
+        });
+    }
+}
+
+impl UpdateService for FakeTelegram {
+    fn set_online(&self, online: bool) -> ApiResult<'_, ()> {
+        self.record(Call::SetOnline(online));
+        Box::pin(ready(Ok(())))
+    }
+
+    fn is_update_loop_running(&self) -> bool {
+        // Updates are pushed with `receive`; there is no loop to start
+        true
+    }
+
+    fn run_update_loop(&self) -> ApiResult<'_, ()> {
+        Box::pin(ready(Ok(())))
+    }
+}
@@ -0,0 +1,278 @@
+//! Fan-out of Telegram updates to any number of subscribers.
+//!
+//! The client streams updates into a single channel, which only one consumer
+//! can read. [`UpdateHub`] reads that channel and copies each update into a
+//! buffered channel per subscriber, so the UI, the recorder and any future
+//! consumer each see every update without taking them from one another.
+//!
+//! A subscriber that joins late is first sent the latest state-like update
+//! for each chat (title, read state, user status and so on), so it doesn't
+//! start out of date. Events such as new messages are not replayed.
+//!
+//! No update is ever dropped: when a subscriber's buffer is full the hub waits
+//! for it to make room, which in turn holds back the client's stream. A
+//! missed new message or deletion would otherwise leave the cache wrong
+//! until the chat is reopened. Subscribers are dropped once their receiver is.
+
+use std::collections::HashMap;
+use std::sync::{Arc, Mutex, MutexGuard};
+
+use tokio::sync::mpsc;
+use tokio::task::JoinHandle;
+use tracing::debug;
+
+use crate::types::{Update, UpdateType};
+
+/// Updates buffered per subscriber by default.
+pub const SUBSCRIBER_BUFFER: usize = 256;
+
+/// Returns `true` for updates that describe the current state of a chat or
+/// user rather than an event, and so are worth replaying to late subscribers.
+const fn is_state(kind: UpdateType) -> bool {
+    matches!(
+        kind,
+        UpdateType::ChatTitle
+            | UpdateType::ChatPhoto
+            | UpdateType::ChatReadInbox
+            | UpdateType::ChatReadOutbox
+            | UpdateType::ChatUnreadCount
+            | UpdateType::ChatDraftMessage
+            | UpdateType::ChatLastMessage
+            | UpdateType::ChatPosition
+            | UpdateType::ChatAutoDelete
+            | UpdateType::ChatPermissions
+            | UpdateType::UserStatus
+    )
+}
+
+/// A consumer of updates.
+struct Subscriber {
+    /// Who subscribed, for logging
+    name: &'static str,
+    tx: mpsc::Sender<Update>,
+}
+
+#[derive(Default)]
+struct Inner {
+    subscribers: Vec<Subscriber>,
+    /// Latest state-like update per kind and chat, with its sequence number
+    latest: HashMap<(UpdateType, i64), (u64, Update)>,
+    /// Sequence number of the next update
+    next_seq: u64,
+}
+
+/// Copies Telegram updates to every subscriber.
+///
+/// Cloning the hub gives another handle to the same subscribers.
+#[derive(Clone)]
+pub struct UpdateHub {
+    inner: Arc<Mutex<Inner>>,
+    /// Updates buffered per subscriber
+    capacity: usize,
+}
+
+impl Default for UpdateHub {
+    fn default() -> Self {
+        Self::new()
+    }
+}
+
+impl UpdateHub {
+    /// Creates a hub buffering [`SUBSCRIBER_BUFFER`] updates per subscriber.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::with_capacity(SUBSCRIBER_BUFFER)
+    }
+
+    /// Creates a hub buffering `capacity` updates per subscriber.
+    ///
+    /// # Panics
+    ///
+    /// Panics if `capacity` is 0.
+    #[must_use]
+    pub fn with_capacity(capacity: usize) -> Self {
+        assert!(capacity > 0, "update hub needs a buffer");
+        Self {
+            inner: Arc::new(Mutex::new(Inner::default())),
+            capacity,
+        }
+    }
+
+    fn inner(&self) -> MutexGuard<'_, Inner> {
+        self.inner
+            .lock()
+            .unwrap_or_else(std::sync::PoisonError::into_inner)
+    }
+
+    /// Adds a subscriber and returns its receiver, already holding the
+    /// latest state-like updates in the order they arrived.
+    ///
+    /// `name` identifies the subscriber in logs.
+    #[must_use]
+    pub fn subscribe(&self, name: &'static str) -> mpsc::Receiver<Update> {
+        let mut inner = self.inner();
+        let mut replay: Vec<&(u64, Update)> = inner.latest.values().collect();
+        replay.sort_by_key(|(seq, _)| *seq);
+
+        let (tx, rx) = mpsc::channel(self.capacity + replay.len());
+        for (_, update) in replay {
+            // Can't fail: the channel has room for every replayed update
+            let _ = tx.try_send(update.clone());
+        }
+        debug!(subscriber = name, "Subscribed to updates");
+        inner.subscribers.push(Subscriber { name, tx });
+        rx
+    }
+
+    /// Returns how many subscribers are still listening.
+    #[must_use]
+    pub fn subscriber_count(&self) -> usize {
+        self.inner()
+            .subscribers
+            .iter()
+            .filter(|s| !s.tx.is_closed())
+            .count()
+    }
+
+    /// Sends `update` to every subscriber, waiting for room in any buffer
+    /// that is full.
+    pub async fn publish(&self, update: Update) {
+        // Send outside the lock, so a subscriber can join while another is
+        // being waited on
+        for (name, tx) in self.record(&update) {
+            if tx.send(update.clone()).await.is_err() {
+                debug!(subscriber = name, "Unsubscribed from updates");
+            }
+        }
+    }
+
+    /// Remembers `update` for late subscribers, forgets subscribers that
+    /// have gone, and returns the ones to send it to.
+    fn record(&self, update: &Update) -> Vec<(&'static str, mpsc::Sender<Update>)> {
+        let mut inner = self.inner();
+        let seq = inner.next_seq;
+        inner.next_seq += 1;
+        if is_state(update.update_type) {
+            inner
+                .latest
+                .insert((update.update_type, update.chat_id), (seq, update.clone()));
+        }
+
+        inner.subscribers.retain(|subscriber| {
+            let open = !subscriber.tx.is_closed();
+            if !open {
+                debug!(subscriber = subscriber.name, "Unsubscribed from updates");
+            }
+            open
+        });
+        inner
+            .subscribers
+            .iter()
+            .map(|subscriber| (subscriber.name, subscriber.tx.clone()))
+            .collect()
+    }
+
+    /// Publishes every update received on `rx` until it closes.
+    pub fn spawn(&self, mut rx: mpsc::Receiver<Update>) -> JoinHandle<()> {
+        let hub = self.clone();
+        tokio::spawn(async move {
+            while let Some(update) = rx.recv().await {
+                hub.publish(update).await;
+            }
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::types::UpdateData;
+
+    fn update(update_type: UpdateType, chat_id: i64, data: UpdateData) -> Update {
+        Update {
+            update_type,
+            chat_id,
+            message: None,
+            data,
+        }
+    }
+
+    fn drain(rx: &mut mpsc::Receiver<Update>) -> Vec<(UpdateType, i64)> {
+        std::iter::from_fn(|| rx.try_recv().ok())
+            .map(|u| (u.update_type, u.chat_id))
+            .collect()
+    }
+
+    #[tokio::test]
+    async fn every_subscriber_sees_every_update() {
+        let hub = UpdateHub::new();
+        let mut first = hub.subscribe("first");
+        let mut second = hub.subscribe("second");
+
+        hub.publish(update(UpdateType::NewMessage, 1, UpdateData::None))
+            .await;
+        hub.publish(update(UpdateType::MessageDeleted, 2, UpdateData::None))
+            .await;
+
+        let expected = vec![(UpdateType::NewMessage, 1), (UpdateType::MessageDeleted, 2)];
+        assert_eq!(drain(&mut first), expected);
+        assert_eq!(drain(&mut second), expected);
+    }
+
+    #[tokio::test]
+    async fn late_subscribers_get_the_latest_state_but_not_events() {
+        let hub = UpdateHub::new();
+        hub.publish(update(UpdateType::NewMessage, 1, UpdateData::None))
+            .await;
+        hub.publish(update(
+            UpdateType::ChatTitle,
+            1,
+            UpdateData::String("Old".to_string()),
+        ))
+        .await;
+        hub.publish(update(
+            UpdateType::ChatUnreadCount,
+            2,
+            UpdateData::Integer(3),
+        ))
+        .await;
+        hub.publish(update(
+            UpdateType::ChatTitle,
+            1,
+            UpdateData::String("New".to_string()),
+        ))
+        .await;
+
+        let mut late = hub.subscribe("late");
+        let replayed: Vec<Update> = std::iter::from_fn(|| late.try_recv().ok()).collect();
+        assert_eq!(replayed.len(), 2);
+        assert_eq!(replayed[0].update_type, UpdateType::ChatUnreadCount);
+        assert!(matches!(&replayed[1].data, UpdateData::String(t) if t == "New"));
+    }
+
+    #[tokio::test]
+    async fn a_full_subscriber_is_waited_for_and_a_closed_one_forgotten() {
+        let hub = UpdateHub::with_capacity(1);
+        let mut slow = hub.subscribe("slow");
+        let gone = hub.subscribe("gone");
+        drop(gone);
+
+        hub.publish(update(UpdateType::NewMessage, 1, UpdateData::None))
+            .await;
+        assert_eq!(hub.subscriber_count(), 1);
+
+        // The buffer is full, so the next update waits rather than drops
+        let publisher = hub.clone();
+        let pending = tokio::spawn(async move {
+            publisher
+                .publish(update(UpdateType::NewMessage, 2, UpdateData::None))
+                .await;
+        });
+        tokio::task::yield_now().await;
+        assert!(!pending.is_finished());
+
+        assert_eq!(slow.recv().await.map(|u| u.chat_id), Some(1));
+        pending.await.unwrap();
+        assert_eq!(drain(&mut slow), vec![(UpdateType::NewMessage, 2)]);
+    }
+}
@@ -3,16 +3,67 @@
 //! This module provides methods for downloading and managing media files:
 //! - Downloading photos
 //! - Downloading documents (future)
+//! - Retrying failed downloads in the background
+//! - Refreshing expired file references
 //! - Opening media files with system viewer
+//! - Saving copies to the downloads directory under readable names
 
 use std::path::{Path, PathBuf};
+use std::sync::Arc;
+use std::time::Duration;
 
+use grammers_client::Client;
+use grammers_session::types::PeerRef;
 use tokio::fs;
-use tracing::{debug, info};
+use tracing::{debug, info, warn};
 
+use super::chats::grammers_message_to_message;
 use super::client::TelegramClient;
 use super::error::TelegramError;
-use crate::types::Message;
+use crate::types::{
+    DownloadStatus, FileDownload, FileDownloadState, Message, MessageType, Update, UpdateData,
+    UpdateType,
+};
+
+/// Attempts made for a media download before giving up.
+const DOWNLOAD_ATTEMPTS: u32 = 3;
+
+/// Returns `true` if a download failed because its file reference expired.
+///
+/// The download API reports transfer failures as I/O errors, so besides the
+/// dedicated variant this also recognises the RPC error name in the text.
+fn is_file_reference_expired(error: &TelegramError) -> bool {
+    match error {
+        TelegramError::FileReferenceExpired => true,
+        TelegramError::Io(msg) | TelegramError::Api(msg) | TelegramError::Network(msg) => {
+            msg.contains("FILE_REFERENCE_") && msg.contains("EXPIRED")
+        },
+        _ => false,
+    }
+}
+
+/// Returns how long to wait before retrying a download that failed with
+/// `error` on attempt number `attempt` (starting at 1), or `None` if the
+/// error is not worth retrying.
+///
+/// Delays double from one second; a flood wait uses the server's delay.
+fn download_retry_delay(attempt: u32, error: &TelegramError) -> Option<Duration> {
+    if attempt >= DOWNLOAD_ATTEMPTS {
+        return None;
+    }
+    match error {
+        TelegramError::FloodWait(secs) => {
+            Some(Duration::from_secs(u64::try_from(*secs).unwrap_or(1)))
+        },
+        // Transfer errors surface as I/O errors; each attempt re-fetches the
+        // message, which also refreshes an expired file reference
+        TelegramError::Network(_)
+        | TelegramError::Timeout
+        | TelegramError::Io(_)
+        | TelegramError::FileReferenceExpired => Some(Duration::from_secs(1 << (attempt - 1))),
+        _ => None,
+    }
+}
 
 /// Builds a deterministic local filename for a downloaded media item.
 ///
@@ -49,7 +100,8 @@ fn document_file_name(
 
 /// Replaces path separators and control characters so a server-supplied
 /// filename can't escape the download directory.
-fn sanitize_filename(name: &str) -> String {
+#[must_use]
+pub fn sanitize_filename(name: &str) -> String {
     name.chars()
         .map(|c| {
             if c == '/' || c == '\\' || c.is_control() {
@@ -61,6 +113,84 @@ fn sanitize_filename(name: &str) -> String {
         .collect()
 }
 
+/// Copies a downloaded attachment into `dir` under a readable name, and
+/// returns the new path.
+///
+/// Documents keep their original name; other media are named after their
+/// kind and send time, e.g. `photo_2026-10-16_14-03-22.jpg`. An existing
+/// file is never overwritten: ` (1)`, ` (2)`, ... is added to the name
+/// instead.
+///
+/// # Errors
+///
+/// Returns an error if the directory can't be created or the copy fails.
+pub fn save_media_copy(
+    source: &Path,
+    dir: &Path,
+    message: &Message,
+) -> Result<PathBuf, TelegramError> {
+    std::fs::create_dir_all(dir)?;
+
+    let name = saved_file_name(message, source);
+    let path = Path::new(&name);
+    let stem = path
+        .file_stem()
+        .map_or_else(|| name.clone(), |s| s.to_string_lossy().into_owned());
+    let ext = path
+        .extension()
+        .map(|e| format!(".{}", e.to_string_lossy()));
+
+    let mut target = dir.join(&name);
+    let mut n = 1;
+    while target.exists() {
+        target = dir.join(format!(
+            "{stem} ({n}){}",
+            ext.as_deref().unwrap_or_default()
+        ));
+        n += 1;
+    }
+
+    std::fs::copy(source, &target)?;
+    info!(
+        "Saved message {} attachment to {}",
+        message.id,
+        target.display()
+    );
+    Ok(target)
+}
+
+/// Picks the name an attachment is saved under (see [`save_media_copy`]).
+fn saved_file_name(message: &Message, source: &Path) -> String {
+    if let Some(name) = message
+        .content
+        .document
+        .as_ref()
+        .map(|doc| doc.file_name.trim())
+        .filter(|name| !name.is_empty())
+    {
+        return sanitize_filename(name);
+    }
+
+    let kind = match message.content.content_type {
+        MessageType::Photo => "photo",
+        MessageType::Video => "video",
+        MessageType::Voice => "voice",
+        MessageType::VideoNote => "video_note",
+        MessageType::Audio => "audio",
+        MessageType::Sticker => "sticker",
+        MessageType::Animation => "animation",
+        _ => "file",
+    };
+    let time = message
+        .date
+        .with_timezone(&chrono::Local)
+        .format("%Y-%m-%d_%H-%M-%S");
+    match source.extension() {
+        Some(ext) => format!("{kind}_{time}.{}", ext.to_string_lossy()),
+        None => format!("{kind}_{time}"),
+    }
+}
+
 /// Maps a MIME type to a sensible file extension, ignoring any `; charset=...`
 /// suffix. Returns `None` for unknown types.
 fn ext_from_mime(mime: &str) -> Option<&'static str> {
@@ -133,27 +263,14 @@ impl TelegramClient {
             message_id, chat_id
         );
 
-        // Fetch the specific message. Re-fetching yields a fresh file reference
-        // (stored references expire), so we always download from the live message.
-        #[allow(clippy::cast_possible_truncation)]
-        let message_id_i32 = message_id as i32;
-
-        let mut iter = client.iter_messages(peer_ref);
-        iter = iter.offset_id(message_id_i32 + 1).limit(1);
-
-        let msg = iter
-            .next()
-            .await
-            .map_err(TelegramError::from)?
-            .ok_or(TelegramError::MessageNotFound(message_id))?;
-
-        // Verify this is the message we want
-        if i64::from(msg.id()) != message_id {
-            return Err(TelegramError::MessageNotFound(message_id));
-        }
+        // Download from the live message rather than cached metadata, whose
+        // file reference may be stale.
+        let msg = self
+            .fetch_media_message(&client, peer_ref, chat_id, message_id)
+            .await?;
 
         // Get the media (any type: photo, document, video, audio, ...)
-        let media = msg.media().ok_or(TelegramError::NoMedia(message_id))?;
+        let mut media = msg.media().ok_or(TelegramError::NoMedia(message_id))?;
 
         // Ensure download directory exists
         fs::create_dir_all(download_dir)
@@ -164,8 +281,9 @@ impl TelegramClient {
         let filename = media_file_name(chat_id, message_id, &media);
         let file_path = download_dir.join(&filename);
 
-        // Reuse an already-downloaded file instead of fetching it again.
-        if file_path.exists() {
+        // Reuse an already-downloaded file instead of fetching it again. An
+        // empty file is what an interrupted download used to leave behind.
+        if fs::metadata(&file_path).await.is_ok_and(|m| m.len() > 0) {
             debug!(
                 "Media for message {} already exists at {}",
                 message_id,
@@ -174,11 +292,34 @@ impl TelegramClient {
             return Ok(file_path);
         }
 
-        // Download the media
-        client
-            .download_media(&media, &file_path)
-            .await
-            .map_err(TelegramError::from)?;
+        // Download to a temporary name so a failed transfer never leaves a
+        // partial file that looks finished
+        let part_path = download_dir.join(format!("{filename}.part"));
+        let mut refreshed = false;
+        loop {
+            match client.download_media(&media, &part_path).await {
+                Ok(()) => break,
+                Err(e) => {
+                    let _ = fs::remove_file(&part_path).await;
+                    let error = TelegramError::from(e);
+                    // The reference can expire between fetching the message
+                    // and the transfer; refresh it and try once more
+                    if refreshed || !is_file_reference_expired(&error) {
+                        return Err(error);
+                    }
+                    debug!(
+                        "File reference for message {} expired, refreshing",
+                        message_id
+                    );
+                    refreshed = true;
+                    let msg = self
+                        .fetch_media_message(&client, peer_ref, chat_id, message_id)
+                        .await?;
+                    media = msg.media().ok_or(TelegramError::NoMedia(message_id))?;
+                },
+            }
+        }
+        fs::rename(&part_path, &file_path).await?;
 
         info!(
             "Downloaded media from message {} to {}",
@@ -189,6 +330,51 @@ impl TelegramClient {
         Ok(file_path)
     }
 
+    /// Fetches a message by ID (`messages.getMessages`, or
+    /// `channels.getMessages` for channels), giving its media a fresh file
+    /// reference, and replaces the cached copy with it.
+    ///
+    /// The cached copy keeps its download state.
+    async fn fetch_media_message(
+        &self,
+        client: &Client,
+        peer_ref: PeerRef,
+        chat_id: i64,
+        message_id: i64,
+    ) -> Result<grammers_client::message::Message, TelegramError> {
+        let id =
+            i32::try_from(message_id).map_err(|_| TelegramError::MessageNotFound(message_id))?;
+        let msg = client
+            .get_messages_by_id(peer_ref, &[id])
+            .await
+            .map_err(TelegramError::from)?
+            .into_iter()
+            .next()
+            .flatten()
+            .ok_or(TelegramError::MessageNotFound(message_id))?;
+
+        let mut fresh = grammers_message_to_message(&msg);
+        // Keep the download state the UI shows for this message
+        if let Some(old) = self
+            .cache()
+            .get_messages(chat_id)
+            .into_iter()
+            .find(|m| m.id == message_id)
+        {
+            let error = old
+                .content
+                .media
+                .as_ref()
+                .and_then(|m| m.download_error())
+                .map(str::to_string);
+            fresh
+                .content
+                .set_download_status(old.content.download_status(), error);
+        }
+        self.cache().update_message(chat_id, fresh);
+        Ok(msg)
+    }
+
     /// Downloads the attachment of a message, reusing a local copy if present.
     ///
     /// Works for any attachment type (photo, document, video, audio, voice,
@@ -217,10 +403,110 @@ impl TelegramClient {
             .await
     }
 
+    /// Downloads the attachment of a message, retrying transient failures
+    /// with backoff.
+    ///
+    /// Every attempt re-fetches the message, so an expired file reference is
+    /// refreshed along the way.
+    ///
+    /// # Errors
+    ///
+    /// Returns the last error once the failure is permanent or
+    /// [`DOWNLOAD_ATTEMPTS`] attempts have failed.
+    pub async fn download_media_with_retry(
+        &self,
+        message: &Message,
+        download_dir: &Path,
+    ) -> Result<PathBuf, TelegramError> {
+        let mut attempt = 1;
+        loop {
+            match self.download_media_if_needed(message, download_dir).await {
+                Ok(path) => return Ok(path),
+                Err(e) => {
+                    let Some(delay) = download_retry_delay(attempt, &e) else {
+                        return Err(e);
+                    };
+                    warn!(
+                        "Download of message {} failed (attempt {}): {}; retrying in {:?}",
+                        message.id, attempt, e, delay
+                    );
+                    tokio::time::sleep(delay).await;
+                    attempt += 1;
+                },
+            }
+        }
+    }
+
+    /// Downloads a message's attachment in the background, then opens it,
+    /// or with `save_to` copies it there instead (see [`save_media_copy`]).
+    ///
+    /// The outcome arrives on the update channel as a
+    /// [`UpdateType::FileDownload`] update carrying the message with its new
+    /// download state.
+    pub fn spawn_media_download(
+        self: &Arc<Self>,
+        message: Message,
+        download_dir: PathBuf,
+        save_to: Option<PathBuf>,
+    ) {
+        let client = Arc::clone(self);
+        tokio::spawn(async move {
+            let result = match client
+                .download_media_with_retry(&message, &download_dir)
+                .await
+            {
+                Ok(path) => match &save_to {
+                    Some(dir) => save_media_copy(&path, dir, &message).map(|saved| (path, saved)),
+                    None => Self::open_media_file(&path)
+                        .await
+                        .map(|()| (path.clone(), path)),
+                },
+                Err(e) => Err(e),
+            };
+
+            let mut message = message;
+            let download = match result {
+                Ok((path, shown)) => {
+                    message
+                        .content
+                        .set_download_status(DownloadStatus::Downloaded, None);
+                    if let Some(media) = message.content.media.as_mut() {
+                        media.local_path = path.display().to_string();
+                    }
+                    FileDownload {
+                        state: FileDownloadState::Completed,
+                        local_path: shown.display().to_string(),
+                        saved: save_to.is_some(),
+                        ..Default::default()
+                    }
+                },
+                Err(e) => {
+                    message
+                        .content
+                        .set_download_status(DownloadStatus::Failed, Some(e.to_string()));
+                    FileDownload {
+                        state: FileDownloadState::Failed,
+                        error: Some(e.to_string()),
+                        ..Default::default()
+                    }
+                },
+            };
+
+            if let Some(tx) = client.get_update_sender().await {
+                let update = Update {
+                    update_type: UpdateType::FileDownload,
+                    chat_id: message.chat_id,
+                    message: Some(Box::new(message)),
+                    data: UpdateData::FileDownload(Box::new(download)),
+                };
+                let _ = tx.send(update).await;
+            }
+        });
+    }
+
     /// Opens a media file with the system's default application.
     ///
-    /// On macOS, this uses `open`. On Linux, it uses `xdg-open`.
-    /// On Windows, it uses `start`.
+    /// See [`crate::platform::open`] for how each OS does it.
     ///
     /// # Arguments
     ///
@@ -236,54 +522,57 @@ impl TelegramClient {
         }
 
         info!("Opening media file: {}", path.display());
-
-        #[cfg(target_os = "macos")]
-        let result = tokio::process::Command::new("open").arg(path).spawn();
-
-        #[cfg(target_os = "linux")]
-        let result = tokio::process::Command::new("xdg-open").arg(path).spawn();
-
-        #[cfg(target_os = "windows")]
-        let result = tokio::process::Command::new("cmd")
-            .args(["/C", "start", "", path.to_str().unwrap_or("")])
-            .spawn();
-
-        result.map_err(|e| TelegramError::Io(e.to_string()))?;
-
-        Ok(())
+        crate::platform::open(path).map_err(|e| TelegramError::Io(e.to_string()))
     }
 
     /// Opens a URL with the system's default browser.
     ///
-    /// On macOS this uses `open`, on Linux `xdg-open`, and on Windows `start`.
-    ///
     /// # Errors
     ///
     /// Returns an error if the open command cannot be spawned.
     #[allow(clippy::unused_async)]
     pub async fn open_url(url: &str) -> Result<(), TelegramError> {
         info!("Opening URL: {}", url);
-
-        #[cfg(target_os = "macos")]
-        let result = tokio::process::Command::new("open").arg(url).spawn();
-
-        #[cfg(target_os = "linux")]
-        let result = tokio::process::Command::new("xdg-open").arg(url).spawn();
-
-        #[cfg(target_os = "windows")]
-        let result = tokio::process::Command::new("cmd")
-            .args(["/C", "start", "", url])
-            .spawn();
-
-        result.map_err(|e| TelegramError::Io(e.to_string()))?;
-
-        Ok(())
+        crate::platform::open(url).map_err(|e| TelegramError::Io(e.to_string()))
     }
 }
 
 #[cfg(test)]
 mod tests {
-    use super::{document_file_name, ext_from_mime, sanitize_filename};
+    use super::{
+        document_file_name, download_retry_delay, ext_from_mime, is_file_reference_expired,
+        sanitize_filename, save_media_copy, Duration, TelegramError,
+    };
+    use crate::types::{Document, Message, MessageType};
+
+    #[test]
+    fn test_file_reference_expiry_is_recognised_in_transfer_errors() {
+        assert!(is_file_reference_expired(
+            &TelegramError::FileReferenceExpired
+        ));
+        assert!(is_file_reference_expired(&TelegramError::Io(
+            "rpc error 400: FILE_REFERENCE_EXPIRED".into()
+        )));
+        assert!(!is_file_reference_expired(&TelegramError::Io(
+            "connection reset".into()
+        )));
+        assert!(!is_file_reference_expired(&TelegramError::Timeout));
+    }
+
+    #[test]
+    fn test_download_retry_backs_off_then_gives_up() {
+        let err = TelegramError::Network("reset".into());
+        assert_eq!(download_retry_delay(1, &err), Some(Duration::from_secs(1)));
+        assert_eq!(download_retry_delay(2, &err), Some(Duration::from_secs(2)));
+        assert_eq!(download_retry_delay(3, &err), None);
+
+        assert_eq!(
+            download_retry_delay(1, &TelegramError::FloodWait(7)),
+            Some(Duration::from_secs(7))
+        );
+        assert!(download_retry_delay(1, &TelegramError::FileReferenceExpired).is_some());
+        assert_eq!(download_retry_delay(1, &TelegramError::NoMedia(1)), None);
+    }
 
     #[test]
     fn test_document_keeps_original_name() {
@@ -331,4 +620,28 @@ mod tests {
         assert_eq!(ext_from_mime("image/png"), Some("png"));
         assert_eq!(ext_from_mime("application/octet-stream"), None);
     }
+
+    #[test]
+    fn test_saved_copies_never_overwrite() {
+        let base = std::env::temp_dir().join(format!("ithil_save_test_{}", std::process::id()));
+        let source = base.join("cache").join("123_42_report.pdf");
+        std::fs::create_dir_all(source.parent().unwrap()).unwrap();
+        std::fs::write(&source, b"pdf").unwrap();
+        let downloads = base.join("downloads");
+
+        let mut message = Message::default();
+        message.content.content_type = MessageType::Document;
+        message.content.document = Some(Box::new(Document {
+            file_name: "report.pdf".to_string(),
+            ..Default::default()
+        }));
+
+        let first = save_media_copy(&source, &downloads, &message).unwrap();
+        let second = save_media_copy(&source, &downloads, &message).unwrap();
+        assert_eq!(first, downloads.join("report.pdf"));
+        assert_eq!(second, downloads.join("report (1).pdf"));
+        assert_eq!(std::fs::read(&second).unwrap(), b"pdf");
+
+        std::fs::remove_dir_all(&base).unwrap();
+    }
 }
@@ -1,32 +1,79 @@
 //! Message operations for the Telegram client.
 //!
 //! This module provides methods for working with messages:
-//! - Fetching message history
+//! - Fetching message history, including from a given date
 //! - Sending messages
 //! - Editing messages
+//! - Reacting to messages
 //! - Deleting messages
-//! - Forwarding messages
+//! - Forwarding messages, optionally hiding the original sender
+//! - Sending typing indicators
 
-use grammers_client::message::InputMessage;
-use grammers_client::tl;
-use grammers_session::types::PeerKind;
-use tracing::{debug, info};
+use chrono::{DateTime, Utc};
+use grammers_client::message::{InputMedia, InputMessage};
+use grammers_client::{tl, Client};
+use grammers_session::types::{PeerKind, PeerRef};
+use tracing::{debug, info, warn};
 
 use super::chats::{grammers_message_to_message, grammers_peer_to_user};
 use super::client::TelegramClient;
 use super::error::TelegramError;
-use crate::types::Message;
-
-/// Returns `true` when the file extension indicates an image that Telegram
-/// should receive as a compressed photo. Everything else is sent as a document.
-fn is_image(path: &std::path::Path) -> bool {
-    matches!(
-        path.extension()
-            .and_then(|e| e.to_str())
-            .map(str::to_ascii_lowercase)
-            .as_deref(),
-        Some("jpg" | "jpeg" | "png" | "webp" | "bmp")
-    )
+use crate::types::{Message, ReportReason, SearchFilter};
+
+/// Most items Telegram takes in one album.
+pub const MAX_ALBUM_SIZE: usize = 10;
+
+/// Finds the ID Telegram gave a sent message in the updates it returned.
+fn sent_message_id(updates: &tl::enums::Updates, random_id: i64) -> Option<i32> {
+    let updates = match updates {
+        tl::enums::Updates::UpdateShortSentMessage(sent) => return Some(sent.id),
+        tl::enums::Updates::Updates(u) => &u.updates,
+        tl::enums::Updates::Combined(u) => &u.updates,
+        _ => return None,
+    };
+    updates.iter().find_map(|update| match update {
+        tl::enums::Update::MessageId(u) if u.random_id == random_id => Some(u.id),
+        _ => None,
+    })
+}
+
+/// Most menus Telegram walks a message report through before accepting it.
+const MAX_REPORT_STEPS: usize = 4;
+
+/// Picks the report menu entry that best matches `reason`.
+///
+/// Telegram words its report menus itself, so entries are matched on a
+/// keyword; anything unmatched falls back to the last entry, which is
+/// normally "Other".
+fn pick_report_option(options: &[(String, Vec<u8>)], reason: ReportReason) -> Option<Vec<u8>> {
+    let keywords: &[&str] = match reason {
+        ReportReason::Spam => &["spam"],
+        ReportReason::Violence => &["violen"],
+        ReportReason::Fake => &["fake", "scam", "impersonat"],
+        ReportReason::Other => &["other"],
+    };
+    options
+        .iter()
+        .find(|(text, _)| {
+            let text = text.to_lowercase();
+            keywords.iter().any(|k| text.contains(k))
+        })
+        .or_else(|| options.last())
+        .map(|(_, option)| option.clone())
+}
+
+/// Returns a random ID for deduplicating a sent message.
+fn random_message_id() -> i64 {
+    let mut bytes = [0u8; 8];
+    // Telegram only needs the IDs to be unique; fall back to the clock if the
+    // OS generator fails
+    if getrandom::getrandom(&mut bytes).is_err() {
+        bytes = Utc::now()
+            .timestamp_nanos_opt()
+            .unwrap_or_default()
+            .to_le_bytes();
+    }
+    i64::from_le_bytes(bytes)
 }
 
 impl TelegramClient {
@@ -66,13 +113,35 @@ impl TelegramClient {
         offset_id: Option<i64>,
     ) -> Result<Vec<Message>, TelegramError> {
         let client = self.require_authorized().await?;
-        let peer_ref = self.get_peer_ref(chat_id).await?;
+        let client = &client;
 
         debug!(
             "Fetching {} messages from chat {}, offset: {:?}",
             limit, chat_id, offset_id
         );
 
+        let messages = self
+            .with_peer_retry(chat_id, |peer_ref| {
+                self.fetch_history(client, peer_ref, chat_id, limit, offset_id)
+            })
+            .await?;
+
+        // Senders Telegram sent no user info for would show as bare IDs
+        self.queue_sender_lookup(chat_id, &messages).await;
+
+        debug!("Fetched {} messages from chat {}", messages.len(), chat_id);
+        Ok(messages)
+    }
+
+    /// Pages through a chat's history for [`Self::get_messages`].
+    async fn fetch_history(
+        &self,
+        client: &Client,
+        peer_ref: PeerRef,
+        chat_id: i64,
+        limit: usize,
+        offset_id: Option<i64>,
+    ) -> Result<Vec<Message>, TelegramError> {
         let mut iter = client.iter_messages(peer_ref);
 
         // Set the offset if provided
@@ -108,17 +177,80 @@ impl TelegramClient {
             }
         }
 
-        debug!("Fetched {} messages from chat {}", messages.len(), chat_id);
         Ok(messages)
     }
 
+    /// Gets the messages sent before a point in time.
+    ///
+    /// Returns up to `limit` messages sent before `date`, newest first, for
+    /// jumping to a day without paging through everything since. The newest
+    /// such message is located with the `offset_date` of `messages.getHistory`
+    /// and the history is then loaded as in [`get_messages`](Self::get_messages).
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// or the chat is not found.
+    pub async fn get_messages_before_date(
+        &self,
+        chat_id: i64,
+        limit: usize,
+        date: DateTime<Utc>,
+    ) -> Result<Vec<Message>, TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        debug!("Looking up messages in chat {} before {}", chat_id, date);
+
+        // The API's timestamps are i32; anything later simply means "latest"
+        let offset_date = i32::try_from(date.timestamp()).unwrap_or(i32::MAX);
+        let history = client
+            .invoke(&tl::functions::messages::GetHistory {
+                peer: tl::enums::InputPeer::from(peer_ref),
+                offset_id: 0,
+                offset_date,
+                add_offset: 0,
+                limit: 1,
+                max_id: 0,
+                min_id: 0,
+                hash: 0,
+            })
+            .await
+            .map_err(TelegramError::from)?;
+
+        let raw = match history {
+            tl::enums::messages::Messages::Messages(m) => m.messages,
+            tl::enums::messages::Messages::Slice(m) => m.messages,
+            tl::enums::messages::Messages::ChannelMessages(m) => m.messages,
+            tl::enums::messages::Messages::NotModified(_) => Vec::new(),
+        };
+        let Some(anchor) = raw.first().map(|m| match m {
+            tl::enums::Message::Empty(m) => m.id,
+            tl::enums::Message::Message(m) => m.id,
+            tl::enums::Message::Service(m) => m.id,
+        }) else {
+            debug!("No messages in chat {} before {}", chat_id, date);
+            return Ok(Vec::new());
+        };
+
+        // offset_id excludes the offset itself, so start just above the anchor
+        self.get_messages(chat_id, limit, Some(i64::from(anchor) + 1))
+            .await
+    }
+
     /// Sends a text message to a chat.
     ///
+    /// The message is cached at once under a local ID and swapped for
+    /// Telegram's copy when the send is confirmed, matched on the send's
+    /// `random_id`, so the update stream's echo never shows up twice.
+    ///
     /// # Arguments
     ///
     /// * `chat_id` - ID of the chat to send the message to
     /// * `text` - Message text
     /// * `reply_to` - Optional message ID to reply to
+    /// * `send_as` - Channel or group to post as instead of the user (see
+    ///   [`get_send_as`](Self::get_send_as))
     ///
     /// # Errors
     ///
@@ -131,10 +263,12 @@ impl TelegramClient {
     /// # use ithil::telegram::TelegramClient;
     /// # async fn example(client: &TelegramClient) -> Result<(), ithil::telegram::TelegramError> {
     /// // Send a simple message
-    /// let msg = client.send_message(123456789, "Hello!", None).await?;
+    /// let msg = client.send_message(123456789, "Hello!", None, None).await?;
     ///
     /// // Reply to a message
-    /// let reply = client.send_message(123456789, "This is a reply", Some(42)).await?;
+    /// let reply = client
+    ///     .send_message(123456789, "This is a reply", Some(42), None)
+    ///     .await?;
     /// # Ok(())
     /// # }
     /// ```
@@ -143,30 +277,116 @@ impl TelegramClient {
         chat_id: i64,
         text: &str,
         reply_to: Option<i64>,
+        send_as: Option<i64>,
     ) -> Result<Message, TelegramError> {
         let client = self.require_authorized().await?;
-        let peer_ref = self.get_peer_ref(chat_id).await?;
+        let client = &client;
+        let send_as_ref = match send_as {
+            Some(id) if id != chat_id => Some(self.get_peer_ref(id).await?),
+            _ => None,
+        };
 
         info!("Sending message to chat {}", chat_id);
 
-        let mut input_message = InputMessage::new().text(text);
+        // Cache an optimistic copy under a local ID; the server's copy
+        // replaces it whether it comes back in the reply or the update
+        // stream first
+        let random_id = random_message_id();
+        let mut optimistic = Message {
+            chat_id,
+            sender_id: send_as.unwrap_or_default(),
+            date: Utc::now(),
+            is_outgoing: true,
+            reply_to_message_id: reply_to.unwrap_or_default(),
+            ..Default::default()
+        };
+        optimistic.content.text = text.to_string();
+        let optimistic = self.cache().begin_send(random_id, optimistic);
+
+        let reply_to = reply_to
+            .and_then(|id| i32::try_from(id).ok())
+            .map(|reply_to_msg_id| {
+                tl::enums::InputReplyTo::from(tl::types::InputReplyToMessage {
+                    reply_to_msg_id,
+                    top_msg_id: None,
+                    reply_to_peer_id: None,
+                    quote_text: None,
+                    quote_entities: None,
+                    quote_offset: None,
+                    monoforum_peer_id: None,
+                    todo_item_id: None,
+                })
+            });
+        let sent = self
+            .with_peer_retry(chat_id, |peer_ref| {
+                let reply_to = reply_to.clone();
+                async move {
+                    // Posting as the chat itself (anonymous admins) reuses its peer
+                    let send_as = send_as.map(|_| send_as_ref.unwrap_or(peer_ref));
+                    let updates = client
+                        .invoke(&tl::functions::messages::SendMessage {
+                            no_webpage: false,
+                            silent: false,
+                            background: false,
+                            clear_draft: false,
+                            noforwards: false,
+                            update_stickersets_order: false,
+                            invert_media: false,
+                            allow_paid_floodskip: false,
+                            peer: tl::enums::InputPeer::from(peer_ref),
+                            reply_to,
+                            message: text.to_string(),
+                            random_id,
+                            reply_markup: None,
+                            entities: None,
+                            schedule_date: None,
+                            send_as: send_as.map(tl::enums::InputPeer::from),
+                            quick_reply_shortcut: None,
+                            effect: None,
+                            allow_paid_stars: None,
+                            suggested_post: None,
+                        })
+                        .await
+                        .map_err(TelegramError::from)?;
+                    Ok((peer_ref, updates))
+                }
+            })
+            .await;
+        let (peer_ref, updates) = match sent {
+            Ok(sent) => sent,
+            Err(e) => {
+                self.cache().abandon_send(random_id);
+                return Err(e);
+            },
+        };
 
-        if let Some(reply_id) = reply_to {
-            // Convert to i32 for grammers
-            #[allow(clippy::cast_possible_truncation)]
-            let reply_id_i32 = reply_id as i32;
-            input_message = input_message.reply_to(Some(reply_id_i32));
+        let Some(id) = sent_message_id(&updates, random_id) else {
+            self.cache().abandon_send(random_id);
+            return Err(TelegramError::Api(
+                "no sent message in the reply".to_string(),
+            ));
+        };
+        self.cache().confirm_send(random_id, i64::from(id));
+
+        // The reply (often just `updateShortSentMessage`) lacks most of the
+        // message, so fetch the real one; if that fails, keep the optimistic
+        // copy under its real ID
+        let message = match client.get_messages_by_id(peer_ref, &[id]).await {
+            Ok(found) => found
+                .into_iter()
+                .next()
+                .flatten()
+                .map(|sent| grammers_message_to_message(&sent)),
+            Err(e) => {
+                warn!("Failed to fetch sent message {}: {}", id, e);
+                None
+            },
         }
-
-        let sent = client
-            .send_message(peer_ref, input_message)
-            .await
-            .map_err(TelegramError::from)?;
-
-        let message = grammers_message_to_message(&sent);
-
-        // Cache the sent message
-        self.cache().add_message(chat_id, message.clone());
+        .unwrap_or(Message {
+            id: i64::from(id),
+            ..optimistic
+        });
+        self.cache().settle_send(chat_id, message.clone());
 
         debug!("Sent message {} to chat {}", message.id, chat_id);
         Ok(message)
@@ -182,6 +402,8 @@ impl TelegramClient {
     /// * `chat_id` - ID of the chat to send to
     /// * `text` - Caption (may be empty)
     /// * `path` - Path to the local file to upload
+    /// * `as_photo` - Send an image as a (compressed) photo rather than as a
+    ///   file in its original quality
     /// * `reply_to` - Optional message ID to reply to
     ///
     /// # Errors
@@ -193,6 +415,7 @@ impl TelegramClient {
         chat_id: i64,
         text: &str,
         path: &std::path::Path,
+        as_photo: bool,
         reply_to: Option<i64>,
     ) -> Result<Message, TelegramError> {
         let client = self.require_authorized().await?;
@@ -203,7 +426,7 @@ impl TelegramClient {
         let uploaded = client.upload_file(path).await?;
 
         let mut input_message = InputMessage::new().text(text);
-        input_message = if is_image(path) {
+        input_message = if as_photo && crate::utils::is_image(path) {
             input_message.photo(uploaded)
         } else {
             input_message.document(uploaded)
@@ -227,6 +450,68 @@ impl TelegramClient {
         Ok(message)
     }
 
+    /// Sends images as one album (grouped media), with `text` as its
+    /// caption.
+    ///
+    /// # Arguments
+    ///
+    /// * `chat_id` - ID of the chat to send to
+    /// * `text` - Caption for the album (may be empty)
+    /// * `paths` - Paths of the images, at most [`MAX_ALBUM_SIZE`]
+    /// * `reply_to` - Optional message ID to reply to
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not authorized, the chat is not found,
+    /// a file cannot be read/uploaded, or sending fails.
+    pub async fn send_album(
+        &self,
+        chat_id: i64,
+        text: &str,
+        paths: &[std::path::PathBuf],
+        reply_to: Option<i64>,
+    ) -> Result<Vec<Message>, TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        info!("Uploading album of {} to chat {}", paths.len(), chat_id);
+
+        let mut media = Vec::with_capacity(paths.len());
+        for (i, path) in paths.iter().enumerate() {
+            let uploaded = client.upload_file(path).await?;
+            // Telegram shows the first item's caption for the album
+            let mut item = if i == 0 {
+                InputMedia::caption(text)
+            } else {
+                InputMedia::default()
+            };
+            item = item.photo(uploaded);
+            if let Some(reply_id) = reply_to {
+                #[allow(clippy::cast_possible_truncation)]
+                let reply_id_i32 = reply_id as i32;
+                item = item.reply_to(Some(reply_id_i32));
+            }
+            media.push(item);
+        }
+
+        let sent = client
+            .send_album(peer_ref, media)
+            .await
+            .map_err(TelegramError::from)?;
+
+        let messages: Vec<Message> = sent
+            .iter()
+            .flatten()
+            .map(grammers_message_to_message)
+            .collect();
+        for message in &messages {
+            self.cache().add_message(chat_id, message.clone());
+        }
+
+        debug!("Sent album of {} to chat {}", messages.len(), chat_id);
+        Ok(messages)
+    }
+
     /// Edits an existing message.
     ///
     /// # Arguments
@@ -296,6 +581,57 @@ impl TelegramClient {
         Ok(message)
     }
 
+    /// Reacts to a message with an emoji, replacing the user's earlier
+    /// reaction, or takes the reaction back if `reaction` is `None`.
+    ///
+    /// Returns the message with its reactions adjusted; Telegram's own
+    /// count arrives later as an update.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// the chat is not found, or the chat doesn't allow the reaction.
+    pub async fn send_reaction(
+        &self,
+        chat_id: i64,
+        message_id: i64,
+        reaction: Option<&str>,
+    ) -> Result<Message, TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        info!("Reacting to message {} in chat {}", message_id, chat_id);
+
+        // Convert to i32 for grammers
+        #[allow(clippy::cast_possible_truncation)]
+        let message_id_i32 = message_id as i32;
+
+        client
+            .invoke(&tl::functions::messages::SendReaction {
+                big: false,
+                add_to_recent: true,
+                peer: tl::enums::InputPeer::from(peer_ref),
+                msg_id: message_id_i32,
+                reaction: reaction.map(|emoji| {
+                    vec![tl::enums::Reaction::Emoji(tl::types::ReactionEmoji {
+                        emoticon: emoji.to_string(),
+                    })]
+                }),
+            })
+            .await
+            .map_err(TelegramError::from)?;
+
+        let mut message = self
+            .cache()
+            .get_messages(chat_id)
+            .into_iter()
+            .find(|m| m.id == message_id)
+            .ok_or(TelegramError::MessageNotFound(message_id))?;
+        message.set_my_reaction(reaction);
+        self.cache().update_message(chat_id, message.clone());
+        Ok(message)
+    }
+
     /// Deletes messages from a chat.
     ///
     /// # Arguments
@@ -377,6 +713,76 @@ impl TelegramClient {
         Ok(())
     }
 
+    /// Reports messages to Telegram's moderators.
+    ///
+    /// Telegram answers a report with a menu of more specific reasons, and
+    /// sometimes a comment prompt; the entry matching `reason` is chosen
+    /// until the report is accepted.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// the chat is not found, or Telegram does not accept the report.
+    pub async fn report_messages(
+        &self,
+        chat_id: i64,
+        message_ids: &[i64],
+        reason: ReportReason,
+    ) -> Result<(), TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        info!(
+            "Reporting {} messages in chat {} for {}",
+            message_ids.len(),
+            chat_id,
+            reason
+        );
+
+        #[allow(clippy::cast_possible_truncation)]
+        let ids: Vec<i32> = message_ids.iter().map(|&id| id as i32).collect();
+        let peer = tl::enums::InputPeer::from(peer_ref);
+        let mut option = Vec::new();
+        let mut message = String::new();
+
+        for _ in 0..MAX_REPORT_STEPS {
+            let result = client
+                .invoke(&tl::functions::messages::Report {
+                    peer: peer.clone(),
+                    id: ids.clone(),
+                    option: option.clone(),
+                    message: message.clone(),
+                })
+                .await
+                .map_err(TelegramError::from)?;
+
+            match result {
+                tl::enums::ReportResult::Reported => {
+                    debug!("Report for chat {} accepted", chat_id);
+                    return Ok(());
+                },
+                tl::enums::ReportResult::ChooseOption(menu) => {
+                    let options: Vec<(String, Vec<u8>)> = menu
+                        .options
+                        .into_iter()
+                        .map(|tl::enums::MessageReportOption::Option(o)| (o.text, o.option))
+                        .collect();
+                    option = pick_report_option(&options, reason).ok_or_else(|| {
+                        TelegramError::Api("Telegram offered no report options".to_string())
+                    })?;
+                },
+                tl::enums::ReportResult::AddComment(comment) => {
+                    option = comment.option;
+                    message = reason.to_string();
+                },
+            }
+        }
+
+        Err(TelegramError::Api(
+            "Telegram did not accept the report".to_string(),
+        ))
+    }
+
     /// Forwards messages to another chat.
     ///
     /// # Arguments
@@ -446,12 +852,140 @@ impl TelegramClient {
         Ok(messages)
     }
 
-    /// Searches messages in a chat.
+    /// Forwards messages to another chat without attributing the original
+    /// sender, so they appear as if sent by the current user.
+    ///
+    /// The forwarded copies arrive through the update stream like any other
+    /// outgoing message.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// either chat is not found, or forwarding fails.
+    pub async fn forward_messages_without_author(
+        &self,
+        from_chat_id: i64,
+        to_chat_id: i64,
+        message_ids: &[i64],
+    ) -> Result<(), TelegramError> {
+        let client = self.require_authorized().await?;
+        let from_peer_ref = self.get_peer_ref(from_chat_id).await?;
+        let to_peer_ref = self.get_peer_ref(to_chat_id).await?;
+
+        info!(
+            "Forwarding {} messages from {} to {} without author",
+            message_ids.len(),
+            from_chat_id,
+            to_chat_id
+        );
+
+        // Convert message IDs to i32
+        #[allow(clippy::cast_possible_truncation)]
+        let ids: Vec<i32> = message_ids.iter().map(|&id| id as i32).collect();
+        let random_id = ids.iter().map(|_| random_message_id()).collect();
+
+        client
+            .invoke(&tl::functions::messages::ForwardMessages {
+                silent: false,
+                background: false,
+                with_my_score: false,
+                drop_author: true,
+                drop_media_captions: false,
+                noforwards: false,
+                allow_paid_floodskip: false,
+                from_peer: tl::enums::InputPeer::from(from_peer_ref),
+                id: ids,
+                random_id,
+                to_peer: tl::enums::InputPeer::from(to_peer_ref),
+                top_msg_id: None,
+                reply_to: None,
+                schedule_date: None,
+                send_as: None,
+                quick_reply_shortcut: None,
+                video_timestamp: None,
+                allow_paid_stars: None,
+                suggested_post: None,
+            })
+            .await
+            .map_err(TelegramError::from)?;
+
+        Ok(())
+    }
+
+    /// Tells the chat that the user is typing.
+    ///
+    /// Telegram shows the indicator for about six seconds, so callers should
+    /// repeat this while the user keeps typing.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// or the chat is not found.
+    pub async fn send_typing(&self, chat_id: i64) -> Result<(), TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        client
+            .invoke(&tl::functions::messages::SetTyping {
+                peer: tl::enums::InputPeer::from(peer_ref),
+                top_msg_id: None,
+                action: tl::enums::SendMessageAction::SendMessageTypingAction,
+            })
+            .await
+            .map_err(TelegramError::from)?;
+
+        Ok(())
+    }
+
+    /// Marks a voice or video message as played, which its sender sees.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// or the chat is not found.
+    pub async fn mark_played(&self, chat_id: i64, message_id: i64) -> Result<(), TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        debug!(
+            "Marking message {} in chat {} as played",
+            message_id, chat_id
+        );
+
+        #[allow(clippy::cast_possible_truncation)]
+        let id = vec![message_id as i32];
+        match peer_ref.id.kind() {
+            PeerKind::Channel => {
+                client
+                    .invoke(&tl::functions::channels::ReadMessageContents {
+                        channel: tl::types::InputChannel {
+                            channel_id: peer_ref.id.bare_id(),
+                            access_hash: peer_ref.auth.hash(),
+                        }
+                        .into(),
+                        id,
+                    })
+                    .await
+                    .map_err(TelegramError::from)?;
+            },
+            PeerKind::User | PeerKind::UserSelf | PeerKind::Chat => {
+                client
+                    .invoke(&tl::functions::messages::ReadMessageContents { id })
+                    .await
+                    .map_err(TelegramError::from)?;
+            },
+        }
+
+        Ok(())
+    }
+
+    /// Searches messages in one chat, or across all chats.
     ///
     /// # Arguments
     ///
-    /// * `chat_id` - ID of the chat to search in
-    /// * `query` - Search query string
+    /// * `chat_id` - ID of the chat to search in, or `None` for all chats
+    /// * `query` - Search query string (may be empty when `filter` is set)
+    /// * `filter` - Kind of message to keep, if any
     /// * `limit` - Maximum number of messages to return
     ///
     /// # Errors
@@ -460,41 +994,64 @@ impl TelegramClient {
     /// or the chat is not found.
     pub async fn search_messages(
         &self,
-        chat_id: i64,
+        chat_id: Option<i64>,
         query: &str,
+        filter: Option<SearchFilter>,
         limit: usize,
     ) -> Result<Vec<Message>, TelegramError> {
         let client = self.require_authorized().await?;
-        let peer_ref = self.get_peer_ref(chat_id).await?;
 
         debug!(
-            "Searching for '{}' in chat {}, limit: {}",
-            query, chat_id, limit
+            "Searching for '{}' in {:?} (filter {:?}), limit: {}",
+            query, chat_id, filter, limit
         );
 
-        let mut iter = client.search_messages(peer_ref).query(query).limit(limit);
-
         let mut messages = Vec::with_capacity(limit);
-
-        while let Some(msg) = iter.next().await.map_err(TelegramError::from)? {
-            let message = grammers_message_to_message(&msg);
-            messages.push(message);
-
-            if messages.len() >= limit {
-                break;
+        if let Some(chat_id) = chat_id {
+            let peer_ref = self.get_peer_ref(chat_id).await?;
+            let mut iter = client.search_messages(peer_ref).query(query).limit(limit);
+            if let Some(filter) = filter {
+                iter = iter.filter(messages_filter(filter));
+            }
+            while let Some(msg) = iter.next().await.map_err(TelegramError::from)? {
+                messages.push(grammers_message_to_message(&msg));
+                if messages.len() >= limit {
+                    break;
+                }
+            }
+        } else {
+            let mut iter = client.search_all_messages().query(query).limit(limit);
+            if let Some(filter) = filter {
+                iter = iter.filter(messages_filter(filter));
+            }
+            while let Some(msg) = iter.next().await.map_err(TelegramError::from)? {
+                messages.push(grammers_message_to_message(&msg));
+                if messages.len() >= limit {
+                    break;
+                }
             }
         }
 
-        debug!(
-            "Found {} messages matching '{}' in chat {}",
-            messages.len(),
-            query,
-            chat_id
-        );
+        debug!("Found {} messages matching '{}'", messages.len(), query);
         Ok(messages)
     }
 }
 
+/// Maps a search filter to Telegram's message filter.
+fn messages_filter(filter: SearchFilter) -> tl::enums::MessagesFilter {
+    use tl::enums::MessagesFilter as F;
+    match filter {
+        SearchFilter::Media => F::InputMessagesFilterPhotoVideo,
+        SearchFilter::Photo => F::InputMessagesFilterPhotos,
+        SearchFilter::Video => F::InputMessagesFilterVideo,
+        SearchFilter::Document => F::InputMessagesFilterDocument,
+        SearchFilter::Voice => F::InputMessagesFilterVoice,
+        SearchFilter::Audio => F::InputMessagesFilterMusic,
+        SearchFilter::Link => F::InputMessagesFilterUrl,
+        SearchFilter::Pinned => F::InputMessagesFilterPinned,
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use crate::types::MessageType;
@@ -506,27 +1063,34 @@ mod tests {
         assert_eq!(format!("{}", MessageType::Video), "Video");
     }
 
-    use super::is_image;
-    use std::path::Path;
-
-    #[test]
-    fn images_classify_as_photo() {
-        for p in ["a.jpg", "a.jpeg", "a.PNG", "dir/sub/photo.WebP", "x.bmp"] {
-            assert!(is_image(Path::new(p)), "{p} should be an image");
-        }
-    }
+    use super::pick_report_option;
+    use crate::types::ReportReason;
 
     #[test]
-    fn non_images_classify_as_document() {
-        for p in [
-            "a.gif",
-            "clip.mp4",
-            "notes.txt",
-            "report.pdf",
-            "noext",
-            "a.tar.gz",
-        ] {
-            assert!(!is_image(Path::new(p)), "{p} should NOT be an image");
-        }
+    fn report_options_match_reason_or_fall_back_to_last() {
+        let options = vec![
+            ("I don't like it".to_string(), vec![1]),
+            ("It's spam".to_string(), vec![2]),
+            ("Violence".to_string(), vec![3]),
+            ("Scam or fraud".to_string(), vec![4]),
+            ("Other".to_string(), vec![5]),
+        ];
+        assert_eq!(
+            pick_report_option(&options, ReportReason::Spam),
+            Some(vec![2])
+        );
+        assert_eq!(
+            pick_report_option(&options, ReportReason::Violence),
+            Some(vec![3])
+        );
+        assert_eq!(
+            pick_report_option(&options, ReportReason::Fake),
+            Some(vec![4])
+        );
+        assert_eq!(
+            pick_report_option(&options[..2], ReportReason::Other),
+            Some(vec![2])
+        );
+        assert_eq!(pick_report_option(&[], ReportReason::Spam), None);
     }
 }
@@ -11,7 +11,14 @@
 //! - Authentication flow (phone → code → optional 2FA password)
 //! - Dialog/chat operations
 //! - Message sending and history retrieval
-//! - Real-time update streaming to the UI via tokio channels
+//! - Real-time update streaming via tokio channels, fanned out to any number
+//!   of subscribers by an [`UpdateHub`]
+//!
+//! The UI holds it as a [`TelegramApi`], which tests implement with an
+//! in-memory backend. [`TelegramApi`] is made of focused services
+//! ([`AuthService`], [`DialogService`], [`MessageService`],
+//! [`MediaService`] and [`UpdateService`]), so code that needs only one of
+//! them can ask for just that one.
 //!
 //! # Example
 //!
@@ -39,13 +46,25 @@
 //! # }
 //! ```
 
+pub mod api;
 pub mod auth;
 pub mod chats;
 pub mod client;
 pub mod error;
+#[cfg(test)]
+pub mod fake;
+pub mod hub;
 pub mod media;
 pub mod messages;
+pub mod participants;
+pub mod polls;
+pub mod presence;
+pub mod replay;
 pub mod updates;
 
+pub use api::{
+    AuthService, DialogService, MediaService, MessageService, TelegramApi, UpdateService,
+};
 pub use client::TelegramClient;
 pub use error::TelegramError;
+pub use hub::UpdateHub;
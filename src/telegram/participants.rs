@@ -0,0 +1,290 @@
+//! Sender lookups for message history.
+//!
+//! History often names senders Telegram didn't include user info for, which
+//! would otherwise render as "User 12345". Unknown senders are queued and,
+//! after a short pause to collect more, looked up together in one
+//! `users.getUsers` call. Each user is addressed through a message they sent
+//! (so no access hash is needed), and the cache's participant TTL keeps a
+//! sender from being looked up again for a while.
+
+use std::collections::HashSet;
+use std::time::{Duration, Instant};
+
+use grammers_client::tl;
+use tracing::{debug, warn};
+
+use super::client::TelegramClient;
+use super::error::TelegramError;
+use super::updates::tl_status_to_user_status;
+use crate::types::{Message, Update, UpdateData, UpdateType, User, UserStatus};
+
+/// Maximum number of users asked for in one `users.getUsers` call.
+const USERS_PER_REQUEST: usize = 100;
+
+/// How long queued senders wait for more to arrive before the lookup.
+const QUEUE_DELAY: Duration = Duration::from_millis(300);
+
+/// A sender to look up, addressed through one of their messages.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+struct QueuedSender {
+    chat_id: i64,
+    user_id: i64,
+    message_id: i64,
+}
+
+/// Senders waiting to be looked up in the next batch.
+#[derive(Debug, Default)]
+pub(crate) struct SenderQueue {
+    pending: Vec<QueuedSender>,
+    scheduled: bool,
+}
+
+impl SenderQueue {
+    /// Queues a chat's `(user_id, message_id)` pairs, ignoring senders
+    /// already queued for the chat.
+    ///
+    /// Returns `true` if no lookup is scheduled yet, meaning the caller
+    /// should schedule one.
+    fn push(&mut self, chat_id: i64, senders: &[(i64, i64)]) -> bool {
+        for &(user_id, message_id) in senders {
+            let queued = self
+                .pending
+                .iter()
+                .any(|s| s.chat_id == chat_id && s.user_id == user_id);
+            if !queued {
+                self.pending.push(QueuedSender {
+                    chat_id,
+                    user_id,
+                    message_id,
+                });
+            }
+        }
+        !std::mem::replace(&mut self.scheduled, true)
+    }
+
+    /// Takes every queued sender, letting the next push schedule a lookup.
+    fn take(&mut self) -> Vec<QueuedSender> {
+        self.scheduled = false;
+        std::mem::take(&mut self.pending)
+    }
+}
+
+impl TelegramClient {
+    /// Queues a chat's unknown senders to be looked up in the background.
+    ///
+    /// Senders that are cached and were looked up within the participant TTL
+    /// are skipped. When names arrive they are cached and a
+    /// [`UpdateType::ParticipantsResolved`] update is sent for each chat so
+    /// the UI can redraw.
+    pub(crate) async fn queue_sender_lookup(&self, chat_id: i64, messages: &[Message]) {
+        let now = Instant::now();
+        let senders = sender_messages(chat_id, messages);
+        let stale = self.cache().participants_to_resolve(
+            chat_id,
+            senders.iter().map(|&(user_id, _)| user_id),
+            now,
+        );
+        if stale.is_empty() {
+            return;
+        }
+        // Marked up front so overlapping loads don't queue them again
+        self.cache().mark_participants(chat_id, &stale, now);
+        let senders: Vec<(i64, i64)> = senders
+            .into_iter()
+            .filter(|(user_id, _)| stale.contains(user_id))
+            .collect();
+
+        if self.sender_queue().lock().await.push(chat_id, &senders) {
+            let client = self.clone();
+            tokio::spawn(async move {
+                tokio::time::sleep(QUEUE_DELAY).await;
+                client.flush_sender_queue().await;
+            });
+        }
+    }
+
+    /// Looks up every queued sender and notifies the UI.
+    ///
+    /// Senders whose lookup fails are forgotten by the participant cache so
+    /// the next load of their chat queues them again.
+    async fn flush_sender_queue(&self) {
+        let queued = self.sender_queue().lock().await.take();
+        if queued.is_empty() {
+            return;
+        }
+
+        let mut requests = Vec::with_capacity(queued.len());
+        let chat_ids: HashSet<i64> = queued.iter().map(|s| s.chat_id).collect();
+        for &chat_id in &chat_ids {
+            let senders = queued.iter().filter(|s| s.chat_id == chat_id);
+            match self.get_peer_ref(chat_id).await {
+                Ok(peer_ref) => {
+                    let peer = tl::enums::InputPeer::from(peer_ref);
+                    requests.extend(senders.map(|s| (*s, peer.clone())));
+                },
+                Err(e) => {
+                    warn!("Cannot look up senders in chat {}: {}", chat_id, e);
+                    let user_ids: Vec<i64> = senders.map(|s| s.user_id).collect();
+                    self.cache().forget_participants(chat_id, &user_ids);
+                },
+            }
+        }
+
+        let mut resolved_chats = HashSet::new();
+        for batch in requests.chunks(USERS_PER_REQUEST) {
+            match self.lookup_users(batch).await {
+                Ok(users) => {
+                    debug!("Resolved {} of {} senders", users.len(), batch.len());
+                    for user in users {
+                        resolved_chats.extend(
+                            batch
+                                .iter()
+                                .filter(|(s, _)| s.user_id == user.id)
+                                .map(|(s, _)| s.chat_id),
+                        );
+                        self.cache().set_user(user);
+                    }
+                },
+                Err(e) => {
+                    warn!("Failed to look up {} senders: {}", batch.len(), e);
+                    for (s, _) in batch {
+                        self.cache().forget_participants(s.chat_id, &[s.user_id]);
+                    }
+                },
+            }
+        }
+
+        if let Some(tx) = self.get_update_sender().await {
+            for chat_id in resolved_chats {
+                let update = Update {
+                    update_type: UpdateType::ParticipantsResolved,
+                    chat_id,
+                    message: None,
+                    data: UpdateData::None,
+                };
+                let _ = tx.send(update).await;
+            }
+        }
+    }
+
+    /// Fetches user info for senders with one `users.getUsers` call.
+    async fn lookup_users(
+        &self,
+        senders: &[(QueuedSender, tl::enums::InputPeer)],
+    ) -> Result<Vec<User>, TelegramError> {
+        let client = self.require_authorized().await?;
+
+        let id = senders
+            .iter()
+            .map(|(s, peer)| {
+                // Message IDs fit in i32 on Telegram's side
+                #[allow(clippy::cast_possible_truncation)]
+                let msg_id = s.message_id as i32;
+                tl::types::InputUserFromMessage {
+                    peer: peer.clone(),
+                    msg_id,
+                    user_id: s.user_id,
+                }
+                .into()
+            })
+            .collect();
+
+        let users = client
+            .invoke(&tl::functions::users::GetUsers { id })
+            .await
+            .map_err(TelegramError::from)?;
+
+        Ok(users.iter().filter_map(tl_user_to_user).collect())
+    }
+}
+
+/// Picks, for each sender in a chat, the newest message they sent.
+///
+/// Outgoing messages and channel posts (sent as the chat itself) are skipped.
+fn sender_messages(chat_id: i64, messages: &[Message]) -> Vec<(i64, i64)> {
+    let mut senders: Vec<(i64, i64)> = Vec::new();
+    for msg in messages {
+        if msg.is_outgoing || msg.sender_id <= 0 || msg.sender_id == chat_id {
+            continue;
+        }
+        match senders
+            .iter_mut()
+            .find(|(user_id, _)| *user_id == msg.sender_id)
+        {
+            Some(entry) => entry.1 = entry.1.max(msg.id),
+            None => senders.push((msg.sender_id, msg.id)),
+        }
+    }
+    senders
+}
+
+/// Converts a raw TL user to our `User` type.
+pub(super) fn tl_user_to_user(user: &tl::enums::User) -> Option<User> {
+    match user {
+        tl::enums::User::User(u) => Some(User {
+            id: u.id,
+            first_name: u.first_name.clone().unwrap_or_default(),
+            last_name: u.last_name.clone().unwrap_or_default(),
+            username: u.username.clone().unwrap_or_default(),
+            phone_number: u.phone.clone().unwrap_or_default(),
+            profile_photo_id: String::new(),
+            status: u
+                .status
+                .as_ref()
+                .map_or(UserStatus::Offline, tl_status_to_user_status),
+            is_bot: u.bot,
+            is_contact: u.contact,
+            is_mutual_contact: u.mutual_contact,
+            is_verified: u.verified,
+            is_premium: u.premium,
+        }),
+        tl::enums::User::Empty(_) => None,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn message(id: i64, sender_id: i64) -> Message {
+        Message {
+            id,
+            chat_id: 10,
+            sender_id,
+            ..Default::default()
+        }
+    }
+
+    #[test]
+    fn picks_newest_message_per_sender() {
+        let mut outgoing = message(5, 7);
+        outgoing.is_outgoing = true;
+        let messages = vec![
+            message(1, 2),
+            message(3, 2),
+            message(2, 4),
+            // Channel post signed as the chat itself
+            message(4, 10),
+            outgoing,
+        ];
+
+        assert_eq!(sender_messages(10, &messages), vec![(2, 3), (4, 2)]);
+    }
+
+    #[test]
+    fn queue_schedules_one_lookup_per_batch() {
+        let mut queue = SenderQueue::default();
+        assert!(queue.push(10, &[(2, 3)]));
+        // Already scheduled; the same sender isn't queued twice
+        assert!(!queue.push(10, &[(2, 5), (4, 2)]));
+        assert!(!queue.push(20, &[(2, 7)]));
+
+        let taken = queue.take();
+        assert_eq!(taken.len(), 3);
+        assert_eq!(taken[0].message_id, 3);
+        assert!(queue.take().is_empty());
+
+        // After a flush the next push schedules again
+        assert!(queue.push(10, &[(6, 1)]));
+    }
+}
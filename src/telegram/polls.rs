@@ -0,0 +1,180 @@
+//! Poll voting and voter lists for the Telegram client.
+//!
+//! Votes are sent with `messages.sendVote`; an empty vote retracts. For
+//! polls with public voters, `messages.getPollVotes` lists who chose each
+//! option, a page at a time.
+
+use chrono::DateTime;
+use grammers_client::tl;
+use tracing::{debug, info};
+
+use super::client::TelegramClient;
+use super::error::TelegramError;
+use super::participants::tl_user_to_user;
+use crate::types::{Poll, PollOption, PollType, PollVoters};
+
+impl TelegramClient {
+    /// Votes in a poll, replacing any earlier vote.
+    ///
+    /// `options` are the chosen options' [`PollOption::option`] identifiers;
+    /// an empty slice retracts the vote. The cached message's poll is
+    /// updated to match.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// the chat is not found, or the poll is closed.
+    pub async fn vote_poll(
+        &self,
+        chat_id: i64,
+        message_id: i64,
+        options: &[Vec<u8>],
+    ) -> Result<(), TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        info!(
+            "{} in poll {} of chat {}",
+            if options.is_empty() {
+                "Retracting vote"
+            } else {
+                "Voting"
+            },
+            message_id,
+            chat_id
+        );
+
+        #[allow(clippy::cast_possible_truncation)]
+        let msg_id = message_id as i32;
+        client
+            .invoke(&tl::functions::messages::SendVote {
+                peer: tl::enums::InputPeer::from(peer_ref),
+                msg_id,
+                options: options.to_vec(),
+            })
+            .await
+            .map_err(TelegramError::from)?;
+
+        // Update cache
+        let cached = self
+            .cache()
+            .get_messages(chat_id)
+            .into_iter()
+            .find(|m| m.id == message_id);
+        if let Some(mut message) = cached {
+            if let Some(poll) = message.content.poll.as_mut() {
+                let chosen: Vec<usize> = poll
+                    .options
+                    .iter()
+                    .enumerate()
+                    .filter(|(_, o)| options.contains(&o.option))
+                    .map(|(i, _)| i)
+                    .collect();
+                poll.apply_vote(&chosen);
+                self.cache().update_message(chat_id, message);
+            }
+        }
+
+        Ok(())
+    }
+
+    /// Fetches one page of the users who chose a poll option.
+    ///
+    /// Pass the previous page's [`PollVoters::next_offset`] as `offset` to
+    /// continue. Only polls with public voters can be listed.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected, not authorized,
+    /// the chat is not found, or the poll is anonymous.
+    pub async fn get_poll_voters(
+        &self,
+        chat_id: i64,
+        message_id: i64,
+        option: &[u8],
+        offset: Option<&str>,
+        limit: usize,
+    ) -> Result<PollVoters, TelegramError> {
+        let client = self.require_authorized().await?;
+        let peer_ref = self.get_peer_ref(chat_id).await?;
+
+        debug!(
+            "Fetching voters for poll {} in chat {} (offset {:?})",
+            message_id, chat_id, offset
+        );
+
+        #[allow(clippy::cast_possible_truncation)]
+        let id = message_id as i32;
+        let tl::enums::messages::VotesList::List(list) = client
+            .invoke(&tl::functions::messages::GetPollVotes {
+                peer: tl::enums::InputPeer::from(peer_ref),
+                id,
+                option: Some(option.to_vec()),
+                offset: offset.map(ToString::to_string),
+                limit: i32::try_from(limit).unwrap_or(i32::MAX),
+            })
+            .await
+            .map_err(TelegramError::from)?;
+
+        // Votes name voters by peer; their details come in `users`
+        let users = list
+            .users
+            .iter()
+            .filter_map(tl_user_to_user)
+            .inspect(|user| self.cache().set_user(user.clone()))
+            .collect();
+
+        Ok(PollVoters {
+            users,
+            total: list.count,
+            next_offset: list.next_offset,
+        })
+    }
+}
+
+/// Converts a raw TL poll and its results to our `Poll` type.
+pub(super) fn tl_poll_to_poll(raw: &tl::types::Poll, results: &tl::types::PollResults) -> Poll {
+    let voters = results.results.as_deref().unwrap_or_default();
+    let options = raw
+        .answers
+        .iter()
+        .map(|answer| {
+            let tl::enums::PollAnswer::Answer(answer) = answer;
+            let counts = voters.iter().find_map(|v| {
+                let tl::enums::PollAnswerVoters::Voters(v) = v;
+                (v.option == answer.option).then_some(v)
+            });
+            PollOption {
+                text: text_with_entities(&answer.text),
+                voter_count: counts.map_or(0, |v| v.voters),
+                is_chosen: counts.is_some_and(|v| v.chosen),
+                option: answer.option.clone(),
+            }
+        })
+        .collect();
+
+    Poll {
+        id: raw.id.to_string(),
+        question: text_with_entities(&raw.question),
+        options,
+        total_voter_count: results.total_voters.unwrap_or(0),
+        is_closed: raw.closed,
+        is_anonymous: !raw.public_voters,
+        allows_multiple_answers: raw.multiple_choice,
+        poll_type: if raw.quiz {
+            PollType::Quiz
+        } else {
+            PollType::Regular
+        },
+        open_period: raw.close_period.unwrap_or(0),
+        close_date: raw
+            .close_date
+            .and_then(|t| DateTime::from_timestamp(i64::from(t), 0)),
+    }
+}
+
+/// Returns the plain text of formatted TL text.
+fn text_with_entities(text: &tl::enums::TextWithEntities) -> String {
+    let tl::enums::TextWithEntities::Entities(text) = text;
+    text.text.clone()
+}
@@ -0,0 +1,35 @@
+//! Online status reporting for the Telegram client.
+//!
+//! Telegram shows a user as online until the status expires (a few minutes)
+//! or an explicit offline update arrives, so callers should re-send "online"
+//! periodically while the user is active.
+
+use grammers_client::tl;
+use tracing::debug;
+
+use super::client::TelegramClient;
+use super::error::TelegramError;
+
+impl TelegramClient {
+    /// Reports the account as online or offline via `account.updateStatus`.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the client is not connected or not authorized,
+    /// or the request fails.
+    pub async fn set_online(&self, online: bool) -> Result<(), TelegramError> {
+        let client = self.require_authorized().await?;
+
+        debug!(
+            "Reporting status: {}",
+            if online { "online" } else { "offline" }
+        );
+
+        client
+            .invoke(&tl::functions::account::UpdateStatus { offline: !online })
+            .await
+            .map_err(TelegramError::from)?;
+
+        Ok(())
+    }
+}
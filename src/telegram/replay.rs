@@ -0,0 +1,681 @@
+//! Recording and replaying update streams.
+//!
+//! `ithil --record FILE` saves every update the client streams to the UI, and
+//! `ithil --replay FILE` plays a recording back into the UI with its original
+//! timing, without connecting to Telegram. A UI bug triggered by a particular
+//! sequence of updates can then be reproduced from the file alone.
+//!
+//! A recording is JSON Lines: first a snapshot of the chats and users cached
+//! when the first update arrived, then one line per update with its offset
+//! from the start of the recording in milliseconds.
+//!
+//! During replay nothing reaches Telegram: reading works from the snapshot
+//! and the replayed messages, and anything that would send fails with
+//! [`TelegramError::NotConnected`].
+
+use std::fs::File;
+use std::io::{self, BufRead, BufReader, BufWriter, Write};
+use std::path::{Path, PathBuf};
+use std::sync::atomic::{AtomicBool, Ordering};
+use std::sync::{Arc, Mutex};
+use std::time::Duration;
+
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+use tokio::sync::mpsc;
+use tokio::task::JoinHandle;
+use tokio::time::Instant;
+use tracing::warn;
+
+use super::api::{
+    ApiResult, AuthService, BoxFuture, DialogService, MediaService, MessageService, UpdateService,
+};
+use super::error::TelegramError;
+use crate::cache::{Cache, SharedCache};
+use crate::types::{
+    AuthState, Chat, ChatPermissions, DownloadStatus, FileDownload, FileDownloadState, Message,
+    PeerHandle, PollVoters, ReportReason, SearchFilter, SendAsPeer, Update, UpdateData, UpdateType,
+    User,
+};
+
+/// One line of a recording.
+#[derive(Debug, Serialize, Deserialize)]
+#[serde(tag = "kind", rename_all = "snake_case")]
+enum Entry {
+    /// What the UI had loaded when recording began
+    Snapshot { chats: Vec<Chat>, users: Vec<User> },
+    /// An update and when it arrived
+    Update { at_ms: u64, update: Update },
+}
+
+/// A loaded recording.
+#[derive(Debug, Clone, Default)]
+pub struct Recording {
+    /// Chats cached when recording began
+    pub chats: Vec<Chat>,
+    /// Users cached when recording began
+    pub users: Vec<User>,
+    /// Updates with their offsets from the start of the recording
+    pub updates: Vec<(Duration, Update)>,
+}
+
+impl Recording {
+    /// Reads a recording from a file.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if the file cannot be read or a line is not a valid
+    /// entry.
+    pub fn load(path: &Path) -> io::Result<Self> {
+        Self::parse(BufReader::new(File::open(path)?))
+    }
+
+    /// Reads a recording line by line. Blank lines are skipped.
+    ///
+    /// # Errors
+    ///
+    /// Returns an error if reading fails or a line is not a valid entry.
+    pub fn parse(reader: impl BufRead) -> io::Result<Self> {
+        let mut recording = Self::default();
+        for (i, line) in reader.lines().enumerate() {
+            let line = line?;
+            if line.trim().is_empty() {
+                continue;
+            }
+            let entry = serde_json::from_str(&line).map_err(|e| {
+                io::Error::new(io::ErrorKind::InvalidData, format!("line {}: {e}", i + 1))
+            })?;
+            match entry {
+                Entry::Snapshot { chats, users } => {
+                    recording.chats = chats;
+                    recording.users = users;
+                },
+                Entry::Update { at_ms, update } => {
+                    recording
+                        .updates
+                        .push((Duration::from_millis(at_ms), update));
+                },
+            }
+        }
+        Ok(recording)
+    }
+}
+
+/// Writes updates as recording entries.
+struct Recorder<W> {
+    out: W,
+    wrote_snapshot: bool,
+}
+
+impl<W: Write> Recorder<W> {
+    const fn new(out: W) -> Self {
+        Self {
+            out,
+            wrote_snapshot: false,
+        }
+    }
+
+    /// Appends `update`, preceded by a snapshot of `cache` the first time.
+    fn record(&mut self, cache: &Cache, update: &Update, at: Duration) -> io::Result<()> {
+        if !self.wrote_snapshot {
+            self.write(&Entry::Snapshot {
+                chats: cache.get_all_chats(),
+                users: cache.get_all_users(),
+            })?;
+            self.wrote_snapshot = true;
+        }
+        self.write(&Entry::Update {
+            at_ms: u64::try_from(at.as_millis()).unwrap_or(u64::MAX),
+            update: update.clone(),
+        })?;
+        // Keep the file useful if the app is killed mid-session
+        self.out.flush()
+    }
+
+    fn write(&mut self, entry: &Entry) -> io::Result<()> {
+        serde_json::to_writer(&mut self.out, entry)?;
+        self.out.write_all(b"\n")
+    }
+}
+
+/// Records updates to `file`.
+///
+/// Every update received on `rx`, typically an [`UpdateHub`] subscription,
+/// is appended to the file. The task ends when the channel closes.
+///
+/// [`UpdateHub`]: super::UpdateHub
+pub fn spawn_recorder(
+    file: File,
+    cache: SharedCache,
+    mut rx: mpsc::Receiver<Update>,
+) -> JoinHandle<()> {
+    let mut recorder = Recorder::new(BufWriter::new(file));
+    let started = Instant::now();
+
+    tokio::spawn(async move {
+        while let Some(update) = rx.recv().await {
+            if let Err(e) = recorder.record(&cache, &update, started.elapsed()) {
+                // Keep the UI running; the recording just stops here
+                warn!("Stopped recording updates: {e}");
+                break;
+            }
+        }
+    })
+}
+
+/// Offline backend that plays back a [`Recording`].
+///
+/// It reports itself as logged in, serves the snapshot as the chat list, and
+/// streams the recorded updates once the app starts its update loop.
+pub struct ReplayTelegram {
+    cache: SharedCache,
+    chats: Vec<Chat>,
+    users: Vec<User>,
+    /// Taken when the update loop starts
+    updates: Mutex<Option<Vec<(Duration, Update)>>>,
+    update_tx: mpsc::Sender<Update>,
+    running: AtomicBool,
+}
+
+impl ReplayTelegram {
+    /// Creates a backend that sends `recording`'s updates to `update_tx`.
+    #[must_use]
+    pub fn new(recording: Recording, cache: SharedCache, update_tx: mpsc::Sender<Update>) -> Self {
+        Self {
+            cache,
+            chats: recording.chats,
+            users: recording.users,
+            updates: Mutex::new(Some(recording.updates)),
+            update_tx,
+            running: AtomicBool::new(false),
+        }
+    }
+
+    /// Returns up to `limit` of the newest cached messages matching `keep`,
+    /// newest first.
+    fn cached_messages(
+        &self,
+        chat_id: i64,
+        limit: usize,
+        keep: impl Fn(&Message) -> bool,
+    ) -> Vec<Message> {
+        let matching: Vec<Message> = self
+            .cache
+            .get_messages(chat_id)
+            .into_iter()
+            .filter(|m| keep(m))
+            .collect();
+        matching.into_iter().rev().take(limit).collect()
+    }
+
+    fn offline<'a, T: Send + 'a>() -> ApiResult<'a, T> {
+        Box::pin(std::future::ready(Err(TelegramError::NotConnected)))
+    }
+
+    fn done<'a>() -> ApiResult<'a, ()> {
+        Box::pin(std::future::ready(Ok(())))
+    }
+}
+
+impl AuthService for ReplayTelegram {
+    fn get_auth_state(&self) -> BoxFuture<'_, AuthState> {
+        Box::pin(std::future::ready(AuthState::Ready))
+    }
+
+    fn request_login_code<'a>(&'a self, _phone: &'a str) -> ApiResult<'a, ()> {
+        Self::offline()
+    }
+
+    fn sign_in<'a>(&'a self, _code: &'a str) -> ApiResult<'a, ()> {
+        Self::offline()
+    }
+
+    fn check_password<'a>(&'a self, _password: &'a str) -> ApiResult<'a, ()> {
+        Self::offline()
+    }
+
+    fn get_me(&self) -> ApiResult<'_, User> {
+        Self::offline()
+    }
+
+    fn log_out(&self) -> ApiResult<'_, ()> {
+        Self::offline()
+    }
+}
+
+impl DialogService for ReplayTelegram {
+    fn get_dialogs(&self) -> ApiResult<'_, Vec<Chat>> {
+        for user in &self.users {
+            self.cache.set_user(user.clone());
+        }
+        for chat in &self.chats {
+            self.cache.set_chat(chat.clone());
+        }
+        Box::pin(std::future::ready(Ok(self.chats.clone())))
+    }
+
+    fn resolve_chat<'a>(&'a self, _handle: &'a PeerHandle) -> ApiResult<'a, Chat> {
+        Self::offline()
+    }
+
+    fn search_global_chats<'a>(
+        &'a self,
+        _query: &'a str,
+        _limit: usize,
+    ) -> ApiResult<'a, Vec<Chat>> {
+        Self::offline()
+    }
+
+    fn mute_chat(&self, _chat_id: i64, _mute: bool) -> ApiResult<'_, ()> {
+        Self::offline()
+    }
+
+    fn mute_chat_for(&self, _chat_id: i64, _duration: chrono::Duration) -> ApiResult<'_, ()> {
+        Self::offline()
+    }
+
+    fn archive_chat(&self, _chat_id: i64, _archive: bool) -> ApiResult<'_, ()> {
+        Self::offline()
+    }
+
+    fn join_chat(&self, _chat_id: i64) -> ApiResult<'_, ()> {
+        Self::offline()
+    }
+
+    fn leave_chat(&self, _chat_id: i64) -> ApiResult<'_, ()> {
+        Self::offline()
+    }
+
+    fn set_auto_delete(&self, _chat_id: i64, _period: i32) -> ApiResult<'_, ()> {
+        Self::offline()
+    }
+
+    fn get_linked_chat(&self, chat_id: i64) -> ApiResult<'_, Option<i64>> {
+        let linked = self
+            .cache
+            .get_chat(chat_id)
+            .map(|c| c.linked_chat_id)
+            .filter(|&id| id != 0);
+        Box::pin(std::future::ready(Ok(linked)))
+    }
+
+    fn get_invite_link(&self, chat_id: i64) -> ApiResult<'_, Option<String>> {
+        let link = self
+            .cache
+            .get_chat(chat_id)
+            .filter(|c| !c.username.is_empty())
+            .map(|c| format!("https://t.me/{}", c.username));
+        Box::pin(std::future::ready(Ok(link)))
+    }
+
+    fn get_send_as(&self, _chat_id: i64) -> ApiResult<'_, Vec<SendAsPeer>> {
+        Box::pin(std::future::ready(Ok(Vec::new())))
+    }
+
+    fn get_members(&self, _chat_id: i64, _limit: usize) -> ApiResult<'_, Vec<User>> {
+        Self::offline()
+    }
+
+    fn set_chat_permissions(
+        &self,
+        _chat_id: i64,
+        _permissions: ChatPermissions,
+    ) -> ApiResult<'_, ()> {
+        Self::offline()
+    }
+
+    fn report_chat(&self, _chat_id: i64, _reason: ReportReason) -> ApiResult<'_, ()> {
+        Self::offline()
+    }
+
+    fn mark_as_read(&self, chat_id: i64) -> ApiResult<'_, ()> {
+        if let Some(mut chat) = self.cache.get_chat(chat_id) {
+            chat.unread_count = 0;
+            chat.missed_calls = 0;
+            chat.unread_mentions = 0;
+            self.cache.set_chat(chat);
+        }
+        Self::done()
+    }
+
+    fn mark_chats_as_read<'a>(
+        &'a self,
+        chat_ids: &'a [i64],
+        progress: &'a (dyn Fn(usize) + Sync),
+    ) -> ApiResult<'a, usize> {
+        for (i, &chat_id) in chat_ids.iter().enumerate() {
+            if let Some(mut chat) = self.cache.get_chat(chat_id) {
+                chat.unread_count = 0;
+                chat.missed_calls = 0;
+                chat.unread_mentions = 0;
+                self.cache.set_chat(chat);
+            }
+            progress(i + 1);
+        }
+        Box::pin(std::future::ready(Ok(chat_ids.len())))
+    }
+}
+
+impl MessageService for ReplayTelegram {
+    fn get_messages(
+        &self,
+        chat_id: i64,
+        limit: usize,
+        offset_id: Option<i64>,
+    ) -> ApiResult<'_, Vec<Message>> {
+        let messages =
+            self.cached_messages(chat_id, limit, |m| offset_id.map_or(true, |id| m.id < id));
+        Box::pin(std::future::ready(Ok(messages)))
+    }
+
+    fn get_messages_before_date(
+        &self,
+        chat_id: i64,
+        limit: usize,
+        date: DateTime<Utc>,
+    ) -> ApiResult<'_, Vec<Message>> {
+        let messages = self.cached_messages(chat_id, limit, |m| m.date < date);
+        Box::pin(std::future::ready(Ok(messages)))
+    }
+
+    fn search_messages<'a>(
+        &'a self,
+        chat_id: Option<i64>,
+        query: &'a str,
+        filter: Option<SearchFilter>,
+        limit: usize,
+    ) -> ApiResult<'a, Vec<Message>> {
+        let query = query.to_lowercase();
+        let chat_ids: Vec<i64> = chat_id.map_or_else(
+            || self.cache.get_all_chats().iter().map(|c| c.id).collect(),
+            |id| vec![id],
+        );
+        let mut messages: Vec<Message> = chat_ids
+            .into_iter()
+            .flat_map(|id| {
+                self.cached_messages(id, usize::MAX, |m| {
+                    m.content.text.to_lowercase().contains(&query)
+                        && filter.map_or(true, |f| f.matches(m))
+                })
+            })
+            .collect();
+        messages.sort_by_key(|m| std::cmp::Reverse(m.date));
+        messages.truncate(limit);
+        Box::pin(std::future::ready(Ok(messages)))
+    }
+
+    fn send_message<'a>(
+        &'a self,
+        _chat_id: i64,
+        _text: &'a str,
+        _reply_to: Option<i64>,
+        _send_as: Option<i64>,
+    ) -> ApiResult<'a, Message> {
+        Self::offline()
+    }
+
+    fn edit_message<'a>(
+        &'a self,
+        _chat_id: i64,
+        _message_id: i64,
+        _new_text: &'a str,
+    ) -> ApiResult<'a, Message> {
+        Self::offline()
+    }
+
+    fn send_reaction<'a>(
+        &'a self,
+        _chat_id: i64,
+        _message_id: i64,
+        _reaction: Option<&'a str>,
+    ) -> ApiResult<'a, Message> {
+        Self::offline()
+    }
+
+    fn delete_messages<'a>(
+        &'a self,
+        _chat_id: i64,
+        _message_ids: &'a [i64],
+        _revoke: bool,
+    ) -> ApiResult<'a, ()> {
+        Self::offline()
+    }
+
+    fn forward_messages<'a>(
+        &'a self,
+        _from_chat_id: i64,
+        _to_chat_id: i64,
+        _message_ids: &'a [i64],
+    ) -> ApiResult<'a, Vec<Message>> {
+        Self::offline()
+    }
+
+    fn forward_messages_without_author<'a>(
+        &'a self,
+        _from_chat_id: i64,
+        _to_chat_id: i64,
+        _message_ids: &'a [i64],
+    ) -> ApiResult<'a, ()> {
+        Self::offline()
+    }
+
+    fn send_typing(&self, _chat_id: i64) -> ApiResult<'_, ()> {
+        Self::done()
+    }
+
+    fn mark_played(&self, _chat_id: i64, _message_id: i64) -> ApiResult<'_, ()> {
+        Self::offline()
+    }
+
+    fn vote_poll<'a>(
+        &'a self,
+        _chat_id: i64,
+        _message_id: i64,
+        _options: &'a [Vec<u8>],
+    ) -> ApiResult<'a, ()> {
+        Self::offline()
+    }
+
+    fn get_poll_voters<'a>(
+        &'a self,
+        _chat_id: i64,
+        _message_id: i64,
+        _option: &'a [u8],
+        _offset: Option<&'a str>,
+        _limit: usize,
+    ) -> ApiResult<'a, PollVoters> {
+        Self::offline()
+    }
+
+    fn report_messages<'a>(
+        &'a self,
+        _chat_id: i64,
+        _message_ids: &'a [i64],
+        _reason: ReportReason,
+    ) -> ApiResult<'a, ()> {
+        Self::offline()
+    }
+}
+
+impl MediaService for ReplayTelegram {
+    fn send_file<'a>(
+        &'a self,
+        _chat_id: i64,
+        _text: &'a str,
+        _path: &'a Path,
+        _as_photo: bool,
+        _reply_to: Option<i64>,
+    ) -> ApiResult<'a, Message> {
+        Self::offline()
+    }
+
+    fn send_album<'a>(
+        &'a self,
+        _chat_id: i64,
+        _text: &'a str,
+        _paths: &'a [PathBuf],
+        _reply_to: Option<i64>,
+    ) -> ApiResult<'a, Vec<Message>> {
+        Self::offline()
+    }
+
+    fn fetch_media<'a>(
+        &'a self,
+        _message: &'a Message,
+        _download_dir: &'a Path,
+    ) -> ApiResult<'a, PathBuf> {
+        Self::offline()
+    }
+
+    fn spawn_media_download(
+        self: Arc<Self>,
+        mut message: Message,
+        _download_dir: PathBuf,
+        _save_to: Option<PathBuf>,
+    ) {
+        let error = TelegramError::NotConnected.to_string();
+        message
+            .content
+            .set_download_status(DownloadStatus::Failed, Some(error.clone()));
+        let update = Update {
+            update_type: UpdateType::FileDownload,
+            chat_id: message.chat_id,
+            message: Some(Box::new(message)),
+            data: UpdateData::FileDownload(Box::new(FileDownload {
+                state: FileDownloadState::Failed,
+                error: Some(error),
+                ..Default::default()
+            })),
+        };
+        tokio::spawn(async move {
+            let _ = self.update_tx.send(update).await;
+        });
+    }
+}
+
+impl UpdateService for ReplayTelegram {
+    fn set_online(&self, _online: bool) -> ApiResult<'_, ()> {
+        Self::done()
+    }
+
+    fn is_update_loop_running(&self) -> bool {
+        self.running.load(Ordering::SeqCst)
+    }
+
+    fn run_update_loop(&self) -> ApiResult<'_, ()> {
+        Box::pin(async move {
+            let updates = self.updates.lock().unwrap().take();
+            let Some(updates) = updates else {
+                return Ok(());
+            };
+            self.running.store(true, Ordering::SeqCst);
+
+            let started = Instant::now();
+            for (at, update) in updates {
+                tokio::time::sleep_until(started + at).await;
+                if self.update_tx.send(update).await.is_err() {
+                    break;
+                }
+            }
+
+            self.running.store(false, Ordering::SeqCst);
+            Ok(())
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::cache::new_shared_cache;
+    use crate::types::MessageContent;
+
+    fn update(id: i64, text: &str) -> Update {
+        Update {
+            update_type: UpdateType::NewMessage,
+            chat_id: 7,
+            message: Some(Box::new(Message {
+                id,
+                chat_id: 7,
+                content: MessageContent {
+                    text: text.to_string(),
+                    ..Default::default()
+                },
+                ..Default::default()
+            })),
+            data: UpdateData::None,
+        }
+    }
+
+    fn text(update: &Update) -> &str {
+        &update.message.as_ref().unwrap().content.text
+    }
+
+    #[test]
+    fn recording_round_trips() {
+        let cache = new_shared_cache(10);
+        cache.set_chat(Chat {
+            id: 7,
+            title: "Seven".to_string(),
+            ..Default::default()
+        });
+
+        let mut recorder = Recorder::new(Vec::new());
+        recorder
+            .record(&cache, &update(1, "first"), Duration::from_millis(5))
+            .unwrap();
+        recorder
+            .record(&cache, &update(2, "second"), Duration::from_millis(250))
+            .unwrap();
+
+        let out = String::from_utf8(recorder.out).unwrap();
+        // One snapshot, then one line per update
+        assert_eq!(out.lines().count(), 3);
+
+        let recording = Recording::parse(out.as_bytes()).unwrap();
+        assert_eq!(recording.chats.len(), 1);
+        assert_eq!(recording.chats[0].title, "Seven");
+        assert_eq!(recording.updates.len(), 2);
+        assert_eq!(recording.updates[1].0, Duration::from_millis(250));
+        assert_eq!(text(&recording.updates[1].1), "second");
+    }
+
+    #[test]
+    fn bad_lines_are_reported_with_their_number() {
+        let input = "\n{\"kind\":\"snapshot\",\"chats\":[],\"users\":[]}\nnot json\n";
+        let err = Recording::parse(input.as_bytes()).unwrap_err();
+        assert_eq!(err.kind(), io::ErrorKind::InvalidData);
+        assert!(err.to_string().starts_with("line 3:"));
+    }
+
+    #[tokio::test]
+    async fn replays_updates_in_order_with_their_timing() {
+        let (tx, mut rx) = mpsc::channel(8);
+        let recording = Recording {
+            updates: vec![
+                (Duration::from_millis(20), update(1, "first")),
+                (Duration::from_millis(60), update(2, "second")),
+            ],
+            ..Default::default()
+        };
+        let replay = Arc::new(ReplayTelegram::new(recording, new_shared_cache(10), tx));
+
+        let started = Instant::now();
+        let task = tokio::spawn({
+            let replay = Arc::clone(&replay);
+            async move { replay.run_update_loop().await }
+        });
+
+        assert_eq!(text(&rx.recv().await.unwrap()), "first");
+        assert!(started.elapsed() >= Duration::from_millis(20));
+        assert_eq!(text(&rx.recv().await.unwrap()), "second");
+        assert!(started.elapsed() >= Duration::from_millis(60));
+
+        task.await.unwrap().unwrap();
+        assert!(!replay.is_update_loop_running());
+        assert!(matches!(
+            replay.send_message(7, "hi", None, None).await,
+            Err(TelegramError::NotConnected)
+        ));
+    }
+}
@@ -7,15 +7,29 @@
 //! - Message deletions
 //! - Chat updates
 //! - User status changes
+//! - Reactions to the user's own messages
+//!
+//! The echo of a message the user sent replaces the optimistic copy
+//! cached while the send was in flight.
+//!
+//! Reads, pins and deletions made on the user's other devices arrive here
+//! too, and are applied to the cache so unread counts, pins and chat order
+//! stay in step with them.
+//!
+//! Being added to a group or channel, or made an admin in one, is reported
+//! as a membership update, after fetching the chat if it's a new one.
 
 use grammers_client::client::UpdateStream;
 use grammers_client::update::Update as GrammersUpdate;
 use tracing::{debug, error, info, trace, warn};
 
-use super::chats::grammers_message_to_message;
+use super::chats::{grammers_message_to_message, permissions_from_banned_rights};
 use super::client::TelegramClient;
 use super::error::TelegramError;
-use crate::types::{Update, UpdateData, UpdateType};
+use crate::types::{
+    CallInfo, MembershipChange, MessageReaction, ReactionEvent, ServiceAction, Update, UpdateData,
+    UpdateType,
+};
 
 impl TelegramClient {
     /// Starts the update loop.
@@ -146,14 +160,39 @@ impl TelegramClient {
 
                 // Update cache
                 self.cache().add_message(chat_id, message.clone());
+                self.queue_sender_lookup(chat_id, std::slice::from_ref(&message))
+                    .await;
 
                 // Update chat's has_new_message flag
                 if let Some(mut chat) = self.cache().get_chat(chat_id) {
                     chat.has_new_message = true;
+                    if message
+                        .content
+                        .call
+                        .as_ref()
+                        .is_some_and(CallInfo::is_missed)
+                    {
+                        chat.missed_calls += 1;
+                    }
+                    if message.mentions_me {
+                        chat.unread_mentions += 1;
+                    }
                     chat.last_message = Some(Box::new(message.clone()));
                     self.cache().set_chat(chat);
                 }
 
+                // Someone adding the user says so in a message of its own
+                if let Some(ServiceAction::MembersAdded(users)) = &message.content.service {
+                    if self.my_id().await.is_some_and(|me| users.contains(&me)) {
+                        let update = self
+                            .membership_update(chat_id, MembershipChange::Added)
+                            .await;
+                        if let Some(tx) = self.get_update_sender().await {
+                            let _ = tx.send(update).await;
+                        }
+                    }
+                }
+
                 Some(Update {
                     update_type: UpdateType::NewMessage,
                     chat_id,
@@ -168,8 +207,9 @@ impl TelegramClient {
                 let message = grammers_message_to_message(&msg);
                 let chat_id = message.chat_id;
 
-                // Update cache - the message might already be there from send_message
-                self.cache().add_message(chat_id, message.clone());
+                // If this confirms a send still in flight, its optimistic copy
+                // goes; the UI is told which local ID to replace
+                let replaced = self.cache().settle_send(chat_id, message.clone());
 
                 // Update chat's last message
                 if let Some(mut chat) = self.cache().get_chat(chat_id) {
@@ -181,7 +221,7 @@ impl TelegramClient {
                     update_type: UpdateType::NewMessage,
                     chat_id,
                     message: Some(Box::new(message)),
-                    data: UpdateData::None,
+                    data: replaced.map_or(UpdateData::None, UpdateData::Integer),
                 })
             },
 
@@ -205,25 +245,25 @@ impl TelegramClient {
             GrammersUpdate::MessageDeleted(deletion) => {
                 debug!("Received message deletion");
 
-                // Get the message IDs
-                let message_ids = deletion.messages();
-                let channel_id = deletion.channel_id();
+                let message_ids: Vec<i64> =
+                    deletion.messages().iter().copied().map(i64::from).collect();
 
-                // We need to determine the chat_id
-                // For channels/supergroups, we have the channel_id
-                // For private chats, we don't have the chat_id in the deletion
-                let chat_id = channel_id.unwrap_or(0);
+                // Channels and supergroups name themselves; private chats and
+                // basic groups share one numbering, so look the chat up by
+                // the messages we have
+                let chat_id = deletion.channel_id().or_else(|| {
+                    message_ids
+                        .iter()
+                        .find_map(|&id| self.cache().find_message_chat(id))
+                })?;
 
-                // Update cache - remove deleted messages
-                for msg_id in message_ids {
-                    self.cache().delete_message(chat_id, i64::from(*msg_id));
-                }
+                self.forget_deleted_messages(chat_id, &message_ids);
 
                 Some(Update {
                     update_type: UpdateType::MessageDeleted,
                     chat_id,
                     message: None,
-                    data: UpdateData::None,
+                    data: UpdateData::MessageIds(message_ids),
                 })
             },
 
@@ -258,19 +298,7 @@ impl TelegramClient {
                     chat_id, max_id, still_unread_count
                 );
 
-                // Update cache
-                if let Some(mut chat) = self.cache().get_chat(chat_id) {
-                    chat.last_read_inbox_id = i64::from(max_id);
-                    chat.unread_count = still_unread_count;
-                    self.cache().set_chat(chat);
-                }
-
-                Some(Update {
-                    update_type: UpdateType::ChatReadInbox,
-                    chat_id,
-                    message: None,
-                    data: UpdateData::Integer(i64::from(max_id)),
-                })
+                Some(self.apply_read_inbox(chat_id, max_id, still_unread_count))
             },
 
             TlUpdate::ReadHistoryOutbox(types::UpdateReadHistoryOutbox {
@@ -278,45 +306,68 @@ impl TelegramClient {
             }) => {
                 let chat_id = peer_to_chat_id(&peer);
                 debug!("Read outbox update for chat {}: max_id={}", chat_id, max_id);
+                Some(self.apply_read_outbox(chat_id, max_id))
+            },
+
+            TlUpdate::ReadChannelOutbox(types::UpdateReadChannelOutbox { channel_id, max_id }) => {
+                debug!("Read channel outbox for {}: max_id={}", channel_id, max_id);
+                Some(self.apply_read_outbox(channel_id, max_id))
+            },
+
+            TlUpdate::ReadChannelInbox(types::UpdateReadChannelInbox {
+                channel_id,
+                max_id,
+                still_unread_count,
+                ..
+            }) => {
+                debug!(
+                    "Read channel inbox for {}: max_id={}, unread={}",
+                    channel_id, max_id, still_unread_count
+                );
+                Some(self.apply_read_inbox(channel_id, max_id, still_unread_count))
+            },
+
+            TlUpdate::PeerHistoryTtl(types::UpdatePeerHistoryTtl {
+                peer, ttl_period, ..
+            }) => {
+                let chat_id = peer_to_chat_id(&peer);
+                let period = ttl_period.unwrap_or(0);
+                debug!("Auto-delete for chat {} set to {}s", chat_id, period);
 
                 // Update cache
                 if let Some(mut chat) = self.cache().get_chat(chat_id) {
-                    chat.last_read_outbox_id = i64::from(max_id);
+                    chat.auto_delete_period = period;
                     self.cache().set_chat(chat);
                 }
 
                 Some(Update {
-                    update_type: UpdateType::ChatReadOutbox,
+                    update_type: UpdateType::ChatAutoDelete,
                     chat_id,
                     message: None,
-                    data: UpdateData::Integer(i64::from(max_id)),
+                    data: UpdateData::Integer(i64::from(period)),
                 })
             },
 
-            TlUpdate::ReadChannelInbox(types::UpdateReadChannelInbox {
-                channel_id,
-                max_id,
-                still_unread_count,
+            TlUpdate::ChatDefaultBannedRights(types::UpdateChatDefaultBannedRights {
+                peer,
+                default_banned_rights,
                 ..
             }) => {
-                let chat_id = channel_id;
-                debug!(
-                    "Read channel inbox for {}: max_id={}, unread={}",
-                    chat_id, max_id, still_unread_count
-                );
+                let chat_id = peer_to_chat_id(&peer);
+                debug!("Default permissions for chat {} changed", chat_id);
 
                 // Update cache
                 if let Some(mut chat) = self.cache().get_chat(chat_id) {
-                    chat.last_read_inbox_id = i64::from(max_id);
-                    chat.unread_count = still_unread_count;
+                    chat.default_permissions =
+                        Some(permissions_from_banned_rights(&default_banned_rights));
                     self.cache().set_chat(chat);
                 }
 
                 Some(Update {
-                    update_type: UpdateType::ChatReadInbox,
+                    update_type: UpdateType::ChatPermissions,
                     chat_id,
                     message: None,
-                    data: UpdateData::Integer(i64::from(max_id)),
+                    data: UpdateData::None,
                 })
             },
 
@@ -339,6 +390,62 @@ impl TelegramClient {
                 })
             },
 
+            // Names the server ID of one of our sends, sometimes before the
+            // send's own reply arrives
+            TlUpdate::MessageId(types::UpdateMessageId { id, random_id }) => {
+                self.cache().confirm_send(random_id, i64::from(id));
+                None
+            },
+
+            TlUpdate::UserTyping(types::UpdateUserTyping {
+                user_id, action, ..
+            }) => typing_update(user_id, user_id, &action),
+
+            TlUpdate::ChatUserTyping(types::UpdateChatUserTyping {
+                chat_id,
+                from_id,
+                action,
+            }) => typing_update(chat_id, peer_to_chat_id(&from_id), &action),
+
+            TlUpdate::ChannelUserTyping(types::UpdateChannelUserTyping {
+                channel_id,
+                from_id,
+                action,
+                ..
+            }) => typing_update(channel_id, peer_to_chat_id(&from_id), &action),
+
+            TlUpdate::ChannelParticipant(types::UpdateChannelParticipant {
+                channel_id,
+                user_id,
+                prev_participant,
+                new_participant,
+                ..
+            }) => {
+                if self.my_id().await != Some(user_id) {
+                    return None;
+                }
+                let change =
+                    membership_change(prev_participant.as_ref(), new_participant.as_ref())?;
+                debug!("Membership in channel {} changed: {:?}", channel_id, change);
+                Some(self.membership_update(channel_id, change).await)
+            },
+
+            TlUpdate::ChatParticipantAdmin(types::UpdateChatParticipantAdmin {
+                chat_id,
+                user_id,
+                is_admin,
+                ..
+            }) => {
+                if !is_admin || self.my_id().await != Some(user_id) {
+                    return None;
+                }
+                debug!("Made an admin in chat {}", chat_id);
+                Some(
+                    self.membership_update(chat_id, MembershipChange::Promoted)
+                        .await,
+                )
+            },
+
             TlUpdate::ChatParticipants(_) => {
                 debug!("Chat participants update");
                 None // We don't track participants yet
@@ -368,9 +475,113 @@ impl TelegramClient {
                 })
             },
 
-            TlUpdate::PinnedDialogs(_) => {
-                debug!("Pinned dialogs update");
-                // Refresh dialogs to get the new order
+            TlUpdate::MessageReactions(types::UpdateMessageReactions {
+                peer,
+                msg_id,
+                reactions,
+                ..
+            }) => {
+                let chat_id = peer_to_chat_id(&peer);
+                let events = unread_reactions(chat_id, i64::from(msg_id), &reactions);
+
+                // Keep the reaction bar of a loaded message current
+                let message = self
+                    .cache()
+                    .get_messages(chat_id)
+                    .into_iter()
+                    .find(|m| m.id == i64::from(msg_id))
+                    .map(|mut message| {
+                        message.reactions = message_reactions(&reactions);
+                        self.cache().update_message(chat_id, message.clone());
+                        Box::new(message)
+                    });
+
+                if events.is_empty() && message.is_none() {
+                    trace!("No new reactions for us on {}/{}", chat_id, msg_id);
+                    return None;
+                }
+                debug!(
+                    "{} new reaction(s) on message {} in chat {}",
+                    events.len(),
+                    msg_id,
+                    chat_id
+                );
+
+                Some(Update {
+                    update_type: UpdateType::MessageReactions,
+                    chat_id,
+                    message,
+                    data: UpdateData::Reactions(events),
+                })
+            },
+
+            TlUpdate::DialogPinned(types::UpdateDialogPinned {
+                pinned,
+                folder_id,
+                peer,
+            }) => {
+                let grammers_client::tl::enums::DialogPeer::Peer(types::DialogPeer { peer }) = peer
+                else {
+                    trace!("Ignoring pinned folder");
+                    return None;
+                };
+                let chat_id = peer_to_chat_id(&peer);
+                debug!(
+                    "Chat {} {} in folder {:?}",
+                    chat_id,
+                    if pinned { "pinned" } else { "unpinned" },
+                    folder_id
+                );
+
+                let mut chat = self.cache().get_chat(chat_id)?;
+                if pinned && !chat.is_pinned {
+                    // New pins go to the top
+                    chat.pin_order = self
+                        .cache()
+                        .get_all_chats()
+                        .iter()
+                        .filter(|c| c.is_pinned)
+                        .map(|c| c.pin_order)
+                        .min()
+                        .map_or(0, |top| top - 1);
+                }
+                chat.is_pinned = pinned;
+                self.cache().set_chat(chat);
+
+                Some(Update {
+                    update_type: UpdateType::ChatPosition,
+                    chat_id,
+                    message: None,
+                    data: UpdateData::None,
+                })
+            },
+
+            TlUpdate::PinnedDialogs(types::UpdatePinnedDialogs { folder_id, order }) => {
+                debug!("Pinned dialogs reordered in folder {:?}", folder_id);
+
+                // Without an order the pins can't be known without
+                // refetching; the next dialog load picks them up
+                let order: Vec<i64> = order?
+                    .iter()
+                    .filter_map(|peer| match peer {
+                        grammers_client::tl::enums::DialogPeer::Peer(p) => {
+                            Some(peer_to_chat_id(&p.peer))
+                        },
+                        grammers_client::tl::enums::DialogPeer::Folder(_) => None,
+                    })
+                    .collect();
+                for mut chat in self.cache().get_all_chats() {
+                    let position = order.iter().position(|&id| id == chat.id);
+                    // Only the main list's order unpins the chats missing
+                    // from it
+                    if position.is_none() && (!chat.is_pinned || folder_id.is_some()) {
+                        continue;
+                    }
+                    chat.is_pinned = position.is_some();
+                    chat.pin_order = position.map_or(0, |p| i32::try_from(p).unwrap_or(i32::MAX));
+                    self.cache().set_chat(chat);
+                }
+
                 Some(Update {
                     update_type: UpdateType::ChatPosition,
                     chat_id: 0,
@@ -385,6 +596,121 @@ impl TelegramClient {
             },
         }
     }
+
+    /// Builds the update saying the user's place in a chat changed. A chat
+    /// the user was just added to isn't among the dialogs loaded so far,
+    /// so they're fetched again to bring it in.
+    async fn membership_update(&self, chat_id: i64, change: MembershipChange) -> Update {
+        if self.cache().get_chat(chat_id).is_none() {
+            if let Err(e) = self.get_dialogs().await {
+                warn!("Failed to fetch the chat the user was added to: {}", e);
+            }
+        }
+
+        Update {
+            update_type: UpdateType::Membership,
+            chat_id,
+            message: None,
+            data: UpdateData::Membership(change),
+        }
+    }
+
+    /// Records that messages up to `max_id` were read, here or on another
+    /// device, and that `unread` messages remain.
+    fn apply_read_inbox(&self, chat_id: i64, max_id: i32, unread: i32) -> Update {
+        if let Some(mut chat) = self.cache().get_chat(chat_id) {
+            chat.last_read_inbox_id = chat.last_read_inbox_id.max(i64::from(max_id));
+            chat.unread_count = unread;
+            if unread == 0 {
+                chat.has_new_message = false;
+                chat.unread_mentions = 0;
+            }
+            self.cache().set_chat(chat);
+        }
+
+        Update {
+            update_type: UpdateType::ChatReadInbox,
+            chat_id,
+            message: None,
+            data: UpdateData::Integer(i64::from(max_id)),
+        }
+    }
+
+    /// Records that the other side read my messages up to `max_id`.
+    fn apply_read_outbox(&self, chat_id: i64, max_id: i32) -> Update {
+        if let Some(mut chat) = self.cache().get_chat(chat_id) {
+            chat.last_read_outbox_id = chat.last_read_outbox_id.max(i64::from(max_id));
+            self.cache().set_chat(chat);
+        }
+
+        Update {
+            update_type: UpdateType::ChatReadOutbox,
+            chat_id,
+            message: None,
+            data: UpdateData::Integer(i64::from(max_id)),
+        }
+    }
+
+    /// Removes deleted messages from the cache, and keeps the chat's last
+    /// message and unread count in step.
+    fn forget_deleted_messages(&self, chat_id: i64, message_ids: &[i64]) {
+        let Some(mut chat) = self.cache().get_chat(chat_id) else {
+            for &id in message_ids {
+                self.cache().delete_message(chat_id, id);
+            }
+            return;
+        };
+
+        // Telegram doesn't resend the unread count when unread messages go
+        let unread_deleted = self
+            .cache()
+            .get_messages(chat_id)
+            .iter()
+            .filter(|m| {
+                message_ids.contains(&m.id) && !m.is_outgoing && m.id > chat.last_read_inbox_id
+            })
+            .count();
+        for &id in message_ids {
+            self.cache().delete_message(chat_id, id);
+        }
+
+        chat.unread_count = chat
+            .unread_count
+            .saturating_sub(i32::try_from(unread_deleted).unwrap_or(i32::MAX))
+            .max(0);
+        if chat
+            .last_message
+            .as_ref()
+            .is_some_and(|m| message_ids.contains(&m.id))
+        {
+            chat.last_message = self.cache().get_messages(chat_id).pop().map(Box::new);
+        }
+        self.cache().set_chat(chat);
+    }
+}
+
+/// Builds a typing update for `chat_id`.
+///
+/// Carries the typist's ID when they start typing and no data when they
+/// stop; other actions (recording, uploading) are ignored.
+fn typing_update(
+    chat_id: i64,
+    user_id: i64,
+    action: &grammers_client::tl::enums::SendMessageAction,
+) -> Option<Update> {
+    use grammers_client::tl::enums::SendMessageAction;
+
+    let data = match action {
+        SendMessageAction::SendMessageTypingAction => UpdateData::Integer(user_id),
+        SendMessageAction::SendMessageCancelAction => UpdateData::None,
+        _ => return None,
+    };
+    Some(Update {
+        update_type: UpdateType::UserTyping,
+        chat_id,
+        message: None,
+        data,
+    })
 }
 
 /// Converts a TL Peer to a chat ID.
@@ -398,8 +724,97 @@ const fn peer_to_chat_id(peer: &grammers_client::tl::enums::Peer) -> i64 {
     }
 }
 
+/// Tells how the user's place in a channel changed from `prev` to `new`,
+/// if it's worth pointing out: joining it, or being made an admin.
+fn membership_change(
+    prev: Option<&grammers_client::tl::enums::ChannelParticipant>,
+    new: Option<&grammers_client::tl::enums::ChannelParticipant>,
+) -> Option<MembershipChange> {
+    use grammers_client::tl::enums::ChannelParticipant as P;
+
+    let is_member = |p: Option<&P>| !matches!(p, None | Some(P::Left(_) | P::Banned(_)));
+    let is_admin = |p: Option<&P>| matches!(p, Some(P::Admin(_) | P::Creator(_)));
+    if !is_member(new) {
+        None
+    } else if !is_member(prev) {
+        Some(MembershipChange::Added)
+    } else if is_admin(new) && !is_admin(prev) {
+        Some(MembershipChange::Promoted)
+    } else {
+        None
+    }
+}
+
+/// Extracts the reactions on a message that Telegram flags as unread.
+///
+/// Telegram only sets the unread flag on reactions others leave on the
+/// current user's messages, which is exactly what the reactions feed shows.
+fn unread_reactions(
+    chat_id: i64,
+    message_id: i64,
+    reactions: &grammers_client::tl::enums::MessageReactions,
+) -> Vec<ReactionEvent> {
+    use grammers_client::tl::enums::{MessagePeerReaction, MessageReactions};
+
+    let MessageReactions::Reactions(reactions) = reactions;
+    reactions
+        .recent_reactions
+        .iter()
+        .flatten()
+        .filter_map(|r| {
+            let MessagePeerReaction::Reaction(r) = r;
+            (r.unread && !r.my).then(|| ReactionEvent {
+                chat_id,
+                message_id,
+                sender_id: peer_to_chat_id(&r.peer_id),
+                reaction: reaction_label(&r.reaction),
+                date: chrono::DateTime::from_timestamp(i64::from(r.date), 0).unwrap_or_default(),
+            })
+        })
+        .collect()
+}
+
+/// Converts a message's TL reactions to our `MessageReaction`s, most given
+/// first.
+pub(crate) fn message_reactions(
+    reactions: &grammers_client::tl::enums::MessageReactions,
+) -> Vec<MessageReaction> {
+    use grammers_client::tl::enums::{MessageReactions, Reaction, ReactionCount};
+
+    let MessageReactions::Reactions(reactions) = reactions;
+    let mut counts: Vec<MessageReaction> = reactions
+        .results
+        .iter()
+        .map(|count| {
+            let ReactionCount::Count(count) = count;
+            MessageReaction {
+                reaction: reaction_label(&count.reaction),
+                count: count.count,
+                chosen: count.chosen_order.is_some(),
+                premium: matches!(count.reaction, Reaction::CustomEmoji(_) | Reaction::Paid),
+            }
+        })
+        .filter(|r| !r.reaction.is_empty())
+        .collect();
+    counts.sort_by(|a, b| b.count.cmp(&a.count));
+    counts
+}
+
+/// Returns a printable form of a TL reaction.
+fn reaction_label(reaction: &grammers_client::tl::enums::Reaction) -> String {
+    use grammers_client::tl::enums::Reaction;
+
+    match reaction {
+        Reaction::Emoji(e) => e.emoticon.clone(),
+        // Custom emoji are documents we can't draw in a terminal
+        Reaction::CustomEmoji(_) => "\u{2728}".to_string(),
+        Reaction::Paid => "\u{2b50}".to_string(),
+        Reaction::Empty => String::new(),
+    }
+}
+
 /// Converts a TL `UserStatus` to our `UserStatus` type.
-const fn tl_status_to_user_status(
+pub(crate) const fn tl_status_to_user_status(
     status: &grammers_client::tl::enums::UserStatus,
 ) -> crate::types::UserStatus {
     use crate::types::UserStatus;
@@ -434,6 +849,92 @@ mod tests {
         assert_eq!(peer_to_chat_id(&channel_peer), 11111);
     }
 
+    #[test]
+    fn test_unread_reactions_keeps_only_new_reactions_from_others() {
+        use grammers_client::tl::{enums, types};
+
+        let peer_reaction = |user_id: i64, emoticon: &str, unread: bool, my: bool| {
+            enums::MessagePeerReaction::Reaction(types::MessagePeerReaction {
+                big: false,
+                unread,
+                my,
+                peer_id: enums::Peer::User(types::PeerUser { user_id }),
+                date: 1_700_000_000,
+                reaction: enums::Reaction::Emoji(types::ReactionEmoji {
+                    emoticon: emoticon.to_string(),
+                }),
+            })
+        };
+        let reactions = enums::MessageReactions::Reactions(types::MessageReactions {
+            min: false,
+            can_see_list: true,
+            reactions_as_tags: false,
+            results: Vec::new(),
+            recent_reactions: Some(vec![
+                peer_reaction(1, "\u{1f44d}", true, false),
+                peer_reaction(2, "\u{2764}", false, false),
+                peer_reaction(3, "\u{1f525}", true, true),
+            ]),
+            top_reactors: None,
+        });
+
+        let events = unread_reactions(-100, 42, &reactions);
+        assert_eq!(events.len(), 1);
+        assert_eq!(events[0].chat_id, -100);
+        assert_eq!(events[0].message_id, 42);
+        assert_eq!(events[0].sender_id, 1);
+        assert_eq!(events[0].reaction, "\u{1f44d}");
+    }
+
+    #[test]
+    fn message_reactions_flag_premium_and_my_own() {
+        use grammers_client::tl::{enums, types};
+
+        let count = |reaction: enums::Reaction, count: i32, mine: bool| {
+            enums::ReactionCount::Count(types::ReactionCount {
+                chosen_order: mine.then_some(0),
+                reaction,
+                count,
+            })
+        };
+        let reactions = enums::MessageReactions::Reactions(types::MessageReactions {
+            min: false,
+            can_see_list: true,
+            reactions_as_tags: false,
+            results: vec![
+                count(
+                    enums::Reaction::CustomEmoji(types::ReactionCustomEmoji { document_id: 7 }),
+                    2,
+                    false,
+                ),
+                count(
+                    enums::Reaction::Emoji(types::ReactionEmoji {
+                        emoticon: "\u{1f44d}".to_string(),
+                    }),
+                    5,
+                    true,
+                ),
+                count(enums::Reaction::Paid, 1, false),
+            ],
+            recent_reactions: None,
+            top_reactors: None,
+        });
+
+        let counts = message_reactions(&reactions);
+        let summary: Vec<(&str, i32, bool, bool)> = counts
+            .iter()
+            .map(|r| (r.reaction.as_str(), r.count, r.chosen, r.premium))
+            .collect();
+        assert_eq!(
+            summary,
+            [
+                ("\u{1f44d}", 5, true, false),
+                ("\u{2728}", 2, false, true),
+                ("\u{2b50}", 1, false, true),
+            ]
+        );
+    }
+
     #[test]
     fn test_tl_status_to_user_status() {
         use grammers_client::tl::types;
@@ -6,6 +6,8 @@
 //! # Design Decisions
 //!
 //! - All types derive `Debug`, `Clone`, and implement `Default` where sensible
+//! - All types are serializable so update streams can be recorded and
+//!   replayed; struct fields missing from a recording take their defaults
 //! - `Option<T>` is used instead of pointer types for nullable fields
 //! - `chrono::DateTime<Utc>` is used for all timestamp fields
 //! - Enums use explicit discriminants for clarity and future serialization
@@ -15,6 +17,7 @@
 #![allow(clippy::cast_precision_loss)]
 
 use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
 use std::fmt;
 use std::time::Duration;
 
@@ -23,7 +26,7 @@ use std::time::Duration;
 // ============================================================================
 
 /// Represents the online status of a Telegram user.
-#[derive(Debug, Clone, Copy, PartialEq, Eq, Default, Hash)]
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Default, Hash)]
 pub enum UserStatus {
     /// User is currently online
     Online,
@@ -51,7 +54,8 @@ impl fmt::Display for UserStatus {
 }
 
 /// Represents a Telegram user.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct User {
     /// Unique user identifier
     pub id: i64,
@@ -128,7 +132,7 @@ impl User {
 // ============================================================================
 
 /// Represents the type of a Telegram chat.
-#[derive(Debug, Clone, Copy, PartialEq, Eq, Default, Hash)]
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Default, Hash)]
 pub enum ChatType {
     /// Private one-on-one conversation
     #[default]
@@ -156,7 +160,8 @@ impl fmt::Display for ChatType {
 }
 
 /// Represents notification settings for a chat.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct NotificationSettings {
     /// Duration to mute notifications (in seconds, 0 = not muted)
     pub mute_for: i32,
@@ -173,7 +178,8 @@ pub struct NotificationSettings {
 }
 
 /// Represents a draft message in a chat.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct Draft {
     /// ID of the message being replied to (0 if not a reply)
     pub reply_to_message_id: i64,
@@ -184,7 +190,8 @@ pub struct Draft {
 }
 
 /// Represents a custom chat folder/filter.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct ChatFilter {
     /// Unique filter identifier
     pub id: i32,
@@ -209,7 +216,8 @@ pub struct ChatFilter {
 }
 
 /// Represents a Telegram chat (private, group, supergroup, or channel).
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct Chat {
     /// Unique chat identifier
     pub id: i64,
@@ -231,6 +239,26 @@ pub struct Chat {
     pub pin_order: i32,
     /// Whether notifications are muted
     pub is_muted: bool,
+    /// Auto-delete timer for new messages in seconds (0 when off)
+    pub auto_delete_period: i32,
+    /// Whether the current user may change the auto-delete timer
+    pub can_set_auto_delete: bool,
+    /// What members may do by default (groups and supergroups only)
+    pub default_permissions: Option<ChatPermissions>,
+    /// Whether the current user may change the default permissions
+    pub can_edit_permissions: bool,
+    /// Whether the current user can't send messages here, as in a channel
+    /// they don't admin or a group they're restricted in
+    pub is_read_only: bool,
+    /// A channel's discussion group, or a discussion group's channel (0 if
+    /// none or not yet fetched)
+    pub linked_chat_id: i64,
+    /// Members of a group or subscribers of a channel (0 if unknown)
+    pub member_count: i32,
+    /// Calls missed since the chat was last read
+    pub missed_calls: i32,
+    /// Unread messages that mention or reply to the user
+    pub unread_mentions: i32,
     /// Draft message text
     pub draft_message: String,
     /// ID of the last read incoming message
@@ -247,12 +275,253 @@ pub struct Chat {
     pub has_new_message: bool,
 }
 
+impl Chat {
+    /// Returns a link that opens the chat, or one of its messages, in the
+    /// official apps: `t.me/<username>[/<post>]` for public chats,
+    /// `t.me/c/<id>/<post>` for private channels and supergroups, and
+    /// `tg://user?id=<id>` for people without a username.
+    ///
+    /// Private chats have no message links, so those open the chat, and
+    /// private channels only have message links, so those open the last
+    /// message. Basic groups and secret chats can't be linked to.
+    #[must_use]
+    pub fn telegram_link(&self, message_id: Option<i64>) -> Option<String> {
+        let post = |base: String| match message_id {
+            Some(id) => format!("{base}/{id}"),
+            None => base,
+        };
+        match self.chat_type {
+            ChatType::Private if self.username.is_empty() => {
+                Some(format!("tg://user?id={}", self.id))
+            },
+            ChatType::Private => Some(format!("https://t.me/{}", self.username)),
+            ChatType::Channel | ChatType::Supergroup if !self.username.is_empty() => {
+                Some(post(format!("https://t.me/{}", self.username)))
+            },
+            ChatType::Channel | ChatType::Supergroup => {
+                let message_id = message_id.or_else(|| self.last_message.as_ref().map(|m| m.id))?;
+                Some(format!("https://t.me/c/{}/{message_id}", self.id))
+            },
+            ChatType::Group | ChatType::Secret => None,
+        }
+    }
+}
+
+/// What members of a group may do unless an admin says otherwise.
+#[derive(Debug, Clone, Copy, Serialize, Deserialize, PartialEq, Eq, Hash)]
+#[serde(default)]
+pub struct ChatPermissions {
+    /// Send text messages
+    pub send_messages: bool,
+    /// Send photos, videos, files, stickers, and other media
+    pub send_media: bool,
+    /// Add new members
+    pub add_members: bool,
+    /// Pin messages
+    pub pin_messages: bool,
+    /// Change the chat's title, photo, and description
+    pub change_info: bool,
+}
+
+impl Default for ChatPermissions {
+    fn default() -> Self {
+        Self {
+            send_messages: true,
+            send_media: true,
+            add_members: true,
+            pin_messages: false,
+            change_info: false,
+        }
+    }
+}
+
+impl ChatPermissions {
+    /// Number of flags, in the order of [`Self::flags`].
+    pub const COUNT: usize = 5;
+
+    /// Returns each flag with its label, in display order.
+    #[must_use]
+    pub const fn flags(&self) -> [(&'static str, bool); Self::COUNT] {
+        [
+            ("Send messages", self.send_messages),
+            ("Send media", self.send_media),
+            ("Add members", self.add_members),
+            ("Pin messages", self.pin_messages),
+            ("Change chat info", self.change_info),
+        ]
+    }
+
+    /// Flips the flag at `index` in [`Self::flags`] order; out-of-range
+    /// indexes are ignored.
+    pub fn toggle(&mut self, index: usize) {
+        let flag = match index {
+            0 => &mut self.send_messages,
+            1 => &mut self.send_media,
+            2 => &mut self.add_members,
+            3 => &mut self.pin_messages,
+            4 => &mut self.change_info,
+            _ => return,
+        };
+        *flag = !*flag;
+    }
+}
+
+/// An identity the user can post as in a group or channel: themselves, a
+/// channel they own, or the group itself when they're an anonymous admin.
+#[derive(Debug, Clone, Serialize, Deserialize, Default, PartialEq, Eq)]
+#[serde(default)]
+pub struct SendAsPeer {
+    /// User or chat ID of the identity
+    pub id: i64,
+    /// Name to show for it
+    pub name: String,
+    /// Whether this is the user themselves
+    pub is_self: bool,
+    /// Whether posting as it needs Telegram Premium
+    pub premium_required: bool,
+}
+
+/// Someone to start a conversation with who may not be in the chat list yet.
+#[derive(Debug, Clone, PartialEq, Eq, Hash)]
+pub enum PeerHandle {
+    /// A public username, without the `@`
+    Username(String),
+    /// A phone number in international format, digits only
+    Phone(String),
+}
+
+impl PeerHandle {
+    /// Parses `@username`, a `t.me/username` link, or a phone number
+    /// starting with `+`. Anything else, including a bare name, is `None`
+    /// so plain chat searches aren't mistaken for handles.
+    #[must_use]
+    pub fn parse(input: &str) -> Option<Self> {
+        let input = input.trim();
+        if let Some(number) = input.strip_prefix('+') {
+            let separators = |c: char| matches!(c, ' ' | '-' | '(' | ')');
+            if !number.chars().all(|c| c.is_ascii_digit() || separators(c)) {
+                return None;
+            }
+            let digits: String = number.chars().filter(char::is_ascii_digit).collect();
+            return (7..=15)
+                .contains(&digits.len())
+                .then_some(Self::Phone(digits));
+        }
+
+        let name = input.strip_prefix('@').or_else(|| {
+            let link = input
+                .trim_start_matches("https://")
+                .trim_start_matches("http://");
+            link.strip_prefix("t.me/")
+                .or_else(|| link.strip_prefix("telegram.me/"))
+                .map(|rest| rest.trim_end_matches('/'))
+        })?;
+        let valid = (4..=32).contains(&name.len())
+            && name.starts_with(|c: char| c.is_ascii_alphabetic())
+            && name.chars().all(|c| c.is_ascii_alphanumeric() || c == '_');
+        valid.then(|| Self::Username(name.to_string()))
+    }
+}
+
+impl fmt::Display for PeerHandle {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Username(name) => write!(f, "@{name}"),
+            Self::Phone(digits) => write!(f, "+{digits}"),
+        }
+    }
+}
+
+/// A `t.me` or `tg://` link to a chat, and maybe one of its messages.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct DeepLink {
+    /// The chat the link points to
+    pub chat: LinkChat,
+    /// The message it points to, if any
+    pub message_id: Option<i64>,
+}
+
+/// How a link names its chat.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum LinkChat {
+    /// A public username or a phone number
+    Handle(PeerHandle),
+    /// A channel or supergroup by ID, as private links (`t.me/c/<id>`) do
+    Id(i64),
+}
+
+/// `t.me` paths that aren't usernames.
+const RESERVED_LINK_PATHS: [&str; 6] = [
+    "joinchat",
+    "addstickers",
+    "addemoji",
+    "share",
+    "proxy",
+    "socks",
+];
+
+impl DeepLink {
+    /// Parses `t.me/<name>[/<post>]`, `t.me/c/<id>/<post>`, `t.me/+<phone>`,
+    /// `tg://resolve?domain=<name>[&post=<id>]` (or `phone=`), and
+    /// `tg://privatepost?channel=<id>&post=<id>`. In topic links
+    /// (`t.me/<name>/<topic>/<post>`) the last number is the message.
+    /// Invite links and anything else are `None`.
+    #[must_use]
+    pub fn parse(input: &str) -> Option<Self> {
+        let input = input.trim();
+        if let Some(rest) = input.strip_prefix("tg://") {
+            let (action, query) = rest.split_once('?')?;
+            let param = |key: &str| {
+                query
+                    .split('&')
+                    .filter_map(|pair| pair.split_once('='))
+                    .find_map(|(k, v)| (k == key).then_some(v))
+            };
+            let chat = match action.trim_end_matches('/') {
+                "resolve" => LinkChat::Handle(match (param("domain"), param("phone")) {
+                    (Some(domain), _) => PeerHandle::parse(&format!("@{domain}"))?,
+                    (None, Some(phone)) => PeerHandle::parse(&format!("+{phone}"))?,
+                    (None, None) => return None,
+                }),
+                "privatepost" => LinkChat::Id(param("channel")?.parse().ok()?),
+                _ => return None,
+            };
+            let message_id = match param("post") {
+                Some(post) => Some(post.parse().ok()?),
+                None => None,
+            };
+            return Some(Self { chat, message_id });
+        }
+
+        let link = input
+            .trim_start_matches("https://")
+            .trim_start_matches("http://");
+        let path = link
+            .strip_prefix("t.me/")
+            .or_else(|| link.strip_prefix("telegram.me/"))?;
+        let path = path.split(['?', '#']).next().unwrap_or_default();
+        let mut parts = path.split('/').filter(|p| !p.is_empty());
+        let chat = match parts.next()? {
+            "c" => LinkChat::Id(parts.next()?.parse().ok()?),
+            "s" => LinkChat::Handle(PeerHandle::parse(&format!("@{}", parts.next()?))?),
+            name if RESERVED_LINK_PATHS.contains(&name) => return None,
+            phone if phone.starts_with('+') => LinkChat::Handle(PeerHandle::parse(phone)?),
+            name => LinkChat::Handle(PeerHandle::parse(&format!("@{name}"))?),
+        };
+        let mut message_id = None;
+        for part in parts {
+            message_id = Some(part.parse().ok()?);
+        }
+        Some(Self { chat, message_id })
+    }
+}
+
 // ============================================================================
 // Message Types
 // ============================================================================
 
 /// Represents the type of message content.
-#[derive(Debug, Clone, Copy, PartialEq, Eq, Default, Hash)]
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Default, Hash)]
 pub enum MessageType {
     /// Plain text message
     #[default]
@@ -283,6 +552,16 @@ pub enum MessageType {
     Venue,
     /// Game
     Game,
+    /// Voice or video call log entry
+    Call,
+    /// Group event, such as a member joining or the title changing
+    Service,
+    /// Invoice or payment request
+    Invoice,
+    /// Giveaway or its results
+    Giveaway,
+    /// Shared or mentioned story
+    Story,
 }
 
 impl MessageType {
@@ -321,12 +600,17 @@ impl fmt::Display for MessageType {
             Self::Poll => write!(f, "Poll"),
             Self::Venue => write!(f, "Venue"),
             Self::Game => write!(f, "Game"),
+            Self::Call => write!(f, "Call"),
+            Self::Service => write!(f, "Service"),
+            Self::Invoice => write!(f, "Invoice"),
+            Self::Giveaway => write!(f, "Giveaway"),
+            Self::Story => write!(f, "Story"),
         }
     }
 }
 
 /// Represents the type of text entity.
-#[derive(Debug, Clone, Copy, PartialEq, Eq, Default, Hash)]
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Default, Hash)]
 pub enum EntityType {
     /// Bold text
     #[default]
@@ -362,7 +646,8 @@ pub enum EntityType {
 }
 
 /// Represents a text entity (bold, italic, link, etc.).
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct MessageEntity {
     /// Type of entity
     pub entity_type: EntityType,
@@ -377,7 +662,8 @@ pub struct MessageEntity {
 }
 
 /// Represents a photo size variant (thumbnail, medium, large, etc.).
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct PhotoSize {
     /// Size type identifier ("s", "m", "x", "y", "w", etc.)
     pub size_type: String,
@@ -390,7 +676,8 @@ pub struct PhotoSize {
 }
 
 /// Represents a thumbnail for media content.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct Thumbnail {
     /// Width in pixels
     pub width: i32,
@@ -401,7 +688,7 @@ pub struct Thumbnail {
 }
 
 /// Represents the current download status of media.
-#[derive(Debug, Clone, Copy, PartialEq, Eq, Default, Hash)]
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Default, Hash)]
 pub enum DownloadStatus {
     /// Not yet downloaded
     #[default]
@@ -415,7 +702,8 @@ pub enum DownloadStatus {
 }
 
 /// Represents download progress information.
-#[derive(Debug, Clone)]
+#[derive(Debug, Clone, Serialize, Deserialize)]
+#[serde(default)]
 pub struct DownloadProgress {
     /// Current download status
     pub status: DownloadStatus,
@@ -488,7 +776,8 @@ impl DownloadProgress {
 }
 
 /// Represents media content in a message.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct Media {
     /// Unique media identifier
     pub id: String,
@@ -522,17 +811,44 @@ pub struct Media {
     pub download_progress: Option<DownloadProgress>,
 }
 
+impl Media {
+    /// Returns the error from the last failed download, if any.
+    #[must_use]
+    pub fn download_error(&self) -> Option<&str> {
+        self.download_progress
+            .as_ref()
+            .and_then(|p| p.error.as_deref())
+    }
+}
+
 /// Represents a geographical location.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct Location {
     /// Latitude
     pub latitude: f64,
     /// Longitude
     pub longitude: f64,
+    /// How long a live location is shared for, in seconds (0 = static)
+    pub live_period: i32,
+    /// Direction of travel in degrees, for live locations
+    pub heading: Option<i32>,
+}
+
+impl Location {
+    /// `live_period` of a live location shared until stopped.
+    pub const LIVE_INDEFINITELY: i32 = i32::MAX;
+
+    /// Returns `true` if this is a live location share.
+    #[must_use]
+    pub const fn is_live(&self) -> bool {
+        self.live_period > 0
+    }
 }
 
 /// Represents a contact shared in a message.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct Contact {
     /// Phone number
     pub phone_number: String,
@@ -546,8 +862,128 @@ pub struct Contact {
     pub vcard: String,
 }
 
+/// How a call ended.
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Default, Hash)]
+pub enum CallOutcome {
+    /// The call was answered, then hung up
+    #[default]
+    Ended,
+    /// Nobody answered
+    Missed,
+    /// The other side was busy or declined
+    Declined,
+}
+
+/// A call log entry. The TUI doesn't place calls, but shows the ones made
+/// from other clients.
+#[derive(Debug, Clone, Serialize, Deserialize, Default, PartialEq, Eq)]
+#[serde(default)]
+pub struct CallInfo {
+    /// Whether the current user placed the call
+    pub is_outgoing: bool,
+    /// Whether it was a video call
+    pub is_video: bool,
+    /// How long the call lasted, in seconds (0 if never answered)
+    pub duration: i32,
+    /// How the call ended
+    pub outcome: CallOutcome,
+}
+
+impl CallInfo {
+    /// Returns `true` for an incoming call nobody answered.
+    #[must_use]
+    pub fn is_missed(&self) -> bool {
+        !self.is_outgoing && self.outcome == CallOutcome::Missed
+    }
+}
+
+impl fmt::Display for CallInfo {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        let kind = if self.is_video { "video call" } else { "call" };
+        match (self.outcome, self.is_outgoing) {
+            (CallOutcome::Missed, false) => write!(f, "Missed {kind}"),
+            (CallOutcome::Missed, true) => write!(f, "Cancelled {kind}"),
+            (CallOutcome::Declined, false) => write!(f, "Declined {kind}"),
+            (CallOutcome::Declined, true) => write!(f, "Busy ({kind})"),
+            (CallOutcome::Ended, false) => write!(f, "Incoming {kind}"),
+            (CallOutcome::Ended, true) => write!(f, "Outgoing {kind}"),
+        }
+    }
+}
+
+/// A group event shown as a system line in the conversation. The message's
+/// sender is the one who acted.
+#[derive(Debug, Clone, Serialize, Deserialize, PartialEq, Eq)]
+pub enum ServiceAction {
+    /// The group or channel was created with this title
+    Created(String),
+    /// The sender joined, by invite link or request
+    Joined,
+    /// The sender added these users
+    MembersAdded(Vec<i64>),
+    /// The sender left
+    Left,
+    /// The sender removed this user
+    MemberRemoved(i64),
+    /// The title was changed to this
+    TitleChanged(String),
+    /// A new group photo was set
+    PhotoChanged,
+    /// The group photo was removed
+    PhotoRemoved,
+    /// The message this one replies to was pinned
+    MessagePinned,
+    /// A video chat started
+    VideoChatStarted,
+    /// A video chat ended after this many seconds
+    VideoChatEnded(i32),
+}
+
+impl ServiceAction {
+    /// Describes the event as a sentence, e.g. `"Alice added Bob"`.
+    ///
+    /// `actor` is the sender's name and `name_of` looks up other users.
+    #[must_use]
+    pub fn describe(&self, actor: &str, name_of: impl Fn(i64) -> String) -> String {
+        match self {
+            Self::Created(title) => format!("{actor} created \u{201c}{title}\u{201d}"),
+            Self::Joined => format!("{actor} joined"),
+            Self::MembersAdded(users) => {
+                let names: Vec<String> = users.iter().map(|&id| name_of(id)).collect();
+                format!("{actor} added {}", names.join(", "))
+            },
+            Self::Left => format!("{actor} left"),
+            Self::MemberRemoved(user) => format!("{actor} removed {}", name_of(*user)),
+            Self::TitleChanged(title) => {
+                format!("{actor} changed the title to \u{201c}{title}\u{201d}")
+            },
+            Self::PhotoChanged => format!("{actor} changed the group photo"),
+            Self::PhotoRemoved => format!("{actor} removed the group photo"),
+            Self::MessagePinned => format!("{actor} pinned a message"),
+            Self::VideoChatStarted => format!("{actor} started a video chat"),
+            Self::VideoChatEnded(_) => "Video chat ended".to_string(),
+        }
+    }
+}
+
+impl fmt::Display for ServiceAction {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Created(_) => write!(f, "Group created"),
+            Self::Joined | Self::MembersAdded(_) => write!(f, "New member"),
+            Self::Left | Self::MemberRemoved(_) => write!(f, "Member left"),
+            Self::TitleChanged(title) => write!(f, "Title changed to \u{201c}{title}\u{201d}"),
+            Self::PhotoChanged => write!(f, "Group photo changed"),
+            Self::PhotoRemoved => write!(f, "Group photo removed"),
+            Self::MessagePinned => write!(f, "Pinned a message"),
+            Self::VideoChatStarted => write!(f, "Video chat started"),
+            Self::VideoChatEnded(_) => write!(f, "Video chat ended"),
+        }
+    }
+}
+
 /// Represents the type of poll.
-#[derive(Debug, Clone, Copy, PartialEq, Eq, Default, Hash)]
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Default, Hash)]
 pub enum PollType {
     /// Regular poll (multiple choice)
     #[default]
@@ -557,7 +993,8 @@ pub enum PollType {
 }
 
 /// Represents an option in a poll.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct PollOption {
     /// Option text
     pub text: String,
@@ -565,10 +1002,13 @@ pub struct PollOption {
     pub voter_count: i32,
     /// Whether the current user chose this option
     pub is_chosen: bool,
+    /// Opaque identifier Telegram expects when voting for this option
+    pub option: Vec<u8>,
 }
 
 /// Represents a poll in a message.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct Poll {
     /// Unique poll identifier
     pub id: String,
@@ -582,6 +1022,8 @@ pub struct Poll {
     pub is_closed: bool,
     /// Whether the poll is anonymous
     pub is_anonymous: bool,
+    /// Whether more than one option may be chosen
+    pub allows_multiple_answers: bool,
     /// Type of poll
     pub poll_type: PollType,
     /// Time in seconds before the poll auto-closes (0 = no limit)
@@ -590,8 +1032,50 @@ pub struct Poll {
     pub close_date: Option<DateTime<Utc>>,
 }
 
+impl Poll {
+    /// Returns `true` if the current user has voted.
+    #[must_use]
+    pub fn has_voted(&self) -> bool {
+        self.options.iter().any(|o| o.is_chosen)
+    }
+
+    /// Records the current user's vote locally: `chosen` holds the indexes
+    /// of the chosen options, and an empty slice retracts the vote.
+    ///
+    /// Counts are adjusted so the poll reads correctly until Telegram sends
+    /// fresh results.
+    pub fn apply_vote(&mut self, chosen: &[usize]) {
+        let had_voted = self.has_voted();
+        for (i, option) in self.options.iter_mut().enumerate() {
+            let now_chosen = chosen.contains(&i);
+            if option.is_chosen != now_chosen {
+                option.voter_count += if now_chosen { 1 } else { -1 };
+                option.is_chosen = now_chosen;
+            }
+        }
+        match (had_voted, chosen.is_empty()) {
+            (false, false) => self.total_voter_count += 1,
+            (true, true) => self.total_voter_count -= 1,
+            _ => {},
+        }
+    }
+}
+
+/// One page of the users who chose a poll option.
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
+pub struct PollVoters {
+    /// Voters on this page
+    pub users: Vec<User>,
+    /// Total number of voters for the option
+    pub total: i32,
+    /// Offset for the next page, if there is one
+    pub next_offset: Option<String>,
+}
+
 /// Represents a sticker in a message.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct Sticker {
     /// Sticker set ID
     pub set_id: i64,
@@ -612,7 +1096,8 @@ pub struct Sticker {
 }
 
 /// Represents an animation (GIF) in a message.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct Animation {
     /// Width in pixels
     pub width: i32,
@@ -631,7 +1116,8 @@ pub struct Animation {
 }
 
 /// Represents a document in a message.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct Document {
     /// File name
     pub file_name: String,
@@ -644,7 +1130,7 @@ pub struct Document {
 }
 
 /// Represents the origin of a forwarded message.
-#[derive(Debug, Clone, Copy, PartialEq, Eq, Default, Hash)]
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Default, Hash)]
 pub enum ForwardOrigin {
     /// Forwarded from a user
     #[default]
@@ -658,7 +1144,8 @@ pub enum ForwardOrigin {
 }
 
 /// Contains information about forwarded messages.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct ForwardInfo {
     /// Origin type of the forward
     pub origin: ForwardOrigin,
@@ -672,10 +1159,27 @@ pub struct ForwardInfo {
     pub date: DateTime<Utc>,
     /// Author signature (for channel posts)
     pub author_signature: String,
+    /// Name Telegram gives for a sender who hid their account
+    pub from_name: String,
+}
+
+impl ForwardInfo {
+    /// Returns the chat and message the forward was copied from, when
+    /// Telegram says which (channel posts, and forwards saved to Saved
+    /// Messages).
+    #[must_use]
+    pub const fn source(&self) -> Option<(i64, i64)> {
+        if self.from_chat_id != 0 && self.message_id != 0 {
+            Some((self.from_chat_id, self.message_id))
+        } else {
+            None
+        }
+    }
 }
 
 /// Represents the content of a message.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct MessageContent {
     /// Type of content
     pub content_type: MessageType,
@@ -699,9 +1203,47 @@ pub struct MessageContent {
     pub animation: Option<Box<Animation>>,
     /// Document data
     pub document: Option<Box<Document>>,
+    /// Call log entry
+    pub call: Option<CallInfo>,
+    /// Group event, for service messages
+    pub service: Option<ServiceAction>,
 }
 
 impl MessageContent {
+    /// Records the download state of the attachment.
+    ///
+    /// Attachment types that carry no [`Media`] details (documents, voice,
+    /// and so on) get an empty entry to hold the state.
+    pub fn set_download_status(&mut self, status: DownloadStatus, error: Option<String>) {
+        let media = self.media.get_or_insert_with(Box::default);
+        media.download_status = status;
+        media.is_downloaded = status == DownloadStatus::Downloaded;
+        let progress = media
+            .download_progress
+            .get_or_insert_with(DownloadProgress::default);
+        progress.status = status;
+        progress.error = error;
+        progress.last_update = Utc::now();
+    }
+
+    /// Returns the download state of the attachment.
+    #[must_use]
+    pub fn download_status(&self) -> DownloadStatus {
+        self.media
+            .as_ref()
+            .map_or(DownloadStatus::NotDownloaded, |m| m.download_status)
+    }
+
+    /// Returns the message's text, or its attachment's caption.
+    #[must_use]
+    pub fn text_or_caption(&self) -> &str {
+        if self.text.is_empty() {
+            &self.caption
+        } else {
+            &self.text
+        }
+    }
+
     /// Human-readable one-line preview of this message's body (no sender prefix).
     #[must_use]
     pub fn preview(&self) -> String {
@@ -740,7 +1282,13 @@ impl MessageContent {
             },
             MessageType::Sticker => preview.push_str("🎨 Sticker"),
             MessageType::Animation => preview.push_str("GIF"),
-            MessageType::Location => preview.push_str("📍 Location"),
+            MessageType::Location => {
+                if self.location.as_ref().is_some_and(Location::is_live) {
+                    preview.push_str("📡 Live location");
+                } else {
+                    preview.push_str("📍 Location");
+                }
+            },
             MessageType::Contact => preview.push_str("👤 Contact"),
             MessageType::Poll => {
                 preview.push_str("📊 Poll");
@@ -751,13 +1299,38 @@ impl MessageContent {
             },
             MessageType::Venue => preview.push_str("📍 Venue"),
             MessageType::Game => preview.push_str("🎮 Game"),
+            MessageType::Invoice => {
+                preview.push_str("🧾 ");
+                preview.push_str(&self.caption);
+            },
+            MessageType::Giveaway => {
+                preview.push_str("🎁 ");
+                preview.push_str(&self.caption);
+            },
+            MessageType::Story => {
+                preview.push_str("📖 ");
+                preview.push_str(&self.caption);
+            },
+            MessageType::Service => {
+                if let Some(ref action) = self.service {
+                    preview.push_str(&action.to_string());
+                }
+            },
+            MessageType::Call => {
+                preview.push_str("📞 ");
+                match self.call {
+                    Some(ref call) => preview.push_str(&call.to_string()),
+                    None => preview.push_str("Call"),
+                }
+            },
         }
         preview
     }
 }
 
 /// Represents a Telegram message.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct Message {
     /// Unique message identifier within the chat
     pub id: i64,
@@ -773,6 +1346,10 @@ pub struct Message {
     pub edit_date: Option<DateTime<Utc>>,
     /// Whether this message was sent by the current user
     pub is_outgoing: bool,
+    /// Whether this message mentions (or replies to) the current user
+    pub mentions_me: bool,
+    /// Whether this voice or video message hasn't been played yet
+    pub media_unread: bool,
     /// Whether this is a channel post
     pub is_channel_post: bool,
     /// Whether this message is pinned
@@ -789,6 +1366,234 @@ pub struct Message {
     pub views: i32,
     /// Media album ID (for grouped media)
     pub media_album_id: i64,
+    /// Reactions left on the message, most given first
+    pub reactions: Vec<MessageReaction>,
+    /// ID of the animated effect the message was sent with, if any
+    pub effect: Option<i64>,
+}
+
+impl Message {
+    /// Hours after sending during which Telegram accepts edits.
+    pub const EDIT_WINDOW_HOURS: i64 = 48;
+
+    /// First ID given to a sent message before Telegram confirms it.
+    ///
+    /// Telegram's message IDs fit in an `i32`, so local IDs never collide
+    /// with them and sort after every real message.
+    pub const FIRST_LOCAL_ID: i64 = 1 << 32;
+
+    /// Returns `true` if this is an optimistic copy of a message Telegram
+    /// hasn't confirmed yet.
+    #[must_use]
+    pub const fn is_pending(&self) -> bool {
+        self.id >= Self::FIRST_LOCAL_ID
+    }
+
+    /// Returns `true` if the user can still edit this message at `now`.
+    ///
+    /// Only the user's own messages can be edited, and only for
+    /// [`Self::EDIT_WINDOW_HOURS`]; channel posts can be edited at any time.
+    #[must_use]
+    pub fn can_edit(&self, now: DateTime<Utc>) -> bool {
+        self.is_outgoing
+            && (self.is_channel_post
+                || now - self.date < chrono::Duration::hours(Self::EDIT_WINDOW_HOURS))
+    }
+
+    /// Returns the live location shared by this message, if any.
+    #[must_use]
+    pub fn live_location(&self) -> Option<&Location> {
+        self.content.location.as_ref().filter(|l| l.is_live())
+    }
+
+    /// Returns when this message's live location stops updating, or `None`
+    /// if it isn't a live location or is shared until stopped.
+    #[must_use]
+    pub fn live_location_expiry(&self) -> Option<DateTime<Utc>> {
+        self.live_location()
+            .filter(|l| l.live_period != Location::LIVE_INDEFINITELY)
+            .map(|l| self.date + chrono::Duration::seconds(i64::from(l.live_period)))
+    }
+
+    /// Returns `true` if this message's live location is still being
+    /// updated at `now`.
+    #[must_use]
+    pub fn is_live_location_active(&self, now: DateTime<Utc>) -> bool {
+        self.live_location().is_some() && self.live_location_expiry().map_or(true, |t| now < t)
+    }
+
+    /// Returns when the live location last moved: its last edit, or when it
+    /// was sent.
+    #[must_use]
+    pub fn live_location_updated(&self) -> DateTime<Utc> {
+        self.edit_date.unwrap_or(self.date)
+    }
+
+    /// Returns the reaction the current user left, if any.
+    #[must_use]
+    pub fn my_reaction(&self) -> Option<&str> {
+        self.reactions
+            .iter()
+            .find(|r| r.chosen)
+            .map(|r| r.reaction.as_str())
+    }
+
+    /// Replaces the current user's reaction with `reaction`, or takes it
+    /// back if `None`, adjusting the counts as Telegram will.
+    pub fn set_my_reaction(&mut self, reaction: Option<&str>) {
+        for r in self.reactions.iter_mut().filter(|r| r.chosen) {
+            r.chosen = false;
+            r.count -= 1;
+        }
+        if let Some(reaction) = reaction {
+            if let Some(r) = self.reactions.iter_mut().find(|r| r.reaction == reaction) {
+                r.chosen = true;
+                r.count += 1;
+            } else {
+                self.reactions.push(MessageReaction {
+                    reaction: reaction.to_string(),
+                    count: 1,
+                    chosen: true,
+                    premium: false,
+                });
+            }
+        }
+        self.reactions.retain(|r| r.count > 0);
+        self.reactions.sort_by(|a, b| b.count.cmp(&a.count));
+    }
+}
+
+/// A reaction left on a message, with how many left it.
+#[derive(Debug, Clone, Serialize, Deserialize, Default, PartialEq, Eq)]
+#[serde(default)]
+pub struct MessageReaction {
+    /// The reaction, as an emoji or a short label
+    pub reaction: String,
+    /// How many left this reaction
+    pub count: i32,
+    /// Whether the current user left it
+    pub chosen: bool,
+    /// Whether it's a premium reaction (a custom emoji or a paid star)
+    pub premium: bool,
+}
+
+/// Someone reacting to one of the current user's messages.
+#[derive(Debug, Clone, Serialize, Deserialize, Default, PartialEq, Eq)]
+#[serde(default)]
+pub struct ReactionEvent {
+    /// Chat containing the message
+    pub chat_id: i64,
+    /// ID of the message that was reacted to
+    pub message_id: i64,
+    /// ID of the user (or chat) that reacted
+    pub sender_id: i64,
+    /// The reaction, as an emoji or a short label
+    pub reaction: String,
+    /// When the reaction was added
+    pub date: DateTime<Utc>,
+}
+
+// ============================================================================
+// Search Types
+// ============================================================================
+
+/// Kind of message a search is limited to (`has:` in search queries).
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize, Deserialize)]
+pub enum SearchFilter {
+    /// Photos and videos
+    Media,
+    /// Photos
+    Photo,
+    /// Videos, including round video notes
+    Video,
+    /// Files
+    Document,
+    /// Voice messages
+    Voice,
+    /// Music and other audio files
+    Audio,
+    /// Messages containing a link
+    Link,
+    /// Pinned messages
+    Pinned,
+}
+
+impl SearchFilter {
+    /// Parses a `has:` value such as `photo` or `link`.
+    #[must_use]
+    pub fn from_name(name: &str) -> Option<Self> {
+        Some(match name.to_lowercase().as_str() {
+            "media" => Self::Media,
+            "photo" | "photos" | "image" => Self::Photo,
+            "video" | "videos" => Self::Video,
+            "file" | "files" | "document" | "doc" => Self::Document,
+            "voice" => Self::Voice,
+            "audio" | "music" => Self::Audio,
+            "link" | "links" | "url" => Self::Link,
+            "pinned" | "pin" => Self::Pinned,
+            _ => return None,
+        })
+    }
+
+    /// Returns `true` if `message` is of this kind.
+    #[must_use]
+    pub fn matches(self, message: &Message) -> bool {
+        let kind = message.content.content_type;
+        match self {
+            Self::Media => matches!(
+                kind,
+                MessageType::Photo | MessageType::Video | MessageType::VideoNote
+            ),
+            Self::Photo => kind == MessageType::Photo,
+            Self::Video => matches!(kind, MessageType::Video | MessageType::VideoNote),
+            Self::Document => kind == MessageType::Document,
+            Self::Voice => kind == MessageType::Voice,
+            Self::Audio => kind == MessageType::Audio,
+            Self::Link => {
+                message
+                    .content
+                    .entities
+                    .iter()
+                    .any(|e| matches!(e.entity_type, EntityType::Url | EntityType::TextUrl))
+                    || crate::utils::first_url(&message.content.text).is_some()
+            },
+            Self::Pinned => message.is_pinned,
+        }
+    }
+}
+
+// ============================================================================
+// Moderation Types
+// ============================================================================
+
+/// Why a chat or message is being reported to Telegram.
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Default, Hash)]
+pub enum ReportReason {
+    /// Unsolicited advertising or bulk messages
+    #[default]
+    Spam,
+    /// Violent content or threats
+    Violence,
+    /// Impersonation or a fake account
+    Fake,
+    /// Anything else
+    Other,
+}
+
+impl ReportReason {
+    /// Every reason, in the order they are offered.
+    pub const ALL: [Self; 4] = [Self::Spam, Self::Violence, Self::Fake, Self::Other];
+}
+
+impl fmt::Display for ReportReason {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::Spam => write!(f, "Spam"),
+            Self::Violence => write!(f, "Violence"),
+            Self::Fake => write!(f, "Fake account"),
+            Self::Other => write!(f, "Other"),
+        }
+    }
 }
 
 // ============================================================================
@@ -796,7 +1601,7 @@ pub struct Message {
 // ============================================================================
 
 /// Represents the authentication state.
-#[derive(Debug, Clone, Copy, PartialEq, Eq, Default, Hash)]
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Default, Hash)]
 pub enum AuthState {
     /// Waiting for phone number input
     #[default]
@@ -831,9 +1636,11 @@ impl fmt::Display for AuthState {
 // ============================================================================
 
 /// Represents the type of Telegram update.
-#[derive(Debug, Clone, Copy, PartialEq, Eq, Default, Hash)]
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Default, Hash)]
 pub enum UpdateType {
-    /// New message received
+    /// New message received. For the echo of a message the user sent,
+    /// the data is the local ID of the optimistic copy it replaces
+    /// (`UpdateData::Integer`), if there was one.
     #[default]
     NewMessage,
     /// Message content was updated
@@ -866,10 +1673,44 @@ pub enum UpdateType {
     File,
     /// File download progress update
     FileDownload,
+    /// New reactions on the current user's messages
+    MessageReactions,
+    /// Unknown group senders were looked up and cached
+    ParticipantsResolved,
+    /// Chat's auto-delete timer changed
+    ChatAutoDelete,
+    /// Group's default member permissions changed
+    ChatPermissions,
+    /// Someone started typing (`UpdateData::Integer` with their user ID) or
+    /// stopped (`UpdateData::None`)
+    UserTyping,
+    /// The user was added to the chat or made an admin in it
+    /// (`UpdateData::Membership`)
+    Membership,
+}
+
+/// A change in the user's own place in a group or channel.
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Hash)]
+pub enum MembershipChange {
+    /// The user was added, or joined on another device
+    Added,
+    /// The user was made an admin
+    Promoted,
+}
+
+impl MembershipChange {
+    /// Returns the tag the chat list shows on the chat until it's opened.
+    #[must_use]
+    pub const fn tag(self) -> &'static str {
+        match self {
+            Self::Added => "NEW",
+            Self::Promoted => "ADMIN",
+        }
+    }
 }
 
 /// Represents any data that can be attached to an update.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
 pub enum UpdateData {
     /// No additional data
     #[default]
@@ -886,10 +1727,17 @@ pub enum UpdateData {
     Message(Box<Message>),
     /// File download data
     FileDownload(Box<FileDownload>),
+    /// Reactions on the current user's messages
+    Reactions(Vec<ReactionEvent>),
+    /// IDs of the messages an update is about, such as deleted ones
+    MessageIds(Vec<i64>),
+    /// How the user's place in a chat changed
+    Membership(MembershipChange),
 }
 
 /// Represents a Telegram update event.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct Update {
     /// Type of update
     pub update_type: UpdateType,
@@ -906,7 +1754,7 @@ pub struct Update {
 // ============================================================================
 
 /// Represents the state of a file download.
-#[derive(Debug, Clone, Copy, PartialEq, Eq, Default, Hash)]
+#[derive(Debug, Clone, Serialize, Deserialize, Copy, PartialEq, Eq, Default, Hash)]
 pub enum FileDownloadState {
     /// Download is pending
     #[default]
@@ -922,7 +1770,8 @@ pub enum FileDownloadState {
 }
 
 /// Tracks the progress of a file download.
-#[derive(Debug, Clone, Default)]
+#[derive(Debug, Clone, Serialize, Deserialize, Default)]
+#[serde(default)]
 pub struct FileDownload {
     /// Unique file identifier
     pub file_id: String,
@@ -934,6 +1783,9 @@ pub struct FileDownload {
     pub total_size: i64,
     /// Local path where file is saved
     pub local_path: String,
+    /// Whether the file was saved to the downloads directory rather than
+    /// opened
+    pub saved: bool,
     /// Error message (if failed)
     pub error: Option<String>,
 }
@@ -1000,6 +1852,180 @@ mod tests {
         }
     }
 
+    mod chat_permissions_tests {
+        use super::*;
+
+        #[test]
+        fn toggle_flips_the_flag_shown_at_that_index() {
+            let mut permissions = ChatPermissions::default();
+            permissions.toggle(1);
+            permissions.toggle(3);
+            permissions.toggle(ChatPermissions::COUNT);
+
+            let flags = permissions.flags();
+            assert_eq!(flags[1], ("Send media", false));
+            assert_eq!(flags[3], ("Pin messages", true));
+            assert!(permissions.send_messages);
+        }
+    }
+
+    mod poll_tests {
+        use super::*;
+
+        fn poll() -> Poll {
+            let option = |text: &str, voter_count| PollOption {
+                text: text.to_string(),
+                voter_count,
+                ..Default::default()
+            };
+            Poll {
+                options: vec![option("Tea", 3), option("Coffee", 2)],
+                total_voter_count: 5,
+                ..Default::default()
+            }
+        }
+
+        #[test]
+        fn voting_then_changing_then_retracting_keeps_counts() {
+            let mut poll = poll();
+            poll.apply_vote(&[1]);
+            assert_eq!(poll.options[1].voter_count, 3);
+            assert_eq!(poll.total_voter_count, 6);
+
+            poll.apply_vote(&[0]);
+            assert_eq!(
+                (poll.options[0].voter_count, poll.options[1].voter_count),
+                (4, 2)
+            );
+            assert_eq!(poll.total_voter_count, 6);
+
+            poll.apply_vote(&[]);
+            assert!(!poll.has_voted());
+            assert_eq!(poll.options[0].voter_count, 3);
+            assert_eq!(poll.total_voter_count, 5);
+        }
+    }
+
+    mod live_location_tests {
+        use super::*;
+
+        fn live(period: i32, minutes_ago: i64) -> Message {
+            Message {
+                date: Utc::now() - chrono::Duration::minutes(minutes_ago),
+                content: MessageContent {
+                    content_type: MessageType::Location,
+                    location: Some(Location {
+                        live_period: period,
+                        ..Default::default()
+                    }),
+                    ..Default::default()
+                },
+                ..Default::default()
+            }
+        }
+
+        #[test]
+        fn live_location_expires_after_its_period() {
+            let now = Utc::now();
+            assert!(live(3600, 20).is_live_location_active(now));
+            assert!(!live(900, 20).is_live_location_active(now));
+            assert!(live(Location::LIVE_INDEFINITELY, 600).is_live_location_active(now));
+            assert!(live(Location::LIVE_INDEFINITELY, 0)
+                .live_location_expiry()
+                .is_none());
+        }
+
+        #[test]
+        fn static_location_is_not_live() {
+            let msg = live(0, 0);
+            assert!(msg.live_location().is_none());
+            assert!(!msg.is_live_location_active(Utc::now()));
+            assert_eq!(msg.content.preview(), "📍 Location");
+        }
+    }
+
+    mod edit_window_tests {
+        use super::*;
+
+        #[test]
+        fn edits_close_after_two_days_except_for_channel_posts() {
+            let now = Utc::now();
+            let mut msg = Message {
+                date: now - chrono::Duration::hours(47),
+                is_outgoing: true,
+                ..Default::default()
+            };
+            assert!(msg.can_edit(now));
+
+            msg.date = now - chrono::Duration::hours(49);
+            assert!(!msg.can_edit(now));
+            msg.is_channel_post = true;
+            assert!(msg.can_edit(now));
+
+            msg.is_outgoing = false;
+            assert!(!msg.can_edit(now));
+        }
+    }
+
+    mod reaction_tests {
+        use super::*;
+
+        fn reaction(emoji: &str, count: i32, chosen: bool) -> MessageReaction {
+            MessageReaction {
+                reaction: emoji.to_string(),
+                count,
+                chosen,
+                premium: false,
+            }
+        }
+
+        #[test]
+        fn my_reaction_replaces_the_last_one() {
+            let mut message = Message {
+                reactions: vec![
+                    reaction("\u{1f44d}", 2, false),
+                    reaction("\u{1f525}", 1, true),
+                ],
+                ..Default::default()
+            };
+            assert_eq!(message.my_reaction(), Some("\u{1f525}"));
+
+            message.set_my_reaction(Some("\u{1f44d}"));
+            assert_eq!(message.reactions, vec![reaction("\u{1f44d}", 3, true)]);
+
+            message.set_my_reaction(Some("\u{1f389}"));
+            assert_eq!(
+                message.reactions,
+                vec![
+                    reaction("\u{1f44d}", 2, false),
+                    reaction("\u{1f389}", 1, true)
+                ]
+            );
+
+            message.set_my_reaction(None);
+            assert_eq!(message.my_reaction(), None);
+            assert_eq!(message.reactions, vec![reaction("\u{1f44d}", 2, false)]);
+        }
+    }
+
+    mod service_action_tests {
+        use super::*;
+
+        #[test]
+        fn describe_names_actor_and_members() {
+            let name_of = |id: i64| if id == 7 { "Bob" } else { "Carol" }.to_string();
+            assert_eq!(
+                ServiceAction::MembersAdded(vec![7, 8]).describe("Alice", name_of),
+                "Alice added Bob, Carol"
+            );
+            assert_eq!(
+                ServiceAction::TitleChanged("Book Club".to_string()).describe("Alice", name_of),
+                "Alice changed the title to \u{201c}Book Club\u{201d}"
+            );
+            assert_eq!(ServiceAction::Left.describe("Bob", name_of), "Bob left");
+        }
+    }
+
     mod download_progress_tests {
         use super::*;
 
@@ -1042,6 +2068,24 @@ mod tests {
             };
             assert_eq!(progress.get_eta(), Duration::ZERO);
         }
+
+        #[test]
+        fn download_status_is_recorded_without_media() {
+            let mut c = MessageContent {
+                content_type: MessageType::Document,
+                ..Default::default()
+            };
+            assert_eq!(c.download_status(), DownloadStatus::NotDownloaded);
+
+            c.set_download_status(DownloadStatus::Failed, Some("timeout".to_string()));
+            assert_eq!(c.download_status(), DownloadStatus::Failed);
+            assert_eq!(c.media.as_ref().unwrap().download_error(), Some("timeout"));
+
+            c.set_download_status(DownloadStatus::Downloaded, None);
+            let media = c.media.as_ref().unwrap();
+            assert!(media.is_downloaded);
+            assert!(media.download_error().is_none());
+        }
     }
 
     mod message_content_preview_tests {
@@ -1075,6 +2119,171 @@ mod tests {
             };
             assert_eq!(c.preview(), "🎤 Voice message");
         }
+
+        #[test]
+        fn message_content_preview_missed_call() {
+            let c = MessageContent {
+                content_type: MessageType::Call,
+                call: Some(CallInfo {
+                    outcome: CallOutcome::Missed,
+                    ..Default::default()
+                }),
+                ..Default::default()
+            };
+            assert_eq!(c.preview(), "📞 Missed call");
+            assert!(c.call.as_ref().is_some_and(CallInfo::is_missed));
+        }
+    }
+
+    mod peer_handle_tests {
+        use super::*;
+
+        #[test]
+        fn parses_usernames_links_and_phone_numbers() {
+            let username = Some(PeerHandle::Username("durov".to_string()));
+            assert_eq!(PeerHandle::parse("@durov"), username);
+            assert_eq!(PeerHandle::parse("https://t.me/durov/"), username);
+            assert_eq!(PeerHandle::parse("t.me/durov"), username);
+            assert_eq!(
+                PeerHandle::parse("+1 (555) 010-9999"),
+                Some(PeerHandle::Phone("15550109999".to_string()))
+            );
+            assert_eq!(
+                PeerHandle::parse("+15550109999").unwrap().to_string(),
+                "+15550109999"
+            );
+        }
+
+        #[test]
+        fn rejects_names_and_malformed_handles() {
+            assert_eq!(PeerHandle::parse("durov"), None);
+            assert_eq!(PeerHandle::parse("@du"), None);
+            assert_eq!(PeerHandle::parse("@1durov"), None);
+            assert_eq!(PeerHandle::parse("@du rov"), None);
+            assert_eq!(PeerHandle::parse("+123"), None);
+            assert_eq!(PeerHandle::parse("+1555abc"), None);
+        }
+    }
+
+    mod deep_link_tests {
+        use super::*;
+
+        fn link(chat: LinkChat, message_id: Option<i64>) -> Option<DeepLink> {
+            Some(DeepLink { chat, message_id })
+        }
+
+        fn name(name: &str) -> LinkChat {
+            LinkChat::Handle(PeerHandle::Username(name.to_string()))
+        }
+
+        #[test]
+        fn parses_public_private_and_tg_links() {
+            assert_eq!(
+                DeepLink::parse("https://t.me/durov/123?single"),
+                link(name("durov"), Some(123))
+            );
+            assert_eq!(DeepLink::parse("t.me/durov"), link(name("durov"), None));
+            assert_eq!(
+                DeepLink::parse("https://t.me/s/durov/7"),
+                link(name("durov"), Some(7))
+            );
+            assert_eq!(
+                DeepLink::parse("https://t.me/c/1234567/89"),
+                link(LinkChat::Id(1_234_567), Some(89))
+            );
+            assert_eq!(
+                DeepLink::parse("https://t.me/durov_chat/5/120"),
+                link(name("durov_chat"), Some(120))
+            );
+            assert_eq!(
+                DeepLink::parse("tg://resolve?domain=durov&post=42"),
+                link(name("durov"), Some(42))
+            );
+            assert_eq!(
+                DeepLink::parse("tg://resolve?phone=15550109999"),
+                link(
+                    LinkChat::Handle(PeerHandle::Phone("15550109999".to_string())),
+                    None
+                )
+            );
+            assert_eq!(
+                DeepLink::parse("tg://privatepost?channel=1234567&post=89"),
+                link(LinkChat::Id(1_234_567), Some(89))
+            );
+        }
+
+        #[test]
+        fn rejects_invites_and_other_links() {
+            assert_eq!(DeepLink::parse("https://t.me/+AbCdEfGh123"), None);
+            assert_eq!(DeepLink::parse("https://t.me/joinchat/AbCdEf"), None);
+            assert_eq!(DeepLink::parse("https://t.me/durov/latest"), None);
+            assert_eq!(DeepLink::parse("tg://join?invite=AbCdEf"), None);
+            assert_eq!(DeepLink::parse("https://example.com/durov/1"), None);
+            assert_eq!(DeepLink::parse("@durov"), None);
+        }
+    }
+
+    mod chat_link_tests {
+        use super::*;
+
+        fn chat(chat_type: ChatType, username: &str) -> Chat {
+            Chat {
+                id: 1_234_567,
+                chat_type,
+                username: username.to_string(),
+                ..Chat::default()
+            }
+        }
+
+        #[test]
+        fn public_chats_link_by_username_and_private_ones_by_id() {
+            let news = chat(ChatType::Channel, "news");
+            assert_eq!(
+                news.telegram_link(Some(89)).unwrap(),
+                "https://t.me/news/89"
+            );
+            assert_eq!(news.telegram_link(None).unwrap(), "https://t.me/news");
+
+            let hidden = chat(ChatType::Supergroup, "");
+            let link = hidden.telegram_link(Some(89)).unwrap();
+            assert_eq!(link, "https://t.me/c/1234567/89");
+            // The app follows its own links back to the message
+            assert_eq!(
+                DeepLink::parse(&link),
+                Some(DeepLink {
+                    chat: LinkChat::Id(1_234_567),
+                    message_id: Some(89),
+                })
+            );
+            assert_eq!(hidden.telegram_link(None), None);
+            let hidden = Chat {
+                last_message: Some(Box::new(Message {
+                    id: 90,
+                    ..Message::default()
+                })),
+                ..hidden
+            };
+            assert_eq!(
+                hidden.telegram_link(None).unwrap(),
+                "https://t.me/c/1234567/90"
+            );
+        }
+
+        #[test]
+        fn people_link_to_the_chat_and_basic_groups_not_at_all() {
+            assert_eq!(
+                chat(ChatType::Private, "durov")
+                    .telegram_link(Some(5))
+                    .unwrap(),
+                "https://t.me/durov"
+            );
+            assert_eq!(
+                chat(ChatType::Private, "").telegram_link(None).unwrap(),
+                "tg://user?id=1234567"
+            );
+            assert_eq!(chat(ChatType::Group, "").telegram_link(None), None);
+            assert_eq!(chat(ChatType::Secret, "").telegram_link(None), None);
+        }
     }
 
     mod enum_display_tests {
@@ -37,10 +37,12 @@
 //! # }
 //! ```
 
+use std::collections::{HashMap, VecDeque};
 use std::sync::Arc;
-use std::time::Duration;
+use std::time::{Duration, Instant};
 
 use anyhow::Result;
+use chrono::NaiveDate;
 use crossterm::event::{self, Event, KeyEvent, KeyEventKind};
 use ratatui::{
     layout::{Alignment, Constraint, Direction, Layout, Rect},
@@ -50,18 +52,172 @@ use ratatui::{
 };
 use tokio::sync::mpsc;
 
-use crate::app::Config;
+use crate::app::storage::{self, LocalData};
+use crate::app::vault::{self, Vault};
+use crate::app::{health, state, Config, StartupView};
 use crate::cache::SharedCache;
-use crate::telegram::TelegramClient;
-use crate::types::{AuthState, Update, UpdateType};
+use crate::telegram::{
+    AuthService, DialogService, MediaService, MessageService, TelegramApi, UpdateService,
+};
+use crate::types::{
+    AuthState, Chat, ChatPermissions, ChatType, DeepLink, DownloadStatus, FileDownloadState,
+    ForwardInfo, LinkChat, MembershipChange, Message, PeerHandle, ReactionEvent, ReportReason,
+    SearchFilter, SendAsPeer, Update, UpdateType,
+};
 
+use super::bulk_download::{self, BulkDownload, BulkRequest};
+use super::components::slash_command;
 use super::components::{
-    AuthAction, AuthModel, ChatListAction, ChatListModel, ConnectionStatus, ConversationAction,
-    ConversationModel, ConversationWidget, SettingsAction, SettingsModel, SettingsWidget,
-    StatusBar, StatusBarWidget,
+    AuthAction, AuthModel, BookmarkList, BookmarkListAction, ChannelManager, ChannelManagerAction,
+    ChatActions, ChatActionsAction, ChatListAction, ChatListModel, ChatMenuItem, ChatStats,
+    ChatStatsAction, ChatStatsView, ConnectionStatus, ConversationAction, ConversationModel,
+    ConversationWidget, DatePrompt, DatePromptAction, ErrorLog, ErrorLogAction, ForwardDialog,
+    ForwardDialogAction, ForwardOptions, Inbox, InboxAction, InboxEntry, InputMode, LockScreen,
+    LockScreenAction, MemberList, MemberListAction, Modal, ModalWidget, PermissionsEditor,
+    PermissionsEditorAction, PinBoard, PinBoardAction, PollView, PollViewAction, QrView,
+    QrViewAction, QuickSwitcher, QuickSwitcherAction, ReactionEntry, ReactionPicker,
+    ReactionPickerAction, ReactionsFeed, ReactionsFeedAction, ReportDialog, ReportDialogAction,
+    ReportTarget, SearchHit, SearchResults, SearchResultsAction, SendAsPicker, SendAsPickerAction,
+    SettingsAction, SettingsModel, SettingsWidget, Severity, SidebarModel, SidebarWidget,
+    SlashCommand, StatusBar, StatusBarWidget, StorageManager, StorageManagerAction, Toasts,
 };
+use super::inbox_fetch::InboxFetch;
 use super::keys::{Action, KeyMap};
+use super::leader::{LeaderCommand, LeaderKeys, LeaderStep};
+use super::mark_read::{MarkRead, MarkReadEvent};
+use super::search::SearchQuery;
+use super::send_queue::{Outgoing, SendQueue, Sent};
 use super::styles::Styles;
+use super::voice_queue::{self, Fetched, VoiceQueue};
+
+/// How often a typing notification is repeated while the user keeps typing.
+const TYPING_REFRESH: Duration = Duration::from_secs(5);
+
+/// How long updates are gathered before the chat list is refreshed and the
+/// open chat marked read, so a busy group costs one refresh per window
+/// rather than one per message.
+const REFRESH_WINDOW: Duration = Duration::from_millis(150);
+
+/// Most users and chats asked of Telegram for a quick-switcher query.
+const SWITCHER_SEARCH_LIMIT: usize = 10;
+
+/// Updates kept for `/dump` while debugging is on.
+const DUMP_UPDATES: usize = 50;
+
+/// Messages loaded when jumping to a date.
+const JUMP_HISTORY_LIMIT: usize = 100;
+
+/// Pages of older history loaded looking for a replied message before
+/// giving up.
+const MAX_REPLY_PAGES: usize = 5;
+
+/// Most unread messages the inbox loads from any one chat.
+const INBOX_PER_CHAT_LIMIT: usize = 20;
+
+/// Most messages `/find` asks the server for.
+const FIND_SERVER_LIMIT: usize = 50;
+
+/// Most members loaded for the member list.
+const MEMBER_LIST_LIMIT: usize = 200;
+
+/// How long the status bar flashes for a new message.
+const FLASH_DURATION: Duration = Duration::from_millis(600);
+
+/// Said when an encrypted chat's media has nowhere private to go.
+const NO_PRIVATE_DIR: &str = "No private directory for encrypted media";
+
+/// The conversation not being worked in while the view is split.
+///
+/// The focused conversation always lives in `App::conversation_model`, so
+/// every handler keeps acting on whichever side the user is typing in;
+/// switching panes swaps the two.
+struct SplitConversation {
+    /// Chat shown, if one has been picked yet
+    chat_id: Option<i64>,
+    model: ConversationModel,
+    /// Whether this conversation is drawn on the right
+    on_right: bool,
+}
+
+/// Everything drawn over the panes: overlays, dialogs and prompts.
+///
+/// Locking replaces the lot with the default, so an overlay added here is
+/// never left showing behind the lock screen.
+#[derive(Default)]
+struct Overlays {
+    /// Whether the help overlay is visible
+    show_help: bool,
+
+    /// Error history overlay, when open (`Alt+E`).
+    error_log: Option<ErrorLog>,
+
+    /// Active file picker overlay, when attaching a file.
+    file_picker: Option<crate::ui::components::FilePicker>,
+
+    /// Active chat quick-switcher overlay (`Ctrl+K`).
+    quick_switcher: Option<QuickSwitcher>,
+
+    /// Message being forwarded (chat ID, message ID), while its destination
+    /// and options are chosen.
+    pending_forward: Option<(i64, i64)>,
+
+    /// Forward options dialog, once a destination is chosen.
+    forward_dialog: Option<ForwardDialog>,
+
+    /// Active "jump to date" prompt (`Ctrl+G`).
+    date_prompt: Option<DatePrompt>,
+
+    /// Report reason picker, for a chat (`/report`) or message (`!`).
+    report_dialog: Option<ReportDialog>,
+
+    /// Reaction picker for the selected message (`+`).
+    reaction_picker: Option<ReactionPicker>,
+
+    /// Group default permissions editor (`/permissions`).
+    permissions_editor: Option<PermissionsEditor>,
+
+    /// Poll overlay for the selected poll message.
+    poll_view: Option<PollView>,
+
+    /// Unread messages from every chat (`Alt+I`).
+    inbox: Option<Inbox>,
+
+    /// Messages bookmarked on this device (`Alt+B`).
+    bookmark_list: Option<BookmarkList>,
+
+    /// The open chat's messages pinned on this device (`Alt+P`).
+    pin_board: Option<PinBoard>,
+
+    /// Statistics for the open chat (`/stats`).
+    chat_stats: Option<ChatStatsView>,
+
+    /// Link shown as a QR code, when open (`/qr`).
+    qr_view: Option<QrView>,
+
+    /// Picker for who to post as in the open chat (`/sendas`).
+    send_as_picker: Option<SendAsPicker>,
+
+    /// Menu of actions for the open chat (`Alt+A`).
+    chat_actions: Option<ChatActions>,
+
+    /// Members of a group or channel, from the chat actions menu.
+    member_list: Option<MemberList>,
+
+    /// Local data and its size, when open (`/storage`).
+    storage_manager: Option<StorageManager>,
+
+    /// Subscribed channels, when open (`/channels`).
+    channel_manager: Option<ChannelManager>,
+
+    /// Results of a search across chats (`/find`).
+    search_results: Option<SearchResults>,
+
+    /// Yes/No prompt, with the action to run if the user says yes.
+    confirmation: Option<(Modal, AppAction)>,
+
+    /// Whether the reactions feed overlay is open (`Alt+R`).
+    show_reactions: bool,
+}
 
 /// Which pane is currently focused in the main view.
 #[derive(Debug, Clone, Copy, PartialEq, Eq, Default)]
@@ -126,23 +282,75 @@ pub enum AppAction {
     ChatSelected(i64),
     /// Send a message to the current chat
     SendMessage(i64, String, Option<i64>),
-    /// Send a message with a file attachment (`chat_id`, caption, file path, optional `reply_to`)
-    SendMessageWithAttachment(i64, String, std::path::PathBuf, Option<i64>),
+    /// Send a message with a file attachment (`chat_id`, caption, file path,
+    /// whether an image goes as a photo, optional `reply_to`)
+    SendMessageWithAttachment(i64, String, std::path::PathBuf, bool, Option<i64>),
+    /// Send several files with one caption (`chat_id`, caption, file paths,
+    /// whether images go as photos, optional `reply_to`)
+    SendAttachments(i64, String, Vec<std::path::PathBuf>, bool, Option<i64>),
     /// Edit an existing message
     EditMessage(i64, i64, String),
     /// Delete a message
     DeleteMessage(i64, i64),
     /// Open media (download if needed and open with system viewer)
     OpenMedia(i64, i64),
+    /// Save a copy of a message's attachment to the downloads directory
+    SaveMedia(i64, i64),
+    /// Edit the composer draft in the external editor
+    OpenEditor,
+    /// Run a slash command typed into the input
+    Command(SlashCommand),
+    /// Tell a chat that the user is typing
+    SendTyping(i64),
+    /// Load a chat's history around a day (chat ID, day)
+    JumpToDate(i64, NaiveDate),
+    /// Forward a message (source chat ID, message ID, destination chat IDs, options)
+    ForwardMessage(i64, i64, Vec<i64>, ForwardOptions),
+    /// Open a chat at a message (chat ID, message ID)
+    JumpToMessage(i64, i64),
+    /// Load older history until a replied message is found, then select it
+    /// (chat ID, message ID)
+    JumpToReply(i64, i64),
+    /// Gather unread messages from every chat into the inbox
+    OpenInbox,
+    /// Open a channel's discussion group (channel ID)
+    OpenDiscussion(i64),
+    /// Report a chat or message to Telegram
+    Report(ReportTarget, ReportReason),
+    /// React to a message (chat ID, message ID), or take the reaction back
+    React(i64, i64, Option<String>),
+    /// Set a group's default member permissions
+    SetPermissions(i64, ChatPermissions),
+    /// Mark these chats as read
+    MarkAsRead(Vec<i64>),
+    /// Vote in a poll (chat ID, message ID, option identifiers; empty
+    /// retracts)
+    VotePoll(i64, i64, Vec<Vec<u8>>),
+    /// Load a page of a poll option's voters (chat ID, message ID, option,
+    /// offset)
+    LoadPollVoters(i64, i64, Vec<u8>, Option<String>),
+    /// Find messages of one kind in a chat, such as its shared media
+    FindInChat(i64, SearchFilter),
+    /// Load a group's or channel's members into the member list
+    LoadMembers(i64),
+    /// Delete these kinds of local data
+    ClearLocalData(Vec<LocalData>),
+    /// Find a user or chat by username or phone number and open it
+    ResolveChat(PeerHandle),
+    /// Find a user or chat by username and forward the pending message there
+    ResolveForwardTarget(PeerHandle),
+    /// Mute, archive or leave the channels picked in the channel view
+    ChangeChannels(ChannelManagerAction),
+    /// Open a chat, or a message in it, in the official Telegram app
+    OpenInTelegram(i64, Option<i64>),
+    /// Log out of Telegram and go back to the sign-in screen
+    LogOut,
 }
 
 /// The main TUI application.
 ///
 /// This struct holds all application state including configuration,
 /// the Telegram client, cache, and UI state.
-// App tracks four independent UI/runtime flags (show_sidebar, show_help,
-// should_quit, terminal_focused); audit before adding a fifth.
-#[allow(clippy::struct_excessive_bools)]
 pub struct App {
     /// Current application state
     pub state: AppState,
@@ -153,8 +361,9 @@ pub struct App {
     /// Whether the sidebar is visible
     pub show_sidebar: bool,
 
-    /// Whether the help overlay is visible
-    pub show_help: bool,
+    /// Width of the last frame drawn, for collapsing to one pane on
+    /// narrow terminals
+    screen_width: u16,
 
     /// Whether the application should quit
     pub should_quit: bool,
@@ -162,11 +371,20 @@ pub struct App {
     /// Application configuration
     pub config: Config,
 
+    /// Where settings are saved: the file the config was loaded from.
+    config_file: std::path::PathBuf,
+
     /// Key bindings
     pub keymap: KeyMap,
 
-    /// Telegram client
-    pub telegram: Arc<TelegramClient>,
+    /// Leader key and its sequences
+    leader: LeaderKeys,
+
+    /// Keys typed since the leader, while a sequence is in progress
+    leader_pending: Option<String>,
+
+    /// Telegram backend
+    pub telegram: Arc<dyn TelegramApi>,
 
     /// Shared cache for Telegram data
     pub cache: SharedCache,
@@ -186,20 +404,92 @@ pub struct App {
     /// Conversation UI model
     conversation_model: ConversationModel,
 
+    /// Second conversation, while the view is split (`Alt+V`).
+    split: Option<SplitConversation>,
+
     /// Settings UI model
     settings_model: SettingsModel,
 
     /// Currently selected chat ID (for conversation view)
     selected_chat_id: Option<i64>,
 
-    /// Status message to display (for errors/info)
-    pub status_message: Option<String>,
+    /// Public channel or group open read-only without being joined, kept
+    /// out of the cache and chat list until `/join`.
+    preview: Option<Chat>,
+
+    /// Notices shown above the status bar, and the error history
+    toasts: Toasts,
+
+    /// Overlays and dialogs drawn over the panes, closed all at once on
+    /// locking.
+    overlays: Overlays,
 
     /// Status bar model
     status_bar: StatusBar,
 
-    /// Active file picker overlay, when attaching a file.
-    file_picker: Option<crate::ui::components::FilePicker>,
+    /// What to open once the chat list first loads; taken when it does.
+    startup_view: Option<StartupView>,
+
+    /// Identity chosen with `/sendas`, per chat; messages go out as the
+    /// user where there is none.
+    send_as: HashMap<i64, SendAsPeer>,
+
+    /// Local names for chats and users, set with `/alias`.
+    aliases: state::Aliases,
+
+    /// Reactions to the user's messages received this session.
+    reactions: ReactionsFeed,
+
+    /// Lock screen hiding the UI (`Ctrl+L`, `/lock`, or idle auto-lock).
+    lock_screen: Option<LockScreen>,
+
+    /// Key for encrypted chats' local data, derived on unlocking and
+    /// dropped on locking.
+    vault: Option<Vault>,
+
+    /// Last window title written to the terminal, with its badge state.
+    terminal_title: Option<(String, bool)>,
+
+    /// When the user last pressed a key or focused the terminal.
+    last_activity: Instant,
+
+    /// When updates first left the chat list behind since its last refresh.
+    list_stale_since: Option<Instant>,
+
+    /// The open chat and when messages first arrived unread in it since it
+    /// was last marked read.
+    unread_in_view_since: Option<(i64, Instant)>,
+
+    /// One-line summaries of the latest updates, oldest first, kept for
+    /// `/dump` while `logging.debug` is on.
+    recent_updates: VecDeque<String>,
+
+    /// The screen and the panes on it as last drawn, for `/dump`.
+    pane_areas: Vec<(&'static str, Rect)>,
+
+    /// Messages and files on their way out.
+    sends: SendQueue,
+
+    /// Chats being marked read.
+    mark_read: Option<MarkRead>,
+
+    /// Unread messages being fetched for the inbox.
+    inbox_fetch: Option<InboxFetch>,
+
+    /// Attachments being saved by `/download`.
+    bulk_download: Option<BulkDownload>,
+
+    /// Voice messages being played by `/listen`.
+    listening: Option<VoiceQueue>,
+
+    /// When the status bar stops flashing for a new message.
+    flash_until: Option<Instant>,
+
+    /// Last online status reported to Telegram, and when.
+    reported_presence: Option<(bool, Instant)>,
+
+    /// Chat and time of the last typing notification sent.
+    last_typing_sent: Option<(i64, Instant)>,
 
     /// Whether the terminal is currently focused. Starts true so terminals
     /// without focus reporting never produce spurious notifications.
@@ -212,7 +502,8 @@ impl App {
     /// # Arguments
     ///
     /// * `config` - Application configuration
-    /// * `telegram` - Telegram client (wrapped in Arc for sharing)
+    /// * `telegram` - Telegram backend, usually a
+    ///   [`TelegramClient`](crate::telegram::TelegramClient)
     /// * `cache` - Shared cache for Telegram data
     ///
     /// # Examples
@@ -236,23 +527,59 @@ impl App {
     /// let app = App::new(config, telegram, cache);
     /// ```
     #[must_use]
-    pub fn new(config: Config, telegram: Arc<TelegramClient>, cache: SharedCache) -> Self {
+    pub fn new(config: Config, telegram: Arc<dyn TelegramApi>, cache: SharedCache) -> Self {
         let vim_mode = config.ui.keyboard.vim_mode;
         let show_sidebar = config.ui.layout.show_info_pane;
-        let chat_list_model = ChatListModel::new(cache.clone());
-        let conversation_model = ConversationModel::new();
+        let startup_view = config.ui.behavior.startup_view();
+        // Encrypted chats' data can only be read after unlocking
+        let privacy = &config.privacy;
+        let lock_screen = (!privacy.encrypted_chats.is_empty()
+            && !privacy.lock_passphrase_hash.is_empty())
+        .then(|| LockScreen::locked(privacy.lock_passphrase_hash.clone()));
+        let aliases = state::load_aliases(&state::aliases_file(&config));
+        let mut chat_list_model = ChatListModel::new(cache.clone());
+        chat_list_model.set_aliases(aliases.as_map().clone());
+        chat_list_model.set_blur_previews(config.privacy.hides_previews());
+        chat_list_model.set_preview_format(
+            config.ui.appearance.message_preview_length,
+            config.ui.appearance.message_preview_lines,
+        );
+        chat_list_model.set_sections(config.ui.layout.chat_list_sections);
+        chat_list_model.set_show_avatars(config.ui.appearance.show_avatars);
+        let mut conversation_model = ConversationModel::new();
+        conversation_model.set_compress_images(config.ui.behavior.compress_images);
         let settings_model = SettingsModel::new(config.clone());
         let mut status_bar = StatusBar::new();
         status_bar.set_vim_mode(vim_mode);
+        let (leader, leader_errors) = LeaderKeys::new(
+            &config.ui.keyboard.leader,
+            &config.ui.keyboard.leader_bindings,
+        );
+        for error in &leader_errors {
+            tracing::warn!("Ignoring {}", error);
+        }
+        let mut toasts = Toasts::new();
+        if let Some(error) = leader_errors.first() {
+            toasts.push(
+                format!("Config: ignoring {error}"),
+                Severity::Error,
+                Instant::now(),
+            );
+        }
 
+        let sends = SendQueue::new(Arc::clone(&telegram));
         Self {
             state: AppState::Loading,
             focused_pane: FocusedPane::ChatList,
             show_sidebar,
-            show_help: false,
+            // Nothing drawn yet; don't collapse until it is
+            screen_width: u16::MAX,
             should_quit: false,
             config,
+            config_file: Config::default_file(),
             keymap: KeyMap::new(vim_mode),
+            leader,
+            leader_pending: None,
             telegram,
             cache,
             update_rx: None,
@@ -260,11 +587,33 @@ impl App {
             auth_model: AuthModel::new(),
             chat_list_model,
             conversation_model,
+            split: None,
             settings_model,
             selected_chat_id: None,
-            status_message: None,
+            preview: None,
+            toasts,
+            overlays: Overlays::default(),
             status_bar,
-            file_picker: None,
+            startup_view: Some(startup_view),
+            send_as: HashMap::new(),
+            aliases,
+            reactions: ReactionsFeed::new(),
+            lock_screen,
+            vault: None,
+            terminal_title: None,
+            last_activity: Instant::now(),
+            list_stale_since: None,
+            unread_in_view_since: None,
+            recent_updates: VecDeque::new(),
+            pane_areas: Vec::new(),
+            sends,
+            mark_read: None,
+            inbox_fetch: None,
+            bulk_download: None,
+            listening: None,
+            flash_until: None,
+            reported_presence: None,
+            last_typing_sent: None,
             terminal_focused: true,
         }
     }
@@ -276,14 +625,65 @@ impl App {
         self.update_rx = Some(rx);
     }
 
-    /// Set a status message to display.
+    /// Sets where settings are saved, in place of the default config file
+    /// (as `--config` does).
+    pub fn set_config_file(&mut self, path: std::path::PathBuf) {
+        self.config_file = path;
+    }
+
+    /// Sets what opens once the chat list loads, in place of the
+    /// configured `startup_view` (as `--chat` does).
+    pub fn set_startup_view(&mut self, view: StartupView) {
+        self.startup_view = Some(view);
+    }
+
+    /// Tells the user which damaged files were set aside at startup. A
+    /// session set aside means logging in again, which the login screen
+    /// that follows walks them through.
+    pub fn report_repairs(&mut self, repairs: &[health::Repair]) {
+        for repair in repairs {
+            let name = repair
+                .moved_to
+                .file_name()
+                .map_or_else(String::new, |n| n.to_string_lossy().into_owned());
+            self.set_error_message(match repair.kind {
+                health::RepairedFile::Session => format!(
+                    "Your session file was damaged ({}) and set aside as {name}; \
+                     log in again to continue",
+                    repair.reason
+                ),
+                health::RepairedFile::LastChat => {
+                    format!("The last open chat couldn't be read and was set aside as {name}")
+                },
+            });
+        }
+    }
+
+    /// Shows an informational notice above the status bar.
     pub fn set_status_message(&mut self, message: impl Into<String>) {
-        self.status_message = Some(message.into());
+        self.toasts.push(message, Severity::Info, Instant::now());
+    }
+
+    /// Shows a notice that something finished as asked.
+    pub fn set_success_message(&mut self, message: impl Into<String>) {
+        self.toasts.push(message, Severity::Success, Instant::now());
     }
 
-    /// Clear the status message.
+    /// Shows an error notice and keeps it in the error history (`Alt+E`).
+    pub fn set_error_message(&mut self, message: impl Into<String>) {
+        self.toasts.push(message, Severity::Error, Instant::now());
+    }
+
+    /// Takes down the notice on screen if it's only information, such as
+    /// progress that has finished. Successes and errors stay up.
     pub fn clear_status_message(&mut self) {
-        self.status_message = None;
+        if self
+            .toasts
+            .current()
+            .is_some_and(|toast| toast.severity == Severity::Info)
+        {
+            self.toasts.dismiss(Instant::now());
+        }
     }
 
     /// Updates the authentication state in both App and `AuthModel`.
@@ -356,6 +756,8 @@ impl App {
 
         loop {
             // Render the UI
+            self.check_auto_lock();
+            self.sync_terminal_title();
             terminal.draw(|frame| self.render(frame))?;
 
             // Handle events
@@ -363,7 +765,9 @@ impl App {
                 if let Event::Key(key) = event::read()? {
                     // Only handle key press events, not release
                     if key.kind == KeyEventKind::Press {
-                        self.handle_key(key);
+                        if let Some(AppAction::OpenEditor) = self.handle_key(key) {
+                            self.open_external_editor(terminal);
+                        }
                     }
                 }
             }
@@ -377,6 +781,8 @@ impl App {
             }
         }
 
+        vault::clear_scratch_dir();
+        crate::app::paths::remove_runtime_dir();
         Ok(())
     }
 
@@ -396,6 +802,8 @@ impl App {
 
         loop {
             // Render the UI
+            self.check_auto_lock();
+            self.sync_terminal_title();
             terminal.draw(|frame| self.render(frame))?;
 
             // Handle events (poll is non-blocking with timeout)
@@ -403,8 +811,10 @@ impl App {
                 if let Event::Key(key) = event::read()? {
                     // Only handle key press events, not release
                     if key.kind == KeyEventKind::Press {
-                        if let Some(action) = self.handle_key(key) {
-                            self.handle_app_action(action).await;
+                        match self.handle_key(key) {
+                            Some(AppAction::OpenEditor) => self.open_external_editor(terminal),
+                            Some(action) => self.handle_app_action(action).await,
+                            None => {},
                         }
                     }
                 }
@@ -419,6 +829,8 @@ impl App {
             }
         }
 
+        vault::clear_scratch_dir();
+        crate::app::paths::remove_runtime_dir();
         Ok(())
     }
 
@@ -453,6 +865,8 @@ impl App {
 
         loop {
             // Render the UI
+            self.check_auto_lock();
+            self.sync_terminal_title();
             terminal.draw(|frame| self.render(frame))?;
 
             // Use tokio::select to handle multiple async sources
@@ -462,13 +876,20 @@ impl App {
                     // Check for terminal events (non-blocking)
                     while event::poll(Duration::from_millis(0))? {
                         match event::read()? {
-                            Event::FocusGained => self.terminal_focused = true,
+                            Event::FocusGained => {
+                                self.terminal_focused = true;
+                                self.last_activity = Instant::now();
+                            },
                             Event::FocusLost => self.terminal_focused = false,
                             Event::Key(key)
                                 if key.kind == KeyEventKind::Press =>
                             {
-                                if let Some(action) = self.handle_key(key) {
-                                    self.handle_app_action(action).await;
+                                match self.handle_key(key) {
+                                    Some(AppAction::OpenEditor) => {
+                                        self.open_external_editor(terminal);
+                                    },
+                                    Some(action) => self.handle_app_action(action).await,
+                                    None => {},
                                 }
                             },
                             _ => {}
@@ -477,6 +898,9 @@ impl App {
 
                     // Process any pending Telegram updates
                     self.process_updates().await;
+
+                    // Report online/offline as focus and activity change
+                    self.sync_presence().await;
                 }
 
                 // Poll the connection handle (only if not already complete)
@@ -500,13 +924,13 @@ impl App {
                         }
                         Ok(Err(e)) => {
                             error!("Connection failed: {e}");
-                            self.set_status_message(format!("Connection failed: {e}"));
+                            self.set_error_message(format!("Connection failed: {e}"));
                             // Stay in loading state but show error
                             // User can quit with 'q' or Ctrl+C
                         }
                         Err(e) => {
                             error!("Connection task panicked: {e}");
-                            self.set_status_message(format!("Connection error: {e}"));
+                            self.set_error_message(format!("Connection error: {e}"));
                         }
                     }
                 }
@@ -518,6 +942,8 @@ impl App {
             }
         }
 
+        vault::clear_scratch_dir();
+        crate::app::paths::remove_runtime_dir();
         Ok(())
     }
 
@@ -531,11 +957,13 @@ impl App {
                 self.handle_chat_selected(chat_id).await;
             },
             AppAction::SendMessage(chat_id, text, reply_to) => {
-                self.handle_send_message(chat_id, text, reply_to).await;
+                self.handle_send_message(chat_id, text, reply_to);
             },
-            AppAction::SendMessageWithAttachment(chat_id, text, path, reply_to) => {
-                self.handle_send_message_with_attachment(chat_id, text, path, reply_to)
-                    .await;
+            AppAction::SendMessageWithAttachment(chat_id, text, path, as_photo, reply_to) => {
+                self.handle_send_attachments(chat_id, text, vec![path], as_photo, reply_to);
+            },
+            AppAction::SendAttachments(chat_id, text, paths, as_photo, reply_to) => {
+                self.handle_send_attachments(chat_id, text, paths, as_photo, reply_to);
             },
             AppAction::EditMessage(chat_id, message_id, text) => {
                 self.handle_edit_message(chat_id, message_id, text).await;
@@ -546,177 +974,2253 @@ impl App {
             AppAction::OpenMedia(chat_id, message_id) => {
                 self.handle_open_media(chat_id, message_id).await;
             },
-            // Quit and Forward are already handled by setting should_quit in handle_key
-            AppAction::Quit | AppAction::Forward(_) => {},
-        }
-    }
-
-    /// Converts a conversation action to an app action.
-    fn handle_conversation_action(&self, action: ConversationAction) -> Option<AppAction> {
-        let chat_id = self.selected_chat_id?;
-
-        match action {
-            ConversationAction::SendMessage(text, reply_to) => {
-                Some(AppAction::SendMessage(chat_id, text, reply_to))
+            AppAction::SaveMedia(chat_id, message_id) => {
+                self.handle_save_media(chat_id, message_id);
             },
-            ConversationAction::EditMessage(message_id, text) => {
-                Some(AppAction::EditMessage(chat_id, message_id, text))
+            AppAction::Command(command) => {
+                self.handle_slash_command(command).await;
             },
-            ConversationAction::DeleteMessage(message_id) => {
-                Some(AppAction::DeleteMessage(chat_id, message_id))
+            AppAction::JumpToDate(chat_id, date) => {
+                self.handle_jump_to_date(chat_id, date).await;
             },
-            ConversationAction::ForwardMessage(_message_id) => {
-                // Forward not yet implemented
-                None
+            AppAction::ForwardMessage(from_chat_id, message_id, to_chat_ids, options) => {
+                self.handle_forward_message(from_chat_id, message_id, &to_chat_ids, &options)
+                    .await;
             },
-            ConversationAction::SendMessageWithAttachment(text, path, reply_to) => Some(
-                AppAction::SendMessageWithAttachment(chat_id, text, path, reply_to),
-            ),
-        }
-    }
-
-    /// Handle sending a message.
-    async fn handle_send_message(&mut self, chat_id: i64, text: String, reply_to: Option<i64>) {
-        match self.telegram.send_message(chat_id, &text, reply_to).await {
-            Ok(message) => {
-                // Add the sent message to the conversation
-                self.conversation_model.add_message(message);
+            AppAction::JumpToMessage(chat_id, message_id) => {
+                self.handle_jump_to_message(chat_id, message_id).await;
             },
-            Err(e) => {
-                self.set_status_message(format!("Failed to send message: {e}"));
+            AppAction::JumpToReply(chat_id, message_id) => {
+                self.handle_jump_to_reply(chat_id, message_id).await;
             },
-        }
-    }
-
-    /// Handle sending a message with a file attachment.
-    async fn handle_send_message_with_attachment(
-        &mut self,
-        chat_id: i64,
-        text: String,
-        path: std::path::PathBuf,
-        reply_to: Option<i64>,
-    ) {
-        self.set_status_message("Uploading\u{2026}".to_string());
-        match self
-            .telegram
-            .send_file(chat_id, &text, &path, reply_to)
-            .await
-        {
-            Ok(message) => {
-                self.conversation_model.add_message(message);
-                self.clear_status_message();
+            AppAction::OpenInbox => self.handle_open_inbox(),
+            AppAction::OpenDiscussion(chat_id) => self.handle_open_discussion(chat_id).await,
+            AppAction::Report(target, reason) => self.handle_report(target, reason).await,
+            AppAction::React(chat_id, message_id, reaction) => {
+                self.handle_react(chat_id, message_id, reaction).await;
             },
-            Err(e) => {
-                self.set_status_message(format!("Failed to send file: {e}"));
+            AppAction::OpenInTelegram(chat_id, message_id) => {
+                self.handle_open_in_telegram(chat_id, message_id).await;
             },
-        }
-    }
-
-    /// Handle editing a message.
-    async fn handle_edit_message(&mut self, chat_id: i64, message_id: i64, text: String) {
-        match self.telegram.edit_message(chat_id, message_id, &text).await {
-            Ok(message) => {
-                self.conversation_model.update_message(message);
+            AppAction::MarkAsRead(chat_ids) => self.handle_mark_as_read(chat_ids),
+            AppAction::VotePoll(chat_id, message_id, options) => {
+                self.handle_vote_poll(chat_id, message_id, &options).await;
             },
-            Err(e) => {
-                self.set_status_message(format!("Failed to edit message: {e}"));
+            AppAction::LoadPollVoters(chat_id, message_id, option, offset) => {
+                let result = self
+                    .telegram
+                    .get_poll_voters(chat_id, message_id, &option, offset.as_deref(), 50)
+                    .await;
+                if let Err(e) = &result {
+                    self.set_error_message(format!("Failed to load voters: {e}"));
+                }
+                if let Some(view) = self.overlays.poll_view.as_mut() {
+                    match result {
+                        Ok(page) => view.add_voters(page),
+                        Err(_) => view.voters_failed(),
+                    }
+                }
+            },
+            AppAction::FindInChat(chat_id, filter) => {
+                self.handle_find_in_chat(chat_id, filter).await
+            },
+            AppAction::LoadMembers(chat_id) => {
+                match self.telegram.get_members(chat_id, MEMBER_LIST_LIMIT).await {
+                    Ok(members) => {
+                        let title = self.chat_display_name(chat_id);
+                        self.overlays.member_list = Some(MemberList::new(title, members));
+                    },
+                    Err(e) => self.set_error_message(format!("Failed to load members: {e}")),
+                }
+            },
+            AppAction::ClearLocalData(kinds) => self.clear_local_data(&kinds),
+            AppAction::LogOut => self.handle_log_out().await,
+            AppAction::ResolveChat(handle) => self.handle_resolve_chat(&handle).await,
+            AppAction::ResolveForwardTarget(handle) => {
+                self.handle_resolve_forward_target(&handle).await;
+            },
+            AppAction::ChangeChannels(change) => self.change_channels(change).await,
+            AppAction::SetPermissions(chat_id, permissions) => {
+                match self
+                    .telegram
+                    .set_chat_permissions(chat_id, permissions)
+                    .await
+                {
+                    Ok(()) => self.set_success_message("Permissions updated"),
+                    Err(e) => {
+                        self.set_error_message(format!("Failed to update permissions: {e}"));
+                    },
+                }
+            },
+            AppAction::SendTyping(chat_id) => {
+                if let Err(e) = self.telegram.send_typing(chat_id).await {
+                    tracing::debug!("Failed to send typing to {}: {}", chat_id, e);
+                }
             },
+            // Quit and Forward are already handled by setting should_quit in handle_key;
+            // OpenEditor needs the terminal, so the run loop handles it directly
+            AppAction::Quit | AppAction::Forward(_) | AppAction::OpenEditor => {},
         }
     }
 
-    /// Handle deleting a message.
-    async fn handle_delete_message(&mut self, chat_id: i64, message_id: i64) {
-        // revoke=true means delete for everyone (if allowed)
-        match self
-            .telegram
-            .delete_messages(chat_id, &[message_id], true)
-            .await
-        {
-            Ok(()) => {
-                self.conversation_model.delete_message(message_id);
-            },
-            Err(e) => {
-                self.set_status_message(format!("Failed to delete message: {e}"));
-            },
+    /// Updates the terminal window title if the unread count, open chat, or
+    /// focus changed since it was last written.
+    fn sync_terminal_title(&mut self) {
+        if !self.config.notifications.terminal_title {
+            // Toggled off at runtime: give the title back once
+            if self.terminal_title.take().is_some() {
+                crate::utils::reset_terminal_title();
+            }
+            return;
+        }
+
+        let unread = self.chat_list_model.total_unread();
+        // Never reveal the open chat's name while locked
+        let chat_name = self
+            .selected_chat_id
+            .filter(|_| self.lock_screen.is_none())
+            .and_then(|id| {
+                self.aliases.get(id).map(str::to_string).or_else(|| {
+                    self.conversation_model
+                        .chat
+                        .as_ref()
+                        .filter(|c| c.id == id)
+                        .map(|c| c.title.clone())
+                })
+            });
+        let title = crate::utils::window_title(unread, chat_name.as_deref(), self.terminal_focused);
+        let badge = unread > 0 && !self.terminal_focused;
+
+        if self.terminal_title.as_ref() != Some(&(title.clone(), badge)) {
+            crate::utils::set_terminal_title(&title, badge);
+            self.terminal_title = Some((title, badge));
         }
     }
 
-    /// Handle opening media from a message.
+    /// Locks the screen once the UI has been idle for the configured time.
     ///
-    /// Downloads the attachment if not already downloaded, then opens it with
-    /// the system viewer. Works for any attachment type, not just photos.
-    async fn handle_open_media(&mut self, chat_id: i64, message_id: i64) {
-        use crate::telegram::TelegramClient;
+    /// Auto-lock only applies once a passphrase has been set, since setting
+    /// one needs the user at the keyboard.
+    fn check_auto_lock(&mut self) {
+        let privacy = &self.config.privacy;
+        if privacy.auto_lock_secs == 0
+            || privacy.lock_passphrase_hash.is_empty()
+            || self.lock_screen.is_some()
+            || !matches!(self.state, AppState::Main | AppState::Settings)
+        {
+            return;
+        }
+        if self.last_activity.elapsed() >= Duration::from_secs(privacy.auto_lock_secs) {
+            self.lock();
+        }
+    }
 
-        // Get the message from cache
-        let message = self
-            .cache
-            .get_messages(chat_id)
-            .into_iter()
-            .find(|m| m.id == message_id);
+    /// Locks the screen, first asking for a passphrase if none is set.
+    fn lock(&mut self) {
+        self.overlays = Overlays::default();
+        self.leader_pending = None;
+        self.vault = None;
+        vault::clear_scratch_dir();
+        let hash = &self.config.privacy.lock_passphrase_hash;
+        self.lock_screen = Some(if hash.is_empty() {
+            LockScreen::setup()
+        } else {
+            LockScreen::locked(hash.clone())
+        });
+    }
 
-        let Some(message) = message else {
-            self.set_status_message("Message not found".to_string());
+    /// Reports online/offline to Telegram when the desired status changes,
+    /// and refreshes "online" before Telegram lets it expire.
+    async fn sync_presence(&mut self) {
+        if self.state != AppState::Main {
             return;
-        };
+        }
 
-        // Messages without a downloadable attachment may still carry a link;
-        // open the first URL in the browser instead.
-        if !message.content.content_type.is_downloadable() {
-            if let Some(url) = crate::utils::first_url(&message.content.text) {
-                if let Err(e) = TelegramClient::open_url(&url).await {
-                    self.set_status_message(format!("Failed to open link: {e}"));
-                }
-            } else {
-                self.set_status_message("Selected message has no attachment or link".to_string());
-            }
+        let privacy = &self.config.privacy;
+        let idle_timeout =
+            (privacy.idle_timeout_secs > 0).then(|| Duration::from_secs(privacy.idle_timeout_secs));
+        let online = crate::utils::should_be_online(
+            privacy.reports_online(),
+            self.terminal_focused,
+            self.last_activity.elapsed(),
+            idle_timeout,
+        );
+
+        let due = self.reported_presence.map_or(true, |(was_online, at)| {
+            was_online != online || (online && at.elapsed() >= crate::utils::ONLINE_REFRESH)
+        });
+        if !due {
             return;
         }
 
-        // Get the media directory from config (clone to avoid borrow issues)
-        let media_dir = self.config.cache.media_directory.clone();
+        if let Err(e) = self.telegram.set_online(online).await {
+            tracing::warn!("Failed to update online status: {e}");
+        }
+        // Record the attempt either way so a failing request isn't retried
+        // every tick
+        self.reported_presence = Some((online, Instant::now()));
+    }
 
-        // Download if needed and open
-        self.set_status_message("Downloading attachment...".to_string());
+    /// Suspends the TUI and edits the composer draft in `$EDITOR`.
+    ///
+    /// Reply/edit state is untouched, so the returned text is sent in the
+    /// same context the draft was started in.
+    fn open_external_editor<B: ratatui::backend::Backend>(&mut self, terminal: &mut Terminal<B>) {
+        let draft = self.conversation_model.input.value().to_string();
+        match super::editor::edit_text(&draft) {
+            Ok(text) => self.conversation_model.input.set_value(text),
+            Err(e) => self.set_error_message(format!("Editor failed: {e}")),
+        }
+        // The editor drew over the screen; force a full redraw.
+        let _ = terminal.clear();
+    }
 
-        match self
-            .telegram
-            .download_media_if_needed(&message, &media_dir)
-            .await
-        {
-            Ok(path) => {
-                self.clear_status_message();
-                // Open the file with system viewer
-                if let Err(e) = TelegramClient::open_media_file(&path).await {
-                    self.set_status_message(format!("Failed to open attachment: {e}"));
-                }
+    /// Parses the input as a slash command, if it is one.
+    ///
+    /// Returns `Some` when the input was consumed: either the command to run,
+    /// or `None` after reporting a parse error. A `//` escape is reduced to a
+    /// single slash so the text is sent as a normal message.
+    fn take_slash_command(&mut self) -> Option<Option<AppAction>> {
+        // Commands don't apply while editing an existing message
+        if self.conversation_model.editing.is_some() {
+            return None;
+        }
+
+        let text = self.conversation_model.input.value().trim_end().to_string();
+        let Some(parsed) = slash_command::parse(&text) else {
+            let unescaped = slash_command::unescape(&text);
+            if unescaped.len() != text.len() {
+                self.conversation_model.input.set_value(unescaped);
+            }
+            return None;
+        };
+
+        match parsed {
+            Ok(command) => {
+                self.conversation_model.input.clear();
+                Some(Some(AppAction::Command(command)))
             },
-            Err(e) => {
-                self.set_status_message(format!("Failed to download attachment: {e}"));
+            Err(message) => {
+                self.set_status_message(message);
+                Some(None)
             },
         }
     }
 
-    /// Handle authentication actions asynchronously.
-    async fn handle_auth_action(&mut self, action: AuthAction) {
-        self.set_auth_loading(true);
-        self.clear_status_message();
+    /// Completes the slash command in the input.
+    ///
+    /// A single candidate replaces the input; several extend it to their
+    /// common prefix and are listed in the status bar.
+    fn complete_slash_command(&mut self) {
+        let text = self.conversation_model.input.value().to_string();
+        let candidates = {
+            let names = self.chat_list_model.chat_names();
+            slash_command::complete(&text, &names)
+        };
 
-        let result: Result<(), crate::telegram::TelegramError> = match action {
-            AuthAction::SubmitPhoneNumber(phone) => self.telegram.request_login_code(&phone).await,
-            AuthAction::SubmitCode(code) => self.telegram.sign_in(&code).await,
-            AuthAction::SubmitPassword(password) => self.telegram.check_password(&password).await,
-            AuthAction::SubmitRegistration(_) => {
-                // Sign up via third-party apps is not supported by Telegram
-                Err(crate::telegram::TelegramError::SignUpRequired)
+        match candidates.as_slice() {
+            [] => self.set_status_message("No completions"),
+            [only] => {
+                self.conversation_model.input.set_value(only.clone());
+                self.clear_status_message();
             },
-            AuthAction::Quit => {
-                self.should_quit = true;
-                return;
+            _ => {
+                let prefix = slash_command::common_prefix(&candidates);
+                if prefix.len() > text.len() {
+                    self.conversation_model.input.set_value(prefix);
+                }
+                self.set_status_message(candidates.join("  "));
             },
-        };
+        }
+    }
 
-        self.set_auth_loading(false);
+    /// Runs a slash command.
+    async fn handle_slash_command(&mut self, command: SlashCommand) {
+        match command {
+            SlashCommand::Goto(query) => {
+                let Some(chat_id) = self.chat_list_model.find_chat(&query) else {
+                    self.set_status_message(format!("No chat matches \"{query}\""));
+                    return;
+                };
+                self.jump_to_chat(chat_id);
+                self.clear_status_message();
+                self.handle_chat_selected(chat_id).await;
+            },
+            SlashCommand::Mute(duration) => {
+                let Some(chat_id) = self.require_open_chat() else {
+                    return;
+                };
+                let result = match duration {
+                    Some(duration) => self.telegram.mute_chat_for(chat_id, duration).await,
+                    None => self.telegram.mute_chat(chat_id, true).await,
+                };
+                match result {
+                    Ok(()) => {
+                        self.refresh_chat_list();
+                        self.set_success_message(duration.map_or_else(
+                            || "Muted".to_string(),
+                            |d| {
+                                let until = chrono::Local::now() + d;
+                                format!("Muted until {}", until.format("%b %-d %H:%M"))
+                            },
+                        ));
+                    },
+                    Err(e) => self.set_error_message(format!("Failed to mute chat: {e}")),
+                }
+            },
+            SlashCommand::Unmute => {
+                let Some(chat_id) = self.require_open_chat() else {
+                    return;
+                };
+                match self.telegram.mute_chat(chat_id, false).await {
+                    Ok(()) => {
+                        self.refresh_chat_list();
+                        self.set_success_message("Unmuted");
+                    },
+                    Err(e) => self.set_error_message(format!("Failed to unmute chat: {e}")),
+                }
+            },
+            SlashCommand::AutoDelete(period) => {
+                let Some(chat_id) = self.require_open_chat() else {
+                    return;
+                };
+                let Some(chat) = self.cache.get_chat(chat_id) else {
+                    return;
+                };
+                let Some(period) = period else {
+                    self.set_status_message(format!(
+                        "Auto-delete: {}",
+                        crate::utils::format_auto_delete(chat.auto_delete_period)
+                    ));
+                    return;
+                };
+                if !chat.can_set_auto_delete {
+                    self.set_status_message("Only admins who can delete messages can change this");
+                    return;
+                }
+                match self.telegram.set_auto_delete(chat_id, period).await {
+                    Ok(()) if period == 0 => self.set_success_message("Auto-delete off"),
+                    Ok(()) => self.set_success_message(format!(
+                        "New messages will be deleted after {}",
+                        crate::utils::format_auto_delete(period)
+                    )),
+                    Err(e) => {
+                        self.set_error_message(format!("Failed to set auto-delete: {e}"));
+                    },
+                }
+            },
+            SlashCommand::Search(query) => {
+                if self.require_open_chat().is_none() {
+                    return;
+                }
+                match self.conversation_model.find_messages(&query) {
+                    0 => self.set_status_message(format!("No loaded messages match \"{query}\"")),
+                    1 => self.set_status_message("1 match"),
+                    n => self.set_status_message(format!("{n} matches, showing newest")),
+                }
+            },
+            SlashCommand::Find(input) => self.handle_find(&input).await,
+            SlashCommand::Theme(theme) => {
+                theme.apply();
+                self.config.ui.theme = theme.to_config_str().to_string();
+                self.persist_config();
+            },
+            SlashCommand::Export => {
+                if let Some(chat_id) = self.require_open_chat() {
+                    // An export is plain text; it would undo the encryption
+                    if self.config.privacy.is_encrypted(chat_id) {
+                        self.set_error_message(
+                            "This chat is encrypted (/encrypt); /export would write it out in \
+                             plain text",
+                        );
+                        return;
+                    }
+                    match self.export_conversation(chat_id) {
+                        Ok(path) => {
+                            self.set_success_message(format!("Exported to {}", path.display()));
+                        },
+                        Err(e) => self.set_error_message(format!("Export failed: {e}")),
+                    }
+                }
+            },
+            SlashCommand::Download(input) => self.handle_bulk_download(&input).await,
+            SlashCommand::Listen(command) => self.handle_listen(command).await,
+            SlashCommand::Dump => {
+                if !self.config.logging.debug {
+                    self.set_error_message("/dump needs debug: true under logging in the config");
+                    return;
+                }
+                match self.write_state_dump() {
+                    Ok(path) => {
+                        self.set_success_message(format!("State saved to {}", path.display()));
+                    },
+                    Err(e) => self.set_error_message(format!("Dump failed: {e}")),
+                }
+            },
+            SlashCommand::Stats => {
+                if let Some(chat_id) = self.require_open_chat() {
+                    let stats = ChatStats::compute(&self.cache.get_messages(chat_id), |message| {
+                        if message.is_outgoing {
+                            "You".to_string()
+                        } else {
+                            self.sender_display_name(message.sender_id)
+                        }
+                    });
+                    self.overlays.chat_stats =
+                        Some(ChatStatsView::new(self.chat_display_name(chat_id), stats));
+                }
+            },
+            SlashCommand::Qr(target) => self.handle_qr(target).await,
+            SlashCommand::SendAs => {
+                let Some(chat_id) = self.require_open_chat() else {
+                    return;
+                };
+                match self.telegram.get_send_as(chat_id).await {
+                    Ok(options) if options.iter().any(|o| !o.is_self) => {
+                        let current = self.send_as.get(&chat_id).map(|p| p.id);
+                        self.overlays.send_as_picker =
+                            Some(SendAsPicker::new(chat_id, options, current));
+                    },
+                    Ok(_) => self.set_status_message("You can only post as yourself here"),
+                    Err(e) => self.set_error_message(format!("Failed to load identities: {e}")),
+                }
+            },
+            SlashCommand::Alias(name) => {
+                let Some(chat_id) = self.require_open_chat() else {
+                    return;
+                };
+                self.aliases.set(chat_id, &name);
+                self.chat_list_model
+                    .set_aliases(self.aliases.as_map().clone());
+                if let Err(e) =
+                    state::save_aliases(&state::aliases_file(&self.config), &self.aliases)
+                {
+                    self.set_error_message(format!("Failed to save aliases: {e}"));
+                }
+            },
+            SlashCommand::Alert => {
+                let Some(chat_id) = self.require_open_chat() else {
+                    return;
+                };
+                let notifications = &mut self.config.notifications;
+                let chosen = notifications.toggle_alert_chat(chat_id);
+                let remaining = notifications.alert_chats.len();
+                let off = !notifications.bell && !notifications.flash;
+                self.persist_config();
+                let name = self.chat_display_name(chat_id);
+                if off {
+                    self.set_status_message(
+                        "Turn on bell or flash under notifications in the config to be alerted",
+                    );
+                } else if chosen {
+                    self.set_success_message(format!(
+                        "Bell and flash for {name} (/alert again to stop)"
+                    ));
+                } else if remaining == 0 {
+                    self.set_status_message("Bell and flash for every un-muted chat again");
+                } else {
+                    self.set_status_message(format!("No more bell and flash for {name}"));
+                }
+            },
+            SlashCommand::Permissions => {
+                let Some(chat_id) = self.require_open_chat() else {
+                    return;
+                };
+                let Some(chat) = self.cache.get_chat(chat_id) else {
+                    return;
+                };
+                let Some(permissions) = chat.default_permissions else {
+                    self.set_status_message("Only groups have member permissions");
+                    return;
+                };
+                if chat.can_edit_permissions {
+                    let title = self.chat_display_name(chat_id);
+                    self.overlays.permissions_editor =
+                        Some(PermissionsEditor::new(chat_id, title, permissions));
+                } else {
+                    // Members can still see what they're allowed to do
+                    self.show_sidebar = true;
+                    self.set_status_message("Only admins who can ban users can change these");
+                }
+            },
+            SlashCommand::Report => {
+                if let Some(chat_id) = self.require_open_chat() {
+                    let label = self.chat_display_name(chat_id);
+                    self.overlays.report_dialog =
+                        Some(ReportDialog::new(ReportTarget::Chat(chat_id), label));
+                }
+            },
+            SlashCommand::ReadAll => self.confirm_mark_all_as_read(),
+            SlashCommand::Lock => self.lock(),
+            SlashCommand::Encrypt => self.toggle_encryption(),
+            SlashCommand::Logout => self.confirm_log_out(),
+            SlashCommand::Join => self.join_preview().await,
+            SlashCommand::Cache => {
+                let metrics = self.cache.metrics();
+                let size = |bytes: usize| {
+                    crate::utils::format_file_size(i64::try_from(bytes).unwrap_or(i64::MAX))
+                };
+                let budget = if metrics.budget_bytes == 0 {
+                    String::new()
+                } else {
+                    format!(" of {}", size(metrics.budget_bytes))
+                };
+                self.set_status_message(format!(
+                    "Cache: {} messages in {} of {} chats, ~{}{budget}; dropped {} old messages and {} histories",
+                    metrics.messages,
+                    metrics.histories,
+                    metrics.chats,
+                    size(metrics.message_bytes),
+                    metrics.trimmed_messages,
+                    metrics.evicted_histories,
+                ));
+            },
+            SlashCommand::Storage => {
+                let rows = LocalData::ALL
+                    .into_iter()
+                    .map(|kind| (kind, self.local_data_size(kind)))
+                    .collect();
+                self.overlays.storage_manager = Some(StorageManager::new(rows));
+            },
+            SlashCommand::Channels => {
+                let view = ChannelManager::new(self.cache.get_all_chats());
+                if view.is_empty() {
+                    self.set_status_message("You aren't subscribed to any channels");
+                } else {
+                    self.overlays.channel_manager = Some(view);
+                }
+            },
+            SlashCommand::Help => {
+                let names: Vec<String> = slash_command::COMMANDS
+                    .iter()
+                    .map(|(name, _, _)| format!("/{name}"))
+                    .collect();
+                self.set_status_message(format!(
+                    "Commands: {} (Tab completes, //help sends /help)",
+                    names.join(" ")
+                ));
+            },
+        }
+    }
+
+    /// Posts as `peer` in a chat from now on.
+    fn choose_send_as(&mut self, chat_id: i64, peer: SendAsPeer) {
+        if peer.is_self {
+            self.send_as.remove(&chat_id);
+            self.set_success_message("Posting as yourself");
+        } else {
+            self.set_success_message(format!("Posting as {}", peer.name));
+            self.send_as.insert(chat_id, peer);
+        }
+    }
+
+    /// Returns the open chat's ID, or reports that no chat is open.
+    fn require_open_chat(&mut self) -> Option<i64> {
+        if self.selected_chat_id.is_none() {
+            self.set_status_message("No chat is open");
+        }
+        self.selected_chat_id
+    }
+
+    /// Saves `self.config` to disk through the settings model.
+    fn persist_config(&mut self) {
+        self.settings_model.reset(self.config.clone());
+        self.save_settings();
+    }
+
+    /// Writes the loaded messages of a chat to a text file.
+    ///
+    /// Files go to an `exports` directory next to the media cache and are
+    /// named after the chat ID and the current time.
+    fn export_conversation(&self, chat_id: i64) -> std::io::Result<std::path::PathBuf> {
+        use std::fmt::Write as _;
+
+        let dir = self.config.cache.exports_directory();
+        std::fs::create_dir_all(&dir)?;
+        let path = dir.join(format!(
+            "{chat_id}-{}.txt",
+            chrono::Local::now().format("%Y%m%d-%H%M%S")
+        ));
+
+        let mut out = String::new();
+        for message in &self.conversation_model.messages {
+            let date = message.date.with_timezone(&chrono::Local);
+            let _ = writeln!(
+                out,
+                "[{}] {}: {}",
+                date.format("%Y-%m-%d %H:%M"),
+                self.sender_display_name(message.sender_id),
+                message.content.text
+            );
+        }
+        std::fs::write(&path, out)?;
+        Ok(path)
+    }
+
+    /// Writes the UI state, the latest updates and a plain-text copy of the
+    /// screen to a file next to the log, for attaching to bug reports.
+    fn write_state_dump(&mut self) -> std::io::Result<std::path::PathBuf> {
+        use std::fmt::Write as _;
+
+        let size = self
+            .pane_areas
+            .first()
+            .map_or(Rect::new(0, 0, 120, 30), |&(_, area)| area);
+        let mut terminal =
+            Terminal::new(ratatui::backend::TestBackend::new(size.width, size.height))?;
+        let frame = terminal.draw(|frame| self.render(frame))?;
+        let screen: Vec<String> = frame
+            .buffer
+            .content()
+            .chunks(usize::from(frame.buffer.area.width).max(1))
+            .map(|row| {
+                row.iter()
+                    .map(ratatui::buffer::Cell::symbol)
+                    .collect::<String>()
+                    .trim_end()
+                    .to_string()
+            })
+            .collect();
+
+        let now = chrono::Local::now();
+        let metrics = self.cache.metrics();
+        let mut out = String::new();
+        let _ = writeln!(
+            out,
+            "Ithil {} state dump, {}\n",
+            env!("CARGO_PKG_VERSION"),
+            now.format("%Y-%m-%d %H:%M:%S")
+        );
+        let _ = writeln!(
+            out,
+            "State: {:?}, focused pane: {}",
+            self.state, self.focused_pane
+        );
+        let _ = writeln!(
+            out,
+            "Open chat: {}",
+            self.selected_chat_id
+                .map_or_else(|| "none".to_string(), |id| id.to_string())
+        );
+        let _ = writeln!(
+            out,
+            "Chat list: {} chats, selected index {}",
+            self.chat_list_model.chats().len(),
+            self.chat_list_model.selected_index()
+        );
+        let _ = writeln!(
+            out,
+            "Conversation: {} messages, selected index {}, scroll offset {}",
+            self.conversation_model.messages.len(),
+            self.conversation_model.selected_index,
+            self.conversation_model.scroll_offset
+        );
+        let _ = writeln!(
+            out,
+            "Cache: {} chats, {} histories, {} messages, ~{} bytes",
+            metrics.chats, metrics.histories, metrics.messages, metrics.message_bytes
+        );
+
+        let _ = writeln!(out, "\nPanes:");
+        for (name, area) in &self.pane_areas {
+            let _ = writeln!(
+                out,
+                "  {name}: {}x{} at {},{}",
+                area.width, area.height, area.x, area.y
+            );
+        }
+
+        let _ = writeln!(out, "\nLast {} updates:", self.recent_updates.len());
+        for update in &self.recent_updates {
+            let _ = writeln!(out, "  {update}");
+        }
+
+        let _ = writeln!(out, "\nScreen:");
+        for row in screen {
+            let _ = writeln!(out, "{row}");
+        }
+
+        let path = self
+            .config
+            .logging
+            .file
+            .with_file_name(format!("ithil-dump-{}.txt", now.format("%Y%m%d-%H%M%S")));
+        if let Some(dir) = path.parent() {
+            std::fs::create_dir_all(dir)?;
+        }
+        std::fs::write(&path, out)?;
+        Ok(path)
+    }
+
+    /// Returns a chat's display name, preferring the local alias.
+    fn chat_display_name(&self, chat_id: i64) -> String {
+        if let Some(alias) = self.aliases.get(chat_id) {
+            return alias.to_string();
+        }
+        self.cache
+            .get_chat(chat_id)
+            .or_else(|| self.previewed(chat_id))
+            .map_or_else(|| format!("Chat {chat_id}"), |c| c.title)
+    }
+
+    /// Returns a user's display name, preferring the local alias.
+    ///
+    /// Channels and groups can be senders too (channel posts, anonymous
+    /// admins, the origins of forwards), so a known chat's title is used
+    /// when there's no such user.
+    fn sender_display_name(&self, user_id: i64) -> String {
+        if let Some(alias) = self.aliases.get(user_id) {
+            return alias.to_string();
+        }
+        if let Some(user) = self.cache.get_user(user_id) {
+            return user.get_display_name();
+        }
+        self.cache
+            .get_chat(user_id)
+            .or_else(|| self.previewed(user_id))
+            .map_or_else(|| format!("User {user_id}"), |c| c.title)
+    }
+
+    /// Returns the chat being previewed, if it is `chat_id`.
+    fn previewed(&self, chat_id: i64) -> Option<Chat> {
+        self.preview.clone().filter(|c| c.id == chat_id)
+    }
+
+    /// Converts a conversation action to an app action.
+    fn handle_conversation_action(&self, action: ConversationAction) -> Option<AppAction> {
+        let chat_id = self.selected_chat_id?;
+
+        match action {
+            ConversationAction::SendMessage(text, reply_to) => {
+                Some(AppAction::SendMessage(chat_id, text, reply_to))
+            },
+            ConversationAction::EditMessage(message_id, text) => {
+                Some(AppAction::EditMessage(chat_id, message_id, text))
+            },
+            ConversationAction::DeleteMessage(message_id) => {
+                Some(AppAction::DeleteMessage(chat_id, message_id))
+            },
+            // Forwarding needs a destination, so the conversation pane
+            // starts it directly rather than through the model
+            ConversationAction::ForwardMessage(_) => None,
+            ConversationAction::SendMessageWithAttachment(text, path, as_photo, reply_to) => Some(
+                AppAction::SendMessageWithAttachment(chat_id, text, path, as_photo, reply_to),
+            ),
+            ConversationAction::SendAttachments(text, paths, as_photo, reply_to) => Some(
+                AppAction::SendAttachments(chat_id, text, paths, as_photo, reply_to),
+            ),
+        }
+    }
+
+    /// Opens the inbox with the unread messages of every chat.
+    ///
+    /// It opens with what is cached; each unread chat's newest unread
+    /// messages, up to [`INBOX_PER_CHAT_LIMIT`], are then fetched in the
+    /// background and replace the cached ones as they arrive, and
+    /// [`Self::finish_inbox_fetch`] fills them in. A chat that can't be
+    /// fetched keeps what is cached.
+    fn handle_open_inbox(&mut self) {
+        let unread: Vec<Chat> = self
+            .cache
+            .get_all_chats()
+            .into_iter()
+            .filter(|c| c.unread_count > 0)
+            .collect();
+
+        let mut entries = Vec::new();
+        let mut to_fetch = Vec::new();
+        for chat in unread {
+            let limit = usize::try_from(chat.unread_count)
+                .unwrap_or(0)
+                .min(INBOX_PER_CHAT_LIMIT);
+            let mut cached = self.cache.get_messages(chat.id);
+            cached.sort_by_key(|m| std::cmp::Reverse(m.id));
+            cached.truncate(limit);
+            entries.extend(self.inbox_entries(&chat, cached));
+            to_fetch.push((chat.id, limit));
+        }
+
+        let mut inbox = Inbox::new(entries);
+        inbox.set_loading(!to_fetch.is_empty());
+        self.overlays.inbox = Some(inbox);
+        self.inbox_fetch =
+            (!to_fetch.is_empty()).then(|| InboxFetch::start(Arc::clone(&self.telegram), to_fetch));
+    }
+
+    /// Fills the inbox in with the chats fetched since the last tick, and
+    /// stops fetching once it's closed.
+    fn finish_inbox_fetch(&mut self) {
+        if self.overlays.inbox.is_none() {
+            self.inbox_fetch = None;
+            return;
+        }
+        let Some(fetch) = self.inbox_fetch.as_mut() else {
+            return;
+        };
+        let fetched = fetch.fetched();
+        let done = fetch.is_done();
+        for (chat_id, result) in fetched {
+            let messages = match result {
+                Ok(messages) => messages,
+                Err(e) => {
+                    tracing::warn!("Failed to load unread messages of chat {}: {}", chat_id, e);
+                    continue;
+                },
+            };
+            // Read in the meantime, it has nothing left to show
+            let Some(chat) = self.cache.get_chat(chat_id).filter(|c| c.unread_count > 0) else {
+                continue;
+            };
+            let entries = self.inbox_entries(&chat, messages);
+            if let Some(inbox) = self.overlays.inbox.as_mut() {
+                inbox.set_chat(chat_id, entries);
+            }
+        }
+        if done {
+            self.inbox_fetch = None;
+            if let Some(inbox) = self.overlays.inbox.as_mut() {
+                inbox.set_loading(false);
+            }
+        }
+    }
+
+    /// Returns the inbox entries for a chat's unread messages among
+    /// `messages`.
+    fn inbox_entries(&self, chat: &Chat, messages: Vec<Message>) -> Vec<InboxEntry> {
+        let hide_previews = self.config.privacy.hides_previews();
+        let preview_length = self.config.ui.appearance.message_preview_length;
+        let chat_name = self.chat_display_name(chat.id);
+        messages
+            .into_iter()
+            .filter(|m| !m.is_outgoing && m.id > chat.last_read_inbox_id)
+            .map(|message| {
+                let sender = if chat.chat_type == ChatType::Private {
+                    String::new()
+                } else {
+                    self.sender_display_name(message.sender_id)
+                };
+                let preview = if hide_previews {
+                    "\u{2022}\u{2022}\u{2022}".to_string()
+                } else {
+                    crate::utils::truncate_string(&message.content.preview(), preview_length)
+                };
+                InboxEntry {
+                    chat_id: chat.id,
+                    message_id: message.id,
+                    chat: chat_name.clone(),
+                    sender,
+                    date: message.date,
+                    preview,
+                }
+            })
+            .collect()
+    }
+
+    /// Shows a link as a QR code: the selected message's, the user's own
+    /// t.me link, the current chat's, or text typed after `/qr`.
+    async fn handle_qr(&mut self, target: slash_command::QrTarget) {
+        use slash_command::QrTarget;
+
+        let (title, link) = match target {
+            QrTarget::Text(text) => ("Link".to_string(), text),
+            QrTarget::Selected => {
+                let link = self.conversation_model.selected_message().and_then(|m| {
+                    crate::utils::first_url(&m.content.text)
+                        .or_else(|| crate::utils::first_url(&m.content.caption))
+                });
+                let Some(link) = link else {
+                    self.set_status_message(
+                        "Selected message has no link (try /qr chat or /qr me)",
+                    );
+                    return;
+                };
+                ("Link".to_string(), link)
+            },
+            QrTarget::Me => match self.telegram.get_me().await {
+                Ok(me) if me.username.is_empty() => {
+                    self.set_status_message("Set a username in Telegram to get a t.me link");
+                    return;
+                },
+                Ok(me) => (
+                    me.get_display_name(),
+                    format!("https://t.me/{}", me.username),
+                ),
+                Err(e) => {
+                    self.set_error_message(format!("Failed to get your profile: {e}"));
+                    return;
+                },
+            },
+            QrTarget::Chat => {
+                let Some(chat_id) = self.require_open_chat() else {
+                    return;
+                };
+                match self.telegram.get_invite_link(chat_id).await {
+                    Ok(Some(link)) => (self.chat_display_name(chat_id), link),
+                    Ok(None) => {
+                        self.set_status_message("This chat has no link you can share");
+                        return;
+                    },
+                    Err(e) => {
+                        self.set_error_message(format!("Failed to get the chat's link: {e}"));
+                        return;
+                    },
+                }
+            },
+        };
+
+        match QrView::new(title, link) {
+            Some(view) => {
+                self.overlays.show_help = false;
+                self.overlays.qr_view = Some(view);
+            },
+            None => self.set_status_message("Too long for a QR code"),
+        }
+    }
+
+    /// Opens the discussion group linked to a channel (or the channel linked
+    /// to a discussion group).
+    async fn handle_open_discussion(&mut self, chat_id: i64) {
+        let cached = self
+            .cache
+            .get_chat(chat_id)
+            .map(|c| c.linked_chat_id)
+            .filter(|&id| id != 0);
+        let linked = match cached {
+            Some(id) => id,
+            None => match self.telegram.get_linked_chat(chat_id).await {
+                Ok(Some(id)) => id,
+                Ok(None) => {
+                    self.set_status_message("This chat has no discussion group");
+                    return;
+                },
+                Err(e) => {
+                    self.set_error_message(format!("Failed to find the discussion: {e}"));
+                    return;
+                },
+            },
+        };
+        if self.cache.get_chat(linked).is_none() {
+            self.set_status_message("Join the discussion group to open it here");
+            return;
+        }
+        self.jump_to_chat(linked);
+        self.handle_chat_selected(linked).await;
+    }
+
+    /// Searches every chat (or the `in:` chat) and shows the results.
+    ///
+    /// Stored history is searched first, then Telegram, and the two are
+    /// merged; operators Telegram can't apply are checked locally. A failed
+    /// server search still shows what was found locally.
+    async fn handle_find(&mut self, input: &str) {
+        let query = match SearchQuery::parse(input, chrono::Local::now().date_naive()) {
+            Ok(query) => query,
+            Err(e) => {
+                self.set_status_message(e);
+                return;
+            },
+        };
+        let in_chat = match &query.in_chat {
+            Some(name) => match self.chat_list_model.find_chat(name) {
+                Some(chat_id) => Some(chat_id),
+                None => {
+                    self.set_status_message(format!("No chat matches \"{name}\""));
+                    return;
+                },
+            },
+            None => None,
+        };
+        self.run_find(input, &query, in_chat).await;
+    }
+
+    /// Opens the chat with someone found by username or phone number,
+    /// adding it to the chat list if it isn't there yet. A public channel
+    /// or group the user isn't in opens as a preview instead.
+    async fn handle_resolve_chat(&mut self, handle: &PeerHandle) {
+        let known = self.known_chat(handle).is_some();
+        match self.telegram.resolve_chat(handle).await {
+            Ok(chat) if !known && Self::previewable(&chat) => {
+                self.open_preview(chat, None).await;
+            },
+            Ok(chat) => {
+                let chat_id = chat.id;
+                // A chat already in the list keeps its last message and counts
+                let chat = self.cache.get_chat(chat_id).unwrap_or(chat);
+                self.chat_list_model.update_chat(chat);
+                self.jump_to_chat(chat_id);
+                self.handle_chat_selected(chat_id).await;
+            },
+            Err(e) => self.set_error_message(format!("Couldn't open {handle}: {e}")),
+        }
+    }
+
+    /// Finds a forward destination picked from the switcher's search
+    /// results, then asks how to forward there.
+    async fn handle_resolve_forward_target(&mut self, handle: &PeerHandle) {
+        match self.telegram.resolve_chat(handle).await {
+            Ok(chat) => {
+                let chat_id = chat.id;
+                let chat = self.cache.get_chat(chat_id).unwrap_or(chat);
+                self.chat_list_model.update_chat(chat);
+                self.open_forward_dialog(vec![chat_id]);
+            },
+            Err(e) => {
+                self.overlays.pending_forward = None;
+                self.set_error_message(format!("Couldn't forward to {handle}: {e}"));
+            },
+        }
+    }
+
+    /// Searches Telegram for the quick switcher's query once typing has
+    /// paused, adding users and chats the chat list doesn't have.
+    async fn search_for_switcher(&mut self, now: Instant) {
+        let Some(query) = self
+            .overlays
+            .quick_switcher
+            .as_mut()
+            .and_then(|switcher| switcher.pending_search(now))
+        else {
+            return;
+        };
+        match self
+            .telegram
+            .search_global_chats(&query, SWITCHER_SEARCH_LIMIT)
+            .await
+        {
+            Ok(chats) => {
+                if let Some(switcher) = self.overlays.quick_switcher.as_mut() {
+                    switcher.set_remote(&query, chats);
+                }
+            },
+            // The local matches still stand
+            Err(e) => tracing::debug!("Chat search for '{}' failed: {}", query, e),
+        }
+    }
+
+    /// Shows the messages of one kind in a chat, such as its shared media
+    /// or pinned messages, as search results.
+    async fn handle_find_in_chat(&mut self, chat_id: i64, filter: SearchFilter) {
+        let query = SearchQuery {
+            has: Some(filter),
+            ..SearchQuery::default()
+        };
+        let label = match filter {
+            SearchFilter::Media => "Shared media",
+            SearchFilter::Pinned => "Pinned messages",
+            _ => "Messages",
+        };
+        let title = format!("{label} in {}", self.chat_display_name(chat_id));
+        self.run_find(&title, &query, Some(chat_id)).await;
+    }
+
+    /// Searches stored history and Telegram for `query`, in one chat or all
+    /// of them, and opens the results under the title `input`.
+    async fn run_find(&mut self, input: &str, query: &SearchQuery, in_chat: Option<i64>) {
+        let mut found: Vec<(i64, Message)> = Vec::new();
+        let chat_ids: Vec<i64> = in_chat.map_or_else(
+            || self.cache.get_all_chats().iter().map(|c| c.id).collect(),
+            |id| vec![id],
+        );
+        for chat_id in chat_ids {
+            found.extend(
+                self.cache
+                    .get_messages(chat_id)
+                    .into_iter()
+                    .map(|m| (chat_id, m)),
+            );
+        }
+
+        // Telegram needs something to look for; a bare from: or date
+        // range is answered from stored history alone
+        let mut note = None;
+        if !query.text.is_empty() || query.has.is_some() {
+            match self
+                .telegram
+                .search_messages(in_chat, &query.text, query.has, FIND_SERVER_LIMIT)
+                .await
+            {
+                Ok(messages) => found.extend(messages.into_iter().map(|m| (m.chat_id, m))),
+                Err(e) => note = Some(format!("Only stored messages searched: {e}")),
+            }
+        }
+
+        found.sort_by_key(|(chat_id, m)| (std::cmp::Reverse(m.date), *chat_id, m.id));
+        found.dedup_by_key(|(chat_id, m)| (*chat_id, m.id));
+
+        let hide_previews = self.config.privacy.hides_previews();
+        let preview_length = self.config.ui.appearance.message_preview_length;
+        let (mut messages, mut media) = (Vec::new(), Vec::new());
+        for (chat_id, message) in found {
+            let sender = if message.is_outgoing {
+                "You".to_string()
+            } else {
+                self.sender_display_name(message.sender_id)
+            };
+            if !query.matches(&message, &sender) {
+                continue;
+            }
+            let private = self
+                .cache
+                .get_chat(chat_id)
+                .is_some_and(|c| c.chat_type == ChatType::Private);
+            let preview = if hide_previews {
+                "\u{2022}\u{2022}\u{2022}".to_string()
+            } else {
+                crate::utils::truncate_string(&message.content.preview(), preview_length)
+            };
+            let hit = SearchHit {
+                chat_id,
+                message_id: message.id,
+                chat: self.chat_display_name(chat_id),
+                sender: if private && !message.is_outgoing {
+                    String::new()
+                } else {
+                    sender
+                },
+                date: message.date,
+                preview,
+            };
+            if message.content.content_type.is_downloadable() {
+                media.push(hit);
+            } else {
+                messages.push(hit);
+            }
+        }
+
+        let chats = if query.is_plain() {
+            let text = query.text.to_lowercase();
+            self.cache
+                .get_all_chats()
+                .iter()
+                .filter(|c| {
+                    c.title.to_lowercase().contains(&text)
+                        || c.username.to_lowercase().contains(&text)
+                        || self
+                            .aliases
+                            .get(c.id)
+                            .is_some_and(|a| a.to_lowercase().contains(&text))
+                })
+                .map(|c| (c.id, self.chat_display_name(c.id)))
+                .collect()
+        } else {
+            Vec::new()
+        };
+
+        let results = SearchResults::new(input, chats, messages, media);
+        self.overlays.search_results = Some(match note {
+            Some(note) => results.with_note(note),
+            None => results,
+        });
+    }
+
+    /// Asks before marking every chat with unread messages as read.
+    fn confirm_mark_all_as_read(&mut self) {
+        if !self.config.privacy.sends_read_receipts() {
+            self.set_status_message("Read receipts are off; nothing was marked read");
+            return;
+        }
+
+        let unread: Vec<Chat> = self
+            .cache
+            .get_all_chats()
+            .into_iter()
+            .filter(|c| c.unread_count > 0)
+            .collect();
+        if unread.is_empty() {
+            self.set_status_message("No unread chats");
+            return;
+        }
+
+        let messages: i64 = unread.iter().map(|c| i64::from(c.unread_count)).sum();
+        let chats = if unread.len() == 1 {
+            "1 chat".to_string()
+        } else {
+            format!("{} chats", unread.len())
+        };
+        let modal = Modal::confirm(
+            "Mark All As Read",
+            format!("Mark {chats} ({messages} unread) as read?"),
+        )
+        .with_size(50, 6);
+        let chat_ids = unread.iter().map(|c| c.id).collect();
+        self.overlays.confirmation = Some((modal, AppAction::MarkAsRead(chat_ids)));
+    }
+
+    /// Returns how much space a kind of local data takes, in bytes.
+    fn local_data_size(&self, kind: LocalData) -> u64 {
+        match kind {
+            LocalData::Session => storage::session_files(&self.config.telegram.session_file)
+                .iter()
+                .map(|path| storage::disk_usage(path))
+                .sum(),
+            LocalData::Messages => {
+                u64::try_from(self.cache.metrics().message_bytes).unwrap_or(u64::MAX)
+            },
+            LocalData::Media => storage::disk_usage(&self.config.cache.media_directory),
+            LocalData::Exports => storage::disk_usage(&self.config.cache.exports_directory()),
+            LocalData::Saved => state::files(&self.config)
+                .iter()
+                .map(|path| storage::disk_usage(path))
+                .sum(),
+        }
+    }
+
+    /// Asks before clearing local data picked in the storage view.
+    fn confirm_clear_local_data(&mut self, kinds: Vec<LocalData>, bytes: u64) {
+        let names: Vec<String> = kinds.iter().map(|k| k.to_string().to_lowercase()).collect();
+        let mut text = format!(
+            "Clear {} ({})?",
+            names.join(", "),
+            crate::utils::format_file_size(i64::try_from(bytes).unwrap_or(i64::MAX))
+        );
+        if kinds.contains(&LocalData::Session) {
+            text.push_str(" You'll have to log in again next time.");
+        }
+        if kinds.contains(&LocalData::Saved) {
+            text.push_str(" Bookmarks, local pins and aliases can't be brought back.");
+        }
+        let modal = Modal::confirm("Clear Local Data", text).with_size(60, 8);
+        self.overlays.confirmation = Some((modal, AppAction::ClearLocalData(kinds)));
+    }
+
+    /// Asks before leaving channels picked in the channel view.
+    fn confirm_leave_channels(&mut self, chat_ids: Vec<i64>) {
+        let text = match chat_ids.as_slice() {
+            [chat_id] => format!("Leave {}?", self.chat_display_name(*chat_id)),
+            ids => format!("Leave {} channels?", ids.len()),
+        };
+        let modal = Modal::confirm("Leave Channels", text).with_size(50, 6);
+        self.overlays.confirmation = Some((
+            modal,
+            AppAction::ChangeChannels(ChannelManagerAction::Leave(chat_ids)),
+        ));
+    }
+
+    /// Mutes, archives or leaves each channel picked in the channel view,
+    /// then reports how many it worked for and the first failure.
+    async fn change_channels(&mut self, change: ChannelManagerAction) {
+        let (chat_ids, done) = match &change {
+            ChannelManagerAction::Mute(ids, true) => (ids.clone(), "Muted"),
+            ChannelManagerAction::Mute(ids, false) => (ids.clone(), "Unmuted"),
+            ChannelManagerAction::Archive(ids) => (ids.clone(), "Archived"),
+            ChannelManagerAction::Leave(ids) => (ids.clone(), "Left"),
+            ChannelManagerAction::None | ChannelManagerAction::Close => return,
+        };
+
+        let mut changed = 0;
+        let mut failure = None;
+        for &chat_id in &chat_ids {
+            let result = match change {
+                ChannelManagerAction::Mute(_, mute) => self.telegram.mute_chat(chat_id, mute).await,
+                ChannelManagerAction::Archive(_) => self.telegram.archive_chat(chat_id, true).await,
+                _ => self.telegram.leave_chat(chat_id).await,
+            };
+            match result {
+                Ok(()) => changed += 1,
+                Err(e) => {
+                    if failure.is_none() {
+                        failure = Some(format!("{}: {e}", self.chat_display_name(chat_id)));
+                    }
+                },
+            }
+        }
+
+        // A channel that was left or archived can't stay open
+        if let Some(open) = self.selected_chat_id {
+            if chat_ids.contains(&open) && self.cache.get_chat(open).is_none() {
+                self.selected_chat_id = None;
+                self.conversation_model.clear_chat();
+            }
+        }
+        self.refresh_chat_list();
+        if let Some(view) = self.overlays.channel_manager.as_mut() {
+            view.set_chats(self.cache.get_all_chats());
+        }
+
+        let channels = if changed == 1 { "channel" } else { "channels" };
+        match failure {
+            None => self.set_success_message(format!("{done} {changed} {channels}")),
+            Some(e) => self.set_error_message(format!(
+                "{done} {changed} of {} {channels}; {e}",
+                chat_ids.len()
+            )),
+        }
+    }
+
+    /// Deletes the chosen kinds of local data and reports the space freed.
+    fn clear_local_data(&mut self, kinds: &[LocalData]) {
+        let mut freed = 0;
+        for &kind in kinds {
+            let result = match kind {
+                LocalData::Session => {
+                    storage::remove_all(&storage::session_files(&self.config.telegram.session_file))
+                },
+                LocalData::Messages => {
+                    Ok(u64::try_from(self.cache.clear_messages()).unwrap_or(u64::MAX))
+                },
+                LocalData::Media => {
+                    storage::remove_all(&[self.config.cache.media_directory.clone()])
+                },
+                LocalData::Exports => storage::remove_all(&[self.config.cache.exports_directory()]),
+                LocalData::Saved => {
+                    self.aliases = state::Aliases::default();
+                    self.chat_list_model.set_aliases(HashMap::new());
+                    storage::remove_all(&state::files(&self.config))
+                },
+            };
+            match result {
+                Ok(bytes) => freed += bytes,
+                Err(e) => {
+                    self.set_error_message(format!(
+                        "Failed to clear {}: {e}",
+                        kind.to_string().to_lowercase()
+                    ));
+                    return;
+                },
+            }
+        }
+        self.set_success_message(format!(
+            "Freed {}",
+            crate::utils::format_file_size(i64::try_from(freed).unwrap_or(i64::MAX))
+        ));
+    }
+
+    /// Asks before logging out, naming the account.
+    fn confirm_log_out(&mut self) {
+        let account = match (
+            self.status_bar.current_user.as_ref(),
+            self.status_bar.account_handle(),
+        ) {
+            (Some(user), Some(handle)) => format!("{} ({handle})", user.get_display_name()),
+            (Some(user), None) => user.get_display_name(),
+            (None, _) => "Telegram".to_string(),
+        };
+        let modal = Modal::confirm(
+            "Log Out",
+            format!("Log out of {account}? This device will need a new login code."),
+        )
+        .with_size(56, 7);
+        self.overlays.confirmation = Some((modal, AppAction::LogOut));
+    }
+
+    /// Logs out of Telegram and forgets the account's chats, back to the
+    /// sign-in screen.
+    async fn handle_log_out(&mut self) {
+        if let Err(e) = self.telegram.log_out().await {
+            self.set_error_message(format!("Failed to log out: {e}"));
+            return;
+        }
+        // Stop what was running for the account, sends still queued
+        // included, so none of it goes out or shows up for the next one
+        self.sends = SendQueue::new(Arc::clone(&self.telegram));
+        self.mark_read = None;
+        self.inbox_fetch = None;
+        self.bulk_download = None;
+        self.listening = None;
+        self.overlays = Overlays::default();
+        self.split = None;
+        self.selected_chat_id = None;
+        self.preview = None;
+        self.send_as.clear();
+        self.reactions = ReactionsFeed::new();
+        self.last_typing_sent = None;
+        self.unread_in_view_since = None;
+        self.conversation_model.clear_chat();
+        self.cache.clear();
+        self.vault = None;
+        vault::clear_scratch_dir();
+        if let Err(e) = storage::remove_all(&state::files(&self.config)) {
+            self.set_error_message(format!("Failed to remove saved bookmarks and pins: {e}"));
+        }
+        self.aliases = state::Aliases::default();
+        self.chat_list_model.set_aliases(HashMap::new());
+        self.config.forget_account();
+        self.settings_model.reset(self.config.clone());
+        if let Err(e) = self.config.save(&self.config_file) {
+            self.set_error_message(format!("Failed to forget the account's settings: {e}"));
+        }
+        self.refresh_chat_list();
+        self.status_bar.set_user(None);
+        self.focused_pane = FocusedPane::ChatList;
+        self.update_auth_state(AuthState::WaitPhoneNumber);
+    }
+
+    /// Marks chats as read on request, unless read receipts are off. The
+    /// run goes on in the background; [`Self::finish_mark_read`] reports on
+    /// it.
+    fn handle_mark_as_read(&mut self, chat_ids: Vec<i64>) {
+        if !self.config.privacy.sends_read_receipts() {
+            self.set_status_message("Read receipts are off; nothing was marked read");
+            return;
+        }
+        if self.mark_read.is_some() {
+            self.set_status_message("Still marking chats read; try again when it's done");
+            return;
+        }
+
+        if chat_ids.len() > 1 {
+            self.set_status_message(format!("Marking {} chats as read\u{2026}", chat_ids.len()));
+        }
+        self.mark_read = Some(MarkRead::start(Arc::clone(&self.telegram), chat_ids));
+    }
+
+    /// Shows how far marking chats read has got since the last tick, and
+    /// how it went once it's done.
+    fn finish_mark_read(&mut self) {
+        let Some(run) = self.mark_read.as_mut() else {
+            return;
+        };
+        let total = run.chat_ids().len();
+        let mut result = None;
+        for event in run.events() {
+            match event {
+                MarkReadEvent::Marked(marked) if total > 1 => {
+                    self.set_status_message(format!(
+                        "Marking chats as read\u{2026} {marked}/{total}"
+                    ));
+                },
+                MarkReadEvent::Marked(_) => {},
+                MarkReadEvent::Done(done) => result = Some(done),
+            }
+        }
+        let Some(result) = result else {
+            return;
+        };
+        let chat_ids = self
+            .mark_read
+            .take()
+            .map(|run| run.chat_ids().to_vec())
+            .unwrap_or_default();
+
+        self.refresh_chat_list();
+        if let Some(inbox) = self.overlays.inbox.as_mut() {
+            for &chat_id in &chat_ids {
+                if self
+                    .cache
+                    .get_chat(chat_id)
+                    .is_some_and(|c| c.unread_count == 0)
+                {
+                    inbox.remove_chat(chat_id);
+                }
+            }
+        }
+        match (result, total) {
+            (Ok(1), 1) => self.set_success_message("Marked as read"),
+            (Ok(marked), total) if marked == total => {
+                self.set_success_message(format!("Marked {marked} chats as read"));
+            },
+            (Ok(marked), total) => {
+                self.set_error_message(format!("Marked {marked} of {total} chats as read"));
+            },
+            (Err(e), _) => self.set_error_message(format!("Failed to mark chats as read: {e}")),
+        }
+    }
+
+    /// Votes in (or retracts from) a poll, then shows the new results in
+    /// the conversation and the poll overlay.
+    async fn handle_vote_poll(&mut self, chat_id: i64, message_id: i64, options: &[Vec<u8>]) {
+        if let Err(e) = self.telegram.vote_poll(chat_id, message_id, options).await {
+            self.set_error_message(format!("Failed to vote: {e}"));
+            return;
+        }
+
+        let updated = self
+            .cache
+            .get_messages(chat_id)
+            .into_iter()
+            .find(|m| m.id == message_id);
+        if let Some(message) = updated {
+            if let (Some(view), Some(poll)) =
+                (self.overlays.poll_view.as_mut(), &message.content.poll)
+            {
+                view.set_poll(poll.clone());
+            }
+            if self.selected_chat_id == Some(chat_id) {
+                self.conversation_model.update_message(message);
+            }
+        }
+        self.set_success_message(if options.is_empty() {
+            "Vote retracted"
+        } else {
+            "Vote sent"
+        });
+    }
+
+    /// Opens a chat, or a message in it, in the official Telegram app, for
+    /// what Ithil can't do, like calls and payments.
+    async fn handle_open_in_telegram(&mut self, chat_id: i64, message_id: Option<i64>) {
+        let Some(link) = self
+            .cache
+            .get_chat(chat_id)
+            .and_then(|chat| chat.telegram_link(message_id))
+        else {
+            self.set_status_message("Telegram has no link to this chat");
+            return;
+        };
+        match TelegramClient::open_url(&link).await {
+            Ok(()) => self.set_status_message(format!("Opened {link}")),
+            Err(e) => self.set_error_message(format!("Failed to open link: {e}")),
+        }
+    }
+
+    /// Reacts to a message and counts the reaction for the picker's quick
+    /// row.
+    async fn handle_react(&mut self, chat_id: i64, message_id: i64, reaction: Option<String>) {
+        match self
+            .telegram
+            .send_reaction(chat_id, message_id, reaction.as_deref())
+            .await
+        {
+            Ok(message) => {
+                if let Some(reaction) = &reaction {
+                    let path = state::reactions_file(&self.config);
+                    let mut reactions = state::load_reactions(&path);
+                    reactions.remember(reaction);
+                    if let Err(e) = state::save_reactions(&path, &reactions) {
+                        self.set_error_message(format!("Failed to save used reactions: {e}"));
+                    }
+                }
+                if let Some(model) = self.split_conversation_for(chat_id) {
+                    model.update_message(message.clone());
+                }
+                if self.selected_chat_id == Some(chat_id) {
+                    self.conversation_model.update_message(message);
+                }
+            },
+            Err(e) => self.set_error_message(format!("Couldn't react: {e}")),
+        }
+    }
+
+    /// Sends a confirmed report and says whether Telegram took it.
+    async fn handle_report(&mut self, target: ReportTarget, reason: ReportReason) {
+        let result = match target {
+            ReportTarget::Chat(chat_id) => self.telegram.report_chat(chat_id, reason).await,
+            ReportTarget::Message(chat_id, message_id) => {
+                self.telegram
+                    .report_messages(chat_id, &[message_id], reason)
+                    .await
+            },
+        };
+        match result {
+            Ok(()) => self.set_success_message(format!("Reported ({reason})")),
+            Err(e) => self.set_error_message(format!("Failed to report: {e}")),
+        }
+    }
+
+    /// Forwards a message to each destination, sending the optional comment
+    /// first, and remembers the destinations it reached for next time.
+    async fn handle_forward_message(
+        &mut self,
+        from_chat_id: i64,
+        message_id: i64,
+        to_chat_ids: &[i64],
+        options: &ForwardOptions,
+    ) {
+        let mut sent = Vec::new();
+        let mut sent_ids = Vec::new();
+        let mut failed = Vec::new();
+        for &to_chat_id in to_chat_ids {
+            let name = self.chat_display_name(to_chat_id);
+            match self
+                .forward_to(from_chat_id, message_id, to_chat_id, options)
+                .await
+            {
+                Ok(()) => {
+                    sent.push(name);
+                    sent_ids.push(to_chat_id);
+                },
+                Err(e) => {
+                    tracing::warn!("Failed to forward to {}: {}", to_chat_id, e);
+                    failed.push(name);
+                },
+            }
+        }
+
+        self.refresh_chat_list();
+        if !sent_ids.is_empty() {
+            let path = state::forward_targets_file(&self.config);
+            // The toast below is about the forward itself
+            if let Err(e) = state::remember_forward_targets(&path, &sent_ids) {
+                tracing::warn!("Failed to save recent forward destinations: {}", e);
+            }
+        }
+
+        let suffix = if options.drop_author {
+            " without sender"
+        } else {
+            ""
+        };
+        if failed.is_empty() {
+            self.set_success_message(format!("Forwarded to {}{suffix}", sent.join(", ")));
+        } else if sent.is_empty() {
+            self.set_error_message(format!("Failed to forward to {}", failed.join(", ")));
+        } else {
+            self.set_error_message(format!(
+                "Forwarded to {} of {} chats{suffix}; failed: {}",
+                sent.len(),
+                to_chat_ids.len(),
+                failed.join(", ")
+            ));
+        }
+    }
+
+    /// Forwards a message to one chat, sending the optional comment first.
+    async fn forward_to(
+        &mut self,
+        from_chat_id: i64,
+        message_id: i64,
+        to_chat_id: i64,
+        options: &ForwardOptions,
+    ) -> Result<()> {
+        let comment = options.comment.trim();
+        if !comment.is_empty() {
+            let message = self
+                .telegram
+                .send_message(
+                    to_chat_id,
+                    comment,
+                    None,
+                    self.send_as.get(&to_chat_id).map(|p| p.id),
+                )
+                .await?;
+            if self.selected_chat_id == Some(to_chat_id) {
+                self.conversation_model.add_message(message);
+            }
+        }
+
+        if options.drop_author {
+            // Copies arrive via the update stream, like any outgoing message
+            self.telegram
+                .forward_messages_without_author(from_chat_id, to_chat_id, &[message_id])
+                .await?;
+        } else {
+            let messages = self
+                .telegram
+                .forward_messages(from_chat_id, to_chat_id, &[message_id])
+                .await?;
+            if self.selected_chat_id == Some(to_chat_id) {
+                for message in messages {
+                    self.conversation_model.add_message(message);
+                }
+            }
+        }
+        Ok(())
+    }
+
+    /// Opens a chat with a message selected, loading older history if the
+    /// message isn't among the latest messages.
+    async fn handle_jump_to_message(&mut self, chat_id: i64, message_id: i64) {
+        if self.selected_chat_id != Some(chat_id) {
+            self.jump_to_chat(chat_id);
+            self.handle_chat_selected(chat_id).await;
+        }
+        self.focused_pane = FocusedPane::Conversation;
+        self.chat_list_model.set_focused(false);
+        if self.conversation_model.jump_to_message(message_id) {
+            return;
+        }
+
+        match self
+            .telegram
+            .get_messages(chat_id, JUMP_HISTORY_LIMIT, Some(message_id + 1))
+            .await
+        {
+            // The user may have switched chats while the history was loading
+            Ok(_) if self.selected_chat_id != Some(chat_id) => {},
+            Ok(messages) if messages.iter().any(|m| m.id == message_id) => {
+                self.conversation_model.set_messages(messages);
+                self.conversation_model.jump_to_message(message_id);
+                self.set_status_message(
+                    "Showing older messages (reopen the chat for the latest)".to_string(),
+                );
+            },
+            Ok(_) => self.set_status_message("Message no longer exists".to_string()),
+            Err(e) => self.set_error_message(format!("Failed to load messages: {e}")),
+        }
+    }
+
+    /// Loads older history page by page, above what's loaded, until the
+    /// replied message turns up, then selects it. Keeping the newer messages
+    /// loaded lets `JumpBack` return to the reply.
+    async fn handle_jump_to_reply(&mut self, chat_id: i64, message_id: i64) {
+        for _ in 0..MAX_REPLY_PAGES {
+            let Some(oldest) = self.conversation_model.oldest_message_id() else {
+                return;
+            };
+            // Anything newer than the oldest loaded message would be loaded
+            if message_id >= oldest {
+                break;
+            }
+            match self
+                .telegram
+                .get_messages(chat_id, JUMP_HISTORY_LIMIT, Some(oldest))
+                .await
+            {
+                // The user may have switched chats while the history was loading
+                Ok(_) if self.selected_chat_id != Some(chat_id) => return,
+                Ok(older) if older.is_empty() => break,
+                Ok(older) => {
+                    self.conversation_model.prepend_messages(older);
+                    if self.conversation_model.jump_to_message(message_id) {
+                        self.conversation_model
+                            .flash_message(message_id, Instant::now());
+                        return;
+                    }
+                },
+                Err(e) => {
+                    self.set_error_message(format!("Failed to load messages: {e}"));
+                    return;
+                },
+            }
+        }
+        if message_id < self.conversation_model.oldest_message_id().unwrap_or(0) {
+            self.set_status_message("The replied message is too far back");
+        } else {
+            self.set_status_message("The replied message no longer exists");
+        }
+    }
+
+    /// Handle sending a message. It's queued behind the chat's earlier
+    /// sends and shows once Telegram has it.
+    fn handle_send_message(&mut self, chat_id: i64, text: String, reply_to: Option<i64>) {
+        let send_as = self.send_as.get(&chat_id).map(|p| p.id);
+        self.sends.push(
+            chat_id,
+            Outgoing::Text {
+                text,
+                reply_to,
+                send_as,
+            },
+        );
+    }
+
+    /// Handle sending files with one caption, which goes with the first.
+    /// Images going as photos are sent as albums of up to ten; with other
+    /// files among them, each file is sent in turn.
+    fn handle_send_attachments(
+        &mut self,
+        chat_id: i64,
+        caption: String,
+        paths: Vec<std::path::PathBuf>,
+        as_photo: bool,
+        reply_to: Option<i64>,
+    ) {
+        if paths.len() == 1 {
+            self.set_status_message("Uploading\u{2026}".to_string());
+        } else {
+            self.set_status_message(format!("Uploading {} files\u{2026}", paths.len()));
+        }
+        self.sends.push(
+            chat_id,
+            Outgoing::Files {
+                caption,
+                paths,
+                as_photo,
+                reply_to,
+            },
+        );
+    }
+
+    /// Shows what the send queue has sent since the last tick, and reports
+    /// what failed.
+    fn finish_sends(&mut self) {
+        for Sent {
+            chat_id,
+            what,
+            messages,
+            error,
+        } in self.sends.finished()
+        {
+            let open = self
+                .conversation_model
+                .chat
+                .as_ref()
+                .map_or(false, |c| c.id == chat_id);
+            if open {
+                for message in messages {
+                    self.conversation_model.add_message(message);
+                }
+            }
+            match error {
+                None if what != "message" => self.clear_status_message(),
+                None => {},
+                Some(e) => {
+                    if e.makes_chat_read_only() {
+                        self.mark_read_only(chat_id);
+                    }
+                    self.set_error_message(format!("Failed to send {what}: {e}"));
+                },
+            }
+        }
+    }
+
+    /// Records that the user can't send messages in a chat, which turns its
+    /// composer read-only.
+    fn mark_read_only(&mut self, chat_id: i64) {
+        if let Some(mut chat) = self.cache.get_chat(chat_id) {
+            chat.is_read_only = true;
+            self.cache.set_chat(chat);
+        }
+        if let Some(chat) = self
+            .conversation_model
+            .chat
+            .as_mut()
+            .filter(|c| c.id == chat_id)
+        {
+            chat.is_read_only = true;
+        }
+    }
+
+    /// Handle editing a message.
+    async fn handle_edit_message(&mut self, chat_id: i64, message_id: i64, text: String) {
+        match self.telegram.edit_message(chat_id, message_id, &text).await {
+            Ok(message) => {
+                self.conversation_model.update_message(message);
+            },
+            // Saving an unchanged edit isn't worth an error
+            Err(crate::telegram::TelegramError::MessageNotModified) => {},
+            Err(
+                e @ (crate::telegram::TelegramError::EditTimeExpired
+                | crate::telegram::TelegramError::MessageNotEditable),
+            ) => self.set_error_message(e.to_string()),
+            Err(e) => {
+                self.set_error_message(format!("Failed to edit message: {e}"));
+            },
+        }
+    }
+
+    /// Handle deleting a message.
+    async fn handle_delete_message(&mut self, chat_id: i64, message_id: i64) {
+        // revoke=true means delete for everyone (if allowed)
+        match self
+            .telegram
+            .delete_messages(chat_id, &[message_id], true)
+            .await
+        {
+            Ok(()) => {
+                self.conversation_model.delete_message(message_id);
+            },
+            Err(e) => {
+                self.set_error_message(format!("Failed to delete message: {e}"));
+            },
+        }
+    }
+
+    /// Handle opening media from a message.
+    ///
+    /// Downloads the attachment if not already downloaded, then opens it with
+    /// the system viewer. Works for any attachment type, not just photos.
+    /// The download runs in the background and retries transient failures;
+    /// a download that still fails is marked in the message and can be
+    /// retried by opening the message again.
+    async fn handle_open_media(&mut self, chat_id: i64, message_id: i64) {
+        use crate::telegram::TelegramClient;
+
+        // Get the message from cache
+        let message = self
+            .cache
+            .get_messages(chat_id)
+            .into_iter()
+            .find(|m| m.id == message_id);
+
+        let Some(mut message) = message else {
+            self.set_status_message("Message not found".to_string());
+            return;
+        };
+
+        // Messages without a downloadable attachment may still carry a link;
+        // open the first URL in the browser instead.
+        if !message.content.content_type.is_downloadable() {
+            if let Some(url) = crate::utils::first_url(&message.content.text) {
+                if let Err(e) = TelegramClient::open_url(&url).await {
+                    self.set_error_message(format!("Failed to open link: {e}"));
+                }
+            } else {
+                self.set_status_message("Selected message has no attachment or link".to_string());
+            }
+            return;
+        }
+
+        if message.content.download_status() == DownloadStatus::Downloading {
+            self.set_status_message("Attachment is still downloading".to_string());
+            return;
+        }
+
+        self.start_media_download(message, None);
+    }
+
+    /// Handle saving a copy of a message's attachment.
+    ///
+    /// The copy goes to the configured downloads directory under a readable
+    /// name. An attachment that's already downloaded is copied straight
+    /// away; otherwise it's downloaded first, in the background.
+    fn handle_save_media(&mut self, chat_id: i64, message_id: i64) {
+        let message = self
+            .cache
+            .get_messages(chat_id)
+            .into_iter()
+            .find(|m| m.id == message_id);
+
+        let Some(message) = message else {
+            self.set_status_message("Message not found".to_string());
+            return;
+        };
+
+        if !message.content.content_type.is_downloadable() {
+            self.set_status_message("Selected message has no attachment".to_string());
+            return;
+        }
+
+        if message.content.download_status() == DownloadStatus::Downloading {
+            self.set_status_message("Attachment is still downloading".to_string());
+            return;
+        }
+
+        let dir = self.config.ui.behavior.download_directory.clone();
+        let downloaded = message
+            .content
+            .media
+            .as_ref()
+            .map(|media| std::path::PathBuf::from(&media.local_path))
+            .filter(|path| path.is_file());
+        if let Some(path) = downloaded {
+            match crate::telegram::media::save_media_copy(&path, &dir, &message) {
+                Ok(saved) => self.set_success_message(format!("Saved to {}", saved.display())),
+                Err(e) => self.set_error_message(format!("Failed to save attachment: {e}")),
+            }
+            return;
+        }
+
+        self.start_media_download(message, Some(dir));
+    }
+
+    /// Saves the open chat's attachments into a folder, a few at a time
+    /// (`/download`).
+    async fn handle_bulk_download(&mut self, input: &str) {
+        let Some(chat_id) = self.require_open_chat() else {
+            return;
+        };
+        if self.bulk_download.is_some() {
+            self.set_status_message("Another /download is still running");
+            return;
+        }
+        let request = match BulkRequest::parse(input, chrono::Local::now().date_naive()) {
+            Ok(request) => request,
+            Err(e) => {
+                self.set_status_message(e);
+                return;
+            },
+        };
+
+        // Telegram can't search by date, so look further back and drop
+        // what's outside the dates here
+        let limit = if request.is_dated() {
+            bulk_download::MAX_COUNT
+        } else {
+            request.count
+        };
+        let mut messages = match self
+            .telegram
+            .search_messages(Some(chat_id), "", Some(request.kind), limit)
+            .await
+        {
+            Ok(messages) => messages,
+            Err(e) => {
+                self.set_error_message(format!("Failed to list the chat's media: {e}"));
+                return;
+            },
+        };
+        messages.retain(|m| request.keeps(m));
+        messages.truncate(request.count);
+        if messages.is_empty() {
+            self.set_status_message("Nothing to download");
+            return;
+        }
+
+        let folder = request.folder.unwrap_or_else(|| {
+            let name = bulk_download::folder_name(&self.chat_display_name(chat_id), chat_id);
+            self.config.ui.behavior.download_directory.join(name)
+        });
+        self.set_status_message(format!(
+            "Downloading {} to {}",
+            messages.len(),
+            folder.display()
+        ));
+        self.bulk_download = Some(BulkDownload::new(chat_id, folder, messages));
+        self.advance_bulk_download();
+    }
+
+    /// Starts as many `/download` attachments as there's room for, copying
+    /// ones already downloaded straight away, and reports once all are
+    /// done.
+    fn advance_bulk_download(&mut self) {
+        loop {
+            let Some(bulk) = self.bulk_download.as_mut() else {
+                return;
+            };
+            if bulk.is_done() {
+                let summary = bulk.summary();
+                self.bulk_download = None;
+                self.set_success_message(summary);
+                return;
+            }
+            let folder = bulk.folder().to_path_buf();
+            let started = bulk.start_next();
+            if started.is_empty() {
+                return;
+            }
+            for mut message in started {
+                let downloaded = message
+                    .content
+                    .media
+                    .as_ref()
+                    .map(|media| std::path::PathBuf::from(&media.local_path))
+                    .filter(|path| path.is_file());
+                if let Some(path) = downloaded {
+                    let saved =
+                        crate::telegram::media::save_media_copy(&path, &folder, &message).is_ok();
+                    if let Some(bulk) = self.bulk_download.as_mut() {
+                        bulk.finish(message.chat_id, message.id, saved);
+                    }
+                    continue;
+                }
+                let media_dir = match self.media_dir_for(&message) {
+                    Ok(dir) => dir,
+                    Err(e) => {
+                        self.set_error_message(format!("{NO_PRIVATE_DIR}: {e}"));
+                        if let Some(bulk) = self.bulk_download.as_mut() {
+                            bulk.finish(message.chat_id, message.id, false);
+                        }
+                        continue;
+                    },
+                };
+                message
+                    .content
+                    .set_download_status(DownloadStatus::Downloading, None);
+                self.store_message(message.clone());
+                Arc::clone(&self.telegram).spawn_media_download(
+                    message,
+                    media_dir,
+                    Some(folder.clone()),
+                );
+            }
+        }
+    }
+
+    /// Starts playing the open chat's unplayed voice messages, or skips to
+    /// the next one or stops.
+    async fn handle_listen(&mut self, command: slash_command::ListenCommand) {
+        use slash_command::ListenCommand;
+
+        match command {
+            ListenCommand::Start => {
+                let Some(chat_id) = self.require_open_chat() else {
+                    return;
+                };
+                // Whatever is playing stops before the list is fetched
+                self.listening = None;
+                let telegram = Arc::clone(&self.telegram);
+                let (tx, rx) = tokio::sync::oneshot::channel();
+                tokio::spawn(async move {
+                    let listed = telegram
+                        .search_messages(
+                            Some(chat_id),
+                            "",
+                            Some(SearchFilter::Voice),
+                            voice_queue::LOOKBACK,
+                        )
+                        .await;
+                    let _ = tx.send(Fetched::Listed(listed));
+                });
+                let mut queue = VoiceQueue::new(chat_id, &[]);
+                queue.wait_for(rx);
+                self.listening = Some(queue);
+                self.set_status_message("Looking for voice messages\u{2026}");
+            },
+            ListenCommand::Next if self.listening.is_some() => self.play_next_voice().await,
+            ListenCommand::Stop if self.listening.is_some() => {
+                self.listening = None;
+                self.set_status_message("Stopped listening");
+            },
+            ListenCommand::Next | ListenCommand::Stop => {
+                self.set_status_message("Nothing is playing; /listen starts");
+            },
+        }
+    }
+
+    /// Stops the voice message playing and moves `/listen` on to the next:
+    /// plays it if it's downloaded, or else downloads it in the background
+    /// for [`Self::check_voice_player`] to play. Once none are left, says
+    /// how many were played.
+    async fn play_next_voice(&mut self) {
+        loop {
+            let Some(queue) = self.listening.as_mut() else {
+                return;
+            };
+            let Some(message) = queue.start_next() else {
+                let (played, _) = queue.progress();
+                self.listening = None;
+                self.set_success_message(format!("Played {played} voice messages"));
+                return;
+            };
+
+            let downloaded = message
+                .content
+                .media
+                .as_ref()
+                .map(|media| std::path::PathBuf::from(&media.local_path))
+                .filter(|path| path.is_file());
+            if let Some(path) = downloaded {
+                self.play_voice(&path).await;
+                return;
+            }
+            let dir = match self.media_dir_for(&message) {
+                Ok(dir) => dir,
+                Err(e) => {
+                    self.set_error_message(format!("{NO_PRIVATE_DIR}: {e}"));
+                    continue;
+                },
+            };
+            let telegram = Arc::clone(&self.telegram);
+            let (tx, rx) = tokio::sync::oneshot::channel();
+            tokio::spawn(async move {
+                let downloaded = telegram.fetch_media(&message, &dir).await;
+                let _ = tx.send(Fetched::Downloaded(downloaded));
+            });
+            if let Some(queue) = self.listening.as_mut() {
+                queue.wait_for(rx);
+            }
+            return;
+        }
+    }
+
+    /// Plays `/listen`'s current voice message from `path` and marks it
+    /// played.
+    async fn play_voice(&mut self, path: &std::path::Path) {
+        let Some(mut message) = self
+            .listening
+            .as_ref()
+            .and_then(|queue| queue.current().cloned())
+        else {
+            return;
+        };
+        let player = self.config.ui.behavior.voice_player.clone();
+        match crate::platform::play(&player, path) {
+            Ok(child) => {
+                if let Some(queue) = self.listening.as_mut() {
+                    queue.set_player(child);
+                }
+            },
+            Err(e) => {
+                self.listening = None;
+                self.set_error_message(format!("Couldn't start \"{player}\": {e}"));
+                return;
+            },
+        }
+
+        // Telegram's apps mark a voice message played as it starts
+        if self.config.privacy.sends_read_receipts() {
+            if let Err(e) = self.telegram.mark_played(message.chat_id, message.id).await {
+                tracing::warn!("Failed to mark message {} played: {}", message.id, e);
+            }
+        }
+        message.media_unread = false;
+        if let Some(media) = message.content.media.as_mut() {
+            media.local_path = path.display().to_string();
+        }
+        self.store_message(message);
+    }
+
+    /// Moves `/listen` on once what it waits for has been fetched, or the
+    /// player for the current voice message has exited. Messages that
+    /// can't be downloaded are skipped.
+    async fn check_voice_player(&mut self) {
+        let Some(queue) = self.listening.as_mut() else {
+            return;
+        };
+        match queue.fetched() {
+            Some(Fetched::Listed(Ok(messages))) => {
+                queue.add_listed(&messages);
+                if queue.is_empty() {
+                    self.listening = None;
+                    self.set_status_message("No unplayed voice messages here");
+                } else {
+                    self.play_next_voice().await;
+                }
+            },
+            Some(Fetched::Listed(Err(e))) => {
+                self.listening = None;
+                self.set_error_message(format!("Failed to list voice messages: {e}"));
+            },
+            Some(Fetched::Downloaded(Ok(path))) => {
+                if let Some(message) = queue.current() {
+                    let (chat_id, message_id) = (message.chat_id, message.id);
+                    self.seal_downloaded(chat_id, message_id, &path);
+                }
+                self.play_voice(&path).await;
+            },
+            Some(Fetched::Downloaded(Err(e))) => {
+                self.set_error_message(format!("Skipped a voice message: {e}"));
+                self.play_next_voice().await;
+            },
+            None if queue.player_finished() => self.play_next_voice().await,
+            None => {},
+        }
+    }
+
+    /// Marks a message's attachment as downloading and fetches it (with
+    /// retries) in the background, then opens it or, with `save_to`, saves a
+    /// copy there. The result arrives as a FileDownload update.
+    fn start_media_download(&mut self, mut message: Message, save_to: Option<std::path::PathBuf>) {
+        let media_dir = match self.media_dir_for(&message) {
+            Ok(dir) => dir,
+            Err(e) => {
+                self.set_error_message(format!("{NO_PRIVATE_DIR}: {e}"));
+                return;
+            },
+        };
+        message
+            .content
+            .set_download_status(DownloadStatus::Downloading, None);
+        self.store_message(message.clone());
+        self.set_status_message("Downloading attachment...".to_string());
+        Arc::clone(&self.telegram).spawn_media_download(message, media_dir, save_to);
+    }
+
+    /// Replaces a message in the cache and, if its chat is open, in the
+    /// conversation.
+    fn store_message(&mut self, message: Message) {
+        if self.selected_chat_id == Some(message.chat_id) {
+            self.conversation_model.update_message(message.clone());
+        }
+        if let Some(model) = self.split_conversation_for(message.chat_id) {
+            model.update_message(message.clone());
+        }
+        self.cache.update_message(message.chat_id, message);
+    }
+
+    /// Handle authentication actions asynchronously.
+    async fn handle_auth_action(&mut self, action: AuthAction) {
+        self.set_auth_loading(true);
+        self.clear_status_message();
+
+        let result: Result<(), crate::telegram::TelegramError> = match action {
+            AuthAction::SubmitPhoneNumber(phone) | AuthAction::ResendCode(phone) => {
+                self.telegram.request_login_code(&phone).await
+            },
+            AuthAction::SubmitCode(code) => self.telegram.sign_in(&code).await,
+            AuthAction::SubmitPassword(password) => self.telegram.check_password(&password).await,
+            AuthAction::SubmitRegistration(_) => {
+                // Sign up via third-party apps is not supported by Telegram
+                Err(crate::telegram::TelegramError::SignUpRequired)
+            },
+            AuthAction::Quit => {
+                self.should_quit = true;
+                return;
+            },
+        };
+
+        self.set_auth_loading(false);
 
         match result {
             Ok(()) => {
@@ -736,9 +3240,18 @@ impl App {
                         let new_state = self.telegram.get_auth_state().await;
                         self.update_auth_state(new_state);
                     },
+                    crate::telegram::TelegramError::FloodWait(secs) => {
+                        let secs = u64::try_from(*secs).unwrap_or(0);
+                        self.auth_model.delay_resend(Duration::from_secs(secs));
+                    },
                     _ => {},
                 }
-                self.set_auth_error(e.to_string());
+                if e.is_recoverable() && !matches!(e, crate::telegram::TelegramError::FloodWait(_))
+                {
+                    self.set_auth_error(format!("{e}; press Enter to try again"));
+                } else {
+                    self.set_auth_error(e.to_string());
+                }
             },
         }
     }
@@ -749,9 +3262,18 @@ impl App {
     async fn on_authorized(&mut self) {
         // Load dialogs
         if let Err(e) = self.telegram.get_dialogs().await {
-            self.set_status_message(format!("Failed to load chats: {e}"));
+            self.set_error_message(format!("Failed to load chats: {e}"));
         } else {
             self.refresh_chat_list();
+            if let Some(view) = self.startup_view.take() {
+                self.open_startup_view(view).await;
+            }
+        }
+
+        // Show which account is logged in
+        match self.telegram.get_me().await {
+            Ok(me) => self.status_bar.set_user(Some(me)),
+            Err(e) => tracing::warn!("Failed to load the logged-in user: {e}"),
         }
 
         // Start the update loop if not already running
@@ -765,9 +3287,181 @@ impl App {
         }
     }
 
+    /// Opens the chat `startup_view` or `--chat` asked for. A remembered
+    /// last chat that is gone is skipped quietly; anything else missing is
+    /// reported.
+    async fn open_startup_view(&mut self, view: StartupView) {
+        let chat_id = match view {
+            StartupView::ChatList => return,
+            StartupView::LastChat => {
+                match state::load_last_chat(&state::last_chat_file(&self.config)) {
+                    Some(chat_id) if self.cache.get_chat(chat_id).is_some() => chat_id,
+                    _ => return,
+                }
+            },
+            StartupView::SavedMessages => match self.telegram.get_me().await {
+                Ok(me) => me.id,
+                Err(e) => {
+                    self.set_error_message(format!("Couldn't open Saved Messages: {e}"));
+                    return;
+                },
+            },
+            StartupView::Chat(chat_id) => chat_id,
+            StartupView::Handle(handle) => {
+                self.handle_resolve_chat(&handle).await;
+                if self.selected_chat_id.is_some() {
+                    self.focused_pane = FocusedPane::Conversation;
+                }
+                return;
+            },
+            StartupView::Link(link) => {
+                self.open_link(link).await;
+                return;
+            },
+        };
+        if self.cache.get_chat(chat_id).is_none() {
+            self.set_status_message(format!("No chat {chat_id} in your chat list"));
+            return;
+        }
+        self.jump_to_chat(chat_id);
+        self.handle_chat_selected(chat_id).await;
+        self.focused_pane = FocusedPane::Conversation;
+    }
+
+    /// Opens the chat a `t.me` or `tg://` link points to, at its message if
+    /// it names one. A public channel or group the user isn't in opens as a
+    /// preview.
+    async fn open_link(&mut self, link: DeepLink) {
+        let handle = match link.chat {
+            LinkChat::Id(chat_id) => {
+                match self.cache.get_chat(chat_id) {
+                    Some(chat) => self.open_chat_at(chat, link.message_id).await,
+                    None => self.set_status_message("That link is to a private chat you aren't in"),
+                }
+                return;
+            },
+            LinkChat::Handle(handle) => handle,
+        };
+
+        if let Some(chat) = self.known_chat(&handle) {
+            self.open_chat_at(chat, link.message_id).await;
+            return;
+        }
+
+        let chat = match self.telegram.resolve_chat(&handle).await {
+            Ok(chat) => chat,
+            Err(e) => {
+                self.set_error_message(format!("Couldn't open {handle}: {e}"));
+                return;
+            },
+        };
+        if Self::previewable(&chat) {
+            self.open_preview(chat, link.message_id).await;
+            return;
+        }
+        self.open_chat_at(chat, link.message_id).await;
+    }
+
+    /// Returns the chat in the list with the username in `handle`.
+    fn known_chat(&self, handle: &PeerHandle) -> Option<Chat> {
+        match handle {
+            PeerHandle::Username(name) => self
+                .cache
+                .get_all_chats()
+                .into_iter()
+                .find(|c| c.username.eq_ignore_ascii_case(name)),
+            PeerHandle::Phone(_) => None,
+        }
+    }
+
+    /// Returns `true` for chats that can be previewed before joining:
+    /// channels and supergroups.
+    fn previewable(chat: &Chat) -> bool {
+        matches!(chat.chat_type, ChatType::Channel | ChatType::Supergroup)
+    }
+
+    /// Shows a public channel or group the user isn't in, read-only and
+    /// outside the chat list, at `message_id` if given. `/join` joins it;
+    /// opening another chat drops it.
+    async fn open_preview(&mut self, chat: Chat, message_id: Option<i64>) {
+        let chat_id = chat.id;
+        self.end_preview();
+        // Resolving cached the chat, which would put it in the list
+        self.cache.remove_chat(chat_id);
+        self.selected_chat_id = Some(chat_id);
+        self.chat_list_model.set_focused(false);
+        self.focused_pane = FocusedPane::Conversation;
+        let title = chat.title.clone();
+        let mut shown = chat.clone();
+        shown.is_read_only = true;
+        self.conversation_model.set_chat(shown);
+        self.preview = Some(chat);
+
+        match self.telegram.get_messages(chat_id, 50, None).await {
+            Ok(messages) => self.conversation_model.set_messages(messages),
+            Err(e) => {
+                self.set_error_message(format!("Failed to load messages: {e}"));
+                return;
+            },
+        }
+        self.set_status_message(format!("Previewing {title}; /join to join"));
+        if let Some(message_id) = message_id {
+            self.handle_jump_to_message(chat_id, message_id).await;
+        }
+    }
+
+    /// Drops the chat being previewed, with anything loaded for it.
+    fn end_preview(&mut self) {
+        if let Some(chat) = self.preview.take() {
+            self.cache.remove_chat(chat.id);
+        }
+    }
+
+    /// Joins the channel or group being previewed, which then opens like
+    /// any chat in the list, still at the selected message.
+    async fn join_preview(&mut self) {
+        let Some(chat) = self.preview.clone() else {
+            self.set_status_message("/join is for channels and groups opened as a preview");
+            return;
+        };
+        let chat_id = chat.id;
+        if let Err(e) = self.telegram.join_chat(chat_id).await {
+            self.set_error_message(format!("Couldn't join: {e}"));
+            return;
+        }
+        self.preview = None;
+        let message_id = self.conversation_model.selected_message().map(|m| m.id);
+        self.set_success_message(format!("Joined {}", chat.title));
+        self.cache.set_chat(chat.clone());
+        self.chat_list_model.update_chat(chat);
+        self.jump_to_chat(chat_id);
+        self.handle_chat_selected(chat_id).await;
+        if let Some(message_id) = message_id {
+            self.handle_jump_to_message(chat_id, message_id).await;
+        }
+    }
+
+    /// Adds a chat to the list if it isn't there and opens it, at
+    /// `message_id` if given.
+    async fn open_chat_at(&mut self, chat: Chat, message_id: Option<i64>) {
+        let chat_id = chat.id;
+        self.chat_list_model.update_chat(chat);
+        if let Some(message_id) = message_id {
+            self.handle_jump_to_message(chat_id, message_id).await;
+            return;
+        }
+        self.jump_to_chat(chat_id);
+        self.handle_chat_selected(chat_id).await;
+        self.focused_pane = FocusedPane::Conversation;
+    }
+
     /// Handle chat selection - load messages for the selected chat.
     async fn handle_chat_selected(&mut self, chat_id: i64) {
         tracing::info!("Chat selected: {}", chat_id);
+        self.end_preview();
+        self.cache.mark_viewed(chat_id);
+        self.chat_list_model.set_highlighted(chat_id, false);
+        self.chat_list_model.set_membership(chat_id, None);
 
         // Get the chat from cache and set it on the conversation model
         if let Some(chat) = self.cache.get_chat(chat_id) {
@@ -787,27 +3481,212 @@ impl App {
             },
             Err(e) => {
                 tracing::error!("Failed to load messages for chat {}: {}", chat_id, e);
-                self.set_status_message(format!("Failed to load messages: {e}"));
+                self.set_error_message(format!("Failed to load messages: {e}"));
             },
         }
 
-        // Mark chat as read
-        if let Err(e) = self.telegram.mark_as_read(chat_id).await {
-            tracing::warn!("Failed to mark chat {} as read: {}", chat_id, e);
+        // Mark chat as read, unless receipts are off (stealth mode)
+        if self.config.privacy.sends_read_receipts() {
+            if let Err(e) = self.telegram.mark_as_read(chat_id).await {
+                tracing::warn!("Failed to mark chat {} as read: {}", chat_id, e);
+            }
         }
         self.refresh_chat_list();
     }
 
+    /// Loads a chat's history up to the end of `date` and selects the first
+    /// message sent that day.
+    async fn handle_jump_to_date(&mut self, chat_id: i64, date: NaiveDate) {
+        let local_midnight = |day: NaiveDate| {
+            day.and_hms_opt(0, 0, 0)
+                .and_then(|t| t.and_local_timezone(chrono::Local).earliest())
+                .map(|t| t.with_timezone(&chrono::Utc))
+        };
+        let (Some(start), Some(end)) = (
+            local_midnight(date),
+            date.succ_opt().and_then(local_midnight),
+        ) else {
+            self.set_status_message(format!("Cannot jump to {date}"));
+            return;
+        };
+
+        let messages = match self
+            .telegram
+            .get_messages_before_date(chat_id, JUMP_HISTORY_LIMIT, end)
+            .await
+        {
+            Ok(messages) => messages,
+            Err(e) => {
+                self.set_error_message(format!("Failed to load messages: {e}"));
+                return;
+            },
+        };
+        // The user may have switched chats while the history was loading
+        if self.selected_chat_id != Some(chat_id) {
+            return;
+        }
+        if messages.is_empty() {
+            self.set_status_message(format!("No messages on or before {date}"));
+            return;
+        }
+
+        self.conversation_model.set_messages(messages);
+        self.conversation_model.select_first_since(start);
+        let on_day = self
+            .conversation_model
+            .selected_message()
+            .is_some_and(|m| m.date >= start);
+        self.set_status_message(if on_day {
+            format!("Showing {date} (reopen the chat for the latest messages)")
+        } else {
+            format!("No messages on {date}; showing the latest before it")
+        });
+    }
+
     /// Handle a key event.
     ///
     /// Returns an optional [`AppAction`] if the key triggered an action
     /// that needs external handling.
     pub fn handle_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        self.last_activity = Instant::now();
+
+        // The lock screen captures all keys while locked.
+        if self.lock_screen.is_some() {
+            return self.handle_lock_screen_key(key);
+        }
+
+        // Ctrl+L locks from the main view and settings alike
+        if matches!(self.state, AppState::Main | AppState::Settings)
+            && self.keymap.get_action(&key) == Some(Action::Lock)
+        {
+            return self.handle_action(Action::Lock);
+        }
+
         // File picker overlay captures all keys while open.
-        if self.file_picker.is_some() {
+        if self.overlays.file_picker.is_some() {
             return self.handle_file_picker_key(key);
         }
 
+        // Quick switcher overlay likewise captures all keys while open.
+        if self.overlays.quick_switcher.is_some() {
+            return self.handle_quick_switcher_key(key);
+        }
+
+        // As do the date prompt and the dialogs.
+        if self.overlays.date_prompt.is_some() {
+            return self.handle_date_prompt_key(key);
+        }
+        if self.overlays.forward_dialog.is_some() {
+            return self.handle_forward_dialog_key(key);
+        }
+        if self.overlays.report_dialog.is_some() {
+            return self.handle_report_dialog_key(key);
+        }
+        if self.overlays.reaction_picker.is_some() {
+            return self.handle_reaction_picker_key(key);
+        }
+        if self.overlays.permissions_editor.is_some() {
+            return self.handle_permissions_editor_key(key);
+        }
+        if self.overlays.poll_view.is_some() {
+            return self.handle_poll_view_key(key);
+        }
+        if self.overlays.confirmation.is_some() {
+            return self.handle_confirmation_key(key);
+        }
+        if self.overlays.show_reactions {
+            return self.handle_reactions_key(key);
+        }
+        if self.overlays.inbox.is_some() {
+            return self.handle_inbox_key(key);
+        }
+        if self.overlays.bookmark_list.is_some() {
+            return self.handle_bookmark_list_key(key);
+        }
+        if self.overlays.pin_board.is_some() {
+            return self.handle_pin_board_key(key);
+        }
+        if self.overlays.search_results.is_some() {
+            return self.handle_search_results_key(key);
+        }
+        if let Some(view) = self.overlays.chat_stats.as_mut() {
+            if view.handle_input(key) == ChatStatsAction::Close {
+                self.overlays.chat_stats = None;
+            }
+            return None;
+        }
+        if let Some(view) = self.overlays.qr_view.as_mut() {
+            if view.handle_input(key) == QrViewAction::Close {
+                self.overlays.qr_view = None;
+            }
+            return None;
+        }
+        if let Some(picker) = self.overlays.send_as_picker.as_mut() {
+            match picker.handle_input(key) {
+                SendAsPickerAction::None => {},
+                SendAsPickerAction::Cancel => self.overlays.send_as_picker = None,
+                SendAsPickerAction::Choose(chat_id, peer) => {
+                    self.overlays.send_as_picker = None;
+                    self.choose_send_as(chat_id, peer);
+                },
+            }
+            return None;
+        }
+        if let Some(menu) = self.overlays.chat_actions.as_mut() {
+            return match menu.handle_input(key) {
+                ChatActionsAction::None => None,
+                ChatActionsAction::Cancel => {
+                    self.overlays.chat_actions = None;
+                    None
+                },
+                ChatActionsAction::Run(chat_id, item) => {
+                    self.overlays.chat_actions = None;
+                    self.run_chat_menu_item(chat_id, item)
+                },
+            };
+        }
+        if let Some(list) = self.overlays.member_list.as_mut() {
+            match list.handle_input(key) {
+                MemberListAction::None => {},
+                MemberListAction::Close => self.overlays.member_list = None,
+                MemberListAction::Open(user_id) => {
+                    if self.cache.get_chat(user_id).is_some() {
+                        self.overlays.member_list = None;
+                        self.jump_to_chat(user_id);
+                        return Some(AppAction::ChatSelected(user_id));
+                    }
+                    self.set_status_message("No chat with them yet");
+                },
+            }
+            return None;
+        }
+        if let Some(view) = self.overlays.storage_manager.as_mut() {
+            match view.handle_input(key) {
+                StorageManagerAction::None => {},
+                StorageManagerAction::Cancel => self.overlays.storage_manager = None,
+                StorageManagerAction::Clear(kinds, bytes) => {
+                    self.overlays.storage_manager = None;
+                    self.confirm_clear_local_data(kinds, bytes);
+                },
+            }
+            return None;
+        }
+        if let Some(view) = self.overlays.channel_manager.as_mut() {
+            match view.handle_input(key) {
+                ChannelManagerAction::None => {},
+                ChannelManagerAction::Close => self.overlays.channel_manager = None,
+                ChannelManagerAction::Leave(chat_ids) => self.confirm_leave_channels(chat_ids),
+                change => return Some(AppAction::ChangeChannels(change)),
+            }
+            return None;
+        }
+        if let Some(log) = self.overlays.error_log.as_mut() {
+            if log.handle_input(key) == ErrorLogAction::Close {
+                self.overlays.error_log = None;
+            }
+            return None;
+        }
+
         // Handle auth state separately - forward all keys to AuthModel
         if self.state == AppState::Auth {
             if let Some(auth_action) = self.auth_model.handle_input(key) {
@@ -827,6 +3706,44 @@ impl App {
             return self.handle_settings_key(key);
         }
 
+        // Ctrl+K (quick switcher), Ctrl+G (jump to date), Alt+R (reactions),
+        // Alt+I (inbox), Alt+B (bookmarks), Alt+P (local pins), Alt+E
+        // (errors), Alt+A (chat actions), Alt+O (open in Telegram) and
+        // Alt+V/Alt+W (split view) work from any pane, before pane-specific
+        // handlers can treat them as text or navigation
+        if self.state == AppState::Main {
+            if let Some(
+                action @ (Action::QuickSwitch
+                | Action::JumpToDate
+                | Action::ShowReactions
+                | Action::ShowInbox
+                | Action::ShowBookmarks
+                | Action::ShowLocalPins
+                | Action::ShowErrors
+                | Action::ChatActions
+                | Action::OpenInTelegram
+                | Action::ToggleSplit
+                | Action::SwitchSplit),
+            ) = self.keymap.get_action(&key)
+            {
+                return self.handle_action(action);
+            }
+        }
+
+        // Leader sequences work from any pane except while typing
+        if self.state == AppState::Main {
+            if let Some(typed) = self.leader_pending.take() {
+                return self.handle_leader_key(typed, key);
+            }
+            if self.focused_pane != FocusedPane::Input
+                && !self.chat_list_model.is_search_mode()
+                && self.leader.is_leader(&key)
+            {
+                self.leader_pending = Some(String::new());
+                return None;
+            }
+        }
+
         // Handle chat list input when focused
         if self.state == AppState::Main && self.focused_pane == FocusedPane::ChatList {
             match self.chat_list_model.handle_input(key) {
@@ -863,8 +3780,18 @@ impl App {
                     | Action::Reply
                     | Action::Edit
                     | Action::Delete
-                    | Action::Forward
                     | Action::CancelAction => {
+                        if action == Action::Edit
+                            && self
+                                .conversation_model
+                                .selected_message()
+                                .is_some_and(|m| m.is_outgoing && !m.can_edit(chrono::Utc::now()))
+                        {
+                            self.set_status_message(
+                                crate::telegram::TelegramError::EditTimeExpired.to_string(),
+                            );
+                            return None;
+                        }
                         let _ = self.conversation_model.handle_action(action);
                         return None;
                     },
@@ -874,18 +3801,160 @@ impl App {
                         self.focused_pane = FocusedPane::Input;
                         return None;
                     },
+                    Action::MuteChat => {
+                        let muted = self.cache.get_chat(self.selected_chat_id?)?.is_muted;
+                        return Some(AppAction::Command(if muted {
+                            SlashCommand::Unmute
+                        } else {
+                            SlashCommand::Mute(None)
+                        }));
+                    },
+                    Action::OpenDiscussion => {
+                        return self.selected_chat_id.map(AppAction::OpenDiscussion);
+                    },
                     Action::OpenMedia => {
                         // Get the selected message ID and open media
                         if let (Some(chat_id), Some(message)) = (
                             self.selected_chat_id,
                             self.conversation_model.selected_message(),
                         ) {
+                            // Polls open in their own overlay
+                            if let Some(poll) = &message.content.poll {
+                                self.overlays.poll_view =
+                                    Some(PollView::new(chat_id, message.id, poll.clone()));
+                                return None;
+                            }
                             return Some(AppAction::OpenMedia(chat_id, message.id));
                         }
                         return None;
                     },
+                    Action::SaveMedia => {
+                        if let (Some(chat_id), Some(message)) = (
+                            self.selected_chat_id,
+                            self.conversation_model.selected_message(),
+                        ) {
+                            return Some(AppAction::SaveMedia(chat_id, message.id));
+                        }
+                        return None;
+                    },
                     Action::AttachFile => {
-                        self.file_picker = Some(crate::ui::components::FilePicker::new());
+                        self.overlays.file_picker = Some(crate::ui::components::FilePicker::new());
+                        return None;
+                    },
+                    Action::CopyMessage => {
+                        if let Some(message) = self.conversation_model.selected_message() {
+                            let content = &message.content;
+                            let text = if content.text.is_empty() {
+                                &content.caption
+                            } else {
+                                &content.text
+                            };
+                            if text.is_empty() {
+                                self.set_status_message("Message has no text to copy");
+                            } else {
+                                match crate::platform::copy_to_clipboard(text) {
+                                    Ok(()) => self.set_success_message("Copied message text"),
+                                    Err(e) => {
+                                        self.set_error_message(format!("Failed to copy: {e}"))
+                                    },
+                                }
+                            }
+                        }
+                        return None;
+                    },
+                    Action::Forward => {
+                        if let (Some(chat_id), Some(message)) = (
+                            self.selected_chat_id,
+                            self.conversation_model.selected_message(),
+                        ) {
+                            self.overlays.pending_forward = Some((chat_id, message.id));
+                            let recent = state::load_forward_targets(&state::forward_targets_file(
+                                &self.config,
+                            ));
+                            self.overlays.quick_switcher = Some(
+                                QuickSwitcher::new(
+                                    self.chat_list_model.chats(),
+                                    self.chat_list_model.aliases(),
+                                )
+                                .with_title(" Forward to ")
+                                .with_multi_select()
+                                .with_recent(&recent),
+                            );
+                        }
+                        return None;
+                    },
+                    Action::ReportMessage => {
+                        if let (Some(chat_id), Some(message)) = (
+                            self.selected_chat_id,
+                            self.conversation_model.selected_message(),
+                        ) {
+                            let label = format!(
+                                "message from {}",
+                                self.sender_display_name(message.sender_id)
+                            );
+                            self.overlays.report_dialog = Some(ReportDialog::new(
+                                ReportTarget::Message(chat_id, message.id),
+                                label,
+                            ));
+                        }
+                        return None;
+                    },
+                    Action::React => {
+                        if let (Some(chat_id), Some(message)) = (
+                            self.selected_chat_id,
+                            self.conversation_model.selected_message(),
+                        ) {
+                            let reactions =
+                                state::load_reactions(&state::reactions_file(&self.config));
+                            self.overlays.reaction_picker = Some(ReactionPicker::new(
+                                chat_id,
+                                message.id,
+                                message.my_reaction().map(ToString::to_string),
+                                reactions.quick(),
+                                reactions.favorites,
+                            ));
+                        }
+                        return None;
+                    },
+                    Action::Bookmark => {
+                        self.toggle_bookmark();
+                        return None;
+                    },
+                    Action::LocalPin => {
+                        self.toggle_local_pin();
+                        return None;
+                    },
+                    Action::JumpToUnread => {
+                        if !self.conversation_model.jump_to_first_unread() {
+                            self.set_status_message("No unread messages loaded");
+                        }
+                        return None;
+                    },
+                    Action::NextMention => {
+                        if !self.conversation_model.jump_to_next_mention() {
+                            self.set_status_message("No mentions of you in loaded messages");
+                        }
+                        return None;
+                    },
+                    Action::JumpToOriginal => return self.jump_to_original(),
+                    Action::JumpToReply => return self.jump_to_reply(),
+                    Action::JumpBack => {
+                        self.conversation_model.jump_back();
+                        return None;
+                    },
+                    Action::JumpForward => {
+                        self.conversation_model.jump_forward();
+                        return None;
+                    },
+                    Action::PreviousDay | Action::NextDay => {
+                        let later = action == Action::NextDay;
+                        if !self.conversation_model.jump_to_day(later) {
+                            self.set_status_message(if later {
+                                "Already on the latest day"
+                            } else {
+                                "No earlier day loaded"
+                            });
+                        }
                         return None;
                     },
                     // Global actions should be handled by handle_action
@@ -897,11 +3966,35 @@ impl App {
 
         // Handle message input when focused
         if self.state == AppState::Main && self.focused_pane == FocusedPane::Input {
+            // Ctrl+E hands the draft to the external editor (overrides Edit)
+            if key
+                .modifiers
+                .contains(crossterm::event::KeyModifiers::CONTROL)
+                && key.code == crossterm::event::KeyCode::Char('e')
+            {
+                return Some(AppAction::OpenEditor);
+            }
+
             // Check for special keys first
             if let Some(action) = self.keymap.get_action(&key) {
                 match action {
                     // Enter key (OpenChat) sends message when in input mode
                     Action::SendMessage | Action::OpenChat => {
+                        if let Some(command) = self.take_slash_command() {
+                            return command;
+                        }
+                        // Keep the draft rather than send it somewhere it
+                        // can only be refused
+                        if self.conversation_model.input_mode != InputMode::Edit
+                            && self
+                                .conversation_model
+                                .chat
+                                .as_ref()
+                                .is_some_and(|c| c.is_read_only)
+                        {
+                            self.set_status_message("You can't send messages in this chat");
+                            return None;
+                        }
                         // Handle send message action
                         if let Some(conv_action) =
                             self.conversation_model.handle_action(Action::SendMessage)
@@ -926,68 +4019,838 @@ impl App {
                         self.conversation_model.input.insert_char('\n');
                         return None;
                     },
+                    // Tab completes a slash command instead of switching panes
+                    Action::NextPane
+                        if slash_command::is_command(self.conversation_model.input.value()) =>
+                    {
+                        self.complete_slash_command();
+                        return None;
+                    },
+                    // The Up arrow in an empty input and Ctrl+Up/Down step
+                    // through my own messages for editing. Other keys bound to
+                    // Up, such as vim's `k`, are typed.
+                    Action::Up
+                        if key.code == crossterm::event::KeyCode::Up
+                            && key.modifiers.is_empty()
+                            && self.conversation_model.input.is_empty() =>
+                    {
+                        let _ = self.conversation_model.handle_action(action);
+                        return None;
+                    },
+                    Action::EditPrevious | Action::EditNext => {
+                        let _ = self.conversation_model.handle_action(action);
+                        return None;
+                    },
                     // Only Quit should work while typing (Ctrl+Q)
                     // Help (?) should be typed as a character
                     Action::Quit => {
                         return self.handle_action(action);
                     },
                     Action::AttachFile => {
-                        self.file_picker = Some(crate::ui::components::FilePicker::new());
+                        self.overlays.file_picker = Some(crate::ui::components::FilePicker::new());
+                        return None;
+                    },
+                    Action::ToggleCompression => {
+                        if !self.conversation_model.toggle_attachment_as_photo() {
+                            self.set_status_message("Only an attached image can go as a photo");
+                        }
                         return None;
                     },
                     _ => {},
                 }
             }
 
-            // Forward raw key events to the input component
-            self.conversation_model.input.handle_input(key);
+            // Forward raw key events to the input component
+            let before = self.conversation_model.input.value().to_string();
+            self.conversation_model.input.handle_input(key);
+            if self.conversation_model.input.value() != before {
+                return self.typing_action();
+            }
+            return None;
+        }
+
+        // Get action from keymap for other states
+        if let Some(action) = self.keymap.get_action(&key) {
+            return self.handle_action(action);
+        }
+
+        None
+    }
+
+    /// Handle key events while the file picker overlay is open.
+    fn handle_file_picker_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        use crate::ui::components::{FilePicker, FilePickerAction};
+        use crate::ui::keys::Action;
+        // Space marks files to attach together
+        if key.code == crossterm::event::KeyCode::Char(' ') && key.modifiers.is_empty() {
+            if let Some(picker) = self.overlays.file_picker.as_mut() {
+                picker.toggle_mark();
+            }
+            return None;
+        }
+        // Compute action before borrowing picker mutably, to satisfy the borrow checker.
+        let action = self.keymap.get_action(&key);
+        match action {
+            Some(Action::Up) => {
+                if let Some(picker) = self.overlays.file_picker.as_mut() {
+                    picker.select_previous();
+                }
+            },
+            Some(Action::Down) => {
+                if let Some(picker) = self.overlays.file_picker.as_mut() {
+                    picker.select_next();
+                }
+            },
+            Some(Action::CancelAction) => {
+                self.overlays.file_picker = None;
+            },
+            Some(Action::OpenChat | Action::SendMessage) => {
+                // Enter activates the current entry.
+                // Call activate() in its own scope so the mutable borrow on file_picker
+                // is dropped before we potentially reassign self.overlays.file_picker below.
+                let picker_result = self.overlays.file_picker.as_mut().map(FilePicker::activate);
+                if let Some(FilePickerAction::Selected(paths)) = picker_result {
+                    for path in paths {
+                        self.conversation_model.add_pending_attachment(path);
+                    }
+                    self.overlays.file_picker = None;
+                    self.conversation_model.input.set_focused(true);
+                    self.focused_pane = FocusedPane::Input;
+                }
+                // FilePickerAction::None means we just descended into a dir; do nothing extra.
+            },
+            _ => {},
+        }
+        None
+    }
+
+    /// Handle key events while the quick switcher overlay is open.
+    ///
+    /// Opening a chat keeps the current pane focused, so a switch from the
+    /// input leaves the cursor in the input. While forwarding, the switcher
+    /// picks one or more destinations instead.
+    fn handle_quick_switcher_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        let action = self.overlays.quick_switcher.as_mut()?.handle_input(key);
+        match action {
+            QuickSwitcherAction::None => None,
+            QuickSwitcherAction::Close => {
+                self.overlays.quick_switcher = None;
+                self.overlays.pending_forward = None;
+                None
+            },
+            QuickSwitcherAction::Open(chat_id) if self.overlays.pending_forward.is_some() => {
+                self.open_forward_dialog(vec![chat_id]);
+                None
+            },
+            QuickSwitcherAction::OpenMany(chat_ids) if self.overlays.pending_forward.is_some() => {
+                self.open_forward_dialog(chat_ids);
+                None
+            },
+            QuickSwitcherAction::Open(chat_id) => {
+                self.overlays.quick_switcher = None;
+                self.jump_to_chat(chat_id);
+                Some(AppAction::ChatSelected(chat_id))
+            },
+            // Only forwarding enables marking several chats
+            QuickSwitcherAction::OpenMany(_) => {
+                self.overlays.quick_switcher = None;
+                None
+            },
+            QuickSwitcherAction::Resolve(handle) if self.overlays.pending_forward.is_some() => {
+                self.overlays.quick_switcher = None;
+                Some(AppAction::ResolveForwardTarget(handle))
+            },
+            QuickSwitcherAction::Resolve(handle) => {
+                self.overlays.quick_switcher = None;
+                Some(AppAction::ResolveChat(handle))
+            },
+        }
+    }
+
+    /// Replaces the destination picker with the forward options dialog.
+    fn open_forward_dialog(&mut self, chat_ids: Vec<i64>) {
+        self.overlays.quick_switcher = None;
+        let label = if chat_ids.len() > 3 {
+            format!("{} chats", chat_ids.len())
+        } else {
+            chat_ids
+                .iter()
+                .map(|&id| self.chat_display_name(id))
+                .collect::<Vec<_>>()
+                .join(", ")
+        };
+        self.overlays.forward_dialog = Some(ForwardDialog::new(chat_ids, label));
+    }
+
+    /// Handle key events while the lock screen is shown.
+    ///
+    /// Ctrl+C and Ctrl+Q still quit, which reveals nothing.
+    fn handle_lock_screen_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        if key
+            .modifiers
+            .contains(crossterm::event::KeyModifiers::CONTROL)
+            && matches!(key.code, crossterm::event::KeyCode::Char('c' | 'q'))
+        {
+            self.should_quit = true;
+            return Some(AppAction::Quit);
+        }
+
+        match self.lock_screen.as_mut()?.handle_input(key) {
+            LockScreenAction::None => {},
+            LockScreenAction::Unlocked(passphrase) => {
+                self.lock_screen = None;
+                let hash = &self.config.privacy.lock_passphrase_hash;
+                self.vault = Some(Vault::derive(&passphrase, hash));
+            },
+            LockScreenAction::Cancel => self.lock_screen = None,
+            LockScreenAction::SetPassphrase(passphrase) => {
+                let hash = crate::utils::hash_passphrase(&passphrase);
+                self.config.privacy.lock_passphrase_hash = hash.clone();
+                self.persist_config();
+                self.lock_screen = Some(LockScreen::locked(hash));
+            },
+        }
+        None
+    }
+
+    /// Marks the open chat's local data to be kept encrypted, or unmarks it
+    /// (`/encrypt`). Marking it seals its bookmarks, local pins and the
+    /// media already downloaded from it.
+    fn toggle_encryption(&mut self) {
+        let Some(chat_id) = self.require_open_chat() else {
+            return;
+        };
+        if self.config.privacy.lock_passphrase_hash.is_empty() {
+            self.set_status_message("Set a lock passphrase first (Ctrl+L); the key comes from it");
+            return;
+        }
+        if self.vault.is_none() {
+            self.set_status_message("Lock and unlock once (Ctrl+L) so the key can be derived");
+            return;
+        }
+
+        let encrypted = self.config.privacy.toggle_encrypted_chat(chat_id);
+        self.persist_config();
+        if !self.reseal_state() {
+            return;
+        }
+        let name = self.chat_display_name(chat_id);
+        if !encrypted {
+            self.set_status_message(format!("{name}'s local data is no longer encrypted"));
+            return;
+        }
+        let media_dir = &self.config.cache.media_directory;
+        let sealed = self
+            .vault
+            .as_ref()
+            .map_or(Ok(0), |v| v.seal_chat_media(media_dir, chat_id));
+        match sealed {
+            Ok(0) => self.set_success_message(format!("{name}'s local data is kept encrypted")),
+            Ok(n) => self.set_success_message(format!(
+                "{name}'s local data is kept encrypted ({n} media files sealed)"
+            )),
+            Err(e) => self.set_error_message(format!("Failed to seal {name}'s media: {e}")),
+        }
+    }
+
+    /// Saves bookmarks and local pins again after the encrypted chats have
+    /// changed. Returns `false`, having said why, if that failed.
+    fn reseal_state(&mut self) -> bool {
+        let vault = self.vault.as_ref();
+        let encrypted = &self.config.privacy.encrypted_chats;
+        let bookmarks_path = state::bookmarks_file(&self.config);
+        let bookmarks = state::load_bookmarks(&bookmarks_path, vault);
+        let pins_path = state::local_pins_file(&self.config);
+        let pins = state::load_local_pins(&pins_path, vault);
+        let mut result = Ok(());
+        if !bookmarks.is_empty() {
+            result = state::save_bookmarks(&bookmarks_path, &bookmarks, vault, encrypted);
+        }
+        if !pins.is_empty() && result.is_ok() {
+            result = state::save_local_pins(&pins_path, &pins, vault, encrypted);
+        }
+        if let Err(e) = result {
+            self.set_error_message(format!("Failed to save bookmarks and local pins: {e}"));
+            return false;
+        }
+        true
+    }
+
+    /// Returns where a message's media is downloaded to: the media cache,
+    /// or for an encrypted chat the scratch directory, after decrypting a
+    /// sealed copy into it if there is one.
+    ///
+    /// Fails rather than let an encrypted chat's media out of a private
+    /// directory.
+    fn media_dir_for(&self, message: &Message) -> std::io::Result<std::path::PathBuf> {
+        if !self.config.privacy.is_encrypted(message.chat_id) {
+            return Ok(self.config.cache.media_directory.clone());
+        }
+        let scratch = vault::scratch_dir()?;
+        if let Some(vault) = &self.vault {
+            // Without a sealed copy it's downloaded again
+            let _ = vault.restore_media(
+                &self.config.cache.media_directory,
+                message.chat_id,
+                message.id,
+                &scratch,
+            );
+        }
+        scratch
+    }
+
+    /// Keeps a sealed copy of media downloaded from an encrypted chat, so
+    /// it needn't be downloaded again after the scratch directory is
+    /// emptied.
+    fn seal_downloaded(&self, chat_id: i64, message_id: i64, path: &std::path::Path) {
+        let media_dir = &self.config.cache.media_directory;
+        let Some(vault) = self.vault.as_ref() else {
+            return;
+        };
+        if !self.config.privacy.is_encrypted(chat_id)
+            || vault::sealed_media_dir(media_dir, chat_id, message_id).exists()
+        {
+            return;
+        }
+        if let Err(e) = vault.seal_media(media_dir, chat_id, message_id, path) {
+            tracing::warn!("Failed to seal media of message {}: {}", message_id, e);
+        }
+    }
+
+    /// Handle key events while the forward options dialog is open.
+    fn handle_forward_dialog_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        let dialog = self.overlays.forward_dialog.as_mut()?;
+        match dialog.handle_input(key) {
+            ForwardDialogAction::None => None,
+            ForwardDialogAction::Cancel => {
+                self.overlays.forward_dialog = None;
+                self.overlays.pending_forward = None;
+                None
+            },
+            ForwardDialogAction::Send(options) => {
+                let to_chat_ids = self.overlays.forward_dialog.take()?.to_chat_ids().to_vec();
+                let (from_chat_id, message_id) = self.overlays.pending_forward.take()?;
+                Some(AppAction::ForwardMessage(
+                    from_chat_id,
+                    message_id,
+                    to_chat_ids,
+                    options,
+                ))
+            },
+        }
+    }
+
+    /// Handle key events while the report dialog is open.
+    fn handle_report_dialog_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        match self.overlays.report_dialog.as_mut()?.handle_input(key) {
+            ReportDialogAction::None => None,
+            ReportDialogAction::Cancel => {
+                self.overlays.report_dialog = None;
+                None
+            },
+            ReportDialogAction::Report(target, reason) => {
+                self.overlays.report_dialog = None;
+                Some(AppAction::Report(target, reason))
+            },
+        }
+    }
+
+    /// Handle key events while the reaction picker is open.
+    ///
+    /// Pinning a favorite keeps the picker open with its quick row updated.
+    fn handle_reaction_picker_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        match self.overlays.reaction_picker.as_mut()?.handle_input(key) {
+            ReactionPickerAction::None => None,
+            ReactionPickerAction::Cancel => {
+                self.overlays.reaction_picker = None;
+                None
+            },
+            ReactionPickerAction::React(chat_id, message_id, reaction) => {
+                self.overlays.reaction_picker = None;
+                Some(AppAction::React(chat_id, message_id, reaction))
+            },
+            ReactionPickerAction::ToggleFavorite(reaction) => {
+                let path = state::reactions_file(&self.config);
+                let mut reactions = state::load_reactions(&path);
+                reactions.toggle_favorite(&reaction);
+                if let Err(e) = state::save_reactions(&path, &reactions) {
+                    self.set_error_message(format!("Failed to save favorite reactions: {e}"));
+                }
+                if let Some(picker) = self.overlays.reaction_picker.as_mut() {
+                    picker.set_quick(reactions.quick(), reactions.favorites);
+                }
+                None
+            },
+        }
+    }
+
+    /// Handle key events while the permissions editor is open.
+    fn handle_permissions_editor_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        match self.overlays.permissions_editor.as_mut()?.handle_input(key) {
+            PermissionsEditorAction::None => None,
+            PermissionsEditorAction::Cancel => {
+                self.overlays.permissions_editor = None;
+                None
+            },
+            PermissionsEditorAction::Apply(chat_id, permissions) => {
+                self.overlays.permissions_editor = None;
+                Some(AppAction::SetPermissions(chat_id, permissions))
+            },
+        }
+    }
+
+    /// Continues a leader sequence with `key`.
+    ///
+    /// Any key that isn't a plain character (such as `Esc`) cancels the
+    /// sequence.
+    fn handle_leader_key(&mut self, mut typed: String, key: KeyEvent) -> Option<AppAction> {
+        let crossterm::event::KeyCode::Char(c) = key.code else {
+            return None;
+        };
+        if key.modifiers.intersects(
+            crossterm::event::KeyModifiers::CONTROL | crossterm::event::KeyModifiers::ALT,
+        ) {
+            return None;
+        }
+        typed.push(c);
+
+        match self.leader.step(&typed) {
+            LeaderStep::Pending => {
+                self.leader_pending = Some(typed);
+                None
+            },
+            LeaderStep::Run(LeaderCommand::Action(action)) => self.handle_action(action),
+            LeaderStep::Run(LeaderCommand::Slash(text)) => match slash_command::parse(&text)? {
+                Ok(command) => Some(AppAction::Command(command)),
+                Err(message) => {
+                    self.set_status_message(message);
+                    None
+                },
+            },
+            LeaderStep::Unbound => {
+                let keys: Vec<String> = typed.chars().map(String::from).collect();
+                self.set_status_message(format!(
+                    "Nothing bound to {} {}",
+                    self.leader.leader_label(),
+                    keys.join(" ")
+                ));
+                None
+            },
+        }
+    }
+
+    /// Handle key events while the poll overlay is open.
+    fn handle_poll_view_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        let view = self.overlays.poll_view.as_mut()?;
+        let (chat_id, message_id) = (view.chat_id(), view.message_id());
+        match view.handle_input(key) {
+            PollViewAction::None => None,
+            PollViewAction::Close => {
+                self.overlays.poll_view = None;
+                None
+            },
+            PollViewAction::Vote(options) => {
+                Some(AppAction::VotePoll(chat_id, message_id, options))
+            },
+            PollViewAction::LoadVoters { option, offset } => Some(AppAction::LoadPollVoters(
+                chat_id, message_id, option, offset,
+            )),
+        }
+    }
+
+    /// Handle key events while a Yes/No prompt is open.
+    ///
+    /// `y` and `n` answer directly; otherwise `←`/`→` pick a button and
+    /// `Enter` presses it.
+    fn handle_confirmation_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        use crossterm::event::KeyCode;
+
+        let (modal, _) = self.overlays.confirmation.as_mut()?;
+        let confirmed = match key.code {
+            KeyCode::Char('y' | 'Y') => true,
+            KeyCode::Char('n' | 'N') | KeyCode::Esc => false,
+            KeyCode::Enter => modal.is_confirmed(),
+            KeyCode::Left | KeyCode::Char('h') => {
+                modal.select_previous();
+                return None;
+            },
+            KeyCode::Right | KeyCode::Char('l') | KeyCode::Tab => {
+                modal.select_next();
+                return None;
+            },
+            _ => return None,
+        };
+
+        let (_, action) = self.overlays.confirmation.take()?;
+        confirmed.then_some(action)
+    }
+
+    /// Handle key events while the reactions feed is open.
+    fn handle_reactions_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        match self.reactions.handle_input(key) {
+            ReactionsFeedAction::None => None,
+            ReactionsFeedAction::Close => {
+                self.overlays.show_reactions = false;
+                None
+            },
+            ReactionsFeedAction::Jump(chat_id, message_id) => {
+                self.overlays.show_reactions = false;
+                Some(AppAction::JumpToMessage(chat_id, message_id))
+            },
+        }
+    }
+
+    /// Handle key events while the pin board is open.
+    fn handle_pin_board_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        let board = self.overlays.pin_board.as_mut()?;
+        let chat_id = board.chat_id();
+        match board.handle_input(key) {
+            PinBoardAction::None => None,
+            PinBoardAction::Close => {
+                self.overlays.pin_board = None;
+                None
+            },
+            PinBoardAction::Jump(message_id) => {
+                self.overlays.pin_board = None;
+                Some(AppAction::JumpToMessage(chat_id, message_id))
+            },
+            PinBoardAction::Unpin(message_id) => {
+                let path = state::local_pins_file(&self.config);
+                let mut pins = state::load_local_pins(&path, self.vault.as_ref());
+                pins.retain(|p| (p.chat_id, p.message_id) != (chat_id, message_id));
+                match state::save_local_pins(
+                    &path,
+                    &pins,
+                    self.vault.as_ref(),
+                    &self.config.privacy.encrypted_chats,
+                ) {
+                    Ok(()) => {
+                        pins.retain(|p| p.chat_id == chat_id);
+                        if let Some(board) = self.overlays.pin_board.as_mut() {
+                            board.set_pins(pins);
+                        }
+                    },
+                    Err(e) => self.set_error_message(format!("Failed to save local pins: {e}")),
+                }
+                None
+            },
+        }
+    }
+
+    /// Handle key events while the bookmark list is open.
+    fn handle_bookmark_list_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        match self.overlays.bookmark_list.as_mut()?.handle_input(key) {
+            BookmarkListAction::None => None,
+            BookmarkListAction::Close => {
+                self.overlays.bookmark_list = None;
+                None
+            },
+            BookmarkListAction::Jump(chat_id, message_id) => {
+                self.overlays.bookmark_list = None;
+                Some(AppAction::JumpToMessage(chat_id, message_id))
+            },
+            BookmarkListAction::Remove(chat_id, message_id) => {
+                let path = state::bookmarks_file(&self.config);
+                let mut bookmarks = state::load_bookmarks(&path, self.vault.as_ref());
+                bookmarks.retain(|b| (b.chat_id, b.message_id) != (chat_id, message_id));
+                match state::save_bookmarks(
+                    &path,
+                    &bookmarks,
+                    self.vault.as_ref(),
+                    &self.config.privacy.encrypted_chats,
+                ) {
+                    Ok(()) => {
+                        if let Some(list) = self.overlays.bookmark_list.as_mut() {
+                            list.set_bookmarks(bookmarks);
+                        }
+                    },
+                    Err(e) => self.set_error_message(format!("Failed to save bookmarks: {e}")),
+                }
+                None
+            },
+        }
+    }
+
+    /// Handle key events while the inbox is open.
+    fn handle_inbox_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        match self.overlays.inbox.as_mut()?.handle_input(key) {
+            InboxAction::None => None,
+            InboxAction::Close => {
+                self.overlays.inbox = None;
+                None
+            },
+            InboxAction::Jump(chat_id, message_id) => {
+                self.overlays.inbox = None;
+                Some(AppAction::JumpToMessage(chat_id, message_id))
+            },
+            InboxAction::MarkRead(chat_id) => Some(AppAction::MarkAsRead(vec![chat_id])),
+        }
+    }
+
+    /// Handle key events while search results are open.
+    fn handle_search_results_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        match self.overlays.search_results.as_mut()?.handle_input(key) {
+            SearchResultsAction::None => None,
+            SearchResultsAction::Close => {
+                self.overlays.search_results = None;
+                None
+            },
+            SearchResultsAction::OpenChat(chat_id) => {
+                self.overlays.search_results = None;
+                self.jump_to_chat(chat_id);
+                Some(AppAction::ChatSelected(chat_id))
+            },
+            SearchResultsAction::Jump(chat_id, message_id) => {
+                self.overlays.search_results = None;
+                Some(AppAction::JumpToMessage(chat_id, message_id))
+            },
+        }
+    }
+
+    /// Handle key events while the date prompt is open.
+    fn handle_date_prompt_key(&mut self, key: KeyEvent) -> Option<AppAction> {
+        let today = chrono::Local::now().date_naive();
+        match self.overlays.date_prompt.as_mut()?.handle_input(key, today) {
+            DatePromptAction::None => None,
+            DatePromptAction::Close => {
+                self.overlays.date_prompt = None;
+                None
+            },
+            DatePromptAction::Jump(date) => {
+                self.overlays.date_prompt = None;
+                self.selected_chat_id
+                    .map(|chat_id| AppAction::JumpToDate(chat_id, date))
+            },
+        }
+    }
+
+    /// Returns a typing notification for the open chat if one is due.
+    ///
+    /// Throttled to one per [`TYPING_REFRESH`] per chat, and never sent when
+    /// typing indicators are off (including in stealth mode).
+    fn typing_action(&mut self) -> Option<AppAction> {
+        if !self.config.privacy.sends_typing() || self.conversation_model.input.is_empty() {
+            return None;
+        }
+        let chat_id = self.selected_chat_id?;
+        if let Some((last_chat, at)) = self.last_typing_sent {
+            if last_chat == chat_id && at.elapsed() < TYPING_REFRESH {
+                return None;
+            }
+        }
+        self.last_typing_sent = Some((chat_id, Instant::now()));
+        Some(AppAction::SendTyping(chat_id))
+    }
+
+    /// Splits the conversation view, or closes the split.
+    ///
+    /// Splitting keeps the open chat on the left and focuses an empty right
+    /// side, to be filled from the chat list. Closing keeps the focused
+    /// conversation, or the other one if no chat was picked for it.
+    fn toggle_split(&mut self) {
+        if let Some(split) = self.split.take() {
+            if self.selected_chat_id.is_none() {
+                self.conversation_model = split.model;
+                self.selected_chat_id = split.chat_id;
+            }
+            return;
+        }
+        let Some(chat_id) = self.selected_chat_id.take() else {
+            self.set_status_message("Open a chat to split the view");
+            return;
+        };
+
+        let mut model = std::mem::take(&mut self.conversation_model);
+        self.conversation_model
+            .set_compress_images(self.config.ui.behavior.compress_images);
+        model.input.set_focused(false);
+        model.clear_action_state();
+        self.split = Some(SplitConversation {
+            chat_id: Some(chat_id),
+            model,
+            on_right: false,
+        });
+        self.focused_pane = FocusedPane::ChatList;
+        self.chat_list_model.set_focused(true);
+        self.set_status_message("Pick a chat for the right side (Alt+W switches, Alt+V closes)");
+    }
+
+    /// Moves focus to the other side of the split view.
+    fn switch_split(&mut self) {
+        let Some(split) = self.split.as_mut() else {
+            self.set_status_message("The view isn't split (Alt+V splits it)");
+            return;
+        };
+
+        self.conversation_model.input.set_focused(false);
+        self.conversation_model.clear_action_state();
+        std::mem::swap(&mut self.conversation_model, &mut split.model);
+        std::mem::swap(&mut self.selected_chat_id, &mut split.chat_id);
+        split.on_right = !split.on_right;
+
+        if let Some(chat_id) = self.selected_chat_id {
+            self.chat_list_model.select_chat(chat_id);
+            self.focused_pane = FocusedPane::Conversation;
+            self.chat_list_model.set_focused(false);
+        } else {
+            self.focused_pane = FocusedPane::ChatList;
+            self.chat_list_model.set_focused(true);
+        }
+    }
+
+    /// Returns the unfocused side of the split view if it shows `chat_id`.
+    fn split_conversation_for(&mut self, chat_id: i64) -> Option<&mut ConversationModel> {
+        self.split
+            .as_mut()
+            .filter(|split| split.chat_id == Some(chat_id))
+            .map(|split| &mut split.model)
+    }
+
+    /// Makes `chat_id` the open chat and highlights it in the chat list.
+    ///
+    /// The caller is responsible for loading the chat's messages.
+    fn jump_to_chat(&mut self, chat_id: i64) {
+        self.selected_chat_id = Some(chat_id);
+        self.chat_list_model.select_chat(chat_id);
+        self.chat_list_model.clear_new_message(chat_id);
+    }
+
+    /// Selects the message the selected one replies to, loading older
+    /// history if it isn't loaded. `JumpBack` returns to the reply.
+    fn jump_to_reply(&mut self) -> Option<AppAction> {
+        let chat_id = self.selected_chat_id?;
+        let Some(reply_to) = self.conversation_model.replied_message_id() else {
+            self.set_status_message("Not a reply");
+            return None;
+        };
+        if self.conversation_model.jump_to_message(reply_to) {
+            self.conversation_model
+                .flash_message(reply_to, Instant::now());
             return None;
         }
+        Some(AppAction::JumpToReply(chat_id, reply_to))
+    }
 
-        // Get action from keymap for other states
-        if let Some(action) = self.keymap.get_action(&key) {
-            return self.handle_action(action);
+    /// Bookmarks the selected message, or removes its bookmark if it has
+    /// one.
+    fn toggle_bookmark(&mut self) {
+        let (Some(chat_id), Some(message)) = (
+            self.selected_chat_id,
+            self.conversation_model.selected_message(),
+        ) else {
+            return;
+        };
+        let message_id = message.id;
+        let path = state::bookmarks_file(&self.config);
+        let mut bookmarks = state::load_bookmarks(&path, self.vault.as_ref());
+        let before = bookmarks.len();
+        bookmarks.retain(|b| (b.chat_id, b.message_id) != (chat_id, message_id));
+        let added = bookmarks.len() == before;
+        if added {
+            let excerpt = crate::utils::truncate_string(&message.content.preview(), 200);
+            bookmarks.insert(
+                0,
+                state::Bookmark {
+                    chat_id,
+                    message_id,
+                    chat: self.chat_display_name(chat_id),
+                    sender: self.sender_display_name(message.sender_id),
+                    excerpt,
+                    date: message.date,
+                    sealed: String::new(),
+                },
+            );
         }
+        match state::save_bookmarks(
+            &path,
+            &bookmarks,
+            self.vault.as_ref(),
+            &self.config.privacy.encrypted_chats,
+        ) {
+            Ok(()) if added => self.set_success_message("Bookmarked (Alt+B to list)"),
+            Ok(()) => self.set_status_message("Bookmark removed"),
+            Err(e) => self.set_error_message(format!("Failed to save bookmarks: {e}")),
+        }
+    }
 
-        None
+    /// Pins the selected message on this device, or unpins it if it's
+    /// pinned.
+    fn toggle_local_pin(&mut self) {
+        let (Some(chat_id), Some(message)) = (
+            self.selected_chat_id,
+            self.conversation_model.selected_message(),
+        ) else {
+            return;
+        };
+        let message_id = message.id;
+        let path = state::local_pins_file(&self.config);
+        let mut pins = state::load_local_pins(&path, self.vault.as_ref());
+        let before = pins.len();
+        pins.retain(|p| (p.chat_id, p.message_id) != (chat_id, message_id));
+        let added = pins.len() == before;
+        if added {
+            let excerpt = crate::utils::truncate_string(&message.content.preview(), 200);
+            pins.insert(
+                0,
+                state::LocalPin {
+                    chat_id,
+                    message_id,
+                    sender: self.sender_display_name(message.sender_id),
+                    excerpt,
+                    date: message.date,
+                    sealed: String::new(),
+                },
+            );
+        }
+        match state::save_local_pins(
+            &path,
+            &pins,
+            self.vault.as_ref(),
+            &self.config.privacy.encrypted_chats,
+        ) {
+            Ok(()) if added => self.set_success_message("Pinned locally (Alt+P to list)"),
+            Ok(()) => self.set_status_message("Local pin removed"),
+            Err(e) => self.set_error_message(format!("Failed to save local pins: {e}")),
+        }
     }
 
-    /// Handle key events while the file picker overlay is open.
-    fn handle_file_picker_key(&mut self, key: KeyEvent) -> Option<AppAction> {
-        use crate::ui::components::{FilePicker, FilePickerAction};
-        use crate::ui::keys::Action;
-        // Compute action before borrowing picker mutably, to satisfy the borrow checker.
-        let action = self.keymap.get_action(&key);
-        match action {
-            Some(Action::Up) => {
-                if let Some(picker) = self.file_picker.as_mut() {
-                    picker.select_previous();
-                }
-            },
-            Some(Action::Down) => {
-                if let Some(picker) = self.file_picker.as_mut() {
-                    picker.select_next();
-                }
-            },
-            Some(Action::CancelAction) => {
-                self.file_picker = None;
+    /// Opens the message the selected forward was copied from, in its
+    /// source chat, if that chat is in the chat list.
+    fn jump_to_original(&mut self) -> Option<AppAction> {
+        let info = self
+            .conversation_model
+            .selected_message()?
+            .forward_info
+            .as_ref();
+        let Some((chat_id, message_id)) = info.and_then(ForwardInfo::source) else {
+            self.set_status_message("No original message to jump to");
+            return None;
+        };
+        if self.cache.get_chat(chat_id).is_none() {
+            self.set_status_message("The original chat isn't in your chat list");
+            return None;
+        }
+        Some(AppAction::JumpToMessage(chat_id, message_id))
+    }
+
+    /// Runs an entry picked from the chat actions menu.
+    fn run_chat_menu_item(&mut self, chat_id: i64, item: ChatMenuItem) -> Option<AppAction> {
+        match item {
+            ChatMenuItem::Search => {
+                self.conversation_model.input.set_value("/search ");
+                self.conversation_model.input.set_focused(true);
+                self.focused_pane = FocusedPane::Input;
+                None
             },
-            Some(Action::OpenChat | Action::SendMessage) => {
-                // Enter activates the current entry.
-                // Call activate() in its own scope so the mutable borrow on file_picker
-                // is dropped before we potentially reassign self.file_picker below.
-                let picker_result = self.file_picker.as_mut().map(FilePicker::activate);
-                if let Some(FilePickerAction::Selected(path)) = picker_result {
-                    self.conversation_model.set_pending_attachment(path);
-                    self.file_picker = None;
-                    self.conversation_model.input.set_focused(true);
-                    self.focused_pane = FocusedPane::Input;
-                }
-                // FilePickerAction::None means we just descended into a dir; do nothing extra.
+            ChatMenuItem::SharedMedia => Some(AppAction::FindInChat(chat_id, SearchFilter::Media)),
+            ChatMenuItem::Pinned => Some(AppAction::FindInChat(chat_id, SearchFilter::Pinned)),
+            ChatMenuItem::Members => Some(AppAction::LoadMembers(chat_id)),
+            ChatMenuItem::Info => {
+                self.show_sidebar = true;
+                None
             },
-            _ => {},
+            ChatMenuItem::OpenInTelegram => Some(AppAction::OpenInTelegram(chat_id, None)),
         }
-        None
     }
 
     /// Handle key events in the Settings state.
@@ -1002,6 +4865,19 @@ impl App {
             return None;
         }
 
+        // While editing a value, plain characters are text even when they
+        // are bound to an action (e.g. `S`, `?`, or `j`/`k` in Vim mode)
+        if self.settings_model.is_editing() {
+            if let crossterm::event::KeyCode::Char(c) = key.code {
+                if key.modifiers.is_empty()
+                    || key.modifiers == crossterm::event::KeyModifiers::SHIFT
+                {
+                    self.settings_model.handle_char(c);
+                    return None;
+                }
+            }
+        }
+
         // Map key to action and forward to settings model
         if let Some(action) = self.keymap.get_action(&key) {
             // Only forward relevant actions; block global actions except Quit
@@ -1046,6 +4922,16 @@ impl App {
             },
             SettingsAction::SaveAndClose(config) => {
                 self.config = *config;
+                self.chat_list_model
+                    .set_blur_previews(self.config.privacy.hides_previews());
+                self.chat_list_model.set_preview_format(
+                    self.config.ui.appearance.message_preview_length,
+                    self.config.ui.appearance.message_preview_lines,
+                );
+                self.chat_list_model
+                    .set_sections(self.config.ui.layout.chat_list_sections);
+                self.chat_list_model
+                    .set_show_avatars(self.config.ui.appearance.show_avatars);
                 self.state = AppState::Main;
             },
             SettingsAction::ThemeChanged(config) => {
@@ -1062,25 +4948,19 @@ impl App {
 
         // Validate before saving
         if let Err(e) = new_config.validate() {
-            self.set_status_message(format!("Invalid config: {e}"));
+            self.set_error_message(format!("Invalid config: {e}"));
             return;
         }
 
-        // Save to ~/.config/ithil/config.yaml
-        let config_path = dirs::home_dir()
-            .unwrap_or_default()
-            .join(".config")
-            .join("ithil")
-            .join("config.yaml");
-
-        match new_config.save(&config_path) {
+        // Save to the file the config came from
+        match new_config.save(&self.config_file) {
             Ok(()) => {
                 self.config = new_config;
                 self.settings_model.has_changes = false;
-                self.set_status_message("Settings saved".to_string());
+                self.set_success_message("Settings saved".to_string());
             },
             Err(e) => {
-                self.set_status_message(format!("Failed to save settings: {e}"));
+                self.set_error_message(format!("Failed to save settings: {e}"));
             },
         }
     }
@@ -1093,13 +4973,13 @@ impl App {
                 Some(AppAction::Quit)
             },
             Action::Help => {
-                self.show_help = !self.show_help;
+                self.overlays.show_help = !self.overlays.show_help;
                 None
             },
             Action::ToggleSidebar => {
                 self.show_sidebar = !self.show_sidebar;
                 // If we were focused on sidebar and it's now hidden, move focus
-                if !self.show_sidebar && self.focused_pane == FocusedPane::Sidebar {
+                if !self.sidebar_visible() && self.focused_pane == FocusedPane::Sidebar {
                     self.focused_pane = FocusedPane::Conversation;
                 }
                 None
@@ -1123,7 +5003,7 @@ impl App {
                 None
             },
             Action::FocusSidebar => {
-                if self.show_sidebar {
+                if self.sidebar_visible() {
                     self.focused_pane = FocusedPane::Sidebar;
                     self.chat_list_model.set_focused(false);
                 }
@@ -1139,6 +5019,126 @@ impl App {
                 self.state = AppState::Settings;
                 None
             },
+            Action::ToggleStealth => {
+                let privacy = &mut self.config.privacy;
+                privacy.stealth_mode = !privacy.stealth_mode;
+                let on = privacy.stealth_mode;
+                self.chat_list_model
+                    .set_blur_previews(self.config.privacy.hides_previews());
+                self.persist_config();
+                self.set_status_message(if on {
+                    "Stealth mode on: no read receipts, typing, or online status"
+                } else {
+                    "Stealth mode off"
+                });
+                None
+            },
+            Action::Lock => {
+                self.lock();
+                None
+            },
+            Action::MarkAsRead => {
+                let chat_id = if self.focused_pane == FocusedPane::ChatList {
+                    self.chat_list_model.get_selected_chat_id()
+                } else {
+                    self.selected_chat_id
+                };
+                chat_id.map(|id| AppAction::MarkAsRead(vec![id]))
+            },
+            Action::MarkAllAsRead => {
+                self.confirm_mark_all_as_read();
+                None
+            },
+            Action::JumpToDate => {
+                if self.require_open_chat().is_some() {
+                    self.overlays.show_help = false;
+                    self.overlays.date_prompt = Some(DatePrompt::new());
+                }
+                None
+            },
+            Action::ShowReactions => {
+                self.overlays.show_help = false;
+                self.reactions.mark_seen();
+                self.overlays.show_reactions = true;
+                None
+            },
+            Action::ShowInbox => {
+                self.overlays.show_help = false;
+                Some(AppAction::OpenInbox)
+            },
+            Action::ShowBookmarks => {
+                self.overlays.show_help = false;
+                let bookmarks = state::load_bookmarks(
+                    &state::bookmarks_file(&self.config),
+                    self.vault.as_ref(),
+                );
+                self.overlays.bookmark_list = Some(BookmarkList::new(bookmarks));
+                None
+            },
+            Action::ShowLocalPins => {
+                self.overlays.show_help = false;
+                let Some(chat_id) = self.selected_chat_id else {
+                    self.set_status_message("Open a chat to see its local pins");
+                    return None;
+                };
+                let mut pins = state::load_local_pins(
+                    &state::local_pins_file(&self.config),
+                    self.vault.as_ref(),
+                );
+                pins.retain(|p| p.chat_id == chat_id);
+                let chat = self.chat_display_name(chat_id);
+                self.overlays.pin_board = Some(PinBoard::new(chat_id, chat, pins));
+                None
+            },
+            Action::ShowErrors => {
+                self.overlays.show_help = false;
+                self.toasts.mark_seen();
+                self.overlays.error_log = Some(ErrorLog::new(self.toasts.history()));
+                None
+            },
+            Action::ChatActions => {
+                self.overlays.show_help = false;
+                let chat_id = self.require_open_chat()?;
+                let has_members = self
+                    .cache
+                    .get_chat(chat_id)
+                    .is_some_and(|c| c.chat_type != ChatType::Private);
+                let title = self.chat_display_name(chat_id);
+                self.overlays.chat_actions = Some(ChatActions::new(chat_id, title, has_members));
+                None
+            },
+            Action::OpenInTelegram => {
+                self.overlays.show_help = false;
+                // The highlighted chat from the list, else the open chat at
+                // the selected message
+                if self.focused_pane == FocusedPane::ChatList {
+                    let chat_id = self.chat_list_model.get_selected_chat_id()?;
+                    return Some(AppAction::OpenInTelegram(chat_id, None));
+                }
+                let chat_id = self.require_open_chat()?;
+                let message_id = if self.focused_pane == FocusedPane::Conversation {
+                    self.conversation_model.selected_message().map(|m| m.id)
+                } else {
+                    None
+                };
+                Some(AppAction::OpenInTelegram(chat_id, message_id))
+            },
+            Action::ToggleSplit => {
+                self.toggle_split();
+                None
+            },
+            Action::SwitchSplit => {
+                self.switch_split();
+                None
+            },
+            Action::QuickSwitch => {
+                self.overlays.show_help = false;
+                self.overlays.quick_switcher = Some(QuickSwitcher::new(
+                    self.chat_list_model.chats(),
+                    self.chat_list_model.aliases(),
+                ));
+                None
+            },
             Action::CancelAction => {
                 match self.state {
                     AppState::Auth => {
@@ -1147,8 +5147,8 @@ impl App {
                     },
                     _ => {
                         // Clear help overlay if visible
-                        if self.show_help {
-                            self.show_help = false;
+                        if self.overlays.show_help {
+                            self.overlays.show_help = false;
                         }
                     },
                 }
@@ -1171,6 +5171,10 @@ impl App {
         for update in updates {
             self.handle_update(update);
         }
+        self.finish_sends();
+        self.finish_mark_read();
+        self.finish_inbox_fetch();
+        self.refresh_if_due(Instant::now());
     }
 
     /// Process pending Telegram updates from the channel.
@@ -1184,36 +5188,97 @@ impl App {
             collected
         });
 
-        // Track whether we received new messages for the active chat
-        let mut should_mark_read = false;
-
-        // Now process all collected updates
+        // Note new messages arriving in the chat being viewed
+        let now = Instant::now();
         for update in &updates {
-            if update.update_type == UpdateType::NewMessage
-                && self.selected_chat_id == Some(update.chat_id)
-                && self.focused_pane != FocusedPane::ChatList
-            {
-                should_mark_read = true;
+            if update.update_type == UpdateType::NewMessage && self.viewing(update.chat_id) {
+                match self.unread_in_view_since {
+                    Some((chat_id, _)) if chat_id == update.chat_id => {},
+                    _ => self.unread_in_view_since = Some((update.chat_id, now)),
+                }
             }
         }
 
         for update in updates {
             self.handle_update(update);
         }
+        self.finish_sends();
+        self.finish_mark_read();
+        self.finish_inbox_fetch();
+
+        self.apply_batched(now).await;
+        self.check_voice_player().await;
+    }
 
-        // Mark active chat as read if we got new messages while viewing it
-        if should_mark_read {
-            if let Some(chat_id) = self.selected_chat_id {
-                if let Err(e) = self.telegram.mark_as_read(chat_id).await {
-                    tracing::warn!("Failed to mark chat {} as read: {}", chat_id, e);
+    /// Marks the open chat read and refreshes the chat list, if updates
+    /// have waited out [`REFRESH_WINDOW`] by `now`, and searches Telegram
+    /// for the quick switcher's query once typing has paused.
+    async fn apply_batched(&mut self, now: Instant) {
+        // Mark the chat read if new messages came in while viewing it and
+        // it's still in view: the user may have moved on or locked the
+        // screen while the window ran
+        if waited_out(self.unread_in_view_since.map(|(_, since)| since), now) {
+            if let Some((chat_id, _)) = self.unread_in_view_since.take() {
+                if self.viewing(chat_id) && self.config.privacy.sends_read_receipts() {
+                    if let Err(e) = self.telegram.mark_as_read(chat_id).await {
+                        tracing::warn!("Failed to mark chat {} as read: {}", chat_id, e);
+                    }
+                    self.list_stale_since = None;
+                    self.refresh_chat_list();
                 }
-                self.refresh_chat_list();
             }
         }
+
+        self.refresh_if_due(now);
+        self.search_for_switcher(now).await;
+    }
+
+    /// Returns `true` if `chat_id` is open with its messages in front of
+    /// the user.
+    fn viewing(&self, chat_id: i64) -> bool {
+        self.selected_chat_id == Some(chat_id)
+            && self.focused_pane != FocusedPane::ChatList
+            && self.lock_screen.is_none()
+    }
+
+    /// Keeps a one-line summary of `update` for `/dump`. Message text is
+    /// left out.
+    fn note_update(&mut self, update: &Update) {
+        if self.recent_updates.len() == DUMP_UPDATES {
+            self.recent_updates.pop_front();
+        }
+        let message = update
+            .message
+            .as_ref()
+            .map_or_else(String::new, |m| format!(" message {}", m.id));
+        self.recent_updates.push_back(format!(
+            "{} {:?} chat {}{message}",
+            chrono::Local::now().format("%H:%M:%S%.3f"),
+            update.update_type,
+            update.chat_id
+        ));
+    }
+
+    /// Notes that the chat list is behind the cache. It's refreshed once
+    /// the updates arriving with this one have been gathered.
+    fn mark_list_stale(&mut self) {
+        self.list_stale_since.get_or_insert_with(Instant::now);
+    }
+
+    /// Refreshes the chat list if it has been stale for [`REFRESH_WINDOW`]
+    /// by `now`.
+    fn refresh_if_due(&mut self, now: Instant) {
+        if waited_out(self.list_stale_since, now) {
+            self.list_stale_since = None;
+            self.refresh_chat_list();
+        }
     }
 
     /// Handle a single Telegram update.
     pub fn handle_update(&mut self, update: Update) {
+        if self.config.logging.debug {
+            self.note_update(&update);
+        }
         let is_selected_chat = self.selected_chat_id == Some(update.chat_id);
 
         match update.update_type {
@@ -1221,64 +5286,148 @@ impl App {
                 if let Some(msg) = update.message {
                     let msg = *msg;
                     self.cache.add_message(update.chat_id, msg.clone());
+                    self.chat_list_model.clear_typing(update.chat_id);
+                    if let Some(queue) = self.listening.as_mut() {
+                        queue.offer(&msg);
+                    }
+                    let highlighted = !msg.is_outgoing
+                        && self
+                            .config
+                            .highlights
+                            .words
+                            .find(msg.content.text_or_caption())
+                            .is_some();
+                    if highlighted && !is_selected_chat {
+                        self.chat_list_model.set_highlighted(update.chat_id, true);
+                    }
                     // Notify the user if an incoming message arrived while the
-                    // terminal is unfocused (gated by config + per-chat mute).
-                    if !msg.is_outgoing
-                        && crate::utils::should_notify(
+                    // terminal is unfocused (gated by config + per-chat mute,
+                    // which highlight words may override).
+                    let alert = highlighted
+                        && self.config.highlights.notify
+                        && crate::utils::should_alert(
                             self.terminal_focused,
                             &self.config.notifications,
+                        );
+                    if !msg.is_outgoing
+                        && (alert
+                            || crate::utils::should_notify(
+                                self.terminal_focused,
+                                &self.config.notifications,
+                                update.chat_id,
+                                self.cache
+                                    .get_chat(update.chat_id)
+                                    .is_some_and(|c| c.is_muted),
+                            ))
+                    {
+                        let body = self.notification_body(&msg, update.chat_id);
+                        crate::utils::send_notification(&body, self.config.notifications.sound);
+                    }
+                    // The bell and flash say nothing of the message, so they
+                    // are safe in stealth mode and on the lock screen
+                    if !msg.is_outgoing
+                        && crate::utils::should_ring(
+                            is_selected_chat && self.terminal_focused && self.lock_screen.is_none(),
+                            &self.config.notifications,
                             update.chat_id,
                             self.cache
                                 .get_chat(update.chat_id)
                                 .is_some_and(|c| c.is_muted),
                         )
                     {
-                        let sender = self
-                            .cache
-                            .get_user(msg.sender_id)
-                            .map(|u| u.get_display_name())
-                            .filter(|n| !n.is_empty())
-                            .or_else(|| self.cache.get_chat(update.chat_id).map(|c| c.title))
-                            .unwrap_or_else(|| "New message".to_string());
-                        let preview = msg.content.preview();
-                        // Reuse the chat-list preview length; notifications have no
-                        // dedicated setting yet.
-                        let limit = self.config.ui.appearance.message_preview_length;
-                        let preview = crate::utils::truncate_string(&preview, limit);
-                        crate::utils::send_notification(
-                            &format!("{sender}: {preview}"),
-                            self.config.notifications.sound,
-                        );
+                        if self.config.notifications.bell {
+                            crate::utils::ring_bell();
+                        }
+                        if self.config.notifications.flash {
+                            self.flash_until = Some(Instant::now() + FLASH_DURATION);
+                        }
+                    }
+                    if !msg.is_outgoing && !self.config.hooks.is_empty() {
+                        let chat = self.cache.get_chat(update.chat_id).unwrap_or(Chat {
+                            id: update.chat_id,
+                            ..Chat::default()
+                        });
+                        let sender = self.sender_display_name(msg.sender_id);
+                        let locked = self.lock_screen.is_some();
+                        crate::app::hooks::run(&self.config.hooks, &chat, &msg, &sender, locked);
+                    }
+                    // Update conversation views showing this chat, swapping
+                    // out the optimistic copy if this confirms a send
+                    let replaces = match update.data {
+                        crate::types::UpdateData::Integer(local_id) => Some(local_id),
+                        _ => None,
+                    };
+                    if let Some(model) = self.split_conversation_for(update.chat_id) {
+                        match replaces {
+                            Some(local_id) => model.replace_message(local_id, msg.clone()),
+                            None => model.add_message(msg.clone()),
+                        }
                     }
-                    // Update conversation view if this is the active chat
                     if is_selected_chat {
-                        self.conversation_model.add_message(msg);
+                        match replaces {
+                            Some(local_id) => {
+                                self.conversation_model.replace_message(local_id, msg)
+                            },
+                            None => self.conversation_model.add_message(msg),
+                        }
                     }
                     // Refresh chat list to update last message / order
-                    self.refresh_chat_list();
+                    self.mark_list_stale();
                 }
             },
             UpdateType::MessageEdited => {
                 if let Some(msg) = update.message {
                     let msg = *msg;
                     self.cache.update_message(update.chat_id, msg.clone());
+                    if let Some(model) = self.split_conversation_for(update.chat_id) {
+                        model.update_message(msg.clone());
+                    }
                     if is_selected_chat {
                         self.conversation_model.update_message(msg);
                     }
                 }
             },
             UpdateType::MessageDeleted => {
-                if let crate::types::UpdateData::Integer(msg_id) = update.data {
-                    self.cache.delete_message(update.chat_id, msg_id);
-                    if is_selected_chat {
-                        self.conversation_model.delete_message(msg_id);
+                if let crate::types::UpdateData::MessageIds(msg_ids) = update.data {
+                    for msg_id in msg_ids {
+                        self.cache.delete_message(update.chat_id, msg_id);
+                        if let Some(model) = self.split_conversation_for(update.chat_id) {
+                            model.delete_message(msg_id);
+                        }
+                        if is_selected_chat {
+                            self.conversation_model.delete_message(msg_id);
+                        }
+                    }
+                    self.mark_list_stale();
+                }
+            },
+            // Reads, pins and deletions from other devices have already
+            // been applied to the cache
+            UpdateType::ChatReadInbox => {
+                if let crate::types::UpdateData::Integer(max_id) = update.data {
+                    self.conversation_model
+                        .set_read_elsewhere(update.chat_id, max_id);
+                }
+                let chat = self.cache.get_chat(update.chat_id);
+                if chat.as_ref().is_some_and(|c| c.unread_count == 0) {
+                    if let Some(inbox) = self.overlays.inbox.as_mut() {
+                        inbox.remove_chat(update.chat_id);
                     }
                 }
+                if is_selected_chat && chat.is_some() {
+                    self.conversation_model.chat = chat;
+                }
+                self.mark_list_stale();
+            },
+            UpdateType::Membership => {
+                if let crate::types::UpdateData::Membership(change) = update.data {
+                    self.note_membership(update.chat_id, change);
+                }
             },
             UpdateType::NewChat => {
                 if let crate::types::UpdateData::Chat(chat) = update.data {
                     self.cache.set_chat(*chat);
-                    self.refresh_chat_list();
+                    self.mark_list_stale();
                 }
             },
             UpdateType::UserStatus => {
@@ -1286,16 +5435,168 @@ impl App {
                     self.cache.set_user(*user);
                 }
             },
+            UpdateType::FileDownload => {
+                let downloaded = update.message.as_ref().map(|m| (m.chat_id, m.id));
+                let cached_at = update
+                    .message
+                    .as_ref()
+                    .and_then(|m| m.content.media.as_ref())
+                    .map(|media| std::path::PathBuf::from(&media.local_path));
+                if let Some(msg) = update.message {
+                    self.store_message(*msg);
+                }
+                if let crate::types::UpdateData::FileDownload(download) = update.data {
+                    if download.state == FileDownloadState::Completed {
+                        if let (Some((chat_id, message_id)), Some(path)) = (downloaded, &cached_at)
+                        {
+                            self.seal_downloaded(chat_id, message_id, path);
+                        }
+                    }
+                    // `/download` reports once at the end, not for each file
+                    let finished = matches!(
+                        download.state,
+                        FileDownloadState::Completed | FileDownloadState::Failed
+                    );
+                    let in_bulk = finished
+                        && downloaded.zip(self.bulk_download.as_mut()).map_or(
+                            false,
+                            |((chat_id, message_id), bulk)| {
+                                let saved = download.state == FileDownloadState::Completed;
+                                bulk.finish(chat_id, message_id, saved)
+                            },
+                        );
+                    match download.state {
+                        _ if in_bulk => self.advance_bulk_download(),
+                        FileDownloadState::Completed if download.saved => {
+                            self.set_success_message(format!("Saved to {}", download.local_path))
+                        },
+                        FileDownloadState::Completed => {
+                            self.set_status_message(format!("Opened {}", download.local_path))
+                        },
+                        FileDownloadState::Failed => self.set_error_message(format!(
+                            "Failed to get attachment: {}",
+                            download.error.as_deref().unwrap_or("unknown error")
+                        )),
+                        _ => {},
+                    }
+                }
+            },
+            // Names are read from the cache on every draw, so only the chat
+            // list's previews need rebuilding
+            UpdateType::ParticipantsResolved
+            | UpdateType::ChatReadOutbox
+            | UpdateType::ChatPosition
+            | UpdateType::ChatDraftMessage
+            | UpdateType::ChatAutoDelete
+            | UpdateType::ChatPermissions => {
+                self.mark_list_stale();
+            },
+            // Only chats that aren't open show who is typing, in place of
+            // their preview
+            UpdateType::UserTyping => match update.data {
+                crate::types::UpdateData::Integer(user_id) if !is_selected_chat => {
+                    self.chat_list_model
+                        .set_typing(update.chat_id, user_id, Instant::now());
+                },
+                _ => self.chat_list_model.clear_typing(update.chat_id),
+            },
+            UpdateType::MessageReactions => {
+                // The cache already has the new counts
+                if let Some(msg) = update.message {
+                    if let Some(model) = self.split_conversation_for(update.chat_id) {
+                        model.update_message((*msg).clone());
+                    }
+                    if is_selected_chat {
+                        self.conversation_model.update_message(*msg);
+                    }
+                }
+                if let crate::types::UpdateData::Reactions(events) = update.data {
+                    for event in events {
+                        self.add_reaction(event);
+                    }
+                }
+            },
             _ => {
                 // Other update types will be handled in future phases
             },
         }
     }
 
+    /// Points out that the user was added to a chat or made an admin in it,
+    /// with a notice and a tag on the chat until it's opened.
+    fn note_membership(&mut self, chat_id: i64, change: MembershipChange) {
+        // Both the message and the update that follows can say so
+        if self.chat_list_model.membership(chat_id) == Some(change) {
+            return;
+        }
+        let title = self.chat_display_name(chat_id);
+        match change {
+            MembershipChange::Added => self.set_success_message(format!("Added to {title}")),
+            MembershipChange::Promoted => {
+                self.set_success_message(format!("You're now an admin in {title}"));
+            },
+        }
+        if self.selected_chat_id != Some(chat_id) {
+            self.chat_list_model.set_membership(chat_id, Some(change));
+        }
+        self.mark_list_stale();
+    }
+
+    /// Adds a reaction to the feed, resolving the names it shows.
+    fn add_reaction(&mut self, event: ReactionEvent) {
+        let preview = self
+            .cache
+            .get_messages(event.chat_id)
+            .iter()
+            .find(|m| m.id == event.message_id)
+            .map(|m| {
+                let limit = self.config.ui.appearance.message_preview_length;
+                crate::utils::truncate_string(&m.content.preview(), limit)
+            })
+            .unwrap_or_default();
+        let entry = ReactionEntry {
+            who: self.sender_display_name(event.sender_id),
+            chat: self.chat_display_name(event.chat_id),
+            preview,
+            event,
+        };
+        self.reactions.push(entry);
+    }
+
+    /// Builds the `"Sender: preview"` text for a new-message notification,
+    /// or just "New message" while the screen is locked.
+    fn notification_body(&self, msg: &Message, chat_id: i64) -> String {
+        // A locked screen must not leak who wrote what
+        if self.lock_screen.is_some() {
+            return "New message".to_string();
+        }
+        // The chat's alias only stands in for its title, so a group's alias
+        // never takes over from who wrote the message
+        let sender = self
+            .aliases
+            .get(msg.sender_id)
+            .map(str::to_string)
+            .or_else(|| {
+                self.cache
+                    .get_user(msg.sender_id)
+                    .map(|u| u.get_display_name())
+                    .filter(|n| !n.is_empty())
+            })
+            .or_else(|| self.aliases.get(chat_id).map(str::to_string))
+            .or_else(|| self.cache.get_chat(chat_id).map(|c| c.title))
+            .unwrap_or_else(|| "New message".to_string());
+        let preview = msg.content.preview();
+        // Reuse the chat-list preview length; notifications have no
+        // dedicated setting yet.
+        let limit = self.config.ui.appearance.message_preview_length;
+        let preview = crate::utils::truncate_string(&preview, limit);
+        format!("{sender}: {preview}")
+    }
+
     /// Cycle focus between panes.
     #[allow(clippy::cast_possible_truncation, clippy::cast_possible_wrap)]
     fn cycle_pane(&mut self, direction: i32) {
-        let panes = if self.show_sidebar {
+        let panes = if self.sidebar_visible() {
             vec![
                 FocusedPane::ChatList,
                 FocusedPane::Conversation,
@@ -1314,29 +5615,197 @@ impl App {
             self.focused_pane = panes[0];
         }
 
-        // Update chat list focus state
-        self.chat_list_model
-            .set_focused(self.focused_pane == FocusedPane::ChatList);
-    }
+        // Update chat list focus state
+        self.chat_list_model
+            .set_focused(self.focused_pane == FocusedPane::ChatList);
+    }
+
+    /// Returns `true` if the terminal is too narrow for side-by-side
+    /// panes, so the chat list and conversation take turns.
+    fn is_collapsed(&self) -> bool {
+        let below = self.config.ui.layout.collapse_below;
+        below > 0 && self.screen_width < below
+    }
+
+    /// Returns `true` if the sidebar is shown: it's on, and the layout
+    /// isn't collapsed.
+    fn sidebar_visible(&self) -> bool {
+        self.show_sidebar && !self.is_collapsed()
+    }
+
+    /// Render the application.
+    pub fn render(&mut self, frame: &mut Frame) {
+        self.pane_areas = vec![("screen", frame.area())];
+        self.screen_width = frame.area().width;
+
+        // Nothing behind the lock screen is drawn, so nothing can show through
+        if let Some(lock_screen) = &self.lock_screen {
+            lock_screen.render(frame);
+            return;
+        }
+
+        match self.state {
+            AppState::Loading => self.render_loading(frame),
+            AppState::Auth => self.render_auth(frame),
+            AppState::Main => self.render_main(frame),
+            AppState::Settings => self.render_settings(frame),
+        }
+
+        // Render help overlay if visible
+        if self.overlays.show_help {
+            self.render_help_overlay(frame);
+        }
+
+        // Render file picker overlay if open
+        if let Some(picker) = &self.overlays.file_picker {
+            picker.render(frame);
+        }
+
+        // Render quick switcher overlay if open
+        if let Some(switcher) = &self.overlays.quick_switcher {
+            switcher.render(frame);
+        }
+
+        // Render date prompt overlay if open
+        if let Some(prompt) = &self.overlays.date_prompt {
+            prompt.render(frame);
+        }
+
+        // Render forward options overlay if open
+        if let Some(dialog) = &self.overlays.forward_dialog {
+            dialog.render(frame);
+        }
+
+        // Render report reason picker if open
+        if let Some(dialog) = &self.overlays.report_dialog {
+            dialog.render(frame);
+        }
+
+        // Render reaction picker if open
+        if let Some(picker) = &self.overlays.reaction_picker {
+            picker.render(frame);
+        }
+
+        // Render permissions editor if open
+        if let Some(editor) = &self.overlays.permissions_editor {
+            editor.render(frame);
+        }
+
+        // Render poll overlay if open
+        if let Some(view) = &self.overlays.poll_view {
+            view.render(frame);
+        }
+
+        // Render reactions feed overlay if open
+        if self.overlays.show_reactions {
+            self.reactions.render(frame);
+        }
+
+        // Render inbox overlay if open
+        if let Some(inbox) = &self.overlays.inbox {
+            inbox.render(frame);
+        }
+
+        // Render bookmarks if open
+        if let Some(list) = &self.overlays.bookmark_list {
+            list.render(frame);
+        }
+
+        // Render the local pin board if open
+        if let Some(board) = &self.overlays.pin_board {
+            board.render(frame);
+        }
+
+        // Render chat statistics if open
+        if let Some(view) = &self.overlays.chat_stats {
+            view.render(frame);
+        }
+
+        // Render search results if open
+        if let Some(results) = &self.overlays.search_results {
+            results.render(frame);
+        }
+
+        // Render the QR code if open
+        if let Some(view) = &self.overlays.qr_view {
+            view.render(frame);
+        }
+
+        // Render the send-as picker if open
+        if let Some(picker) = &self.overlays.send_as_picker {
+            picker.render(frame);
+        }
+
+        // Render the chat actions menu and member list if open
+        if let Some(menu) = &self.overlays.chat_actions {
+            menu.render(frame);
+        }
+        if let Some(list) = &self.overlays.member_list {
+            list.render(frame);
+        }
+
+        // Render the local data and channel views if open
+        if let Some(view) = &self.overlays.storage_manager {
+            view.render(frame);
+        }
+        if let Some(view) = &self.overlays.channel_manager {
+            view.render(frame);
+        }
+
+        // Render the error history if open
+        if let Some(log) = &self.overlays.error_log {
+            log.render(frame);
+        }
 
-    /// Render the application.
-    pub fn render(&mut self, frame: &mut Frame) {
-        match self.state {
-            AppState::Loading => self.render_loading(frame),
-            AppState::Auth => self.render_auth(frame),
-            AppState::Main => self.render_main(frame),
-            AppState::Settings => self.render_settings(frame),
+        // Render the Yes/No prompt above everything else
+        if let Some((modal, _)) = &self.overlays.confirmation {
+            frame.render_widget(ModalWidget::new(modal), frame.area());
         }
 
-        // Render help overlay if visible
-        if self.show_help {
-            self.render_help_overlay(frame);
+        // Render leader key hints while a sequence is in progress
+        if let Some(typed) = &self.leader_pending {
+            self.render_leader_hints(frame, typed);
         }
+    }
 
-        // Render file picker overlay if open
-        if let Some(picker) = &self.file_picker {
-            picker.render(frame);
+    /// Renders the keys that can continue a leader sequence in the bottom
+    /// right corner, which-key style.
+    fn render_leader_hints(&self, frame: &mut Frame, typed: &str) {
+        let area = frame.area();
+        let hints = self.leader.continuations(typed);
+        let width = 40.min(area.width);
+        #[allow(clippy::cast_possible_truncation)]
+        let height = (hints.len().min(usize::from(u16::MAX)) as u16 + 2).min(area.height);
+        let hint_area = Rect::new(
+            area.width.saturating_sub(width),
+            area.height.saturating_sub(height + 1),
+            width,
+            height,
+        );
+
+        frame.render_widget(Clear, hint_area);
+
+        let mut title = self.leader.leader_label();
+        for c in typed.chars() {
+            title.push(' ');
+            title.push(c);
         }
+        let lines: Vec<Line> = hints
+            .into_iter()
+            .map(|(key, description)| {
+                Line::from(vec![
+                    Span::styled(format!(" {key}  "), Styles::text_accent()),
+                    Span::styled(description, Styles::text()),
+                ])
+            })
+            .collect();
+
+        let block = Block::default()
+            .title(format!(" {title} "))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+        frame.render_widget(Paragraph::new(lines).block(block), hint_area);
     }
 
     /// Render the loading screen.
@@ -1404,29 +5873,59 @@ impl App {
         let main_area = vertical[0];
         let status_area = vertical[1];
 
-        // Calculate layout based on config
-        let constraints = self.calculate_layout_constraints();
-
-        let chunks = Layout::default()
-            .direction(Direction::Horizontal)
-            .constraints(constraints)
-            .split(main_area);
+        if self.is_collapsed() {
+            // One pane at a time: the chat list while it has focus, and
+            // the conversation otherwise
+            if self.focused_pane == FocusedPane::Sidebar {
+                self.focused_pane = FocusedPane::Conversation;
+                self.chat_list_model.set_focused(false);
+            }
+            if self.focused_pane == FocusedPane::ChatList {
+                self.pane_areas.push(("chat list", main_area));
+                self.render_chat_list_pane(frame, main_area);
+            } else {
+                self.pane_areas.push(("conversation", main_area));
+                self.render_conversation_pane(frame, main_area);
+            }
+        } else {
+            // Calculate layout based on config
+            let constraints = self.calculate_layout_constraints();
+
+            let chunks = Layout::default()
+                .direction(Direction::Horizontal)
+                .constraints(constraints)
+                .split(main_area);
+
+            self.pane_areas.push(("chat list", chunks[0]));
+            self.pane_areas.push(("conversation", chunks[1]));
+            if self.show_sidebar && chunks.len() > 2 {
+                self.pane_areas.push(("sidebar", chunks[2]));
+            }
 
-        // Render chat list
-        self.render_chat_list_pane(frame, chunks[0]);
+            // Render chat list
+            self.render_chat_list_pane(frame, chunks[0]);
 
-        // Render conversation
-        self.render_conversation_pane(frame, chunks[1]);
+            // Render conversation
+            self.render_conversation_pane(frame, chunks[1]);
 
-        // Render sidebar if visible
-        if self.show_sidebar && chunks.len() > 2 {
-            self.render_sidebar_pane(frame, chunks[2]);
+            // Render sidebar if visible
+            if self.show_sidebar && chunks.len() > 2 {
+                self.render_sidebar_pane(frame, chunks[2]);
+            }
         }
+        self.pane_areas.push(("status bar", status_area));
 
         // Update and render status bar
         self.update_status_bar();
         let widget = StatusBarWidget::new(&self.status_bar);
         frame.render_widget(widget, status_area);
+
+        // Notices sit on the line above it, over the panes' bottom edge
+        self.toasts.tick(Instant::now());
+        if status_area.y > area.y {
+            let toast_area = Rect::new(area.x, status_area.y - 1, area.width, 1);
+            self.toasts.render(frame, toast_area);
+        }
     }
 
     /// Update the status bar with current app state.
@@ -1438,7 +5937,7 @@ impl App {
         };
         self.status_bar.set_connection_status(conn_status);
         self.status_bar
-            .set_status_message(self.status_message.clone());
+            .set_unseen_errors(self.toasts.unseen_errors());
         let total_unread: i32 = self
             .cache
             .get_all_chats()
@@ -1446,6 +5945,25 @@ impl App {
             .map(|c| c.unread_count)
             .sum();
         self.status_bar.set_unread_count(total_unread);
+        self.status_bar
+            .set_unseen_reactions(self.reactions.unseen());
+        self.status_bar
+            .set_stealth_mode(self.config.privacy.stealth_mode);
+        self.status_bar
+            .set_downloads(self.bulk_download.as_ref().map(BulkDownload::progress));
+        let playing = self.listening.as_ref().and_then(|queue| {
+            let message = queue.current()?;
+            let (n, total) = queue.progress();
+            let mut playing = self.sender_display_name(message.sender_id);
+            if let Some(media) = message.content.media.as_ref().filter(|m| m.duration > 0) {
+                let length = chrono::Duration::seconds(i64::from(media.duration));
+                playing.push_str(&format!(" {}", crate::utils::format_duration(length)));
+            }
+            Some(format!("{playing} ({n}/{total})"))
+        });
+        self.status_bar.set_playing(playing);
+        self.status_bar
+            .set_flashing(self.flash_until.is_some_and(|t| Instant::now() < t));
     }
 
     /// Calculate layout constraints based on configuration.
@@ -1473,57 +5991,88 @@ impl App {
         self.chat_list_model.render(frame, area);
     }
 
-    /// Render the conversation pane.
+    /// Render the conversation pane, or both sides of a split view.
     fn render_conversation_pane(&self, frame: &mut Frame, area: Rect) {
         let is_focused = self.focused_pane == FocusedPane::Conversation
             || self.focused_pane == FocusedPane::Input;
 
-        // Create a closure to look up sender names from the cache
-        let cache = &self.cache;
-        let get_sender_name = |user_id: i64| -> String {
-            cache
-                .get_user(user_id)
-                .map_or_else(|| format!("User {user_id}"), |u| u.get_display_name())
-        };
+        // Create a closure to look up sender names, preferring local aliases
+        let get_sender_name = |user_id: i64| self.sender_display_name(user_id);
+
+        let mut area = area;
+        if let Some(split) = &self.split {
+            let halves = Layout::default()
+                .direction(Direction::Horizontal)
+                .constraints([Constraint::Percentage(50), Constraint::Percentage(50)])
+                .split(area);
+            let (other_area, focused_area) = if split.on_right {
+                (halves[1], halves[0])
+            } else {
+                (halves[0], halves[1])
+            };
+            let alias = split.chat_id.and_then(|id| self.aliases.get(id));
+            let send_as = split.chat_id.and_then(|id| self.send_as_name(id));
+            let widget = ConversationWidget::new(&split.model, get_sender_name)
+                .focused(false)
+                .alias(alias)
+                .send_as(send_as)
+                .big_emoji(self.config.ui.appearance.big_emoji)
+                .highlights(&self.config.highlights.words)
+                .minimap(self.config.ui.appearance.minimap)
+                .max_message_width(self.config.ui.appearance.max_message_width)
+                .group_within(Duration::from_secs(
+                    self.config.ui.appearance.group_messages_secs,
+                ));
+            frame.render_widget(widget, other_area);
+            area = focused_area;
+        }
 
-        let widget =
-            ConversationWidget::new(&self.conversation_model, get_sender_name).focused(is_focused);
+        let alias = self.selected_chat_id.and_then(|id| self.aliases.get(id));
+        let read_only_hint = match (self.preview.is_some(), self.keymap.is_vim_mode()) {
+            (true, true) => "Preview \u{2022} i /join to join",
+            (true, false) => "Preview \u{2022} Enter /join to join",
+            (false, true) => "m mute/unmute \u{2022} d discussion \u{2022} i /commands",
+            (false, false) => "F3 mute/unmute \u{2022} d discussion \u{2022} Enter /commands",
+        };
+        let widget = ConversationWidget::new(&self.conversation_model, get_sender_name)
+            .focused(is_focused)
+            .alias(alias)
+            .read_only_hint(read_only_hint)
+            .big_emoji(self.config.ui.appearance.big_emoji)
+            .highlights(&self.config.highlights.words)
+            .minimap(self.config.ui.appearance.minimap)
+            .max_message_width(self.config.ui.appearance.max_message_width)
+            .group_within(Duration::from_secs(
+                self.config.ui.appearance.group_messages_secs,
+            ))
+            .send_as(self.selected_chat_id.and_then(|id| self.send_as_name(id)));
 
         frame.render_widget(widget, area);
     }
 
-    /// Render the sidebar pane.
-    fn render_sidebar_pane(&self, frame: &mut Frame, area: Rect) {
-        let is_focused = self.focused_pane == FocusedPane::Sidebar;
-        let border_style = if is_focused {
-            Styles::border_focused()
-        } else {
-            Styles::border()
-        };
-
-        let block = Block::default()
-            .title(" Info ")
-            .borders(Borders::ALL)
-            .border_style(border_style);
-
-        let inner = block.inner(area);
-        frame.render_widget(block, area);
+    /// Returns the name of the identity chosen for a chat with `/sendas`.
+    fn send_as_name(&self, chat_id: i64) -> Option<&str> {
+        self.send_as.get(&chat_id).map(|p| p.name.as_str())
+    }
 
-        // Placeholder content - will be implemented in Phase 8
-        let cache_stats = self.cache.stats();
-        let content = Paragraph::new(vec![
-            Line::from(Span::styled("Sidebar", Styles::text_bright())),
-            Line::from(""),
-            Line::from(Span::styled(
-                format!("Cached users: {}", cache_stats.1),
-                Styles::text_muted(),
-            )),
-            Line::from(""),
-            Line::from(Span::styled("(Phase 8)", Styles::text_muted())),
-        ])
-        .alignment(Alignment::Center);
+    /// Render the sidebar pane with the open chat's details.
+    fn render_sidebar_pane(&self, frame: &mut Frame, area: Rect) {
+        let mut model = SidebarModel::new();
+        if let Some(chat) = self.selected_chat_id.and_then(|id| self.cache.get_chat(id)) {
+            let user = if chat.chat_type == ChatType::Private {
+                self.cache.get_user(chat.id)
+            } else {
+                None
+            };
+            let alias = self.aliases.get(chat.id).map(ToString::to_string);
+            model.set_chat(chat, user);
+            model.set_alias(alias);
+        }
 
-        frame.render_widget(content, inner);
+        let widget = SidebarWidget::new(&model)
+            .focused(self.focused_pane == FocusedPane::Sidebar)
+            .avatar(self.config.ui.appearance.show_avatars);
+        frame.render_widget(widget, area);
     }
 
     /// Render the settings screen.
@@ -1589,23 +6138,33 @@ impl App {
     }
 }
 
+/// Returns `true` if a batch started at `since` has waited out
+/// [`REFRESH_WINDOW`] by `now`.
+fn waited_out(since: Option<Instant>, now: Instant) -> bool {
+    since.is_some_and(|since| now.saturating_duration_since(since) >= REFRESH_WINDOW)
+}
+
 impl std::fmt::Debug for App {
     fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
         f.debug_struct("App")
             .field("state", &self.state)
             .field("focused_pane", &self.focused_pane)
             .field("show_sidebar", &self.show_sidebar)
-            .field("show_help", &self.show_help)
+            .field("show_help", &self.overlays.show_help)
             .field("should_quit", &self.should_quit)
             .field("auth_state", &self.auth_state)
             .finish_non_exhaustive()
     }
 }
 
+#[cfg(test)]
+mod flow_tests;
+
 #[cfg(test)]
 mod tests {
     use super::*;
     use crate::cache::new_shared_cache;
+    use crate::telegram::TelegramClient;
 
     fn create_test_app() -> App {
         let config = Config::default();
@@ -1625,7 +6184,7 @@ mod tests {
         assert_eq!(app.state, AppState::Loading);
         assert_eq!(app.focused_pane, FocusedPane::ChatList);
         assert!(!app.should_quit);
-        assert!(!app.show_help);
+        assert!(!app.overlays.show_help);
     }
 
     #[test]
@@ -1674,13 +6233,13 @@ mod tests {
     #[test]
     fn test_toggle_help() {
         let mut app = create_test_app();
-        assert!(!app.show_help);
+        assert!(!app.overlays.show_help);
 
         app.handle_action(Action::Help);
-        assert!(app.show_help);
+        assert!(app.overlays.show_help);
 
         app.handle_action(Action::Help);
-        assert!(!app.show_help);
+        assert!(!app.overlays.show_help);
     }
 
     #[test]
@@ -1785,13 +6344,31 @@ mod tests {
     #[test]
     fn test_status_message() {
         let mut app = create_test_app();
-        assert!(app.status_message.is_none());
+        assert!(app.toasts.current().is_none());
 
         app.set_status_message("Test message");
-        assert_eq!(app.status_message, Some("Test message".to_string()));
+        assert_eq!(
+            app.toasts.current().map(|t| t.text.as_str()),
+            Some("Test message")
+        );
+
+        app.clear_status_message();
+        assert!(app.toasts.current().is_none());
 
+        // Errors outlive a clear and are kept in the history
+        app.set_error_message("Failed to send message: offline");
         app.clear_status_message();
-        assert!(app.status_message.is_none());
+        assert!(app.toasts.current().is_some());
+        assert_eq!(app.toasts.unseen_errors(), 1);
+
+        let alt_e = KeyEvent::new(
+            crossterm::event::KeyCode::Char('e'),
+            crossterm::event::KeyModifiers::ALT,
+        );
+        app.state = AppState::Main;
+        app.handle_key(alt_e);
+        assert!(app.overlays.error_log.is_some());
+        assert_eq!(app.toasts.unseen_errors(), 0);
     }
 
     #[test]
@@ -1802,6 +6379,466 @@ mod tests {
         assert!(debug.contains("state"));
     }
 
+    #[test]
+    fn test_up_in_empty_input_starts_editing_last_message() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.focused_pane = FocusedPane::Input;
+        app.conversation_model.input.set_focused(true);
+        app.conversation_model
+            .set_messages(vec![crate::types::Message {
+                id: 9,
+                is_outgoing: true,
+                content: crate::types::MessageContent {
+                    text: "typo".to_string(),
+                    ..Default::default()
+                },
+                ..Default::default()
+            }]);
+
+        let up = KeyEvent::new(
+            crossterm::event::KeyCode::Up,
+            crossterm::event::KeyModifiers::NONE,
+        );
+        app.handle_key(up);
+
+        assert_eq!(app.conversation_model.editing, Some(9));
+        assert_eq!(app.conversation_model.input.value(), "typo");
+    }
+
+    #[test]
+    fn test_ctrl_e_in_input_requests_external_editor() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.focused_pane = FocusedPane::Input;
+        app.conversation_model.input.set_focused(true);
+
+        let ctrl_e = KeyEvent::new(
+            crossterm::event::KeyCode::Char('e'),
+            crossterm::event::KeyModifiers::CONTROL,
+        );
+        assert!(matches!(
+            app.handle_key(ctrl_e),
+            Some(AppAction::OpenEditor)
+        ));
+    }
+
+    #[test]
+    fn test_enter_on_slash_command_runs_it_instead_of_sending() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.focused_pane = FocusedPane::Input;
+        app.conversation_model.input.set_focused(true);
+        app.conversation_model.input.set_value("/theme nord");
+
+        let enter = KeyEvent::new(
+            crossterm::event::KeyCode::Enter,
+            crossterm::event::KeyModifiers::NONE,
+        );
+        assert!(matches!(
+            app.handle_key(enter),
+            Some(AppAction::Command(SlashCommand::Theme(_)))
+        ));
+        assert!(app.conversation_model.input.value().is_empty());
+
+        app.conversation_model.input.set_value("/nope");
+        assert!(app.handle_key(enter).is_none());
+        assert!(app.toasts.current().is_some());
+        assert_eq!(app.conversation_model.input.value(), "/nope");
+    }
+
+    #[test]
+    fn test_tab_completes_slash_command() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.focused_pane = FocusedPane::Input;
+        app.conversation_model.input.set_focused(true);
+        app.conversation_model.input.set_value("/exp");
+
+        let tab = KeyEvent::new(
+            crossterm::event::KeyCode::Tab,
+            crossterm::event::KeyModifiers::NONE,
+        );
+        app.handle_key(tab);
+        assert_eq!(app.conversation_model.input.value(), "/export");
+        assert_eq!(app.focused_pane, FocusedPane::Input);
+    }
+
+    #[test]
+    fn test_ctrl_k_opens_quick_switcher_from_input_and_keeps_focus() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.chat_list_model.set_chats(vec![
+            crate::types::Chat {
+                id: 7,
+                title: "Book Club".to_string(),
+                ..Default::default()
+            },
+            crate::types::Chat {
+                id: 8,
+                title: "Work".to_string(),
+                ..Default::default()
+            },
+        ]);
+        app.focused_pane = FocusedPane::Input;
+        app.conversation_model.input.set_focused(true);
+
+        let ctrl_k = KeyEvent::new(
+            crossterm::event::KeyCode::Char('k'),
+            crossterm::event::KeyModifiers::CONTROL,
+        );
+        assert!(app.handle_key(ctrl_k).is_none());
+        assert!(app.overlays.quick_switcher.is_some());
+
+        for c in "wrk".chars() {
+            app.handle_key(KeyEvent::from(crossterm::event::KeyCode::Char(c)));
+        }
+        assert!(app.conversation_model.input.value().is_empty());
+
+        let enter = KeyEvent::from(crossterm::event::KeyCode::Enter);
+        assert!(matches!(
+            app.handle_key(enter),
+            Some(AppAction::ChatSelected(8))
+        ));
+        assert!(app.overlays.quick_switcher.is_none());
+        assert_eq!(app.selected_chat_id, Some(8));
+        assert_eq!(app.focused_pane, FocusedPane::Input);
+    }
+
+    #[test]
+    fn test_forward_picks_destination_then_options() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.chat_list_model.set_chats(vec![crate::types::Chat {
+            id: 9,
+            title: "Work".to_string(),
+            ..Default::default()
+        }]);
+        app.selected_chat_id = Some(1);
+        app.conversation_model
+            .set_messages(vec![crate::types::Message {
+                id: 77,
+                chat_id: 1,
+                ..Default::default()
+            }]);
+        app.focused_pane = FocusedPane::Conversation;
+
+        // Vim mode: 'f' forwards the selected message
+        app.handle_key(KeyEvent::from(crossterm::event::KeyCode::Char('f')));
+        assert!(app.overlays.quick_switcher.is_some());
+        assert_eq!(app.overlays.pending_forward, Some((1, 77)));
+
+        let enter = KeyEvent::from(crossterm::event::KeyCode::Enter);
+        assert!(app.handle_key(enter).is_none());
+        assert!(app.overlays.forward_dialog.is_some());
+        // Picking a destination doesn't open it
+        assert_eq!(app.selected_chat_id, Some(1));
+
+        for c in "fyi".chars() {
+            app.handle_key(KeyEvent::from(crossterm::event::KeyCode::Char(c)));
+        }
+        app.handle_key(KeyEvent::from(crossterm::event::KeyCode::Tab));
+        let action = app.handle_key(enter);
+        assert!(matches!(
+            action,
+            Some(AppAction::ForwardMessage(1, 77, ref to, ForwardOptions { ref comment, drop_author: true }))
+                if to == &[9] && comment == "fyi"
+        ));
+        assert!(app.overlays.forward_dialog.is_none());
+        assert!(app.overlays.pending_forward.is_none());
+    }
+
+    #[test]
+    fn test_failed_download_update_marks_message() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.selected_chat_id = Some(1);
+        let mut msg = crate::types::Message {
+            id: 3,
+            chat_id: 1,
+            ..Default::default()
+        };
+        app.conversation_model.set_messages(vec![msg.clone()]);
+
+        msg.content
+            .set_download_status(DownloadStatus::Failed, Some("timeout".to_string()));
+        app.handle_update(Update {
+            update_type: UpdateType::FileDownload,
+            chat_id: 1,
+            message: Some(Box::new(msg)),
+            data: crate::types::UpdateData::FileDownload(Box::new(crate::types::FileDownload {
+                state: FileDownloadState::Failed,
+                error: Some("timeout".to_string()),
+                ..Default::default()
+            })),
+        });
+
+        assert_eq!(
+            app.conversation_model
+                .selected_message()
+                .map(|m| m.content.download_status()),
+            Some(DownloadStatus::Failed)
+        );
+        assert!(app
+            .toasts
+            .current()
+            .is_some_and(|t| t.text.contains("timeout")));
+    }
+
+    #[test]
+    fn test_deletions_from_other_devices_leave_the_conversation() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.selected_chat_id = Some(1);
+        let messages: Vec<crate::types::Message> = (1..=3)
+            .map(|id| crate::types::Message {
+                id,
+                chat_id: 1,
+                ..Default::default()
+            })
+            .collect();
+        app.conversation_model.set_messages(messages);
+
+        app.handle_update(Update {
+            update_type: UpdateType::MessageDeleted,
+            chat_id: 1,
+            message: None,
+            data: crate::types::UpdateData::MessageIds(vec![1, 3]),
+        });
+
+        let left: Vec<i64> = app
+            .conversation_model
+            .messages
+            .iter()
+            .map(|m| m.id)
+            .collect();
+        assert_eq!(left, vec![2]);
+    }
+
+    #[test]
+    fn test_typing_shows_only_for_chats_not_open() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.selected_chat_id = Some(1);
+        let typing = |chat_id, data| Update {
+            update_type: UpdateType::UserTyping,
+            chat_id,
+            message: None,
+            data,
+        };
+
+        app.handle_update(typing(1, crate::types::UpdateData::Integer(7)));
+        app.handle_update(typing(2, crate::types::UpdateData::Integer(7)));
+        let now = Instant::now();
+        assert_eq!(app.chat_list_model.typing_name(1, now), None);
+        assert!(app.chat_list_model.typing_name(2, now).is_some());
+
+        app.handle_update(typing(2, crate::types::UpdateData::None));
+        assert_eq!(app.chat_list_model.typing_name(2, now), None);
+    }
+
+    #[test]
+    fn test_reaction_updates_feed_and_jump() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        let event = ReactionEvent {
+            chat_id: 4,
+            message_id: 40,
+            sender_id: 8,
+            reaction: "\u{1f44d}".to_string(),
+            ..Default::default()
+        };
+        let update = || Update {
+            update_type: UpdateType::MessageReactions,
+            chat_id: 4,
+            message: None,
+            data: crate::types::UpdateData::Reactions(vec![event.clone()]),
+        };
+        app.handle_update(update());
+        // Telegram repeats unread reactions; they count once
+        app.handle_update(update());
+        assert_eq!(app.reactions.unseen(), 1);
+
+        let alt_r = KeyEvent::new(
+            crossterm::event::KeyCode::Char('r'),
+            crossterm::event::KeyModifiers::ALT,
+        );
+        app.handle_key(alt_r);
+        assert!(app.overlays.show_reactions);
+        assert_eq!(app.reactions.unseen(), 0);
+
+        let action = app.handle_key(KeyEvent::from(crossterm::event::KeyCode::Enter));
+        assert!(matches!(action, Some(AppAction::JumpToMessage(4, 40))));
+        assert!(!app.overlays.show_reactions);
+    }
+
+    #[test]
+    fn test_forward_to_several_marked_chats() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        let dir =
+            std::env::temp_dir().join(format!("ithil_forward_dialog_test_{}", std::process::id()));
+        app.config.telegram.session_file = dir.join("ithil.session");
+        state::remember_forward_targets(&state::forward_targets_file(&app.config), &[3]).unwrap();
+        app.chat_list_model.set_chats(
+            [(1, "Home"), (2, "Work"), (3, "Team")]
+                .into_iter()
+                .map(|(id, title)| crate::types::Chat {
+                    id,
+                    title: title.to_string(),
+                    ..Default::default()
+                })
+                .collect(),
+        );
+        app.selected_chat_id = Some(1);
+        app.conversation_model
+            .set_messages(vec![crate::types::Message {
+                id: 5,
+                chat_id: 1,
+                ..Default::default()
+            }]);
+        app.focused_pane = FocusedPane::Conversation;
+
+        app.handle_key(KeyEvent::from(crossterm::event::KeyCode::Char('f')));
+        // The last destination is listed first; mark it and the next chat
+        let tab = KeyEvent::from(crossterm::event::KeyCode::Tab);
+        app.handle_key(tab);
+        app.handle_key(tab);
+        let enter = KeyEvent::from(crossterm::event::KeyCode::Enter);
+        app.handle_key(enter);
+        assert_eq!(
+            app.overlays
+                .forward_dialog
+                .as_ref()
+                .map(|d| d.to_chat_ids().to_vec()),
+            Some(vec![3, 1])
+        );
+
+        let action = app.handle_key(enter);
+        assert!(matches!(
+            action,
+            Some(AppAction::ForwardMessage(1, 5, ref to, _)) if to == &[3, 1]
+        ));
+        std::fs::remove_dir_all(&dir).unwrap();
+    }
+
+    #[test]
+    fn test_ctrl_g_prompts_for_date_in_open_chat() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        let ctrl_g = KeyEvent::new(
+            crossterm::event::KeyCode::Char('g'),
+            crossterm::event::KeyModifiers::CONTROL,
+        );
+
+        // No chat open: nothing to jump in
+        app.handle_key(ctrl_g);
+        assert!(app.overlays.date_prompt.is_none());
+
+        app.selected_chat_id = Some(42);
+        app.focused_pane = FocusedPane::Input;
+        app.conversation_model.input.set_focused(true);
+        app.handle_key(ctrl_g);
+        assert!(app.overlays.date_prompt.is_some());
+
+        for c in "2024-01-02".chars() {
+            app.handle_key(KeyEvent::from(crossterm::event::KeyCode::Char(c)));
+        }
+        assert!(app.conversation_model.input.value().is_empty());
+
+        let enter = KeyEvent::from(crossterm::event::KeyCode::Enter);
+        assert!(matches!(
+            app.handle_key(enter),
+            Some(AppAction::JumpToDate(42, date)) if date == NaiveDate::from_ymd_opt(2024, 1, 2).unwrap()
+        ));
+        assert!(app.overlays.date_prompt.is_none());
+    }
+
+    #[test]
+    fn test_lock_screen_captures_keys_until_unlocked() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.config.privacy.lock_passphrase_hash = crate::utils::hash_passphrase("pw");
+        app.focused_pane = FocusedPane::Input;
+        app.conversation_model.input.set_focused(true);
+
+        let ctrl_l = KeyEvent::new(
+            crossterm::event::KeyCode::Char('l'),
+            crossterm::event::KeyModifiers::CONTROL,
+        );
+        app.handle_key(ctrl_l);
+        assert!(app.lock_screen.is_some());
+
+        let type_and_enter = |app: &mut App, text: &str| {
+            for c in text.chars() {
+                app.handle_key(KeyEvent::from(crossterm::event::KeyCode::Char(c)));
+            }
+            app.handle_key(KeyEvent::from(crossterm::event::KeyCode::Enter));
+        };
+        type_and_enter(&mut app, "nope");
+        assert!(app.lock_screen.is_some());
+        assert!(app.conversation_model.input.value().is_empty());
+
+        type_and_enter(&mut app, "pw");
+        assert!(app.lock_screen.is_none());
+        assert_eq!(app.focused_pane, FocusedPane::Input);
+    }
+
+    #[test]
+    fn test_locking_closes_every_overlay() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.config.privacy.lock_passphrase_hash = crate::utils::hash_passphrase("pw");
+        app.overlays.show_help = true;
+        app.overlays.show_reactions = true;
+        app.overlays.pending_forward = Some((1, 2));
+        app.overlays.confirmation = Some((Modal::confirm("Log Out", "Sure?"), AppAction::LogOut));
+
+        app.lock();
+        assert!(app.lock_screen.is_some());
+        assert!(!app.overlays.show_help);
+        assert!(!app.overlays.show_reactions);
+        assert!(app.overlays.pending_forward.is_none());
+        assert!(app.overlays.confirmation.is_none());
+    }
+
+    #[test]
+    fn test_auto_lock_after_idle_only_with_passphrase() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.config.privacy.auto_lock_secs = 1;
+        app.last_activity = Instant::now() - Duration::from_secs(2);
+
+        app.check_auto_lock();
+        assert!(app.lock_screen.is_none());
+
+        app.config.privacy.lock_passphrase_hash = crate::utils::hash_passphrase("pw");
+        app.check_auto_lock();
+        assert!(app.lock_screen.is_some());
+    }
+
+    #[test]
+    fn test_typing_notifies_once_and_never_in_stealth() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.selected_chat_id = Some(42);
+        app.focused_pane = FocusedPane::Input;
+        app.conversation_model.input.set_focused(true);
+
+        let key = |c| KeyEvent::from(crossterm::event::KeyCode::Char(c));
+        assert!(matches!(
+            app.handle_key(key('h')),
+            Some(AppAction::SendTyping(42))
+        ));
+        // Throttled while the indicator is still showing
+        assert!(app.handle_key(key('i')).is_none());
+
+        app.last_typing_sent = None;
+        app.config.privacy.stealth_mode = true;
+        assert!(app.handle_key(key('!')).is_none());
+        assert_eq!(app.conversation_model.input.value(), "hi!");
+    }
+
     #[test]
     fn test_esc_clears_staged_attachment_keeps_input_focus() {
         let mut app = create_test_app();
@@ -1841,4 +6878,37 @@ mod tests {
             "second Esc (no attachment) should move focus to Conversation"
         );
     }
+
+    #[test]
+    fn test_alt_o_opens_the_selected_message_or_chat_in_telegram() {
+        let mut app = create_test_app();
+        app.state = AppState::Main;
+        app.chat_list_model.set_chats(vec![crate::types::Chat {
+            id: 1,
+            title: "Home".to_string(),
+            ..Default::default()
+        }]);
+        app.selected_chat_id = Some(1);
+        app.conversation_model
+            .set_messages(vec![crate::types::Message {
+                id: 5,
+                chat_id: 1,
+                ..Default::default()
+            }]);
+        let alt_o = KeyEvent::new(
+            crossterm::event::KeyCode::Char('o'),
+            crossterm::event::KeyModifiers::ALT,
+        );
+
+        app.focused_pane = FocusedPane::Conversation;
+        assert!(matches!(
+            app.handle_key(alt_o),
+            Some(AppAction::OpenInTelegram(1, Some(5)))
+        ));
+        app.focused_pane = FocusedPane::ChatList;
+        assert!(matches!(
+            app.handle_key(alt_o),
+            Some(AppAction::OpenInTelegram(1, None))
+        ));
+    }
 }
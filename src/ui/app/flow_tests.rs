@@ -0,0 +1,2055 @@
+//! Scripted end-to-end tests.
+//!
+//! Each test drives an [`App`] the way a user would, with key presses, against
+//! a [`FakeTelegram`] backend, and checks both what the app sent to Telegram
+//! and what ends up on screen.
+
+use std::sync::Arc;
+use std::time::Instant;
+
+use chrono::{Duration, Utc};
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+use ratatui::backend::TestBackend;
+use ratatui::Terminal;
+use tokio::sync::mpsc;
+
+use super::{App, AppAction, AppState, FocusedPane, REFRESH_WINDOW};
+use crate::app::{state, Config, StartupView};
+use crate::cache::new_shared_cache;
+use crate::telegram::fake::{Call, FakeTelegram, LOGIN_CODE};
+use crate::types::{
+    AuthState, Chat, ChatPermissions, ChatType, DeepLink, FileDownload, FileDownloadState,
+    ForwardInfo, ForwardOrigin, MembershipChange, Message, MessageContent, MessageType, PeerHandle,
+    Poll, PollOption, ReportReason, SendAsPeer, Update, UpdateData, UpdateType, User,
+};
+use crate::ui::components::{ForwardOptions, InputMode};
+use crate::utils::Keywords;
+
+const ALICE: i64 = 42;
+
+/// An app wired to a fake backend.
+struct Session {
+    app: App,
+    telegram: Arc<FakeTelegram>,
+}
+
+impl Session {
+    /// Starts at the login screen.
+    fn start(telegram: impl FnOnce(crate::cache::SharedCache) -> FakeTelegram) -> Self {
+        let cache = new_shared_cache(100);
+        let telegram = Arc::new(telegram(cache.clone()));
+        let (tx, rx) = mpsc::channel(16);
+        telegram.set_update_channel(tx);
+
+        let mut app = App::new(Config::default(), telegram.clone(), cache);
+        app.set_update_receiver(rx);
+        app.update_auth_state(AuthState::WaitPhoneNumber);
+        Self { app, telegram }
+    }
+
+    /// Starts already logged in, with the chat list loaded.
+    async fn logged_in(telegram: impl FnOnce(crate::cache::SharedCache) -> FakeTelegram) -> Self {
+        let mut session = Self::start(|cache| telegram(cache).logged_in());
+        session.app.update_auth_state(AuthState::Ready);
+        session.app.on_authorized().await;
+        session
+    }
+
+    /// Presses a key, with any modifiers, and carries out whatever it asks
+    /// for.
+    async fn press_key(&mut self, key: KeyEvent) {
+        if let Some(action) = self.app.handle_key(key) {
+            self.app.handle_app_action(action).await;
+        }
+    }
+
+    /// Presses a key.
+    async fn press(&mut self, code: KeyCode) {
+        self.press_key(KeyEvent::new(code, KeyModifiers::NONE))
+            .await;
+    }
+
+    /// Presses `Alt` and a character key.
+    async fn press_alt(&mut self, c: char) {
+        self.press_key(KeyEvent::new(KeyCode::Char(c), KeyModifiers::ALT))
+            .await;
+    }
+
+    /// Presses `Ctrl` and a character key.
+    async fn press_ctrl(&mut self, c: char) {
+        self.press_key(KeyEvent::new(KeyCode::Char(c), KeyModifiers::CONTROL))
+            .await;
+    }
+
+    /// Types `text` character by character.
+    async fn type_text(&mut self, text: &str) {
+        for c in text.chars() {
+            self.press(KeyCode::Char(c)).await;
+        }
+    }
+
+    /// Types `text`, presses Enter, and waits for anything it sent.
+    async fn submit(&mut self, text: &str) {
+        self.type_text(text).await;
+        self.press(KeyCode::Enter).await;
+        self.settle().await;
+    }
+
+    /// Lets queued sends, marking chats read and fetching the inbox
+    /// finish, and shows what they did.
+    async fn settle(&mut self) {
+        for _ in 0..100 {
+            if self.app.sends.pending() == 0
+                && self.app.mark_read.is_none()
+                && self.app.inbox_fetch.is_none()
+            {
+                break;
+            }
+            tokio::task::yield_now().await;
+            self.app.finish_sends();
+            self.app.finish_mark_read();
+            self.app.finish_inbox_fetch();
+        }
+    }
+
+    /// Handles pending Telegram updates, as the event loop does every tick,
+    /// without waiting out the refresh window.
+    async fn sync(&mut self) {
+        self.app.process_updates().await;
+        self.app
+            .apply_batched(Instant::now() + REFRESH_WINDOW)
+            .await;
+    }
+
+    /// Renders the app and returns the screen as text.
+    fn screen(&mut self) -> String {
+        self.screen_at(120)
+    }
+
+    /// Draws the app on a terminal `width` columns wide.
+    fn screen_at(&mut self, width: u16) -> String {
+        let mut terminal = Terminal::new(TestBackend::new(width, 30)).unwrap();
+        terminal.draw(|frame| self.app.render(frame)).unwrap();
+        let buffer = terminal.backend().buffer();
+        buffer
+            .content()
+            .chunks(usize::from(buffer.area.width))
+            .map(|row| {
+                row.iter()
+                    .map(ratatui::buffer::Cell::symbol)
+                    .collect::<String>()
+            })
+            .collect::<Vec<_>>()
+            .join("\n")
+    }
+}
+
+fn chat(id: i64, title: &str) -> Chat {
+    Chat {
+        id,
+        title: title.to_string(),
+        chat_type: ChatType::Private,
+        ..Default::default()
+    }
+}
+
+fn message(id: i64, chat_id: i64, text: &str, minutes_ago: i64) -> Message {
+    Message {
+        id,
+        chat_id,
+        sender_id: chat_id,
+        date: Utc::now() - Duration::minutes(minutes_ago),
+        content: MessageContent {
+            text: text.to_string(),
+            ..Default::default()
+        },
+        ..Default::default()
+    }
+}
+
+fn with_alice(cache: crate::cache::SharedCache) -> FakeTelegram {
+    let mut alice = chat(ALICE, "Alice");
+    alice.unread_count = 1;
+    alice.can_set_auto_delete = true;
+    FakeTelegram::new(cache).with_chat(
+        alice,
+        vec![
+            message(1, ALICE, "Are you around?", 10),
+            message(2, ALICE, "Lunch tomorrow?", 5),
+        ],
+    )
+}
+
+#[tokio::test]
+async fn logs_in_with_two_factor_password() {
+    let mut session = Session::start(|cache| with_alice(cache).with_password("hunter2"));
+    assert_eq!(session.app.state, AppState::Auth);
+
+    session.submit("+15550100").await;
+    assert_eq!(session.app.auth_state, AuthState::WaitCode);
+
+    // A wrong code keeps the user on the code prompt
+    session.submit("00000").await;
+    assert_eq!(session.app.auth_state, AuthState::WaitCode);
+    assert!(session.screen().contains("Invalid"));
+
+    for _ in 0..5 {
+        session.press(KeyCode::Backspace).await;
+    }
+    session.submit(LOGIN_CODE).await;
+    assert_eq!(session.app.auth_state, AuthState::WaitPassword);
+
+    session.submit("hunter2").await;
+    assert_eq!(session.app.state, AppState::Main);
+    assert!(session.screen().contains("Alice"));
+}
+
+#[tokio::test]
+async fn logging_out_asks_first_and_returns_to_sign_in() {
+    let mut session = Session::logged_in(with_alice).await;
+    let dir = std::env::temp_dir().join(format!("ithil_log_out_flow_{}", std::process::id()));
+    session.app.config.telegram.session_file = dir.join("ithil.session");
+    session.app.set_config_file(dir.join("config.yaml"));
+    std::fs::create_dir_all(&dir).unwrap();
+    for name in state::FILES {
+        std::fs::write(dir.join(name), "[]").unwrap();
+    }
+    session.app.aliases.set(ALICE, "Al");
+    session.app.config.notifications.muted_chats = vec![ALICE];
+    session.app.config.privacy.encrypted_chats = vec![ALICE];
+    assert_eq!(
+        session.app.status_bar.current_user.as_ref().map(|u| u.id),
+        Some(1)
+    );
+    session.press(KeyCode::Enter).await;
+
+    session.press(KeyCode::Char('i')).await;
+    session.submit("/logout").await;
+    assert!(session.screen().contains("Log out of Me?"));
+    assert!(!session.telegram.calls().contains(&Call::LogOut));
+
+    session.press(KeyCode::Char('y')).await;
+    assert!(session.telegram.calls().contains(&Call::LogOut));
+    assert_eq!(session.app.state, AppState::Auth);
+    assert_eq!(session.app.auth_state, AuthState::WaitPhoneNumber);
+    assert!(session.app.cache.get_chat(ALICE).is_none());
+    assert!(session.app.status_bar.current_user.is_none());
+    assert!(session.screen().contains("Sign In"));
+
+    // Nothing about the old account's chats is left for the next one
+    assert!(state::files(&session.app.config)
+        .iter()
+        .all(|p| !p.exists()));
+    assert!(session.app.aliases.get(ALICE).is_none());
+    assert!(session.app.config.notifications.muted_chats.is_empty());
+    assert!(!session.app.config.privacy.is_encrypted(ALICE));
+    // The config is saved where it was loaded from
+    let saved = Config::load(Some(dir.join("config.yaml").as_path())).unwrap();
+    assert!(saved.notifications.muted_chats.is_empty());
+    std::fs::remove_dir_all(&dir).unwrap();
+}
+
+#[tokio::test]
+async fn logging_out_drops_the_split_and_queued_sends() {
+    const BOB: i64 = 43;
+    let mut session = Session::logged_in(|cache| {
+        with_alice(cache).with_chat(
+            chat(BOB, "Bob"),
+            vec![message(3, BOB, "Notes attached", 30)],
+        )
+    })
+    .await;
+    let dir = std::env::temp_dir().join(format!("ithil_log_out_split_flow_{}", std::process::id()));
+    session.app.config.telegram.session_file = dir.join("ithil.session");
+    session.app.set_config_file(dir.join("config.yaml"));
+    std::fs::create_dir_all(&dir).unwrap();
+
+    // Alice on the left, Bob on the right with a send held up
+    session.press(KeyCode::Enter).await;
+    session.press_alt('v').await;
+    session.press(KeyCode::Down).await;
+    session.press(KeyCode::Enter).await;
+    let held = session.telegram.hold_sends(BOB);
+    session.press(KeyCode::Char('i')).await;
+    session.submit("On my way").await;
+    assert!(session.app.split.is_some());
+    assert_eq!(session.app.sends.pending(), 1);
+
+    session.submit("/logout").await;
+    session.press(KeyCode::Char('y')).await;
+    assert!(session.telegram.calls().contains(&Call::LogOut));
+    drop(held);
+    session.settle().await;
+
+    assert!(session.app.split.is_none());
+    assert_eq!(session.app.sends.pending(), 0);
+    assert!(session.app.overlays.confirmation.is_none());
+    assert!(session.app.conversation_model.messages.is_empty());
+    assert!(!session
+        .telegram
+        .calls()
+        .iter()
+        .any(|call| matches!(call, Call::SendMessage { .. })));
+    assert!(!session.screen().contains("Notes attached"));
+    std::fs::remove_dir_all(&dir).unwrap();
+}
+
+#[tokio::test]
+async fn opening_a_chat_loads_history_and_marks_it_read() {
+    let mut session = Session::logged_in(with_alice).await;
+
+    session.press(KeyCode::Enter).await;
+
+    assert_eq!(session.app.get_selected_chat_id(), Some(ALICE));
+    assert_eq!(session.app.focused_pane, FocusedPane::Conversation);
+    assert_eq!(session.app.conversation_model.messages.len(), 2);
+    assert!(session.telegram.calls().contains(&Call::MarkAsRead(ALICE)));
+    assert_eq!(
+        session.app.cache.get_chat(ALICE).map(|c| c.unread_count),
+        Some(0)
+    );
+    assert!(session.screen().contains("Lunch tomorrow?"));
+}
+
+#[tokio::test]
+async fn tab_cycles_the_panes_and_esc_steps_out_of_the_input() {
+    let mut session = Session::logged_in(with_alice).await;
+    assert_eq!(session.app.focused_pane, FocusedPane::ChatList);
+    session.press(KeyCode::Tab).await;
+    assert_eq!(session.app.focused_pane, FocusedPane::Conversation);
+    let back = KeyEvent::new(KeyCode::BackTab, KeyModifiers::SHIFT);
+    session.press_key(back).await;
+    assert_eq!(session.app.focused_pane, FocusedPane::ChatList);
+    // Back from the first pane wraps around to the last
+    session.press_key(back).await;
+    assert_ne!(session.app.focused_pane, FocusedPane::ChatList);
+    session.press(KeyCode::Tab).await;
+    assert_eq!(session.app.focused_pane, FocusedPane::ChatList);
+
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+    assert_eq!(session.app.focused_pane, FocusedPane::Input);
+    // Shortcuts are text while typing
+    session.type_text("bS?").await;
+    assert_eq!(session.app.conversation_model.input.value(), "bS?");
+    assert!(!session.app.config.privacy.stealth_mode);
+    assert!(!session.app.overlays.show_help);
+
+    session.press(KeyCode::Esc).await;
+    assert_eq!(session.app.focused_pane, FocusedPane::Conversation);
+    assert_eq!(session.app.conversation_model.input.value(), "bS?");
+}
+
+#[tokio::test]
+async fn chat_search_takes_keys_as_its_query_until_esc() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.press(KeyCode::Char('/')).await;
+    assert!(session.app.chat_list_model.is_search_mode());
+
+    // Shortcuts and the leader are part of the query
+    session.type_text("S ?").await;
+    assert!(!session.app.config.privacy.stealth_mode);
+    assert!(!session.app.overlays.show_help);
+    assert!(session.app.leader_pending.is_none());
+    assert_eq!(session.app.chat_list_model.get_selected_chat_id(), None);
+
+    session.press(KeyCode::Esc).await;
+    assert!(!session.app.chat_list_model.is_search_mode());
+    assert_eq!(session.app.focused_pane, FocusedPane::ChatList);
+    assert!(session.screen().contains("Alice"));
+
+    session.press(KeyCode::Char('/')).await;
+    session.submit("ali").await;
+    assert!(!session.app.chat_list_model.is_search_mode());
+    assert_eq!(session.app.selected_chat_id, Some(ALICE));
+    assert_eq!(session.app.focused_pane, FocusedPane::Conversation);
+}
+
+#[tokio::test]
+async fn an_open_overlay_keeps_keys_from_the_panes_beneath() {
+    let mut session = Session::logged_in(with_alice).await;
+    let dir = std::env::temp_dir().join(format!("ithil_overlay_flow_{}", std::process::id()));
+    session.app.config.telegram.session_file = dir.join("ithil.session");
+    session.press(KeyCode::Enter).await;
+
+    session.press_alt('b').await;
+    assert!(session.app.overlays.bookmark_list.is_some());
+    // Neither the pane's shortcuts nor other overlays get through
+    session.press(KeyCode::Char('b')).await;
+    session.press_alt('i').await;
+    session.press(KeyCode::Char('?')).await;
+    assert!(session.app.overlays.inbox.is_none());
+    assert!(!session.app.overlays.show_help);
+    assert!(session.screen().contains("Bookmarks (0)"));
+
+    session.press(KeyCode::Esc).await;
+    assert!(session.app.overlays.bookmark_list.is_none());
+    assert_eq!(session.app.focused_pane, FocusedPane::Conversation);
+    assert_eq!(session.app.selected_chat_id, Some(ALICE));
+    assert!(!dir.exists());
+}
+
+#[tokio::test]
+async fn typed_message_is_sent_to_the_open_chat() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.press(KeyCode::Enter).await;
+
+    session.press(KeyCode::Char('i')).await;
+    session.submit("See you at noon").await;
+
+    assert!(session.telegram.calls().contains(&Call::SendMessage {
+        chat_id: ALICE,
+        text: "See you at noon".to_string(),
+        reply_to: None,
+        send_as: None,
+    }));
+    assert_eq!(session.app.conversation_model.input.value(), "");
+    assert!(session
+        .app
+        .conversation_model
+        .messages
+        .iter()
+        .any(|m| m.is_outgoing && m.content.text == "See you at noon"));
+    assert!(session.screen().contains("See you at noon"));
+}
+
+#[tokio::test]
+async fn vim_keys_are_typed_and_only_the_up_arrow_edits() {
+    let mut session = Session::logged_in(with_alice).await;
+    assert!(session.app.config.ui.keyboard.vim_mode);
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+    session.submit("See you at noon").await;
+
+    session.type_text("ok").await;
+    assert_eq!(session.app.conversation_model.input.value(), "ok");
+    session.press(KeyCode::Backspace).await;
+    session.press(KeyCode::Backspace).await;
+    session.type_text("k").await;
+    assert_eq!(session.app.conversation_model.input.value(), "k");
+    assert_eq!(session.app.conversation_model.input_mode, InputMode::Normal);
+
+    session.press(KeyCode::Backspace).await;
+    session.press(KeyCode::Up).await;
+    assert_eq!(session.app.conversation_model.input_mode, InputMode::Edit);
+    assert_eq!(
+        session.app.conversation_model.input.value(),
+        "See you at noon"
+    );
+}
+
+#[tokio::test]
+async fn bot_commands_are_sent_and_only_known_commands_run() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+
+    session.submit("/start@somebot").await;
+    session.submit("//lock").await;
+    session.submit("/lock").await;
+
+    let sent: Vec<String> = session
+        .telegram
+        .calls()
+        .into_iter()
+        .filter_map(|call| match call {
+            Call::SendMessage { text, .. } => Some(text),
+            _ => None,
+        })
+        .collect();
+    assert_eq!(sent, ["/start@somebot", "/lock"]);
+    assert!(session.app.lock_screen.is_some());
+}
+
+#[tokio::test]
+async fn sent_message_shows_once_after_its_echo() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+    session.submit("See you at noon").await;
+
+    let sent = session.app.conversation_model.messages.last().unwrap().id;
+    session.telegram.echo(ALICE, sent).await;
+    session.sync().await;
+
+    let copies = |messages: &[Message]| {
+        messages
+            .iter()
+            .filter(|m| m.content.text == "See you at noon")
+            .count()
+    };
+    assert_eq!(copies(&session.app.conversation_model.messages), 1);
+    assert_eq!(copies(&session.app.cache.get_messages(ALICE)), 1);
+    assert!(!session
+        .app
+        .cache
+        .get_messages(ALICE)
+        .iter()
+        .any(Message::is_pending));
+}
+
+#[tokio::test]
+async fn incoming_message_appears_in_the_open_chat() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.press(KeyCode::Enter).await;
+    let reads_before = session
+        .telegram
+        .calls()
+        .iter()
+        .filter(|c| **c == Call::MarkAsRead(ALICE))
+        .count();
+
+    session.telegram.receive(ALICE, ALICE, "On my way").await;
+    session.sync().await;
+
+    assert_eq!(
+        session
+            .app
+            .conversation_model
+            .messages
+            .last()
+            .map(|m| m.content.text.as_str()),
+        Some("On my way")
+    );
+    // Viewing the chat reads the new message too
+    let reads_after = session
+        .telegram
+        .calls()
+        .iter()
+        .filter(|c| **c == Call::MarkAsRead(ALICE))
+        .count();
+    assert_eq!(reads_after, reads_before + 1);
+    assert!(session.screen().contains("On my way"));
+}
+
+#[tokio::test]
+async fn new_messages_flash_the_status_bar_unless_seen_or_left_out() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.app.config.notifications.desktop = false;
+    session.app.config.notifications.flash = true;
+    // Nothing is sent for it, so stealth mode doesn't hold it back
+    session.app.config.privacy.stealth_mode = true;
+
+    session.telegram.receive(ALICE, ALICE, "Ping").await;
+    session.sync().await;
+    session.screen();
+    assert!(session.app.status_bar.flashing);
+
+    // Not for a message in the chat being read
+    session.app.flash_until = None;
+    session.press(KeyCode::Enter).await;
+    session.telegram.receive(ALICE, ALICE, "Still there?").await;
+    session.sync().await;
+    session.screen();
+    assert!(!session.app.status_bar.flashing);
+
+    // Nor for chats left out once others are chosen with /alert
+    session.app.terminal_focused = false;
+    session.app.config.notifications.alert_chats = vec![7];
+    session.telegram.receive(ALICE, ALICE, "Hello?").await;
+    session.sync().await;
+    session.screen();
+    assert!(!session.app.status_bar.flashing);
+
+    session.app.config.notifications.alert_chats = vec![ALICE];
+    session.telegram.receive(ALICE, ALICE, "Hello??").await;
+    session.sync().await;
+    session.screen();
+    assert!(session.app.status_bar.flashing);
+}
+
+#[tokio::test]
+async fn notifications_name_the_sender_before_an_aliased_group() {
+    const TEAM: i64 = -100;
+    const BOB: i64 = 7;
+    let mut session = Session::logged_in(with_alice).await;
+    session.app.cache.set_user(User {
+        id: BOB,
+        first_name: "Bob".to_string(),
+        ..Default::default()
+    });
+    session.app.aliases.set(TEAM, "Work");
+    let from = |sender_id| Message {
+        sender_id,
+        ..message(1, TEAM, "Standup?", 0)
+    };
+
+    assert_eq!(
+        session.app.notification_body(&from(BOB), TEAM),
+        "Bob: Standup?"
+    );
+    session.app.aliases.set(BOB, "Boss");
+    assert_eq!(
+        session.app.notification_body(&from(BOB), TEAM),
+        "Boss: Standup?"
+    );
+    // Only a sender who can't be named falls back to the group
+    assert_eq!(
+        session.app.notification_body(&from(99), TEAM),
+        "Work: Standup?"
+    );
+
+    // Locked, nothing about the message shows
+    session.app.config.privacy.lock_passphrase_hash = crate::utils::hash_passphrase("pw");
+    session.press_ctrl('l').await;
+    assert_eq!(
+        session.app.notification_body(&from(BOB), TEAM),
+        "New message"
+    );
+}
+
+#[tokio::test]
+async fn aliases_are_kept_locally_beside_bookmarks() {
+    let mut session = Session::logged_in(with_alice).await;
+    let dir = std::env::temp_dir().join(format!("ithil_alias_flow_{}", std::process::id()));
+    session.app.config.telegram.session_file = dir.join("ithil.session");
+    session.press(KeyCode::Enter).await;
+
+    session.press(KeyCode::Char('i')).await;
+    session.submit("/alias Al").await;
+    assert_eq!(session.app.chat_display_name(ALICE), "Al");
+    assert!(session.app.toasts.current().is_none());
+    let path = state::aliases_file(&session.app.config);
+    assert_eq!(state::load_aliases(&path).get(ALICE), Some("Al"));
+
+    session.submit("/alias").await;
+    assert_eq!(session.app.chat_display_name(ALICE), "Alice");
+    assert!(state::load_aliases(&path).get(ALICE).is_none());
+    std::fs::remove_dir_all(&dir).unwrap();
+}
+
+#[tokio::test]
+async fn auto_delete_timer_is_set_and_shown_in_the_sidebar() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.app.show_sidebar = true;
+    session.press(KeyCode::Enter).await;
+
+    session.press(KeyCode::Char('i')).await;
+    session.submit("/autodelete 1w").await;
+
+    assert!(session.telegram.calls().contains(&Call::SetAutoDelete {
+        chat_id: ALICE,
+        period: 7 * 86_400,
+    }));
+    assert!(session.screen().contains("Auto-delete: 1 week"));
+}
+
+#[tokio::test]
+async fn message_is_reported_only_after_confirming() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.press(KeyCode::Enter).await;
+    let selected = session
+        .app
+        .conversation_model
+        .selected_message()
+        .map(|m| m.id)
+        .unwrap();
+
+    session.press(KeyCode::Char('!')).await;
+    assert!(session.screen().contains("Report message from"));
+    session.press(KeyCode::Down).await;
+    session.press(KeyCode::Enter).await;
+    assert!(!session
+        .telegram
+        .calls()
+        .iter()
+        .any(|c| matches!(c, Call::ReportMessages { .. })));
+
+    session.press(KeyCode::Enter).await;
+
+    assert!(session.telegram.calls().contains(&Call::ReportMessages {
+        chat_id: ALICE,
+        message_ids: vec![selected],
+        reason: ReportReason::Violence,
+    }));
+}
+
+#[tokio::test]
+async fn admin_edits_group_permissions() {
+    const GROUP: i64 = 77;
+    let mut session = Session::logged_in(|cache| {
+        let mut group = chat(GROUP, "Book Club");
+        group.chat_type = ChatType::Group;
+        group.default_permissions = Some(ChatPermissions::default());
+        group.can_edit_permissions = true;
+        FakeTelegram::new(cache).with_chat(group, vec![message(1, GROUP, "Chapter 3?", 1)])
+    })
+    .await;
+    session.press(KeyCode::Enter).await;
+
+    session.press(KeyCode::Char('i')).await;
+    session.submit("/permissions").await;
+    assert!(session.screen().contains("Permissions: Book Club"));
+    session.press(KeyCode::Down).await;
+    session.press(KeyCode::Char(' ')).await;
+    session.press(KeyCode::Enter).await;
+
+    let expected = ChatPermissions {
+        send_media: false,
+        ..ChatPermissions::default()
+    };
+    assert!(session.telegram.calls().contains(&Call::SetPermissions {
+        chat_id: GROUP,
+        permissions: expected,
+    }));
+    assert_eq!(
+        session
+            .app
+            .cache
+            .get_chat(GROUP)
+            .and_then(|c| c.default_permissions),
+        Some(expected)
+    );
+}
+
+#[tokio::test]
+async fn poll_vote_is_sent_listed_and_retracted() {
+    const GROUP: i64 = 77;
+    let mut session = Session::logged_in(|cache| {
+        let mut group = chat(GROUP, "Lunch Crew");
+        group.chat_type = ChatType::Group;
+        let mut poll = message(1, GROUP, "", 1);
+        poll.content.content_type = MessageType::Poll;
+        poll.content.poll = Some(Poll {
+            question: "Lunch?".to_string(),
+            options: ["Pizza", "Sushi"]
+                .iter()
+                .zip(0u8..)
+                .map(|(text, option)| PollOption {
+                    text: (*text).to_string(),
+                    option: vec![option],
+                    ..Default::default()
+                })
+                .collect(),
+            ..Default::default()
+        });
+        let bob = User {
+            id: 5,
+            first_name: "Bob".to_string(),
+            ..Default::default()
+        };
+        FakeTelegram::new(cache)
+            .with_chat(group, vec![poll])
+            .with_voters(&[1], vec![bob])
+    })
+    .await;
+    session.press(KeyCode::Enter).await;
+
+    session.press(KeyCode::Char('o')).await;
+    assert!(session.screen().contains("Lunch?"));
+    session.press(KeyCode::Down).await;
+    session.press(KeyCode::Enter).await;
+    assert!(session.telegram.calls().contains(&Call::Vote {
+        chat_id: GROUP,
+        message_id: 1,
+        options: vec![vec![1]],
+    }));
+
+    session.press(KeyCode::Char('v')).await;
+    assert!(session.screen().contains("Bob"));
+    session.press(KeyCode::Esc).await;
+
+    session.press(KeyCode::Char('r')).await;
+    assert!(session.telegram.calls().contains(&Call::Vote {
+        chat_id: GROUP,
+        message_id: 1,
+        options: Vec::new(),
+    }));
+}
+
+#[tokio::test]
+async fn actions_before_login_are_refused() {
+    let session = Session::start(with_alice);
+    let result =
+        crate::telegram::MessageService::send_message(&*session.telegram, ALICE, "hi", None, None)
+            .await;
+    assert!(matches!(
+        result,
+        Err(crate::telegram::TelegramError::AuthRequired)
+    ));
+    assert!(session.telegram.calls().is_empty());
+}
+
+#[tokio::test]
+async fn leader_sequence_marks_all_chats_read_after_confirming() {
+    let mut session = Session::logged_in(with_alice).await;
+
+    session.press(KeyCode::Char(' ')).await;
+    session.press(KeyCode::Char('m')).await;
+    let screen = session.screen();
+    assert!(screen.contains("Space m"));
+    assert!(screen.contains("Mark All As Read"));
+
+    // Saying no leaves everything unread
+    session.press(KeyCode::Char('a')).await;
+    assert!(session.screen().contains("Mark 1 chat (1 unread) as read?"));
+    session.press(KeyCode::Char('n')).await;
+    assert!(!session.telegram.calls().contains(&Call::MarkAsRead(ALICE)));
+
+    session.type_text(" ma").await;
+    session.press(KeyCode::Char('y')).await;
+    session.settle().await;
+    assert!(session.telegram.calls().contains(&Call::MarkAsRead(ALICE)));
+    assert_eq!(
+        session.app.cache.get_chat(ALICE).map(|c| c.unread_count),
+        Some(0)
+    );
+    assert!(!session.screen().contains("Space m"));
+}
+
+#[tokio::test]
+async fn inbox_lists_unread_messages_and_marks_chats_read() {
+    let mut session = Session::logged_in(with_alice).await;
+
+    session.type_text(" i").await;
+    assert!(session.screen().contains("loading"));
+    session.settle().await;
+    let screen = session.screen();
+    assert!(screen.contains("Inbox (1)"));
+    assert!(screen.contains("[Alice] Lunch tomorrow?"));
+    assert!(!screen.contains("Are you around?"));
+
+    session.press(KeyCode::Char('r')).await;
+    session.settle().await;
+    assert!(session.telegram.calls().contains(&Call::MarkAsRead(ALICE)));
+    assert!(session.screen().contains("Nothing unread"));
+
+    session.press(KeyCode::Esc).await;
+    assert!(!session.screen().contains("Inbox"));
+}
+
+#[tokio::test]
+async fn split_view_shows_two_chats_side_by_side() {
+    const BOB: i64 = 43;
+    let mut session = Session::logged_in(|cache| {
+        with_alice(cache).with_chat(
+            chat(BOB, "Bob"),
+            vec![message(3, BOB, "Notes attached", 30)],
+        )
+    })
+    .await;
+    session.press(KeyCode::Enter).await;
+    assert_eq!(session.app.get_selected_chat_id(), Some(ALICE));
+
+    // Splitting keeps Alice on the left and picks Bob for the right
+    session.press_alt('v').await;
+    assert_eq!(session.app.focused_pane, FocusedPane::ChatList);
+    session.press(KeyCode::Down).await;
+    session.press(KeyCode::Enter).await;
+    assert_eq!(session.app.get_selected_chat_id(), Some(BOB));
+    assert_eq!(
+        session.app.split.as_ref().map(|split| split.chat_id),
+        Some(Some(ALICE))
+    );
+    let screen = session.screen();
+    assert!(screen.contains("Lunch tomorrow?"));
+    assert!(screen.contains("Notes attached"));
+
+    session.press_alt('w').await;
+    assert_eq!(session.app.get_selected_chat_id(), Some(ALICE));
+    assert_eq!(session.app.focused_pane, FocusedPane::Conversation);
+
+    // Closing keeps the focused side
+    session.press_alt('v').await;
+    assert!(session.app.split.is_none());
+    assert_eq!(session.app.get_selected_chat_id(), Some(ALICE));
+    assert_eq!(session.app.conversation_model.messages.len(), 2);
+}
+
+#[tokio::test]
+async fn find_groups_results_and_jumps_to_a_message() {
+    const CLUB: i64 = 44;
+    let mut session = Session::logged_in(|cache| {
+        with_alice(cache).with_chat(
+            chat(CLUB, "Lunch club"),
+            vec![message(3, CLUB, "Notes attached", 30)],
+        )
+    })
+    .await;
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+
+    session.submit("/find lunch").await;
+    let screen = session.screen();
+    assert!(screen.contains("Search: lunch (2)"));
+    assert!(screen.contains("Chats (1)"));
+    assert!(screen.contains("[Alice] Lunch tomorrow?"));
+
+    session.press(KeyCode::Down).await;
+    session.press(KeyCode::Enter).await;
+    assert!(session.app.overlays.search_results.is_none());
+    assert_eq!(session.app.get_selected_chat_id(), Some(ALICE));
+
+    session.press(KeyCode::Char('i')).await;
+    session.submit("/find from:me").await;
+    assert!(session.screen().contains("No results"));
+}
+
+#[tokio::test]
+async fn read_only_channel_keeps_the_draft_but_runs_commands() {
+    const NEWS: i64 = 45;
+    let mut session = Session::logged_in(|cache| {
+        let mut news = chat(NEWS, "News");
+        news.chat_type = ChatType::Channel;
+        news.is_read_only = true;
+        FakeTelegram::new(cache).with_chat(news, vec![message(1, NEWS, "Headline", 5)])
+    })
+    .await;
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+
+    session.submit("hello").await;
+    assert!(!session
+        .telegram
+        .calls()
+        .iter()
+        .any(|call| matches!(call, Call::SendMessage { .. })));
+    let screen = session.screen();
+    assert!(screen.contains("Read-only"));
+    assert!(screen.contains("You can't send messages in this chat"));
+    assert_eq!(session.app.conversation_model.input.value(), "hello");
+}
+
+#[tokio::test]
+async fn channel_subscribers_get_mute_and_discussion_instead_of_a_composer() {
+    const NEWS: i64 = 46;
+    const TALK: i64 = 47;
+    let mut session = Session::logged_in(|cache| {
+        let mut news = chat(NEWS, "News");
+        news.chat_type = ChatType::Channel;
+        news.is_read_only = true;
+        news.linked_chat_id = TALK;
+        let mut talk = chat(TALK, "News talk");
+        talk.chat_type = ChatType::Supergroup;
+        FakeTelegram::new(cache)
+            .with_chat(news, vec![message(1, NEWS, "Headline", 5)])
+            .with_chat(talk, vec![message(2, TALK, "First!", 4)])
+    })
+    .await;
+    session.app.jump_to_chat(NEWS);
+    session.app.handle_chat_selected(NEWS).await;
+    session.app.focused_pane = FocusedPane::Conversation;
+
+    let screen = session.screen();
+    assert!(screen.contains("You can't send messages here"));
+    assert!(!screen.contains(" Message "));
+
+    session.press(KeyCode::Char('m')).await;
+    assert!(session.telegram.calls().contains(&Call::Mute {
+        chat_id: NEWS,
+        mute: true
+    }));
+
+    session.press(KeyCode::Char('d')).await;
+    assert_eq!(session.app.get_selected_chat_id(), Some(TALK));
+    assert!(session.screen().contains("First!"));
+}
+
+#[tokio::test]
+async fn qr_shows_my_link_as_a_scannable_code() {
+    let mut session = Session::logged_in(|cache| {
+        with_alice(cache).with_me(User {
+            id: 1,
+            first_name: "Frodo".to_string(),
+            username: "ringbearer".to_string(),
+            ..Default::default()
+        })
+    })
+    .await;
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+
+    session.submit("/qr me").await;
+    let screen = session.screen();
+    assert!(screen.contains("QR: Frodo"));
+    assert!(screen.contains("https://t.me/ringbearer"));
+    assert!(screen.contains('\u{2588}'));
+
+    session.press(KeyCode::Esc).await;
+    assert!(session.app.overlays.qr_view.is_none());
+
+    session.submit("/qr chat").await;
+    assert!(session
+        .screen()
+        .contains("This chat has no link you can share"));
+}
+
+#[tokio::test]
+async fn channel_owner_posts_in_the_group_as_their_channel() {
+    const GROUP: i64 = 77;
+    const CHANNEL: i64 = 88;
+    let mut session = Session::logged_in(|cache| {
+        let mut group = chat(GROUP, "Fan Club");
+        group.chat_type = ChatType::Supergroup;
+        let options = vec![
+            SendAsPeer {
+                id: 1,
+                name: "Me".to_string(),
+                is_self: true,
+                premium_required: false,
+            },
+            SendAsPeer {
+                id: CHANNEL,
+                name: "Daily Digest".to_string(),
+                is_self: false,
+                premium_required: false,
+            },
+        ];
+        FakeTelegram::new(cache)
+            .with_chat(group, vec![message(1, GROUP, "Welcome!", 1)])
+            .with_send_as(GROUP, options)
+    })
+    .await;
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+
+    session.submit("/sendas").await;
+    assert!(session.screen().contains("Post as"));
+    session.press(KeyCode::Down).await;
+    session.press(KeyCode::Enter).await;
+    assert!(session.screen().contains("Message as Daily Digest"));
+
+    session.submit("Hello from the channel").await;
+    assert!(session.telegram.calls().contains(&Call::SendMessage {
+        chat_id: GROUP,
+        text: "Hello from the channel".to_string(),
+        reply_to: None,
+        send_as: Some(CHANNEL),
+    }));
+}
+
+#[tokio::test]
+async fn chat_actions_menu_lists_members_and_pinned_messages() {
+    const GROUP: i64 = 77;
+    let mut session = Session::logged_in(|cache| {
+        let mut group = chat(GROUP, "Fan Club");
+        group.chat_type = ChatType::Supergroup;
+        let mut rules = message(1, GROUP, "Be kind to each other", 60);
+        rules.is_pinned = true;
+        let members = vec![User {
+            id: 5,
+            first_name: "Bob".to_string(),
+            username: "bobby".to_string(),
+            ..User::default()
+        }];
+        FakeTelegram::new(cache)
+            .with_chat(group, vec![rules, message(2, GROUP, "Morning all", 1)])
+            .with_members(GROUP, members)
+    })
+    .await;
+    session.press(KeyCode::Enter).await;
+
+    session.press_alt('a').await;
+    assert!(session.screen().contains("Shared media"));
+    session.press(KeyCode::Char('u')).await;
+    assert!(session.screen().contains("Members of Fan Club"));
+    assert!(session.screen().contains("@bobby"));
+    session.press(KeyCode::Esc).await;
+
+    session.press_alt('a').await;
+    session.press(KeyCode::Char('p')).await;
+    assert!(session
+        .screen()
+        .contains("Search: Pinned messages in Fan Club (1)"));
+}
+
+#[tokio::test]
+async fn quick_switcher_starts_a_conversation_by_username() {
+    const NIKOLAI: i64 = 99;
+    let mut session = Session::logged_in(|cache| {
+        with_alice(cache).with_stranger(
+            PeerHandle::Username("nik_d".to_string()),
+            chat(NIKOLAI, "Nikolai"),
+            Vec::new(),
+        )
+    })
+    .await;
+
+    session.press_ctrl('k').await;
+    session.type_text("@nik_d").await;
+    assert!(session.screen().contains("Message @nik_d"));
+    session.press(KeyCode::Enter).await;
+    assert_eq!(session.app.selected_chat_id, Some(NIKOLAI));
+    assert!(session.screen().contains("Nikolai"));
+
+    session.press(KeyCode::Char('i')).await;
+    session.submit("Hi, it's me from the meetup").await;
+    assert!(session.telegram.calls().contains(&Call::SendMessage {
+        chat_id: NIKOLAI,
+        text: "Hi, it's me from the meetup".to_string(),
+        reply_to: None,
+        send_as: None,
+    }));
+
+    session.press_ctrl('k').await;
+    session.type_text("@nobody_here").await;
+    session.press(KeyCode::Enter).await;
+    let toast = session.app.toasts.current().unwrap();
+    assert!(toast
+        .text
+        .ends_with("No one on Telegram goes by @nobody_here"));
+}
+
+#[tokio::test]
+async fn reactions_are_counted_for_the_quick_row_and_picked_by_digit() {
+    const THUMBS_UP: &str = "\u{1f44d}";
+    let mut session = Session::logged_in(with_alice).await;
+    let dir = std::env::temp_dir().join(format!("ithil_reactions_flow_{}", std::process::id()));
+    session.app.config.telegram.session_file = dir.join("ithil.session");
+    session.press(KeyCode::Enter).await;
+    let message_id = session
+        .app
+        .conversation_model
+        .selected_message()
+        .unwrap()
+        .id;
+
+    session.press(KeyCode::Char('+')).await;
+    assert!(session.screen().contains(" React "));
+    session.press(KeyCode::Enter).await;
+    assert!(session.telegram.calls().contains(&Call::React {
+        chat_id: ALICE,
+        message_id,
+        reaction: Some(THUMBS_UP.to_string()),
+    }));
+    let reactions_file = state::reactions_file(&session.app.config);
+    assert_eq!(
+        state::load_reactions(&reactions_file).quick(),
+        vec![THUMBS_UP]
+    );
+    let reacted = |session: &Session| {
+        session
+            .app
+            .conversation_model
+            .selected_message()
+            .and_then(|m| m.my_reaction().map(ToString::to_string))
+    };
+    assert_eq!(reacted(&session), Some(THUMBS_UP.to_string()));
+
+    // Now first in the quick row, and picking it again takes it back
+    session.press(KeyCode::Char('+')).await;
+    session.press(KeyCode::Char('1')).await;
+    assert_eq!(
+        session.telegram.calls().last(),
+        Some(&Call::React {
+            chat_id: ALICE,
+            message_id,
+            reaction: None,
+        })
+    );
+    assert_eq!(reacted(&session), None);
+    std::fs::remove_dir_all(&dir).unwrap();
+}
+
+#[tokio::test]
+async fn narrow_terminals_show_one_pane_at_a_time() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.app.show_sidebar = true;
+    let panes = |session: &Session| {
+        session
+            .app
+            .pane_areas
+            .iter()
+            .map(|(name, _)| *name)
+            .collect::<Vec<_>>()
+    };
+
+    session.screen_at(60);
+    assert_eq!(panes(&session), ["screen", "chat list", "status bar"]);
+
+    // Opening the chat swaps the list for it, and Tab flips back
+    session.press(KeyCode::Enter).await;
+    session.screen_at(60);
+    assert_eq!(panes(&session), ["screen", "conversation", "status bar"]);
+    session.press(KeyCode::Tab).await;
+    session.screen_at(60);
+    assert_eq!(panes(&session), ["screen", "chat list", "status bar"]);
+
+    // Wide again, everything is back
+    session.screen();
+    assert_eq!(
+        panes(&session),
+        [
+            "screen",
+            "chat list",
+            "conversation",
+            "sidebar",
+            "status bar"
+        ]
+    );
+}
+
+#[tokio::test]
+async fn download_saves_a_chats_photos_a_few_at_a_time() {
+    let photos = (1..=5)
+        .map(|id| {
+            let mut photo = message(id, ALICE, "", 60 - id);
+            photo.content.content_type = MessageType::Photo;
+            photo
+        })
+        .collect();
+    let mut session = Session::logged_in(|cache| {
+        FakeTelegram::new(cache).with_chat(chat(ALICE, "Alice"), photos)
+    })
+    .await;
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+
+    session.submit("/download photo 4").await;
+    let folder = session
+        .app
+        .config
+        .ui
+        .behavior
+        .download_directory
+        .join("Alice");
+    let downloads = |session: &Session| {
+        session
+            .telegram
+            .calls()
+            .into_iter()
+            .filter_map(|call| match call {
+                Call::Download {
+                    message_id,
+                    save_to,
+                    ..
+                } => {
+                    assert_eq!(save_to.as_ref(), Some(&folder));
+                    Some(message_id)
+                },
+                _ => None,
+            })
+            .collect::<Vec<_>>()
+    };
+    // Newest first, three at once
+    assert_eq!(downloads(&session), [5, 4, 3]);
+    assert!(session.screen().contains("\u{2193}0/4"));
+
+    let finish = |message_id: i64, state: FileDownloadState| Update {
+        update_type: UpdateType::FileDownload,
+        chat_id: ALICE,
+        message: Some(Box::new(Message {
+            id: message_id,
+            chat_id: ALICE,
+            ..Message::default()
+        })),
+        data: UpdateData::FileDownload(Box::new(FileDownload {
+            state,
+            ..FileDownload::default()
+        })),
+    };
+    session
+        .app
+        .handle_update(finish(4, FileDownloadState::Failed));
+    assert_eq!(downloads(&session), [5, 4, 3, 2]);
+    for id in [5, 3, 2] {
+        session
+            .app
+            .handle_update(finish(id, FileDownloadState::Completed));
+    }
+    assert!(session.app.bulk_download.is_none());
+    assert_eq!(
+        session.app.toasts.current().map(|t| t.text.clone()),
+        Some(format!("Saved 3 of 4 to {} (1 failed)", folder.display()))
+    );
+}
+
+#[tokio::test]
+async fn highlight_words_mark_the_chat_until_it_is_opened() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.app.config.highlights.words = Keywords::new(["urgent"]);
+    session.telegram.receive(ALICE, ALICE, "Not urgently").await;
+    session.sync().await;
+    assert!(!session.screen().contains(" ! "));
+
+    session
+        .telegram
+        .receive(ALICE, ALICE, "URGENT: call me")
+        .await;
+    session.sync().await;
+    assert!(session.screen().contains(" ! "));
+
+    // Opening the chat clears the badge; the message itself stays marked,
+    // on its first line as it follows on from Alice's last one
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('k')).await;
+    let screen = session.screen();
+    assert!(screen.contains("! URGENT: call me"));
+    assert!(screen
+        .lines()
+        .filter(|line| line.contains(" ! "))
+        .all(|line| line.contains("! URGENT")));
+}
+
+#[tokio::test]
+async fn chats_the_user_joins_or_runs_are_tagged_until_opened() {
+    let mut ops = chat(7, "Ops");
+    ops.chat_type = ChatType::Supergroup;
+    let mut session =
+        Session::logged_in(|cache| with_alice(cache).with_chat(ops, Vec::new())).await;
+    let membership = |chat_id: i64, change: MembershipChange| Update {
+        update_type: UpdateType::Membership,
+        chat_id,
+        message: None,
+        data: UpdateData::Membership(change),
+    };
+
+    session
+        .app
+        .handle_update(membership(7, MembershipChange::Added));
+    session.sync().await;
+    assert_eq!(
+        session.app.toasts.current().map(|t| t.text.clone()),
+        Some("Added to Ops".to_string())
+    );
+    assert!(session.screen().contains(" NEW "));
+
+    session
+        .app
+        .handle_update(membership(ALICE, MembershipChange::Promoted));
+    session.sync().await;
+    assert_eq!(
+        session.app.toasts.current().map(|t| t.text.clone()),
+        Some("You're now an admin in Alice".to_string())
+    );
+    assert!(session.screen().contains(" ADMIN "));
+
+    // Opening a chat takes its tag away
+    session.app.handle_chat_selected(7).await;
+    session.sync().await;
+    let screen = session.screen();
+    assert!(!screen.contains(" NEW "));
+    assert!(screen.contains(" ADMIN "));
+}
+
+#[tokio::test]
+async fn bookmarks_are_kept_locally_and_lead_back_to_the_message() {
+    let mut session = Session::logged_in(with_alice).await;
+    let dir = std::env::temp_dir().join(format!("ithil_bookmark_flow_{}", std::process::id()));
+    session.app.config.telegram.session_file = dir.join("ithil.session");
+    session.press(KeyCode::Enter).await;
+
+    // b on a bookmarked message takes the bookmark back
+    session.press(KeyCode::Char('b')).await;
+    session.press(KeyCode::Char('k')).await;
+    session.press(KeyCode::Char('b')).await;
+    session.press(KeyCode::Char('j')).await;
+    session.press(KeyCode::Char('b')).await;
+    assert_eq!(
+        session.app.toasts.current().map(|t| t.text.clone()),
+        Some("Bookmark removed".to_string())
+    );
+
+    session.press_alt('b').await;
+    let screen = session.screen();
+    assert!(screen.contains("Bookmarks (1)"));
+    assert!(screen.contains("Are you around?"));
+    session.press(KeyCode::Enter).await;
+    assert!(session.app.overlays.bookmark_list.is_none());
+    assert_eq!(
+        session
+            .app
+            .conversation_model
+            .selected_message()
+            .map(|m| m.id),
+        Some(1)
+    );
+    assert!(session
+        .telegram
+        .calls()
+        .iter()
+        .all(|call| !matches!(call, Call::SendMessage { .. })));
+
+    session.press_alt('b').await;
+    session.press(KeyCode::Char('d')).await;
+    assert!(session.screen().contains("Bookmarks (0)"));
+    std::fs::remove_dir_all(&dir).unwrap();
+}
+
+#[tokio::test]
+async fn local_pins_are_listed_per_chat_without_telegram() {
+    let mut session = Session::logged_in(with_alice).await;
+    let dir = std::env::temp_dir().join(format!("ithil_local_pin_flow_{}", std::process::id()));
+    session.app.config.telegram.session_file = dir.join("ithil.session");
+    session.press(KeyCode::Enter).await;
+
+    let calls = session.telegram.calls().len();
+    session.press(KeyCode::Char('k')).await;
+    session.press(KeyCode::Char('P')).await;
+    assert_eq!(
+        session.app.toasts.current().map(|t| t.text.clone()),
+        Some("Pinned locally (Alt+P to list)".to_string())
+    );
+    session.press(KeyCode::Char('j')).await;
+
+    session.press_alt('p').await;
+    let screen = session.screen();
+    assert!(screen.contains("Pinned locally in Alice (1)"));
+    assert!(screen.contains("Are you around?"));
+    // Telegram never hears of it
+    assert_eq!(session.telegram.calls().len(), calls);
+    session.press(KeyCode::Enter).await;
+    assert!(session.app.overlays.pin_board.is_none());
+    assert_eq!(
+        session
+            .app
+            .conversation_model
+            .selected_message()
+            .map(|m| m.id),
+        Some(1)
+    );
+
+    session.press_alt('p').await;
+    session.press(KeyCode::Char('d')).await;
+    assert!(session.screen().contains("Pinned locally in Alice (0)"));
+    std::fs::remove_dir_all(&dir).unwrap();
+}
+
+#[tokio::test]
+async fn an_encrypted_chats_local_pins_are_sealed_and_read_after_unlocking() {
+    let mut session = Session::logged_in(with_alice).await;
+    let dir = std::env::temp_dir().join(format!("ithil_encrypted_flow_{}", std::process::id()));
+    session.app.config.telegram.session_file = dir.join("ithil.session");
+    session.press(KeyCode::Enter).await;
+
+    // The key comes from the lock passphrase
+    session.press(KeyCode::Char('i')).await;
+    session.submit("/encrypt").await;
+    assert!(session
+        .app
+        .toasts
+        .current()
+        .map_or(false, |t| t.text.starts_with("Set a lock passphrase first")));
+    session.press(KeyCode::Esc).await;
+
+    let privacy = &mut session.app.config.privacy;
+    privacy.lock_passphrase_hash = crate::utils::hash_passphrase("pw");
+    privacy.encrypted_chats = vec![ALICE];
+    session.press_ctrl('l').await;
+    session.submit("pw").await;
+    assert!(session.app.lock_screen.is_none());
+
+    session.press(KeyCode::Char('k')).await;
+    session.press(KeyCode::Char('P')).await;
+    let stored = std::fs::read_to_string(dir.join(state::LOCAL_PINS_FILE)).unwrap();
+    assert!(stored.contains("\"sealed\""));
+    assert!(!stored.contains("Are you around?"));
+    session.press_alt('p').await;
+    assert!(session.screen().contains("Are you around?"));
+    session.press(KeyCode::Esc).await;
+
+    // Locked, the key is gone; unlocked, it's back
+    session.press_ctrl('l').await;
+    assert!(session.app.vault.is_none());
+    session.submit("pw").await;
+    session.press_alt('p').await;
+    assert!(session.screen().contains("Are you around?"));
+    std::fs::remove_dir_all(&dir).unwrap();
+}
+
+#[tokio::test]
+async fn an_encrypted_chat_is_not_exported_in_plain_text() {
+    let mut session = Session::logged_in(with_alice).await;
+    let dir = std::env::temp_dir().join(format!("ithil_export_flow_{}", std::process::id()));
+    session.app.config.cache.media_directory = dir.join("media");
+    let exports = session.app.config.cache.exports_directory();
+    session.app.config.privacy.encrypted_chats = vec![ALICE];
+    session.press(KeyCode::Enter).await;
+
+    session.press(KeyCode::Char('i')).await;
+    session.submit("/export").await;
+    let toast = session.app.toasts.current().unwrap();
+    assert!(toast.text.contains("This chat is encrypted"));
+    assert!(!exports.exists());
+
+    // Once it's no longer encrypted, it can be
+    session.app.config.privacy.encrypted_chats.clear();
+    session.submit("/export").await;
+    let exported = std::fs::read_dir(&exports).unwrap().count();
+    assert_eq!(exported, 1);
+    std::fs::remove_dir_all(&dir).unwrap();
+}
+
+#[tokio::test]
+async fn dump_saves_the_screen_and_state_when_debugging() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+    session.submit("/dump").await;
+    let toast = session.app.toasts.current().unwrap();
+    assert!(toast.text.contains("/dump needs debug: true"));
+
+    let dir = std::env::temp_dir().join(format!("ithil_dump_test_{}", std::process::id()));
+    session.app.config.logging.debug = true;
+    session.app.config.logging.file = dir.join("ithil.log");
+    session
+        .telegram
+        .receive(ALICE, ALICE, "Are you there?")
+        .await;
+    session.sync().await;
+    session.screen();
+    session.submit("/dump").await;
+
+    let path = std::fs::read_dir(&dir)
+        .unwrap()
+        .map(|entry| entry.unwrap().path())
+        .find(|path| path.to_string_lossy().contains("ithil-dump-"))
+        .unwrap();
+    let dump = std::fs::read_to_string(&path).unwrap();
+    assert!(dump.contains("Open chat: 42"));
+    assert!(dump.contains("Conversation: 3 messages"));
+    assert!(dump.contains("conversation: "));
+    assert!(dump.contains("NewMessage chat 42 message 3"));
+    // The screen's text, including the message, without styling
+    assert!(dump.contains("Are you there?"));
+    assert!(!dump.contains('\u{1b}'));
+    std::fs::remove_dir_all(&dir).unwrap();
+}
+
+#[tokio::test]
+async fn quick_switcher_searches_telegram_for_chats_not_in_the_list() {
+    const NIKOLAI: i64 = 99;
+    let mut session = Session::logged_in(|cache| {
+        let mut nikolai = chat(NIKOLAI, "Nikolai");
+        nikolai.username = "nik_d".to_string();
+        with_alice(cache).with_stranger(
+            PeerHandle::Username("nik_d".to_string()),
+            nikolai,
+            Vec::new(),
+        )
+    })
+    .await;
+
+    session.press_ctrl('k').await;
+    session.type_text("niko").await;
+    assert!(session.screen().contains("No matching chats"));
+
+    // Once typing pauses, Telegram's results join the list
+    session
+        .app
+        .apply_batched(Instant::now() + std::time::Duration::from_secs(1))
+        .await;
+    let screen = session.screen();
+    assert!(screen.contains("Nikolai"));
+    assert!(screen.contains("@nik_d  \u{2022} search"));
+
+    session.press(KeyCode::Enter).await;
+    assert_eq!(session.app.selected_chat_id, Some(NIKOLAI));
+}
+
+#[tokio::test]
+async fn forward_names_its_channel_and_jumps_to_the_original_post() {
+    const NEWS: i64 = 500;
+    let mut session = Session::logged_in(|cache| {
+        let mut news = chat(NEWS, "Tech News");
+        news.chat_type = ChatType::Channel;
+        let mut shared = message(3, ALICE, "Launch day!", 1);
+        shared.is_forwarded = true;
+        shared.forward_info = Some(ForwardInfo {
+            origin: ForwardOrigin::Channel,
+            from_chat_id: NEWS,
+            message_id: 77,
+            date: Utc::now() - Duration::hours(3),
+            ..ForwardInfo::default()
+        });
+        FakeTelegram::new(cache)
+            .with_chat(chat(ALICE, "Alice"), vec![shared])
+            .with_chat(
+                news,
+                vec![
+                    message(77, NEWS, "Launch day!", 180),
+                    message(78, NEWS, "Follow-up", 120),
+                ],
+            )
+    })
+    .await;
+    session.press(KeyCode::Enter).await;
+    assert!(session.screen().contains("Forwarded from Tech News"));
+
+    session.press(KeyCode::Char('O')).await;
+    assert_eq!(session.app.get_selected_chat_id(), Some(NEWS));
+    assert_eq!(
+        session
+            .app
+            .conversation_model
+            .selected_message()
+            .map(|m| m.id),
+        Some(77)
+    );
+}
+
+#[tokio::test]
+async fn only_chats_forwarded_to_become_recent_destinations() {
+    const GONE: i64 = 404;
+    let mut session = Session::logged_in(with_alice).await;
+    let dir = std::env::temp_dir().join(format!("ithil_forward_flow_{}", std::process::id()));
+    session.app.config.telegram.session_file = dir.join("ithil.session");
+
+    let action = AppAction::ForwardMessage(ALICE, 1, vec![GONE, ALICE], ForwardOptions::default());
+    session.app.handle_app_action(action).await;
+    let toast = session.app.toasts.current().unwrap();
+    assert!(toast.text.contains("Forwarded to 1 of 2 chats"));
+    let path = state::forward_targets_file(&session.app.config);
+    assert_eq!(state::load_forward_targets(&path), vec![ALICE]);
+    std::fs::remove_dir_all(&dir).unwrap();
+}
+
+#[tokio::test]
+async fn reply_jumps_to_the_older_message_it_answers_and_back() {
+    let mut session = Session::logged_in(|cache| {
+        let mut history = vec![message(1, ALICE, "Where shall we meet?", 500)];
+        history.extend((2..80).map(|id| message(id, ALICE, "chatter", 500 - id)));
+        history.push(Message {
+            reply_to_message_id: 1,
+            ..message(80, ALICE, "At the station", 1)
+        });
+        FakeTelegram::new(cache).with_chat(chat(ALICE, "Alice"), history)
+    })
+    .await;
+    session.press(KeyCode::Enter).await;
+    let selected = |session: &Session| {
+        session
+            .app
+            .conversation_model
+            .selected_message()
+            .map(|m| m.id)
+    };
+    assert_eq!(selected(&session), Some(80));
+
+    session.press(KeyCode::Char('R')).await;
+    assert_eq!(selected(&session), Some(1));
+    assert!(session.screen().contains("Where shall we meet?"));
+
+    let back = KeyEvent::new(KeyCode::Left, KeyModifiers::ALT);
+    assert!(session.app.handle_key(back).is_none());
+    assert_eq!(selected(&session), Some(80));
+}
+
+#[tokio::test]
+async fn channel_view_mutes_leaves_and_archives_channels_in_bulk() {
+    const NEWS: i64 = 46;
+    const ART: i64 = 48;
+    const MEMES: i64 = 49;
+    let mut session = Session::logged_in(|cache| {
+        let mut fake = with_alice(cache);
+        for (id, title, subscribers) in [
+            (NEWS, "News", 12_345),
+            (ART, "Art", 800),
+            (MEMES, "Memes", 2_000_000),
+        ] {
+            let mut channel = chat(id, title);
+            channel.chat_type = ChatType::Channel;
+            channel.member_count = subscribers;
+            fake = fake.with_chat(channel, vec![message(id, id, "Post", 60)]);
+        }
+        fake
+    })
+    .await;
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+    session.submit("/channels").await;
+
+    let screen = session.screen();
+    assert!(screen.contains("Channels (3, by name)"));
+    assert!(screen.contains("12.3K subs"));
+
+    // Art and Memes are marked; the highlight ends on News
+    session.press(KeyCode::Char(' ')).await;
+    session.press(KeyCode::Char(' ')).await;
+    session.press(KeyCode::Char('m')).await;
+    let calls = session.telegram.calls();
+    assert!(calls.contains(&Call::Mute {
+        chat_id: ART,
+        mute: true
+    }));
+    assert!(calls.contains(&Call::Mute {
+        chat_id: MEMES,
+        mute: true
+    }));
+    assert_eq!(
+        session.app.toasts.current().unwrap().text,
+        "Muted 2 channels"
+    );
+    assert!(session.screen().contains("muted"));
+
+    session.press(KeyCode::Char('L')).await;
+    assert!(session.screen().contains("Leave News?"));
+    session.press(KeyCode::Char('y')).await;
+    assert!(session.telegram.calls().contains(&Call::Leave(NEWS)));
+    assert!(session.app.cache.get_chat(NEWS).is_none());
+    assert!(session.screen().contains("Channels (2, by name)"));
+
+    session.press(KeyCode::Char('a')).await;
+    assert!(session.telegram.calls().contains(&Call::Archive {
+        chat_id: MEMES,
+        archive: true
+    }));
+    assert!(session.app.cache.get_chat(MEMES).is_none());
+    assert!(session.app.cache.get_chat(ART).is_some());
+}
+
+#[tokio::test]
+async fn startup_opens_the_chat_asked_for_once_the_list_loads() {
+    let mut session = Session::start(|cache| {
+        let mut news = chat(46, "News");
+        news.username = "dailynews".to_string();
+        with_alice(cache)
+            .with_chat(news, vec![message(3, 46, "Headline", 1)])
+            .logged_in()
+    });
+    session
+        .app
+        .set_startup_view(StartupView::Handle(PeerHandle::Username(
+            "dailynews".to_string(),
+        )));
+    session.app.update_auth_state(AuthState::Ready);
+    session.app.on_authorized().await;
+
+    assert_eq!(session.app.get_selected_chat_id(), Some(46));
+    assert_eq!(session.app.focused_pane, FocusedPane::Conversation);
+    assert!(session.screen().contains("Headline"));
+
+    // Saved Messages is the user's own chat
+    let mut session = Session::start(|cache| {
+        with_alice(cache)
+            .with_chat(
+                chat(1, "Saved Messages"),
+                vec![message(4, 1, "Note to self", 1)],
+            )
+            .logged_in()
+    });
+    session.app.set_startup_view(StartupView::SavedMessages);
+    session.app.update_auth_state(AuthState::Ready);
+    session.app.on_authorized().await;
+    assert_eq!(session.app.get_selected_chat_id(), Some(1));
+    assert!(session.screen().contains("Note to self"));
+}
+
+#[tokio::test]
+async fn link_to_a_channel_post_previews_it_until_joined() {
+    const NEWS: i64 = 46;
+    let mut session = Session::start(|cache| {
+        let mut news = chat(NEWS, "Daily News");
+        news.chat_type = ChatType::Channel;
+        news.username = "dailynews".to_string();
+        with_alice(cache)
+            .with_stranger(
+                PeerHandle::Username("dailynews".to_string()),
+                news,
+                vec![
+                    message(7, NEWS, "Yesterday's story", 60),
+                    message(8, NEWS, "Today's story", 1),
+                ],
+            )
+            .logged_in()
+    });
+    session.app.set_startup_view(StartupView::Link(
+        DeepLink::parse("https://t.me/dailynews/7").unwrap(),
+    ));
+    session.app.update_auth_state(AuthState::Ready);
+    session.app.on_authorized().await;
+
+    // The post shows read-only, without joining or adding the channel
+    let selected = |session: &Session| {
+        session
+            .app
+            .conversation_model
+            .selected_message()
+            .map(|m| m.id)
+    };
+    assert_eq!(session.app.get_selected_chat_id(), Some(NEWS));
+    assert_eq!(selected(&session), Some(7));
+    assert!(!session.telegram.calls().contains(&Call::Join(NEWS)));
+    assert!(session.app.cache.get_chat(NEWS).is_none());
+    assert!(session.screen().contains("/join to join"));
+
+    session.press(KeyCode::Char('i')).await;
+    session.submit("/join").await;
+
+    assert!(session.telegram.calls().contains(&Call::Join(NEWS)));
+    assert!(session.app.cache.get_chat(NEWS).is_some());
+    assert_eq!(session.app.get_selected_chat_id(), Some(NEWS));
+    assert_eq!(selected(&session), Some(7));
+}
+
+#[tokio::test]
+async fn leaving_a_channel_preview_drops_it() {
+    const NEWS: i64 = 46;
+    let mut session = Session::start(|cache| {
+        let mut news = chat(NEWS, "Daily News");
+        news.chat_type = ChatType::Channel;
+        with_alice(cache)
+            .with_stranger(
+                PeerHandle::Username("dailynews".to_string()),
+                news,
+                vec![message(8, NEWS, "Today's story", 1)],
+            )
+            .logged_in()
+    });
+    session.app.update_auth_state(AuthState::Ready);
+    session.app.on_authorized().await;
+    session
+        .app
+        .handle_resolve_chat(&PeerHandle::Username("dailynews".to_string()))
+        .await;
+    assert!(session.screen().contains("Today's story"));
+
+    session.app.handle_chat_selected(ALICE).await;
+
+    assert!(session.app.preview.is_none());
+    assert!(session.app.cache.get_chat(NEWS).is_none());
+    assert!(!session.screen().contains("Today's story"));
+    assert!(!session.telegram.calls().contains(&Call::Join(NEWS)));
+}
+
+#[tokio::test]
+async fn reads_on_another_device_leave_a_marker_in_the_conversation() {
+    let mut session = Session::logged_in(with_alice).await;
+
+    // Read the first message on the phone while the terminal looks elsewhere
+    session.telegram.read_elsewhere(ALICE, 1).await;
+    session.sync().await;
+    session.press(KeyCode::Enter).await;
+
+    assert_eq!(
+        session.app.conversation_model.read_elsewhere_index(),
+        Some(0)
+    );
+    assert!(session.screen().contains("read on another device"));
+}
+
+#[tokio::test]
+async fn a_burst_of_messages_is_marked_read_once() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.press(KeyCode::Enter).await;
+    let reads = |session: &Session| {
+        session
+            .telegram
+            .calls()
+            .iter()
+            .filter(|c| **c == Call::MarkAsRead(ALICE))
+            .count()
+    };
+    let before = reads(&session);
+
+    for i in 0..12 {
+        session
+            .telegram
+            .receive(ALICE, ALICE, &format!("Message {i}"))
+            .await;
+    }
+    session.app.process_updates().await;
+
+    // The messages show at once; the read receipt waits for the burst
+    assert_eq!(session.app.conversation_model.messages.len(), 14);
+    assert_eq!(reads(&session), before);
+
+    session.sync().await;
+    assert_eq!(reads(&session), before + 1);
+}
+
+#[tokio::test]
+async fn messages_are_not_marked_read_once_the_chat_is_left() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.press(KeyCode::Enter).await;
+    session
+        .telegram
+        .receive(ALICE, ALICE, "Are you there?")
+        .await;
+    session.app.process_updates().await;
+    let before = session.telegram.calls().len();
+
+    // Back to the chat list before the read receipt went out
+    session
+        .press_key(KeyEvent::new(KeyCode::BackTab, KeyModifiers::SHIFT))
+        .await;
+    assert_eq!(session.app.focused_pane, FocusedPane::ChatList);
+    session.sync().await;
+    assert!(!session.telegram.calls()[before..].contains(&Call::MarkAsRead(ALICE)));
+}
+
+#[tokio::test]
+async fn listen_plays_unplayed_voice_messages_in_turn() {
+    let voice = |id, minutes_ago| {
+        let mut message = message(id, ALICE, "", minutes_ago);
+        message.content.content_type = MessageType::Voice;
+        message.media_unread = true;
+        message
+    };
+    let mut played = voice(3, 6);
+    played.media_unread = false;
+    let mut session = Session::logged_in(|cache| {
+        FakeTelegram::new(cache).with_chat(
+            chat(ALICE, "Alice"),
+            vec![
+                voice(2, 8),
+                played,
+                voice(4, 4),
+                message(5, ALICE, "Sorry, long one", 2),
+            ],
+        )
+    })
+    .await;
+    // A player that finishes straight away
+    session.app.config.ui.behavior.voice_player = "true".to_string();
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+
+    session.submit("/listen").await;
+    // The list and each download arrive in the background
+    let first = Call::MarkPlayed {
+        chat_id: ALICE,
+        message_id: 2,
+    };
+    let started = Instant::now();
+    while !session.telegram.calls().contains(&first) && started.elapsed().as_secs() < 5 {
+        tokio::task::yield_now().await;
+        session.sync().await;
+    }
+    assert!(session.telegram.calls().contains(&first));
+    assert!(session.screen().contains("Alice (1/2)"));
+
+    let started = Instant::now();
+    while session.app.listening.is_some() && started.elapsed().as_secs() < 5 {
+        tokio::time::sleep(std::time::Duration::from_millis(10)).await;
+        session.sync().await;
+    }
+    let marked: Vec<Call> = session
+        .telegram
+        .calls()
+        .into_iter()
+        .filter(|call| matches!(call, Call::MarkPlayed { .. }))
+        .collect();
+    assert_eq!(
+        marked,
+        [
+            Call::MarkPlayed {
+                chat_id: ALICE,
+                message_id: 2
+            },
+            Call::MarkPlayed {
+                chat_id: ALICE,
+                message_id: 4
+            }
+        ]
+    );
+    assert!(session.app.listening.is_none());
+}
+
+#[tokio::test]
+async fn alt_c_sends_a_staged_image_as_a_file() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+    session
+        .app
+        .conversation_model
+        .set_pending_attachment(std::path::PathBuf::from("/tmp/cat.png"));
+    assert!(session.screen().contains("as photo (compressed)"));
+
+    session.press_alt('c').await;
+    assert!(session.screen().contains("as file (original quality)"));
+    session.submit("uncompressed").await;
+
+    assert!(session.telegram.calls().contains(&Call::SendFile {
+        chat_id: ALICE,
+        caption: "uncompressed".to_string(),
+        path: std::path::PathBuf::from("/tmp/cat.png"),
+        as_photo: false,
+    }));
+}
+
+#[tokio::test]
+async fn a_staged_file_is_described_above_the_composer() {
+    let path = std::env::temp_dir().join(format!("ithil_strip_{}.png", std::process::id()));
+    std::fs::write(
+        &path,
+        b"\x89PNG\r\n\x1a\n\0\0\0\x0dIHDR\0\0\x02\x80\0\0\x01\xe0",
+    )
+    .unwrap();
+    let mut session = Session::logged_in(with_alice).await;
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+    session
+        .app
+        .conversation_model
+        .set_pending_attachment(path.clone());
+    std::fs::remove_file(&path).unwrap();
+
+    let screen = session.screen();
+    assert!(screen.contains("24 B \u{2022} PNG image \u{2022} 640\u{d7}480"));
+    assert!(screen.contains("Ctrl+T to add another"));
+
+    // Ctrl+T picks more files; Esc removes them
+    session.press_ctrl('t').await;
+    assert!(session.app.overlays.file_picker.is_some());
+    session.press(KeyCode::Esc).await;
+    session.press(KeyCode::Esc).await;
+    assert!(session
+        .app
+        .conversation_model
+        .pending_attachment()
+        .is_none());
+    assert!(!session.screen().contains("PNG image"));
+}
+
+#[tokio::test]
+async fn several_images_go_as_an_album_and_mixed_files_one_by_one() {
+    let mut session = Session::logged_in(with_alice).await;
+    session.press(KeyCode::Enter).await;
+    session.press(KeyCode::Char('i')).await;
+    let cat = std::path::PathBuf::from("/tmp/cat.png");
+    let dog = std::path::PathBuf::from("/tmp/dog.jpg");
+    let notes = std::path::PathBuf::from("/tmp/notes.pdf");
+
+    session
+        .app
+        .conversation_model
+        .add_pending_attachment(cat.clone());
+    session
+        .app
+        .conversation_model
+        .add_pending_attachment(dog.clone());
+    assert!(session.screen().contains("as an album of 2 photos"));
+    session.submit("Pets").await;
+    assert!(session.telegram.calls().contains(&Call::SendAlbum {
+        chat_id: ALICE,
+        caption: "Pets".to_string(),
+        paths: vec![cat.clone(), dog.clone()],
+    }));
+
+    // With a document among them, each goes on its own, the caption first
+    session
+        .app
+        .conversation_model
+        .add_pending_attachment(cat.clone());
+    session
+        .app
+        .conversation_model
+        .add_pending_attachment(notes.clone());
+    session.submit("Vet bill").await;
+    let files: Vec<Call> = session
+        .telegram
+        .calls()
+        .into_iter()
+        .filter(|call| matches!(call, Call::SendFile { .. }))
+        .collect();
+    assert_eq!(
+        files,
+        [
+            Call::SendFile {
+                chat_id: ALICE,
+                caption: "Vet bill".to_string(),
+                path: cat,
+                as_photo: true,
+            },
+            Call::SendFile {
+                chat_id: ALICE,
+                caption: String::new(),
+                path: notes,
+                as_photo: true,
+            }
+        ]
+    );
+    assert_eq!(session.app.conversation_model.attachment_count(), 0);
+}
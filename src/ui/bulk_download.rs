@@ -0,0 +1,321 @@
+//! Downloading a chat's attachments in bulk (`/download`).
+//!
+//! `/download` saves the open chat's photos and videos, newest first, into
+//! a folder named after the chat under the download directory. Arguments
+//! narrow it down, in any order:
+//!
+//! | Argument            | Effect                                         |
+//! |---------------------|------------------------------------------------|
+//! | `photo`             | Only photos (also `video`, `file`, `voice`, `audio`, `media`) |
+//! | `250`               | At most this many (100 by default)             |
+//! | `after:2w`          | Only those sent on or after the day            |
+//! | `before:2024-01-01` | Only those sent before the day                 |
+//! | `to:~/Pictures/trip`| Save into this folder instead                  |
+//!
+//! Dates take the same forms as "jump to date". A few files download at
+//! once; the status bar counts them off, and a notice says where they went
+//! when all are done. Files keep the names Save (`s`) gives them.
+//!
+//! # Example
+//!
+//! ```rust
+//! use chrono::NaiveDate;
+//! use ithil::types::SearchFilter;
+//! use ithil::ui::bulk_download::BulkRequest;
+//!
+//! let today = NaiveDate::from_ymd_opt(2024, 3, 15).unwrap();
+//! let request = BulkRequest::parse("photo 20 after:1w", today).unwrap();
+//! assert_eq!(request.kind, SearchFilter::Photo);
+//! assert_eq!(request.count, 20);
+//! assert_eq!(request.after, NaiveDate::from_ymd_opt(2024, 3, 8));
+//! ```
+
+use std::collections::VecDeque;
+use std::path::{Path, PathBuf};
+
+use chrono::{Local, NaiveDate};
+
+use crate::types::{Message, SearchFilter};
+use crate::utils::parse_date;
+
+/// How many attachments are saved when no count is given.
+pub const DEFAULT_COUNT: usize = 100;
+
+/// The most attachments one `/download` saves.
+pub const MAX_COUNT: usize = 1000;
+
+/// How many attachments download at once.
+pub const PARALLEL: usize = 3;
+
+/// What `/download` should save.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct BulkRequest {
+    /// Kind of attachment
+    pub kind: SearchFilter,
+    /// At most this many, newest first
+    pub count: usize,
+    /// Only those sent on or after this day
+    pub after: Option<NaiveDate>,
+    /// Only those sent before this day
+    pub before: Option<NaiveDate>,
+    /// Folder to save into, instead of one named after the chat
+    pub folder: Option<PathBuf>,
+}
+
+impl Default for BulkRequest {
+    fn default() -> Self {
+        Self {
+            kind: SearchFilter::Media,
+            count: DEFAULT_COUNT,
+            after: None,
+            before: None,
+            folder: None,
+        }
+    }
+}
+
+impl BulkRequest {
+    /// Parses the arguments of `/download`, reading relative dates against
+    /// `today`.
+    ///
+    /// # Errors
+    ///
+    /// Returns a message if an argument can't be understood.
+    pub fn parse(input: &str, today: NaiveDate) -> Result<Self, String> {
+        let mut request = Self::default();
+        for word in input.split_whitespace() {
+            if let Some((key, value)) = word.split_once(':') {
+                let date = || {
+                    parse_date(value, today)
+                        .ok_or_else(|| format!("{key}: \"{value}\" is not a date"))
+                };
+                match key.to_lowercase().as_str() {
+                    "after" => request.after = Some(date()?),
+                    "before" => request.before = Some(date()?),
+                    "to" if !value.is_empty() => request.folder = Some(expand_home(value)),
+                    _ => return Err(format!("Unknown option: {word}")),
+                }
+            } else if let Ok(count) = word.parse::<usize>() {
+                if !(1..=MAX_COUNT).contains(&count) {
+                    return Err(format!("Download between 1 and {MAX_COUNT} at a time"));
+                }
+                request.count = count;
+            } else {
+                request.kind = SearchFilter::from_name(word)
+                    .filter(|kind| !matches!(kind, SearchFilter::Link | SearchFilter::Pinned))
+                    .ok_or_else(|| {
+                        format!(
+                            "\"{word}\" is not one of media, photo, video, file, voice or audio"
+                        )
+                    })?;
+            }
+        }
+        Ok(request)
+    }
+
+    /// Returns `true` if a date bound is set, so more messages have to be
+    /// looked at than are saved.
+    #[must_use]
+    pub const fn is_dated(&self) -> bool {
+        self.after.is_some() || self.before.is_some()
+    }
+
+    /// Returns `true` if `message` has an attachment of the kind asked for,
+    /// sent within the dates.
+    #[must_use]
+    pub fn keeps(&self, message: &Message) -> bool {
+        let day = message.date.with_timezone(&Local).date_naive();
+        message.content.content_type.is_downloadable()
+            && self.kind.matches(message)
+            && self.before.map_or(true, |before| day < before)
+            && self.after.map_or(true, |after| day >= after)
+    }
+}
+
+/// Names the folder a chat's attachments go into after its title. A title
+/// that would leave no name, or name `.` or `..`, falls back to the chat's
+/// ID, so the files never land above the download directory.
+#[must_use]
+pub fn folder_name(title: &str, chat_id: i64) -> String {
+    let name = crate::telegram::media::sanitize_filename(title.trim());
+    if name.chars().all(|c| c == '.') {
+        chat_id.to_string()
+    } else {
+        name
+    }
+}
+
+/// Reads a leading `~/` as the home directory.
+fn expand_home(path: &str) -> PathBuf {
+    match (path.strip_prefix("~/"), dirs::home_dir()) {
+        (Some(rest), Some(home)) => home.join(rest),
+        _ => PathBuf::from(path),
+    }
+}
+
+/// Attachments being saved from one chat, a few at a time.
+#[derive(Debug)]
+pub struct BulkDownload {
+    chat_id: i64,
+    folder: PathBuf,
+    /// Not started yet, newest first
+    queue: VecDeque<Message>,
+    /// IDs of the messages downloading now
+    in_flight: Vec<i64>,
+    total: usize,
+    saved: usize,
+    failed: usize,
+}
+
+impl BulkDownload {
+    /// Queues `messages` from a chat to be saved into `folder`.
+    #[must_use]
+    pub fn new(chat_id: i64, folder: PathBuf, messages: Vec<Message>) -> Self {
+        Self {
+            chat_id,
+            folder,
+            total: messages.len(),
+            queue: messages.into(),
+            in_flight: Vec::new(),
+            saved: 0,
+            failed: 0,
+        }
+    }
+
+    /// Returns the folder the attachments go into.
+    #[must_use]
+    pub fn folder(&self) -> &Path {
+        &self.folder
+    }
+
+    /// Takes the next messages to download, as many as there is room for.
+    pub fn start_next(&mut self) -> Vec<Message> {
+        let room = PARALLEL.saturating_sub(self.in_flight.len());
+        let started: Vec<Message> = self.queue.drain(..room.min(self.queue.len())).collect();
+        self.in_flight.extend(started.iter().map(|m| m.id));
+        started
+    }
+
+    /// Notes that a download finished, or failed. Returns `false` if it
+    /// wasn't one of these.
+    pub fn finish(&mut self, chat_id: i64, message_id: i64, saved: bool) -> bool {
+        if chat_id != self.chat_id {
+            return false;
+        }
+        let Some(pos) = self.in_flight.iter().position(|&id| id == message_id) else {
+            return false;
+        };
+        self.in_flight.remove(pos);
+        if saved {
+            self.saved += 1;
+        } else {
+            self.failed += 1;
+        }
+        true
+    }
+
+    /// Returns how many are done, and how many there are in all.
+    #[must_use]
+    pub const fn progress(&self) -> (usize, usize) {
+        (self.saved + self.failed, self.total)
+    }
+
+    /// Returns `true` once every attachment has been tried.
+    #[must_use]
+    pub fn is_done(&self) -> bool {
+        self.queue.is_empty() && self.in_flight.is_empty()
+    }
+
+    /// Describes how it went.
+    #[must_use]
+    pub fn summary(&self) -> String {
+        let mut summary = format!(
+            "Saved {} of {} to {}",
+            self.saved,
+            self.total,
+            self.folder.display()
+        );
+        if self.failed > 0 {
+            summary.push_str(&format!(" ({} failed)", self.failed));
+        }
+        summary
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::types::{MessageContent, MessageType};
+
+    fn today() -> NaiveDate {
+        NaiveDate::from_ymd_opt(2024, 3, 15).unwrap()
+    }
+
+    fn photo(id: i64) -> Message {
+        Message {
+            id,
+            chat_id: 7,
+            content: MessageContent {
+                content_type: MessageType::Photo,
+                ..MessageContent::default()
+            },
+            ..Message::default()
+        }
+    }
+
+    #[test]
+    fn parses_kind_count_dates_and_folder_in_any_order() {
+        assert_eq!(BulkRequest::parse("", today()), Ok(BulkRequest::default()));
+        assert_eq!(
+            BulkRequest::parse("to:/tmp/trip 5 before:2024-03-01 video", today()),
+            Ok(BulkRequest {
+                kind: SearchFilter::Video,
+                count: 5,
+                before: NaiveDate::from_ymd_opt(2024, 3, 1),
+                folder: Some(PathBuf::from("/tmp/trip")),
+                ..BulkRequest::default()
+            })
+        );
+        assert!(BulkRequest::parse("links", today()).is_err());
+        assert!(BulkRequest::parse("0", today()).is_err());
+        assert!(BulkRequest::parse("after:someday", today()).is_err());
+        assert!(BulkRequest::parse("from:me", today()).is_err());
+    }
+
+    #[test]
+    fn folders_are_named_after_the_chat_but_stay_put() {
+        assert_eq!(folder_name("Trip / 2024", 7), "Trip _ 2024");
+        assert_eq!(folder_name("..", 7), "7");
+        assert_eq!(folder_name(" . ", 7), "7");
+        assert_eq!(folder_name("", 7), "7");
+        assert_eq!(folder_name("...and more", 7), "...and more");
+    }
+
+    #[test]
+    fn downloads_a_few_at_a_time_until_all_are_tried() {
+        let messages = (1..=5).map(photo).collect();
+        let mut bulk = BulkDownload::new(7, PathBuf::from("out"), messages);
+
+        let first: Vec<i64> = bulk.start_next().iter().map(|m| m.id).collect();
+        assert_eq!(first, [1, 2, 3]);
+        assert!(bulk.start_next().is_empty());
+
+        assert!(!bulk.finish(8, 1, true));
+        assert!(!bulk.finish(7, 4, true));
+        assert!(bulk.finish(7, 2, true));
+        assert_eq!(
+            bulk.start_next().iter().map(|m| m.id).collect::<Vec<_>>(),
+            [4]
+        );
+
+        for (id, saved) in [(1, true), (3, false), (4, true)] {
+            assert!(bulk.finish(7, id, saved));
+        }
+        assert_eq!(bulk.progress(), (4, 5));
+        assert!(!bulk.is_done());
+        assert_eq!(bulk.start_next().len(), 1);
+        assert!(bulk.finish(7, 5, true));
+        assert!(bulk.is_done());
+        assert_eq!(bulk.summary(), "Saved 4 of 5 to out (1 failed)");
+    }
+}
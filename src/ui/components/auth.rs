@@ -6,6 +6,14 @@
 //! 3. 2FA password input (if enabled)
 //! 4. Registration (for new accounts - typically redirects to official app)
 //!
+//! The phone number is checked and put in international form before it is
+//! sent; `Tab` opens a searchable list of country calling codes, and the
+//! country the number belongs to is shown below it.
+//!
+//! While waiting for the code, `Ctrl+R` asks for a new one once a countdown
+//! runs out. A code that didn't get through, or a network failure while
+//! checking one, never means starting over: the code can be entered again.
+//!
 //! # Example
 //!
 //! ```rust,no_run
@@ -17,7 +25,9 @@
 //! // Handle input, render, etc.
 //! ```
 
-use crossterm::event::{KeyCode, KeyEvent};
+use std::time::{Duration, Instant};
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
 use ratatui::{
     layout::{Alignment, Constraint, Layout, Rect},
     text::{Line, Span},
@@ -28,8 +38,15 @@ use ratatui::{
 use crate::types::AuthState;
 use crate::ui::styles::Styles;
 
+use crate::utils::{countries_for, normalize_phone, Country};
+
+use super::country_picker::{CountryPicker, CountryPickerAction};
 use super::input::{EchoMode, InputComponent};
 
+/// How long after a code is sent another may be asked for. Telegram lets a
+/// new code be requested about a minute after the last.
+pub const RESEND_WAIT: Duration = Duration::from_secs(60);
+
 /// Actions that the auth model can produce.
 ///
 /// These are returned to the caller for handling (e.g., making API calls).
@@ -39,6 +56,8 @@ pub enum AuthAction {
     SubmitPhoneNumber(String),
     /// User submitted verification code
     SubmitCode(String),
+    /// User asked for a new code to be sent to this phone number
+    ResendCode(String),
     /// User submitted 2FA password
     SubmitPassword(String),
     /// User submitted registration info
@@ -61,6 +80,14 @@ pub struct AuthModel {
     error_message: Option<String>,
     /// Whether an operation is in progress
     loading: bool,
+    /// The phone number the code was sent to
+    phone: String,
+    /// When a new code may be asked for, while waiting for one
+    resend_at: Option<Instant>,
+    /// Whether the operation in progress is sending a new code
+    resending: bool,
+    /// Country calling codes, while picking one for the phone number
+    country_picker: Option<CountryPicker>,
     /// Component dimensions
     width: u16,
     height: u16,
@@ -80,9 +107,13 @@ impl AuthModel {
     pub fn new() -> Self {
         let mut model = Self {
             auth_state: AuthState::WaitPhoneNumber,
-            input: InputComponent::new("Phone number (e.g., +1234567890)").with_char_limit(20),
+            input: InputComponent::new("Phone number (e.g., +1234567890)").with_char_limit(24),
             error_message: None,
             loading: false,
+            phone: String::new(),
+            resend_at: None,
+            resending: false,
+            country_picker: None,
             width: 80,
             height: 24,
         };
@@ -97,12 +128,32 @@ impl AuthModel {
     }
 
     /// Sets the authentication state and updates the input field accordingly.
+    ///
+    /// Entering (or re-entering, after a new code is sent) the code step
+    /// starts the countdown to when another code may be asked for.
     pub fn set_auth_state(&mut self, state: AuthState) {
         self.auth_state = state;
         self.loading = false;
+        self.resending = false;
+        self.country_picker = None;
+        self.resend_at = (state == AuthState::WaitCode).then(|| Instant::now() + RESEND_WAIT);
         self.update_input_for_state();
     }
 
+    /// Holds off asking for a new code for `wait`, as Telegram asked.
+    pub fn delay_resend(&mut self, wait: Duration) {
+        if self.auth_state == AuthState::WaitCode {
+            self.resend_at = Some(Instant::now() + wait);
+        }
+    }
+
+    /// Returns how long until a new code may be asked for, if one is being
+    /// waited for.
+    #[must_use]
+    pub fn resend_wait(&self, now: Instant) -> Option<Duration> {
+        self.resend_at.map(|at| at.saturating_duration_since(now))
+    }
+
     /// Sets an error message to display.
     pub fn set_error(&mut self, message: impl Into<String>) {
         self.error_message = Some(message.into());
@@ -117,6 +168,7 @@ impl AuthModel {
     /// Sets the loading state.
     pub fn set_loading(&mut self, loading: bool) {
         self.loading = loading;
+        self.resending &= loading;
     }
 
     /// Returns `true` if currently loading.
@@ -141,6 +193,18 @@ impl AuthModel {
             return None;
         }
 
+        if let Some(picker) = &mut self.country_picker {
+            match picker.handle_input(key) {
+                CountryPickerAction::None => {},
+                CountryPickerAction::Cancel => self.country_picker = None,
+                CountryPickerAction::Choose(country) => {
+                    self.country_picker = None;
+                    self.use_country(country);
+                },
+            }
+            return None;
+        }
+
         match key.code {
             KeyCode::Enter => {
                 return self.handle_submit();
@@ -148,6 +212,13 @@ impl AuthModel {
             KeyCode::Esc => {
                 return Some(AuthAction::Quit);
             },
+            KeyCode::Char('r') if key.modifiers.contains(KeyModifiers::CONTROL) => {
+                return self.handle_resend(Instant::now());
+            },
+            KeyCode::Tab if self.auth_state == AuthState::WaitPhoneNumber => {
+                self.clear_error();
+                self.country_picker = Some(CountryPicker::new());
+            },
             _ => {
                 // Clear error on any input
                 if self.error_message.is_some() {
@@ -170,11 +241,26 @@ impl AuthModel {
             return None;
         }
 
+        let value = if self.auth_state == AuthState::WaitPhoneNumber {
+            match normalize_phone(&value) {
+                Ok(phone) => phone,
+                Err(e) => {
+                    self.set_error(e);
+                    return None;
+                },
+            }
+        } else {
+            value
+        };
+
         self.loading = true;
         self.clear_error();
 
         match self.auth_state {
-            AuthState::WaitPhoneNumber => Some(AuthAction::SubmitPhoneNumber(value)),
+            AuthState::WaitPhoneNumber => {
+                self.phone.clone_from(&value);
+                Some(AuthAction::SubmitPhoneNumber(value))
+            },
             AuthState::WaitCode => Some(AuthAction::SubmitCode(value)),
             AuthState::WaitPassword => Some(AuthAction::SubmitPassword(value)),
             AuthState::WaitRegistration => Some(AuthAction::SubmitRegistration(value)),
@@ -182,6 +268,37 @@ impl AuthModel {
         }
     }
 
+    /// Puts `country`'s calling code in front of the number typed so far,
+    /// in place of the code it had. A national number's leading `0` is
+    /// dropped, as it is when dialing from abroad.
+    fn use_country(&mut self, country: &Country) {
+        let value = self.input.value();
+        let digits: String = value.chars().filter(char::is_ascii_digit).collect();
+        let national = match countries_for(value).first() {
+            Some(current) => digits[current.code.len()..].to_string(),
+            None => digits.trim_start_matches('0').to_string(),
+        };
+        self.input
+            .set_value(format!("+{} {national}", country.code));
+    }
+
+    /// Asks for a new code, if one is being waited for and the countdown
+    /// has run out by `now`.
+    fn handle_resend(&mut self, now: Instant) -> Option<AuthAction> {
+        let wait = self.resend_wait(now)?;
+        if !wait.is_zero() {
+            self.set_error(format!(
+                "You can ask for a new code in {}s",
+                wait.as_secs() + 1
+            ));
+            return None;
+        }
+        self.loading = true;
+        self.resending = true;
+        self.clear_error();
+        Some(AuthAction::ResendCode(self.phone.clone()))
+    }
+
     /// Updates the input field configuration for the current state.
     fn update_input_for_state(&mut self) {
         self.input.clear();
@@ -229,6 +346,9 @@ impl AuthModel {
 
     /// Returns the loading message for the current state.
     const fn get_loading_message(&self) -> &'static str {
+        if self.resending {
+            return "Sending a new code...";
+        }
         match self.auth_state {
             AuthState::WaitPhoneNumber => "Sending verification code...",
             AuthState::WaitCode => "Verifying code...",
@@ -307,6 +427,20 @@ impl AuthModel {
             }
         }
 
+        // Name the country the number belongs to
+        if self.auth_state == AuthState::WaitPhoneNumber && !self.loading {
+            let countries = countries_for(self.input.value());
+            if !countries.is_empty() {
+                let names: Vec<&str> = countries.iter().map(|c| c.name).collect();
+                let hint = Paragraph::new(Line::from(Span::styled(
+                    names.join(" / "),
+                    Styles::text_muted(),
+                )))
+                .alignment(Alignment::Center);
+                frame.render_widget(hint, chunks[4]);
+            }
+        }
+
         // Show error message if present
         if let Some(ref error) = self.error_message {
             let error_para = Paragraph::new(Line::from(Span::styled(error, Styles::error())))
@@ -316,13 +450,29 @@ impl AuthModel {
 
         // Show help text
         let help_text = if self.loading {
-            "Please wait..."
+            "Please wait...".to_string()
         } else {
-            "Enter: Submit • Esc: Quit"
+            match self.resend_wait(Instant::now()) {
+                Some(wait) if wait.is_zero() => {
+                    "Enter: Submit • Ctrl+R: New code • Esc: Quit".to_string()
+                },
+                Some(wait) => format!(
+                    "Enter: Submit • New code in {}s • Esc: Quit",
+                    wait.as_secs() + 1
+                ),
+                None if self.auth_state == AuthState::WaitPhoneNumber => {
+                    "Enter: Submit • Tab: Country code • Esc: Quit".to_string()
+                },
+                None => "Enter: Submit • Esc: Quit".to_string(),
+            }
         };
         let help = Paragraph::new(Line::from(Span::styled(help_text, Styles::text_muted())))
             .alignment(Alignment::Center);
         frame.render_widget(help, chunks[6]);
+
+        if let Some(picker) = &self.country_picker {
+            picker.render(frame);
+        }
     }
 
     /// Renders the loading indicator.
@@ -430,6 +580,41 @@ mod tests {
         assert!(model.is_loading());
     }
 
+    #[test]
+    fn test_phone_number_is_checked_before_it_is_sent() {
+        let mut model = AuthModel::new();
+        model.input.set_value("555 0100");
+        assert!(model.handle_submit().is_none());
+        assert!(model.error_message.is_some());
+        assert!(!model.is_loading());
+
+        model.input.set_value("0044 (20) 7946-0958");
+        assert_eq!(
+            model.handle_submit(),
+            Some(AuthAction::SubmitPhoneNumber("+442079460958".to_string()))
+        );
+    }
+
+    #[test]
+    fn test_country_code_from_the_picker_replaces_the_typed_one() {
+        let mut model = AuthModel::new();
+        model.input.set_value("020 7946 0958");
+        model.handle_input(KeyEvent::from(KeyCode::Tab));
+        for c in "+44".chars() {
+            model.handle_input(KeyEvent::from(KeyCode::Char(c)));
+        }
+        model.handle_input(KeyEvent::from(KeyCode::Enter));
+        assert!(model.country_picker.is_none());
+        assert_eq!(model.input.value(), "+44 2079460958");
+
+        model.handle_input(KeyEvent::from(KeyCode::Tab));
+        for c in "germany".chars() {
+            model.handle_input(KeyEvent::from(KeyCode::Char(c)));
+        }
+        model.handle_input(KeyEvent::from(KeyCode::Enter));
+        assert_eq!(model.input.value(), "+49 2079460958");
+    }
+
     #[test]
     fn test_submit_code() {
         let mut model = AuthModel::new();
@@ -453,6 +638,34 @@ mod tests {
         );
     }
 
+    #[test]
+    fn test_new_code_once_the_countdown_runs_out() {
+        let mut model = AuthModel::new();
+        model.input.set_value("+1234567890");
+        model.handle_submit();
+        model.set_auth_state(AuthState::WaitCode);
+
+        let now = Instant::now();
+        assert!(model.handle_resend(now).is_none());
+        assert!(model.error_message.is_some());
+
+        let later = now + RESEND_WAIT + Duration::from_secs(1);
+        assert_eq!(
+            model.handle_resend(later),
+            Some(AuthAction::ResendCode("+1234567890".to_string()))
+        );
+        assert_eq!(model.get_loading_message(), "Sending a new code...");
+
+        // Telegram asking for patience pushes the countdown back
+        model.set_loading(false);
+        model.delay_resend(RESEND_WAIT * 5);
+        assert!(model.handle_resend(later).is_none());
+
+        // No countdown outside the code step
+        model.set_auth_state(AuthState::WaitPassword);
+        assert!(model.resend_wait(later).is_none());
+    }
+
     #[test]
     fn test_esc_quits() {
         let mut model = AuthModel::new();
@@ -0,0 +1,190 @@
+//! Messages bookmarked with `b`, to jump back to (`Alt+B`).
+//!
+//! Bookmarks are kept on this device, apart from Telegram's Saved Messages,
+//! newest first. `Enter` opens the chat at the message and `d` removes the
+//! bookmark.
+
+use chrono::Local;
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, List, ListItem, ListState, Paragraph},
+    Frame,
+};
+
+use crate::app::state::Bookmark;
+use crate::ui::styles::Styles;
+use crate::utils::truncate_string;
+
+/// Result of a key press in the bookmark list.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum BookmarkListAction {
+    /// Key was handled; keep the list open
+    None,
+    /// Close the list
+    Close,
+    /// Open the chat and select the message (chat ID, message ID)
+    Jump(i64, i64),
+    /// Remove the bookmark (chat ID, message ID)
+    Remove(i64, i64),
+}
+
+/// The user's bookmarks.
+#[derive(Debug, Clone)]
+pub struct BookmarkList {
+    bookmarks: Vec<Bookmark>,
+    selected: usize,
+}
+
+impl BookmarkList {
+    /// Creates the list from the bookmarks, newest first.
+    #[must_use]
+    pub const fn new(bookmarks: Vec<Bookmark>) -> Self {
+        Self {
+            bookmarks,
+            selected: 0,
+        }
+    }
+
+    /// Replaces the bookmarks, as after one was removed, keeping the
+    /// highlight in place where possible.
+    pub fn set_bookmarks(&mut self, bookmarks: Vec<Bookmark>) {
+        self.bookmarks = bookmarks;
+        self.selected = self.selected.min(self.bookmarks.len().saturating_sub(1));
+    }
+
+    /// Handles a key press.
+    pub fn handle_input(&mut self, key: KeyEvent) -> BookmarkListAction {
+        let selected = self.bookmarks.get(self.selected);
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') => BookmarkListAction::Close,
+            KeyCode::Enter => selected.map_or(BookmarkListAction::None, |b| {
+                BookmarkListAction::Jump(b.chat_id, b.message_id)
+            }),
+            KeyCode::Char('d') | KeyCode::Delete => selected
+                .map_or(BookmarkListAction::None, |b| {
+                    BookmarkListAction::Remove(b.chat_id, b.message_id)
+                }),
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                BookmarkListAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                if self.selected + 1 < self.bookmarks.len() {
+                    self.selected += 1;
+                }
+                BookmarkListAction::None
+            },
+            _ => BookmarkListAction::None,
+        }
+    }
+
+    /// Renders the list as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 76.min(area.width.saturating_sub(4));
+        let h = 20.min(area.height.saturating_sub(2));
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" Bookmarks ({}) ", self.bookmarks.len()),
+                Styles::text_bright(),
+            ))
+            .title_bottom(Span::styled(
+                " Enter go to message \u{2022} d remove \u{2022} Esc close ",
+                Styles::text_muted(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        if self.bookmarks.is_empty() {
+            let empty = Paragraph::new(Span::styled(
+                "No bookmarks yet; press b on a message to add one",
+                Styles::text_muted(),
+            ))
+            .block(block);
+            frame.render_widget(empty, modal);
+            return;
+        }
+
+        let excerpt_width = usize::from(w.saturating_sub(4));
+        let items: Vec<ListItem> = self
+            .bookmarks
+            .iter()
+            .map(|b| {
+                let date = b.date.with_timezone(&Local).format("%Y-%m-%d %H:%M");
+                ListItem::new(vec![
+                    Line::from(vec![
+                        Span::styled(b.chat.clone(), Styles::text_accent()),
+                        Span::styled(
+                            format!(" \u{2022} {} \u{2022} {date}", b.sender),
+                            Styles::text_muted(),
+                        ),
+                    ]),
+                    Line::from(Span::styled(
+                        format!("  {}", truncate_string(&b.excerpt, excerpt_width)),
+                        Styles::text(),
+                    )),
+                ])
+            })
+            .collect();
+
+        let list = List::new(items)
+            .block(block)
+            .highlight_style(Styles::highlight());
+        let mut state = ListState::default();
+        state.select(Some(self.selected));
+        frame.render_stateful_widget(list, modal, &mut state);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use chrono::Utc;
+
+    fn bookmark(chat_id: i64, message_id: i64) -> Bookmark {
+        Bookmark {
+            chat_id,
+            message_id,
+            chat: "Chat".to_string(),
+            sender: "Alice".to_string(),
+            excerpt: "Remember this".to_string(),
+            date: Utc::now(),
+            sealed: String::new(),
+        }
+    }
+
+    #[test]
+    fn enter_jumps_and_d_removes_the_highlighted_bookmark() {
+        let mut list = BookmarkList::new(vec![bookmark(1, 10), bookmark(2, 20)]);
+        assert_eq!(
+            list.handle_input(KeyEvent::from(KeyCode::Enter)),
+            BookmarkListAction::Jump(1, 10)
+        );
+        list.handle_input(KeyEvent::from(KeyCode::Down));
+        assert_eq!(
+            list.handle_input(KeyEvent::from(KeyCode::Char('d'))),
+            BookmarkListAction::Remove(2, 20)
+        );
+
+        // The highlight stays on the list once the last one is gone
+        list.set_bookmarks(vec![bookmark(1, 10)]);
+        assert_eq!(
+            list.handle_input(KeyEvent::from(KeyCode::Enter)),
+            BookmarkListAction::Jump(1, 10)
+        );
+        list.set_bookmarks(Vec::new());
+        assert_eq!(
+            list.handle_input(KeyEvent::from(KeyCode::Char('d'))),
+            BookmarkListAction::None
+        );
+    }
+}
@@ -0,0 +1,364 @@
+//! Subscribed channels with their subscriber counts and mute state, for
+//! pruning them in bulk (`/channels`).
+//!
+//! Channels are marked with Space; `m` mutes them (or unmutes them if all
+//! are muted already), `a` moves them to the Archive folder and `L` leaves
+//! them, after the app asks for confirmation. With nothing marked, these
+//! act on the highlighted channel. `s` sorts by name, by subscribers, or
+//! by how long since the last post.
+
+use chrono::Local;
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, List, ListItem, ListState, Paragraph},
+    Frame,
+};
+
+use crate::types::{Chat, ChatType};
+use crate::ui::styles::Styles;
+use crate::utils::{format_compact_time, truncate_string};
+
+/// Result of a key press in the channel view.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum ChannelManagerAction {
+    /// Key was handled; keep the view open
+    None,
+    /// Close the view
+    Close,
+    /// Mute (`true`) or unmute these channels
+    Mute(Vec<i64>, bool),
+    /// Move these channels to the Archive folder
+    Archive(Vec<i64>),
+    /// Leave these channels
+    Leave(Vec<i64>),
+}
+
+/// How the channels are ordered.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum ChannelSort {
+    /// Alphabetically by title
+    Name,
+    /// Most subscribers first
+    Subscribers,
+    /// Longest silent first
+    LastPost,
+}
+
+impl ChannelSort {
+    const fn next(self) -> Self {
+        match self {
+            Self::Name => Self::Subscribers,
+            Self::Subscribers => Self::LastPost,
+            Self::LastPost => Self::Name,
+        }
+    }
+
+    const fn label(self) -> &'static str {
+        match self {
+            Self::Name => "name",
+            Self::Subscribers => "subscribers",
+            Self::LastPost => "last post",
+        }
+    }
+}
+
+/// The channels the user is subscribed to.
+#[derive(Debug, Clone)]
+pub struct ChannelManager {
+    channels: Vec<Chat>,
+    /// IDs of the channels marked for a bulk action
+    marked: Vec<i64>,
+    selected: usize,
+    sort: ChannelSort,
+}
+
+impl ChannelManager {
+    /// Creates the view from the user's chats, keeping only channels.
+    #[must_use]
+    pub fn new(chats: Vec<Chat>) -> Self {
+        let mut view = Self {
+            channels: Vec::new(),
+            marked: Vec::new(),
+            selected: 0,
+            sort: ChannelSort::Name,
+        };
+        view.set_chats(chats);
+        view
+    }
+
+    /// Replaces the channels, as after some were left or archived. Marks
+    /// are cleared and the highlight stays in place where possible.
+    pub fn set_chats(&mut self, chats: Vec<Chat>) {
+        self.channels = chats
+            .into_iter()
+            .filter(|c| c.chat_type == ChatType::Channel)
+            .collect();
+        self.marked.clear();
+        self.sort_channels();
+        self.selected = self.selected.min(self.channels.len().saturating_sub(1));
+    }
+
+    /// Returns how many channels are listed.
+    #[must_use]
+    pub fn len(&self) -> usize {
+        self.channels.len()
+    }
+
+    /// Returns `true` if the user has no channels.
+    #[must_use]
+    pub fn is_empty(&self) -> bool {
+        self.channels.is_empty()
+    }
+
+    fn sort_channels(&mut self) {
+        match self.sort {
+            ChannelSort::Name => self.channels.sort_by_key(|c| c.title.to_lowercase()),
+            ChannelSort::Subscribers => self
+                .channels
+                .sort_by(|a, b| b.member_count.cmp(&a.member_count)),
+            ChannelSort::LastPost => self
+                .channels
+                .sort_by_key(|c| c.last_message.as_ref().map(|m| m.date)),
+        }
+    }
+
+    /// Returns the marked channels, or else the highlighted one.
+    fn chosen(&self) -> Vec<&Chat> {
+        let marked: Vec<&Chat> = self
+            .channels
+            .iter()
+            .filter(|c| self.marked.contains(&c.id))
+            .collect();
+        if marked.is_empty() {
+            self.channels.get(self.selected).into_iter().collect()
+        } else {
+            marked
+        }
+    }
+
+    /// Handles a key press.
+    pub fn handle_input(&mut self, key: KeyEvent) -> ChannelManagerAction {
+        let ids = |chosen: &[&Chat]| chosen.iter().map(|c| c.id).collect::<Vec<_>>();
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') => ChannelManagerAction::Close,
+            KeyCode::Char(' ') => {
+                if let Some(id) = self.channels.get(self.selected).map(|c| c.id) {
+                    if let Some(pos) = self.marked.iter().position(|&m| m == id) {
+                        self.marked.remove(pos);
+                    } else {
+                        self.marked.push(id);
+                    }
+                }
+                if self.selected + 1 < self.channels.len() {
+                    self.selected += 1;
+                }
+                ChannelManagerAction::None
+            },
+            KeyCode::Char('m') => {
+                let chosen = self.chosen();
+                if chosen.is_empty() {
+                    return ChannelManagerAction::None;
+                }
+                let mute = !chosen.iter().all(|c| c.is_muted);
+                ChannelManagerAction::Mute(ids(&chosen), mute)
+            },
+            KeyCode::Char('a') => {
+                let chosen = self.chosen();
+                if chosen.is_empty() {
+                    return ChannelManagerAction::None;
+                }
+                ChannelManagerAction::Archive(ids(&chosen))
+            },
+            KeyCode::Char('L') => {
+                let chosen = self.chosen();
+                if chosen.is_empty() {
+                    return ChannelManagerAction::None;
+                }
+                ChannelManagerAction::Leave(ids(&chosen))
+            },
+            KeyCode::Char('s') => {
+                self.sort = self.sort.next();
+                self.sort_channels();
+                self.selected = 0;
+                ChannelManagerAction::None
+            },
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                ChannelManagerAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                if self.selected + 1 < self.channels.len() {
+                    self.selected += 1;
+                }
+                ChannelManagerAction::None
+            },
+            _ => ChannelManagerAction::None,
+        }
+    }
+
+    /// Renders the view as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 76.min(area.width.saturating_sub(4));
+        let h = 24.min(area.height.saturating_sub(2));
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let title = if self.marked.is_empty() {
+            format!(
+                " Channels ({}, by {}) ",
+                self.channels.len(),
+                self.sort.label()
+            )
+        } else {
+            format!(
+                " Channels ({}, {} marked) ",
+                self.channels.len(),
+                self.marked.len()
+            )
+        };
+        let block = Block::default()
+            .title(Span::styled(title, Styles::text_bright()))
+            .title_bottom(Span::styled(
+                " Space mark \u{2022} m mute \u{2022} a archive \u{2022} L leave \u{2022} s sort \u{2022} Esc close ",
+                Styles::text_muted(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        if self.channels.is_empty() {
+            let empty = Paragraph::new(Span::styled(
+                "You aren't subscribed to any channels",
+                Styles::text_muted(),
+            ))
+            .block(block);
+            frame.render_widget(empty, modal);
+            return;
+        }
+
+        let now = Local::now();
+        let title_width = usize::from(w.saturating_sub(36));
+        let items: Vec<ListItem> = self
+            .channels
+            .iter()
+            .map(|chat| {
+                let check = if self.marked.contains(&chat.id) {
+                    "[x]"
+                } else {
+                    "[ ]"
+                };
+                let last_post = chat
+                    .last_message
+                    .as_ref()
+                    .map_or_else(String::new, |m| format_compact_time(m.date, now));
+                ListItem::new(Line::from(vec![
+                    Span::styled(
+                        format!(
+                            "{check} {:<title_width$}",
+                            truncate_string(&chat.title, title_width)
+                        ),
+                        Styles::text(),
+                    ),
+                    Span::styled(
+                        format!("{:>7} subs", subscribers(chat.member_count)),
+                        Styles::text_muted(),
+                    ),
+                    Span::styled(
+                        if chat.is_muted { "  muted" } else { "       " },
+                        Styles::text_muted(),
+                    ),
+                    Span::styled(format!("  {last_post:>9}"), Styles::text_muted()),
+                ]))
+            })
+            .collect();
+
+        let list = List::new(items)
+            .block(block)
+            .highlight_style(Styles::highlight());
+        let mut state = ListState::default();
+        state.select(Some(self.selected));
+        frame.render_stateful_widget(list, modal, &mut state);
+    }
+}
+
+/// Formats a subscriber count compactly, e.g. `12.3K` (`?` if unknown).
+fn subscribers(count: i32) -> String {
+    match count {
+        i32::MIN..=0 => "?".to_string(),
+        1..=9_999 => count.to_string(),
+        10_000..=999_999 => format!("{:.1}K", f64::from(count) / 1_000.0),
+        _ => format!("{:.1}M", f64::from(count) / 1_000_000.0),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn channel(id: i64, title: &str, members: i32, muted: bool) -> Chat {
+        Chat {
+            id,
+            chat_type: ChatType::Channel,
+            title: title.to_string(),
+            member_count: members,
+            is_muted: muted,
+            ..Chat::default()
+        }
+    }
+
+    #[test]
+    fn acts_on_the_marked_channels_or_else_the_highlighted_one() {
+        let chats = vec![
+            channel(1, "News", 12_000, true),
+            Chat {
+                id: 2,
+                chat_type: ChatType::Private,
+                title: "Ann".to_string(),
+                ..Chat::default()
+            },
+            channel(3, "Art", 800, false),
+            channel(4, "Memes", 2_000_000, true),
+        ];
+        let mut view = ChannelManager::new(chats);
+        assert_eq!(view.len(), 3);
+
+        // Sorted by name: Art, Memes, News
+        assert_eq!(
+            view.handle_input(KeyEvent::from(KeyCode::Char('a'))),
+            ChannelManagerAction::Archive(vec![3])
+        );
+
+        view.handle_input(KeyEvent::from(KeyCode::Down));
+        view.handle_input(KeyEvent::from(KeyCode::Char(' ')));
+        view.handle_input(KeyEvent::from(KeyCode::Char(' ')));
+        assert_eq!(
+            view.handle_input(KeyEvent::from(KeyCode::Char('m'))),
+            ChannelManagerAction::Mute(vec![4, 1], false)
+        );
+        assert_eq!(
+            view.handle_input(KeyEvent::from(KeyCode::Char('L'))),
+            ChannelManagerAction::Leave(vec![4, 1])
+        );
+
+        // Once the others are gone the marks go too
+        view.set_chats(vec![channel(3, "Art", 800, false)]);
+        assert_eq!(
+            view.handle_input(KeyEvent::from(KeyCode::Char('m'))),
+            ChannelManagerAction::Mute(vec![3], true)
+        );
+    }
+
+    #[test]
+    fn subscriber_counts_are_compact() {
+        assert_eq!(subscribers(0), "?");
+        assert_eq!(subscribers(950), "950");
+        assert_eq!(subscribers(12_345), "12.3K");
+        assert_eq!(subscribers(2_000_000), "2.0M");
+    }
+}
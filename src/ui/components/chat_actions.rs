@@ -0,0 +1,221 @@
+//! Menu of things to do with the open chat (`Alt+A`).
+//!
+//! Gathers the chat-scoped actions that otherwise each need their own key or
+//! command, so they can be found without memorizing them. Each entry has a
+//! letter that runs it directly.
+
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use crate::ui::styles::Styles;
+
+/// An entry in the chat actions menu.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ChatMenuItem {
+    /// Search this chat's messages
+    Search,
+    /// Photos and videos sent in the chat
+    SharedMedia,
+    /// Pinned messages
+    Pinned,
+    /// The group's or channel's members
+    Members,
+    /// Chat details in the sidebar
+    Info,
+    /// The chat in the official Telegram app
+    OpenInTelegram,
+}
+
+impl ChatMenuItem {
+    /// Letter that runs the entry.
+    #[must_use]
+    pub const fn key(self) -> char {
+        match self {
+            Self::Search => 's',
+            Self::SharedMedia => 'm',
+            Self::Pinned => 'p',
+            Self::Members => 'u',
+            Self::Info => 'i',
+            Self::OpenInTelegram => 'o',
+        }
+    }
+
+    /// Text shown for the entry.
+    #[must_use]
+    pub const fn label(self) -> &'static str {
+        match self {
+            Self::Search => "Search messages",
+            Self::SharedMedia => "Shared media",
+            Self::Pinned => "Pinned messages",
+            Self::Members => "Members",
+            Self::Info => "Chat info",
+            Self::OpenInTelegram => "Open in Telegram",
+        }
+    }
+}
+
+/// Result of a key press in the chat actions menu.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ChatActionsAction {
+    /// Key was handled; keep the menu open
+    None,
+    /// Close without doing anything
+    Cancel,
+    /// Run this entry for the chat
+    Run(i64, ChatMenuItem),
+}
+
+/// The actions available for one chat.
+#[derive(Debug, Clone)]
+pub struct ChatActions {
+    chat_id: i64,
+    /// Chat name, for the title
+    title: String,
+    items: Vec<ChatMenuItem>,
+    selected: usize,
+}
+
+impl ChatActions {
+    /// Creates the menu; `has_members` offers the member list, which private
+    /// chats don't have.
+    #[must_use]
+    pub fn new(chat_id: i64, title: impl Into<String>, has_members: bool) -> Self {
+        let items = [
+            ChatMenuItem::Search,
+            ChatMenuItem::SharedMedia,
+            ChatMenuItem::Pinned,
+            ChatMenuItem::Members,
+            ChatMenuItem::Info,
+            ChatMenuItem::OpenInTelegram,
+        ]
+        .into_iter()
+        .filter(|item| has_members || *item != ChatMenuItem::Members)
+        .collect();
+        Self {
+            chat_id,
+            title: title.into(),
+            items,
+            selected: 0,
+        }
+    }
+
+    /// Returns the entries on offer.
+    #[must_use]
+    pub fn items(&self) -> &[ChatMenuItem] {
+        &self.items
+    }
+
+    /// Handles a key press.
+    pub fn handle_input(&mut self, key: KeyEvent) -> ChatActionsAction {
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') => ChatActionsAction::Cancel,
+            KeyCode::Enter => self
+                .items
+                .get(self.selected)
+                .map_or(ChatActionsAction::Cancel, |item| {
+                    ChatActionsAction::Run(self.chat_id, *item)
+                }),
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                ChatActionsAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                if self.selected + 1 < self.items.len() {
+                    self.selected += 1;
+                }
+                ChatActionsAction::None
+            },
+            KeyCode::Char(c) => self
+                .items
+                .iter()
+                .find(|item| item.key() == c)
+                .map_or(ChatActionsAction::None, |item| {
+                    ChatActionsAction::Run(self.chat_id, *item)
+                }),
+            _ => ChatActionsAction::None,
+        }
+    }
+
+    /// Renders the menu as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 40.min(area.width.saturating_sub(4));
+        #[allow(clippy::cast_possible_truncation)]
+        let rows = self.items.len().min(usize::from(u16::MAX)) as u16;
+        let h = (rows + 4).min(area.height);
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" {} ", self.title),
+                Styles::text_bright(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let mut lines: Vec<Line> = self
+            .items
+            .iter()
+            .enumerate()
+            .map(|(i, item)| {
+                let (marker, style) = if i == self.selected {
+                    ("> ", Styles::selected())
+                } else {
+                    ("  ", Styles::text())
+                };
+                Line::from(vec![
+                    Span::styled(marker, style),
+                    Span::styled(format!("{} ", item.key()), Styles::text_accent()),
+                    Span::styled(item.label(), style),
+                ])
+            })
+            .collect();
+        lines.push(Line::from(""));
+        lines.push(Line::from(Span::styled(
+            "letter or Enter run \u{2022} Esc cancel",
+            Styles::text_muted(),
+        )));
+
+        frame.render_widget(Paragraph::new(lines).block(block), modal);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn letters_run_entries_and_private_chats_have_no_members() {
+        let menu = ChatActions::new(7, "Alice", false);
+        assert!(!menu.items().contains(&ChatMenuItem::Members));
+
+        let mut menu = ChatActions::new(-100, "Work", true);
+        assert_eq!(
+            menu.handle_input(KeyEvent::from(KeyCode::Char('u'))),
+            ChatActionsAction::Run(-100, ChatMenuItem::Members)
+        );
+        menu.handle_input(KeyEvent::from(KeyCode::Down));
+        assert_eq!(
+            menu.handle_input(KeyEvent::from(KeyCode::Enter)),
+            ChatActionsAction::Run(-100, ChatMenuItem::SharedMedia)
+        );
+        assert_eq!(
+            menu.handle_input(KeyEvent::from(KeyCode::Char('x'))),
+            ChatActionsAction::None
+        );
+        assert_eq!(
+            menu.handle_input(KeyEvent::from(KeyCode::Esc)),
+            ChatActionsAction::Cancel
+        );
+    }
+}
@@ -29,6 +29,7 @@
 //!     .highlight_style(ratatui::style::Style::default());
 //! ```
 
+use chrono::{DateTime, Local};
 use ratatui::{
     style::{Modifier, Style},
     text::{Line, Span, Text},
@@ -36,9 +37,24 @@ use ratatui::{
 };
 use unicode_width::UnicodeWidthStr;
 
-use crate::types::{Chat, ChatType, UserStatus};
+use crate::types::{Chat, ChatType, User, UserStatus};
 use crate::ui::styles::{colors, Styles};
-use crate::utils::{format_timestamp, truncate_string};
+use crate::utils::{format_compact_time, initials, truncate_string, word_wrap};
+
+/// Most lines a message preview may take.
+pub const MAX_PREVIEW_LINES: usize = 3;
+
+/// Marks a preview showing the chat's unsent draft.
+const DRAFT_PREFIX: &str = "Draft: ";
+
+/// Narrowest the title is squeezed to before badges are dropped.
+const MIN_TITLE_WIDTH: usize = 8;
+
+/// Columns an initials avatar takes.
+pub const AVATAR_WIDTH: usize = 4;
+
+/// Shown in place of previews hidden by stealth mode.
+const HIDDEN_PREVIEW: &str = "\u{2022}\u{2022}\u{2022} hidden (v to reveal)";
 
 /// Builder for creating styled [`ListItem`] entries from chat data.
 ///
@@ -49,25 +65,53 @@ use crate::utils::{format_timestamp, truncate_string};
 ///
 /// # Visual Layout
 ///
-/// Each chat item displays two lines:
+/// Each chat item displays a title line and up to [`MAX_PREVIEW_LINES`]
+/// preview lines:
 ///
 /// ```text
 /// ┌────────────────────────────────────────┐
-/// │ Chat Title  📌 ●              [3] 12:30 │
-/// │   Last message preview...              │
+/// │ Chat Title ✓ 📌 ●          @ [3] 12:30 │
+/// │   Alice: Last message preview...       │
 /// └────────────────────────────────────────┘
 /// ```
 ///
 /// Where:
+/// - `✓` marks verified users and `🤖` bots (private chats)
 /// - `📌` appears for pinned chats
+/// - `🔇` marks muted chats, whose title and unread count are dimmed
 /// - `●` appears for online users (private chats)
+/// - `📵` marks calls missed since the chat was read, with a count if more
+///   than one
+/// - `@` shows there are unread messages mentioning the user
 /// - `[3]` is the unread count badge
-/// - `12:30` is the timestamp
+/// - `12:30` is when the last message arrived, compacted by age ("now",
+///   "5m", "12:30", "Tue", "3/2"); it is dropped when the row is too narrow
+/// - `Alice: ` names the sender in groups (`You: ` for own messages), or
+///   reads `Draft: ` when the chat has an unsent draft
+/// - while someone is typing, the preview reads `typing…` (`Alice is
+///   typing…` in groups) instead
+///
+/// With avatars on, the title line starts with the chat's initials on its
+/// Telegram peer color, and the preview lines up under the title.
+///
+/// In narrow panes the timestamp goes first, then the title badges; the
+/// unread count and mention marker always stay.
 #[derive(Debug, Clone)]
 pub struct ChatItemBuilder<'a> {
     chat: &'a Chat,
     width: u16,
     show_preview: bool,
+    hide_preview_text: bool,
+    alias: Option<&'a str>,
+    sender_name: Option<&'a str>,
+    typing: Option<&'a str>,
+    user: Option<&'a User>,
+    preview_lines: usize,
+    preview_length: usize,
+    now: Option<DateTime<Local>>,
+    highlighted: bool,
+    tag: Option<&'static str>,
+    show_avatar: bool,
 }
 
 impl<'a> ChatItemBuilder<'a> {
@@ -83,6 +127,17 @@ impl<'a> ChatItemBuilder<'a> {
             chat,
             width,
             show_preview: true,
+            hide_preview_text: false,
+            alias: None,
+            sender_name: None,
+            typing: None,
+            user: None,
+            preview_lines: 1,
+            preview_length: 0,
+            now: None,
+            highlighted: false,
+            tag: None,
+            show_avatar: false,
         }
     }
 
@@ -93,6 +148,92 @@ impl<'a> ChatItemBuilder<'a> {
         self
     }
 
+    /// Masks the preview text (stealth mode) while keeping the line.
+    #[must_use]
+    pub const fn hide_preview_text(mut self, hide: bool) -> Self {
+        self.hide_preview_text = hide;
+        self
+    }
+
+    /// Sets a local alias to display in place of the chat title.
+    ///
+    /// The real title is still shown as a muted secondary label.
+    #[must_use]
+    pub const fn alias(mut self, alias: Option<&'a str>) -> Self {
+        self.alias = alias;
+        self
+    }
+
+    /// Sets the name of the last message's sender, shown before the
+    /// preview in group chats.
+    #[must_use]
+    pub const fn sender_name(mut self, name: Option<&'a str>) -> Self {
+        self.sender_name = name;
+        self
+    }
+
+    /// Sets the name of someone typing in the chat, which replaces the
+    /// preview until cleared.
+    #[must_use]
+    pub const fn typing(mut self, name: Option<&'a str>) -> Self {
+        self.typing = name;
+        self
+    }
+
+    /// Sets whether an unread message matches one of the highlight words,
+    /// which adds a `!` marker.
+    #[must_use]
+    pub const fn highlighted(mut self, highlighted: bool) -> Self {
+        self.highlighted = highlighted;
+        self
+    }
+
+    /// Sets a tag such as `NEW`, for a chat the user was just added to,
+    /// shown until the chat is opened.
+    #[must_use]
+    pub const fn tag(mut self, tag: Option<&'static str>) -> Self {
+        self.tag = tag;
+        self
+    }
+
+    /// Sets whether the title starts with an initials avatar.
+    #[must_use]
+    pub const fn avatar(mut self, show: bool) -> Self {
+        self.show_avatar = show;
+        self
+    }
+
+    /// Sets the other user of a private chat, whose flags add verified
+    /// and bot badges.
+    #[must_use]
+    pub const fn user(mut self, user: Option<&'a User>) -> Self {
+        self.user = user;
+        self
+    }
+
+    /// Sets how many lines the preview may wrap onto (clamped to
+    /// 1..=[`MAX_PREVIEW_LINES`]).
+    #[must_use]
+    pub fn preview_lines(mut self, lines: usize) -> Self {
+        self.preview_lines = lines.clamp(1, MAX_PREVIEW_LINES);
+        self
+    }
+
+    /// Caps the preview at `length` columns before wrapping (0 for no cap).
+    #[must_use]
+    pub const fn preview_length(mut self, length: usize) -> Self {
+        self.preview_length = length;
+        self
+    }
+
+    /// Sets the time the last-message timestamp is relative to (defaults to
+    /// the current time).
+    #[must_use]
+    pub const fn now(mut self, now: DateTime<Local>) -> Self {
+        self.now = Some(now);
+        self
+    }
+
     /// Builds the [`ListItem`] for this chat.
     ///
     /// The returned item is fully owned (`'static` lifetime) and can be used
@@ -104,11 +245,9 @@ impl<'a> ChatItemBuilder<'a> {
         // Title line
         lines.push(self.build_title_line());
 
-        // Preview line (if enabled and message exists)
+        // Preview lines (if enabled and there is something to show)
         if self.show_preview {
-            if let Some(preview_line) = self.build_preview_line() {
-                lines.push(preview_line);
-            }
+            lines.extend(self.build_preview_lines());
         }
 
         // Add a blank line at the bottom for visual separation between items
@@ -119,13 +258,30 @@ impl<'a> ChatItemBuilder<'a> {
 
     /// Builds the title line with chat name, badges, and timestamp.
     fn build_title_line(&self) -> Line<'static> {
-        let mut spans: Vec<Span<'static>> = Vec::new();
-        let width = self.width as usize;
-
-        // Calculate available space for title
-        // Reserve space for: badges (~8), timestamp (~8), unread (~4), padding (~4)
-        let reserved_width = 24_usize;
-        let max_title_width = width.saturating_sub(reserved_width).max(8);
+        let width = (self.width as usize).saturating_sub(self.avatar_indent());
+
+        // The right side (mention marker + unread count) always stays; the
+        // badges go if they'd squeeze the title too far
+        let mut right_spans = self.build_right_content();
+        let mut right_width = spans_width(&right_spans);
+        let mut badges = self.badges();
+        if width.saturating_sub(right_width + spans_width(&badges)) < MIN_TITLE_WIDTH {
+            badges.clear();
+        }
+        let fixed_width = right_width + spans_width(&badges);
+
+        // Leave room for the timestamp too, unless that's what squeezes it
+        let timestamp = self.timestamp();
+        let timestamp_width = timestamp
+            .as_deref()
+            .map_or(0, |t| UnicodeWidthStr::width(t) + 1);
+        let title_room = width.saturating_sub(fixed_width);
+        let max_title_width = if title_room >= timestamp_width + MIN_TITLE_WIDTH {
+            title_room - timestamp_width
+        } else {
+            title_room
+        }
+        .max(1);
 
         // Chat title (owned string)
         let title = if self.chat.title.is_empty() {
@@ -133,31 +289,55 @@ impl<'a> ChatItemBuilder<'a> {
         } else {
             self.chat.title.clone()
         };
-        let truncated_title = truncate_string(&title, max_title_width);
 
-        // Title styling: bold, and highlighted if has new messages
+        // With an alias, the alias leads and the real title follows as a
+        // secondary label sharing the same width budget.
+        let (primary, secondary) = match self.alias {
+            Some(alias) => (alias.to_string(), Some(title)),
+            None => (title, None),
+        };
+        let truncated_title = truncate_string(&primary, max_title_width);
+
+        // Title styling: bold, highlighted if it has new messages, dimmed
+        // if muted
         let title_style = if self.chat.has_new_message {
             Style::default()
                 .fg(colors::fg_bright())
                 .add_modifier(Modifier::BOLD)
+        } else if self.chat.is_muted {
+            Style::default().fg(colors::fg_muted())
         } else {
             Style::default()
                 .fg(colors::fg_primary())
                 .add_modifier(Modifier::BOLD)
         };
+        let remaining =
+            max_title_width.saturating_sub(UnicodeWidthStr::width(truncated_title.as_str()));
+        let mut spans = Vec::new();
+        if self.show_avatar {
+            spans.push(avatar(self.chat.id, &self.chat.title));
+            spans.push(Span::raw(" "));
+        }
         spans.push(Span::styled(truncated_title, title_style));
 
-        // Add status badges inline
-        self.append_badges(&mut spans);
+        // Real name shown after the alias, only if there is room for it
+        if let Some(real) = secondary {
+            if remaining > 5 {
+                let label = truncate_string(&real, remaining - 3);
+                spans.push(Span::styled(format!(" ({label})"), Styles::text_muted()));
+            }
+        }
 
-        // Calculate current content width
-        let left_content: String = spans.iter().map(|s| s.content.as_ref()).collect();
-        let left_width = UnicodeWidthStr::width(left_content.as_str());
+        spans.extend(badges);
+        let left_width = spans_width(&spans).saturating_sub(self.avatar_indent());
 
-        // Build right side (unread badge + timestamp)
-        let right_spans = self.build_right_content();
-        let right_content: String = right_spans.iter().map(|s| s.content.as_ref()).collect();
-        let right_width = UnicodeWidthStr::width(right_content.as_str());
+        // The timestamp only goes in if it fits beside the title
+        if let Some(timestamp) = timestamp {
+            if left_width + right_width + timestamp_width <= width {
+                right_width += timestamp_width - 1;
+                right_spans.push(Span::styled(timestamp, Styles::text_muted()));
+            }
+        }
 
         // Calculate padding to right-align
         let padding = width.saturating_sub(left_width + right_width);
@@ -170,40 +350,109 @@ impl<'a> ChatItemBuilder<'a> {
         Line::from(spans)
     }
 
-    /// Appends status badge spans to the given vector.
-    fn append_badges(&self, spans: &mut Vec<Span<'static>>) {
+    /// Returns the columns the avatar and the space after it take.
+    const fn avatar_indent(&self) -> usize {
+        if self.show_avatar {
+            AVATAR_WIDTH + 1
+        } else {
+            0
+        }
+    }
+
+    /// Returns the status badges shown after the title.
+    fn badges(&self) -> Vec<Span<'static>> {
+        let mut spans = Vec::new();
+        let mut badge = |text: String, color| {
+            spans.push(Span::raw(" "));
+            spans.push(Span::styled(text, Style::default().fg(color)));
+        };
+
+        // Who the other side of a private chat is
+        if let Some(user) = self.user {
+            if user.is_verified {
+                badge("\u{2713}".to_string(), colors::accent_primary());
+            }
+            if user.is_bot {
+                badge("🤖".to_string(), colors::fg_muted());
+            }
+        }
+
         // Pinned indicator with icon
         if self.chat.is_pinned {
-            spans.push(Span::raw(" "));
-            spans.push(Span::styled(
-                "📌".to_string(),
-                Style::default().fg(colors::status_attention()),
-            ));
+            badge("📌".to_string(), colors::status_attention());
         }
 
         // Muted indicator
         if self.chat.is_muted {
-            spans.push(Span::raw(" "));
-            spans.push(Span::styled(
-                "🔇".to_string(),
-                Style::default().fg(colors::fg_muted()),
-            ));
+            badge("🔇".to_string(), colors::fg_muted());
         }
 
         // Online status indicator for private chats
         if self.chat.chat_type == ChatType::Private && self.chat.user_status == UserStatus::Online {
-            spans.push(Span::raw(" "));
-            spans.push(Span::styled(
-                "●".to_string(),
-                Style::default().fg(colors::status_success()),
-            ));
+            badge("●".to_string(), colors::status_success());
+        }
+
+        // Missed calls indicator
+        if self.chat.missed_calls > 0 {
+            let text = if self.chat.missed_calls > 1 {
+                format!("📵{}", self.chat.missed_calls)
+            } else {
+                "📵".to_string()
+            };
+            badge(text, colors::status_error());
         }
+
+        spans
     }
 
-    /// Builds the right-side content (unread badge + timestamp).
+    /// Returns when the last message arrived, compacted by age.
+    fn timestamp(&self) -> Option<String> {
+        let last_message = self.chat.last_message.as_ref()?;
+        let now = self.now.unwrap_or_else(Local::now);
+        Some(format_compact_time(last_message.date, now))
+    }
+
+    /// Builds the right-side mention marker and unread badge.
     fn build_right_content(&self) -> Vec<Span<'static>> {
         let mut spans: Vec<Span<'static>> = Vec::new();
 
+        // Mentions stand out even in muted chats, as Telegram notifies
+        // about them regardless
+        if self.chat.unread_mentions > 0 {
+            spans.push(Span::styled(
+                " @ ".to_string(),
+                Style::default()
+                    .bg(colors::accent_secondary())
+                    .fg(colors::bg_primary())
+                    .add_modifier(Modifier::BOLD),
+            ));
+            spans.push(Span::raw(" "));
+        }
+
+        // So does a chat the user was just added to or promoted in
+        if let Some(tag) = self.tag {
+            spans.push(Span::styled(
+                format!(" {tag} "),
+                Style::default()
+                    .bg(colors::accent_primary())
+                    .fg(colors::bg_primary())
+                    .add_modifier(Modifier::BOLD),
+            ));
+            spans.push(Span::raw(" "));
+        }
+
+        // Highlight words stand out the same way
+        if self.highlighted {
+            spans.push(Span::styled(
+                " ! ".to_string(),
+                Style::default()
+                    .bg(colors::status_attention())
+                    .fg(colors::bg_primary())
+                    .add_modifier(Modifier::BOLD),
+            ));
+            spans.push(Span::raw(" "));
+        }
+
         // Unread count badge
         if self.chat.unread_count > 0 {
             let unread_text = if self.chat.unread_count > 99 {
@@ -233,65 +482,151 @@ impl<'a> ChatItemBuilder<'a> {
             spans.push(Span::raw(" "));
         }
 
-        // Timestamp
-        if let Some(ref last_message) = self.chat.last_message {
-            let timestamp = format_timestamp(last_message.date, true);
-            // Use non-breaking spaces to prevent wrapping
-            let timestamp = timestamp.replace(' ', "\u{00A0}");
-            spans.push(Span::styled(timestamp, Styles::text_muted()));
-        }
-
         spans
     }
 
-    /// Builds the preview line showing the last message.
-    fn build_preview_line(&self) -> Option<Line<'static>> {
-        let preview_text = self.get_preview_text();
-        if preview_text.is_empty() {
-            return None;
+    /// Builds the preview lines showing the draft or last message.
+    fn build_preview_lines(&self) -> Vec<Line<'static>> {
+        let (prefix, body) = self.preview_parts();
+        if body.is_empty() {
+            return Vec::new();
         }
 
-        let max_len = (self.width as usize).saturating_sub(4);
-        let truncated = truncate_string(&preview_text, max_len);
+        let indent = if self.show_avatar {
+            self.avatar_indent()
+        } else {
+            2
+        };
+        let max_len = (self.width as usize).saturating_sub(indent + 2);
+        let mut text = format!("{prefix}{}", body.replace('\n', " "));
+        if self.preview_length > 0 {
+            text = truncate_string(&text, self.preview_length);
+        }
+
+        let wrapped = word_wrap(&text, max_len);
+        let wrapped: Vec<&str> = wrapped.lines().collect();
+        let mut rows: Vec<String> = wrapped
+            .iter()
+            .take(self.preview_lines)
+            .map(|row| truncate_string(row, max_len))
+            .collect();
+        if wrapped.len() > self.preview_lines {
+            // Whatever didn't fit ends the last line with an ellipsis
+            let rest = wrapped[self.preview_lines - 1..].join(" ");
+            if let Some(last) = rows.last_mut() {
+                *last = truncate_string(&rest, max_len);
+            }
+        }
 
         let style = Style::default()
-            .fg(colors::fg_muted())
+            .fg(if self.typing.is_some() {
+                colors::accent_primary()
+            } else {
+                colors::fg_muted()
+            })
             .add_modifier(Modifier::ITALIC);
+        let prefix_style = if prefix == DRAFT_PREFIX {
+            Style::default().fg(colors::status_error())
+        } else {
+            Style::default().fg(colors::fg_primary())
+        };
 
-        Some(Line::from(vec![
-            Span::raw("  ".to_string()), // Indent for visual hierarchy
-            Span::styled(truncated, style),
-        ]))
+        rows.into_iter()
+            .enumerate()
+            .map(|(i, row)| {
+                let mut spans = vec![Span::raw(" ".repeat(indent))]; // Indent for visual hierarchy
+                match row.strip_prefix(prefix.as_str()) {
+                    Some(rest) if i == 0 && !prefix.is_empty() => {
+                        spans.push(Span::styled(prefix.clone(), prefix_style));
+                        spans.push(Span::styled(rest.to_string(), style));
+                    },
+                    _ => spans.push(Span::styled(row, style)),
+                }
+                Line::from(spans)
+            })
+            .collect()
     }
 
-    /// Gets the preview text for the last message.
-    fn get_preview_text(&self) -> String {
-        let Some(ref msg) = self.chat.last_message else {
-            return String::new();
+    /// Splits the preview into a prefix (draft marker or sender) and text.
+    ///
+    /// Someone typing takes precedence over everything else, then an unsent
+    /// draft over the last message.
+    fn preview_parts(&self) -> (String, String) {
+        let is_group = matches!(self.chat.chat_type, ChatType::Group | ChatType::Supergroup);
+        match self.typing {
+            Some(name) if is_group && !name.is_empty() => {
+                return (String::new(), format!("{name} is typing\u{2026}"));
+            },
+            Some(_) => return (String::new(), "typing\u{2026}".to_string()),
+            None => {},
+        }
+
+        let draft = self.chat.draft_message.trim();
+        let last_message = self.chat.last_message.as_deref();
+        if draft.is_empty() && last_message.is_none() {
+            return (String::new(), String::new());
+        }
+        if self.hide_preview_text {
+            return (String::new(), HIDDEN_PREVIEW.to_string());
+        }
+        let Some(msg) = last_message.filter(|_| draft.is_empty()) else {
+            return (DRAFT_PREFIX.to_string(), draft.to_string());
         };
 
-        let mut preview = if msg.is_outgoing {
+        let prefix = if msg.is_outgoing {
             "You: ".to_string()
         } else {
-            String::new()
+            match self.sender_name {
+                Some(name) if is_group && !name.is_empty() => format!("{name}: "),
+                _ => String::new(),
+            }
         };
 
-        preview.push_str(&msg.content.preview());
+        (prefix, msg.content.preview())
+    }
 
-        preview
+    /// Gets the full preview text, prefix included.
+    fn get_preview_text(&self) -> String {
+        let (prefix, body) = self.preview_parts();
+        if body.is_empty() {
+            return String::new();
+        }
+        format!("{prefix}{body}")
     }
 
     /// Returns the expected height of this item in lines.
     #[must_use]
-    pub const fn height(&self) -> u16 {
-        if self.show_preview && self.chat.last_message.is_some() {
-            3 // Title + preview + spacing
+    pub fn height(&self) -> u16 {
+        let preview = if self.show_preview {
+            self.build_preview_lines().len()
         } else {
-            2 // Title + spacing
-        }
+            0
+        };
+        // Title + preview + spacing
+        u16::try_from(preview).unwrap_or(0) + 2
     }
 }
 
+/// Returns the display width of some spans.
+fn spans_width(spans: &[Span<'_>]) -> usize {
+    spans
+        .iter()
+        .map(|s| UnicodeWidthStr::width(s.content.as_ref()))
+        .sum()
+}
+
+/// Returns the initials avatar for the user or chat with this ID and name,
+/// [`AVATAR_WIDTH`] columns wide.
+#[must_use]
+pub fn avatar(id: i64, name: &str) -> Span<'static> {
+    let initials = initials(name);
+    let pad = (AVATAR_WIDTH - 2).saturating_sub(UnicodeWidthStr::width(initials.as_str()));
+    Span::styled(
+        format!(" {initials}{} ", " ".repeat(pad)),
+        Styles::avatar(id),
+    )
+}
+
 // ============================================================================
 // Legacy compatibility - ChatItemComponent and ChatItemConfig
 // ============================================================================
@@ -398,6 +733,17 @@ mod tests {
         assert!(item.height() > 0);
     }
 
+    #[test]
+    fn test_alias_replaces_title_and_keeps_real_name() {
+        let chat = create_test_chat();
+        let line = ChatItemBuilder::new(&chat, 60)
+            .alias(Some("Bestie"))
+            .build_title_line();
+        let text: String = line.spans.iter().map(|s| s.content.as_ref()).collect();
+        assert!(text.starts_with("Bestie"));
+        assert!(text.contains("(Test Chat)"));
+    }
+
     #[test]
     fn test_preview_text_with_message() {
         let chat = create_test_chat();
@@ -406,6 +752,16 @@ mod tests {
         assert!(preview.contains("Hello, world!"));
     }
 
+    #[test]
+    fn test_hidden_preview_masks_text() {
+        let chat = create_test_chat();
+        let preview = ChatItemBuilder::new(&chat, 40)
+            .hide_preview_text(true)
+            .get_preview_text();
+        assert!(!preview.contains("Hello"));
+        assert!(preview.contains("hidden"));
+    }
+
     #[test]
     fn test_preview_text_outgoing() {
         let mut chat = create_test_chat();
@@ -417,6 +773,75 @@ mod tests {
         assert!(preview.starts_with("You: "));
     }
 
+    #[test]
+    fn test_group_preview_names_sender() {
+        let mut chat = create_test_chat();
+        let preview = ChatItemBuilder::new(&chat, 40)
+            .sender_name(Some("Alice"))
+            .get_preview_text();
+        // Private chats don't need the sender
+        assert_eq!(preview, "Hello, world!");
+
+        chat.chat_type = ChatType::Supergroup;
+        let preview = ChatItemBuilder::new(&chat, 40)
+            .sender_name(Some("Alice"))
+            .get_preview_text();
+        assert_eq!(preview, "Alice: Hello, world!");
+    }
+
+    #[test]
+    fn test_draft_replaces_last_message() {
+        let mut chat = create_test_chat();
+        chat.draft_message = "half a thought".to_string();
+        let builder = ChatItemBuilder::new(&chat, 40);
+        assert_eq!(builder.get_preview_text(), "Draft: half a thought");
+
+        let hidden = builder.hide_preview_text(true).get_preview_text();
+        assert!(!hidden.contains("thought"));
+    }
+
+    #[test]
+    fn test_typing_replaces_preview() {
+        let mut chat = create_test_chat();
+        let preview = ChatItemBuilder::new(&chat, 40)
+            .typing(Some("Alice"))
+            .get_preview_text();
+        assert_eq!(preview, "typing\u{2026}");
+
+        chat.chat_type = ChatType::Group;
+        chat.draft_message = "half a thought".to_string();
+        let preview = ChatItemBuilder::new(&chat, 40)
+            .typing(Some("Alice"))
+            .get_preview_text();
+        assert_eq!(preview, "Alice is typing\u{2026}");
+    }
+
+    #[test]
+    fn test_preview_wraps_onto_configured_lines() {
+        let mut chat = create_test_chat();
+        if let Some(ref mut msg) = chat.last_message {
+            msg.content.text = "one two three four five six seven eight nine ten".to_string();
+        }
+
+        let single = ChatItemBuilder::new(&chat, 24);
+        assert_eq!(single.build_preview_lines().len(), 1);
+        assert_eq!(single.height(), 3);
+
+        let lines = ChatItemBuilder::new(&chat, 24)
+            .preview_lines(2)
+            .build_preview_lines();
+        assert_eq!(lines.len(), 2);
+        let last: String = lines[1].spans.iter().map(|s| s.content.as_ref()).collect();
+        assert!(last.ends_with("..."));
+
+        // Capped length keeps it on one line
+        let capped = ChatItemBuilder::new(&chat, 24)
+            .preview_lines(3)
+            .preview_length(10)
+            .build_preview_lines();
+        assert_eq!(capped.len(), 1);
+    }
+
     #[test]
     fn test_height_with_preview() {
         let chat = create_test_chat();
@@ -453,6 +878,21 @@ mod tests {
         assert!(component.height() >= 2);
     }
 
+    #[test]
+    fn test_timestamp_dropped_when_too_narrow() {
+        let chat = create_test_chat();
+        let text = |width| {
+            let line = ChatItemBuilder::new(&chat, width).build_title_line();
+            line.spans
+                .iter()
+                .map(|s| s.content.as_ref())
+                .collect::<String>()
+        };
+
+        assert!(text(40).trim_end().ends_with("now"));
+        assert!(!text(16).contains("now"));
+    }
+
     #[test]
     fn test_unread_badge_capped() {
         let mut chat = create_test_chat();
@@ -462,4 +902,108 @@ mod tests {
         let text: String = right_spans.iter().map(|s| s.content.as_ref()).collect();
         assert!(text.contains("99+"));
     }
+
+    fn title_text(builder: ChatItemBuilder<'_>) -> String {
+        builder
+            .build_title_line()
+            .spans
+            .iter()
+            .map(|s| s.content.as_ref())
+            .collect()
+    }
+
+    #[test]
+    fn test_mention_marker_and_user_badges() {
+        let mut chat = create_test_chat();
+        chat.unread_mentions = 1;
+        let user = User {
+            id: chat.id,
+            is_verified: true,
+            is_bot: true,
+            ..Default::default()
+        };
+        let text = title_text(ChatItemBuilder::new(&chat, 60).user(Some(&user)));
+        assert!(text.starts_with("Test Chat \u{2713} 🤖 📌"));
+        assert!(text.contains(" @   5 "));
+    }
+
+    #[test]
+    fn test_highlight_marker() {
+        let chat = create_test_chat();
+        assert!(!title_text(ChatItemBuilder::new(&chat, 60)).contains(" ! "));
+        let text = title_text(ChatItemBuilder::new(&chat, 60).highlighted(true));
+        assert!(text.contains(" !   5 "));
+    }
+
+    #[test]
+    fn test_membership_tag() {
+        let chat = create_test_chat();
+        let text = title_text(ChatItemBuilder::new(&chat, 60).tag(Some("NEW")));
+        assert!(text.contains(" NEW   5 "));
+    }
+
+    #[test]
+    fn test_avatar_leads_the_title_in_the_peer_color() {
+        let chat = create_test_chat();
+        let line = ChatItemBuilder::new(&chat, 60)
+            .avatar(true)
+            .build_title_line();
+        assert_eq!(line.spans[0].content, " TC ");
+        assert_eq!(line.spans[0].style.bg, Some(colors::peer_color(chat.id)));
+        assert_eq!(
+            UnicodeWidthStr::width(
+                title_text(ChatItemBuilder::new(&chat, 60).avatar(true)).as_str()
+            ),
+            60
+        );
+
+        // The preview lines up under the title
+        let item = ChatItemBuilder::new(&chat, 60)
+            .avatar(true)
+            .build_preview_lines();
+        assert_eq!(item[0].spans[0].content.len(), AVATAR_WIDTH + 1);
+        assert_eq!(avatar(7, "Bob").content, " B  ");
+    }
+
+    #[test]
+    fn test_narrow_pane_drops_badges_but_keeps_counts() {
+        let mut chat = create_test_chat();
+        chat.unread_mentions = 2;
+        chat.is_muted = true;
+        let text = title_text(ChatItemBuilder::new(&chat, 18));
+        assert!(!text.contains('📌') && !text.contains('🔇'));
+        assert!(text.contains(" @ ") && text.contains(" 5 "));
+        assert!(UnicodeWidthStr::width(text.as_str()) <= 18);
+    }
+
+    #[test]
+    fn test_muted_title_is_dimmed() {
+        let mut chat = create_test_chat();
+        chat.is_muted = true;
+        let line = ChatItemBuilder::new(&chat, 60).build_title_line();
+        assert_eq!(line.spans[0].style.fg, Some(colors::fg_muted()));
+
+        // New messages still stand out
+        chat.has_new_message = true;
+        let line = ChatItemBuilder::new(&chat, 60).build_title_line();
+        assert_eq!(line.spans[0].style.fg, Some(colors::fg_bright()));
+    }
+
+    #[test]
+    fn test_missed_calls_badge() {
+        let mut chat = create_test_chat();
+        let title = |chat: &Chat| {
+            let line = ChatItemBuilder::new(chat, 60).build_title_line();
+            line.spans
+                .iter()
+                .map(|s| s.content.as_ref())
+                .collect::<String>()
+        };
+        assert!(!title(&chat).contains('📵'));
+
+        chat.missed_calls = 1;
+        assert!(title(&chat).contains("📵 "));
+        chat.missed_calls = 3;
+        assert!(title(&chat).contains("📵3"));
+    }
 }
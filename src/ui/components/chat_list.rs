@@ -9,21 +9,69 @@
 //! - Uses the standard [`List`] widget with [`ListState`] for selection
 //! - Leverages [`ListItem`] created by [`ChatItemBuilder`] for consistent styling
 //! - Applies highlight styles via the `List` widget's built-in methods
+//!
+//! Optionally the list is split into sections (pinned, unread, everything
+//! else) under headers, and a section can be collapsed with `z`.
+
+use std::collections::{HashMap, HashSet};
+use std::time::{Duration, Instant};
 
 use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
 use ratatui::{
     layout::Rect,
     style::{Modifier, Style},
     text::{Line, Span},
-    widgets::{Block, Borders, HighlightSpacing, List, ListState, Paragraph},
+    widgets::{Block, Borders, HighlightSpacing, List, ListItem, ListState, Paragraph},
 };
 
 use crate::cache::SharedCache;
-use crate::types::Chat;
+use crate::types::{Chat, ChatType, MembershipChange};
 use crate::ui::styles::{colors, Styles};
 
 use super::chat_item::ChatItemBuilder;
 
+/// How long a typing indicator lasts unless renewed; Telegram clients
+/// repeat it about every five seconds while typing.
+const TYPING_TIMEOUT: Duration = Duration::from_secs(6);
+
+/// A group of chats shown under its own header when sections are enabled.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, PartialOrd, Ord)]
+pub enum ChatSection {
+    /// Pinned chats
+    Pinned,
+    /// Unpinned chats with unread messages
+    Unread,
+    /// Everything else
+    All,
+}
+
+impl ChatSection {
+    /// Sections in display order.
+    pub const ALL: [Self; 3] = [Self::Pinned, Self::Unread, Self::All];
+
+    /// Returns the section a chat belongs in.
+    #[must_use]
+    pub const fn of(chat: &Chat) -> Self {
+        if chat.is_pinned {
+            Self::Pinned
+        } else if chat.unread_count > 0 {
+            Self::Unread
+        } else {
+            Self::All
+        }
+    }
+
+    /// Returns the header label.
+    #[must_use]
+    pub const fn label(self) -> &'static str {
+        match self {
+            Self::Pinned => "Pinned",
+            Self::Unread => "Unread",
+            Self::All => "All chats",
+        }
+    }
+}
+
 /// Actions that can result from chat list input handling.
 #[derive(Debug, Clone, PartialEq, Eq)]
 pub enum ChatListAction {
@@ -42,6 +90,8 @@ pub enum ChatListAction {
 /// - Jump to top/bottom with g/G or Home/End
 /// - Search mode activated with `/`
 /// - Quick jump to chats 1-9 with number keys
+/// - Optional section headers; `z` collapses the selected chat's section and
+///   `Z` expands them all
 ///
 /// # Example
 ///
@@ -75,6 +125,33 @@ pub struct ChatListModel {
     search_query: String,
     /// Filtered chats (when in search mode)
     filtered_chats: Vec<Chat>,
+    /// Local display-name overrides keyed by chat ID
+    aliases: HashMap<i64, String>,
+    /// Whether message previews are hidden (stealth mode)
+    blur_previews: bool,
+    /// Chats whose preview was revealed while previews are hidden
+    revealed: HashSet<i64>,
+    /// Maximum preview length in columns (0 for no cap)
+    preview_length: usize,
+    /// Lines each preview may wrap onto
+    preview_lines: usize,
+    /// Whether chats show initials avatars
+    show_avatars: bool,
+    /// Whether chats are grouped under section headers
+    sections: bool,
+    /// Sections whose chats are hidden
+    collapsed: HashSet<ChatSection>,
+    /// Chats in display order with collapsed sections left out (only
+    /// maintained while sections are enabled)
+    sectioned_chats: Vec<Chat>,
+    /// Render state for the list including header rows
+    render_state: ListState,
+    /// Who is typing in each chat and until when
+    typing: HashMap<i64, (i64, Instant)>,
+    /// Chats with a message matching a highlight word since last opened
+    highlighted: HashSet<i64>,
+    /// Chats the user was added to or promoted in since last opened
+    membership: HashMap<i64, MembershipChange>,
 }
 
 impl ChatListModel {
@@ -91,7 +168,208 @@ impl ChatListModel {
             search_mode: false,
             search_query: String::new(),
             filtered_chats: Vec::new(),
+            aliases: HashMap::new(),
+            blur_previews: false,
+            revealed: HashSet::new(),
+            preview_length: 0,
+            preview_lines: 1,
+            show_avatars: false,
+            sections: false,
+            collapsed: HashSet::new(),
+            sectioned_chats: Vec::new(),
+            render_state: ListState::default(),
+            typing: HashMap::new(),
+            highlighted: HashSet::new(),
+            membership: HashMap::new(),
+        }
+    }
+
+    /// Turns section headers on or off.
+    pub fn set_sections(&mut self, enabled: bool) {
+        if self.sections == enabled {
+            return;
         }
+        let selected = self.get_selected_chat_id();
+        self.sections = enabled;
+        self.collapsed.clear();
+        self.resort();
+        self.reselect(selected);
+    }
+
+    /// Collapses the selected chat's section.
+    ///
+    /// The selection moves to the first chat after the section, or the last
+    /// visible chat if none follows.
+    fn collapse_selected_section(&mut self) {
+        let Some(chat) = self.get_selected_chat() else {
+            return;
+        };
+        let section = ChatSection::of(chat);
+        self.collapsed.insert(section);
+        self.resort();
+
+        let visible = self.get_active_chats().len();
+        if visible == 0 {
+            self.list_state.select(None);
+        } else {
+            // Chats after the collapsed section shift up into its place
+            let first_after = self
+                .sectioned_chats
+                .iter()
+                .position(|c| ChatSection::of(c) > section)
+                .unwrap_or(visible);
+            self.list_state.select(Some(first_after.min(visible - 1)));
+        }
+    }
+
+    /// Expands every collapsed section, keeping the selection.
+    fn expand_all_sections(&mut self) {
+        let selected = self.get_selected_chat_id();
+        self.collapsed.clear();
+        self.resort();
+        self.reselect(selected);
+    }
+
+    /// Selects `chat_id` if it is visible, or the first chat otherwise.
+    fn reselect(&mut self, chat_id: Option<i64>) {
+        match chat_id.and_then(|id| self.get_active_chats().iter().position(|c| c.id == id)) {
+            Some(idx) => self.list_state.select(Some(idx)),
+            None => self.select_first_if_available(),
+        }
+    }
+
+    /// Sorts the chats and, with sections on, rebuilds the sectioned view.
+    fn resort(&mut self) {
+        Self::sort_chats(&mut self.chats);
+        if self.sections {
+            // Stable, so each section keeps the recency order
+            let mut chats: Vec<Chat> = self
+                .chats
+                .iter()
+                .filter(|c| !self.collapsed.contains(&ChatSection::of(c)))
+                .cloned()
+                .collect();
+            chats.sort_by_key(ChatSection::of);
+            self.sectioned_chats = chats;
+        } else {
+            self.sectioned_chats.clear();
+        }
+    }
+
+    /// Sets whether chats show initials avatars.
+    pub fn set_show_avatars(&mut self, show: bool) {
+        self.show_avatars = show;
+    }
+
+    /// Sets how long previews may be and how many lines they may take.
+    pub fn set_preview_format(&mut self, length: usize, lines: usize) {
+        self.preview_length = length;
+        self.preview_lines = lines;
+    }
+
+    /// Returns the short name of the last message's sender in a group,
+    /// preferring the local alias.
+    fn preview_sender(&self, chat: &Chat) -> Option<String> {
+        let msg = chat.last_message.as_ref()?;
+        if msg.is_outgoing || msg.sender_id == 0 {
+            return None;
+        }
+        self.short_name(msg.sender_id)
+    }
+
+    /// Returns a user's alias or first name.
+    fn short_name(&self, user_id: i64) -> Option<String> {
+        if let Some(alias) = self.alias_for(user_id) {
+            return Some(alias.to_string());
+        }
+        let user = self.cache.get_user(user_id)?;
+        if user.first_name.is_empty() {
+            Some(user.get_display_name())
+        } else {
+            Some(user.first_name)
+        }
+    }
+
+    /// Notes that `user_id` started typing in a chat at `now`; the chat's
+    /// preview reads "typing…" for the next few seconds.
+    pub fn set_typing(&mut self, chat_id: i64, user_id: i64, now: Instant) {
+        self.typing.retain(|_, (_, until)| *until > now);
+        self.typing.insert(chat_id, (user_id, now + TYPING_TIMEOUT));
+    }
+
+    /// Drops a chat's typing indicator, as when the message arrives.
+    pub fn clear_typing(&mut self, chat_id: i64) {
+        self.typing.remove(&chat_id);
+    }
+
+    /// Returns the name of whoever is typing in a chat at `now` (empty if
+    /// unknown), or `None` if nobody is.
+    #[must_use]
+    pub fn typing_name(&self, chat_id: i64, now: Instant) -> Option<String> {
+        let (user_id, until) = self.typing.get(&chat_id)?;
+        (*until > now).then(|| self.short_name(*user_id).unwrap_or_default())
+    }
+
+    /// Marks a chat as having a message that matches a highlight word, or
+    /// clears the mark once the chat is opened.
+    pub fn set_highlighted(&mut self, chat_id: i64, highlighted: bool) {
+        if highlighted {
+            self.highlighted.insert(chat_id);
+        } else {
+            self.highlighted.remove(&chat_id);
+        }
+    }
+
+    /// Tags a chat the user was added to or made an admin in, or clears
+    /// the tag once the chat is opened.
+    pub fn set_membership(&mut self, chat_id: i64, change: Option<MembershipChange>) {
+        match change {
+            Some(change) => self.membership.insert(chat_id, change),
+            None => self.membership.remove(&chat_id),
+        };
+    }
+
+    /// Returns how the user's place in a chat changed since it was last
+    /// opened, if it did.
+    #[must_use]
+    pub fn membership(&self, chat_id: i64) -> Option<MembershipChange> {
+        self.membership.get(&chat_id).copied()
+    }
+
+    /// Hides (or shows) message previews; hiding forgets earlier reveals.
+    pub fn set_blur_previews(&mut self, blur: bool) {
+        if blur && !self.blur_previews {
+            self.revealed.clear();
+        }
+        self.blur_previews = blur;
+    }
+
+    /// Returns `true` if the chat's preview is currently hidden.
+    #[must_use]
+    pub fn is_preview_hidden(&self, chat_id: i64) -> bool {
+        self.blur_previews && !self.revealed.contains(&chat_id)
+    }
+
+    /// Toggles the preview of the selected chat while previews are hidden.
+    fn toggle_reveal_selected(&mut self) {
+        if let Some(chat_id) = self.get_selected_chat_id() {
+            if !self.revealed.remove(&chat_id) {
+                self.revealed.insert(chat_id);
+            }
+        }
+    }
+
+    /// Sets the local chat aliases used for display and search.
+    pub fn set_aliases(&mut self, aliases: HashMap<i64, String>) {
+        self.aliases = aliases;
+    }
+
+    /// Returns the alias for a chat, ignoring blank entries.
+    fn alias_for(&self, chat_id: i64) -> Option<&str> {
+        self.aliases
+            .get(&chat_id)
+            .map(|a| a.trim())
+            .filter(|a| !a.is_empty())
     }
 
     /// Sets the size of the chat list pane.
@@ -125,18 +403,10 @@ impl ChatListModel {
         // Sort chats by recency (pinned first, then by last message date)
         Self::sort_chats(&mut chats);
         self.chats = chats;
+        self.resort();
 
-        // Try to maintain selection on the same chat
-        if let Some(chat_id) = selected_chat_id {
-            if let Some(new_idx) = self.chats.iter().position(|c| c.id == chat_id) {
-                self.list_state.select(Some(new_idx));
-            } else {
-                // Chat not found, select first if available
-                self.select_first_if_available();
-            }
-        } else {
-            self.select_first_if_available();
-        }
+        // Try to maintain selection on the same chat, else select the first
+        self.reselect(selected_chat_id);
     }
 
     /// Selects the first chat if the list is not empty.
@@ -173,7 +443,7 @@ impl ChatListModel {
         } else {
             self.chats.push(chat);
         }
-        Self::sort_chats(&mut self.chats);
+        self.resort();
     }
 
     /// Marks a chat as having a new message and moves it to top.
@@ -181,7 +451,7 @@ impl ChatListModel {
         if let Some(chat) = self.chats.iter_mut().find(|c| c.id == chat_id) {
             chat.has_new_message = true;
         }
-        Self::sort_chats(&mut self.chats);
+        self.resort();
     }
 
     /// Clears the new message flag for a chat.
@@ -189,6 +459,7 @@ impl ChatListModel {
         if let Some(chat) = self.chats.iter_mut().find(|c| c.id == chat_id) {
             chat.has_new_message = false;
         }
+        self.resort();
     }
 
     /// Returns the currently selected chat.
@@ -210,13 +481,16 @@ impl ChatListModel {
             &self.filtered_chats
         } else if self.search_mode {
             &[]
+        } else if self.sections {
+            &self.sectioned_chats
         } else {
             &self.chats
         }
     }
 
     /// Returns the current selection index.
-    fn selected_index(&self) -> usize {
+    #[must_use]
+    pub fn selected_index(&self) -> usize {
         self.list_state.selected().unwrap_or(0)
     }
 
@@ -307,6 +581,18 @@ impl ChatListModel {
                 }
                 ChatListAction::None
             },
+            KeyCode::Char('v') if self.blur_previews => {
+                self.toggle_reveal_selected();
+                ChatListAction::None
+            },
+            KeyCode::Char('z') if self.sections => {
+                self.collapse_selected_section();
+                ChatListAction::None
+            },
+            KeyCode::Char('Z') if self.sections => {
+                self.expand_all_sections();
+                ChatListAction::None
+            },
             KeyCode::Home | KeyCode::Char('g') => {
                 if !self.get_active_chats().is_empty() {
                     self.list_state.select(Some(0));
@@ -395,6 +681,13 @@ impl ChatListModel {
             .chats
             .iter()
             .filter(|chat| {
+                // Search in alias
+                if self
+                    .alias_for(chat.id)
+                    .is_some_and(|a| a.to_lowercase().contains(&query))
+                {
+                    return true;
+                }
                 // Search in title
                 if chat.title.to_lowercase().contains(&query) {
                     return true;
@@ -429,6 +722,93 @@ impl ChatListModel {
         self.search_mode
     }
 
+    /// Returns all chats in list order (pinned first, then most recent).
+    #[must_use]
+    pub fn chats(&self) -> &[Chat] {
+        &self.chats
+    }
+
+    /// Returns the total unread count across chats that are not muted.
+    #[must_use]
+    pub fn total_unread(&self) -> u32 {
+        self.chats
+            .iter()
+            .filter(|c| !c.is_muted)
+            .map(|c| u32::try_from(c.unread_count).unwrap_or(0))
+            .sum()
+    }
+
+    /// Returns the local chat aliases.
+    #[must_use]
+    pub const fn aliases(&self) -> &HashMap<i64, String> {
+        &self.aliases
+    }
+
+    /// Returns each chat's display name (alias or title), in list order.
+    #[must_use]
+    pub fn chat_names(&self) -> Vec<&str> {
+        self.chats
+            .iter()
+            .map(|c| self.alias_for(c.id).unwrap_or(&c.title))
+            .collect()
+    }
+
+    /// Finds the chat best matching `query` by alias, title, or username.
+    ///
+    /// Exact matches beat prefix matches, which beat substring matches; ties
+    /// go to the chat higher in the list.
+    #[must_use]
+    pub fn find_chat(&self, query: &str) -> Option<i64> {
+        let query = query.trim().trim_start_matches('@').to_lowercase();
+        if query.is_empty() {
+            return None;
+        }
+
+        self.chats
+            .iter()
+            .filter_map(|chat| {
+                let names = [
+                    self.alias_for(chat.id).unwrap_or_default(),
+                    chat.title.as_str(),
+                    chat.username.as_str(),
+                ];
+                names
+                    .iter()
+                    .filter(|n| !n.is_empty())
+                    .filter_map(|n| {
+                        let n = n.to_lowercase();
+                        if n == query {
+                            Some(0)
+                        } else if n.starts_with(&query) {
+                            Some(1)
+                        } else if n.contains(&query) {
+                            Some(2)
+                        } else {
+                            None
+                        }
+                    })
+                    .min()
+                    .map(|rank| (rank, chat.id))
+            })
+            .min_by_key(|(rank, _)| *rank)
+            .map(|(_, id)| id)
+    }
+
+    /// Selects the chat with the given ID, leaving search mode if needed.
+    pub fn select_chat(&mut self, chat_id: i64) {
+        let Some(chat) = self.chats.iter().find(|c| c.id == chat_id) else {
+            return;
+        };
+        self.search_mode = false;
+        self.search_query.clear();
+        self.filtered_chats.clear();
+        // Jumping into a collapsed section opens it
+        if self.collapsed.remove(&ChatSection::of(chat)) {
+            self.resort();
+        }
+        self.reselect(Some(chat_id));
+    }
+
     /// Renders the chat list.
     pub fn render(&mut self, frame: &mut ratatui::Frame<'_>, area: Rect) {
         // Update size
@@ -454,8 +834,10 @@ impl ChatListModel {
 
         // Get active chats
         let chats = self.get_active_chats();
+        let show_sections = self.sections && !self.search_mode;
 
-        if chats.is_empty() {
+        // With every section collapsed the headers still need showing
+        if chats.is_empty() && !(show_sections && !self.chats.is_empty()) {
             // Render empty state
             frame.render_widget(block, area);
             let empty_text = if self.search_mode {
@@ -477,15 +859,66 @@ impl ChatListModel {
             return;
         }
 
-        // Build list items using ChatItemBuilder
-        let items: Vec<_> = chats
-            .iter()
-            .map(|chat| {
-                ChatItemBuilder::new(chat, inner_area.width.saturating_sub(4))
-                    .show_preview(true)
-                    .build()
-            })
-            .collect();
+        // Build list items using ChatItemBuilder; timestamps are relative
+        // to the moment of this draw, so they age with each tick
+        let now = chrono::Local::now();
+        let instant = Instant::now();
+        let chat_item = |chat: &Chat| {
+            let sender = self.preview_sender(chat);
+            let typing = self.typing_name(chat.id, instant);
+            let user = if chat.chat_type == ChatType::Private {
+                self.cache.get_user(chat.id)
+            } else {
+                None
+            };
+            ChatItemBuilder::new(chat, inner_area.width.saturating_sub(4))
+                .show_preview(true)
+                .alias(self.alias_for(chat.id))
+                .sender_name(sender.as_deref())
+                .typing(typing.as_deref())
+                .user(user.as_ref())
+                .preview_length(self.preview_length)
+                .preview_lines(self.preview_lines)
+                .now(now)
+                .hide_preview_text(self.is_preview_hidden(chat.id))
+                .highlighted(self.highlighted.contains(&chat.id))
+                .tag(self.membership(chat.id).map(MembershipChange::tag))
+                .avatar(self.show_avatars)
+                .build()
+        };
+
+        // Header rows shift chats down, so the rendered row of the
+        // selection is tracked separately from its index among the chats
+        let mut items = Vec::with_capacity(chats.len() + ChatSection::ALL.len());
+        let mut selected_row = None;
+        if show_sections {
+            let selected = self.list_state.selected();
+            let mut index = 0;
+            for section in ChatSection::ALL {
+                let total = self
+                    .chats
+                    .iter()
+                    .filter(|c| ChatSection::of(c) == section)
+                    .count();
+                if total == 0 {
+                    continue;
+                }
+                let collapsed = self.collapsed.contains(&section);
+                items.push(section_header(section, total, collapsed));
+                if collapsed {
+                    continue;
+                }
+                for chat in chats.iter().filter(|c| ChatSection::of(c) == section) {
+                    if selected == Some(index) {
+                        selected_row = Some(items.len());
+                    }
+                    items.push(chat_item(chat));
+                    index += 1;
+                }
+            }
+        } else {
+            items.extend(chats.iter().map(chat_item));
+        }
 
         // Create the List widget with Ratatui's standard patterns
         let highlight_style = if self.focused {
@@ -507,7 +940,12 @@ impl ChatListModel {
             .repeat_highlight_symbol(true);
 
         // Render the list with state
-        frame.render_stateful_widget(list, area, &mut self.list_state);
+        if show_sections {
+            self.render_state.select(selected_row);
+            frame.render_stateful_widget(list, area, &mut self.render_state);
+        } else {
+            frame.render_stateful_widget(list, area, &mut self.list_state);
+        }
     }
 
     /// Builds the title for the chat list pane.
@@ -535,11 +973,20 @@ pub struct ChatListState {
     pub scroll_offset: usize,
 }
 
+/// Builds the header row for a section, with its chat count.
+fn section_header(section: ChatSection, total: usize, collapsed: bool) -> ListItem<'static> {
+    let marker = if collapsed { "\u{25b8}" } else { "\u{25be}" };
+    ListItem::new(Line::from(Span::styled(
+        format!("{marker} {} ({total})", section.label()),
+        Styles::text_muted().add_modifier(Modifier::BOLD),
+    )))
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
     use crate::cache::new_shared_cache;
-    use crate::types::{ChatType, Message, MessageContent};
+    use crate::types::{Message, MessageContent};
     use chrono::Utc;
 
     fn create_test_model() -> ChatListModel {
@@ -565,6 +1012,79 @@ mod tests {
         }
     }
 
+    #[test]
+    fn test_v_reveals_hidden_preview_of_selected_chat() {
+        let mut model = create_test_model();
+        model.set_chats(vec![create_test_chat(1, "A"), create_test_chat(2, "B")]);
+        model.set_blur_previews(true);
+        assert!(model.is_preview_hidden(1));
+
+        let selected = model.get_selected_chat_id().unwrap();
+        model.handle_input(KeyEvent::from(KeyCode::Char('v')));
+        assert!(!model.is_preview_hidden(selected));
+
+        // Turning blur off and on again hides everything once more
+        model.set_blur_previews(false);
+        model.set_blur_previews(true);
+        assert!(model.is_preview_hidden(selected));
+    }
+
+    #[test]
+    fn test_typing_expires_and_clears() {
+        let mut model = create_test_model();
+        model.set_aliases(HashMap::from([(7, "Alice".to_string())]));
+        let start = Instant::now();
+        model.set_typing(1, 7, start);
+        model.set_typing(2, 8, start);
+        assert_eq!(model.typing_name(1, start).as_deref(), Some("Alice"));
+        assert_eq!(
+            model.typing_name(2, start).as_deref(),
+            Some(""),
+            "unknown typist"
+        );
+
+        model.clear_typing(2);
+        assert_eq!(model.typing_name(2, start), None);
+        assert_eq!(
+            model.typing_name(1, start + TYPING_TIMEOUT),
+            None,
+            "expired"
+        );
+    }
+
+    #[test]
+    fn test_total_unread_skips_muted_chats() {
+        let mut model = create_test_model();
+        let mut a = create_test_chat(1, "A");
+        a.unread_count = 3;
+        let mut b = create_test_chat(2, "B");
+        b.unread_count = 5;
+        b.is_muted = true;
+        let mut c = create_test_chat(3, "C");
+        c.unread_count = 2;
+        model.set_chats(vec![a, b, c]);
+        assert_eq!(model.total_unread(), 5);
+    }
+
+    #[test]
+    fn test_find_chat_prefers_exact_then_prefix() {
+        let mut model = create_test_model();
+        model.set_chats(vec![
+            create_test_chat(1, "Project Alpha"),
+            create_test_chat(2, "Alpha"),
+            create_test_chat(3, "Alphabet Soup"),
+        ]);
+        model.set_aliases(HashMap::from([(4, "Boss".to_string())]));
+        model.update_chat(create_test_chat(4, "Jane Smith"));
+
+        assert_eq!(model.find_chat("alpha"), Some(2));
+        assert_eq!(model.find_chat("alphab"), Some(3));
+        assert_eq!(model.find_chat("ject"), Some(1));
+        assert_eq!(model.find_chat("boss"), Some(4));
+        assert_eq!(model.find_chat("nobody"), None);
+        assert!(model.chat_names().contains(&"Boss"));
+    }
+
     #[test]
     fn test_new_model() {
         let model = create_test_model();
@@ -627,6 +1147,22 @@ mod tests {
         assert_eq!(model.chat_count(), 3);
     }
 
+    #[test]
+    fn test_search_matches_alias() {
+        let mut model = create_test_model();
+        model.set_chats(vec![
+            create_test_chat(1, "Alice"),
+            create_test_chat(2, "+1 555 0100"),
+        ]);
+        model.set_aliases(HashMap::from([(2, "Plumber".to_string())]));
+
+        model.enter_search_mode();
+        model.search_query = "plumb".to_string();
+        model.filter_chats();
+        assert_eq!(model.chat_count(), 1);
+        assert_eq!(model.get_selected_chat_id(), Some(2));
+    }
+
     #[test]
     fn test_pinned_sorting() {
         let mut model = create_test_model();
@@ -647,6 +1183,55 @@ mod tests {
         assert_eq!(chats[2].title, "Unpinned");
     }
 
+    fn sectioned_model() -> ChatListModel {
+        let mut model = create_test_model();
+        let mut pinned = create_test_chat(1, "Pinned");
+        pinned.is_pinned = true;
+        let mut unread = create_test_chat(2, "Unread");
+        unread.unread_count = 3;
+        let read = create_test_chat(3, "Read");
+        // Most recent first would put the read chat above the unread one
+        if let Some(msg) = unread.last_message.as_mut() {
+            msg.date = Utc::now() - chrono::Duration::hours(1);
+        }
+        model.set_chats(vec![read, unread, pinned]);
+        model.set_sections(true);
+        model
+    }
+
+    #[test]
+    fn test_sections_group_unread_before_the_rest() {
+        let model = sectioned_model();
+        let titles: Vec<&str> = model
+            .get_active_chats()
+            .iter()
+            .map(|c| c.title.as_str())
+            .collect();
+        assert_eq!(titles, vec!["Pinned", "Unread", "Read"]);
+    }
+
+    #[test]
+    fn test_z_collapses_selected_section() {
+        let mut model = sectioned_model();
+        model.select_chat(2);
+        model.handle_input(KeyEvent::from(KeyCode::Char('z')));
+
+        let ids: Vec<i64> = model.get_active_chats().iter().map(|c| c.id).collect();
+        assert_eq!(ids, vec![1, 3]);
+        // Selection moves to the chat after the collapsed section
+        assert_eq!(model.get_selected_chat_id(), Some(3));
+
+        // Jumping to a hidden chat opens its section again
+        model.select_chat(2);
+        assert_eq!(model.get_selected_chat_id(), Some(2));
+        assert_eq!(model.get_active_chats().len(), 3);
+
+        model.handle_input(KeyEvent::from(KeyCode::Char('z')));
+        model.handle_input(KeyEvent::from(KeyCode::Char('Z')));
+        assert_eq!(model.get_active_chats().len(), 3);
+        assert_eq!(model.get_selected_chat_id(), Some(3));
+    }
+
     #[test]
     fn test_open_chat_action() {
         let mut model = create_test_model();
@@ -0,0 +1,383 @@
+//! Statistics for the open chat, computed from its stored history
+//! (`/stats`).
+//!
+//! Shows who writes most, when the chat is busiest (as an ASCII heatmap of
+//! weekday by hour), how much media is shared, and how quickly people
+//! answer each other. Only messages already stored locally are counted, so
+//! scrolling further back gives a fuller picture.
+
+use chrono::{DateTime, Datelike, Local, Timelike, Utc};
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use crate::types::{Message, MessageType};
+use crate::ui::styles::Styles;
+use crate::utils::format_duration;
+
+/// Gaps longer than this between two people's messages are a new
+/// conversation rather than a reply, and don't count towards reply time.
+const MAX_REPLY_GAP_SECS: i64 = 6 * 3600;
+
+/// Heatmap shades from quietest to busiest.
+const SHADES: [char; 5] = [' ', '.', ':', '*', '#'];
+
+/// Participants listed, busiest first.
+const TOP_PARTICIPANTS: usize = 10;
+
+/// Statistics computed from a chat's messages.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct ChatStats {
+    /// Messages counted
+    pub total: usize,
+    /// Oldest and newest message times
+    pub span: Option<(DateTime<Utc>, DateTime<Utc>)>,
+    /// Messages per sender name, busiest first
+    pub per_participant: Vec<(String, usize)>,
+    /// Messages per local weekday (Monday first) and hour
+    pub heatmap: [[usize; 24]; 7],
+    /// Messages per kind of media, most common first
+    pub media: Vec<(&'static str, usize)>,
+    /// Average time before someone answers another person's message
+    pub average_reply_secs: Option<i64>,
+}
+
+impl ChatStats {
+    /// Computes statistics for `messages`, naming senders with `name_of`.
+    ///
+    /// Service messages (joins, title changes) are skipped.
+    #[must_use]
+    pub fn compute(messages: &[Message], name_of: impl Fn(&Message) -> String) -> Self {
+        let mut messages: Vec<&Message> = messages
+            .iter()
+            .filter(|m| m.content.content_type != MessageType::Service)
+            .collect();
+        messages.sort_by_key(|m| (m.date, m.id));
+
+        let mut stats = Self {
+            total: messages.len(),
+            span: messages
+                .first()
+                .zip(messages.last())
+                .map(|(a, b)| (a.date, b.date)),
+            ..Self::default()
+        };
+
+        let mut replies = (0_i64, 0_i64);
+        let mut previous: Option<&Message> = None;
+        for message in &messages {
+            let name = name_of(message);
+            match stats.per_participant.iter_mut().find(|(n, _)| *n == name) {
+                Some((_, count)) => *count += 1,
+                None => stats.per_participant.push((name, 1)),
+            }
+
+            let local = message.date.with_timezone(&Local);
+            let day = local.weekday().num_days_from_monday() as usize;
+            stats.heatmap[day][local.hour() as usize] += 1;
+
+            if let Some(kind) = media_kind(message.content.content_type) {
+                match stats.media.iter_mut().find(|(k, _)| *k == kind) {
+                    Some((_, count)) => *count += 1,
+                    None => stats.media.push((kind, 1)),
+                }
+            }
+
+            if let Some(prev) = previous {
+                let gap = (message.date - prev.date).num_seconds();
+                if prev.sender_id != message.sender_id && (0..=MAX_REPLY_GAP_SECS).contains(&gap) {
+                    replies.0 += gap;
+                    replies.1 += 1;
+                }
+            }
+            previous = Some(message);
+        }
+
+        stats
+            .per_participant
+            .sort_by(|a, b| b.1.cmp(&a.1).then_with(|| a.0.cmp(&b.0)));
+        stats
+            .media
+            .sort_by(|a, b| b.1.cmp(&a.1).then_with(|| a.0.cmp(&b.0)));
+        stats.average_reply_secs = (replies.1 > 0).then(|| replies.0 / replies.1);
+        stats
+    }
+
+    /// Returns the heatmap as text: one row per weekday, one column per
+    /// hour, shaded relative to the busiest hour.
+    #[must_use]
+    pub fn heatmap_rows(&self) -> Vec<String> {
+        const DAYS: [&str; 7] = ["Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"];
+        let busiest = self.heatmap.iter().flatten().copied().max().unwrap_or(0);
+
+        DAYS.iter()
+            .zip(&self.heatmap)
+            .map(|(day, hours)| {
+                let cells: String = hours.iter().map(|&count| shade(count, busiest)).collect();
+                format!("{day} |{cells}|")
+            })
+            .collect()
+    }
+}
+
+/// Picks the heatmap shade for `count` out of `busiest`.
+fn shade(count: usize, busiest: usize) -> char {
+    if count == 0 || busiest == 0 {
+        return SHADES[0];
+    }
+    // Any activity gets at least the faintest mark
+    let level = (count * (SHADES.len() - 1)).div_ceil(busiest);
+    SHADES[level.clamp(1, SHADES.len() - 1)]
+}
+
+/// Returns the label media of this kind is counted under, if it's media.
+const fn media_kind(kind: MessageType) -> Option<&'static str> {
+    Some(match kind {
+        MessageType::Photo => "Photos",
+        MessageType::Video | MessageType::VideoNote => "Videos",
+        MessageType::Voice => "Voice messages",
+        MessageType::Audio => "Audio",
+        MessageType::Document => "Files",
+        MessageType::Sticker => "Stickers",
+        MessageType::Animation => "GIFs",
+        MessageType::Location | MessageType::Venue => "Locations",
+        MessageType::Poll => "Polls",
+        _ => return None,
+    })
+}
+
+/// Result of a key press in the stats view.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ChatStatsAction {
+    /// Key was handled; keep the view open
+    None,
+    /// Close the view
+    Close,
+}
+
+/// Overlay showing a chat's [`ChatStats`].
+#[derive(Debug, Clone)]
+pub struct ChatStatsView {
+    /// Chat name, for the title
+    title: String,
+    stats: ChatStats,
+    scroll: u16,
+}
+
+impl ChatStatsView {
+    /// Creates the view for a chat's computed statistics.
+    #[must_use]
+    pub fn new(title: impl Into<String>, stats: ChatStats) -> Self {
+        Self {
+            title: title.into(),
+            stats,
+            scroll: 0,
+        }
+    }
+
+    /// Returns the statistics shown.
+    #[must_use]
+    pub const fn stats(&self) -> &ChatStats {
+        &self.stats
+    }
+
+    /// Handles a key press: `j`/`k` scroll, `Esc`/`q` close.
+    pub fn handle_input(&mut self, key: KeyEvent) -> ChatStatsAction {
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') => ChatStatsAction::Close,
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.scroll = self.scroll.saturating_sub(1);
+                ChatStatsAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                self.scroll = self.scroll.saturating_add(1);
+                ChatStatsAction::None
+            },
+            _ => ChatStatsAction::None,
+        }
+    }
+
+    /// Builds the text shown in the view.
+    fn lines(&self) -> Vec<Line<'static>> {
+        let stats = &self.stats;
+        let heading =
+            |text: &str| Line::from(Span::styled(text.to_string(), Styles::text_accent()));
+        let row = |label: String, value: String| {
+            Line::from(vec![
+                Span::styled(format!("  {label:<20}"), Styles::text()),
+                Span::styled(value, Styles::text_bright()),
+            ])
+        };
+
+        if stats.total == 0 {
+            return vec![Line::from(Span::styled(
+                "No stored messages yet",
+                Styles::text_muted(),
+            ))];
+        }
+
+        let mut lines = Vec::new();
+        let span = stats.span.map_or_else(String::new, |(first, last)| {
+            format!(
+                " from {} to {}",
+                first.with_timezone(&Local).format("%b %-d, %Y"),
+                last.with_timezone(&Local).format("%b %-d, %Y")
+            )
+        });
+        lines.push(Line::from(Span::styled(
+            format!("{} stored messages{span}", stats.total),
+            Styles::text(),
+        )));
+        if let Some(secs) = stats.average_reply_secs {
+            lines.push(Line::from(Span::styled(
+                format!(
+                    "Average reply time: {}",
+                    format_duration(chrono::Duration::seconds(secs))
+                ),
+                Styles::text(),
+            )));
+        }
+
+        lines.push(Line::from(""));
+        lines.push(heading("Messages per participant"));
+        for (name, count) in stats.per_participant.iter().take(TOP_PARTICIPANTS) {
+            lines.push(row(name.clone(), count.to_string()));
+        }
+        let others = stats.per_participant.len().saturating_sub(TOP_PARTICIPANTS);
+        if others > 0 {
+            lines.push(Line::from(Span::styled(
+                format!("  and {others} more"),
+                Styles::text_muted(),
+            )));
+        }
+
+        lines.push(Line::from(""));
+        lines.push(heading("Busiest hours"));
+        lines.push(Line::from(Span::styled(
+            "      0     6     12    18",
+            Styles::text_muted(),
+        )));
+        for text in stats.heatmap_rows() {
+            lines.push(Line::from(Span::styled(format!(" {text}"), Styles::text())));
+        }
+
+        if !stats.media.is_empty() {
+            lines.push(Line::from(""));
+            lines.push(heading("Media"));
+            for (kind, count) in &stats.media {
+                lines.push(row((*kind).to_string(), count.to_string()));
+            }
+        }
+        lines
+    }
+
+    /// Renders the view as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 60.min(area.width.saturating_sub(4));
+        let h = 32.min(area.height.saturating_sub(2));
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" Stats: {} ", self.title),
+                Styles::text_bright(),
+            ))
+            .title_bottom(Span::styled(
+                " j/k scroll \u{2022} Esc close ",
+                Styles::text_muted(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let paragraph = Paragraph::new(self.lines())
+            .block(block)
+            .scroll((self.scroll, 0));
+        frame.render_widget(paragraph, modal);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use chrono::{Duration, TimeZone};
+
+    use super::*;
+
+    fn message(id: i64, sender_id: i64, minutes: i64, kind: MessageType) -> Message {
+        let start = Utc.with_ymd_and_hms(2026, 3, 2, 9, 0, 0).unwrap();
+        let mut message = Message {
+            id,
+            chat_id: 1,
+            sender_id,
+            date: start + Duration::minutes(minutes),
+            ..Default::default()
+        };
+        message.content.content_type = kind;
+        message
+    }
+
+    fn names(message: &Message) -> String {
+        if message.sender_id == 1 {
+            "Alice".to_string()
+        } else {
+            "Bob".to_string()
+        }
+    }
+
+    #[test]
+    fn counts_participants_media_and_reply_time() {
+        let messages = vec![
+            message(1, 1, 0, MessageType::Text),
+            message(2, 2, 10, MessageType::Photo),
+            message(3, 2, 11, MessageType::Photo),
+            message(4, 1, 31, MessageType::Text),
+            // A day later: a new conversation, not a reply
+            message(5, 2, 24 * 60, MessageType::Voice),
+            message(6, 1, 24 * 60 + 1, MessageType::Service),
+        ];
+        let stats = ChatStats::compute(&messages, names);
+
+        assert_eq!(stats.total, 5);
+        assert_eq!(
+            stats.per_participant,
+            vec![("Bob".to_string(), 3), ("Alice".to_string(), 2)]
+        );
+        assert_eq!(stats.media, vec![("Photos", 2), ("Voice messages", 1)]);
+        // Bob answered after 10 minutes, Alice after 20
+        assert_eq!(stats.average_reply_secs, Some(15 * 60));
+    }
+
+    #[test]
+    fn heatmap_marks_active_hours() {
+        let messages = vec![
+            message(1, 1, 0, MessageType::Text),
+            message(2, 1, 1, MessageType::Text),
+            message(3, 2, 2, MessageType::Text),
+        ];
+        let stats = ChatStats::compute(&messages, names);
+        let rows = stats.heatmap_rows();
+
+        assert_eq!(rows.len(), 7);
+        let marks: usize = rows
+            .iter()
+            .map(|r| r.chars().filter(|c| *c == '#').count())
+            .sum();
+        assert_eq!(marks, 1, "only the busiest hour is fully shaded");
+        assert!(rows.iter().all(|r| r.chars().count() == 30));
+    }
+
+    #[test]
+    fn empty_history_has_no_stats() {
+        let stats = ChatStats::compute(&[], names);
+        assert_eq!(stats, ChatStats::default());
+    }
+}
@@ -5,7 +5,7 @@
 //! - Message list with scrolling and selection
 //! - Input area for composing messages
 //! - Reply and edit modes
-//! - Keyboard navigation
+//! - Keyboard navigation, including jumps along reply chains
 //!
 //! # Architecture
 //!
@@ -28,6 +28,11 @@
 //! //     .focused(true);
 //! ```
 
+use std::collections::HashMap;
+use std::ops::Range;
+use std::time::{Duration, Instant};
+
+use chrono::{DateTime, Local, Utc};
 use ratatui::{
     buffer::Buffer,
     layout::{Constraint, Direction, Layout, Rect},
@@ -35,10 +40,11 @@ use ratatui::{
     widgets::{Block, Borders, Paragraph, Widget},
 };
 
-use crate::types::{Chat, Message};
+use crate::types::{Chat, ChatType, Message};
 use crate::ui::components::InputComponent;
 use crate::ui::keys::Action;
 use crate::ui::styles::Styles;
+use crate::utils::{format_file_size, Keywords};
 
 use super::message::MessageWidget;
 
@@ -76,10 +82,77 @@ pub struct ConversationModel {
     pub editing: Option<i64>,
     /// Current input mode
     pub input_mode: InputMode,
-    /// Path of a file staged to send with the next message, if any.
-    pub pending_attachment: Option<std::path::PathBuf>,
+    /// Files staged to send with the next message, in the order added
+    attachments: Vec<StagedFile>,
+    /// Whether staged images go as compressed photos rather than files
+    attachment_as_photo: bool,
+    /// Whether images are staged to go as photos (`compress_images`)
+    compress_images: bool,
+    /// A file path found in the composed text and offered as the attachment
+    path_offer: Option<PathOffer>,
+    /// Sent-text history of chats other than the current one, keyed by chat ID.
+    /// The current chat's history lives in `input`.
+    histories: HashMap<i64, Vec<String>>,
     /// Visible height of the message area (in lines)
     visible_height: usize,
+    /// Message IDs selected before each jump, most recent last
+    jump_back: Vec<i64>,
+    /// Message IDs left by jumping back, most recent last
+    jump_forward: Vec<i64>,
+    /// Message briefly highlighted after a jump, and when it started
+    flash: Option<(i64, Instant)>,
+    /// Last message read on another device, keyed by chat ID
+    read_elsewhere: HashMap<i64, i64>,
+}
+
+/// Maximum number of remembered jump positions.
+const MAX_JUMPS: usize = 100;
+
+/// Rows the strip describing a staged attachment takes above the composer.
+const ATTACHMENT_STRIP_HEIGHT: u16 = 2;
+
+/// How long a message jumped to stays highlighted.
+const FLASH_DURATION: Duration = Duration::from_millis(1500);
+
+/// What a row of the minimap gutter marks, most telling first.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord)]
+pub enum MinimapMark {
+    /// A message mentioning the user
+    Mention,
+    /// A message with an attachment
+    Media,
+    /// The first message of a day
+    DayStart,
+    /// Nothing in particular
+    Plain,
+}
+
+/// One row of the minimap gutter, covering a slice of the loaded history.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct MinimapRow {
+    /// The most telling mark among the row's messages
+    pub mark: MinimapMark,
+    /// Whether any of the row's messages is unread
+    pub unread: bool,
+    /// Whether any of the row's messages is on screen
+    pub in_view: bool,
+}
+
+/// A file staged to send with the next message.
+#[derive(Debug, Clone)]
+struct StagedFile {
+    path: std::path::PathBuf,
+    /// Size and kind, read when the file was staged
+    info: Option<crate::utils::FileInfo>,
+}
+
+/// A file path spotted in composed text when sending.
+#[derive(Debug, Clone, PartialEq, Eq)]
+enum PathOffer {
+    /// The file is staged; `Esc` puts back this text as typed
+    Offered(String),
+    /// The offer was turned down; this text is sent as typed
+    Declined(String),
 }
 
 impl Default for ConversationModel {
@@ -113,8 +186,16 @@ impl ConversationModel {
             reply_to: None,
             editing: None,
             input_mode: InputMode::Normal,
-            pending_attachment: None,
+            attachments: Vec::new(),
+            attachment_as_photo: true,
+            compress_images: true,
+            path_offer: None,
+            histories: HashMap::new(),
             visible_height: 20,
+            jump_back: Vec::new(),
+            jump_forward: Vec::new(),
+            flash: None,
+            read_elsewhere: HashMap::new(),
         }
     }
 
@@ -122,22 +203,42 @@ impl ConversationModel {
     ///
     /// This clears the message list and resets selection state.
     pub fn set_chat(&mut self, chat: Chat) {
+        self.stash_history();
+        let history = self.histories.remove(&chat.id).unwrap_or_default();
+        self.input.set_history(history);
         self.chat = Some(chat);
         self.messages.clear();
         self.selected_index = 0;
         self.scroll_offset = 0;
+        self.jump_back.clear();
+        self.jump_forward.clear();
+        self.flash = None;
         self.clear_action_state();
     }
 
     /// Clears the current chat.
     pub fn clear_chat(&mut self) {
+        self.stash_history();
         self.chat = None;
         self.messages.clear();
         self.selected_index = 0;
         self.scroll_offset = 0;
+        self.jump_back.clear();
+        self.jump_forward.clear();
+        self.flash = None;
         self.clear_action_state();
     }
 
+    /// Moves the input's history into `histories` under the current chat.
+    fn stash_history(&mut self) {
+        let history = self.input.take_history();
+        if let Some(chat) = self.chat.as_ref() {
+            if !history.is_empty() {
+                self.histories.insert(chat.id, history);
+            }
+        }
+    }
+
     /// Sets the messages for the current chat.
     ///
     /// Messages from Telegram come in reverse chronological order (newest first),
@@ -155,6 +256,26 @@ impl ConversationModel {
         }
     }
 
+    /// Adds older history above the loaded messages, newest first as
+    /// Telegram sends it, keeping the selection on the same message.
+    ///
+    /// Messages already loaded are skipped. Returns how many were added.
+    pub fn prepend_messages(&mut self, older: Vec<Message>) -> usize {
+        let mut older: Vec<Message> = older
+            .into_iter()
+            .filter(|m| self.index_of(m.id).is_none())
+            .collect();
+        older.reverse();
+        let added = older.len();
+        older.append(&mut self.messages);
+        self.messages = older;
+        if self.messages.len() > added {
+            self.selected_index += added;
+            self.scroll_offset += added;
+        }
+        added
+    }
+
     /// Adds a new message to the chat.
     ///
     /// If the user was viewing the latest message, auto-scrolls to the new one.
@@ -177,6 +298,25 @@ impl ConversationModel {
         }
     }
 
+    /// Swaps the optimistic copy of a sent message, cached under
+    /// `local_id`, for the server's copy.
+    ///
+    /// The server's copy keeps the optimistic one's place. If it is already
+    /// shown, the optimistic copy is just dropped; if the optimistic copy
+    /// isn't shown, the server's is added like a new message.
+    pub fn replace_message(&mut self, local_id: i64, message: Message) {
+        let Some(idx) = self.messages.iter().position(|m| m.id == local_id) else {
+            self.add_message(message);
+            return;
+        };
+        if self.messages.iter().any(|m| m.id == message.id) {
+            self.delete_message(local_id);
+            self.update_message(message);
+        } else {
+            self.messages[idx] = message;
+        }
+    }
+
     /// Updates an existing message.
     ///
     /// Finds the message by ID and replaces it.
@@ -251,20 +391,11 @@ impl ConversationModel {
                 None
             },
             Action::Edit => {
-                // Extract needed values before mutation to avoid borrow issues
-                let edit_info = self.selected_message().and_then(|msg| {
-                    if msg.is_outgoing {
-                        Some((msg.id, msg.content.text.clone()))
-                    } else {
-                        None
-                    }
-                });
-                if let Some((id, text)) = edit_info {
-                    self.editing = Some(id);
-                    self.input_mode = InputMode::Edit;
-                    self.input.set_value(&text);
-                    self.input.set_focused(true);
-                    self.input.set_placeholder("Edit message...");
+                if self
+                    .selected_message()
+                    .is_some_and(|msg| msg.can_edit(Utc::now()))
+                {
+                    self.start_editing(self.selected_index);
                 }
                 None
             },
@@ -287,7 +418,12 @@ impl ConversationModel {
         match action {
             Action::SendMessage => self.submit_input(),
             Action::CancelAction => {
-                if self.pending_attachment.take().is_some() {
+                // Staged files go one at a time, the last added first
+                if self.attachments.pop().is_some() {
+                    if let Some(PathOffer::Offered(text)) = self.path_offer.take() {
+                        self.path_offer = Some(PathOffer::Declined(text.trim().to_string()));
+                        self.input.set_value(text);
+                    }
                     return None;
                 }
                 self.input.set_focused(false);
@@ -298,26 +434,113 @@ impl ConversationModel {
                 self.input.insert_char('\n');
                 None
             },
+            // Up in an empty composer recalls my last message for editing
+            Action::Up if self.input.is_empty() && self.input_mode == InputMode::Normal => {
+                self.edit_adjacent(true);
+                None
+            },
+            Action::EditPrevious => {
+                self.edit_adjacent(true);
+                None
+            },
+            Action::EditNext => {
+                self.edit_adjacent(false);
+                None
+            },
             _ => None,
         }
     }
 
+    /// Enters edit mode for the message at `idx`.
+    ///
+    /// Loads the message text into the input and selects the message so the
+    /// list scrolls to it.
+    fn start_editing(&mut self, idx: usize) {
+        let Some(msg) = self.messages.get(idx) else {
+            return;
+        };
+        let (id, text) = (msg.id, msg.content.text.clone());
+        self.editing = Some(id);
+        self.reply_to = None;
+        self.input_mode = InputMode::Edit;
+        self.input.set_value(&text);
+        self.input.set_focused(true);
+        self.input.set_placeholder("Edit message...");
+        self.selected_index = idx;
+        self.ensure_selected_visible();
+    }
+
+    /// Moves edit mode to the previous (`older`) or next message that can
+    /// still be edited.
+    ///
+    /// With nothing being edited, starts from the most recent one. Stepping
+    /// past the newest one leaves edit mode.
+    fn edit_adjacent(&mut self, older: bool) {
+        let current = self
+            .editing
+            .and_then(|id| self.messages.iter().position(|m| m.id == id));
+        let now = Utc::now();
+        let editable = |m: &Message| m.can_edit(now);
+
+        let target = match (current, older) {
+            (None, true) => self.messages.iter().rposition(editable),
+            (None, false) => None,
+            (Some(idx), true) => self.messages[..idx].iter().rposition(editable),
+            (Some(idx), false) => self.messages[idx + 1..]
+                .iter()
+                .position(editable)
+                .map(|offset| idx + 1 + offset),
+        };
+
+        match target {
+            Some(idx) => self.start_editing(idx),
+            None if current.is_some() && !older => {
+                self.input.clear();
+                self.clear_action_state();
+            },
+            None => {},
+        }
+    }
+
     /// Submits the current input.
     fn submit_input(&mut self) -> Option<ConversationAction> {
         let text = self.input.value().trim().to_string();
 
         // With an attachment, an empty caption is allowed. Without one, keep the
         // existing guard that drops empty submissions.
-        if text.is_empty() && self.pending_attachment.is_none() {
+        if text.is_empty() && self.attachments.is_empty() {
             return None;
         }
 
+        // A pasted or dropped file path is offered as the attachment, with
+        // the rest of the text as its caption, unless already turned down
+        let declined = matches!(&self.path_offer, Some(PathOffer::Declined(t)) if *t == text);
+        if self.attachments.is_empty() && self.editing.is_none() && !declined {
+            if let Some((path, caption)) = crate::utils::find_file_path(&text) {
+                self.path_offer = Some(PathOffer::Offered(self.input.value().to_string()));
+                self.set_pending_attachment(path);
+                self.input.set_value(caption);
+                return None;
+            }
+        }
+        self.path_offer = None;
+
         // attachment takes precedence over an in-progress edit
-        let action = if let Some(path) = self.pending_attachment.take() {
-            ConversationAction::SendMessageWithAttachment(text, path, self.reply_to)
+        let mut paths: Vec<_> = self.attachments.drain(..).map(|f| f.path).collect();
+        let action = if paths.len() > 1 {
+            ConversationAction::SendAttachments(
+                text,
+                paths,
+                self.attachment_as_photo,
+                self.reply_to,
+            )
+        } else if let Some(path) = paths.pop() {
+            let as_photo = self.attachment_as_photo && crate::utils::is_image(&path);
+            ConversationAction::SendMessageWithAttachment(text, path, as_photo, self.reply_to)
         } else if let Some(edit_id) = self.editing {
             ConversationAction::EditMessage(edit_id, text)
         } else {
+            self.input.push_history(text.clone());
             ConversationAction::SendMessage(text, self.reply_to)
         };
 
@@ -326,15 +549,62 @@ impl ConversationModel {
         Some(action)
     }
 
-    /// Stages a file to be sent with the next message.
+    /// Stages a file to be sent with the next message, in place of any
+    /// staged before.
     pub fn set_pending_attachment(&mut self, path: std::path::PathBuf) {
-        self.pending_attachment = Some(path);
+        self.attachments.clear();
+        self.add_pending_attachment(path);
+    }
+
+    /// Stages another file to be sent with the next message. A file already
+    /// staged isn't added twice.
+    pub fn add_pending_attachment(&mut self, path: std::path::PathBuf) {
+        if self.attachments.iter().any(|f| f.path == path) {
+            return;
+        }
+        if self.attachments.is_empty() {
+            self.attachment_as_photo = self.compress_images;
+        }
+        let info = crate::utils::file_info(&path);
+        self.attachments.push(StagedFile { path, info });
+    }
+
+    /// Sets whether staged images go as photos by default
+    /// (`compress_images`).
+    pub fn set_compress_images(&mut self, compress: bool) {
+        self.compress_images = compress;
+    }
+
+    /// Switches the staged images between compressed photos and files in
+    /// their original quality. Returns `false` if no image is staged.
+    pub fn toggle_attachment_as_photo(&mut self) -> bool {
+        if !self
+            .attachments
+            .iter()
+            .any(|f| crate::utils::is_image(&f.path))
+        {
+            return false;
+        }
+        self.attachment_as_photo = !self.attachment_as_photo;
+        true
+    }
+
+    /// Returns `true` if staged images will go as compressed photos.
+    #[must_use]
+    pub const fn attachment_as_photo(&self) -> bool {
+        self.attachment_as_photo
+    }
+
+    /// Returns the first staged attachment's path, if any.
+    #[must_use]
+    pub fn pending_attachment(&self) -> Option<&std::path::PathBuf> {
+        self.attachments.first().map(|f| &f.path)
     }
 
-    /// Returns the staged attachment path, if any.
+    /// Returns how many files are staged.
     #[must_use]
-    pub const fn pending_attachment(&self) -> Option<&std::path::PathBuf> {
-        self.pending_attachment.as_ref()
+    pub fn attachment_count(&self) -> usize {
+        self.attachments.len()
     }
 
     /// Clears the reply/edit state.
@@ -351,6 +621,300 @@ impl ConversationModel {
         self.messages.get(self.selected_index)
     }
 
+    /// Selects the newest loaded message whose text contains `query`.
+    ///
+    /// Matching is case-insensitive. Returns the number of matching messages;
+    /// the selection is left alone if there are none.
+    pub fn find_messages(&mut self, query: &str) -> usize {
+        let query = query.to_lowercase();
+        let matches: Vec<usize> = self
+            .messages
+            .iter()
+            .enumerate()
+            .filter(|(_, m)| m.content.text.to_lowercase().contains(&query))
+            .map(|(i, _)| i)
+            .collect();
+        if let Some(&newest) = matches.last() {
+            self.jump_to(newest);
+        }
+        matches.len()
+    }
+
+    /// Selects the oldest loaded message sent at or after `time`.
+    ///
+    /// Falls back to the newest message if every loaded message is older.
+    /// Returns `false` if there are no messages.
+    pub fn select_first_since(&mut self, time: DateTime<Utc>) -> bool {
+        if self.messages.is_empty() {
+            return false;
+        }
+        self.selected_index = self
+            .messages
+            .iter()
+            .position(|m| m.date >= time)
+            .unwrap_or(self.messages.len() - 1);
+        self.ensure_selected_visible();
+        true
+    }
+
+    /// Selects the first unread incoming message.
+    ///
+    /// Unread messages are those newer than the chat's last read message as
+    /// of when it was opened; if that is unknown, the chat's unread count is
+    /// used instead. Returns `false` if nothing loaded is unread.
+    pub fn jump_to_first_unread(&mut self) -> bool {
+        self.first_unread_index().map_or(false, |index| {
+            self.jump_to(index);
+            true
+        })
+    }
+
+    /// Returns the index of the first unread incoming message; see
+    /// [`jump_to_first_unread`](Self::jump_to_first_unread).
+    fn first_unread_index(&self) -> Option<usize> {
+        let chat = self.chat.as_ref()?;
+        let incoming: Vec<usize> = self
+            .messages
+            .iter()
+            .enumerate()
+            .filter(|(_, m)| !m.is_outgoing)
+            .map(|(i, _)| i)
+            .collect();
+
+        if chat.last_read_inbox_id > 0 {
+            let last_read = chat.last_read_inbox_id;
+            incoming
+                .into_iter()
+                .find(|&i| self.messages[i].id > last_read)
+        } else {
+            let unread = usize::try_from(chat.unread_count).unwrap_or(0);
+            // More unread than loaded: the oldest loaded is the best we have
+            (unread > 0)
+                .then(|| incoming.len().saturating_sub(unread))
+                .and_then(|i| incoming.get(i).copied())
+        }
+    }
+
+    /// Selects the first message of the next day (`later`) or the previous
+    /// one, in local time. Returns `false` if there is no such day loaded.
+    pub fn jump_to_day(&mut self, later: bool) -> bool {
+        let starts: Vec<usize> = (0..self.messages.len())
+            .filter(|&i| i == 0 || self.starts_day(i))
+            .collect();
+        let target = if later {
+            starts.into_iter().find(|&i| i > self.selected_index)
+        } else {
+            let current = starts.iter().rposition(|&i| i <= self.selected_index);
+            current
+                .and_then(|c| c.checked_sub(1))
+                .map(|previous| starts[previous])
+        };
+        target.map_or(false, |index| {
+            self.jump_to(index);
+            true
+        })
+    }
+
+    /// Returns `true` if the message at `index` is the first of its day.
+    fn starts_day(&self, index: usize) -> bool {
+        let day = |m: &Message| m.date.with_timezone(&Local).date_naive();
+        index > 0 && day(&self.messages[index]) != day(&self.messages[index - 1])
+    }
+
+    /// Lays the loaded history out over `rows` rows of the minimap gutter,
+    /// oldest at the top. `visible` holds the indices of the messages on
+    /// screen.
+    #[must_use]
+    pub fn minimap(&self, rows: usize, visible: Range<usize>) -> Vec<MinimapRow> {
+        let count = self.messages.len();
+        if count == 0 {
+            return Vec::new();
+        }
+        let first_unread = self.first_unread_index().unwrap_or(count);
+        (0..rows)
+            .map(|row| {
+                let start = (row * count / rows).min(count - 1);
+                let end = ((row + 1) * count / rows).clamp(start + 1, count);
+                let slice = start..end;
+                let mark = slice
+                    .clone()
+                    .map(|i| {
+                        let m = &self.messages[i];
+                        if m.mentions_me {
+                            MinimapMark::Mention
+                        } else if m.content.content_type.is_downloadable() {
+                            MinimapMark::Media
+                        } else if self.starts_day(i) {
+                            MinimapMark::DayStart
+                        } else {
+                            MinimapMark::Plain
+                        }
+                    })
+                    .min()
+                    .unwrap_or(MinimapMark::Plain);
+                MinimapRow {
+                    mark,
+                    unread: end > first_unread,
+                    in_view: slice.start < visible.end && visible.start < slice.end,
+                }
+            })
+            .collect()
+    }
+
+    /// Selects the next message after the selection that mentions the
+    /// current user, wrapping around. Returns `false` if none is loaded.
+    pub fn jump_to_next_mention(&mut self) -> bool {
+        let len = self.messages.len();
+        let next = (1..=len)
+            .map(|offset| (self.selected_index + offset) % len)
+            .find(|&i| self.messages[i].mentions_me);
+        next.map_or(false, |index| {
+            self.jump_to(index);
+            true
+        })
+    }
+
+    /// Selects the message with `message_id`, if it is loaded.
+    ///
+    /// Like the other jumps, this can be undone with
+    /// [`jump_back`](Self::jump_back).
+    pub fn jump_to_message(&mut self, message_id: i64) -> bool {
+        self.index_of(message_id).map_or(false, |index| {
+            self.jump_to(index);
+            true
+        })
+    }
+
+    /// Returns the ID of the message the selected one replies to, if any.
+    #[must_use]
+    pub fn replied_message_id(&self) -> Option<i64> {
+        self.selected_message()
+            .map(|m| m.reply_to_message_id)
+            .filter(|&id| id > 0)
+    }
+
+    /// Returns the ID of the oldest loaded message.
+    #[must_use]
+    pub fn oldest_message_id(&self) -> Option<i64> {
+        self.messages.first().map(|m| m.id)
+    }
+
+    /// Highlights a message for a moment from `now`, to show where a jump
+    /// landed.
+    pub fn flash_message(&mut self, message_id: i64, now: Instant) {
+        self.flash = Some((message_id, now));
+    }
+
+    /// Returns `true` if `message_id` is still highlighted at `now`.
+    #[must_use]
+    pub fn is_flashing(&self, message_id: i64, now: Instant) -> bool {
+        self.flash.is_some_and(|(id, since)| {
+            id == message_id && now.saturating_duration_since(since) < FLASH_DURATION
+        })
+    }
+
+    /// Records that another device read `chat_id` up to `message_id`.
+    pub fn set_read_elsewhere(&mut self, chat_id: i64, message_id: i64) {
+        let last = self.read_elsewhere.entry(chat_id).or_insert(message_id);
+        *last = (*last).max(message_id);
+    }
+
+    /// Returns the index of the last message read on another device, if
+    /// newer messages follow it; the marker goes below this message.
+    #[must_use]
+    pub fn read_elsewhere_index(&self) -> Option<usize> {
+        let chat_id = self.chat.as_ref()?.id;
+        let read = *self.read_elsewhere.get(&chat_id)?;
+        let index = self.messages.iter().rposition(|m| m.id <= read)?;
+        (index + 1 < self.messages.len()).then_some(index)
+    }
+
+    /// Returns `true` if the message at `index` follows on from the one
+    /// before it, from the same sender within `window` on the same day, so
+    /// it can go without a header of its own. Group events never group, and
+    /// edited messages and those with an effect keep the header that says
+    /// so.
+    #[must_use]
+    pub fn continues_previous(&self, index: usize, window: Duration) -> bool {
+        let (Some(prev), Some(msg)) = (
+            index.checked_sub(1).and_then(|i| self.messages.get(i)),
+            self.messages.get(index),
+        ) else {
+            return false;
+        };
+        let day = |m: &Message| m.date.with_timezone(&Local).date_naive();
+        !window.is_zero()
+            && msg.sender_id == prev.sender_id
+            && msg.is_outgoing == prev.is_outgoing
+            && msg.content.service.is_none()
+            && prev.content.service.is_none()
+            && !msg.is_edited
+            && msg.effect.is_none()
+            && day(msg) == day(prev)
+            && (msg.date - prev.date)
+                .to_std()
+                .is_ok_and(|gap| gap <= window)
+    }
+
+    /// Returns to the message selected before the last jump.
+    ///
+    /// Positions whose message is no longer loaded are skipped. Returns
+    /// `false` if there is nowhere to go back to.
+    pub fn jump_back(&mut self) -> bool {
+        while let Some(id) = self.jump_back.pop() {
+            if let Some(index) = self.index_of(id) {
+                if let Some(current) = self.selected_message().map(|m| m.id) {
+                    self.jump_forward.push(current);
+                }
+                self.select_index(index);
+                return true;
+            }
+        }
+        false
+    }
+
+    /// Re-does a jump undone with [`jump_back`](Self::jump_back).
+    ///
+    /// Returns `false` if there is nowhere to go forward to.
+    pub fn jump_forward(&mut self) -> bool {
+        while let Some(id) = self.jump_forward.pop() {
+            if let Some(index) = self.index_of(id) {
+                if let Some(current) = self.selected_message().map(|m| m.id) {
+                    self.jump_back.push(current);
+                }
+                self.select_index(index);
+                return true;
+            }
+        }
+        false
+    }
+
+    /// Selects `index`, remembering the current selection for
+    /// [`jump_back`](Self::jump_back).
+    fn jump_to(&mut self, index: usize) {
+        if index == self.selected_index {
+            self.ensure_selected_visible();
+            return;
+        }
+        if let Some(current) = self.selected_message().map(|m| m.id) {
+            if self.jump_back.len() == MAX_JUMPS {
+                self.jump_back.remove(0);
+            }
+            self.jump_back.push(current);
+        }
+        self.jump_forward.clear();
+        self.select_index(index);
+    }
+
+    fn select_index(&mut self, index: usize) {
+        self.selected_index = index;
+        self.ensure_selected_visible();
+    }
+
+    fn index_of(&self, message_id: i64) -> Option<usize> {
+        self.messages.iter().position(|m| m.id == message_id)
+    }
+
     /// Returns true if there are no messages.
     #[must_use]
     pub fn is_empty(&self) -> bool {
@@ -436,8 +1000,12 @@ impl ConversationModel {
 pub enum ConversationAction {
     /// Send a new message (text, optional `reply_to` message ID)
     SendMessage(String, Option<i64>),
-    /// Send a message with a file attachment (caption, file path, optional `reply_to`)
-    SendMessageWithAttachment(String, std::path::PathBuf, Option<i64>),
+    /// Send a message with a file attachment (caption, file path, whether an
+    /// image goes as a photo, optional `reply_to`)
+    SendMessageWithAttachment(String, std::path::PathBuf, bool, Option<i64>),
+    /// Send several files with one caption (caption, file paths, whether
+    /// images go as photos, optional `reply_to`)
+    SendAttachments(String, Vec<std::path::PathBuf>, bool, Option<i64>),
     /// Edit an existing message (`message_id`, `new_text`)
     EditMessage(i64, String),
     /// Delete a message
@@ -459,6 +1027,22 @@ where
     is_focused: bool,
     /// Function to get sender name from user ID
     get_sender_name: F,
+    /// Local alias for the current chat, shown before the real title
+    alias: Option<&'a str>,
+    /// Keys offered in place of the composer in read-only channels
+    read_only_hint: &'a str,
+    /// Name of the identity messages are posted as, if not the user
+    send_as: Option<&'a str>,
+    /// Whether emoji-only messages are enlarged
+    big_emoji: bool,
+    /// Words that make incoming messages stand out
+    highlights: Option<&'a Keywords>,
+    /// Whether a minimap gutter runs down the right edge
+    minimap: bool,
+    /// Widest a message is drawn, or 0 for the full width
+    max_message_width: u16,
+    /// How close together a sender's messages must be to share a header
+    group_within: Duration,
 }
 
 impl<'a, F> ConversationWidget<'a, F>
@@ -478,9 +1062,79 @@ where
             model,
             is_focused: false,
             get_sender_name,
+            alias: None,
+            read_only_hint: "",
+            send_as: None,
+            big_emoji: false,
+            highlights: None,
+            minimap: false,
+            max_message_width: 0,
+            group_within: Duration::ZERO,
         }
     }
 
+    /// Sets the local alias for the current chat.
+    #[must_use]
+    pub const fn alias(mut self, alias: Option<&'a str>) -> Self {
+        self.alias = alias;
+        self
+    }
+
+    /// Sets the keys offered in place of the composer in channels the user
+    /// can't post in.
+    #[must_use]
+    pub const fn read_only_hint(mut self, hint: &'a str) -> Self {
+        self.read_only_hint = hint;
+        self
+    }
+
+    /// Sets the name of the channel or group messages are posted as,
+    /// shown in the composer's title.
+    #[must_use]
+    pub const fn send_as(mut self, name: Option<&'a str>) -> Self {
+        self.send_as = name;
+        self
+    }
+
+    /// Sets whether messages of only a few emoji are shown enlarged.
+    #[must_use]
+    pub const fn big_emoji(mut self, big: bool) -> Self {
+        self.big_emoji = big;
+        self
+    }
+
+    /// Sets the words that make incoming messages stand out.
+    #[must_use]
+    pub const fn highlights(mut self, words: &'a Keywords) -> Self {
+        self.highlights = Some(words);
+        self
+    }
+
+    /// Sets whether a minimap of the loaded history runs down the right
+    /// edge of the messages.
+    #[must_use]
+    pub const fn minimap(mut self, minimap: bool) -> Self {
+        self.minimap = minimap;
+        self
+    }
+
+    /// Sets the widest a message is drawn, in columns; 0 uses the full
+    /// width of the pane.
+    #[must_use]
+    pub const fn max_message_width(mut self, width: u16) -> Self {
+        self.max_message_width = width;
+        self
+    }
+
+    /// Sets how close together one sender's messages must be for the
+    /// later ones to go without a header and sit closer; zero shows every
+    /// header.
+    #[must_use]
+    pub const fn group_within(mut self, window: Duration) -> Self {
+        self.group_within = window;
+        self
+    }
+
     /// Sets whether this pane is focused.
     #[must_use]
     pub const fn focused(mut self, focused: bool) -> Self {
@@ -494,13 +1148,31 @@ where
     F: Fn(i64) -> String,
 {
     fn render(self, area: Rect, buf: &mut Buffer) {
-        // Split into messages area and input area. A staged attachment adds a
-        // banner row above the input, so the input region needs one extra line
-        // to keep the bordered text box from collapsing to zero interior rows.
-        let input_height = if self.model.pending_attachment.is_some() {
-            4
+        // Split into messages area and input area. The composer grows with
+        // the draft's line count, up to half the pane. A staged attachment adds
+        // a two-row strip above the input, so the input region needs as many
+        // extra lines to keep the bordered text box from collapsing to zero
+        // interior rows.
+        let banner = if self.model.attachments.is_empty() {
+            0
         } else {
+            ATTACHMENT_STRIP_HEIGHT
+        };
+        let max_input_height = (area.height / 2).max(3 + banner);
+        #[allow(clippy::cast_possible_truncation)]
+        let draft_lines = self.model.input.line_count().min(usize::from(u16::MAX)) as u16;
+        // Channels the user can't post in get a bar instead of the composer,
+        // which only comes back while it's focused for /commands
+        let subscriber_bar = !self.model.input.is_focused()
+            && self
+                .model
+                .chat
+                .as_ref()
+                .is_some_and(|c| c.chat_type == ChatType::Channel && c.is_read_only);
+        let input_height = if subscriber_bar {
             3
+        } else {
+            (draft_lines + 2 + banner).min(max_input_height)
         };
         let chunks = Layout::default()
             .direction(Direction::Vertical)
@@ -522,14 +1194,32 @@ where
 
         let title = self.model.chat.as_ref().map_or_else(
             || " No chat selected ".to_string(),
-            |chat| format!(" {} ", chat.title),
+            |chat| match self.alias {
+                Some(alias) => format!(" {alias} ({}) ", chat.title),
+                None => format!(" {} ", chat.title),
+            },
         );
 
-        let block = Block::default()
+        let mut block = Block::default()
             .title(Span::styled(title, Styles::text_bright()))
             .borders(Borders::ALL)
             .border_style(border_style);
 
+        // Say why `e` does nothing on my own messages past the edit window
+        let selected = self.model.selected_message();
+        if self.is_focused
+            && !self.model.input.is_focused()
+            && selected.is_some_and(|m| m.is_outgoing && !m.can_edit(Utc::now()))
+        {
+            block = block.title_bottom(Span::styled(
+                format!(
+                    " Editing closed: sent over {} hours ago ",
+                    Message::EDIT_WINDOW_HOURS
+                ),
+                Styles::text_muted(),
+            ));
+        }
+
         let inner_area = block.inner(messages_area);
         block.render(messages_area, buf);
 
@@ -541,7 +1231,11 @@ where
         }
 
         // Render input area
-        self.render_input(input_area, buf);
+        if subscriber_bar {
+            self.render_subscriber_bar(input_area, buf);
+        } else {
+            self.render_input(input_area, buf);
+        }
     }
 }
 
@@ -556,21 +1250,45 @@ where
     /// 1. Start from the selected message and work backwards to find which messages fit
     /// 2. Render those messages from top to bottom, anchored to the bottom of the area
     fn render_messages(&self, area: Rect, buf: &mut Buffer) {
-        let message_spacing: u16 = 1; // Blank line between messages
-
         if self.model.messages.is_empty() {
             return;
         }
 
+        // Follow-ups from the same sender drop their header and the blank
+        // line above them, unless the read-elsewhere marker needs the gap
+        let read_elsewhere = self.model.read_elsewhere_index();
+        let continued: Vec<bool> = (0..self.model.messages.len())
+            .map(|idx| {
+                read_elsewhere != idx.checked_sub(1)
+                    && self.model.continues_previous(idx, self.group_within)
+            })
+            .collect();
+        // Blank line above a message
+        let spacing = |idx: usize| continued.get(idx).map_or(1, |&c| u16::from(!c));
+
+        // The minimap takes the last column, on panes wide enough to spare it
+        let gutter = (self.minimap && area.width > 20)
+            .then(|| Rect::new(area.x + area.width - 1, area.y, 1, area.height));
+        let area = if gutter.is_some() {
+            Rect::new(area.x, area.y, area.width - 2, area.height)
+        } else {
+            area
+        };
+
         // Pre-calculate heights for ALL messages
         let all_heights: Vec<u16> = self
             .model
             .messages
             .iter()
-            .map(|msg| {
+            .enumerate()
+            .map(|(idx, msg)| {
                 let sender_name = (self.get_sender_name)(msg.sender_id);
                 MessageWidget::new(msg, sender_name)
+                    .continued(continued[idx])
                     .width(area.width)
+                    .max_width(self.max_message_width)
+                    .name_of(&self.get_sender_name)
+                    .big_emoji(self.big_emoji)
                     .height()
             })
             .collect();
@@ -590,7 +1308,7 @@ where
             let needed = if messages_to_render.is_empty() {
                 height
             } else {
-                height + message_spacing
+                height + spacing(idx)
             };
 
             if accumulated_height + needed <= area.height {
@@ -605,7 +1323,8 @@ where
         if anchor_index > 0 {
             for idx in (0..anchor_index).rev() {
                 let height = all_heights[idx];
-                let needed = height + message_spacing; // Always need spacing since we have messages
+                // Always need spacing since we have messages
+                let needed = height + spacing(idx + 1);
 
                 if accumulated_height + needed <= area.height {
                     accumulated_height += needed;
@@ -619,6 +1338,11 @@ where
         if messages_to_render.is_empty() {
             return;
         }
+        if let Some(gutter) = gutter {
+            let first = messages_to_render[0].0;
+            let last = messages_to_render[messages_to_render.len() - 1].0;
+            self.render_minimap(gutter, buf, first..last + 1);
+        }
 
         // Calculate starting Y position to anchor messages to the bottom
         let total_height = accumulated_height;
@@ -631,6 +1355,7 @@ where
         // Render messages
         let mut y = start_y;
         let max_y = area.y + area.height;
+        let now = Instant::now();
 
         for (idx, msg_height) in messages_to_render {
             if y >= max_y {
@@ -640,39 +1365,169 @@ where
             let msg = &self.model.messages[idx];
             let sender_name = (self.get_sender_name)(msg.sender_id);
             let is_selected = idx == self.model.selected_index;
+            let is_highlighted = !msg.is_outgoing
+                && self
+                    .highlights
+                    .and_then(|words| words.find(msg.content.text_or_caption()))
+                    .is_some();
 
             let msg_widget = MessageWidget::new(msg, sender_name)
+                .continued(continued[idx])
                 .selected(is_selected)
-                .width(area.width);
+                .flashing(self.model.is_flashing(msg.id, now))
+                .highlighted(is_highlighted)
+                .width(area.width)
+                .max_width(self.max_message_width)
+                .name_of(&self.get_sender_name)
+                .big_emoji(self.big_emoji);
 
             let render_height = msg_height.min(max_y - y);
             let msg_area = Rect::new(area.x, y, area.width, render_height);
 
             msg_widget.render(msg_area, buf);
-            y += msg_height + message_spacing;
+            // The gap below the last message read on another device
+            // carries a marker
+            if read_elsewhere == Some(idx) && y + msg_height < max_y {
+                render_read_marker(Rect::new(area.x, y + msg_height, area.width, 1), buf);
+            }
+            y += msg_height + spacing(idx + 1);
+        }
+    }
+
+    /// Renders the minimap gutter: a column standing for the loaded
+    /// history, with the part on screen bright, the unread part in the
+    /// accent color, and marks for mentions (`@`), attachments (`▪`) and
+    /// the start of each day (`─`).
+    fn render_minimap(&self, area: Rect, buf: &mut Buffer, visible: Range<usize>) {
+        let rows = self.model.minimap(usize::from(area.height), visible);
+        for (y, row) in (area.y..).zip(rows) {
+            let symbol = match row.mark {
+                MinimapMark::Mention => "@",
+                MinimapMark::Media => "\u{25aa}",
+                MinimapMark::DayStart => "\u{2500}",
+                MinimapMark::Plain => "\u{2502}",
+            };
+            let style = if row.mark == MinimapMark::Mention {
+                Styles::warning()
+            } else if row.in_view {
+                Styles::text_bright()
+            } else if row.unread {
+                Styles::text_accent()
+            } else {
+                Styles::text_muted()
+            };
+            buf[(area.x, y)].set_symbol(symbol).set_style(style);
+        }
+    }
+
+    /// Renders the bar shown instead of the composer in channels the user
+    /// can't post in.
+    fn render_subscriber_bar(&self, area: Rect, buf: &mut Buffer) {
+        let block = Block::default()
+            .title(Span::styled(" Channel ", Styles::text_muted()))
+            .borders(Borders::ALL)
+            .border_style(Styles::border());
+        let line = Line::from(vec![
+            Span::styled("You can't send messages here", Styles::text()),
+            Span::styled(format!("  {}", self.read_only_hint), Styles::text_muted()),
+        ]);
+        Paragraph::new(line).block(block).render(area, buf);
+    }
+
+    /// Renders the strip above the composer describing the staged
+    /// attachments: a file's name, size, kind and an image's dimensions, or
+    /// several files' names and total size, then how they will go and the
+    /// keys to change that.
+    fn render_attachment_strip(&self, area: Rect, buf: &mut Buffer) {
+        let files = &self.model.attachments;
+        let name = |path: &std::path::Path| {
+            path.file_name().map_or_else(
+                || path.display().to_string(),
+                |n| n.to_string_lossy().into_owned(),
+            )
+        };
+        let size = |bytes: u64| format_file_size(i64::try_from(bytes).unwrap_or(i64::MAX));
+
+        let mut details = Vec::new();
+        let title = if let [file] = files.as_slice() {
+            if let Some(info) = file.info.as_ref() {
+                details.push(size(info.size));
+                details.push(info.kind.clone());
+                if let Some((width, height)) = info.dimensions {
+                    details.push(format!("{width}\u{d7}{height}"));
+                }
+            }
+            name(&file.path)
+        } else {
+            details.push(size(
+                files
+                    .iter()
+                    .filter_map(|f| f.info.as_ref())
+                    .map(|i| i.size)
+                    .sum(),
+            ));
+            let names: Vec<String> = files.iter().map(|f| name(&f.path)).collect();
+            format!("{} files: {}", files.len(), names.join(", "))
+        };
+        let mut about = vec![Span::styled(format!("📎 {title}"), Styles::text_accent())];
+        if !details.is_empty() {
+            about.push(Span::styled(
+                format!("  {}", details.join(" \u{2022} ")),
+                Styles::text_muted(),
+            ));
+        }
+
+        let images = files
+            .iter()
+            .filter(|f| crate::utils::is_image(&f.path))
+            .count();
+        let mut keys = Vec::new();
+        if images > 0 {
+            let photos = self.model.attachment_as_photo;
+            let how = match (files.len(), photos) {
+                (1, true) => "   as photo (compressed) \u{2022} Alt+C send as file".to_string(),
+                (1, false) => {
+                    "   as file (original quality) \u{2022} Alt+C send as photo".to_string()
+                },
+                (n, true) if images == n => {
+                    format!("   as an album of {n} photos \u{2022} Alt+C send as files")
+                },
+                (_, true) => {
+                    "   one by one, images as photos \u{2022} Alt+C send as files".to_string()
+                },
+                (_, false) => {
+                    "   one by one, as files \u{2022} Alt+C send images as photos".to_string()
+                },
+            };
+            keys.push(Span::styled(how, Styles::text()));
         }
+        let hint = if matches!(self.model.path_offer, Some(PathOffer::Offered(_))) {
+            "   Enter to send as file \u{2022} Esc to keep as text"
+        } else if files.len() > 1 {
+            "   Ctrl+T to add more \u{2022} Esc to remove the last"
+        } else {
+            "   Ctrl+T to add another \u{2022} Esc to remove"
+        };
+        keys.push(Span::styled(hint, Styles::text_muted()));
+
+        Paragraph::new(vec![Line::from(about), Line::from(keys)]).render(area, buf);
     }
 
     /// Renders the input area.
     fn render_input(&self, area: Rect, buf: &mut Buffer) {
-        // Reserve a banner line for a staged attachment.
-        let area = if let Some(path) = self.model.pending_attachment.as_ref() {
+        // Reserve the strip describing a staged attachment.
+        let area = if self.model.attachments.is_empty() {
+            area
+        } else {
             let rows = Layout::default()
                 .direction(Direction::Vertical)
-                .constraints([Constraint::Length(1), Constraint::Min(2)])
+                .constraints([
+                    Constraint::Length(ATTACHMENT_STRIP_HEIGHT),
+                    Constraint::Min(2),
+                ])
                 .split(area);
-            let name = path.file_name().map_or_else(
-                || path.display().to_string(),
-                |n| n.to_string_lossy().into_owned(),
-            );
-            let banner = Paragraph::new(Line::from(vec![
-                Span::styled(format!("📎 {name}"), Styles::text_accent()),
-                Span::styled("  Esc to remove", Styles::text_muted()),
-            ]));
-            banner.render(rows[0], buf);
+            self.render_attachment_strip(rows[0], buf);
             rows[1]
-        } else {
-            area
         };
 
         let input_border_style = if self.model.input.is_focused() {
@@ -681,29 +1536,40 @@ where
             Styles::border()
         };
 
+        let read_only = self.model.chat.as_ref().is_some_and(|c| c.is_read_only);
         let input_title = match self.model.input_mode {
             InputMode::Edit => " Edit message (Esc to cancel) ",
+            InputMode::Reply | InputMode::Normal if read_only => " Read-only: only /commands work ",
             InputMode::Reply => " Reply (Esc to cancel) ",
             InputMode::Normal => " Message ",
         };
+        let mut title = vec![Span::styled(input_title, Styles::text())];
+        if let Some(name) = self
+            .send_as
+            .filter(|_| self.model.input_mode != InputMode::Edit)
+        {
+            title.push(Span::styled(format!("as {name} "), Styles::text_accent()));
+        }
 
         let input_block = Block::default()
-            .title(Span::styled(input_title, Styles::text()))
+            .title(Line::from(title))
             .borders(Borders::ALL)
             .border_style(input_border_style);
 
         let input_inner = input_block.inner(area);
         input_block.render(area, buf);
 
-        // Render input text
-        let (paragraph, _cursor_pos) = self.model.input.render_paragraph();
-        paragraph.render(input_inner, buf);
+        // Render input text, scrolled so the cursor row stays visible once the
+        // draft is taller than the composer
+        let (paragraph, cursor_pos) = self.model.input.render_paragraph();
+        let (cursor_col, cursor_row) = cursor_pos.unwrap_or((0, 0));
+        let scroll = cursor_row.saturating_sub(input_inner.height.saturating_sub(1));
+        paragraph.scroll((scroll, 0)).render(input_inner, buf);
 
         // Show cursor if focused
-        if self.model.input.is_focused() {
-            #[allow(clippy::cast_possible_truncation)]
-            let cursor_x = input_inner.x + self.model.input.cursor() as u16;
-            let cursor_y = input_inner.y;
+        if self.model.input.is_focused() && input_inner.height > 0 {
+            let cursor_x = input_inner.x + cursor_col;
+            let cursor_y = input_inner.y + cursor_row - scroll;
             if cursor_x < input_inner.x + input_inner.width {
                 buf[(cursor_x, cursor_y)].set_style(Styles::input_cursor());
             }
@@ -711,6 +1577,25 @@ where
     }
 }
 
+/// Draws the dim "read on another device" line across `area`.
+fn render_read_marker(area: Rect, buf: &mut Buffer) {
+    const LABEL: &str = " read on another device ";
+    let width = usize::from(area.width);
+    let label_width = LABEL.chars().count();
+    let line = if width > label_width + 4 {
+        let left = (width - label_width) / 2;
+        let right = width - label_width - left;
+        format!(
+            "{}{LABEL}{}",
+            "\u{2500}".repeat(left),
+            "\u{2500}".repeat(right)
+        )
+    } else {
+        "\u{2500}".repeat(width)
+    };
+    Line::from(Span::styled(line, Styles::text_muted())).render(area, buf);
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -810,6 +1695,27 @@ mod tests {
         assert_eq!(model.messages[0].content.text, "Updated");
     }
 
+    #[test]
+    fn test_replace_message_swaps_the_optimistic_copy() {
+        let local = Message::FIRST_LOCAL_ID;
+        let mut model = ConversationModel::new();
+        model.set_messages(vec![
+            create_test_message(local, "hi", true),
+            create_test_message(1, "First", false),
+        ]);
+
+        model.replace_message(local, create_test_message(7, "hi", true));
+        let ids: Vec<i64> = model.messages.iter().map(|m| m.id).collect();
+        assert_eq!(ids, vec![1, 7]);
+
+        // The echo already showed up: the optimistic copy just goes
+        model.add_message(create_test_message(local + 1, "again", true));
+        model.add_message(create_test_message(8, "again", true));
+        model.replace_message(local + 1, create_test_message(8, "again", true));
+        let ids: Vec<i64> = model.messages.iter().map(|m| m.id).collect();
+        assert_eq!(ids, vec![1, 7, 8]);
+    }
+
     #[test]
     fn test_delete_message() {
         let mut model = ConversationModel::new();
@@ -921,6 +1827,86 @@ mod tests {
         assert_eq!(model.input.value(), "My message");
     }
 
+    #[test]
+    fn messages_past_the_edit_window_are_skipped() {
+        let mut model = ConversationModel::new();
+        let mut old = create_test_message(1, "mine, old", true);
+        old.date = Utc::now() - chrono::Duration::days(3);
+        model.set_messages(vec![create_test_message(2, "mine, new", true), old]);
+        model.selected_index = 0;
+
+        model.handle_action(Action::Edit);
+        assert!(model.editing.is_none());
+
+        model.input.set_focused(true);
+        model.handle_action(Action::Up);
+        assert_eq!(model.editing, Some(2));
+        model.handle_action(Action::EditPrevious);
+        assert_eq!(model.editing, Some(2), "the old message is skipped");
+    }
+
+    #[test]
+    fn up_in_empty_input_edits_last_outgoing() {
+        let mut model = ConversationModel::new();
+        model.set_messages(vec![
+            create_test_message(3, "theirs", false),
+            create_test_message(2, "mine newer", true),
+            create_test_message(1, "mine older", true),
+        ]);
+        model.input.set_focused(true);
+
+        model.handle_action(Action::Up);
+
+        assert_eq!(model.editing, Some(2));
+        assert_eq!(model.input_mode, InputMode::Edit);
+        assert_eq!(model.input.value(), "mine newer");
+        assert_eq!(model.selected_index, 1);
+    }
+
+    #[test]
+    fn up_with_text_does_not_start_edit() {
+        let mut model = ConversationModel::new();
+        model.set_messages(vec![create_test_message(1, "mine", true)]);
+        model.input.set_focused(true);
+        model.input.set_value("draft");
+
+        model.handle_action(Action::Up);
+
+        assert!(model.editing.is_none());
+        assert_eq!(model.input.value(), "draft");
+    }
+
+    #[test]
+    fn ctrl_up_down_cycles_own_messages() {
+        let mut model = ConversationModel::new();
+        model.set_messages(vec![
+            create_test_message(4, "mine 4", true),
+            create_test_message(3, "theirs", false),
+            create_test_message(2, "mine 2", true),
+            create_test_message(1, "mine 1", true),
+        ]);
+        model.input.set_focused(true);
+
+        model.handle_action(Action::EditPrevious);
+        assert_eq!(model.editing, Some(4));
+        model.handle_action(Action::EditPrevious);
+        assert_eq!(model.editing, Some(2));
+        model.handle_action(Action::EditPrevious);
+        assert_eq!(model.editing, Some(1));
+        // Already at the oldest: stays put
+        model.handle_action(Action::EditPrevious);
+        assert_eq!(model.editing, Some(1));
+
+        model.handle_action(Action::EditNext);
+        assert_eq!(model.editing, Some(2));
+        model.handle_action(Action::EditNext);
+        assert_eq!(model.editing, Some(4));
+        // Past the newest: leaves edit mode with an empty composer
+        model.handle_action(Action::EditNext);
+        assert!(model.editing.is_none());
+        assert!(model.input.is_empty());
+    }
+
     #[test]
     fn test_cancel_action_clears_state() {
         let mut model = ConversationModel::new();
@@ -1030,12 +2016,55 @@ mod tests {
             Some(ConversationAction::SendMessageWithAttachment(
                 "look".to_string(),
                 PathBuf::from("/tmp/cat.png"),
+                true,
                 None
             ))
         );
         assert!(model.pending_attachment().is_none(), "cleared after send");
     }
 
+    #[test]
+    fn staged_images_can_go_as_files_instead_of_photos() {
+        use std::path::PathBuf;
+        let mut model = ConversationModel::new();
+        model.input.set_focused(true);
+        model.set_pending_attachment(PathBuf::from("/tmp/cat.png"));
+        assert!(model.toggle_attachment_as_photo());
+        assert!(!model.attachment_as_photo());
+        assert_eq!(
+            model.handle_action(Action::SendMessage),
+            Some(ConversationAction::SendMessageWithAttachment(
+                String::new(),
+                PathBuf::from("/tmp/cat.png"),
+                false,
+                None
+            ))
+        );
+
+        // The setting picks the default for each new attachment
+        model.set_compress_images(false);
+        model.set_pending_attachment(PathBuf::from("/tmp/cat.png"));
+        assert!(!model.attachment_as_photo());
+        model.toggle_attachment_as_photo();
+        assert!(matches!(
+            model.handle_action(Action::SendMessage),
+            Some(ConversationAction::SendMessageWithAttachment(_, _, true, _))
+        ));
+
+        // Other files always go as files
+        model.set_pending_attachment(PathBuf::from("/tmp/notes.txt"));
+        assert!(!model.toggle_attachment_as_photo());
+        assert!(matches!(
+            model.handle_action(Action::SendMessage),
+            Some(ConversationAction::SendMessageWithAttachment(
+                _,
+                _,
+                false,
+                _
+            ))
+        ));
+    }
+
     #[test]
     fn submit_attachment_with_empty_caption_still_sends() {
         use std::path::PathBuf;
@@ -1045,7 +2074,7 @@ mod tests {
         let action = model.handle_action(Action::SendMessage);
         assert!(matches!(
             action,
-            Some(ConversationAction::SendMessageWithAttachment(_, _, _))
+            Some(ConversationAction::SendMessageWithAttachment(_, _, _, _))
         ));
     }
 
@@ -1061,6 +2090,347 @@ mod tests {
         );
     }
 
+    #[test]
+    fn send_history_is_kept_per_chat() {
+        let mut model = ConversationModel::new();
+        model.set_chat(create_test_chat(1, "One"));
+        model.input.set_focused(true);
+        model.input.set_value("hello one");
+        model.handle_action(Action::SendMessage);
+
+        model.set_chat(create_test_chat(2, "Two"));
+        assert!(model.input.history().is_empty());
+
+        model.set_chat(create_test_chat(1, "One"));
+        assert_eq!(model.input.history(), ["hello one".to_string()]);
+    }
+
+    #[test]
+    fn find_messages_selects_newest_match() {
+        let mut model = ConversationModel::new();
+        // set_messages expects newest first
+        model.set_messages(vec![
+            create_test_message(3, "lunch tomorrow?", false),
+            create_test_message(2, "unrelated", true),
+            create_test_message(1, "Lunch was great", false),
+        ]);
+        model.select_first();
+
+        assert_eq!(model.find_messages("LUNCH"), 2);
+        assert_eq!(model.selected_message().map(|m| m.id), Some(3));
+
+        assert_eq!(model.find_messages("nothing"), 0);
+        assert_eq!(model.selected_message().map(|m| m.id), Some(3));
+    }
+
+    #[test]
+    fn jumps_to_first_unread_and_back() {
+        let mut model = ConversationModel::new();
+        model.set_chat(Chat {
+            last_read_inbox_id: 2,
+            ..create_test_chat(100, "Test")
+        });
+        model.set_messages(vec![
+            create_test_message(5, "newest", false),
+            create_test_message(4, "mine", true),
+            create_test_message(3, "first unread", false),
+            create_test_message(2, "read", false),
+        ]);
+        assert_eq!(model.selected_message().map(|m| m.id), Some(5));
+
+        assert!(model.jump_to_first_unread());
+        assert_eq!(model.selected_message().map(|m| m.id), Some(3));
+
+        assert!(model.jump_back());
+        assert_eq!(model.selected_message().map(|m| m.id), Some(5));
+        assert!(model.jump_forward());
+        assert_eq!(model.selected_message().map(|m| m.id), Some(3));
+        assert!(!model.jump_forward());
+    }
+
+    #[test]
+    fn read_marker_follows_the_other_device_and_the_chat() {
+        let mut model = ConversationModel::new();
+        model.set_read_elsewhere(100, 2);
+        model.set_chat(create_test_chat(100, "Test"));
+        model.set_messages(vec![
+            create_test_message(4, "newest", false),
+            create_test_message(3, "unread", false),
+            create_test_message(2, "read on the phone", false),
+        ]);
+        assert_eq!(model.read_elsewhere_index(), Some(0));
+
+        // Reads only move forward
+        model.set_read_elsewhere(100, 3);
+        model.set_read_elsewhere(100, 1);
+        assert_eq!(model.read_elsewhere_index(), Some(1));
+
+        // Nothing to mark once everything is read
+        model.set_read_elsewhere(100, 4);
+        assert_eq!(model.read_elsewhere_index(), None);
+
+        model.set_chat(create_test_chat(200, "Other"));
+        model.set_messages(vec![create_test_message(9, "hi", false)]);
+        assert_eq!(model.read_elsewhere_index(), None);
+    }
+
+    /// Six messages over three days, two a day; the third mentions the
+    /// user, the last is a photo, and the last two are unread.
+    fn three_days_model() -> ConversationModel {
+        use chrono::TimeZone;
+
+        let mut model = ConversationModel::new();
+        model.set_chat(Chat {
+            last_read_inbox_id: 4,
+            ..create_test_chat(100, "Test")
+        });
+        let mut messages: Vec<Message> = (1..=6)
+            .map(|id| {
+                let day = u32::try_from((id + 1) / 2).unwrap();
+                let minute = u32::try_from(id).unwrap();
+                let mut message = create_test_message(id, "hi", false);
+                message.date = Local
+                    .with_ymd_and_hms(2024, 3, day, 12, minute, 0)
+                    .unwrap()
+                    .with_timezone(&Utc);
+                message
+            })
+            .collect();
+        messages[2].mentions_me = true;
+        messages[5].content.content_type = MessageType::Photo;
+        messages.reverse();
+        model.set_messages(messages);
+        model
+    }
+
+    #[test]
+    fn day_jumps_land_on_the_first_message_of_each_day() {
+        let mut model = three_days_model();
+        assert_eq!(model.selected_message().map(|m| m.id), Some(6));
+
+        assert!(model.jump_to_day(false));
+        assert_eq!(model.selected_message().map(|m| m.id), Some(3));
+        assert!(model.jump_to_day(false));
+        assert_eq!(model.selected_message().map(|m| m.id), Some(1));
+        assert!(!model.jump_to_day(false));
+
+        assert!(model.jump_to_day(true));
+        assert_eq!(model.selected_message().map(|m| m.id), Some(3));
+        assert!(model.jump_back());
+        assert_eq!(model.selected_message().map(|m| m.id), Some(1));
+    }
+
+    #[test]
+    fn messages_group_with_the_one_before_within_the_window_on_the_same_day() {
+        let mut model = three_days_model();
+        let window = Duration::from_secs(5 * 60);
+        let continued = |model: &ConversationModel, window: Duration| -> Vec<i64> {
+            (0..model.messages.len())
+                .filter(|&idx| model.continues_previous(idx, window))
+                .map(|idx| model.messages[idx].id)
+                .collect()
+        };
+        assert_eq!(continued(&model, window), [2, 4, 6]);
+        assert!(continued(&model, Duration::ZERO).is_empty());
+        assert!(continued(&model, Duration::from_secs(30)).is_empty());
+
+        // Another sender, or an edit, starts a new group
+        model.messages[1].sender_id = 7;
+        model.messages[3].is_edited = true;
+        assert_eq!(continued(&model, window), [6]);
+    }
+
+    #[test]
+    fn minimap_marks_mentions_media_days_and_the_unread_part() {
+        let model = three_days_model();
+
+        let rows = model.minimap(6, 4..6);
+        let marks: Vec<MinimapMark> = rows.iter().map(|r| r.mark).collect();
+        assert_eq!(
+            marks,
+            [
+                MinimapMark::Plain,
+                MinimapMark::Plain,
+                MinimapMark::Mention,
+                MinimapMark::Plain,
+                MinimapMark::DayStart,
+                MinimapMark::Media,
+            ]
+        );
+        let unread: Vec<bool> = rows.iter().map(|r| r.unread).collect();
+        assert_eq!(unread, [false, false, false, false, true, true]);
+        let in_view: Vec<bool> = rows.iter().map(|r| r.in_view).collect();
+        assert_eq!(in_view, [false, false, false, false, true, true]);
+
+        // Fewer rows than messages: each row shows its most telling mark
+        let marks: Vec<MinimapMark> = model.minimap(3, 4..6).iter().map(|r| r.mark).collect();
+        assert_eq!(
+            marks,
+            [MinimapMark::Plain, MinimapMark::Mention, MinimapMark::Media]
+        );
+    }
+
+    #[test]
+    fn older_history_goes_above_and_keeps_the_selection() {
+        let mut model = ConversationModel::new();
+        model.set_messages(vec![
+            Message {
+                reply_to_message_id: 1,
+                ..create_test_message(4, "yes, that one", false)
+            },
+            create_test_message(3, "c", false),
+        ]);
+        assert_eq!(model.replied_message_id(), Some(1));
+
+        let added = model.prepend_messages(vec![
+            create_test_message(3, "c", false),
+            create_test_message(2, "b", false),
+            create_test_message(1, "the question", true),
+        ]);
+        assert_eq!(added, 2);
+        assert_eq!(model.oldest_message_id(), Some(1));
+        assert_eq!(model.selected_message().map(|m| m.id), Some(4));
+
+        let now = Instant::now();
+        assert!(model.jump_to_message(1));
+        model.flash_message(1, now);
+        assert!(model.is_flashing(1, now + Duration::from_millis(500)));
+        assert!(!model.is_flashing(1, now + FLASH_DURATION));
+        assert!(model.jump_back());
+        assert_eq!(model.selected_message().map(|m| m.id), Some(4));
+    }
+
+    #[test]
+    fn first_unread_falls_back_to_unread_count() {
+        let mut model = ConversationModel::new();
+        model.set_chat(Chat {
+            unread_count: 2,
+            ..create_test_chat(100, "Test")
+        });
+        model.set_messages(vec![
+            create_test_message(3, "c", false),
+            create_test_message(2, "b", false),
+            create_test_message(1, "a", false),
+        ]);
+        assert!(model.jump_to_first_unread());
+        assert_eq!(model.selected_message().map(|m| m.id), Some(2));
+    }
+
+    #[test]
+    fn next_mention_wraps_around() {
+        let mention = |id| Message {
+            mentions_me: true,
+            ..create_test_message(id, "@me", false)
+        };
+        let mut model = ConversationModel::new();
+        model.set_messages(vec![
+            create_test_message(4, "d", false),
+            mention(3),
+            create_test_message(2, "b", false),
+            mention(1),
+        ]);
+        model.select_first();
+
+        assert!(model.jump_to_next_mention());
+        assert_eq!(model.selected_message().map(|m| m.id), Some(3));
+        assert!(model.jump_to_next_mention());
+        assert_eq!(model.selected_message().map(|m| m.id), Some(1));
+
+        let mut empty = ConversationModel::new();
+        assert!(!empty.jump_to_next_mention());
+        assert!(!empty.jump_back());
+    }
+
+    #[test]
+    fn select_first_since_picks_oldest_match() {
+        let now = Utc::now();
+        let messages: Vec<Message> = (1..=3)
+            .rev()
+            .map(|id| Message {
+                date: now - chrono::Duration::days(3 - id),
+                ..create_test_message(id, "x", false)
+            })
+            .collect();
+        let mut model = ConversationModel::new();
+        assert!(!model.select_first_since(now));
+
+        model.set_messages(messages);
+        assert!(model.select_first_since(now - chrono::Duration::hours(36)));
+        assert_eq!(model.selected_message().map(|m| m.id), Some(2));
+
+        // Nothing that recent: fall back to the newest message
+        assert!(model.select_first_since(now + chrono::Duration::days(1)));
+        assert_eq!(model.selected_message().map(|m| m.id), Some(3));
+    }
+
+    #[test]
+    fn pasted_file_path_is_offered_as_attachment() {
+        let dir = std::env::temp_dir().join(format!("ithil_offer_test_{}", std::process::id()));
+        std::fs::create_dir_all(&dir).unwrap();
+        let path = dir.join("cat.png");
+        std::fs::write(&path, b"png").unwrap();
+        let typed = format!("{} so cute", path.display());
+
+        let mut model = ConversationModel::new();
+        model.input.set_focused(true);
+        model.input.set_value(typed.clone());
+        assert_eq!(model.handle_action(Action::SendMessage), None);
+        assert_eq!(model.pending_attachment(), Some(&path));
+        assert_eq!(model.input.value(), "so cute");
+
+        // Esc keeps the text as typed, and it then sends as text
+        model.handle_action(Action::CancelAction);
+        assert!(model.pending_attachment().is_none());
+        assert_eq!(model.input.value(), typed);
+        assert_eq!(
+            model.handle_action(Action::SendMessage),
+            Some(ConversationAction::SendMessage(typed, None))
+        );
+
+        // Accepting sends the file with the rest as its caption
+        model.input.set_value(format!("{} so cute", path.display()));
+        model.handle_action(Action::SendMessage);
+        assert_eq!(
+            model.handle_action(Action::SendMessage),
+            Some(ConversationAction::SendMessageWithAttachment(
+                "so cute".to_string(),
+                path.clone(),
+                true,
+                None
+            ))
+        );
+        std::fs::remove_dir_all(&dir).unwrap();
+    }
+
+    #[test]
+    fn several_files_are_staged_and_sent_together() {
+        use std::path::PathBuf;
+        let mut model = ConversationModel::new();
+        model.input.set_focused(true);
+        model.add_pending_attachment(PathBuf::from("/tmp/cat.png"));
+        model.add_pending_attachment(PathBuf::from("/tmp/dog.jpg"));
+        model.add_pending_attachment(PathBuf::from("/tmp/cat.png"));
+        model.add_pending_attachment(PathBuf::from("/tmp/notes.pdf"));
+        assert_eq!(model.attachment_count(), 3, "staged once each");
+
+        // Esc takes off the last added
+        model.handle_action(Action::CancelAction);
+        assert_eq!(model.attachment_count(), 2);
+        assert!(model.input.is_focused());
+
+        model.input.set_value("pets");
+        assert_eq!(
+            model.handle_action(Action::SendMessage),
+            Some(ConversationAction::SendAttachments(
+                "pets".to_string(),
+                vec![PathBuf::from("/tmp/cat.png"), PathBuf::from("/tmp/dog.jpg")],
+                true,
+                None
+            ))
+        );
+        assert_eq!(model.attachment_count(), 0);
+    }
+
     #[test]
     fn esc_clears_pending_attachment_first() {
         use std::path::PathBuf;
@@ -0,0 +1,176 @@
+//! Picker for the country calling code at sign-in (`Tab` on the phone
+//! number prompt).
+//!
+//! Typing narrows the list by country name, ISO code or calling code;
+//! `Enter` puts the highlighted country's code in front of the number.
+
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, List, ListItem, ListState},
+    Frame,
+};
+
+use crate::ui::styles::Styles;
+use crate::utils::{search_countries, Country};
+
+/// Result of a key press in the country picker.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum CountryPickerAction {
+    /// Key was handled; keep the picker open
+    None,
+    /// Close without changing the number
+    Cancel,
+    /// Use this country's calling code
+    Choose(&'static Country),
+}
+
+/// Searchable list of countries and their calling codes.
+#[derive(Debug, Clone)]
+pub struct CountryPicker {
+    query: String,
+    matches: Vec<&'static Country>,
+    selected: usize,
+}
+
+impl Default for CountryPicker {
+    fn default() -> Self {
+        Self::new()
+    }
+}
+
+impl CountryPicker {
+    /// Creates the picker listing every country.
+    #[must_use]
+    pub fn new() -> Self {
+        Self {
+            query: String::new(),
+            matches: search_countries(""),
+            selected: 0,
+        }
+    }
+
+    fn search(&mut self) {
+        self.matches = search_countries(&self.query);
+        self.selected = 0;
+    }
+
+    /// Handles a key press.
+    pub fn handle_input(&mut self, key: KeyEvent) -> CountryPickerAction {
+        match key.code {
+            KeyCode::Esc => CountryPickerAction::Cancel,
+            KeyCode::Enter => self
+                .matches
+                .get(self.selected)
+                .map_or(CountryPickerAction::None, |&c| {
+                    CountryPickerAction::Choose(c)
+                }),
+            KeyCode::Up => {
+                self.selected = self.selected.saturating_sub(1);
+                CountryPickerAction::None
+            },
+            KeyCode::Down => {
+                if self.selected + 1 < self.matches.len() {
+                    self.selected += 1;
+                }
+                CountryPickerAction::None
+            },
+            KeyCode::Backspace => {
+                self.query.pop();
+                self.search();
+                CountryPickerAction::None
+            },
+            KeyCode::Char(c) => {
+                self.query.push(c);
+                self.search();
+                CountryPickerAction::None
+            },
+            _ => CountryPickerAction::None,
+        }
+    }
+
+    /// Renders the picker as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 44.min(area.width.saturating_sub(4));
+        let h = 16.min(area.height.saturating_sub(2));
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let title = if self.query.is_empty() {
+            " Country code: type to search ".to_string()
+        } else {
+            format!(" Country code: {} ", self.query)
+        };
+        let block = Block::default()
+            .title(Span::styled(title, Styles::text_bright()))
+            .title_bottom(Span::styled(
+                " Enter use \u{2022} Esc cancel ",
+                Styles::text_muted(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let items: Vec<ListItem> = self
+            .matches
+            .iter()
+            .map(|c| {
+                ListItem::new(Line::from(vec![
+                    Span::styled(format!("+{:<4} ", c.code), Styles::text_accent()),
+                    Span::styled(c.name, Styles::text()),
+                    Span::styled(format!(" {}", c.iso), Styles::text_muted()),
+                ]))
+            })
+            .collect();
+
+        let list = List::new(items)
+            .block(block)
+            .highlight_style(Styles::highlight());
+        let mut state = ListState::default();
+        state.select((!self.matches.is_empty()).then_some(self.selected));
+        frame.render_stateful_widget(list, modal, &mut state);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn press(picker: &mut CountryPicker, code: KeyCode) -> CountryPickerAction {
+        picker.handle_input(KeyEvent::from(code))
+    }
+
+    #[test]
+    fn typing_narrows_the_list_and_enter_picks() {
+        let mut picker = CountryPicker::new();
+        for c in "united".chars() {
+            press(&mut picker, KeyCode::Char(c));
+        }
+        press(&mut picker, KeyCode::Down);
+        let CountryPickerAction::Choose(country) = press(&mut picker, KeyCode::Enter) else {
+            panic!("expected a country");
+        };
+        assert_eq!(country.iso, "GB");
+
+        // A new query starts from the top of its matches
+        for _ in 0..6 {
+            press(&mut picker, KeyCode::Backspace);
+        }
+        for c in "+49".chars() {
+            press(&mut picker, KeyCode::Char(c));
+        }
+        assert!(matches!(
+            press(&mut picker, KeyCode::Enter),
+            CountryPickerAction::Choose(Country { iso: "DE", .. })
+        ));
+        assert_eq!(
+            press(&mut picker, KeyCode::Esc),
+            CountryPickerAction::Cancel
+        );
+    }
+}
@@ -0,0 +1,157 @@
+//! "Jump to date" prompt for the conversation (`Ctrl+G`).
+//!
+//! Accepts the forms understood by [`crate::utils::parse_date`], such as
+//! `yesterday`, `2024-03-15`, `03-15`, or `2w`.
+
+use chrono::{Local, NaiveDate};
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use crate::ui::styles::Styles;
+use crate::utils::parse_date;
+
+/// Result of a key press in the date prompt.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum DatePromptAction {
+    /// Key was handled; keep the prompt open
+    None,
+    /// Close the prompt without jumping
+    Close,
+    /// Jump to this day
+    Jump(NaiveDate),
+}
+
+/// Modal prompt asking for a date to jump to.
+#[derive(Debug, Clone, Default)]
+pub struct DatePrompt {
+    input: String,
+    error: Option<String>,
+}
+
+impl DatePrompt {
+    /// Creates an empty prompt.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Handles a key press, interpreting dates relative to `today`.
+    pub fn handle_input(&mut self, key: KeyEvent, today: NaiveDate) -> DatePromptAction {
+        let ctrl = key.modifiers.contains(KeyModifiers::CONTROL);
+        match key.code {
+            KeyCode::Esc => DatePromptAction::Close,
+            KeyCode::Char('g') if ctrl => DatePromptAction::Close,
+            KeyCode::Enter => match parse_date(&self.input, today) {
+                Some(date) => DatePromptAction::Jump(date),
+                None => {
+                    self.error = Some(format!("Not a past date: {}", self.input.trim()));
+                    DatePromptAction::None
+                },
+            },
+            KeyCode::Backspace => {
+                self.input.pop();
+                self.error = None;
+                DatePromptAction::None
+            },
+            KeyCode::Char('u') if ctrl => {
+                self.input.clear();
+                self.error = None;
+                DatePromptAction::None
+            },
+            KeyCode::Char(c) if !ctrl => {
+                self.input.push(c);
+                self.error = None;
+                DatePromptAction::None
+            },
+            _ => DatePromptAction::None,
+        }
+    }
+
+    /// Renders the prompt as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 50.min(area.width.saturating_sub(4));
+        let h = 6.min(area.height);
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(" Jump to date ", Styles::text_bright()))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let hint = self.error.as_ref().map_or_else(
+            || {
+                let example = Local::now().date_naive().format("%Y-%m-%d");
+                Line::from(Span::styled(
+                    format!("e.g. {example}, 03-15, yesterday, 2w"),
+                    Styles::text_muted(),
+                ))
+            },
+            |e| Line::from(Span::styled(e.as_str(), Styles::error())),
+        );
+        let lines = vec![
+            Line::from(vec![
+                Span::styled("> ", Styles::text_accent()),
+                Span::styled(self.input.as_str(), Styles::text()),
+                Span::styled("\u{2588}", Styles::input_cursor()),
+            ]),
+            Line::from(""),
+            hint,
+        ];
+
+        frame.render_widget(Paragraph::new(lines).block(block), modal);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn today() -> NaiveDate {
+        NaiveDate::from_ymd_opt(2024, 3, 15).unwrap()
+    }
+
+    fn type_str(prompt: &mut DatePrompt, text: &str) {
+        for c in text.chars() {
+            prompt.handle_input(KeyEvent::from(KeyCode::Char(c)), today());
+        }
+    }
+
+    #[test]
+    fn enter_jumps_to_parsed_date() {
+        let mut prompt = DatePrompt::new();
+        type_str(&mut prompt, "2024-01-02");
+        assert_eq!(
+            prompt.handle_input(KeyEvent::from(KeyCode::Enter), today()),
+            DatePromptAction::Jump(NaiveDate::from_ymd_opt(2024, 1, 2).unwrap())
+        );
+    }
+
+    #[test]
+    fn invalid_date_keeps_prompt_open_with_error() {
+        let mut prompt = DatePrompt::new();
+        type_str(&mut prompt, "tomorrow");
+        assert_eq!(
+            prompt.handle_input(KeyEvent::from(KeyCode::Enter), today()),
+            DatePromptAction::None
+        );
+        assert!(prompt.error.is_some());
+
+        prompt.handle_input(KeyEvent::from(KeyCode::Backspace), today());
+        assert!(prompt.error.is_none());
+        assert_eq!(
+            prompt.handle_input(KeyEvent::from(KeyCode::Esc), today()),
+            DatePromptAction::Close
+        );
+    }
+}
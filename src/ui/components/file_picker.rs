@@ -1,7 +1,8 @@
-//! Modal file browser for selecting a file to attach to a message.
+//! Modal file browser for selecting files to attach to a message.
 //!
 //! Rendered as an overlay (like the help overlay). Navigation logic is kept
 //! separate from rendering so it can be unit-tested against a temp directory.
+//! `Space` marks several files, in any directories, to attach together.
 
 use std::path::PathBuf;
 
@@ -19,8 +20,9 @@ use crate::ui::styles::Styles;
 pub enum FilePickerAction {
     /// Navigated or descended into a directory — nothing to report.
     None,
-    /// User confirmed a file; contains the absolute path to the selected file.
-    Selected(PathBuf),
+    /// User confirmed; contains the absolute paths of the marked files and
+    /// the highlighted one, in the order they were marked.
+    Selected(Vec<PathBuf>),
 }
 
 #[derive(Debug, Clone)]
@@ -39,6 +41,8 @@ pub struct FilePicker {
     current_dir: PathBuf,
     entries: Vec<Entry>,
     selected: usize,
+    /// Files marked to attach, in the order marked
+    marked: Vec<PathBuf>,
 }
 
 impl FilePicker {
@@ -59,6 +63,7 @@ impl FilePicker {
             current_dir: dir,
             entries: Vec::new(),
             selected: 0,
+            marked: Vec::new(),
         };
         picker.reload();
         picker
@@ -118,11 +123,25 @@ impl FilePicker {
         }
     }
 
+    /// Marks the highlighted file to attach, or unmarks it. Directories
+    /// can't be marked.
+    pub fn toggle_mark(&mut self) {
+        let Some(entry) = self.entries.get(self.selected).filter(|e| !e.is_dir) else {
+            return;
+        };
+        if let Some(i) = self.marked.iter().position(|p| *p == entry.path) {
+            self.marked.remove(i);
+        } else {
+            self.marked.push(entry.path.clone());
+        }
+        self.select_next();
+    }
+
     /// Activates the currently highlighted entry.
     ///
     /// If the entry is a directory (including `..`), descends into it and
     /// returns [`FilePickerAction::None`]. If it is a file, returns
-    /// [`FilePickerAction::Selected`] with the file's path.
+    /// [`FilePickerAction::Selected`] with the marked files and this one.
     pub fn activate(&mut self) -> FilePickerAction {
         let Some(entry) = self.entries.get(self.selected) else {
             return FilePickerAction::None;
@@ -132,7 +151,11 @@ impl FilePicker {
             self.reload();
             FilePickerAction::None
         } else {
-            FilePickerAction::Selected(entry.path.clone())
+            let mut paths = self.marked.clone();
+            if !paths.contains(&entry.path) {
+                paths.push(entry.path.clone());
+            }
+            FilePickerAction::Selected(paths)
         }
     }
 
@@ -159,9 +182,21 @@ impl FilePicker {
 
         frame.render_widget(Clear, modal);
 
-        let title = format!(" Attach file — {} ", self.current_dir.display());
+        let title = if self.marked.is_empty() {
+            format!(" Attach file — {} ", self.current_dir.display())
+        } else {
+            format!(
+                " Attach {} marked — {} ",
+                self.marked.len(),
+                self.current_dir.display()
+            )
+        };
         let block = Block::default()
             .title(Span::styled(title, Styles::text_bright()))
+            .title_bottom(Span::styled(
+                " Enter attach \u{2022} Space mark more \u{2022} Esc cancel ",
+                Styles::text_muted(),
+            ))
             .borders(Borders::ALL)
             .border_style(Styles::border_focused())
             .style(Styles::modal_background());
@@ -175,7 +210,15 @@ impl FilePicker {
                 } else {
                     Styles::text()
                 };
-                ListItem::new(Line::from(Span::styled(e.label.clone(), style)))
+                let mark = if self.marked.contains(&e.path) {
+                    "\u{2713} "
+                } else {
+                    "  "
+                };
+                ListItem::new(Line::from(vec![
+                    Span::styled(mark, Styles::text_accent()),
+                    Span::styled(e.label.clone(), style),
+                ]))
             })
             .collect();
 
@@ -241,11 +284,49 @@ mod tests {
         assert_eq!(picker.activate(), FilePickerAction::None);
         picker.select_next();
         match picker.activate() {
-            FilePickerAction::Selected(p) => assert!(p.ends_with("nested.txt")),
+            FilePickerAction::Selected(p) => assert!(p[0].ends_with("nested.txt")),
             other @ FilePickerAction::None => panic!("expected Selected, got {other:?}"),
         }
     }
 
+    #[test]
+    fn marked_files_are_attached_together() {
+        let dir = temp_tree();
+        fs::write(dir.join("file_b.txt"), b"b").unwrap();
+        let mut picker = FilePicker::with_dir(dir.clone());
+        // .., subdir/, file_a.txt, file_b.txt
+        picker.select_next();
+        picker.toggle_mark();
+        assert_eq!(picker.selected_index(), 1, "directories can't be marked");
+        picker.select_next();
+        picker.toggle_mark();
+
+        // Marks are kept across directories
+        picker.select_previous();
+        picker.select_previous();
+        picker.activate();
+        picker.select_next();
+        assert_eq!(
+            picker.activate(),
+            FilePickerAction::Selected(vec![
+                dir.join("file_a.txt"),
+                dir.join("subdir").join("nested.txt"),
+            ])
+        );
+
+        // Marking again unmarks
+        let mut picker = FilePicker::with_dir(dir.clone());
+        picker.select_next();
+        picker.select_next();
+        picker.toggle_mark();
+        picker.select_previous();
+        picker.toggle_mark();
+        assert_eq!(
+            picker.activate(),
+            FilePickerAction::Selected(vec![dir.join("file_b.txt")])
+        );
+    }
+
     #[test]
     fn parent_entry_ascends() {
         let dir = temp_tree();
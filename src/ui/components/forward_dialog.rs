@@ -0,0 +1,180 @@
+//! Options shown after choosing where to forward a message.
+//!
+//! The user may type a comment, sent to the destination just before the
+//! forwarded message, and may hide the original sender (Telegram's
+//! `drop_author`), which makes the forward look like a fresh message.
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use crate::ui::styles::Styles;
+
+/// How to forward a message.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct ForwardOptions {
+    /// Comment to send before the forwarded message (empty for none)
+    pub comment: String,
+    /// Forward without attributing the original sender
+    pub drop_author: bool,
+}
+
+/// Result of a key press in the forward dialog.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum ForwardDialogAction {
+    /// Key was handled; keep the dialog open
+    None,
+    /// Cancel the forward
+    Cancel,
+    /// Forward to the destination with these options
+    Send(ForwardOptions),
+}
+
+/// Forward options dialog.
+#[derive(Debug, Clone)]
+pub struct ForwardDialog {
+    /// Destination chat IDs
+    to_chat_ids: Vec<i64>,
+    /// Destination names, for the title
+    destination: String,
+    options: ForwardOptions,
+}
+
+impl ForwardDialog {
+    /// Creates a dialog for forwarding to `to_chat_ids`, shown as
+    /// `destination`.
+    #[must_use]
+    pub fn new(to_chat_ids: Vec<i64>, destination: impl Into<String>) -> Self {
+        Self {
+            to_chat_ids,
+            destination: destination.into(),
+            options: ForwardOptions::default(),
+        }
+    }
+
+    /// Returns the destination chat IDs.
+    #[must_use]
+    pub fn to_chat_ids(&self) -> &[i64] {
+        &self.to_chat_ids
+    }
+
+    /// Handles a key press.
+    ///
+    /// Typing edits the comment, `Tab` toggles hiding the sender, `Enter`
+    /// sends, and `Esc` cancels.
+    pub fn handle_input(&mut self, key: KeyEvent) -> ForwardDialogAction {
+        let ctrl = key.modifiers.contains(KeyModifiers::CONTROL);
+        match key.code {
+            KeyCode::Esc => ForwardDialogAction::Cancel,
+            KeyCode::Enter => ForwardDialogAction::Send(self.options.clone()),
+            KeyCode::Tab => {
+                self.options.drop_author = !self.options.drop_author;
+                ForwardDialogAction::None
+            },
+            KeyCode::Backspace => {
+                self.options.comment.pop();
+                ForwardDialogAction::None
+            },
+            KeyCode::Char('u') if ctrl => {
+                self.options.comment.clear();
+                ForwardDialogAction::None
+            },
+            KeyCode::Char(c) if !ctrl => {
+                self.options.comment.push(c);
+                ForwardDialogAction::None
+            },
+            _ => ForwardDialogAction::None,
+        }
+    }
+
+    /// Renders the dialog as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 60.min(area.width.saturating_sub(4));
+        let h = 8.min(area.height);
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" Forward to {} ", self.destination),
+                Styles::text_bright(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let comment = if self.options.comment.is_empty() {
+            Span::styled("(optional)", Styles::input_placeholder())
+        } else {
+            Span::styled(self.options.comment.as_str(), Styles::text())
+        };
+        let checkbox = if self.options.drop_author {
+            "[x]"
+        } else {
+            "[ ]"
+        };
+        let lines = vec![
+            Line::from(vec![
+                Span::styled("Comment: ", Styles::text_accent()),
+                comment,
+                Span::styled("\u{2588}", Styles::input_cursor()),
+            ]),
+            Line::from(""),
+            Line::from(vec![
+                Span::styled(format!("{checkbox} "), Styles::text_accent()),
+                Span::styled("Hide original sender", Styles::text()),
+            ]),
+            Line::from(""),
+            Line::from(Span::styled(
+                "Enter forward \u{2022} Tab toggle sender \u{2022} Esc cancel",
+                Styles::text_muted(),
+            )),
+        ];
+
+        frame.render_widget(Paragraph::new(lines).block(block), modal);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn collects_comment_and_drop_author() {
+        let mut dialog = ForwardDialog::new(vec![7, 8], "Work, Home");
+        for c in "fyi".chars() {
+            dialog.handle_input(KeyEvent::from(KeyCode::Char(c)));
+        }
+        dialog.handle_input(KeyEvent::from(KeyCode::Tab));
+
+        assert_eq!(dialog.to_chat_ids(), &[7, 8]);
+        assert_eq!(
+            dialog.handle_input(KeyEvent::from(KeyCode::Enter)),
+            ForwardDialogAction::Send(ForwardOptions {
+                comment: "fyi".to_string(),
+                drop_author: true,
+            })
+        );
+    }
+
+    #[test]
+    fn defaults_to_plain_forward_and_esc_cancels() {
+        let mut dialog = ForwardDialog::new(vec![7], "Work");
+        assert_eq!(
+            dialog.handle_input(KeyEvent::from(KeyCode::Enter)),
+            ForwardDialogAction::Send(ForwardOptions::default())
+        );
+        assert_eq!(
+            dialog.handle_input(KeyEvent::from(KeyCode::Esc)),
+            ForwardDialogAction::Cancel
+        );
+    }
+}
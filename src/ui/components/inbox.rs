@@ -0,0 +1,268 @@
+//! Inbox of unread messages from every chat (`Alt+I`).
+//!
+//! Unread messages are gathered into one stream, oldest first, each
+//! prefixed with its chat, so everything waiting can be triaged in one
+//! place. `Enter` jumps to a message and `r` marks its chat read; once it
+//! is, the chat's messages leave the inbox. It opens with what is cached and
+//! takes each chat's fetched messages as they arrive.
+
+use chrono::{DateTime, Local, Utc};
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, List, ListItem, ListState, Paragraph},
+    Frame,
+};
+
+use crate::ui::styles::Styles;
+
+/// An unread message, with the names needed to show it.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct InboxEntry {
+    /// Chat the message is in
+    pub chat_id: i64,
+    /// The message's ID
+    pub message_id: i64,
+    /// Chat name
+    pub chat: String,
+    /// Sender name (empty in private chats, where it's the chat name)
+    pub sender: String,
+    /// When the message was sent
+    pub date: DateTime<Utc>,
+    /// One-line preview of the message
+    pub preview: String,
+}
+
+/// Result of a key press in the inbox.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum InboxAction {
+    /// Key was handled; keep the inbox open
+    None,
+    /// Close the inbox
+    Close,
+    /// Open the chat and select the message (chat ID, message ID)
+    Jump(i64, i64),
+    /// Mark the chat read
+    MarkRead(i64),
+}
+
+/// Unread messages from every chat, oldest first.
+#[derive(Debug, Clone, Default)]
+pub struct Inbox {
+    entries: Vec<InboxEntry>,
+    selected: usize,
+    /// Whether chats are still being fetched
+    loading: bool,
+}
+
+impl Inbox {
+    /// Creates an inbox from unread messages in any order.
+    #[must_use]
+    pub fn new(mut entries: Vec<InboxEntry>) -> Self {
+        entries.sort_by_key(|e| (e.date, e.chat_id, e.message_id));
+        Self {
+            entries,
+            selected: 0,
+            loading: false,
+        }
+    }
+
+    /// Notes whether chats are still being fetched, which the title shows.
+    pub fn set_loading(&mut self, loading: bool) {
+        self.loading = loading;
+    }
+
+    /// Replaces a chat's entries with `entries`, keeping the selected
+    /// message selected if it is still there.
+    pub fn set_chat(&mut self, chat_id: i64, entries: Vec<InboxEntry>) {
+        let selected = self
+            .entries
+            .get(self.selected)
+            .map(|e| (e.chat_id, e.message_id));
+        self.entries.retain(|e| e.chat_id != chat_id);
+        self.entries.extend(entries);
+        self.entries
+            .sort_by_key(|e| (e.date, e.chat_id, e.message_id));
+        self.selected = selected
+            .and_then(|key| {
+                self.entries
+                    .iter()
+                    .position(|e| (e.chat_id, e.message_id) == key)
+            })
+            .unwrap_or(self.selected)
+            .min(self.entries.len().saturating_sub(1));
+    }
+
+    /// Returns the entries, oldest first.
+    #[must_use]
+    pub fn entries(&self) -> &[InboxEntry] {
+        &self.entries
+    }
+
+    /// Removes a chat's entries, keeping the selection in range.
+    pub fn remove_chat(&mut self, chat_id: i64) {
+        self.entries.retain(|e| e.chat_id != chat_id);
+        self.selected = self.selected.min(self.entries.len().saturating_sub(1));
+    }
+
+    /// Handles a key press while the inbox is open.
+    pub fn handle_input(&mut self, key: KeyEvent) -> InboxAction {
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') => InboxAction::Close,
+            KeyCode::Enter => self
+                .entries
+                .get(self.selected)
+                .map_or(InboxAction::None, |e| {
+                    InboxAction::Jump(e.chat_id, e.message_id)
+                }),
+            KeyCode::Char('r') => self
+                .entries
+                .get(self.selected)
+                .map_or(InboxAction::None, |e| InboxAction::MarkRead(e.chat_id)),
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                InboxAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                if self.selected + 1 < self.entries.len() {
+                    self.selected += 1;
+                }
+                InboxAction::None
+            },
+            _ => InboxAction::None,
+        }
+    }
+
+    /// Renders the inbox as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 80.min(area.width.saturating_sub(4));
+        let h = 24.min(area.height.saturating_sub(2));
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(
+                if self.loading {
+                    format!(" Inbox ({}, loading\u{2026}) ", self.entries.len())
+                } else {
+                    format!(" Inbox ({}) ", self.entries.len())
+                },
+                Styles::text_bright(),
+            ))
+            .title_bottom(Span::styled(
+                " Enter go to message \u{2022} r mark chat read \u{2022} Esc close ",
+                Styles::text_muted(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        if self.entries.is_empty() {
+            let text = if self.loading {
+                "Loading\u{2026}"
+            } else {
+                "Nothing unread"
+            };
+            let empty = Paragraph::new(Span::styled(text, Styles::text_muted())).block(block);
+            frame.render_widget(empty, modal);
+            return;
+        }
+
+        let today = Local::now().date_naive();
+        let items: Vec<ListItem> = self
+            .entries
+            .iter()
+            .map(|e| {
+                let local = e.date.with_timezone(&Local);
+                let time = if local.date_naive() == today {
+                    local.format("%H:%M").to_string()
+                } else {
+                    local.format("%b %-d").to_string()
+                };
+                let mut spans = vec![
+                    Span::styled(format!("{time:>6} "), Styles::text_muted()),
+                    Span::styled(format!("[{}] ", e.chat), Styles::text_accent()),
+                ];
+                if !e.sender.is_empty() {
+                    spans.push(Span::styled(
+                        format!("{}: ", e.sender),
+                        Styles::text_bright(),
+                    ));
+                }
+                spans.push(Span::styled(e.preview.clone(), Styles::text()));
+                ListItem::new(Line::from(spans))
+            })
+            .collect();
+
+        let list = List::new(items)
+            .block(block)
+            .highlight_style(Styles::highlight());
+        let mut state = ListState::default();
+        state.select(Some(self.selected));
+        frame.render_stateful_widget(list, modal, &mut state);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use chrono::Duration;
+
+    use super::*;
+
+    fn entry(chat_id: i64, message_id: i64, minutes_ago: i64) -> InboxEntry {
+        InboxEntry {
+            chat_id,
+            message_id,
+            chat: format!("Chat {chat_id}"),
+            sender: String::new(),
+            date: Utc::now() - Duration::minutes(minutes_ago),
+            preview: format!("Message {message_id}"),
+        }
+    }
+
+    fn press(inbox: &mut Inbox, code: KeyCode) -> InboxAction {
+        inbox.handle_input(KeyEvent::from(code))
+    }
+
+    #[test]
+    fn entries_from_all_chats_are_merged_oldest_first() {
+        let inbox = Inbox::new(vec![entry(1, 10, 5), entry(2, 20, 30), entry(1, 11, 1)]);
+        let order: Vec<i64> = inbox.entries().iter().map(|e| e.message_id).collect();
+        assert_eq!(order, vec![20, 10, 11]);
+    }
+
+    #[test]
+    fn marking_a_chat_read_drops_its_entries() {
+        let mut inbox = Inbox::new(vec![entry(1, 10, 5), entry(2, 20, 30), entry(1, 11, 1)]);
+        assert_eq!(press(&mut inbox, KeyCode::Enter), InboxAction::Jump(2, 20));
+
+        press(&mut inbox, KeyCode::Down);
+        press(&mut inbox, KeyCode::Down);
+        assert_eq!(
+            press(&mut inbox, KeyCode::Char('r')),
+            InboxAction::MarkRead(1)
+        );
+        inbox.remove_chat(1);
+        assert_eq!(inbox.entries().len(), 1);
+        assert_eq!(press(&mut inbox, KeyCode::Enter), InboxAction::Jump(2, 20));
+        assert_eq!(press(&mut inbox, KeyCode::Esc), InboxAction::Close);
+    }
+
+    #[test]
+    fn fetched_entries_replace_a_chats_cached_ones() {
+        let mut inbox = Inbox::new(vec![entry(1, 10, 5), entry(2, 20, 30)]);
+        press(&mut inbox, KeyCode::Down);
+        assert_eq!(press(&mut inbox, KeyCode::Enter), InboxAction::Jump(1, 10));
+
+        inbox.set_chat(2, vec![entry(2, 21, 1), entry(2, 20, 30)]);
+        let order: Vec<i64> = inbox.entries().iter().map(|e| e.message_id).collect();
+        assert_eq!(order, vec![20, 10, 21]);
+        // The selection stays on the same message
+        assert_eq!(press(&mut inbox, KeyCode::Enter), InboxAction::Jump(1, 10));
+    }
+}
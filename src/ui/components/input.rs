@@ -5,6 +5,7 @@
 //! - Text insertion and deletion
 //! - Password masking mode
 //! - Placeholder text support
+//! - Shell-style history recall (Ctrl+P / Ctrl+N)
 //!
 //! # Example
 //!
@@ -30,6 +31,9 @@ use unicode_width::UnicodeWidthStr;
 
 use crate::ui::styles::Styles;
 
+/// Maximum number of entries kept in the recall history.
+const MAX_HISTORY: usize = 100;
+
 /// Input echo mode for password fields.
 #[derive(Debug, Clone, Copy, PartialEq, Eq, Default)]
 pub enum EchoMode {
@@ -60,6 +64,12 @@ pub struct InputComponent {
     focused: bool,
     /// Visible width for rendering
     width: u16,
+    /// Previously submitted values, oldest first
+    history: Vec<String>,
+    /// Position in `history` while recalling, `None` when not recalling
+    history_index: Option<usize>,
+    /// Text that was in the input before recall started
+    history_draft: String,
 }
 
 impl Default for InputComponent {
@@ -93,6 +103,9 @@ impl InputComponent {
             echo_mode: EchoMode::Normal,
             focused: true,
             width: 30,
+            history: Vec::new(),
+            history_index: None,
+            history_draft: String::new(),
         }
     }
 
@@ -153,6 +166,12 @@ impl InputComponent {
         self.value.is_empty()
     }
 
+    /// Returns the number of lines in the current value (at least 1).
+    #[must_use]
+    pub fn line_count(&self) -> usize {
+        self.value.split('\n').count()
+    }
+
     /// Sets whether the input is focused.
     pub fn set_focused(&mut self, focused: bool) {
         self.focused = focused;
@@ -215,6 +234,15 @@ impl InputComponent {
                     self.delete_word_backward();
                     return true;
                 }
+                // Check for Ctrl+P / Ctrl+N (history recall)
+                if key.modifiers.contains(KeyModifiers::CONTROL) && c == 'p' {
+                    self.history_previous();
+                    return true;
+                }
+                if key.modifiers.contains(KeyModifiers::CONTROL) && c == 'n' {
+                    self.history_next();
+                    return true;
+                }
 
                 self.insert_char(c);
                 true
@@ -247,6 +275,80 @@ impl InputComponent {
         }
     }
 
+    /// Records a submitted value in the recall history.
+    ///
+    /// Empty values and immediate repeats are skipped, and the oldest entry
+    /// is dropped once the history holds `MAX_HISTORY` values.
+    pub fn push_history(&mut self, entry: impl Into<String>) {
+        let entry = entry.into();
+        self.history_index = None;
+        self.history_draft.clear();
+        if entry.trim().is_empty() || self.history.last() == Some(&entry) {
+            return;
+        }
+        if self.history.len() >= MAX_HISTORY {
+            self.history.remove(0);
+        }
+        self.history.push(entry);
+    }
+
+    /// Recalls the previous (older) history entry into the input.
+    ///
+    /// The current text is saved on the first recall so that stepping past
+    /// the newest entry with [`history_next`](Self::history_next) restores it.
+    pub fn history_previous(&mut self) {
+        if self.history.is_empty() {
+            return;
+        }
+        let idx = match self.history_index {
+            None => {
+                self.history_draft = self.value.clone();
+                self.history.len() - 1
+            },
+            Some(0) => 0,
+            Some(i) => i - 1,
+        };
+        self.history_index = Some(idx);
+        let value = self.history[idx].clone();
+        self.set_value(value);
+    }
+
+    /// Recalls the next (newer) history entry, or the saved draft.
+    pub fn history_next(&mut self) {
+        let Some(idx) = self.history_index else {
+            return;
+        };
+        if idx + 1 < self.history.len() {
+            self.history_index = Some(idx + 1);
+            let value = self.history[idx + 1].clone();
+            self.set_value(value);
+        } else {
+            self.history_index = None;
+            let draft = std::mem::take(&mut self.history_draft);
+            self.set_value(draft);
+        }
+    }
+
+    /// Returns the recall history, oldest first.
+    #[must_use]
+    pub fn history(&self) -> &[String] {
+        &self.history
+    }
+
+    /// Replaces the recall history, e.g. when switching chats.
+    pub fn set_history(&mut self, history: Vec<String>) {
+        self.history = history;
+        self.history_index = None;
+        self.history_draft.clear();
+    }
+
+    /// Takes the recall history out of the input, leaving it empty.
+    pub fn take_history(&mut self) -> Vec<String> {
+        self.history_index = None;
+        self.history_draft.clear();
+        std::mem::take(&mut self.history)
+    }
+
     /// Inserts a character at the cursor position.
     pub fn insert_char(&mut self, c: char) {
         // Check character limit
@@ -373,7 +475,12 @@ impl InputComponent {
             Styles::input()
         };
 
-        let paragraph = Paragraph::new(Line::from(Span::styled(display_value, style)));
+        // One `Line` per row so multi-line drafts render as typed
+        let lines: Vec<Line<'_>> = display_value
+            .split('\n')
+            .map(|row| Line::from(Span::styled(row.to_string(), style)))
+            .collect();
+        let paragraph = Paragraph::new(lines);
 
         // Calculate cursor position for display
         let cursor_pos = if self.focused && !self.value.is_empty() {
@@ -381,7 +488,9 @@ impl InputComponent {
                 EchoMode::Normal => &self.value[..self.cursor_byte_index()],
                 EchoMode::Password => &"*".repeat(self.cursor),
             };
-            Some((display_text.width() as u16, 0))
+            let row = display_text.matches('\n').count();
+            let col = display_text.rsplit('\n').next().unwrap_or_default().width();
+            Some((col as u16, row as u16))
         } else if self.focused && self.value.is_empty() {
             Some((0, 0))
         } else {
@@ -611,4 +720,57 @@ mod tests {
         let key = KeyEvent::from(KeyCode::Char('a'));
         assert!(!input.handle_input(key));
     }
+
+    #[test]
+    fn test_history_recall_restores_draft() {
+        let mut input = InputComponent::new("");
+        input.push_history("first");
+        input.push_history("second");
+        input.set_value("draft");
+
+        let ctrl_p = KeyEvent::new(KeyCode::Char('p'), KeyModifiers::CONTROL);
+        let ctrl_n = KeyEvent::new(KeyCode::Char('n'), KeyModifiers::CONTROL);
+
+        input.handle_input(ctrl_p);
+        assert_eq!(input.value(), "second");
+        input.handle_input(ctrl_p);
+        assert_eq!(input.value(), "first");
+        // Stops at the oldest entry
+        input.handle_input(ctrl_p);
+        assert_eq!(input.value(), "first");
+
+        input.handle_input(ctrl_n);
+        assert_eq!(input.value(), "second");
+        input.handle_input(ctrl_n);
+        assert_eq!(input.value(), "draft");
+    }
+
+    #[test]
+    fn test_history_skips_empty_and_repeats() {
+        let mut input = InputComponent::new("");
+        input.push_history("hi");
+        input.push_history("hi");
+        input.push_history("   ");
+        assert_eq!(input.history(), ["hi".to_string()]);
+    }
+
+    #[test]
+    fn test_history_is_capped() {
+        let mut input = InputComponent::new("");
+        for i in 0..=MAX_HISTORY {
+            input.push_history(i.to_string());
+        }
+        assert_eq!(input.history().len(), MAX_HISTORY);
+        assert_eq!(input.history()[0], "1");
+    }
+
+    #[test]
+    fn test_multiline_cursor_position() {
+        let mut input = InputComponent::new("");
+        input.set_value("first\nsecond");
+        assert_eq!(input.line_count(), 2);
+
+        let (_, cursor) = input.render_paragraph();
+        assert_eq!(cursor, Some((6, 1)));
+    }
 }
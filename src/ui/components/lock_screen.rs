@@ -0,0 +1,239 @@
+//! Lock screen that hides the whole UI behind a local passphrase.
+//!
+//! The passphrase never leaves the machine and is only stored hashed (see
+//! [`crate::utils::hash_passphrase`]). The first time the screen is locked
+//! without a passphrase configured, the lock screen asks for a new one twice
+//! before locking.
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+use ratatui::{
+    layout::{Alignment, Rect},
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use crate::ui::styles::Styles;
+use crate::utils::verify_passphrase;
+
+/// Result of a key press on the lock screen.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum LockScreenAction {
+    /// Key was handled; stay locked
+    None,
+    /// The correct passphrase was entered, for deriving the key of
+    /// encrypted chats
+    Unlocked(String),
+    /// Passphrase setup was cancelled
+    Cancel,
+    /// A new passphrase was entered and confirmed
+    SetPassphrase(String),
+}
+
+#[derive(Debug, Clone)]
+enum Mode {
+    /// Locked; unlocks with the passphrase matching `hash`
+    Locked { hash: String },
+    /// Choosing a passphrase; `first` holds the entry awaiting confirmation
+    Setup { first: Option<String> },
+}
+
+/// Full-screen lock overlay.
+#[derive(Debug, Clone)]
+pub struct LockScreen {
+    mode: Mode,
+    input: String,
+    error: Option<String>,
+}
+
+impl LockScreen {
+    /// Creates a locked screen that unlocks with the passphrase behind `hash`.
+    #[must_use]
+    pub fn locked(hash: impl Into<String>) -> Self {
+        Self {
+            mode: Mode::Locked { hash: hash.into() },
+            input: String::new(),
+            error: None,
+        }
+    }
+
+    /// Creates a screen asking for a new passphrase.
+    #[must_use]
+    pub const fn setup() -> Self {
+        Self {
+            mode: Mode::Setup { first: None },
+            input: String::new(),
+            error: None,
+        }
+    }
+
+    /// Returns `true` while choosing a new passphrase.
+    #[must_use]
+    pub const fn is_setup(&self) -> bool {
+        matches!(self.mode, Mode::Setup { .. })
+    }
+
+    /// Handles a key press.
+    pub fn handle_input(&mut self, key: KeyEvent) -> LockScreenAction {
+        let ctrl = key.modifiers.contains(KeyModifiers::CONTROL);
+        match key.code {
+            KeyCode::Enter => self.submit(),
+            KeyCode::Esc if self.is_setup() => LockScreenAction::Cancel,
+            KeyCode::Esc => {
+                self.input.clear();
+                LockScreenAction::None
+            },
+            KeyCode::Backspace => {
+                self.input.pop();
+                LockScreenAction::None
+            },
+            KeyCode::Char('u') if ctrl => {
+                self.input.clear();
+                LockScreenAction::None
+            },
+            KeyCode::Char(c) if !ctrl => {
+                self.input.push(c);
+                self.error = None;
+                LockScreenAction::None
+            },
+            _ => LockScreenAction::None,
+        }
+    }
+
+    fn submit(&mut self) -> LockScreenAction {
+        let input = std::mem::take(&mut self.input);
+        match &mut self.mode {
+            Mode::Locked { hash } => {
+                if verify_passphrase(&input, hash) {
+                    LockScreenAction::Unlocked(input)
+                } else {
+                    self.error = Some("Wrong passphrase".to_string());
+                    LockScreenAction::None
+                }
+            },
+            Mode::Setup { first } => {
+                if input.is_empty() {
+                    self.error = Some("Passphrase cannot be empty".to_string());
+                    return LockScreenAction::None;
+                }
+                match first.take() {
+                    None => {
+                        *first = Some(input);
+                        LockScreenAction::None
+                    },
+                    Some(expected) if expected == input => LockScreenAction::SetPassphrase(input),
+                    Some(_) => {
+                        self.error = Some("Passphrases do not match, try again".to_string());
+                        LockScreenAction::None
+                    },
+                }
+            },
+        }
+    }
+
+    /// Renders the lock screen over the entire terminal.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        frame.render_widget(Clear, area);
+        frame.render_widget(Block::default().style(Styles::modal_background()), area);
+
+        let w = 48.min(area.width.saturating_sub(4));
+        let h = 7.min(area.height);
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 2;
+        let modal = Rect::new(x, y, w, h);
+
+        let (title, prompt, hint) = match &self.mode {
+            Mode::Locked { .. } => (" Locked ", "Passphrase", "Enter to unlock"),
+            Mode::Setup { first: None } => (
+                " Set lock passphrase ",
+                "New passphrase",
+                "Enter to continue, Esc to cancel",
+            ),
+            Mode::Setup { first: Some(_) } => (
+                " Set lock passphrase ",
+                "Confirm passphrase",
+                "Enter to lock, Esc to cancel",
+            ),
+        };
+
+        let block = Block::default()
+            .title(Span::styled(title, Styles::text_bright()))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let masked = "\u{2022}".repeat(self.input.chars().count());
+        let status = self.error.as_ref().map_or_else(
+            || Line::from(Span::styled(hint, Styles::text_muted())),
+            |e| Line::from(Span::styled(e.as_str(), Styles::error())),
+        );
+        let lines = vec![
+            Line::from(""),
+            Line::from(vec![
+                Span::styled(format!("{prompt}: "), Styles::text_accent()),
+                Span::styled(masked, Styles::text()),
+                Span::styled("\u{2588}", Styles::input_cursor()),
+            ]),
+            Line::from(""),
+            status,
+        ];
+
+        let paragraph = Paragraph::new(lines)
+            .block(block)
+            .alignment(Alignment::Center);
+        frame.render_widget(paragraph, modal);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::utils::hash_passphrase;
+
+    fn type_str(screen: &mut LockScreen, text: &str) -> LockScreenAction {
+        for c in text.chars() {
+            screen.handle_input(KeyEvent::from(KeyCode::Char(c)));
+        }
+        screen.handle_input(KeyEvent::from(KeyCode::Enter))
+    }
+
+    #[test]
+    fn unlocks_only_with_correct_passphrase() {
+        let mut screen = LockScreen::locked(hash_passphrase("hunter2"));
+        assert_eq!(type_str(&mut screen, "hunter3"), LockScreenAction::None);
+        assert!(screen.error.is_some());
+        assert_eq!(
+            type_str(&mut screen, "hunter2"),
+            LockScreenAction::Unlocked("hunter2".to_string())
+        );
+    }
+
+    #[test]
+    fn locked_screen_cannot_be_dismissed() {
+        let mut screen = LockScreen::locked(hash_passphrase("x"));
+        assert_eq!(
+            screen.handle_input(KeyEvent::from(KeyCode::Esc)),
+            LockScreenAction::None
+        );
+    }
+
+    #[test]
+    fn setup_requires_matching_confirmation() {
+        let mut screen = LockScreen::setup();
+        assert_eq!(type_str(&mut screen, ""), LockScreenAction::None);
+        assert_eq!(type_str(&mut screen, "one"), LockScreenAction::None);
+        assert_eq!(type_str(&mut screen, "two"), LockScreenAction::None);
+        assert!(screen.error.is_some());
+
+        assert_eq!(type_str(&mut screen, "same"), LockScreenAction::None);
+        assert_eq!(
+            type_str(&mut screen, "same"),
+            LockScreenAction::SetPassphrase("same".to_string())
+        );
+        assert_eq!(
+            screen.handle_input(KeyEvent::from(KeyCode::Esc)),
+            LockScreenAction::Cancel
+        );
+    }
+}
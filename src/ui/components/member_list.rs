@@ -0,0 +1,160 @@
+//! Members of a group or channel, from the chat actions menu.
+
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, List, ListItem, ListState, Paragraph},
+    Frame,
+};
+
+use crate::types::User;
+use crate::ui::styles::Styles;
+
+/// Result of a key press in the member list.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum MemberListAction {
+    /// Key was handled; keep the list open
+    None,
+    /// Close the list
+    Close,
+    /// Open the private chat with this user
+    Open(i64),
+}
+
+/// A chat's members.
+#[derive(Debug, Clone)]
+pub struct MemberList {
+    /// Chat name, for the title
+    title: String,
+    members: Vec<User>,
+    selected: usize,
+}
+
+impl MemberList {
+    /// Creates the list.
+    #[must_use]
+    pub fn new(title: impl Into<String>, members: Vec<User>) -> Self {
+        Self {
+            title: title.into(),
+            members,
+            selected: 0,
+        }
+    }
+
+    /// Handles a key press while the list is open.
+    pub fn handle_input(&mut self, key: KeyEvent) -> MemberListAction {
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') => MemberListAction::Close,
+            KeyCode::Enter => self
+                .members
+                .get(self.selected)
+                .map_or(MemberListAction::Close, |user| {
+                    MemberListAction::Open(user.id)
+                }),
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                MemberListAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                if self.selected + 1 < self.members.len() {
+                    self.selected += 1;
+                }
+                MemberListAction::None
+            },
+            _ => MemberListAction::None,
+        }
+    }
+
+    /// Renders the list as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 60.min(area.width.saturating_sub(4));
+        let h = 20.min(area.height.saturating_sub(2));
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" Members of {} ({}) ", self.title, self.members.len()),
+                Styles::text_bright(),
+            ))
+            .title_bottom(Span::styled(
+                " Enter open chat \u{2022} Esc close ",
+                Styles::text_muted(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        if self.members.is_empty() {
+            let empty = Paragraph::new(Span::styled("No members to show", Styles::text_muted()))
+                .block(block);
+            frame.render_widget(empty, modal);
+            return;
+        }
+
+        let items: Vec<ListItem> = self
+            .members
+            .iter()
+            .map(|user| {
+                let mut spans = vec![Span::styled(user.get_display_name(), Styles::text())];
+                if user.is_verified {
+                    spans.push(Span::styled(" \u{2713}", Styles::text_accent()));
+                }
+                if user.is_bot {
+                    spans.push(Span::styled(" bot", Styles::text_muted()));
+                }
+                if !user.username.is_empty() {
+                    spans.push(Span::styled(
+                        format!("  @{}", user.username),
+                        Styles::text_muted(),
+                    ));
+                }
+                ListItem::new(Line::from(spans))
+            })
+            .collect();
+
+        let list = List::new(items)
+            .block(block)
+            .highlight_style(Styles::highlight());
+        let mut state = ListState::default();
+        state.select(Some(self.selected));
+        frame.render_stateful_widget(list, modal, &mut state);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn enter_opens_the_selected_member() {
+        let users = vec![
+            User {
+                id: 1,
+                first_name: "Ann".to_string(),
+                ..User::default()
+            },
+            User {
+                id: 2,
+                first_name: "Bob".to_string(),
+                ..User::default()
+            },
+        ];
+        let mut list = MemberList::new("Work", users);
+        list.handle_input(KeyEvent::from(KeyCode::Down));
+        list.handle_input(KeyEvent::from(KeyCode::Down));
+        assert_eq!(
+            list.handle_input(KeyEvent::from(KeyCode::Enter)),
+            MemberListAction::Open(2)
+        );
+        assert_eq!(
+            MemberList::new("Work", Vec::new()).handle_input(KeyEvent::from(KeyCode::Enter)),
+            MemberListAction::Close
+        );
+    }
+}
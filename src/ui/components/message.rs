@@ -2,7 +2,15 @@
 //!
 //! This module provides a widget for rendering individual Telegram messages
 //! with proper formatting for different message types, selection state,
-//! timestamps, and reply indicators.
+//! timestamps, and reply and forward indicators.
+//!
+//! Messages of only a few emoji can be shown enlarged, the way official
+//! clients do; a terminal can't scale text, so they get a centered line of
+//! their own with room around it.
+//!
+//! Text wraps on word boundaries with a hanging indent, so every line sits
+//! under the sender's name, and can be held to a maximum width so long
+//! messages stay readable on very wide terminals.
 //!
 //! # Example
 //!
@@ -17,6 +25,7 @@
 //!     .width(80);
 //! ```
 
+use chrono::{Duration, Utc};
 use ratatui::{
     buffer::Buffer,
     layout::Rect,
@@ -24,9 +33,25 @@ use ratatui::{
     widgets::{Paragraph, Widget, Wrap},
 };
 
-use crate::types::{Message, MessageType};
+use crate::types::{DownloadStatus, ForwardOrigin, Message, MessageType};
 use crate::ui::styles::Styles;
-use crate::utils::format_timestamp;
+use crate::utils::{
+    emoji_only, format_absolute_time, format_duration, format_relative_time, format_timestamp,
+    truncate_string, wrap_lines,
+};
+
+/// Most emoji a message may have to be shown enlarged.
+const MAX_BIG_EMOJI: usize = 3;
+
+/// Rows an enlarged emoji message takes: the emoji with a blank row above
+/// and below.
+const BIG_EMOJI_ROWS: u16 = 3;
+
+/// Columns text is indented by, lining it up under the sender's name.
+const INDENT: &str = "  ";
+
+/// Columns kept clear to the right of the text.
+const RIGHT_MARGIN: usize = 2;
 
 /// A widget that renders a single message.
 ///
@@ -40,10 +65,24 @@ pub struct MessageWidget<'a> {
     sender_name: String,
     /// Whether this message is currently selected
     is_selected: bool,
+    /// Whether this message is briefly highlighted, after a jump to it
+    is_flashing: bool,
+    /// Whether this message matches a highlight word
+    is_highlighted: bool,
+    /// Whether this message follows on from the sender's last one, and
+    /// goes without a header
+    is_continued: bool,
     /// Whether to show the timestamp
     show_timestamp: bool,
     /// Available width for rendering
     width: u16,
+    /// Widest the message may be drawn, or 0 for the full width
+    max_width: u16,
+    /// Looks up other users named in group events, and where forwards
+    /// came from
+    name_of: Option<&'a dyn Fn(i64) -> String>,
+    /// Whether emoji-only messages are enlarged
+    big_emoji: bool,
 }
 
 impl<'a> MessageWidget<'a> {
@@ -70,11 +109,25 @@ impl<'a> MessageWidget<'a> {
             message,
             sender_name,
             is_selected: false,
+            is_flashing: false,
+            is_highlighted: false,
+            is_continued: false,
             show_timestamp: true,
             width: 80,
+            max_width: 0,
+            name_of: None,
+            big_emoji: false,
         }
     }
 
+    /// Sets how users named in group events (e.g. "Alice added Bob") and
+    /// the origins of forwards are looked up.
+    #[must_use]
+    pub const fn name_of(mut self, name_of: &'a dyn Fn(i64) -> String) -> Self {
+        self.name_of = Some(name_of);
+        self
+    }
+
     /// Sets whether this message is selected.
     ///
     /// When selected, the message will have a selection marker and
@@ -85,6 +138,31 @@ impl<'a> MessageWidget<'a> {
         self
     }
 
+    /// Sets whether this message is briefly highlighted, to show where a
+    /// jump landed.
+    #[must_use]
+    pub const fn flashing(mut self, flashing: bool) -> Self {
+        self.is_flashing = flashing;
+        self
+    }
+
+    /// Sets whether this message matches a highlight word, which marks it
+    /// with `!` and sets its text apart.
+    #[must_use]
+    pub const fn highlighted(mut self, highlighted: bool) -> Self {
+        self.is_highlighted = highlighted;
+        self
+    }
+
+    /// Sets whether this message follows on from the same sender's last
+    /// one. It then goes without the sender and time, and its marker moves
+    /// to its first line.
+    #[must_use]
+    pub const fn continued(mut self, continued: bool) -> Self {
+        self.is_continued = continued;
+        self
+    }
+
     /// Sets the available width for rendering.
     ///
     /// This affects text wrapping calculations.
@@ -94,6 +172,21 @@ impl<'a> MessageWidget<'a> {
         self
     }
 
+    /// Sets the widest the message may be drawn, however wide the pane;
+    /// 0 uses the full width.
+    #[must_use]
+    pub const fn max_width(mut self, max_width: u16) -> Self {
+        self.max_width = max_width;
+        self
+    }
+
+    /// Sets whether messages of only 1-3 emoji are shown enlarged.
+    #[must_use]
+    pub const fn big_emoji(mut self, big: bool) -> Self {
+        self.big_emoji = big;
+        self
+    }
+
     /// Sets whether to show the timestamp.
     #[must_use]
     #[allow(dead_code)]
@@ -108,6 +201,8 @@ impl<'a> MessageWidget<'a> {
     /// - Header line (sender + timestamp)
     /// - Content lines (with wrapping)
     /// - Optional reply indicator
+    /// - Optional reaction bar
+    /// - Optional download status line
     ///
     /// # Returns
     ///
@@ -115,36 +210,69 @@ impl<'a> MessageWidget<'a> {
     #[must_use]
     #[allow(clippy::cast_possible_truncation)]
     pub fn height(&self) -> u16 {
-        // Header line (sender + timestamp)
-        let mut lines: u16 = 1;
+        // Group events are a single system line
+        if self.message.content.service.is_some() {
+            return 1;
+        }
+
+        // Header line (sender + timestamp), unless following on
+        let header = u16::from(!self.is_continued);
+        let mut lines: u16 = header;
 
         // Content
-        let content = self.get_content_text();
-        let content_width = self.width.saturating_sub(4) as usize; // Account for padding
-        if content_width > 0 && !content.is_empty() {
-            // Count lines in content, accounting for wrapping
-            for line in content.lines() {
-                let line_count = if line.is_empty() {
-                    1
-                } else {
-                    (line.len().saturating_sub(1) / content_width + 1) as u16
-                };
-                lines = lines.saturating_add(line_count);
-            }
-            // If no newlines in content, count as single wrapped block
-            if !content.contains('\n') && content.lines().count() == 1 {
-                lines = 1 + (content.len().saturating_sub(1) / content_width + 1) as u16;
-            }
-        } else {
-            lines = lines.saturating_add(1); // At least one content line
+        if self.big_emoji_text().is_some() {
+            let reply = u16::from(self.message.reply_to_message_id > 0);
+            let forward = u16::from(self.forward_line().is_some());
+            let reactions = u16::from(!self.message.reactions.is_empty());
+            return lines + BIG_EMOJI_ROWS + reply + forward + reactions;
         }
+        let content = self.content_lines().len().clamp(1, usize::from(u16::MAX)) as u16;
+        lines = lines.saturating_add(content);
 
-        // Reply indicator
+        // Reply and forward indicators
         if self.message.reply_to_message_id > 0 {
             lines = lines.saturating_add(1);
         }
+        if self.forward_line().is_some() {
+            lines = lines.saturating_add(1);
+        }
+
+        if !self.message.reactions.is_empty() {
+            lines = lines.saturating_add(1);
+        }
 
-        lines.max(2) // Minimum 2 lines
+        // Download status
+        if self.download_status_line().is_some() {
+            lines = lines.saturating_add(1);
+        }
+
+        lines.max(header + 1) // At least the header and a line of content
+    }
+
+    /// Returns the columns text may take: the width, held to the maximum
+    /// if one is set, less the indent and right margin.
+    fn text_width(&self) -> usize {
+        let width = if self.max_width > 0 {
+            self.width.min(self.max_width)
+        } else {
+            self.width
+        };
+        usize::from(width).saturating_sub(INDENT.len() + RIGHT_MARGIN)
+    }
+
+    /// Returns the message's text wrapped to the text width.
+    fn content_lines(&self) -> Vec<String> {
+        wrap_lines(&self.get_content_text(), self.text_width())
+    }
+
+    /// Returns the message's emoji spaced out for enlarging, if enlarging
+    /// is on and the message is only a few emoji.
+    fn big_emoji_text(&self) -> Option<String> {
+        let content = &self.message.content;
+        if !self.big_emoji || content.content_type != MessageType::Text || content.media.is_some() {
+            return None;
+        }
+        emoji_only(&content.text, MAX_BIG_EMOJI).map(|emoji| emoji.join("  "))
     }
 
     /// Gets the text content to display for this message.
@@ -207,7 +335,7 @@ impl<'a> MessageWidget<'a> {
                 |sticker| format!("[Sticker: {}]", sticker.emoji),
             ),
             MessageType::Animation => "🎞 [GIF]".to_string(),
-            MessageType::Location => "📍 [Location]".to_string(),
+            MessageType::Location => self.location_text(),
             MessageType::Contact => "👤 [Contact]".to_string(),
             MessageType::Poll => self.message.content.poll.as_ref().map_or_else(
                 || "📊 [Poll]".to_string(),
@@ -215,15 +343,181 @@ impl<'a> MessageWidget<'a> {
             ),
             MessageType::Venue => "📍 [Venue]".to_string(),
             MessageType::Game => "🎮 [Game]".to_string(),
+            MessageType::Service => self.service_text(),
+            MessageType::Invoice => format!("🧾 [{}]", self.message.content.caption),
+            MessageType::Giveaway => format!("🎁 [{}]", self.message.content.caption),
+            MessageType::Story => format!("📖 [{}]", self.message.content.caption),
+            MessageType::Call => self.message.content.call.as_ref().map_or_else(
+                || "📞 [Call]".to_string(),
+                |call| {
+                    if call.duration > 0 {
+                        let duration = format_duration(Duration::seconds(i64::from(call.duration)));
+                        format!("📞 {call} ({duration})")
+                    } else {
+                        format!("📞 {call}")
+                    }
+                },
+            ),
+        }
+    }
+
+    /// Describes a group event, naming who acted.
+    fn service_text(&self) -> String {
+        self.message
+            .content
+            .service
+            .as_ref()
+            .map_or_else(String::new, |action| {
+                action.describe(&self.sender_name, |id| {
+                    self.name_of
+                        .map_or_else(|| "someone".to_string(), |f| f(id))
+                })
+            })
+    }
+
+    /// Describes a location, with how fresh a live one is.
+    ///
+    /// Once a live location expires its last position is stale, so only
+    /// the fact that sharing ended is shown.
+    fn location_text(&self) -> String {
+        let Some(location) = &self.message.content.location else {
+            return "📍 [Location]".to_string();
+        };
+        let coords = format!("{:.5}, {:.5}", location.latitude, location.longitude);
+        if !location.is_live() {
+            return format!("📍 [Location] {coords}");
+        }
+        if !self.message.is_live_location_active(Utc::now()) {
+            return "📡 Live location — sharing ended".to_string();
+        }
+
+        let mut text = format!(
+            "📡 Live location — updated {}",
+            format_relative_time(self.message.live_location_updated())
+        );
+        if let Some(expiry) = self.message.live_location_expiry() {
+            text.push_str(&format!(", expires {}", format_relative_time(expiry)));
+        }
+        text.push_str(&format!(" ({coords})"));
+        text
+    }
+
+    /// Builds the line describing an in-progress or failed attachment
+    /// download, if any.
+    ///
+    /// The text is cut to the available width so it stays on one line.
+    fn download_status_line(&self) -> Option<Line<'static>> {
+        let width = self.text_width();
+        let (text, style) = match self.message.content.download_status() {
+            DownloadStatus::Downloading => (
+                "\u{23f3} Downloading\u{2026}".to_string(),
+                Styles::text_muted(),
+            ),
+            DownloadStatus::Failed => {
+                let error = self
+                    .message
+                    .content
+                    .media
+                    .as_ref()
+                    .and_then(|m| m.download_error())
+                    .unwrap_or("unknown error");
+                (
+                    format!("\u{26a0} Download failed ({error}); open again to retry"),
+                    Styles::error(),
+                )
+            },
+            DownloadStatus::NotDownloaded | DownloadStatus::Downloaded => return None,
+        };
+        Some(Line::from(vec![
+            Span::raw("  "),
+            Span::styled(truncate_string(&text, width), style),
+        ]))
+    }
+
+    /// Builds the "Forwarded from" line naming where a forwarded message
+    /// came from and when it was first sent.
+    ///
+    /// The text is cut to the available width so it stays on one line.
+    fn forward_line(&self) -> Option<Line<'static>> {
+        if !self.message.is_forwarded && self.message.forward_info.is_none() {
+            return None;
+        }
+        let width = self.text_width();
+        let text = self.message.forward_info.as_ref().map_or_else(
+            || "\u{21aa} Forwarded".to_string(),
+            |info| {
+                let id = match info.origin {
+                    ForwardOrigin::User => info.from_user_id,
+                    ForwardOrigin::Chat | ForwardOrigin::Channel => info.from_chat_id,
+                    ForwardOrigin::HiddenUser => 0,
+                };
+                let mut origin = match self.name_of {
+                    Some(name_of) if id != 0 => name_of(id),
+                    _ if !info.from_name.is_empty() => info.from_name.clone(),
+                    _ => "a hidden account".to_string(),
+                };
+                if !info.author_signature.is_empty() {
+                    origin = format!("{origin} ({})", info.author_signature);
+                }
+                format!(
+                    "\u{21aa} Forwarded from {origin} \u{b7} {}",
+                    format_absolute_time(info.date)
+                )
+            },
+        );
+        Some(Line::from(vec![
+            Span::raw("  "),
+            Span::styled(truncate_string(&text, width), Styles::text_muted()),
+        ]))
+    }
+
+    /// Builds the reaction bar: each reaction with its count, the user's
+    /// own in the accent color, and premium ones (custom emoji and paid
+    /// stars) flagged with `★`.
+    fn reactions_line(&self) -> Option<Line<'static>> {
+        if self.message.reactions.is_empty() {
+            return None;
+        }
+        let mut spans = vec![Span::raw("  ")];
+        for reaction in &self.message.reactions {
+            let star = if reaction.premium { "\u{2605}" } else { "" };
+            let style = if reaction.chosen {
+                Styles::text_accent()
+            } else {
+                Styles::text_muted()
+            };
+            spans.push(Span::styled(
+                format!("{star}{} {}", reaction.reaction, reaction.count),
+                style,
+            ));
+            spans.push(Span::raw("  "));
         }
+        spans.pop();
+        Some(Line::from(spans))
     }
 
     /// Builds the lines to render for this message.
     fn build_lines(&self) -> Vec<Line<'static>> {
+        // Group events are centered system lines, without a header
+        if self.message.content.service.is_some() {
+            let (text, style) = if self.is_selected {
+                (format!("▶ {} ◀", self.service_text()), Styles::highlight())
+            } else {
+                (self.service_text(), Styles::text_muted())
+            };
+            return vec![Line::from(Span::styled(text, style)).centered()];
+        }
+
         let mut lines = Vec::new();
 
         // Selection indicator
-        let selection_marker = if self.is_selected { "▶ " } else { "  " };
+        let selection_marker = if self.is_selected {
+            "▶ "
+        } else if self.is_highlighted {
+            "! "
+        } else {
+            "  "
+        };
 
         // Header: sender name + timestamp
         let timestamp = if self.show_timestamp {
@@ -238,15 +532,18 @@ impl<'a> MessageWidget<'a> {
             Styles::username()
         };
 
+        let marker = Span::styled(
+            selection_marker.to_string(),
+            if self.is_selected {
+                Styles::highlight()
+            } else if self.is_highlighted {
+                Styles::message_highlight()
+            } else {
+                Styles::text()
+            },
+        );
         let mut header_spans = vec![
-            Span::styled(
-                selection_marker.to_string(),
-                if self.is_selected {
-                    Styles::highlight()
-                } else {
-                    Styles::text()
-                },
-            ),
+            marker.clone(),
             Span::styled(self.sender_name.clone(), header_style),
         ];
 
@@ -255,9 +552,17 @@ impl<'a> MessageWidget<'a> {
             header_spans.push(Span::styled(timestamp, Styles::timestamp()));
         }
 
-        if self.message.is_edited {
+        // Live locations move by editing, which isn't worth flagging
+        if self.message.is_edited && self.message.live_location().is_none() {
             header_spans.push(Span::styled(" (edited)".to_string(), Styles::text_muted()));
         }
+        // Effects are animations a terminal can't play; just say there was one
+        if self.message.effect.is_some() {
+            header_spans.push(Span::styled(
+                " \u{1f386} effect".to_string(),
+                Styles::text_muted(),
+            ));
+        }
 
         lines.push(Line::from(header_spans));
 
@@ -268,36 +573,56 @@ impl<'a> MessageWidget<'a> {
                 Span::styled("↩ Reply to message".to_string(), Styles::text_muted()),
             ]));
         }
+        if let Some(line) = self.forward_line() {
+            lines.push(line);
+        }
 
         // Content
-        let content = self.get_content_text();
-        let content_style = if self.is_selected {
+        let content_style = if self.is_flashing {
+            Styles::highlight()
+        } else if self.is_selected {
             Styles::selected()
+        } else if self.is_highlighted {
+            Styles::message_highlight()
         } else {
             Styles::text()
         };
 
-        if content.is_empty() {
-            lines.push(Line::from(vec![
-                Span::raw("  "),
-                Span::styled(String::new(), content_style),
-            ]));
+        if let Some(emoji) = self.big_emoji_text() {
+            lines.push(Line::default());
+            lines.push(Line::from(Span::styled(emoji, content_style)).centered());
+            lines.push(Line::default());
         } else {
-            for line in content.lines() {
-                lines.push(Line::from(vec![
-                    Span::raw("  "),
-                    Span::styled(line.to_string(), content_style),
-                ]));
+            let mut content = self.content_lines();
+            if content.is_empty() {
+                content.push(String::new());
             }
-            // Handle case where content has no newlines
-            if !content.contains('\n') && content.lines().count() == 0 {
+            for line in content {
                 lines.push(Line::from(vec![
-                    Span::raw("  "),
-                    Span::styled(content, content_style),
+                    Span::raw(INDENT),
+                    Span::styled(line, content_style),
                 ]));
             }
         }
 
+        if let Some(line) = self.reactions_line() {
+            lines.push(line);
+        }
+        if let Some(line) = self.download_status_line() {
+            lines.push(line);
+        }
+
+        // A follow-up drops the header; its marker takes the indent of the
+        // first line left
+        if self.is_continued && lines.len() > 1 {
+            lines.remove(0);
+            let first = &mut lines[0];
+            match first.spans.first_mut() {
+                Some(span) if span.content == INDENT => *span = marker,
+                _ => first.spans.insert(0, marker),
+            }
+        }
+
         lines
     }
 }
@@ -350,6 +675,23 @@ mod tests {
         assert!(widget.is_selected);
     }
 
+    #[test]
+    fn test_highlighted_message_is_marked() {
+        let msg = create_test_message("Deploy failed", false);
+        let lines = MessageWidget::new(&msg, "Bob".to_string())
+            .highlighted(true)
+            .build_lines();
+        assert_eq!(lines[0].spans[0].content, "! ");
+        assert_eq!(lines[1].spans[1].style, Styles::message_highlight());
+
+        // The selection marker wins
+        let lines = MessageWidget::new(&msg, "Bob".to_string())
+            .highlighted(true)
+            .selected(true)
+            .build_lines();
+        assert_eq!(lines[0].spans[0].content, "▶ ");
+    }
+
     #[test]
     fn test_width_setting() {
         let msg = create_test_message("Test", false);
@@ -367,6 +709,41 @@ mod tests {
         assert!(height >= 2); // At least header + content
     }
 
+    #[test]
+    fn test_long_text_wraps_under_the_name_within_the_max_width() {
+        let msg = create_test_message("one two three four five six seven eight nine", false);
+        let widget = MessageWidget::new(&msg, "Dan".to_string())
+            .width(200)
+            .max_width(20);
+        let lines = widget.build_lines();
+        let text: Vec<String> = lines[1..]
+            .iter()
+            .map(|line| line.spans.iter().map(|s| s.content.as_ref()).collect())
+            .collect();
+        assert_eq!(
+            text,
+            ["  one two three", "  four five six", "  seven eight nine"]
+        );
+        assert_eq!(widget.height(), 4);
+
+        // Without a maximum, the pane's width is used
+        let wide = MessageWidget::new(&msg, "Dan".to_string()).width(200);
+        assert_eq!(wide.height(), 2);
+    }
+
+    #[test]
+    fn test_continued_message_drops_the_header_but_keeps_its_marker() {
+        let msg = create_test_message("And another thing", false);
+        let widget = MessageWidget::new(&msg, "Dan".to_string())
+            .continued(true)
+            .selected(true);
+        let lines = widget.build_lines();
+        assert_eq!(lines.len(), 1);
+        assert_eq!(lines[0].spans[0].content, "▶ ");
+        assert_eq!(lines[0].spans[1].content, "And another thing");
+        assert_eq!(widget.height(), 1);
+    }
+
     #[test]
     fn test_height_with_reply() {
         let mut msg = create_test_message("Reply message", false);
@@ -378,6 +755,53 @@ mod tests {
         assert!(height >= 3); // Header + reply indicator + content
     }
 
+    #[test]
+    fn test_forward_names_the_origin_and_its_date() {
+        let mut msg = create_test_message("Big news", false);
+        msg.is_forwarded = true;
+        let plain = MessageWidget::new(&msg, "Ann".to_string()).width(80);
+        let generic = plain.forward_line().unwrap();
+        assert_eq!(generic.spans[1].content, "\u{21aa} Forwarded");
+
+        msg.forward_info = Some(crate::types::ForwardInfo {
+            origin: ForwardOrigin::Channel,
+            from_chat_id: 500,
+            message_id: 77,
+            author_signature: "Editor".to_string(),
+            date: Utc::now(),
+            ..Default::default()
+        });
+        let name_of = |id: i64| format!("Chat {id}");
+        let widget = MessageWidget::new(&msg, "Ann".to_string())
+            .width(80)
+            .name_of(&name_of);
+        let line = widget.forward_line().unwrap();
+        assert!(line.spans[1]
+            .content
+            .starts_with("\u{21aa} Forwarded from Chat 500 (Editor) \u{b7} "));
+        assert_eq!(
+            widget.height(),
+            MessageWidget::new(&create_test_message("Big news", false), "Ann".to_string())
+                .width(80)
+                .height()
+                + 1
+        );
+    }
+
+    #[test]
+    fn test_failed_download_adds_status_line() {
+        let mut msg = create_test_message("", false);
+        msg.content.content_type = MessageType::Document;
+        let before = MessageWidget::new(&msg, "Ann".to_string()).height();
+
+        msg.content
+            .set_download_status(DownloadStatus::Failed, Some("timeout".to_string()));
+        let widget = MessageWidget::new(&msg, "Ann".to_string());
+        assert_eq!(widget.height(), before + 1);
+        let line = widget.download_status_line().unwrap();
+        assert!(line.spans[1].content.contains("timeout"));
+    }
+
     #[test]
     fn test_content_text_for_text_message() {
         let msg = create_test_message("Hello, world!", false);
@@ -481,6 +905,72 @@ mod tests {
         assert!(lines.len() >= 2); // Header + content
     }
 
+    #[test]
+    fn test_live_location_content() {
+        let mut msg = create_test_message("", false);
+        msg.content.content_type = MessageType::Location;
+        msg.content.location = Some(crate::types::Location {
+            latitude: 51.5074,
+            longitude: -0.1278,
+            live_period: 3600,
+            heading: None,
+        });
+        msg.date = Utc::now() - chrono::Duration::minutes(19);
+        msg.edit_date = Some(Utc::now() - chrono::Duration::minutes(2));
+        msg.is_edited = true;
+
+        let widget = MessageWidget::new(&msg, "Liam".to_string());
+        let text = widget.get_content_text();
+        assert!(text.starts_with("📡 Live location — updated 2m ago, expires in 40m"));
+        assert!(text.ends_with("(51.50740, -0.12780)"));
+        let header: String = widget.build_lines()[0]
+            .spans
+            .iter()
+            .map(|s| s.content.as_ref())
+            .collect();
+        assert!(!header.contains("(edited)"));
+
+        // Stale positions aren't shown once sharing ends
+        msg.date = Utc::now() - chrono::Duration::minutes(61);
+        let widget = MessageWidget::new(&msg, "Liam".to_string());
+        assert_eq!(
+            widget.get_content_text(),
+            "📡 Live location — sharing ended"
+        );
+    }
+
+    #[test]
+    fn test_service_message_is_one_centered_line() {
+        let mut msg = create_test_message("", false);
+        msg.content.content_type = MessageType::Service;
+        msg.content.service = Some(crate::types::ServiceAction::MemberRemoved(7));
+        let name_of = |_: i64| "Bob".to_string();
+
+        let widget = MessageWidget::new(&msg, "Alice".to_string()).name_of(&name_of);
+        assert_eq!(widget.height(), 1);
+        let lines = widget.build_lines();
+        assert_eq!(lines.len(), 1);
+        assert_eq!(lines[0].alignment, Some(ratatui::layout::Alignment::Center));
+        assert_eq!(lines[0].to_string(), "Alice removed Bob");
+    }
+
+    #[test]
+    fn test_emoji_only_message_is_enlarged_when_enabled() {
+        let msg = create_test_message("\u{1F389}\u{1F389}", false);
+        let widget = MessageWidget::new(&msg, "Alice".to_string()).big_emoji(true);
+        assert_eq!(widget.height(), 4);
+        let lines = widget.build_lines();
+        assert_eq!(lines[2].alignment, Some(ratatui::layout::Alignment::Center));
+        assert_eq!(lines[2].to_string(), "\u{1F389}  \u{1F389}");
+
+        // Off, or with text alongside, it's an ordinary message
+        let plain = MessageWidget::new(&msg, "Alice".to_string());
+        assert_eq!(plain.build_lines()[1].to_string(), "  \u{1F389}\u{1F389}");
+        let msg = create_test_message("party \u{1F389}", false);
+        let widget = MessageWidget::new(&msg, "Alice".to_string()).big_emoji(true);
+        assert!(widget.big_emoji_text().is_none());
+    }
+
     #[test]
     fn test_build_lines_with_selection() {
         let msg = create_test_message("Selected", false);
@@ -504,4 +994,41 @@ mod tests {
         let first_line_text: String = lines[0].spans.iter().map(|s| s.content.as_ref()).collect();
         assert!(first_line_text.contains("(edited)"));
     }
+
+    #[test]
+    fn test_effect_and_reactions_are_shown() {
+        let mut msg = create_test_message("Happy birthday!", false);
+        msg.effect = Some(5_046_509_860_389_126_442);
+        msg.reactions = vec![
+            crate::types::MessageReaction {
+                reaction: "\u{1f389}".to_string(),
+                count: 3,
+                chosen: true,
+                premium: false,
+            },
+            crate::types::MessageReaction {
+                reaction: "\u{2728}".to_string(),
+                count: 1,
+                chosen: false,
+                premium: true,
+            },
+        ];
+        let plain = create_test_message("Happy birthday!", false);
+
+        let widget = MessageWidget::new(&msg, "Lena".to_string()).width(80);
+        let text: Vec<String> = widget
+            .build_lines()
+            .iter()
+            .map(|l| l.spans.iter().map(|s| s.content.as_ref()).collect())
+            .collect();
+        assert!(text[0].contains("\u{1f386} effect"));
+        assert_eq!(text.last().unwrap(), "  \u{1f389} 3  \u{2605}\u{2728} 1");
+        assert_eq!(
+            widget.height(),
+            MessageWidget::new(&plain, "Lena".to_string())
+                .width(80)
+                .height()
+                + 1
+        );
+    }
 }
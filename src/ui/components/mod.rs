@@ -7,6 +7,7 @@
 //!
 //! - [`InputComponent`]: Text input field with cursor handling
 //! - [`AuthModel`]: Authentication flow UI (phone, code, password)
+//! - [`CountryPicker`]: Country calling codes for the phone number (`Tab`)
 //! - [`ChatItemComponent`]: Single chat entry in the chat list
 //! - [`ChatListModel`]: Chat list pane with selection and search
 //! - [`ConversationModel`]: Conversation view with message list and input
@@ -16,6 +17,31 @@
 //! - [`StatusBar`]: Status bar showing connection and user info
 //! - [`Modal`]: Generic modal dialog for confirmations and alerts
 //! - [`HelpModal`]: Help overlay showing keyboard shortcuts
+//! - [`LockScreen`]: Passphrase lock screen (`Ctrl+L`)
+//! - [`DatePrompt`]: "Jump to date" prompt for the conversation (`Ctrl+G`)
+//! - [`ForwardDialog`]: Comment and hide-sender options for forwarding
+//! - [`PermissionsEditor`]: Group default permissions editor (`/permissions`)
+//! - [`PollView`]: Poll results, voting, and voter lists
+//! - [`ReportDialog`]: Reason picker for reporting a chat or message
+//! - [`QuickSwitcher`]: Fuzzy chat switcher overlay (`Ctrl+K`)
+//! - [`ReactionPicker`]: Reacting to a message, favorites first (`+`)
+//! - [`ReactionsFeed`]: Reactions to the user's messages (`Alt+R`)
+//! - [`Inbox`]: Unread messages from every chat in one stream (`Alt+I`)
+//! - [`BookmarkList`]: Messages bookmarked with `b` (`Alt+B`)
+//! - [`PinBoard`]: The open chat's messages pinned locally with `P`
+//!   (`Alt+P`)
+//! - [`ChatStatsView`]: Statistics from a chat's stored history (`/stats`)
+//! - [`SearchResults`]: Chats, messages and media found by `/find`
+//! - [`Toasts`]: Notices above the status bar, and the error history
+//!   ([`ErrorLog`], `Alt+E`)
+//! - [`QrView`]: A link shown as a QR code (`/qr`)
+//! - [`SendAsPicker`]: Who to post as in a group or channel (`/sendas`)
+//! - [`ChatActions`]: Things to do with the open chat (`Alt+A`)
+//! - [`MemberList`]: A group's or channel's members
+//! - [`StorageManager`]: Local data and its size, for clearing (`/storage`)
+//! - [`ChannelManager`]: Subscribed channels, to mute, archive or leave in
+//!   bulk (`/channels`)
+//! - [`SlashCommand`]: Client-side `/commands` typed into the input
 //!
 //! # Design Pattern
 //!
@@ -25,27 +51,73 @@
 //! - `render()` draws to the terminal (view)
 
 mod auth;
+mod bookmark_list;
+mod channel_manager;
+mod chat_actions;
 mod chat_item;
 mod chat_list;
+mod chat_stats;
 pub mod conversation;
+mod country_picker;
+mod date_prompt;
 mod file_picker;
+mod forward_dialog;
 mod help_modal;
+mod inbox;
 mod input;
+mod lock_screen;
+mod member_list;
 pub mod message;
 mod modal;
+mod permissions_editor;
+mod pin_board;
+mod poll_view;
+mod qr_view;
+mod quick_switcher;
+mod reaction_picker;
+mod reactions_feed;
+mod report_dialog;
+mod search_results;
+mod send_as_picker;
 pub mod settings;
 pub mod sidebar;
+pub mod slash_command;
 mod status_bar;
+mod storage_manager;
+mod toasts;
 
 pub use auth::{AuthAction, AuthModel};
+pub use bookmark_list::{BookmarkList, BookmarkListAction};
+pub use channel_manager::{ChannelManager, ChannelManagerAction};
+pub use chat_actions::{ChatActions, ChatActionsAction, ChatMenuItem};
 pub use chat_item::{ChatItemBuilder, ChatItemComponent, ChatItemConfig};
 pub use chat_list::{ChatListAction, ChatListModel, ChatListState};
+pub use chat_stats::{ChatStats, ChatStatsAction, ChatStatsView};
 pub use conversation::{ConversationAction, ConversationModel, ConversationWidget, InputMode};
+pub use country_picker::{CountryPicker, CountryPickerAction};
+pub use date_prompt::{DatePrompt, DatePromptAction};
 pub use file_picker::{FilePicker, FilePickerAction};
+pub use forward_dialog::{ForwardDialog, ForwardDialogAction, ForwardOptions};
 pub use help_modal::{HelpModal, HelpModalWidget};
+pub use inbox::{Inbox, InboxAction, InboxEntry};
 pub use input::InputComponent;
+pub use lock_screen::{LockScreen, LockScreenAction};
+pub use member_list::{MemberList, MemberListAction};
 pub use message::MessageWidget;
 pub use modal::{Modal, ModalWidget};
+pub use permissions_editor::{PermissionsEditor, PermissionsEditorAction};
+pub use pin_board::{PinBoard, PinBoardAction};
+pub use poll_view::{PollView, PollViewAction};
+pub use qr_view::{QrView, QrViewAction};
+pub use quick_switcher::{QuickSwitcher, QuickSwitcherAction};
+pub use reaction_picker::{ReactionPicker, ReactionPickerAction};
+pub use reactions_feed::{ReactionEntry, ReactionsFeed, ReactionsFeedAction};
+pub use report_dialog::{ReportDialog, ReportDialogAction, ReportTarget};
+pub use search_results::{SearchHit, SearchResults, SearchResultsAction};
+pub use send_as_picker::{SendAsPicker, SendAsPickerAction};
 pub use settings::{SettingsAction, SettingsModel, SettingsSection, SettingsWidget};
 pub use sidebar::{SidebarModel, SidebarWidget};
+pub use slash_command::SlashCommand;
 pub use status_bar::{ConnectionStatus, StatusBar, StatusBarWidget};
+pub use storage_manager::{StorageManager, StorageManagerAction};
+pub use toasts::{ErrorLog, ErrorLogAction, LoggedError, Severity, Toast, Toasts};
@@ -0,0 +1,163 @@
+//! Editor for a group's default member permissions (`/permissions`).
+//!
+//! Changes are staged locally and only sent when the admin applies them.
+
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use crate::types::ChatPermissions;
+use crate::ui::styles::Styles;
+
+/// Result of a key press in the permissions editor.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum PermissionsEditorAction {
+    /// Key was handled; keep the editor open
+    None,
+    /// Close without applying
+    Cancel,
+    /// Apply these permissions (chat ID, permissions)
+    Apply(i64, ChatPermissions),
+}
+
+/// Toggle list of a group's default permissions.
+#[derive(Debug, Clone)]
+pub struct PermissionsEditor {
+    chat_id: i64,
+    /// Chat name, for the title
+    title: String,
+    original: ChatPermissions,
+    permissions: ChatPermissions,
+    selected: usize,
+}
+
+impl PermissionsEditor {
+    /// Creates an editor for `chat_id`, starting from its current
+    /// permissions.
+    #[must_use]
+    pub fn new(chat_id: i64, title: impl Into<String>, permissions: ChatPermissions) -> Self {
+        Self {
+            chat_id,
+            title: title.into(),
+            original: permissions,
+            permissions,
+            selected: 0,
+        }
+    }
+
+    /// Returns the permissions as currently edited.
+    #[must_use]
+    pub const fn permissions(&self) -> ChatPermissions {
+        self.permissions
+    }
+
+    /// Handles a key press.
+    ///
+    /// `↑`/`↓` (or `k`/`j`) move, `Space` toggles, `Enter` applies, and
+    /// `Esc` cancels. Applying without changes just closes the editor.
+    pub fn handle_input(&mut self, key: KeyEvent) -> PermissionsEditorAction {
+        match key.code {
+            KeyCode::Esc => PermissionsEditorAction::Cancel,
+            KeyCode::Enter if self.permissions == self.original => PermissionsEditorAction::Cancel,
+            KeyCode::Enter => PermissionsEditorAction::Apply(self.chat_id, self.permissions),
+            KeyCode::Char(' ') => {
+                self.permissions.toggle(self.selected);
+                PermissionsEditorAction::None
+            },
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                PermissionsEditorAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                self.selected = (self.selected + 1).min(ChatPermissions::COUNT - 1);
+                PermissionsEditorAction::None
+            },
+            _ => PermissionsEditorAction::None,
+        }
+    }
+
+    /// Renders the editor as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 50.min(area.width.saturating_sub(4));
+        let h = 10.min(area.height);
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" Permissions: {} ", self.title),
+                Styles::text_bright(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let mut lines: Vec<Line> = self
+            .permissions
+            .flags()
+            .iter()
+            .enumerate()
+            .map(|(i, (label, allowed))| {
+                let checkbox = if *allowed { "[x]" } else { "[ ]" };
+                let style = if i == self.selected {
+                    Styles::selected()
+                } else {
+                    Styles::text()
+                };
+                Line::from(Span::styled(format!("{checkbox} {label}"), style))
+            })
+            .collect();
+        lines.push(Line::from(""));
+        lines.push(Line::from(Span::styled(
+            "Space toggle \u{2022} Enter apply \u{2022} Esc cancel",
+            Styles::text_muted(),
+        )));
+
+        frame.render_widget(Paragraph::new(lines).block(block), modal);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn press(editor: &mut PermissionsEditor, code: KeyCode) -> PermissionsEditorAction {
+        editor.handle_input(KeyEvent::from(code))
+    }
+
+    #[test]
+    fn applies_toggled_flags() {
+        let mut editor = PermissionsEditor::new(7, "Book Club", ChatPermissions::default());
+        press(&mut editor, KeyCode::Down);
+        press(&mut editor, KeyCode::Char(' '));
+
+        let expected = ChatPermissions {
+            send_media: false,
+            ..ChatPermissions::default()
+        };
+        assert_eq!(editor.permissions(), expected);
+        assert_eq!(
+            press(&mut editor, KeyCode::Enter),
+            PermissionsEditorAction::Apply(7, expected)
+        );
+    }
+
+    #[test]
+    fn applying_unchanged_permissions_just_closes() {
+        let mut editor = PermissionsEditor::new(7, "Book Club", ChatPermissions::default());
+        press(&mut editor, KeyCode::Char(' '));
+        press(&mut editor, KeyCode::Char(' '));
+        assert_eq!(
+            press(&mut editor, KeyCode::Enter),
+            PermissionsEditorAction::Cancel
+        );
+    }
+}
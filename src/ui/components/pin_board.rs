@@ -0,0 +1,194 @@
+//! The open chat's local pins (`Alt+P`).
+//!
+//! Messages pinned with `P` are kept on this device, apart from the chat's
+//! Telegram pins, so they work in channels and groups where the user can't
+//! pin. `Enter` selects the message and `d` unpins it.
+
+use chrono::Local;
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, List, ListItem, ListState, Paragraph},
+    Frame,
+};
+
+use crate::app::state::LocalPin;
+use crate::ui::styles::Styles;
+use crate::utils::truncate_string;
+
+/// Result of a key press in the pin board.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum PinBoardAction {
+    /// Key was handled; keep the board open
+    None,
+    /// Close the board
+    Close,
+    /// Select the message (message ID)
+    Jump(i64),
+    /// Unpin the message (message ID)
+    Unpin(i64),
+}
+
+/// One chat's local pins.
+#[derive(Debug, Clone)]
+pub struct PinBoard {
+    chat_id: i64,
+    chat: String,
+    pins: Vec<LocalPin>,
+    selected: usize,
+}
+
+impl PinBoard {
+    /// Creates the board for a chat from its pins, newest pin first.
+    #[must_use]
+    pub const fn new(chat_id: i64, chat: String, pins: Vec<LocalPin>) -> Self {
+        Self {
+            chat_id,
+            chat,
+            pins,
+            selected: 0,
+        }
+    }
+
+    /// Returns the chat whose pins are shown.
+    #[must_use]
+    pub const fn chat_id(&self) -> i64 {
+        self.chat_id
+    }
+
+    /// Replaces the pins, as after one was removed, keeping the highlight
+    /// in place where possible.
+    pub fn set_pins(&mut self, pins: Vec<LocalPin>) {
+        self.pins = pins;
+        self.selected = self.selected.min(self.pins.len().saturating_sub(1));
+    }
+
+    /// Handles a key press.
+    pub fn handle_input(&mut self, key: KeyEvent) -> PinBoardAction {
+        let selected = self.pins.get(self.selected);
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') => PinBoardAction::Close,
+            KeyCode::Enter => {
+                selected.map_or(PinBoardAction::None, |p| PinBoardAction::Jump(p.message_id))
+            },
+            KeyCode::Char('d') | KeyCode::Delete => selected.map_or(PinBoardAction::None, |p| {
+                PinBoardAction::Unpin(p.message_id)
+            }),
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                PinBoardAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                if self.selected + 1 < self.pins.len() {
+                    self.selected += 1;
+                }
+                PinBoardAction::None
+            },
+            _ => PinBoardAction::None,
+        }
+    }
+
+    /// Renders the board as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 76.min(area.width.saturating_sub(4));
+        let h = 20.min(area.height.saturating_sub(2));
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" Pinned locally in {} ({}) ", self.chat, self.pins.len()),
+                Styles::text_bright(),
+            ))
+            .title_bottom(Span::styled(
+                " Enter go to message \u{2022} d unpin \u{2022} Esc close ",
+                Styles::text_muted(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        if self.pins.is_empty() {
+            let empty = Paragraph::new(Span::styled(
+                "Nothing pinned here yet; press P on a message to pin it",
+                Styles::text_muted(),
+            ))
+            .block(block);
+            frame.render_widget(empty, modal);
+            return;
+        }
+
+        let excerpt_width = usize::from(w.saturating_sub(4));
+        let items: Vec<ListItem> = self
+            .pins
+            .iter()
+            .map(|p| {
+                let date = p.date.with_timezone(&Local).format("%Y-%m-%d %H:%M");
+                ListItem::new(vec![
+                    Line::from(vec![
+                        Span::styled(p.sender.clone(), Styles::text_accent()),
+                        Span::styled(format!(" \u{2022} {date}"), Styles::text_muted()),
+                    ]),
+                    Line::from(Span::styled(
+                        format!("  {}", truncate_string(&p.excerpt, excerpt_width)),
+                        Styles::text(),
+                    )),
+                ])
+            })
+            .collect();
+
+        let list = List::new(items)
+            .block(block)
+            .highlight_style(Styles::highlight());
+        let mut state = ListState::default();
+        state.select(Some(self.selected));
+        frame.render_stateful_widget(list, modal, &mut state);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use chrono::Utc;
+
+    fn pin(message_id: i64) -> LocalPin {
+        LocalPin {
+            chat_id: 1,
+            message_id,
+            sender: "Alice".to_string(),
+            excerpt: "Read this first".to_string(),
+            date: Utc::now(),
+            sealed: String::new(),
+        }
+    }
+
+    #[test]
+    fn enter_jumps_and_d_unpins_the_highlighted_message() {
+        let mut board = PinBoard::new(1, "Ops".to_string(), vec![pin(10), pin(20)]);
+        assert_eq!(
+            board.handle_input(KeyEvent::from(KeyCode::Enter)),
+            PinBoardAction::Jump(10)
+        );
+        board.handle_input(KeyEvent::from(KeyCode::Down));
+        assert_eq!(
+            board.handle_input(KeyEvent::from(KeyCode::Char('d'))),
+            PinBoardAction::Unpin(20)
+        );
+
+        board.set_pins(vec![pin(10)]);
+        assert_eq!(
+            board.handle_input(KeyEvent::from(KeyCode::Enter)),
+            PinBoardAction::Jump(10)
+        );
+        board.set_pins(Vec::new());
+        assert_eq!(
+            board.handle_input(KeyEvent::from(KeyCode::Char('d'))),
+            PinBoardAction::None
+        );
+    }
+}
@@ -0,0 +1,432 @@
+//! Poll overlay, opened on a poll message with the open-media key.
+//!
+//! Shows the results and lets the user vote, change or retract their vote,
+//! and, when the poll's voters are public, page through who chose each
+//! option.
+
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use crate::types::{Poll, PollType, PollVoters};
+use crate::ui::styles::Styles;
+
+/// Result of a key press in the poll view.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum PollViewAction {
+    /// Key was handled; keep the view open
+    None,
+    /// Close the view
+    Close,
+    /// Vote for the options with these identifiers; empty retracts
+    Vote(Vec<Vec<u8>>),
+    /// Fetch a page of voters for an option, after `offset`
+    LoadVoters {
+        option: Vec<u8>,
+        offset: Option<String>,
+    },
+}
+
+/// Voters for one option, loaded a page at a time.
+#[derive(Debug, Clone)]
+struct VoterList {
+    /// Index of the option
+    option: usize,
+    names: Vec<String>,
+    total: i32,
+    next_offset: Option<String>,
+    loading: bool,
+    scroll: usize,
+}
+
+/// Poll results and voting overlay.
+#[derive(Debug, Clone)]
+pub struct PollView {
+    chat_id: i64,
+    message_id: i64,
+    poll: Poll,
+    selected: usize,
+    /// Options marked for a multiple-choice vote
+    marked: Vec<usize>,
+    voters: Option<VoterList>,
+}
+
+impl PollView {
+    /// Creates a view of the poll in message `message_id` of `chat_id`.
+    #[must_use]
+    pub fn new(chat_id: i64, message_id: i64, poll: Poll) -> Self {
+        Self {
+            chat_id,
+            message_id,
+            poll,
+            selected: 0,
+            marked: Vec::new(),
+            voters: None,
+        }
+    }
+
+    /// Returns the chat the poll is in.
+    #[must_use]
+    pub const fn chat_id(&self) -> i64 {
+        self.chat_id
+    }
+
+    /// Returns the poll's message ID.
+    #[must_use]
+    pub const fn message_id(&self) -> i64 {
+        self.message_id
+    }
+
+    /// Replaces the poll, e.g. after voting.
+    pub fn set_poll(&mut self, poll: Poll) {
+        self.poll = poll;
+        self.marked.clear();
+    }
+
+    /// Appends a page of voters to the open voter list.
+    pub fn add_voters(&mut self, page: PollVoters) {
+        if let Some(list) = self.voters.as_mut() {
+            list.names
+                .extend(page.users.iter().map(crate::types::User::get_display_name));
+            list.total = page.total;
+            list.next_offset = page.next_offset;
+            list.loading = false;
+        }
+    }
+
+    /// Marks the pending voter page as failed so it can be retried.
+    pub fn voters_failed(&mut self) {
+        if let Some(list) = self.voters.as_mut() {
+            list.loading = false;
+        }
+    }
+
+    /// Handles a key press.
+    ///
+    /// In the results, `↑`/`↓` (or `k`/`j`) pick an option and `Enter`
+    /// votes for it; in multiple-choice polls `Space` marks several first.
+    /// `r` retracts the vote and `v` lists the option's voters when they are
+    /// public. In the voter list, `n` loads the next page and `Esc` goes
+    /// back.
+    pub fn handle_input(&mut self, key: KeyEvent) -> PollViewAction {
+        if self.voters.is_some() {
+            return self.handle_voters_input(key);
+        }
+
+        let count = self.poll.options.len();
+        match key.code {
+            KeyCode::Esc => PollViewAction::Close,
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                PollViewAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                self.selected = (self.selected + 1).min(count.saturating_sub(1));
+                PollViewAction::None
+            },
+            KeyCode::Char(' ') if self.poll.allows_multiple_answers => {
+                if let Some(pos) = self.marked.iter().position(|&i| i == self.selected) {
+                    self.marked.remove(pos);
+                } else {
+                    self.marked.push(self.selected);
+                }
+                PollViewAction::None
+            },
+            KeyCode::Enter if !self.poll.is_closed && count > 0 => {
+                let chosen = if self.marked.is_empty() {
+                    vec![self.selected]
+                } else {
+                    self.marked.clone()
+                };
+                PollViewAction::Vote(
+                    chosen
+                        .iter()
+                        .filter_map(|&i| self.poll.options.get(i))
+                        .map(|o| o.option.clone())
+                        .collect(),
+                )
+            },
+            // Quiz answers are final
+            KeyCode::Char('r')
+                if self.poll.has_voted()
+                    && !self.poll.is_closed
+                    && self.poll.poll_type != PollType::Quiz =>
+            {
+                PollViewAction::Vote(Vec::new())
+            },
+            KeyCode::Char('v') if !self.poll.is_anonymous && count > 0 => {
+                self.voters = Some(VoterList {
+                    option: self.selected,
+                    names: Vec::new(),
+                    total: 0,
+                    next_offset: None,
+                    loading: true,
+                    scroll: 0,
+                });
+                PollViewAction::LoadVoters {
+                    option: self.poll.options[self.selected].option.clone(),
+                    offset: None,
+                }
+            },
+            _ => PollViewAction::None,
+        }
+    }
+
+    fn handle_voters_input(&mut self, key: KeyEvent) -> PollViewAction {
+        let Some(list) = self.voters.as_mut() else {
+            return PollViewAction::None;
+        };
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('v') => {
+                self.voters = None;
+                PollViewAction::None
+            },
+            KeyCode::Up | KeyCode::Char('k') => {
+                list.scroll = list.scroll.saturating_sub(1);
+                PollViewAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                list.scroll = (list.scroll + 1).min(list.names.len().saturating_sub(1));
+                PollViewAction::None
+            },
+            KeyCode::Char('n') | KeyCode::PageDown if !list.loading => {
+                let Some(offset) = list.next_offset.clone() else {
+                    return PollViewAction::None;
+                };
+                list.loading = true;
+                PollViewAction::LoadVoters {
+                    option: self.poll.options[list.option].option.clone(),
+                    offset: Some(offset),
+                }
+            },
+            _ => PollViewAction::None,
+        }
+    }
+
+    /// Renders the view as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 60.min(area.width.saturating_sub(4));
+        let h = 18.min(area.height);
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 4;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" {} ", self.poll.question),
+                Styles::text_bright(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let lines = match &self.voters {
+            Some(list) => self.voter_lines(list, usize::from(h.saturating_sub(5))),
+            None => self.result_lines(),
+        };
+
+        frame.render_widget(Paragraph::new(lines).block(block), modal);
+    }
+
+    fn result_lines(&self) -> Vec<Line<'static>> {
+        let total = self.poll.total_voter_count.max(1);
+        let mut lines: Vec<Line> = self
+            .poll
+            .options
+            .iter()
+            .enumerate()
+            .map(|(i, option)| {
+                let mark = if option.is_chosen {
+                    "\u{2713}"
+                } else if self.marked.contains(&i) {
+                    "*"
+                } else {
+                    " "
+                };
+                let percent = option.voter_count * 100 / total;
+                let style = if i == self.selected {
+                    Styles::selected()
+                } else {
+                    Styles::text()
+                };
+                Line::from(vec![
+                    Span::styled(format!("{mark} {percent:>3}% "), Styles::text_accent()),
+                    Span::styled(option.text.clone(), style),
+                    Span::styled(format!(" ({})", option.voter_count), Styles::text_muted()),
+                ])
+            })
+            .collect();
+
+        let mut status = format!("{} votes", self.poll.total_voter_count);
+        if self.poll.is_anonymous {
+            status.push_str(" \u{2022} anonymous");
+        }
+        if self.poll.is_closed {
+            status.push_str(" \u{2022} closed");
+        }
+        lines.push(Line::from(""));
+        lines.push(Line::from(Span::styled(status, Styles::text_muted())));
+
+        let mut hints = Vec::new();
+        if !self.poll.is_closed {
+            hints.push("Enter vote");
+            if self.poll.allows_multiple_answers {
+                hints.push("Space mark");
+            }
+            if self.poll.has_voted() && self.poll.poll_type != PollType::Quiz {
+                hints.push("r retract");
+            }
+        }
+        if !self.poll.is_anonymous {
+            hints.push("v voters");
+        }
+        hints.push("Esc close");
+        lines.push(Line::from(Span::styled(
+            hints.join(" \u{2022} "),
+            Styles::text_muted(),
+        )));
+        lines
+    }
+
+    fn voter_lines(&self, list: &VoterList, height: usize) -> Vec<Line<'static>> {
+        let option = &self.poll.options[list.option];
+        let mut lines = vec![
+            Line::from(Span::styled(
+                format!("Voted for \"{}\" ({})", option.text, list.total),
+                Styles::text_accent(),
+            )),
+            Line::from(""),
+        ];
+        lines.extend(
+            list.names
+                .iter()
+                .skip(list.scroll)
+                .take(height)
+                .map(|name| Line::from(Span::styled(name.clone(), Styles::text()))),
+        );
+        if list.loading {
+            lines.push(Line::from(Span::styled("Loading...", Styles::text_muted())));
+        }
+
+        let hint = if list.next_offset.is_some() {
+            "n more \u{2022} Esc back"
+        } else {
+            "Esc back"
+        };
+        lines.push(Line::from(""));
+        lines.push(Line::from(Span::styled(hint, Styles::text_muted())));
+        lines
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::types::{PollOption, User};
+
+    fn poll(multiple: bool) -> Poll {
+        let option = |text: &str, id: u8| PollOption {
+            text: text.to_string(),
+            option: vec![id],
+            ..Default::default()
+        };
+        Poll {
+            question: "Lunch?".to_string(),
+            options: vec![option("Pizza", 0), option("Sushi", 1), option("Tacos", 2)],
+            allows_multiple_answers: multiple,
+            ..Default::default()
+        }
+    }
+
+    fn press(view: &mut PollView, code: KeyCode) -> PollViewAction {
+        view.handle_input(KeyEvent::from(code))
+    }
+
+    #[test]
+    fn votes_for_selected_or_marked_options() {
+        let mut view = PollView::new(1, 2, poll(false));
+        press(&mut view, KeyCode::Down);
+        assert_eq!(
+            press(&mut view, KeyCode::Enter),
+            PollViewAction::Vote(vec![vec![1]])
+        );
+
+        let mut view = PollView::new(1, 2, poll(true));
+        press(&mut view, KeyCode::Char(' '));
+        press(&mut view, KeyCode::Down);
+        press(&mut view, KeyCode::Down);
+        press(&mut view, KeyCode::Char(' '));
+        assert_eq!(
+            press(&mut view, KeyCode::Enter),
+            PollViewAction::Vote(vec![vec![0], vec![2]])
+        );
+    }
+
+    #[test]
+    fn retracting_needs_a_vote_and_is_refused_for_quizzes() {
+        let mut view = PollView::new(1, 2, poll(false));
+        assert_eq!(press(&mut view, KeyCode::Char('r')), PollViewAction::None);
+
+        let mut voted = poll(false);
+        voted.apply_vote(&[0]);
+        view.set_poll(voted.clone());
+        assert_eq!(
+            press(&mut view, KeyCode::Char('r')),
+            PollViewAction::Vote(Vec::new())
+        );
+
+        voted.poll_type = PollType::Quiz;
+        view.set_poll(voted);
+        assert_eq!(press(&mut view, KeyCode::Char('r')), PollViewAction::None);
+    }
+
+    #[test]
+    fn pages_through_voters_of_public_polls() {
+        let mut view = PollView::new(1, 2, poll(false));
+        press(&mut view, KeyCode::Down);
+        assert_eq!(
+            press(&mut view, KeyCode::Char('v')),
+            PollViewAction::LoadVoters {
+                option: vec![1],
+                offset: None,
+            }
+        );
+
+        let user = |name: &str| User {
+            first_name: name.to_string(),
+            ..Default::default()
+        };
+        view.add_voters(PollVoters {
+            users: vec![user("Ann"), user("Bob")],
+            total: 3,
+            next_offset: Some("2".to_string()),
+        });
+        assert_eq!(
+            press(&mut view, KeyCode::Char('n')),
+            PollViewAction::LoadVoters {
+                option: vec![1],
+                offset: Some("2".to_string()),
+            }
+        );
+        // No second request while one is pending
+        assert_eq!(press(&mut view, KeyCode::Char('n')), PollViewAction::None);
+
+        assert_eq!(press(&mut view, KeyCode::Esc), PollViewAction::None);
+        assert_eq!(press(&mut view, KeyCode::Esc), PollViewAction::Close);
+    }
+
+    #[test]
+    fn anonymous_polls_hide_voters() {
+        let mut anonymous = poll(false);
+        anonymous.is_anonymous = true;
+        let mut view = PollView::new(1, 2, anonymous);
+        assert_eq!(press(&mut view, KeyCode::Char('v')), PollViewAction::None);
+    }
+}
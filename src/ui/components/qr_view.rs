@@ -0,0 +1,152 @@
+//! A link shown as a QR code (`/qr`), for moving it to a phone.
+//!
+//! The code is drawn with Unicode half blocks; `a` switches to plain ASCII
+//! for fonts without them.
+
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use crate::ui::styles::Styles;
+use crate::utils::QrCode;
+
+/// Result of a key press in the QR view.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum QrViewAction {
+    /// Key was handled; keep the view open
+    None,
+    /// Close the view
+    Close,
+}
+
+/// A link and its QR code.
+#[derive(Debug, Clone)]
+pub struct QrView {
+    /// What the link is, for the title
+    title: String,
+    link: String,
+    code: QrCode,
+    /// Draw with `##` instead of half blocks
+    ascii: bool,
+}
+
+impl QrView {
+    /// Creates the view, or returns `None` if `link` is too long to encode.
+    #[must_use]
+    pub fn new(title: impl Into<String>, link: impl Into<String>) -> Option<Self> {
+        let link = link.into();
+        let code = QrCode::encode(&link)?;
+        Some(Self {
+            title: title.into(),
+            link,
+            code,
+            ascii: false,
+        })
+    }
+
+    /// Returns the encoded link.
+    #[must_use]
+    pub fn link(&self) -> &str {
+        &self.link
+    }
+
+    /// Handles a key press while the view is open.
+    pub fn handle_input(&mut self, key: KeyEvent) -> QrViewAction {
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') | KeyCode::Enter => QrViewAction::Close,
+            KeyCode::Char('a') => {
+                self.ascii = !self.ascii;
+                QrViewAction::None
+            },
+            _ => QrViewAction::None,
+        }
+    }
+
+    /// Renders the code as a centered overlay, or a note asking for a
+    /// larger terminal if it doesn't fit.
+    pub fn render(&self, frame: &mut Frame) {
+        let rows = if self.ascii {
+            self.code.to_ascii()
+        } else {
+            self.code.to_unicode()
+        };
+        let area = frame.area();
+        let code_width = rows.first().map_or(0, |row| row.chars().count());
+
+        // Borders, plus a line for the link under the code
+        let needed_w = u16::try_from(code_width + 2).unwrap_or(u16::MAX);
+        let needed_h = u16::try_from(rows.len() + 3).unwrap_or(u16::MAX);
+        let fits = needed_w <= area.width && needed_h <= area.height;
+
+        let (w, h) = if fits {
+            (needed_w.max(30).min(area.width), needed_h)
+        } else {
+            (60.min(area.width.saturating_sub(4)), 6.min(area.height))
+        };
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 2;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" QR: {} ", self.title),
+                Styles::text_bright(),
+            ))
+            .title_bottom(Span::styled(
+                if self.ascii {
+                    " a half blocks \u{2022} Esc close "
+                } else {
+                    " a ASCII \u{2022} Esc close "
+                },
+                Styles::text_muted(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let link = Line::from(Span::styled(self.link.clone(), Styles::text_accent()));
+        let lines: Vec<Line> = if fits {
+            rows.into_iter()
+                .map(|row| Line::from(Span::styled(row, Styles::qr_code())).centered())
+                .chain(std::iter::once(link.centered()))
+                .collect()
+        } else {
+            vec![
+                Line::from(Span::styled(
+                    format!("Enlarge the terminal to {needed_w}x{needed_h} to show the code"),
+                    Styles::text_muted(),
+                )),
+                link,
+            ]
+        };
+        frame.render_widget(Paragraph::new(lines).block(block), modal);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn long_links_are_refused_and_a_toggles_ascii() {
+        assert!(QrView::new("Link", "x".repeat(300)).is_none());
+
+        let mut view = QrView::new("Link", "https://t.me/durov").unwrap();
+        assert_eq!(view.link(), "https://t.me/durov");
+        assert_eq!(
+            view.handle_input(KeyEvent::from(KeyCode::Char('a'))),
+            QrViewAction::None
+        );
+        assert!(view.ascii);
+        assert_eq!(
+            view.handle_input(KeyEvent::from(KeyCode::Esc)),
+            QrViewAction::Close
+        );
+    }
+}
@@ -0,0 +1,674 @@
+//! Quick-switcher overlay for jumping to any chat (`Ctrl+K`).
+//!
+//! Unlike the chat list's `/` search, the switcher works from any pane and
+//! leaves the chat list untouched. The query is matched fuzzily (as a
+//! subsequence) against each chat's alias, title, and username; better
+//! matches come first, and equally good matches keep the chat list's order,
+//! so pinned and recently active chats win ties.
+//!
+//! A query that is an `@username`, a `t.me` link or a `+` phone number also
+//! offers a last row to message that person, for conversations that aren't
+//! in the chat list yet.
+//!
+//! Once typing pauses on a query of a few letters, the app also asks
+//! Telegram for matching users and public chats ([`QuickSwitcher::pending_search`]).
+//! Those not in the chat list are listed after the local matches and marked
+//! as found by search; picking one resolves it by username first.
+//!
+//! The switcher doubles as the destination picker for forwarding, where it
+//! lists recently used destinations first and lets `Tab` mark several chats.
+
+use std::collections::HashMap;
+use std::time::{Duration, Instant};
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+use ratatui::{
+    layout::{Constraint, Direction, Layout, Rect},
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, List, ListItem, ListState, Paragraph},
+    Frame,
+};
+
+use crate::types::{Chat, PeerHandle};
+use crate::ui::styles::Styles;
+
+/// How long typing has to pause before the query is searched on Telegram.
+const SEARCH_PAUSE: Duration = Duration::from_millis(300);
+
+/// Shortest query, in characters, worth searching on Telegram.
+const MIN_SEARCH_LEN: usize = 3;
+
+/// Result of a key press in the quick switcher.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum QuickSwitcherAction {
+    /// Key was handled; keep the switcher open
+    None,
+    /// Close the switcher without switching
+    Close,
+    /// Open the chat with this ID
+    Open(i64),
+    /// Several chats were marked (multi-select only), in marking order
+    OpenMany(Vec<i64>),
+    /// Find the user or chat with this username or phone number and open it
+    Resolve(PeerHandle),
+}
+
+#[derive(Debug, Clone)]
+struct Entry {
+    chat_id: i64,
+    /// Alias if set, otherwise the chat title
+    name: String,
+    /// Real title, kept for matching when an alias is shown
+    title: String,
+    username: String,
+    is_pinned: bool,
+    unread_count: i32,
+    /// Found by searching Telegram rather than in the chat list; opening
+    /// it resolves `username` first
+    remote: bool,
+}
+
+/// Fuzzy chat switcher overlay.
+#[derive(Debug)]
+pub struct QuickSwitcher {
+    query: String,
+    /// Candidate chats, in chat list order (pinned, then most recent),
+    /// followed by those found by searching Telegram
+    entries: Vec<Entry>,
+    /// Indices into `entries` of the current matches, best first
+    matches: Vec<usize>,
+    /// Username or phone number in the query that no listed chat has,
+    /// offered as a row after the matches
+    handle: Option<PeerHandle>,
+    /// Highlighted row; `matches.len()` is the handle row
+    selected: usize,
+    /// Border title, e.g. " Jump to chat "
+    title: &'static str,
+    /// Whether `Tab` marks chats instead of moving the selection
+    multi_select: bool,
+    /// Marked chat IDs, in marking order
+    marked: Vec<i64>,
+    /// When the query last changed, until it has been handed out to search
+    typed_at: Option<Instant>,
+}
+
+impl QuickSwitcher {
+    /// Creates a switcher over `chats`, which should be in chat list order.
+    #[must_use]
+    pub fn new(chats: &[Chat], aliases: &HashMap<i64, String>) -> Self {
+        let entries = chats
+            .iter()
+            .map(|chat| {
+                let alias = aliases
+                    .get(&chat.id)
+                    .map(|a| a.trim())
+                    .filter(|a| !a.is_empty());
+                Entry {
+                    chat_id: chat.id,
+                    name: alias.unwrap_or(&chat.title).to_string(),
+                    title: chat.title.clone(),
+                    username: chat.username.clone(),
+                    is_pinned: chat.is_pinned,
+                    unread_count: chat.unread_count,
+                    remote: false,
+                }
+            })
+            .collect();
+
+        let mut switcher = Self {
+            query: String::new(),
+            entries,
+            matches: Vec::new(),
+            handle: None,
+            selected: 0,
+            title: " Jump to chat ",
+            multi_select: false,
+            marked: Vec::new(),
+            typed_at: None,
+        };
+        switcher.update_matches();
+        switcher
+    }
+
+    /// Replaces the border title, for reusing the switcher as a chat picker.
+    #[must_use]
+    pub const fn with_title(mut self, title: &'static str) -> Self {
+        self.title = title;
+        self
+    }
+
+    /// Lets `Tab` mark several chats, returned together on `Enter`.
+    ///
+    /// Only listed chats can be picked then, so no handle row is offered.
+    #[must_use]
+    pub fn with_multi_select(mut self) -> Self {
+        self.multi_select = true;
+        self.update_matches();
+        self
+    }
+
+    /// Lists the chats in `recent` first, most recent first, ahead of the
+    /// usual chat list order. IDs not in the list are ignored.
+    #[must_use]
+    pub fn with_recent(mut self, recent: &[i64]) -> Self {
+        let rank = |id: i64| recent.iter().position(|&r| r == id).unwrap_or(usize::MAX);
+        // Stable: chats that aren't recent keep their order
+        self.entries.sort_by_key(|e| rank(e.chat_id));
+        self.update_matches();
+        self
+    }
+
+    /// Returns the marked chat IDs, in marking order.
+    #[must_use]
+    pub fn marked(&self) -> &[i64] {
+        &self.marked
+    }
+
+    /// Returns the current query.
+    #[must_use]
+    pub fn query(&self) -> &str {
+        &self.query
+    }
+
+    /// Returns the chat IDs of the current matches, best first, with those
+    /// found by search last.
+    #[must_use]
+    pub fn match_ids(&self) -> Vec<i64> {
+        self.matches
+            .iter()
+            .map(|&i| self.entries[i].chat_id)
+            .collect()
+    }
+
+    /// Returns the highlighted chat ID, if any chat matches.
+    #[must_use]
+    pub fn selected_chat_id(&self) -> Option<i64> {
+        self.selected_entry().map(|e| e.chat_id)
+    }
+
+    fn selected_entry(&self) -> Option<&Entry> {
+        self.matches.get(self.selected).map(|&i| &self.entries[i])
+    }
+
+    /// Returns the query to search Telegram for, once typing has paused
+    /// for [`SEARCH_PAUSE`] by `now` on a query long enough to search.
+    /// Each query is handed out once.
+    pub fn pending_search(&mut self, now: Instant) -> Option<String> {
+        let typed_at = self.typed_at?;
+        if now.saturating_duration_since(typed_at) < SEARCH_PAUSE {
+            return None;
+        }
+        self.typed_at = None;
+        let query = search_term(&self.query);
+        (query.chars().count() >= MIN_SEARCH_LEN).then(|| query.to_string())
+    }
+
+    /// Adds the chats Telegram found for `query`, skipping those already
+    /// listed and those without a username to resolve them by. Results for
+    /// a query that has since changed are dropped.
+    pub fn set_remote(&mut self, query: &str, chats: Vec<Chat>) {
+        if search_term(&self.query) != query {
+            return;
+        }
+        let selected = self.selected_chat_id();
+        self.entries.retain(|e| !e.remote);
+        for chat in chats {
+            if chat.username.is_empty() || self.entries.iter().any(|e| e.chat_id == chat.id) {
+                continue;
+            }
+            self.entries.push(Entry {
+                chat_id: chat.id,
+                name: chat.title.clone(),
+                title: chat.title,
+                username: chat.username,
+                is_pinned: false,
+                unread_count: 0,
+                remote: true,
+            });
+        }
+        self.update_matches();
+        // Results arriving shouldn't move the highlight off a local chat
+        if let Some(pos) = selected.and_then(|id| self.match_ids().iter().position(|&m| m == id)) {
+            self.selected = pos;
+        }
+    }
+
+    /// Handles a key press.
+    pub fn handle_input(&mut self, key: KeyEvent) -> QuickSwitcherAction {
+        let ctrl = key.modifiers.contains(KeyModifiers::CONTROL);
+        match key.code {
+            KeyCode::Esc => QuickSwitcherAction::Close,
+            // Ctrl+K again toggles the switcher closed
+            KeyCode::Char('k') if ctrl => QuickSwitcherAction::Close,
+            KeyCode::Enter if !self.marked.is_empty() => {
+                QuickSwitcherAction::OpenMany(self.marked.clone())
+            },
+            KeyCode::Enter => match (self.selected_entry(), &self.handle) {
+                (Some(e), _) if e.remote => {
+                    QuickSwitcherAction::Resolve(PeerHandle::Username(e.username.clone()))
+                },
+                (Some(e), _) => QuickSwitcherAction::Open(e.chat_id),
+                (None, Some(handle)) => QuickSwitcherAction::Resolve(handle.clone()),
+                (None, None) => QuickSwitcherAction::None,
+            },
+            KeyCode::Tab if self.multi_select => {
+                self.toggle_mark();
+                self.select_next();
+                QuickSwitcherAction::None
+            },
+            KeyCode::Up => {
+                self.select_previous();
+                QuickSwitcherAction::None
+            },
+            KeyCode::Char('p') if ctrl => {
+                self.select_previous();
+                QuickSwitcherAction::None
+            },
+            KeyCode::Down | KeyCode::Tab => {
+                self.select_next();
+                QuickSwitcherAction::None
+            },
+            KeyCode::Char('n') if ctrl => {
+                self.select_next();
+                QuickSwitcherAction::None
+            },
+            KeyCode::Backspace => {
+                if self.query.pop().is_some() {
+                    self.query_changed();
+                }
+                QuickSwitcherAction::None
+            },
+            KeyCode::Char('u') if ctrl => {
+                self.query.clear();
+                self.query_changed();
+                QuickSwitcherAction::None
+            },
+            KeyCode::Char(c) if !ctrl => {
+                self.query.push(c);
+                self.query_changed();
+                QuickSwitcherAction::None
+            },
+            _ => QuickSwitcherAction::None,
+        }
+    }
+
+    fn query_changed(&mut self) {
+        self.typed_at = Some(Instant::now());
+        self.update_matches();
+    }
+
+    /// Marks or unmarks the highlighted chat. Chats found by search have to
+    /// be resolved before they can be forwarded to, so they can't be marked.
+    fn toggle_mark(&mut self) {
+        if let Some(id) = self
+            .selected_entry()
+            .filter(|e| !e.remote)
+            .map(|e| e.chat_id)
+        {
+            if let Some(pos) = self.marked.iter().position(|&m| m == id) {
+                self.marked.remove(pos);
+            } else {
+                self.marked.push(id);
+            }
+        }
+    }
+
+    fn select_previous(&mut self) {
+        self.selected = self.selected.saturating_sub(1);
+    }
+
+    fn select_next(&mut self) {
+        let rows = self.matches.len() + usize::from(self.handle.is_some());
+        if rows > 0 {
+            self.selected = (self.selected + 1).min(rows - 1);
+        }
+    }
+
+    /// Re-scores every entry against the query and resets the selection.
+    fn update_matches(&mut self) {
+        let query = search_term(&self.query);
+        let mut scored: Vec<(bool, i32, usize)> = self
+            .entries
+            .iter()
+            .enumerate()
+            .filter_map(|(i, e)| {
+                [&e.name, &e.title, &e.username]
+                    .into_iter()
+                    .filter_map(|field| fuzzy_score(query, field))
+                    .max()
+                    .map(|score| (e.remote, score, i))
+            })
+            .collect();
+        // Stable sort keeps chat list order (pins, then recency) among ties,
+        // and search results after the chat list's own
+        scored.sort_by_key(|&(remote, score, _)| (remote, std::cmp::Reverse(score)));
+        self.matches = scored.into_iter().map(|(_, _, i)| i).collect();
+        self.handle = PeerHandle::parse(&self.query)
+            .filter(|_| !self.multi_select)
+            .filter(|handle| match handle {
+                PeerHandle::Username(name) => !self
+                    .entries
+                    .iter()
+                    .any(|e| e.username.eq_ignore_ascii_case(name)),
+                PeerHandle::Phone(_) => true,
+            });
+        self.selected = 0;
+    }
+
+    /// Renders the switcher as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 60.min(area.width.saturating_sub(4));
+        let h = 16.min(area.height.saturating_sub(4));
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let title = if self.marked.is_empty() {
+            self.title.to_string()
+        } else {
+            format!("{}({} marked) ", self.title, self.marked.len())
+        };
+        let block = Block::default()
+            .title(Span::styled(title, Styles::text_bright()))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+        let inner = block.inner(modal);
+        frame.render_widget(block, modal);
+
+        let chunks = Layout::default()
+            .direction(Direction::Vertical)
+            .constraints([Constraint::Length(2), Constraint::Min(0)])
+            .split(inner);
+
+        let mut prompt_spans = vec![
+            Span::styled("> ", Styles::text_accent()),
+            Span::styled(self.query.as_str(), Styles::text()),
+            Span::styled("\u{2588}", Styles::input_cursor()),
+        ];
+        if self.multi_select {
+            prompt_spans.push(Span::styled("  Tab mark", Styles::text_muted()));
+        }
+        let prompt = Paragraph::new(Line::from(prompt_spans));
+        frame.render_widget(prompt, chunks[0]);
+
+        if self.matches.is_empty() && self.handle.is_none() {
+            let empty = Paragraph::new(Span::styled("No matching chats", Styles::text_muted()));
+            frame.render_widget(empty, chunks[1]);
+            return;
+        }
+
+        let mut items: Vec<ListItem> = self
+            .matches
+            .iter()
+            .map(|&i| {
+                let e = &self.entries[i];
+                let mut spans = Vec::with_capacity(5);
+                if self.multi_select {
+                    let mark = if self.marked.contains(&e.chat_id) {
+                        "[x] "
+                    } else {
+                        "[ ] "
+                    };
+                    spans.push(Span::styled(mark, Styles::text_accent()));
+                }
+                if e.is_pinned {
+                    spans.push(Span::styled("\u{1f4cc} ", Styles::chat_pinned()));
+                }
+                spans.push(Span::styled(e.name.clone(), Styles::text()));
+                if !e.username.is_empty() {
+                    spans.push(Span::styled(
+                        format!("  @{}", e.username),
+                        Styles::text_muted(),
+                    ));
+                }
+                if e.remote {
+                    spans.push(Span::styled("  \u{2022} search", Styles::text_muted()));
+                }
+                if e.unread_count > 0 {
+                    spans.push(Span::styled(
+                        format!("  ({})", e.unread_count),
+                        Styles::chat_unread(),
+                    ));
+                }
+                ListItem::new(Line::from(spans))
+            })
+            .collect();
+        if let Some(handle) = &self.handle {
+            items.push(ListItem::new(Line::from(vec![
+                Span::styled("\u{2709} ", Styles::text_accent()),
+                Span::styled(format!("Message {handle}"), Styles::text()),
+            ])));
+        }
+
+        let list = List::new(items).highlight_style(Styles::highlight());
+        let mut state = ListState::default();
+        state.select(Some(self.selected));
+        frame.render_stateful_widget(list, chunks[1], &mut state);
+    }
+}
+
+/// Returns the part of a query that is matched against chats: trimmed and
+/// without a leading `@`.
+fn search_term(query: &str) -> &str {
+    query.trim().trim_start_matches('@')
+}
+
+/// Scores `haystack` against `needle` as a case-insensitive subsequence.
+///
+/// Returns `None` if the characters of `needle` do not all appear in order.
+/// Consecutive runs, word starts, and a matching prefix score higher. An
+/// empty needle matches everything with a score of 0.
+#[must_use]
+pub fn fuzzy_score(needle: &str, haystack: &str) -> Option<i32> {
+    if needle.is_empty() {
+        return Some(0);
+    }
+
+    let hay: Vec<char> = haystack.to_lowercase().chars().collect();
+    let mut score = 0;
+    let mut pos = 0;
+    let mut prev: Option<usize> = None;
+
+    for n in needle.to_lowercase().chars() {
+        let found = hay[pos..].iter().position(|&h| h == n)? + pos;
+        score += 1;
+        if prev.is_some_and(|p| p + 1 == found) {
+            score += 4;
+        }
+        if found == 0 || matches!(hay[found - 1], ' ' | '_' | '-' | '.') {
+            score += 3;
+        }
+        prev = Some(found);
+        pos = found + 1;
+    }
+
+    if hay
+        .iter()
+        .collect::<String>()
+        .starts_with(&needle.to_lowercase())
+    {
+        score += 5;
+    }
+    Some(score)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn chat(id: i64, title: &str, username: &str) -> Chat {
+        Chat {
+            id,
+            title: title.to_string(),
+            username: username.to_string(),
+            ..Default::default()
+        }
+    }
+
+    fn type_str(switcher: &mut QuickSwitcher, text: &str) {
+        for c in text.chars() {
+            switcher.handle_input(KeyEvent::from(KeyCode::Char(c)));
+        }
+    }
+
+    #[test]
+    fn fuzzy_score_requires_subsequence() {
+        assert!(fuzzy_score("tn", "Tokyo Night").is_some());
+        assert!(fuzzy_score("nt", "Tokyo").is_none());
+        assert_eq!(fuzzy_score("", "anything"), Some(0));
+    }
+
+    #[test]
+    fn fuzzy_score_prefers_contiguous_word_starts() {
+        let tight = fuzzy_score("work", "Work Team").unwrap();
+        let loose = fuzzy_score("work", "Wild orchid park").unwrap();
+        assert!(tight > loose);
+    }
+
+    #[test]
+    fn empty_query_keeps_list_order() {
+        let chats = [chat(1, "Pinned", ""), chat(2, "Recent", "")];
+        let switcher = QuickSwitcher::new(&chats, &HashMap::new());
+        assert_eq!(switcher.match_ids(), vec![1, 2]);
+    }
+
+    #[test]
+    fn ranks_better_matches_first_and_breaks_ties_by_order() {
+        let chats = [
+            chat(1, "Family", ""),
+            chat(2, "Dev Ops", "devops_team"),
+            chat(3, "Dave", ""),
+            chat(4, "Devon", ""),
+        ];
+        let mut switcher = QuickSwitcher::new(&chats, &HashMap::new());
+        type_str(&mut switcher, "dev");
+        assert_eq!(switcher.match_ids(), vec![2, 4]);
+
+        // Backspace widens the match again
+        switcher.handle_input(KeyEvent::from(KeyCode::Backspace));
+        assert_eq!(switcher.query(), "de");
+        assert!(switcher.match_ids().contains(&3));
+    }
+
+    #[test]
+    fn matches_alias_and_username() {
+        let chats = [chat(1, "Jane Smith", "jsmith"), chat(2, "Other", "")];
+        let aliases = HashMap::from([(1, "Boss".to_string())]);
+        let mut switcher = QuickSwitcher::new(&chats, &aliases);
+        type_str(&mut switcher, "boss");
+        assert_eq!(switcher.selected_chat_id(), Some(1));
+
+        let mut switcher = QuickSwitcher::new(&chats, &aliases);
+        type_str(&mut switcher, "@jsm");
+        assert_eq!(switcher.selected_chat_id(), Some(1));
+    }
+
+    #[test]
+    fn enter_opens_selection_and_esc_closes() {
+        let chats = [chat(1, "Alpha", ""), chat(2, "Beta", "")];
+        let mut switcher = QuickSwitcher::new(&chats, &HashMap::new());
+        switcher.handle_input(KeyEvent::from(KeyCode::Down));
+        assert_eq!(
+            switcher.handle_input(KeyEvent::from(KeyCode::Enter)),
+            QuickSwitcherAction::Open(2)
+        );
+        assert_eq!(
+            switcher.handle_input(KeyEvent::from(KeyCode::Esc)),
+            QuickSwitcherAction::Close
+        );
+    }
+
+    #[test]
+    fn offers_to_message_a_handle_no_chat_has() {
+        let chats = [chat(1, "Jane Smith", "jsmith"), chat(2, "Nikolai", "")];
+        let mut switcher = QuickSwitcher::new(&chats, &HashMap::new());
+        type_str(&mut switcher, "@nik_d");
+        assert!(switcher.match_ids().is_empty());
+        assert_eq!(
+            switcher.handle_input(KeyEvent::from(KeyCode::Enter)),
+            QuickSwitcherAction::Resolve(PeerHandle::Username("nik_d".to_string()))
+        );
+
+        // The handle row comes after the chats that match
+        let mut switcher = QuickSwitcher::new(&chats, &HashMap::new());
+        type_str(&mut switcher, "@nikolai");
+        assert_eq!(switcher.match_ids(), vec![2]);
+        switcher.handle_input(KeyEvent::from(KeyCode::Down));
+        assert_eq!(
+            switcher.handle_input(KeyEvent::from(KeyCode::Enter)),
+            QuickSwitcherAction::Resolve(PeerHandle::Username("nikolai".to_string()))
+        );
+
+        // A username already in the list opens that chat instead
+        let mut switcher = QuickSwitcher::new(&chats, &HashMap::new());
+        type_str(&mut switcher, "@jsmith");
+        switcher.handle_input(KeyEvent::from(KeyCode::Down));
+        assert_eq!(
+            switcher.handle_input(KeyEvent::from(KeyCode::Enter)),
+            QuickSwitcherAction::Open(1)
+        );
+    }
+
+    #[test]
+    fn search_results_follow_local_matches_and_resolve_when_picked() {
+        let chats = [chat(1, "Rustaceans", "rust_local"), chat(2, "Other", "")];
+        let mut switcher = QuickSwitcher::new(&chats, &HashMap::new());
+        type_str(&mut switcher, "ru");
+        // Too short to search, however long typing pauses
+        assert_eq!(switcher.pending_search(Instant::now() + SEARCH_PAUSE), None);
+
+        type_str(&mut switcher, "st");
+        assert_eq!(switcher.pending_search(Instant::now()), None);
+        let query = switcher.pending_search(Instant::now() + SEARCH_PAUSE);
+        assert_eq!(query.as_deref(), Some("rust"));
+        // Handed out once
+        assert_eq!(switcher.pending_search(Instant::now() + SEARCH_PAUSE), None);
+
+        // Already listed, no username, and new
+        switcher.set_remote(
+            "rust",
+            vec![
+                chat(1, "Rustaceans", "rust_local"),
+                chat(7, "Rust fans", ""),
+                chat(8, "Rust News", "rustnews"),
+            ],
+        );
+        assert_eq!(switcher.match_ids(), vec![1, 8]);
+        assert_eq!(switcher.selected_chat_id(), Some(1));
+        switcher.handle_input(KeyEvent::from(KeyCode::Down));
+        assert_eq!(
+            switcher.handle_input(KeyEvent::from(KeyCode::Enter)),
+            QuickSwitcherAction::Resolve(PeerHandle::Username("rustnews".to_string()))
+        );
+
+        // Late results for an older query are dropped
+        type_str(&mut switcher, "a");
+        switcher.set_remote("rust", vec![chat(9, "Rust Lang", "rustlang")]);
+        assert_eq!(switcher.match_ids(), vec![1]);
+    }
+
+    #[test]
+    fn recent_chats_come_first() {
+        let chats = [chat(1, "A", ""), chat(2, "B", ""), chat(3, "C", "")];
+        let switcher = QuickSwitcher::new(&chats, &HashMap::new()).with_recent(&[3, 99, 2]);
+        assert_eq!(switcher.match_ids(), vec![3, 2, 1]);
+    }
+
+    #[test]
+    fn multi_select_marks_with_tab() {
+        let chats = [chat(1, "A", ""), chat(2, "B", ""), chat(3, "C", "")];
+        let mut switcher = QuickSwitcher::new(&chats, &HashMap::new()).with_multi_select();
+        switcher.handle_input(KeyEvent::from(KeyCode::Tab));
+        switcher.handle_input(KeyEvent::from(KeyCode::Down));
+        switcher.handle_input(KeyEvent::from(KeyCode::Tab));
+        assert_eq!(switcher.marked(), &[1, 3]);
+        assert_eq!(
+            switcher.handle_input(KeyEvent::from(KeyCode::Enter)),
+            QuickSwitcherAction::OpenMany(vec![1, 3])
+        );
+    }
+}
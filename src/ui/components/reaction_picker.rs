@@ -0,0 +1,337 @@
+//! Picker for reacting to the selected message (`+`).
+//!
+//! The top row holds the reactions pinned as favorites (marked `★`) and
+//! then the most used ones, numbered so `1`-`9` react straight away. Below
+//! it are Telegram's standard reactions. `f` pins or unpins the highlighted
+//! reaction, and picking the reaction already left takes it back.
+
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use crate::ui::styles::Styles;
+
+/// Telegram's standard reactions, offered below the quick row.
+const STANDARD_REACTIONS: [&str; 32] = [
+    "\u{1f44d}",
+    "\u{1f44e}",
+    "\u{2764}",
+    "\u{1f525}",
+    "\u{1f970}",
+    "\u{1f44f}",
+    "\u{1f601}",
+    "\u{1f914}",
+    "\u{1f92f}",
+    "\u{1f631}",
+    "\u{1f92c}",
+    "\u{1f622}",
+    "\u{1f389}",
+    "\u{1f929}",
+    "\u{1f92e}",
+    "\u{1f4a9}",
+    "\u{1f64f}",
+    "\u{1f44c}",
+    "\u{1f54a}",
+    "\u{1f921}",
+    "\u{1f971}",
+    "\u{1f974}",
+    "\u{1f60d}",
+    "\u{1f433}",
+    "\u{1f31a}",
+    "\u{1f4af}",
+    "\u{1f923}",
+    "\u{26a1}",
+    "\u{1f3c6}",
+    "\u{1f494}",
+    "\u{1f440}",
+    "\u{1f917}",
+];
+
+/// Reactions per row of the standard set.
+const COLUMNS: usize = 8;
+
+/// Result of a key press in the reaction picker.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum ReactionPickerAction {
+    /// Key was handled; keep the picker open
+    None,
+    /// Close without reacting
+    Cancel,
+    /// React to the message (chat ID, message ID) with this reaction, or
+    /// take the reaction back if `None`
+    React(i64, i64, Option<String>),
+    /// Pin this reaction to the quick row, or unpin it
+    ToggleFavorite(String),
+}
+
+/// Grid of reactions for one message.
+#[derive(Debug, Clone)]
+pub struct ReactionPicker {
+    chat_id: i64,
+    message_id: i64,
+    /// The reaction the user already left, if any
+    current: Option<String>,
+    /// Favorites, then the most used
+    quick: Vec<String>,
+    favorites: Vec<String>,
+    /// Highlighted row; with a quick row, row 0 is the quick row
+    row: usize,
+    col: usize,
+}
+
+impl ReactionPicker {
+    /// Creates the picker for a message, with the quick row of `quick`
+    /// reactions, of which `favorites` are pinned.
+    #[must_use]
+    pub fn new(
+        chat_id: i64,
+        message_id: i64,
+        current: Option<String>,
+        quick: Vec<String>,
+        favorites: Vec<String>,
+    ) -> Self {
+        Self {
+            chat_id,
+            message_id,
+            current,
+            quick,
+            favorites,
+            row: 0,
+            col: 0,
+        }
+    }
+
+    /// Replaces the quick row, as after pinning a reaction. The highlight
+    /// stays on the same reaction where it can.
+    pub fn set_quick(&mut self, quick: Vec<String>, favorites: Vec<String>) {
+        let selected = self.selected().map(ToString::to_string);
+        let had_quick_row = !self.quick.is_empty();
+        self.quick = quick;
+        self.favorites = favorites;
+        match (had_quick_row, self.quick.is_empty()) {
+            (false, false) => self.row += 1,
+            (true, true) => self.row = self.row.saturating_sub(1),
+            _ => {},
+        }
+        if self.is_quick_row() {
+            self.col = selected
+                .and_then(|s| self.quick.iter().position(|r| *r == s))
+                .unwrap_or(0);
+        }
+        self.col = self.col.min(self.row_len(self.row).saturating_sub(1));
+    }
+
+    /// Returns the highlighted reaction.
+    #[must_use]
+    pub fn selected(&self) -> Option<&str> {
+        if self.is_quick_row() {
+            self.quick.get(self.col).map(String::as_str)
+        } else {
+            let standard_row = self.row - usize::from(!self.quick.is_empty());
+            STANDARD_REACTIONS
+                .get(standard_row * COLUMNS + self.col)
+                .copied()
+        }
+    }
+
+    fn is_quick_row(&self) -> bool {
+        !self.quick.is_empty() && self.row == 0
+    }
+
+    fn rows(&self) -> usize {
+        usize::from(!self.quick.is_empty()) + STANDARD_REACTIONS.len().div_ceil(COLUMNS)
+    }
+
+    fn row_len(&self, row: usize) -> usize {
+        if !self.quick.is_empty() && row == 0 {
+            return self.quick.len();
+        }
+        let standard_row = row - usize::from(!self.quick.is_empty());
+        STANDARD_REACTIONS
+            .len()
+            .saturating_sub(standard_row * COLUMNS)
+            .min(COLUMNS)
+    }
+
+    /// Reacts with `reaction`, or takes it back if it's the one left.
+    fn react(&self, reaction: &str) -> ReactionPickerAction {
+        let reaction = (self.current.as_deref() != Some(reaction)).then(|| reaction.to_string());
+        ReactionPickerAction::React(self.chat_id, self.message_id, reaction)
+    }
+
+    /// Handles a key press.
+    pub fn handle_input(&mut self, key: KeyEvent) -> ReactionPickerAction {
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') => ReactionPickerAction::Cancel,
+            KeyCode::Enter => self
+                .selected()
+                .map_or(ReactionPickerAction::None, |r| self.react(r)),
+            KeyCode::Char(c @ '1'..='9') => {
+                let index = c as usize - '1' as usize;
+                self.quick
+                    .get(index)
+                    .map_or(ReactionPickerAction::None, |r| self.react(r))
+            },
+            KeyCode::Char('f') => self.selected().map_or(ReactionPickerAction::None, |r| {
+                ReactionPickerAction::ToggleFavorite(r.to_string())
+            }),
+            KeyCode::Left | KeyCode::Char('h') => {
+                self.col = self.col.saturating_sub(1);
+                ReactionPickerAction::None
+            },
+            KeyCode::Right | KeyCode::Char('l') => {
+                if self.col + 1 < self.row_len(self.row) {
+                    self.col += 1;
+                }
+                ReactionPickerAction::None
+            },
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.row = self.row.saturating_sub(1);
+                self.col = self.col.min(self.row_len(self.row).saturating_sub(1));
+                ReactionPickerAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                if self.row + 1 < self.rows() {
+                    self.row += 1;
+                    self.col = self.col.min(self.row_len(self.row).saturating_sub(1));
+                }
+                ReactionPickerAction::None
+            },
+            _ => ReactionPickerAction::None,
+        }
+    }
+
+    /// Renders the picker as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 46.min(area.width.saturating_sub(4));
+        #[allow(clippy::cast_possible_truncation)]
+        let rows = self.rows().min(usize::from(u16::MAX)) as u16;
+        let h = (rows + u16::from(!self.quick.is_empty()) + 2).min(area.height);
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(" React ", Styles::text_bright()))
+            .title_bottom(Span::styled(
+                " Enter react \u{2022} 1-9 quick \u{2022} f pin \u{2022} Esc close ",
+                Styles::text_muted(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let cell = |row: usize, col: usize, label: String, reaction: &str| {
+            let style = if row == self.row && col == self.col {
+                Styles::selected()
+            } else if self.current.as_deref() == Some(reaction) {
+                Styles::text_accent()
+            } else {
+                Styles::text()
+            };
+            Span::styled(label, style)
+        };
+
+        let mut lines = Vec::with_capacity(self.rows() + 1);
+        if !self.quick.is_empty() {
+            let spans = self
+                .quick
+                .iter()
+                .enumerate()
+                .map(|(col, reaction)| {
+                    let star = if self.favorites.contains(reaction) {
+                        "\u{2605}"
+                    } else {
+                        ""
+                    };
+                    cell(0, col, format!(" {}{star}{reaction} ", col + 1), reaction)
+                })
+                .collect::<Vec<_>>();
+            lines.push(Line::from(spans));
+            lines.push(Line::from(""));
+        }
+        let first_standard_row = usize::from(!self.quick.is_empty());
+        for (i, chunk) in STANDARD_REACTIONS.chunks(COLUMNS).enumerate() {
+            let row = first_standard_row + i;
+            let spans = chunk
+                .iter()
+                .enumerate()
+                .map(|(col, reaction)| cell(row, col, format!(" {reaction} "), reaction))
+                .collect::<Vec<_>>();
+            lines.push(Line::from(spans));
+        }
+
+        frame.render_widget(Paragraph::new(lines).block(block), modal);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn press(picker: &mut ReactionPicker, code: KeyCode) -> ReactionPickerAction {
+        picker.handle_input(KeyEvent::from(code))
+    }
+
+    #[test]
+    fn digits_pick_from_the_quick_row_and_the_grid_follows() {
+        let quick = vec!["\u{1f3b8}".to_string(), "\u{1f44d}".to_string()];
+        let mut picker = ReactionPicker::new(
+            7,
+            3,
+            Some("\u{1f44d}".to_string()),
+            quick,
+            vec!["\u{1f3b8}".to_string()],
+        );
+        assert_eq!(
+            press(&mut picker, KeyCode::Char('1')),
+            ReactionPickerAction::React(7, 3, Some("\u{1f3b8}".to_string()))
+        );
+        // Picking the reaction already left takes it back
+        assert_eq!(
+            press(&mut picker, KeyCode::Char('2')),
+            ReactionPickerAction::React(7, 3, None)
+        );
+        assert_eq!(
+            press(&mut picker, KeyCode::Char('3')),
+            ReactionPickerAction::None
+        );
+
+        // Down into the standard set, one row further and along
+        press(&mut picker, KeyCode::Down);
+        press(&mut picker, KeyCode::Down);
+        press(&mut picker, KeyCode::Right);
+        assert_eq!(picker.selected(), Some(STANDARD_REACTIONS[COLUMNS + 1]));
+        assert_eq!(
+            press(&mut picker, KeyCode::Char('f')),
+            ReactionPickerAction::ToggleFavorite(STANDARD_REACTIONS[COLUMNS + 1].to_string())
+        );
+    }
+
+    #[test]
+    fn pinning_keeps_the_highlight_on_the_reaction() {
+        let mut picker = ReactionPicker::new(7, 3, None, Vec::new(), Vec::new());
+        press(&mut picker, KeyCode::Right);
+        assert_eq!(picker.selected(), Some(STANDARD_REACTIONS[1]));
+
+        // The first favorite adds the quick row above the grid
+        picker.set_quick(
+            vec![STANDARD_REACTIONS[1].to_string()],
+            vec![STANDARD_REACTIONS[1].to_string()],
+        );
+        assert_eq!(picker.selected(), Some(STANDARD_REACTIONS[1]));
+        press(&mut picker, KeyCode::Up);
+        assert_eq!(picker.selected(), Some(STANDARD_REACTIONS[1]));
+
+        // Unpinning it from the quick row takes the row away again
+        picker.set_quick(Vec::new(), Vec::new());
+        assert_eq!(picker.selected(), Some(STANDARD_REACTIONS[0]));
+    }
+}
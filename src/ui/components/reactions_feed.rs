@@ -0,0 +1,235 @@
+//! Feed of reactions other people left on the user's messages (`Alt+R`).
+//!
+//! Entries arrive from reaction updates while the app runs; the status bar
+//! counts the ones not yet seen. Opening the feed marks everything seen, and
+//! `Enter` jumps to the reacted-to message.
+
+use chrono::Local;
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, List, ListItem, ListState, Paragraph},
+    Frame,
+};
+
+use crate::types::ReactionEvent;
+use crate::ui::styles::Styles;
+
+/// Maximum number of entries kept; older ones are dropped.
+const MAX_ENTRIES: usize = 100;
+
+/// A reaction, with the names needed to show it.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct ReactionEntry {
+    /// The reaction itself
+    pub event: ReactionEvent,
+    /// Who reacted
+    pub who: String,
+    /// Chat the message is in
+    pub chat: String,
+    /// Preview of the reacted-to message (empty if not loaded)
+    pub preview: String,
+}
+
+/// Result of a key press in the reactions feed.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ReactionsFeedAction {
+    /// Key was handled; keep the feed open
+    None,
+    /// Close the feed
+    Close,
+    /// Open the chat and select the message (chat ID, message ID)
+    Jump(i64, i64),
+}
+
+/// Reactions received this session, newest first.
+#[derive(Debug, Clone, Default)]
+pub struct ReactionsFeed {
+    entries: Vec<ReactionEntry>,
+    unseen: usize,
+    selected: usize,
+}
+
+impl ReactionsFeed {
+    /// Creates an empty feed.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Adds a reaction to the top of the feed.
+    ///
+    /// Telegram repeats a message's unread reactions every time any reaction
+    /// on it changes, so a reaction already in the feed is ignored. Returns
+    /// `true` if the entry was added.
+    pub fn push(&mut self, entry: ReactionEntry) -> bool {
+        let e = &entry.event;
+        if self.entries.iter().any(|old| {
+            old.event.chat_id == e.chat_id
+                && old.event.message_id == e.message_id
+                && old.event.sender_id == e.sender_id
+                && old.event.reaction == e.reaction
+        }) {
+            return false;
+        }
+        self.entries.insert(0, entry);
+        self.entries.truncate(MAX_ENTRIES);
+        self.unseen = (self.unseen + 1).min(self.entries.len());
+        true
+    }
+
+    /// Returns the entries, newest first.
+    #[must_use]
+    pub fn entries(&self) -> &[ReactionEntry] {
+        &self.entries
+    }
+
+    /// Returns the number of reactions not yet seen in the feed.
+    #[must_use]
+    pub const fn unseen(&self) -> usize {
+        self.unseen
+    }
+
+    /// Marks every reaction as seen and selects the newest.
+    pub fn mark_seen(&mut self) {
+        self.unseen = 0;
+        self.selected = 0;
+    }
+
+    /// Handles a key press while the feed is open.
+    pub fn handle_input(&mut self, key: KeyEvent) -> ReactionsFeedAction {
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') => ReactionsFeedAction::Close,
+            KeyCode::Enter => self
+                .entries
+                .get(self.selected)
+                .map_or(ReactionsFeedAction::None, |e| {
+                    ReactionsFeedAction::Jump(e.event.chat_id, e.event.message_id)
+                }),
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                ReactionsFeedAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                if self.selected + 1 < self.entries.len() {
+                    self.selected += 1;
+                }
+                ReactionsFeedAction::None
+            },
+            _ => ReactionsFeedAction::None,
+        }
+    }
+
+    /// Renders the feed as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 70.min(area.width.saturating_sub(4));
+        let h = 20.min(area.height.saturating_sub(2));
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(" Reactions ", Styles::text_bright()))
+            .title_bottom(Span::styled(
+                " Enter go to message \u{2022} Esc close ",
+                Styles::text_muted(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        if self.entries.is_empty() {
+            let empty = Paragraph::new(Span::styled(
+                "No reactions to your messages yet",
+                Styles::text_muted(),
+            ))
+            .block(block);
+            frame.render_widget(empty, modal);
+            return;
+        }
+
+        let items: Vec<ListItem> = self
+            .entries
+            .iter()
+            .map(|e| {
+                let time = e.event.date.with_timezone(&Local).format("%H:%M");
+                let mut spans = vec![
+                    Span::styled(format!("{time} "), Styles::text_muted()),
+                    Span::styled(format!("{} ", e.event.reaction), Styles::text()),
+                    Span::styled(e.who.clone(), Styles::text_accent()),
+                    Span::styled(format!(" in {}", e.chat), Styles::text_muted()),
+                ];
+                if !e.preview.is_empty() {
+                    spans.push(Span::styled(format!(": {}", e.preview), Styles::text()));
+                }
+                ListItem::new(Line::from(spans))
+            })
+            .collect();
+
+        let list = List::new(items)
+            .block(block)
+            .highlight_style(Styles::highlight());
+        let mut state = ListState::default();
+        state.select(Some(self.selected));
+        frame.render_stateful_widget(list, modal, &mut state);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn entry(message_id: i64, sender_id: i64, reaction: &str) -> ReactionEntry {
+        ReactionEntry {
+            event: ReactionEvent {
+                chat_id: 1,
+                message_id,
+                sender_id,
+                reaction: reaction.to_string(),
+                ..Default::default()
+            },
+            who: format!("User {sender_id}"),
+            chat: "Chat".to_string(),
+            preview: String::new(),
+        }
+    }
+
+    #[test]
+    fn repeated_reactions_are_ignored() {
+        let mut feed = ReactionsFeed::new();
+        assert!(feed.push(entry(10, 2, "\u{1f44d}")));
+        assert!(!feed.push(entry(10, 2, "\u{1f44d}")));
+        assert!(feed.push(entry(10, 3, "\u{1f44d}")));
+        assert_eq!(feed.entries().len(), 2);
+        assert_eq!(feed.unseen(), 2);
+
+        feed.mark_seen();
+        assert_eq!(feed.unseen(), 0);
+    }
+
+    #[test]
+    fn enter_jumps_to_selected_message() {
+        let mut feed = ReactionsFeed::new();
+        feed.push(entry(10, 2, "\u{1f44d}"));
+        feed.push(entry(11, 2, "\u{1f525}"));
+
+        // Newest first
+        assert_eq!(
+            feed.handle_input(KeyEvent::from(KeyCode::Enter)),
+            ReactionsFeedAction::Jump(1, 11)
+        );
+        feed.handle_input(KeyEvent::from(KeyCode::Down));
+        assert_eq!(
+            feed.handle_input(KeyEvent::from(KeyCode::Enter)),
+            ReactionsFeedAction::Jump(1, 10)
+        );
+        assert_eq!(
+            feed.handle_input(KeyEvent::from(KeyCode::Esc)),
+            ReactionsFeedAction::Close
+        );
+    }
+}
@@ -0,0 +1,199 @@
+//! Reason picker for reporting a chat or message to Telegram.
+//!
+//! The user picks a reason, then confirms; nothing is sent until the
+//! second step, since a report can't be taken back.
+
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use crate::types::ReportReason;
+use crate::ui::styles::Styles;
+
+/// What is being reported.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ReportTarget {
+    /// A whole chat, group, or channel
+    Chat(i64),
+    /// One message (chat ID, message ID)
+    Message(i64, i64),
+}
+
+/// Result of a key press in the report dialog.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ReportDialogAction {
+    /// Key was handled; keep the dialog open
+    None,
+    /// Close without reporting
+    Cancel,
+    /// Send the report
+    Report(ReportTarget, ReportReason),
+}
+
+/// Report reason picker with a confirmation step.
+#[derive(Debug, Clone)]
+pub struct ReportDialog {
+    target: ReportTarget,
+    /// Name of the reported chat or message, for the title
+    label: String,
+    selected: usize,
+    confirming: bool,
+}
+
+impl ReportDialog {
+    /// Creates a dialog for reporting `target`, shown as `label`.
+    #[must_use]
+    pub fn new(target: ReportTarget, label: impl Into<String>) -> Self {
+        Self {
+            target,
+            label: label.into(),
+            selected: 0,
+            confirming: false,
+        }
+    }
+
+    /// Returns the highlighted reason.
+    #[must_use]
+    pub const fn reason(&self) -> ReportReason {
+        ReportReason::ALL[self.selected]
+    }
+
+    /// Handles a key press.
+    ///
+    /// `↑`/`↓` (or `k`/`j`) pick a reason and `Enter` asks for confirmation.
+    /// While confirming, `Enter` or `y` sends the report and `Esc` or `n`
+    /// goes back to the reasons.
+    pub fn handle_input(&mut self, key: KeyEvent) -> ReportDialogAction {
+        if self.confirming {
+            return match key.code {
+                KeyCode::Enter | KeyCode::Char('y') => {
+                    ReportDialogAction::Report(self.target, self.reason())
+                },
+                KeyCode::Esc | KeyCode::Char('n') => {
+                    self.confirming = false;
+                    ReportDialogAction::None
+                },
+                _ => ReportDialogAction::None,
+            };
+        }
+
+        match key.code {
+            KeyCode::Esc => ReportDialogAction::Cancel,
+            KeyCode::Enter => {
+                self.confirming = true;
+                ReportDialogAction::None
+            },
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                ReportDialogAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                self.selected = (self.selected + 1).min(ReportReason::ALL.len() - 1);
+                ReportDialogAction::None
+            },
+            _ => ReportDialogAction::None,
+        }
+    }
+
+    /// Renders the dialog as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 56.min(area.width.saturating_sub(4));
+        let h = 10.min(area.height);
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" Report {} ", self.label),
+                Styles::text_bright(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let lines = if self.confirming {
+            vec![
+                Line::from(""),
+                Line::from(vec![
+                    Span::styled("Report for ", Styles::text()),
+                    Span::styled(self.reason().to_string(), Styles::text_accent()),
+                    Span::styled("?", Styles::text()),
+                ]),
+                Line::from(Span::styled(
+                    "Telegram's moderators will review it.",
+                    Styles::text_muted(),
+                )),
+                Line::from(""),
+                Line::from(Span::styled(
+                    "Enter/y report \u{2022} Esc/n back",
+                    Styles::text_muted(),
+                )),
+            ]
+        } else {
+            let mut lines: Vec<Line> = ReportReason::ALL
+                .iter()
+                .enumerate()
+                .map(|(i, reason)| {
+                    if i == self.selected {
+                        Line::from(Span::styled(format!("> {reason}"), Styles::selected()))
+                    } else {
+                        Line::from(Span::styled(format!("  {reason}"), Styles::text()))
+                    }
+                })
+                .collect();
+            lines.push(Line::from(""));
+            lines.push(Line::from(Span::styled(
+                "\u{2191}/\u{2193} choose \u{2022} Enter continue \u{2022} Esc cancel",
+                Styles::text_muted(),
+            )));
+            lines
+        };
+
+        frame.render_widget(Paragraph::new(lines).block(block), modal);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn press(dialog: &mut ReportDialog, code: KeyCode) -> ReportDialogAction {
+        dialog.handle_input(KeyEvent::from(code))
+    }
+
+    #[test]
+    fn reports_only_after_confirming() {
+        let mut dialog = ReportDialog::new(ReportTarget::Message(7, 42), "message");
+        press(&mut dialog, KeyCode::Down);
+        press(&mut dialog, KeyCode::Down);
+        assert_eq!(dialog.reason(), ReportReason::Fake);
+
+        assert_eq!(press(&mut dialog, KeyCode::Enter), ReportDialogAction::None);
+        assert_eq!(
+            press(&mut dialog, KeyCode::Char('y')),
+            ReportDialogAction::Report(ReportTarget::Message(7, 42), ReportReason::Fake)
+        );
+    }
+
+    #[test]
+    fn backing_out_of_confirmation_returns_to_reasons() {
+        let mut dialog = ReportDialog::new(ReportTarget::Chat(7), "Spammy Channel");
+        press(&mut dialog, KeyCode::Enter);
+        assert_eq!(press(&mut dialog, KeyCode::Esc), ReportDialogAction::None);
+
+        // Selection stays within the list
+        for _ in 0..10 {
+            press(&mut dialog, KeyCode::Down);
+        }
+        assert_eq!(dialog.reason(), ReportReason::Other);
+        assert_eq!(press(&mut dialog, KeyCode::Esc), ReportDialogAction::Cancel);
+    }
+}
@@ -0,0 +1,294 @@
+//! Results of a search across chats (`/find`).
+//!
+//! Results are grouped into chats whose names match, messages, and media,
+//! in one scrolling list. `Enter` opens a chat or jumps to a message.
+
+use chrono::{DateTime, Local, Utc};
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, List, ListItem, ListState, Paragraph},
+    Frame,
+};
+
+use crate::ui::styles::Styles;
+
+/// A message found by a search, with the names needed to show it.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct SearchHit {
+    /// Chat the message is in
+    pub chat_id: i64,
+    /// The message's ID
+    pub message_id: i64,
+    /// Chat name
+    pub chat: String,
+    /// Sender name
+    pub sender: String,
+    /// When the message was sent
+    pub date: DateTime<Utc>,
+    /// One-line preview of the message
+    pub preview: String,
+}
+
+/// Result of a key press in the search results.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum SearchResultsAction {
+    /// Key was handled; keep the results open
+    None,
+    /// Close the results
+    Close,
+    /// Open a chat
+    OpenChat(i64),
+    /// Open a chat at a message (chat ID, message ID)
+    Jump(i64, i64),
+}
+
+/// One selectable row.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum Row {
+    Chat(usize),
+    Message(usize),
+    Media(usize),
+}
+
+/// Search results grouped into chats, messages and media.
+#[derive(Debug, Clone, Default)]
+pub struct SearchResults {
+    /// The query as typed, for the title
+    query: String,
+    /// Matching chats (chat ID, name)
+    chats: Vec<(i64, String)>,
+    messages: Vec<SearchHit>,
+    media: Vec<SearchHit>,
+    /// Why server results are missing, if they are
+    note: Option<String>,
+    selected: usize,
+}
+
+impl SearchResults {
+    /// Creates the results for `query`. Hits should be newest first.
+    #[must_use]
+    pub fn new(
+        query: impl Into<String>,
+        chats: Vec<(i64, String)>,
+        messages: Vec<SearchHit>,
+        media: Vec<SearchHit>,
+    ) -> Self {
+        Self {
+            query: query.into(),
+            chats,
+            messages,
+            media,
+            note: None,
+            selected: 0,
+        }
+    }
+
+    /// Adds a note shown under the results, such as a server error.
+    #[must_use]
+    pub fn with_note(mut self, note: impl Into<String>) -> Self {
+        self.note = Some(note.into());
+        self
+    }
+
+    /// Returns the total number of results.
+    #[must_use]
+    pub fn len(&self) -> usize {
+        self.chats.len() + self.messages.len() + self.media.len()
+    }
+
+    /// Returns `true` if nothing was found.
+    #[must_use]
+    pub fn is_empty(&self) -> bool {
+        self.len() == 0
+    }
+
+    /// Lists the selectable rows in display order.
+    fn rows(&self) -> Vec<Row> {
+        (0..self.chats.len())
+            .map(Row::Chat)
+            .chain((0..self.messages.len()).map(Row::Message))
+            .chain((0..self.media.len()).map(Row::Media))
+            .collect()
+    }
+
+    /// Handles a key press while the results are open.
+    pub fn handle_input(&mut self, key: KeyEvent) -> SearchResultsAction {
+        let rows = self.rows();
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') => SearchResultsAction::Close,
+            KeyCode::Enter => match rows.get(self.selected) {
+                Some(Row::Chat(i)) => SearchResultsAction::OpenChat(self.chats[*i].0),
+                Some(Row::Message(i)) => {
+                    let hit = &self.messages[*i];
+                    SearchResultsAction::Jump(hit.chat_id, hit.message_id)
+                },
+                Some(Row::Media(i)) => {
+                    let hit = &self.media[*i];
+                    SearchResultsAction::Jump(hit.chat_id, hit.message_id)
+                },
+                None => SearchResultsAction::None,
+            },
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                SearchResultsAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                if self.selected + 1 < rows.len() {
+                    self.selected += 1;
+                }
+                SearchResultsAction::None
+            },
+            _ => SearchResultsAction::None,
+        }
+    }
+
+    /// Renders the results as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 90.min(area.width.saturating_sub(4));
+        let h = 28.min(area.height.saturating_sub(2));
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let footer = self.note.as_ref().map_or_else(
+            || " Enter open \u{2022} Esc close ".to_string(),
+            |note| format!(" {note} "),
+        );
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" Search: {} ({}) ", self.query, self.len()),
+                Styles::text_bright(),
+            ))
+            .title_bottom(Span::styled(footer, Styles::text_muted()))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        if self.is_empty() {
+            let empty =
+                Paragraph::new(Span::styled("No results", Styles::text_muted())).block(block);
+            frame.render_widget(empty, modal);
+            return;
+        }
+
+        // Section headings are list items too, so track where the
+        // selection lands among them
+        let mut items: Vec<ListItem<'static>> = Vec::new();
+        let mut selected_item = 0;
+        let mut row = 0;
+        let mut select = |items: &mut Vec<ListItem<'static>>, item: ListItem<'static>| {
+            if row == self.selected {
+                selected_item = items.len();
+            }
+            row += 1;
+            items.push(item);
+        };
+
+        if !self.chats.is_empty() {
+            items.push(heading(&format!("Chats ({})", self.chats.len())));
+            for (_, name) in &self.chats {
+                select(
+                    &mut items,
+                    ListItem::new(Span::styled(format!("  {name}"), Styles::text())),
+                );
+            }
+        }
+        for (title, hits) in [("Messages", &self.messages), ("Media", &self.media)] {
+            if hits.is_empty() {
+                continue;
+            }
+            items.push(heading(&format!("{title} ({})", hits.len())));
+            for hit in hits {
+                select(&mut items, hit_item(hit));
+            }
+        }
+
+        let list = List::new(items)
+            .block(block)
+            .highlight_style(Styles::highlight());
+        let mut state = ListState::default();
+        state.select(Some(selected_item));
+        frame.render_stateful_widget(list, modal, &mut state);
+    }
+}
+
+/// Builds a section heading.
+fn heading(text: &str) -> ListItem<'static> {
+    ListItem::new(Span::styled(text.to_string(), Styles::text_accent()))
+}
+
+/// Builds the row for a message or media hit.
+fn hit_item(hit: &SearchHit) -> ListItem<'static> {
+    let date = hit
+        .date
+        .with_timezone(&Local)
+        .format("%Y-%m-%d")
+        .to_string();
+    let mut spans = vec![
+        Span::styled(format!("  {date} "), Styles::text_muted()),
+        Span::styled(format!("[{}] ", hit.chat), Styles::text_accent()),
+    ];
+    if !hit.sender.is_empty() {
+        spans.push(Span::styled(
+            format!("{}: ", hit.sender),
+            Styles::text_bright(),
+        ));
+    }
+    spans.push(Span::styled(hit.preview.clone(), Styles::text()));
+    ListItem::new(Line::from(spans))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn hit(chat_id: i64, message_id: i64) -> SearchHit {
+        SearchHit {
+            chat_id,
+            message_id,
+            chat: format!("Chat {chat_id}"),
+            sender: String::new(),
+            date: Utc::now(),
+            preview: format!("Message {message_id}"),
+        }
+    }
+
+    fn press(results: &mut SearchResults, code: KeyCode) -> SearchResultsAction {
+        results.handle_input(KeyEvent::from(code))
+    }
+
+    #[test]
+    fn selection_runs_through_every_section() {
+        let mut results = SearchResults::new(
+            "lunch",
+            vec![(5, "Lunch club".to_string())],
+            vec![hit(1, 10)],
+            vec![hit(2, 20)],
+        );
+        assert_eq!(results.len(), 3);
+        assert_eq!(
+            press(&mut results, KeyCode::Enter),
+            SearchResultsAction::OpenChat(5)
+        );
+        press(&mut results, KeyCode::Down);
+        assert_eq!(
+            press(&mut results, KeyCode::Enter),
+            SearchResultsAction::Jump(1, 10)
+        );
+        press(&mut results, KeyCode::Down);
+        press(&mut results, KeyCode::Down);
+        assert_eq!(
+            press(&mut results, KeyCode::Enter),
+            SearchResultsAction::Jump(2, 20)
+        );
+        assert_eq!(
+            press(&mut results, KeyCode::Esc),
+            SearchResultsAction::Close
+        );
+    }
+}
@@ -0,0 +1,167 @@
+//! Picker for who to post as in a group or channel (`/sendas`).
+//!
+//! Telegram lets channel owners post in a linked group as the channel, and
+//! anonymous admins post as the group itself.
+
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use crate::types::SendAsPeer;
+use crate::ui::styles::Styles;
+
+/// Result of a key press in the send-as picker.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum SendAsPickerAction {
+    /// Key was handled; keep the picker open
+    None,
+    /// Close without changing anything
+    Cancel,
+    /// Post as this identity in the chat from now on
+    Choose(i64, SendAsPeer),
+}
+
+/// List of the identities the user can post as in one chat.
+#[derive(Debug, Clone)]
+pub struct SendAsPicker {
+    chat_id: i64,
+    options: Vec<SendAsPeer>,
+    /// ID of the identity in use
+    current: Option<i64>,
+    selected: usize,
+}
+
+impl SendAsPicker {
+    /// Creates the picker with the identity in use (`None` for the user
+    /// themselves) selected.
+    #[must_use]
+    pub fn new(chat_id: i64, options: Vec<SendAsPeer>, current: Option<i64>) -> Self {
+        let selected = options
+            .iter()
+            .position(|o| current.map_or(o.is_self, |id| o.id == id))
+            .unwrap_or(0);
+        Self {
+            chat_id,
+            options,
+            current,
+            selected,
+        }
+    }
+
+    /// Handles a key press.
+    pub fn handle_input(&mut self, key: KeyEvent) -> SendAsPickerAction {
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') => SendAsPickerAction::Cancel,
+            KeyCode::Enter => self
+                .options
+                .get(self.selected)
+                .map_or(SendAsPickerAction::Cancel, |option| {
+                    SendAsPickerAction::Choose(self.chat_id, option.clone())
+                }),
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                SendAsPickerAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                if self.selected + 1 < self.options.len() {
+                    self.selected += 1;
+                }
+                SendAsPickerAction::None
+            },
+            _ => SendAsPickerAction::None,
+        }
+    }
+
+    /// Renders the picker as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 50.min(area.width.saturating_sub(4));
+        #[allow(clippy::cast_possible_truncation)]
+        let rows = self.options.len().min(usize::from(u16::MAX)) as u16;
+        let h = (rows + 4).min(area.height);
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(" Post as ", Styles::text_bright()))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let mut lines: Vec<Line> = self
+            .options
+            .iter()
+            .enumerate()
+            .map(|(i, option)| {
+                let in_use = self.current.map_or(option.is_self, |id| option.id == id);
+                let mut label = if option.is_self {
+                    format!("{} (you)", option.name)
+                } else {
+                    option.name.clone()
+                };
+                if in_use {
+                    label.push_str(" \u{2713}");
+                }
+                let mut spans = if i == self.selected {
+                    vec![Span::styled(format!("> {label}"), Styles::selected())]
+                } else {
+                    vec![Span::styled(format!("  {label}"), Styles::text())]
+                };
+                if option.premium_required {
+                    spans.push(Span::styled("  Premium", Styles::text_muted()));
+                }
+                Line::from(spans)
+            })
+            .collect();
+        lines.push(Line::from(""));
+        lines.push(Line::from(Span::styled(
+            "\u{2191}/\u{2193} choose \u{2022} Enter post as \u{2022} Esc cancel",
+            Styles::text_muted(),
+        )));
+
+        frame.render_widget(Paragraph::new(lines).block(block), modal);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn peer(id: i64, name: &str, is_self: bool) -> SendAsPeer {
+        SendAsPeer {
+            id,
+            name: name.to_string(),
+            is_self,
+            premium_required: false,
+        }
+    }
+
+    #[test]
+    fn starts_on_the_identity_in_use() {
+        let options = vec![peer(1, "Me", true), peer(-5, "My Channel", false)];
+        let mut picker = SendAsPicker::new(7, options.clone(), Some(-5));
+        assert_eq!(
+            picker.handle_input(KeyEvent::from(KeyCode::Enter)),
+            SendAsPickerAction::Choose(7, options[1].clone())
+        );
+
+        let mut picker = SendAsPicker::new(7, options.clone(), None);
+        picker.handle_input(KeyEvent::from(KeyCode::Down));
+        picker.handle_input(KeyEvent::from(KeyCode::Down));
+        assert_eq!(
+            picker.handle_input(KeyEvent::from(KeyCode::Enter)),
+            SendAsPickerAction::Choose(7, options[1].clone())
+        );
+        assert_eq!(
+            picker.handle_input(KeyEvent::from(KeyCode::Esc)),
+            SendAsPickerAction::Cancel
+        );
+    }
+}
@@ -349,6 +349,8 @@ impl SettingsModel {
                 5 => self.config.ui.appearance.show_avatars.to_string(),
                 6 => self.config.ui.appearance.show_status_bar.to_string(),
                 7 => self.config.ui.appearance.relative_timestamps.to_string(),
+                8 => self.config.ui.appearance.big_emoji.to_string(),
+                9 => self.config.ui.appearance.minimap.to_string(),
                 _ => String::new(),
             },
             SettingsSection::Keyboard => match self.selected_item {
@@ -360,6 +362,8 @@ impl SettingsModel {
                 1 => self.config.privacy.show_read_receipts.to_string(),
                 2 => self.config.privacy.show_typing.to_string(),
                 3 => self.config.privacy.stealth_mode.to_string(),
+                4 => self.config.privacy.idle_timeout_secs.to_string(),
+                5 => self.config.privacy.blur_previews.to_string(),
                 _ => String::new(),
             },
             SettingsSection::Credentials => match self.selected_item {
@@ -403,6 +407,8 @@ impl SettingsModel {
                 7 => {
                     self.config.ui.appearance.relative_timestamps = value.to_lowercase() == "true";
                 },
+                8 => self.config.ui.appearance.big_emoji = value.to_lowercase() == "true",
+                9 => self.config.ui.appearance.minimap = value.to_lowercase() == "true",
                 _ => {},
             },
             SettingsSection::Keyboard => {
@@ -415,6 +421,12 @@ impl SettingsModel {
                 1 => self.config.privacy.show_read_receipts = value.to_lowercase() == "true",
                 2 => self.config.privacy.show_typing = value.to_lowercase() == "true",
                 3 => self.config.privacy.stealth_mode = value.to_lowercase() == "true",
+                4 => {
+                    if let Ok(v) = value.parse() {
+                        self.config.privacy.idle_timeout_secs = v;
+                    }
+                },
+                5 => self.config.privacy.blur_previews = value.to_lowercase() == "true",
                 _ => {},
             },
             SettingsSection::Credentials => match self.selected_item {
@@ -474,6 +486,8 @@ impl SettingsModel {
                     "Relative Timestamps",
                     self.config.ui.appearance.relative_timestamps.to_string(),
                 ),
+                ("Big Emoji", self.config.ui.appearance.big_emoji.to_string()),
+                ("Minimap", self.config.ui.appearance.minimap.to_string()),
             ],
             SettingsSection::Keyboard => {
                 vec![("Vim Mode", self.config.ui.keyboard.vim_mode.to_string())]
@@ -489,6 +503,14 @@ impl SettingsModel {
                 ),
                 ("Show Typing", self.config.privacy.show_typing.to_string()),
                 ("Stealth Mode", self.config.privacy.stealth_mode.to_string()),
+                (
+                    "Idle Timeout (s)",
+                    self.config.privacy.idle_timeout_secs.to_string(),
+                ),
+                (
+                    "Blur Previews (Stealth)",
+                    self.config.privacy.blur_previews.to_string(),
+                ),
             ],
             SettingsSection::Credentials => vec![
                 (
@@ -1011,9 +1033,10 @@ mod tests {
         model.selected_item = 0;
 
         let items = model.get_section_items();
-        assert_eq!(items.len(), 4);
+        assert_eq!(items.len(), 6);
         assert_eq!(items[0].0, "Show Online Status");
         assert_eq!(items[3].0, "Stealth Mode");
+        assert_eq!(items[4].0, "Idle Timeout (s)");
     }
 
     #[test]
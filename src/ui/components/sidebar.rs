@@ -5,7 +5,8 @@
 //! - Chat title and type
 //! - User information (for private chats)
 //! - Member counts (for groups/channels)
-//! - Chat settings (pinned, muted, unread count)
+//! - Chat settings (pinned, muted, auto-delete timer, unread count)
+//! - Default member permissions (for groups)
 //!
 //! # Architecture
 //!
@@ -35,6 +36,9 @@ use ratatui::{
 
 use crate::types::{Chat, ChatType, User, UserStatus};
 use crate::ui::styles::Styles;
+use crate::utils::format_auto_delete;
+
+use super::chat_item::avatar;
 
 /// Model for the sidebar (info panel).
 ///
@@ -52,6 +56,8 @@ pub struct SidebarModel {
     pub online_count: Option<i32>,
     /// Chat description/bio
     pub description: Option<String>,
+    /// Local alias for the chat, shown in place of the title
+    pub alias: Option<String>,
 }
 
 impl SidebarModel {
@@ -73,6 +79,7 @@ impl SidebarModel {
             member_count: None,
             online_count: None,
             description: None,
+            alias: None,
         }
     }
 
@@ -92,6 +99,7 @@ impl SidebarModel {
         self.member_count = None;
         self.online_count = None;
         self.description = None;
+        self.alias = None;
     }
 
     /// Sets the group/channel information.
@@ -114,6 +122,13 @@ impl SidebarModel {
         self.description = description;
     }
 
+    /// Sets the local alias for the current chat.
+    ///
+    /// Pass `None` to show the chat's real title only.
+    pub fn set_alias(&mut self, alias: Option<String>) {
+        self.alias = alias;
+    }
+
     /// Clears all sidebar information.
     pub fn clear(&mut self) {
         self.chat = None;
@@ -121,6 +136,7 @@ impl SidebarModel {
         self.member_count = None;
         self.online_count = None;
         self.description = None;
+        self.alias = None;
     }
 
     /// Returns `true` if a chat is currently set.
@@ -139,6 +155,8 @@ pub struct SidebarWidget<'a> {
     model: &'a SidebarModel,
     /// Whether this pane is focused
     is_focused: bool,
+    /// Whether the title starts with an initials avatar
+    show_avatar: bool,
 }
 
 impl<'a> SidebarWidget<'a> {
@@ -152,6 +170,7 @@ impl<'a> SidebarWidget<'a> {
         Self {
             model,
             is_focused: false,
+            show_avatar: false,
         }
     }
 
@@ -162,6 +181,13 @@ impl<'a> SidebarWidget<'a> {
         self
     }
 
+    /// Sets whether the title starts with an initials avatar.
+    #[must_use]
+    pub const fn avatar(mut self, show: bool) -> Self {
+        self.show_avatar = show;
+        self
+    }
+
     /// Builds the lines to display for the current chat.
     fn build_content_lines(&self) -> Vec<Line<'static>> {
         let Some(chat) = self.model.chat.as_ref() else {
@@ -173,11 +199,24 @@ impl<'a> SidebarWidget<'a> {
 
         let mut lines: Vec<Line<'static>> = Vec::new();
 
-        // Title
-        lines.push(Line::from(vec![Span::styled(
-            chat.title.clone(),
-            Styles::highlight(),
-        )]));
+        // Title, after the avatar, with the real name underneath when an
+        // alias is set
+        let mut title = Vec::new();
+        if self.show_avatar {
+            title.push(avatar(chat.id, &chat.title));
+            title.push(Span::raw(" "));
+        }
+        if let Some(ref alias) = self.model.alias {
+            title.push(Span::styled(alias.clone(), Styles::highlight()));
+            lines.push(Line::from(title));
+            lines.push(Line::from(vec![Span::styled(
+                chat.title.clone(),
+                Styles::text_muted(),
+            )]));
+        } else {
+            title.push(Span::styled(chat.title.clone(), Styles::highlight()));
+            lines.push(Line::from(title));
+        }
         lines.push(Line::from("")); // spacer
 
         // Chat type
@@ -246,6 +285,15 @@ impl<'a> SidebarWidget<'a> {
                 Styles::chat_muted(),
             )]));
         }
+        if chat.auto_delete_period > 0 {
+            lines.push(Line::from(vec![Span::styled(
+                format!(
+                    "⏱ Auto-delete: {}",
+                    format_auto_delete(chat.auto_delete_period)
+                ),
+                Styles::text(),
+            )]));
+        }
 
         // Unread count
         if chat.unread_count > 0 {
@@ -255,6 +303,30 @@ impl<'a> SidebarWidget<'a> {
             )]));
         }
 
+        if let Some(permissions) = chat.default_permissions {
+            lines.push(Line::from("")); // spacer
+            lines.push(Line::from(vec![Span::styled(
+                "─── Members can ───",
+                Styles::text_muted(),
+            )]));
+            for (label, allowed) in permissions.flags() {
+                lines.push(if allowed {
+                    Line::from(vec![Span::styled(format!("✓ {label}"), Styles::text())])
+                } else {
+                    Line::from(vec![Span::styled(
+                        format!("✗ {label}"),
+                        Styles::text_muted(),
+                    )])
+                });
+            }
+            if chat.can_edit_permissions {
+                lines.push(Line::from(vec![Span::styled(
+                    "/permissions to edit",
+                    Styles::text_muted(),
+                )]));
+            }
+        }
+
         lines
     }
 
@@ -484,6 +556,21 @@ mod tests {
         assert!(widget.is_focused);
     }
 
+    #[test]
+    fn test_avatar_leads_the_title() {
+        let mut model = SidebarModel::new();
+        model.set_chat(create_test_chat(7, "Ada Lovelace", ChatType::Private), None);
+
+        let lines = SidebarWidget::new(&model)
+            .avatar(true)
+            .build_content_lines();
+        assert_eq!(lines[0].spans[0].content, " AL ");
+        assert_eq!(lines[0].spans[2].content, "Ada Lovelace");
+
+        let lines = SidebarWidget::new(&model).build_content_lines();
+        assert_eq!(lines[0].spans[0].content, "Ada Lovelace");
+    }
+
     #[test]
     fn test_widget_no_chat_shows_placeholder() {
         let model = SidebarModel::new();
@@ -546,6 +633,19 @@ mod tests {
         assert!(lines.len() >= 4);
     }
 
+    #[test]
+    fn test_widget_alias_shows_real_title_below() {
+        let mut model = SidebarModel::new();
+        model.set_chat(create_test_chat(1, "+1 555 0100", ChatType::Private), None);
+        model.set_alias(Some("Plumber".to_string()));
+
+        let widget = SidebarWidget::new(&model);
+        let lines = widget.build_content_lines();
+
+        assert_eq!(lines[0].spans[0].content, "Plumber");
+        assert_eq!(lines[1].spans[0].content, "+1 555 0100");
+    }
+
     #[test]
     fn test_widget_muted_chat() {
         let mut model = SidebarModel::new();
@@ -560,4 +660,40 @@ mod tests {
         // Should include muted indicator in the settings section
         assert!(lines.len() >= 5);
     }
+
+    #[test]
+    fn test_widget_shows_auto_delete_timer() {
+        let mut model = SidebarModel::new();
+        let mut chat = create_test_chat(1, "Ephemeral", ChatType::Private);
+        chat.auto_delete_period = 7 * 86_400;
+        model.set_chat(chat, None);
+
+        let text: Vec<String> = SidebarWidget::new(&model)
+            .build_content_lines()
+            .iter()
+            .map(ToString::to_string)
+            .collect();
+        assert!(text.iter().any(|l| l.contains("Auto-delete: 1 week")));
+    }
+
+    #[test]
+    fn test_widget_shows_group_permissions() {
+        let mut model = SidebarModel::new();
+        let mut chat = create_test_chat(1, "Book Club", ChatType::Group);
+        chat.default_permissions = Some(crate::types::ChatPermissions {
+            send_media: false,
+            ..Default::default()
+        });
+        model.set_chat(chat, None);
+
+        let text: Vec<String> = SidebarWidget::new(&model)
+            .build_content_lines()
+            .iter()
+            .map(ToString::to_string)
+            .collect();
+        assert!(text.iter().any(|l| l == "✓ Send messages"));
+        assert!(text.iter().any(|l| l == "✗ Send media"));
+        // Only admins are pointed at the editor
+        assert!(!text.iter().any(|l| l.contains("/permissions")));
+    }
 }
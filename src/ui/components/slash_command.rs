@@ -0,0 +1,584 @@
+//! Client-side slash commands typed into the message input.
+//!
+//! A draft starting with `/` and one of these names is run as a command
+//! instead of being sent:
+//!
+//! | Command            | Effect                                      |
+//! |--------------------|---------------------------------------------|
+//! | `/goto <chat>`     | Open the best-matching chat                 |
+//! | `/join`            | Join the channel or group being previewed   |
+//! | `/mute [8h]`       | Mute the current chat (forever by default)  |
+//! | `/unmute`          | Unmute the current chat                     |
+//! | `/autodelete [1w]` | Show or set the auto-delete timer           |
+//! | `/permissions`     | Show or edit a group's member permissions   |
+//! | `/report`          | Report the current chat to Telegram         |
+//! | `/search <text>`   | Find messages in the current chat           |
+//! | `/find <query>`    | Search every chat, with `from:`, `has:`...  |
+//! | `/theme <name>`    | Switch the color theme                      |
+//! | `/export`          | Save the loaded messages to a text file     |
+//! | `/download [photo]`| Save the chat's media to a folder           |
+//! | `/listen [next]`   | Play unplayed voice messages in turn        |
+//! | `/stats`           | Show statistics from the stored history     |
+//! | `/qr [me\|chat]`   | Show a link as a QR code                    |
+//! | `/alias [name]`    | Set (or clear) the current chat's alias     |
+//! | `/alert`           | Choose this chat for the bell and flash     |
+//! | `/sendas`          | Choose who to post as in a group or channel |
+//! | `/readall`         | Mark every chat as read, after confirming   |
+//! | `/lock`            | Lock the screen                             |
+//! | `/encrypt`         | Keep this chat's local data encrypted       |
+//! | `/logout`          | Log out of Telegram, after confirming       |
+//! | `/cache`           | Show what the message cache holds           |
+//! | `/storage`         | Show and clear local data                   |
+//! | `/channels`        | Mute, archive or leave channels in bulk     |
+//! | `/dump`            | Save a debug dump (needs `logging.debug`)   |
+//! | `/help`            | List the available commands                 |
+//!
+//! Anything else starting with `/`, such as a bot command (`/start`,
+//! `/help@somebot`) or a path, is sent as typed. A leading `//` escapes the
+//! slash of a command name, so `//help` sends the text `/help`.
+//!
+//! # Example
+//!
+//! ```rust
+//! use ithil::ui::components::slash_command::{parse, SlashCommand};
+//!
+//! assert_eq!(
+//!     parse("/goto alice"),
+//!     Some(Ok(SlashCommand::Goto("alice".to_string())))
+//! );
+//! assert_eq!(parse("hello"), None);
+//! ```
+
+use chrono::Duration;
+
+use crate::ui::styles::Theme;
+use crate::utils::parse_duration;
+
+/// Command names, argument hints, and descriptions, in display order.
+pub const COMMANDS: [(&str, &str, &str); 27] = [
+    ("goto", "<chat>", "Open a chat"),
+    ("mute", "[8h|2d|forever]", "Mute the current chat"),
+    ("unmute", "", "Unmute the current chat"),
+    (
+        "autodelete",
+        "[1d|1w|1m|off]",
+        "Show or set the auto-delete timer",
+    ),
+    ("permissions", "", "Show or edit member permissions"),
+    ("report", "", "Report this chat to Telegram"),
+    ("search", "<text>", "Find messages in this chat"),
+    (
+        "find",
+        "<query>",
+        "Search all chats (from: in: has: before: after:)",
+    ),
+    ("theme", "<name>", "Switch color theme"),
+    ("export", "", "Save loaded messages to a file"),
+    (
+        "download",
+        "[photo|video|file] [count] [after:]",
+        "Save this chat's media to a folder",
+    ),
+    (
+        "listen",
+        "[next|stop]",
+        "Play this chat's unplayed voice messages",
+    ),
+    ("stats", "", "Show this chat's statistics"),
+    (
+        "qr",
+        "[me|chat|<link>]",
+        "Show the selected message's link as a QR code",
+    ),
+    ("alias", "[name]", "Set or clear this chat's alias"),
+    ("alert", "", "Ring and flash for this chat (or stop)"),
+    ("sendas", "", "Choose who to post as here"),
+    ("readall", "", "Mark every chat as read"),
+    ("lock", "", "Lock the screen"),
+    (
+        "encrypt",
+        "",
+        "Keep this chat's local data encrypted (or stop)",
+    ),
+    ("logout", "", "Log out of Telegram"),
+    ("cache", "", "Show what the message cache holds"),
+    ("storage", "", "Show and clear local data"),
+    ("channels", "", "Mute, archive or leave channels in bulk"),
+    ("join", "", "Join the channel or group being previewed"),
+    ("dump", "", "Save a debug dump (needs logging.debug)"),
+    ("help", "", "List commands"),
+];
+
+/// Arguments offered when completing `/qr`.
+const QR_SUGGESTIONS: [&str; 2] = ["me", "chat"];
+
+/// Arguments offered when completing `/listen`.
+const LISTEN_SUGGESTIONS: [&str; 2] = ["next", "stop"];
+
+/// Suggested durations offered when completing `/mute`.
+const MUTE_SUGGESTIONS: [&str; 5] = ["1h", "8h", "1d", "1w", "forever"];
+
+/// Auto-delete timers that can be set, with their periods in seconds.
+const AUTO_DELETE_CHOICES: [(&str, i32); 4] = [
+    ("1d", 86_400),
+    ("1w", 7 * 86_400),
+    ("1m", 31 * 86_400),
+    ("off", 0),
+];
+
+/// A parsed slash command.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum SlashCommand {
+    /// Open the chat best matching the query
+    Goto(String),
+    /// Mute the current chat; `None` mutes indefinitely
+    Mute(Option<Duration>),
+    /// Unmute the current chat
+    Unmute,
+    /// Set the current chat's auto-delete period in seconds (0 turns it
+    /// off); `None` shows the current one
+    AutoDelete(Option<i32>),
+    /// Show or edit the current group's default permissions
+    Permissions,
+    /// Report the current chat
+    Report,
+    /// Search messages in the current chat
+    Search(String),
+    /// Search every chat; the query may use filter operators
+    Find(String),
+    /// Switch to the named theme
+    Theme(Theme),
+    /// Export the current chat's loaded messages
+    Export,
+    /// Save the current chat's attachments; the arguments narrow them down
+    /// (see [`crate::ui::bulk_download`])
+    Download(String),
+    /// Play, skip or stop the current chat's unplayed voice messages
+    Listen(ListenCommand),
+    /// Show statistics for the current chat
+    Stats,
+    /// Show a link as a QR code
+    Qr(QrTarget),
+    /// Set the current chat's alias; an empty name clears it
+    Alias(String),
+    /// Add the current chat to those that ring the bell and flash, or take
+    /// it off
+    Alert,
+    /// Choose who to post as in the current chat
+    SendAs,
+    /// Mark every chat as read
+    ReadAll,
+    /// Lock the screen
+    Lock,
+    /// Keep the current chat's bookmarks, local pins and media encrypted on
+    /// disk, or stop
+    Encrypt,
+    /// Log out of Telegram
+    Logout,
+    /// Show message cache metrics
+    Cache,
+    /// Show local data and clear some of it
+    Storage,
+    /// List subscribed channels to mute, archive or leave them
+    Channels,
+    /// Join the channel or group being previewed
+    Join,
+    /// Save the screen and UI state to a file, for bug reports
+    Dump,
+    /// Show the command list
+    Help,
+}
+
+/// What `/listen` does.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ListenCommand {
+    /// Start playing
+    Start,
+    /// Skip to the next voice message
+    Next,
+    /// Stop playing
+    Stop,
+}
+
+/// The link `/qr` shows.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum QrTarget {
+    /// The first link in the selected message
+    Selected,
+    /// The user's own t.me link
+    Me,
+    /// The current chat's public or invite link
+    Chat,
+    /// Text typed after the command
+    Text(String),
+}
+
+/// Returns `true` if `text` starts like a command, for completing it.
+///
+/// Only [`parse`] knows whether it names one.
+#[must_use]
+pub fn is_command(text: &str) -> bool {
+    text.starts_with('/') && !text.starts_with("//")
+}
+
+/// Removes the `//` escape so the text is sent with a single leading slash.
+#[must_use]
+pub fn unescape(text: &str) -> &str {
+    text.strip_prefix('/')
+        .filter(|rest| rest.starts_with('/'))
+        .unwrap_or(text)
+}
+
+/// Parses `text` as a slash command.
+///
+/// Returns `None` if `text` doesn't name a command, so it should be sent as
+/// typed, and `Some(Err(message))` if it does but its argument cannot be
+/// understood.
+#[must_use]
+pub fn parse(text: &str) -> Option<Result<SlashCommand, String>> {
+    if !is_command(text) {
+        return None;
+    }
+
+    let body = text[1..].trim();
+    let (name, arg) = body
+        .split_once(char::is_whitespace)
+        .map_or((body, ""), |(n, a)| (n, a.trim()));
+
+    let result = match name.to_lowercase().as_str() {
+        "goto" | "g" => required(arg, "/goto needs a chat name").map(SlashCommand::Goto),
+        "mute" => parse_mute(arg),
+        "unmute" => Ok(SlashCommand::Unmute),
+        "autodelete" | "ttl" => parse_auto_delete(arg),
+        "permissions" | "perms" => Ok(SlashCommand::Permissions),
+        "report" => Ok(SlashCommand::Report),
+        "search" | "s" => required(arg, "/search needs some text").map(SlashCommand::Search),
+        "find" | "f" => required(arg, "/find needs a query").map(SlashCommand::Find),
+        "theme" => required(arg, "/theme needs a theme name").and_then(|name| {
+            find_theme(&name)
+                .map(SlashCommand::Theme)
+                .ok_or_else(|| format!("Unknown theme: {name}"))
+        }),
+        "export" => Ok(SlashCommand::Export),
+        "download" | "dl" => Ok(SlashCommand::Download(arg.to_string())),
+        "listen" => match arg.to_lowercase().as_str() {
+            "" | "start" => Ok(SlashCommand::Listen(ListenCommand::Start)),
+            "next" | "skip" => Ok(SlashCommand::Listen(ListenCommand::Next)),
+            "stop" => Ok(SlashCommand::Listen(ListenCommand::Stop)),
+            _ => Err(format!("/listen takes next or stop, not \"{arg}\"")),
+        },
+        "stats" => Ok(SlashCommand::Stats),
+        "qr" => Ok(SlashCommand::Qr(match arg.to_lowercase().as_str() {
+            "" => QrTarget::Selected,
+            "me" => QrTarget::Me,
+            "chat" => QrTarget::Chat,
+            _ => QrTarget::Text(arg.to_string()),
+        })),
+        "alias" => Ok(SlashCommand::Alias(arg.to_string())),
+        "alert" => Ok(SlashCommand::Alert),
+        "sendas" | "as" => Ok(SlashCommand::SendAs),
+        "readall" => Ok(SlashCommand::ReadAll),
+        "lock" => Ok(SlashCommand::Lock),
+        "encrypt" => Ok(SlashCommand::Encrypt),
+        "logout" => Ok(SlashCommand::Logout),
+        "cache" => Ok(SlashCommand::Cache),
+        "storage" => Ok(SlashCommand::Storage),
+        "channels" => Ok(SlashCommand::Channels),
+        "join" => Ok(SlashCommand::Join),
+        "dump" => Ok(SlashCommand::Dump),
+        "help" | "?" => Ok(SlashCommand::Help),
+        // Bot commands, paths and the like
+        _ => return None,
+    };
+    Some(result)
+}
+
+/// Returns the argument, or `message` as an error if it is empty.
+fn required(arg: &str, message: &str) -> Result<String, String> {
+    if arg.is_empty() {
+        Err(message.to_string())
+    } else {
+        Ok(arg.to_string())
+    }
+}
+
+/// Parses the `/mute` duration argument.
+fn parse_mute(arg: &str) -> Result<SlashCommand, String> {
+    if arg.is_empty() || arg.eq_ignore_ascii_case("forever") {
+        return Ok(SlashCommand::Mute(None));
+    }
+    parse_duration(arg)
+        .map(|d| SlashCommand::Mute(Some(d)))
+        .ok_or_else(|| format!("Invalid duration: {arg} (e.g. 30m, 8h, 2d)"))
+}
+
+/// Parses the `/autodelete` argument.
+fn parse_auto_delete(arg: &str) -> Result<SlashCommand, String> {
+    if arg.is_empty() {
+        return Ok(SlashCommand::AutoDelete(None));
+    }
+    let arg = arg.to_lowercase();
+    let choice = match arg.as_str() {
+        "day" | "24h" => "1d",
+        "week" | "7d" => "1w",
+        "month" | "1mo" | "31d" => "1m",
+        "0" | "never" => "off",
+        other => other,
+    };
+    AUTO_DELETE_CHOICES
+        .iter()
+        .find(|(name, _)| *name == choice)
+        .map(|(_, period)| SlashCommand::AutoDelete(Some(*period)))
+        .ok_or_else(|| format!("Invalid timer: {arg} (choose 1d, 1w, 1m or off)"))
+}
+
+/// Looks up a theme by its config name, accepting the usual spellings.
+///
+/// Unlike [`Theme::from_config_str`], unknown names are rejected instead of
+/// falling back to the system theme.
+fn find_theme(name: &str) -> Option<Theme> {
+    let theme = Theme::from_config_str(name);
+    let known = theme != Theme::System
+        || matches!(
+            name.to_lowercase().as_str(),
+            "system" | "default" | "dark" | "light"
+        );
+    known.then_some(theme)
+}
+
+/// Returns completions for a partially typed command.
+///
+/// Each completion is the full replacement text for the input. Command names
+/// are completed first; once a command is chosen, its argument is completed
+/// from `chat_names` (for `/goto`), theme names, mute durations, or
+/// auto-delete timers.
+#[must_use]
+pub fn complete(text: &str, chat_names: &[&str]) -> Vec<String> {
+    if !is_command(text) {
+        return Vec::new();
+    }
+    let body = &text[1..];
+
+    let Some((name, arg)) = body.split_once(' ') else {
+        let prefix = body.to_lowercase();
+        return COMMANDS
+            .iter()
+            .filter(|(cmd, _, _)| cmd.starts_with(&prefix))
+            .map(|(cmd, hint, _)| {
+                if hint.is_empty() {
+                    format!("/{cmd}")
+                } else {
+                    format!("/{cmd} ")
+                }
+            })
+            .collect();
+    };
+
+    let arg_lower = arg.trim_start().to_lowercase();
+    let candidates: Vec<&str> = match name.to_lowercase().as_str() {
+        "goto" | "g" => chat_names
+            .iter()
+            .copied()
+            .filter(|n| n.to_lowercase().contains(&arg_lower))
+            .collect(),
+        "theme" => Theme::ALL
+            .iter()
+            .map(Theme::to_config_str)
+            .filter(|t| t.starts_with(&arg_lower))
+            .collect(),
+        "mute" => MUTE_SUGGESTIONS
+            .iter()
+            .copied()
+            .filter(|d| d.starts_with(&arg_lower))
+            .collect(),
+        "qr" => QR_SUGGESTIONS
+            .iter()
+            .copied()
+            .filter(|t| t.starts_with(&arg_lower))
+            .collect(),
+        "listen" => LISTEN_SUGGESTIONS
+            .iter()
+            .copied()
+            .filter(|t| t.starts_with(&arg_lower))
+            .collect(),
+        "autodelete" | "ttl" => AUTO_DELETE_CHOICES
+            .iter()
+            .map(|(name, _)| *name)
+            .filter(|d| d.starts_with(&arg_lower))
+            .collect(),
+        _ => Vec::new(),
+    };
+
+    candidates
+        .into_iter()
+        .map(|c| format!("/{name} {c}"))
+        .collect()
+}
+
+/// Returns the longest common prefix of `items`, by characters.
+#[must_use]
+pub fn common_prefix(items: &[String]) -> String {
+    let Some(first) = items.first() else {
+        return String::new();
+    };
+    let mut len = first.chars().count();
+    for item in &items[1..] {
+        len = first
+            .chars()
+            .zip(item.chars())
+            .take(len)
+            .take_while(|(a, b)| a == b)
+            .count();
+    }
+    first.chars().take(len).collect()
+}
+
+/// Returns one `"/cmd hint — description"` line per command, for `/help`.
+#[must_use]
+pub fn help_lines() -> Vec<String> {
+    COMMANDS
+        .iter()
+        .map(|(cmd, hint, desc)| {
+            if hint.is_empty() {
+                format!("/{cmd} — {desc}")
+            } else {
+                format!("/{cmd} {hint} — {desc}")
+            }
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn plain_text_is_not_a_command() {
+        assert_eq!(parse("hello /goto"), None);
+        assert_eq!(parse("//shrug"), None);
+        assert_eq!(unescape("//shrug"), "/shrug");
+        assert_eq!(unescape("hi"), "hi");
+    }
+
+    #[test]
+    fn parses_commands_with_arguments() {
+        assert_eq!(
+            parse("/goto  Work chat "),
+            Some(Ok(SlashCommand::Goto("Work chat".to_string())))
+        );
+        assert_eq!(
+            parse("/mute 8h"),
+            Some(Ok(SlashCommand::Mute(Some(Duration::hours(8)))))
+        );
+        assert_eq!(parse("/mute"), Some(Ok(SlashCommand::Mute(None))));
+        assert_eq!(
+            parse("/autodelete 1w"),
+            Some(Ok(SlashCommand::AutoDelete(Some(604_800))))
+        );
+        assert_eq!(
+            parse("/autodelete off"),
+            Some(Ok(SlashCommand::AutoDelete(Some(0))))
+        );
+        assert_eq!(
+            parse("/autodelete"),
+            Some(Ok(SlashCommand::AutoDelete(None)))
+        );
+        assert_eq!(
+            parse("/theme nord"),
+            Some(Ok(SlashCommand::Theme(Theme::Nord)))
+        );
+        assert_eq!(parse("/EXPORT"), Some(Ok(SlashCommand::Export)));
+        assert_eq!(
+            parse("/download photo 20"),
+            Some(Ok(SlashCommand::Download("photo 20".to_string())))
+        );
+        assert_eq!(parse("/lock"), Some(Ok(SlashCommand::Lock)));
+        assert_eq!(parse("/encrypt"), Some(Ok(SlashCommand::Encrypt)));
+        assert_eq!(parse("/logout"), Some(Ok(SlashCommand::Logout)));
+        assert_eq!(parse("/cache"), Some(Ok(SlashCommand::Cache)));
+        assert_eq!(parse("/storage"), Some(Ok(SlashCommand::Storage)));
+        assert_eq!(parse("/channels"), Some(Ok(SlashCommand::Channels)));
+        assert_eq!(parse("/join"), Some(Ok(SlashCommand::Join)));
+        assert_eq!(parse("/alert"), Some(Ok(SlashCommand::Alert)));
+        assert_eq!(
+            parse("/listen"),
+            Some(Ok(SlashCommand::Listen(ListenCommand::Start)))
+        );
+        assert_eq!(
+            parse("/listen skip"),
+            Some(Ok(SlashCommand::Listen(ListenCommand::Next)))
+        );
+        assert!(matches!(parse("/listen louder"), Some(Err(_))));
+        assert_eq!(parse("/dump"), Some(Ok(SlashCommand::Dump)));
+        assert_eq!(parse("/readall"), Some(Ok(SlashCommand::ReadAll)));
+        assert_eq!(parse("/stats"), Some(Ok(SlashCommand::Stats)));
+        assert_eq!(parse("/qr"), Some(Ok(SlashCommand::Qr(QrTarget::Selected))));
+        assert_eq!(parse("/qr Me"), Some(Ok(SlashCommand::Qr(QrTarget::Me))));
+        assert_eq!(
+            parse("/qr https://example.com"),
+            Some(Ok(SlashCommand::Qr(QrTarget::Text(
+                "https://example.com".to_string()
+            ))))
+        );
+        assert_eq!(
+            parse("/find has:photo beach"),
+            Some(Ok(SlashCommand::Find("has:photo beach".to_string())))
+        );
+        assert_eq!(parse("/report"), Some(Ok(SlashCommand::Report)));
+        assert_eq!(parse("/perms"), Some(Ok(SlashCommand::Permissions)));
+        assert_eq!(parse("/sendas"), Some(Ok(SlashCommand::SendAs)));
+        assert_eq!(
+            parse("/alias"),
+            Some(Ok(SlashCommand::Alias(String::new())))
+        );
+    }
+
+    #[test]
+    fn reports_errors() {
+        assert!(matches!(parse("/goto"), Some(Err(_))));
+        assert!(matches!(parse("/mute later"), Some(Err(_))));
+        assert!(matches!(parse("/autodelete 2d"), Some(Err(_))));
+        assert!(matches!(parse("/theme neon"), Some(Err(_))));
+    }
+
+    #[test]
+    fn unknown_names_are_sent_as_typed() {
+        assert_eq!(parse("/start"), None);
+        assert_eq!(parse("/help@somebot"), None);
+        assert_eq!(parse("/usr/bin/env is missing"), None);
+        assert_eq!(parse("/"), None);
+        assert_eq!(parse("/help"), Some(Ok(SlashCommand::Help)));
+    }
+
+    #[test]
+    fn completes_command_names() {
+        assert_eq!(complete("/th", &[]), vec!["/theme ".to_string()]);
+        assert_eq!(complete("/ex", &[]), vec!["/export".to_string()]);
+        assert_eq!(complete("/", &[]).len(), COMMANDS.len());
+    }
+
+    #[test]
+    fn completes_arguments() {
+        let chats = ["Alice", "Work", "Alan"];
+        assert_eq!(
+            complete("/goto al", &chats),
+            vec!["/goto Alice".to_string(), "/goto Alan".to_string()]
+        );
+        assert_eq!(
+            complete("/theme dr", &[]),
+            vec!["/theme dracula".to_string()]
+        );
+        assert_eq!(complete("/mute f", &[]), vec!["/mute forever".to_string()]);
+        assert_eq!(
+            complete("/autodelete o", &[]),
+            vec!["/autodelete off".to_string()]
+        );
+    }
+
+    #[test]
+    fn common_prefix_of_completions() {
+        let items = vec!["/goto Alice".to_string(), "/goto Alan".to_string()];
+        assert_eq!(common_prefix(&items), "/goto Al");
+        assert_eq!(common_prefix(&[]), "");
+    }
+}
@@ -1,7 +1,8 @@
 //! Status bar component for Ithil.
 //!
 //! Displays connection status, current user information, and unread counts
-//! at the bottom of the application window.
+//! at the bottom of the application window. Notices are drawn above it by
+//! [`Toasts`](super::Toasts).
 //!
 //! # Example
 //!
@@ -18,6 +19,7 @@
 use ratatui::{
     buffer::Buffer,
     layout::{Alignment, Constraint, Direction, Layout, Rect},
+    style::{Modifier, Style},
     text::{Line, Span},
     widgets::{Paragraph, Widget},
 };
@@ -54,10 +56,17 @@ impl std::fmt::Display for ConnectionStatus {
 ///
 /// The status bar is displayed at the bottom of the screen and shows:
 /// - Connection status indicator (left)
-/// - Current user name (left)
-/// - Status message or app name (center)
+/// - Current user name, with their @username or phone number (left)
+/// - Key hints (center)
 /// - Unread message count (right)
+/// - Unseen reactions count (right)
+/// - Unseen errors count (right)
+/// - Bulk download progress (right)
+/// - The voice message `/listen` is playing (center)
 /// - Vim mode indicator (right)
+///
+/// It flashes, with its colors inverted, when a message arrives and
+/// `notifications.flash` is on.
 #[derive(Debug, Clone, Default)]
 pub struct StatusBar {
     /// Current connection state
@@ -66,10 +75,20 @@ pub struct StatusBar {
     pub current_user: Option<User>,
     /// Total unread message count across all chats
     pub total_unread: i32,
-    /// Temporary status message to display
-    pub status_message: Option<String>,
+    /// Errors not yet seen in the error history
+    pub unseen_errors: usize,
     /// Whether vim keybindings are active
     pub vim_mode: bool,
+    /// Whether stealth mode is on
+    pub stealth_mode: bool,
+    /// Reactions to the user's messages not yet seen
+    pub unseen_reactions: usize,
+    /// Attachments saved so far and in all, while `/download` runs
+    pub downloads: Option<(usize, usize)>,
+    /// The voice message playing, while `/listen` runs
+    pub playing: Option<String>,
+    /// Whether the bar is flashing for a new message
+    pub flashing: bool,
 }
 
 impl StatusBar {
@@ -110,6 +129,20 @@ impl StatusBar {
         self.current_user = user;
     }
 
+    /// Returns how the logged-in account is told apart from others: its
+    /// `@username`, or its phone number if it has none.
+    #[must_use]
+    pub fn account_handle(&self) -> Option<String> {
+        let user = self.current_user.as_ref()?;
+        if !user.username.is_empty() {
+            Some(format!("@{}", user.username))
+        } else if !user.phone_number.is_empty() {
+            Some(format!("+{}", user.phone_number.trim_start_matches('+')))
+        } else {
+            None
+        }
+    }
+
     /// Sets the total unread message count.
     ///
     /// # Examples
@@ -125,24 +158,47 @@ impl StatusBar {
         self.total_unread = count;
     }
 
-    /// Sets a temporary status message.
-    ///
-    /// Pass `None` to clear the message and show the default app name.
-    pub fn set_status_message(&mut self, msg: Option<String>) {
-        self.status_message = msg;
+    /// Sets the number of errors not yet seen in the error history.
+    pub fn set_unseen_errors(&mut self, count: usize) {
+        self.unseen_errors = count;
     }
 
     /// Enables or disables vim mode indicator.
     pub fn set_vim_mode(&mut self, enabled: bool) {
         self.vim_mode = enabled;
     }
+
+    /// Enables or disables the stealth mode indicator.
+    pub fn set_stealth_mode(&mut self, enabled: bool) {
+        self.stealth_mode = enabled;
+    }
+
+    /// Sets the number of unseen reactions to the user's messages.
+    pub fn set_unseen_reactions(&mut self, count: usize) {
+        self.unseen_reactions = count;
+    }
+
+    /// Sets the progress of a running `/download` (done, total).
+    pub fn set_downloads(&mut self, progress: Option<(usize, usize)>) {
+        self.downloads = progress;
+    }
+
+    /// Sets what `/listen` is playing, shown in place of the key hints.
+    pub fn set_playing(&mut self, playing: Option<String>) {
+        self.playing = playing;
+    }
+
+    /// Inverts the bar's colors while a new message flashes it.
+    pub fn set_flashing(&mut self, flashing: bool) {
+        self.flashing = flashing;
+    }
 }
 
 /// Widget for rendering the status bar.
 ///
 /// This widget renders the status bar with three sections:
 /// - Left: Connection indicator and user name
-/// - Center: Status message or app name
+/// - Center: Key hints
 /// - Right: Unread count and vim mode indicator
 pub struct StatusBarWidget<'a> {
     model: &'a StatusBar,
@@ -190,21 +246,28 @@ impl Widget for StatusBarWidget<'_> {
             .map(User::get_display_name)
             .unwrap_or_default();
 
-        let left = Line::from(vec![
+        let mut left = Line::from(vec![
             Span::raw(" "),
             Span::styled(conn_icon, conn_style),
             Span::raw(" "),
             Span::styled(user_name, Styles::text()),
         ]);
+        if let Some(handle) = self.model.account_handle() {
+            left.push_span(Span::styled(format!(" {handle}"), Styles::text_muted()));
+        }
         Paragraph::new(left).render(chunks[0], buf);
 
-        // Center section: status message or key hints
-        let center_text = self
-            .model
-            .status_message
-            .as_deref()
-            .unwrap_or("? Help  Ctrl+P Settings");
-        let center = Line::from(vec![Span::styled(center_text, Styles::text_muted())]);
+        // Center section: what's playing, or key hints
+        let center = match &self.model.playing {
+            Some(playing) => Line::from(vec![
+                Span::styled(format!("\u{25b6} {playing}"), Styles::text_accent()),
+                Span::styled("  /listen next", Styles::text_muted()),
+            ]),
+            None => Line::from(vec![Span::styled(
+                "? Help  Ctrl+P Settings",
+                Styles::text_muted(),
+            )]),
+        };
         Paragraph::new(center)
             .alignment(Alignment::Center)
             .render(chunks[1], buf);
@@ -219,6 +282,31 @@ impl Widget for StatusBarWidget<'_> {
             ));
         }
 
+        if self.model.unseen_reactions > 0 {
+            right_spans.push(Span::styled(
+                format!("\u{2665}{} ", self.model.unseen_reactions),
+                Styles::chat_unread(),
+            ));
+        }
+
+        if let Some((done, total)) = self.model.downloads {
+            right_spans.push(Span::styled(
+                format!("\u{2193}{done}/{total} "),
+                Styles::text_accent(),
+            ));
+        }
+
+        if self.model.unseen_errors > 0 {
+            right_spans.push(Span::styled(
+                format!("!{} Alt+E ", self.model.unseen_errors),
+                Styles::error(),
+            ));
+        }
+
+        if self.model.stealth_mode {
+            right_spans.push(Span::styled("[STEALTH] ", Styles::warning()));
+        }
+
         if self.model.vim_mode {
             right_spans.push(Span::styled("[VIM] ", Styles::text_accent()));
         }
@@ -233,6 +321,10 @@ impl Widget for StatusBarWidget<'_> {
         Paragraph::new(right)
             .alignment(Alignment::Right)
             .render(chunks[2], buf);
+
+        if self.model.flashing {
+            buf.set_style(area, Style::default().add_modifier(Modifier::REVERSED));
+        }
     }
 }
 
@@ -246,8 +338,13 @@ mod tests {
         assert_eq!(status.connection_status, ConnectionStatus::Disconnected);
         assert!(status.current_user.is_none());
         assert_eq!(status.total_unread, 0);
-        assert!(status.status_message.is_none());
+        assert_eq!(status.unseen_errors, 0);
         assert!(!status.vim_mode);
+        assert!(!status.stealth_mode);
+        assert_eq!(status.unseen_reactions, 0);
+        assert_eq!(status.downloads, None);
+        assert_eq!(status.playing, None);
+        assert!(!status.flashing);
     }
 
     #[test]
@@ -288,6 +385,24 @@ mod tests {
         assert!(status.current_user.is_none());
     }
 
+    #[test]
+    fn test_account_handle_prefers_the_username() {
+        let mut status = StatusBar::new();
+        assert_eq!(status.account_handle(), None);
+
+        let mut user = User {
+            first_name: "John".to_string(),
+            phone_number: "15550100".to_string(),
+            ..Default::default()
+        };
+        status.set_user(Some(user.clone()));
+        assert_eq!(status.account_handle().as_deref(), Some("+15550100"));
+
+        user.username = "johnd".to_string();
+        status.set_user(Some(user));
+        assert_eq!(status.account_handle().as_deref(), Some("@johnd"));
+    }
+
     #[test]
     fn test_set_unread_count() {
         let mut status = StatusBar::new();
@@ -301,15 +416,10 @@ mod tests {
     }
 
     #[test]
-    fn test_set_status_message() {
+    fn test_set_unseen_errors() {
         let mut status = StatusBar::new();
-        assert!(status.status_message.is_none());
-
-        status.set_status_message(Some("Loading...".to_string()));
-        assert_eq!(status.status_message.as_deref(), Some("Loading..."));
-
-        status.set_status_message(None);
-        assert!(status.status_message.is_none());
+        status.set_unseen_errors(2);
+        assert_eq!(status.unseen_errors, 2);
     }
 
     #[test]
@@ -0,0 +1,183 @@
+//! Local data and its size, for clearing some of it (`/storage`).
+//!
+//! Kinds are marked with Space and cleared together with Enter, after the
+//! app asks for confirmation. Enter with nothing marked clears the
+//! highlighted kind.
+
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use crate::app::storage::LocalData;
+use crate::ui::styles::Styles;
+use crate::utils::format_file_size;
+
+/// Result of a key press in the storage view.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum StorageManagerAction {
+    /// Key was handled; keep the view open
+    None,
+    /// Close without clearing anything
+    Cancel,
+    /// Clear these kinds, which take this many bytes together
+    Clear(Vec<LocalData>, u64),
+}
+
+/// Each kind of local data with its size.
+#[derive(Debug, Clone)]
+pub struct StorageManager {
+    /// Kinds and their sizes in bytes
+    rows: Vec<(LocalData, u64)>,
+    /// Which rows are marked for clearing
+    marked: Vec<bool>,
+    selected: usize,
+}
+
+impl StorageManager {
+    /// Creates the view from each kind and its size in bytes.
+    #[must_use]
+    pub fn new(rows: Vec<(LocalData, u64)>) -> Self {
+        let marked = vec![false; rows.len()];
+        Self {
+            rows,
+            marked,
+            selected: 0,
+        }
+    }
+
+    /// Handles a key press.
+    pub fn handle_input(&mut self, key: KeyEvent) -> StorageManagerAction {
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') => StorageManagerAction::Cancel,
+            KeyCode::Char(' ') => {
+                if let Some(mark) = self.marked.get_mut(self.selected) {
+                    *mark = !*mark;
+                }
+                StorageManagerAction::None
+            },
+            KeyCode::Enter => {
+                let mut chosen: Vec<(LocalData, u64)> = self
+                    .rows
+                    .iter()
+                    .zip(&self.marked)
+                    .filter(|(_, marked)| **marked)
+                    .map(|(row, _)| *row)
+                    .collect();
+                if chosen.is_empty() {
+                    chosen.extend(self.rows.get(self.selected).copied());
+                }
+                if chosen.is_empty() {
+                    return StorageManagerAction::Cancel;
+                }
+                let bytes = chosen.iter().map(|(_, size)| size).sum();
+                StorageManagerAction::Clear(
+                    chosen.into_iter().map(|(kind, _)| kind).collect(),
+                    bytes,
+                )
+            },
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                StorageManagerAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                if self.selected + 1 < self.rows.len() {
+                    self.selected += 1;
+                }
+                StorageManagerAction::None
+            },
+            _ => StorageManagerAction::None,
+        }
+    }
+
+    /// Renders the view as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 72.min(area.width.saturating_sub(4));
+        #[allow(clippy::cast_possible_truncation)]
+        let rows = self.rows.len().min(usize::from(u16::MAX)) as u16;
+        let h = (rows + 5).min(area.height);
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let size = |bytes: u64| format_file_size(i64::try_from(bytes).unwrap_or(i64::MAX));
+        let total: u64 = self.rows.iter().map(|(_, bytes)| bytes).sum();
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" Local Data ({}) ", size(total)),
+                Styles::text_bright(),
+            ))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        let mut lines: Vec<Line> = self
+            .rows
+            .iter()
+            .zip(&self.marked)
+            .enumerate()
+            .map(|(i, ((kind, bytes), marked))| {
+                let style = if i == self.selected {
+                    Styles::selected()
+                } else {
+                    Styles::text()
+                };
+                let check = if *marked { "[x]" } else { "[ ]" };
+                Line::from(vec![
+                    Span::styled(
+                        format!("{check} {:<14}{:>9}  ", kind.to_string(), size(*bytes)),
+                        style,
+                    ),
+                    Span::styled(kind.description(), Styles::text_muted()),
+                ])
+            })
+            .collect();
+        lines.push(Line::from(""));
+        lines.push(Line::from(Span::styled(
+            "Space mark \u{2022} Enter clear \u{2022} Esc close",
+            Styles::text_muted(),
+        )));
+
+        frame.render_widget(Paragraph::new(lines).block(block), modal);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn clears_the_marked_kinds_or_else_the_highlighted_one() {
+        let rows = vec![
+            (LocalData::Session, 10),
+            (LocalData::Media, 300),
+            (LocalData::Exports, 5),
+        ];
+        let mut view = StorageManager::new(rows.clone());
+        view.handle_input(KeyEvent::from(KeyCode::Down));
+        assert_eq!(
+            view.handle_input(KeyEvent::from(KeyCode::Enter)),
+            StorageManagerAction::Clear(vec![LocalData::Media], 300)
+        );
+
+        let mut view = StorageManager::new(rows);
+        view.handle_input(KeyEvent::from(KeyCode::Char(' ')));
+        view.handle_input(KeyEvent::from(KeyCode::Down));
+        view.handle_input(KeyEvent::from(KeyCode::Down));
+        view.handle_input(KeyEvent::from(KeyCode::Char(' ')));
+        assert_eq!(
+            view.handle_input(KeyEvent::from(KeyCode::Enter)),
+            StorageManagerAction::Clear(vec![LocalData::Session, LocalData::Exports], 15)
+        );
+        assert_eq!(
+            view.handle_input(KeyEvent::from(KeyCode::Esc)),
+            StorageManagerAction::Cancel
+        );
+    }
+}
@@ -0,0 +1,391 @@
+//! Short notices shown just above the status bar.
+//!
+//! Each notice has a severity. Notices that arrive while another is still
+//! on screen wait in a queue, so a quick run of messages doesn't hide the
+//! ones before it. Info notices give way at once. Successes and errors stay
+//! up for a minimum time first. Errors are also kept in a history, which
+//! [`ErrorLog`] shows (`Alt+E`).
+
+use std::collections::VecDeque;
+use std::time::{Duration, Instant};
+
+use chrono::{DateTime, Local};
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::{
+    layout::Rect,
+    style::Style,
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, List, ListItem, ListState, Paragraph},
+    Frame,
+};
+
+use crate::ui::styles::Styles;
+
+/// Most notices waiting behind the one on screen.
+const MAX_QUEUED: usize = 8;
+
+/// Most errors kept in the history.
+const MAX_HISTORY: usize = 50;
+
+/// How serious a notice is.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord)]
+pub enum Severity {
+    /// Progress and other passing information
+    Info,
+    /// Something finished as asked
+    Success,
+    /// Something failed
+    Error,
+}
+
+impl Severity {
+    /// How long a notice stays up before a queued one may replace it.
+    #[must_use]
+    pub const fn min_display(self) -> Duration {
+        match self {
+            Self::Info => Duration::ZERO,
+            Self::Success => Duration::from_millis(1500),
+            Self::Error => Duration::from_secs(3),
+        }
+    }
+
+    /// How long a notice stays up if nothing replaces it.
+    #[must_use]
+    pub const fn lifetime(self) -> Duration {
+        match self {
+            Self::Info => Duration::from_secs(5),
+            Self::Success => Duration::from_secs(4),
+            Self::Error => Duration::from_secs(10),
+        }
+    }
+
+    /// Style for the notice's text.
+    fn style(self) -> Style {
+        match self {
+            Self::Info => Styles::text(),
+            Self::Success => Styles::success(),
+            Self::Error => Styles::error(),
+        }
+    }
+}
+
+/// A notice.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Toast {
+    /// Text to show
+    pub text: String,
+    /// How serious it is
+    pub severity: Severity,
+}
+
+/// An error from the history.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct LoggedError {
+    /// When it happened
+    pub at: DateTime<Local>,
+    /// What was shown
+    pub text: String,
+}
+
+/// The notice on screen, those waiting, and the error history.
+#[derive(Debug, Clone, Default)]
+pub struct Toasts {
+    /// Notice on screen and when it went up
+    current: Option<(Toast, Instant)>,
+    queue: VecDeque<Toast>,
+    /// Errors, oldest first
+    history: VecDeque<LoggedError>,
+    /// Errors added since the history was last opened
+    unseen_errors: usize,
+}
+
+impl Toasts {
+    /// Creates an empty queue.
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Adds a notice at `now`.
+    ///
+    /// It goes up at once if nothing is waiting and the notice on screen
+    /// has had its minimum time or is less serious; otherwise it waits.
+    /// A notice identical to the one on screen only restarts its timer.
+    pub fn push(&mut self, text: impl Into<String>, severity: Severity, now: Instant) {
+        let toast = Toast {
+            text: text.into(),
+            severity,
+        };
+        if severity == Severity::Error {
+            self.history.push_back(LoggedError {
+                at: Local::now(),
+                text: toast.text.clone(),
+            });
+            if self.history.len() > MAX_HISTORY {
+                self.history.pop_front();
+            }
+            self.unseen_errors += 1;
+        }
+
+        let Some((shown, since)) = &mut self.current else {
+            self.current = Some((toast, now));
+            return;
+        };
+        if *shown == toast {
+            *since = now;
+            return;
+        }
+        if self.queue.contains(&toast) {
+            return;
+        }
+        if self.queue.is_empty()
+            && (severity > shown.severity
+                || now.duration_since(*since) >= shown.severity.min_display())
+        {
+            *shown = toast;
+            *since = now;
+        } else {
+            self.queue.push_back(toast);
+            if self.queue.len() > MAX_QUEUED {
+                self.queue.pop_front();
+            }
+        }
+    }
+
+    /// Moves the queue along: retires the notice on screen once its
+    /// lifetime is up, or once it has had its minimum time and another is
+    /// waiting.
+    pub fn tick(&mut self, now: Instant) {
+        let Some((shown, since)) = &self.current else {
+            return;
+        };
+        let elapsed = now.duration_since(*since);
+        let waiting = !self.queue.is_empty();
+        if elapsed >= shown.severity.lifetime()
+            || (waiting && elapsed >= shown.severity.min_display())
+        {
+            self.current = self.queue.pop_front().map(|toast| (toast, now));
+        }
+    }
+
+    /// Takes down the notice on screen, showing the next waiting one.
+    pub fn dismiss(&mut self, now: Instant) {
+        self.current = self.queue.pop_front().map(|toast| (toast, now));
+    }
+
+    /// Returns the notice on screen.
+    #[must_use]
+    pub fn current(&self) -> Option<&Toast> {
+        self.current.as_ref().map(|(toast, _)| toast)
+    }
+
+    /// Returns the error history, oldest first.
+    #[must_use]
+    pub fn history(&self) -> Vec<LoggedError> {
+        self.history.iter().cloned().collect()
+    }
+
+    /// Returns how many errors were added since [`Self::mark_seen`].
+    #[must_use]
+    pub const fn unseen_errors(&self) -> usize {
+        self.unseen_errors
+    }
+
+    /// Marks every error in the history as seen.
+    pub fn mark_seen(&mut self) {
+        self.unseen_errors = 0;
+    }
+
+    /// Renders the notice on screen, if any, over the line `area`.
+    pub fn render(&self, frame: &mut Frame, area: Rect) {
+        let Some(toast) = self.current() else {
+            return;
+        };
+        let text = format!(" {} ", toast.text);
+        #[allow(clippy::cast_possible_truncation)]
+        let width =
+            (unicode_width::UnicodeWidthStr::width(text.as_str()).min(usize::from(u16::MAX))
+                as u16)
+                .min(area.width);
+        let line = Rect::new(
+            area.x + area.width - width,
+            area.y,
+            width,
+            1.min(area.height),
+        );
+
+        frame.render_widget(Clear, line);
+        frame.render_widget(
+            Paragraph::new(Span::styled(text, toast.severity.style()))
+                .style(Styles::modal_background()),
+            line,
+        );
+    }
+}
+
+/// Result of a key press in the error history.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ErrorLogAction {
+    /// Key was handled; keep the history open
+    None,
+    /// Close the history
+    Close,
+}
+
+/// The error history, newest first.
+#[derive(Debug, Clone, Default)]
+pub struct ErrorLog {
+    errors: Vec<LoggedError>,
+    selected: usize,
+}
+
+impl ErrorLog {
+    /// Creates the view from the history, oldest first.
+    #[must_use]
+    pub fn new(mut errors: Vec<LoggedError>) -> Self {
+        errors.reverse();
+        Self {
+            errors,
+            selected: 0,
+        }
+    }
+
+    /// Handles a key press while the history is open.
+    pub fn handle_input(&mut self, key: KeyEvent) -> ErrorLogAction {
+        match key.code {
+            KeyCode::Esc | KeyCode::Char('q') | KeyCode::Enter => ErrorLogAction::Close,
+            KeyCode::Up | KeyCode::Char('k') => {
+                self.selected = self.selected.saturating_sub(1);
+                ErrorLogAction::None
+            },
+            KeyCode::Down | KeyCode::Char('j') => {
+                if self.selected + 1 < self.errors.len() {
+                    self.selected += 1;
+                }
+                ErrorLogAction::None
+            },
+            _ => ErrorLogAction::None,
+        }
+    }
+
+    /// Renders the history as a centered overlay.
+    pub fn render(&self, frame: &mut Frame) {
+        let area = frame.area();
+        let w = 90.min(area.width.saturating_sub(4));
+        let h = 20.min(area.height.saturating_sub(2));
+        let x = (area.width.saturating_sub(w)) / 2;
+        let y = (area.height.saturating_sub(h)) / 3;
+        let modal = Rect::new(x, y, w, h);
+
+        frame.render_widget(Clear, modal);
+
+        let block = Block::default()
+            .title(Span::styled(
+                format!(" Errors ({}) ", self.errors.len()),
+                Styles::text_bright(),
+            ))
+            .title_bottom(Span::styled(" Esc close ", Styles::text_muted()))
+            .borders(Borders::ALL)
+            .border_style(Styles::border_focused())
+            .style(Styles::modal_background());
+
+        if self.errors.is_empty() {
+            let empty =
+                Paragraph::new(Span::styled("No errors", Styles::text_muted())).block(block);
+            frame.render_widget(empty, modal);
+            return;
+        }
+
+        let today = Local::now().date_naive();
+        let items: Vec<ListItem> = self
+            .errors
+            .iter()
+            .map(|e| {
+                let time = if e.at.date_naive() == today {
+                    e.at.format("%H:%M:%S").to_string()
+                } else {
+                    e.at.format("%b %-d %H:%M").to_string()
+                };
+                ListItem::new(Line::from(vec![
+                    Span::styled(format!("{time:>12} "), Styles::text_muted()),
+                    Span::styled(e.text.clone(), Styles::error()),
+                ]))
+            })
+            .collect();
+
+        let list = List::new(items)
+            .block(block)
+            .highlight_style(Styles::highlight());
+        let mut state = ListState::default();
+        state.select(Some(self.selected));
+        frame.render_stateful_widget(list, modal, &mut state);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn after(start: Instant, millis: u64) -> Instant {
+        start + Duration::from_millis(millis)
+    }
+
+    fn shown(toasts: &Toasts) -> Option<&str> {
+        toasts.current().map(|t| t.text.as_str())
+    }
+
+    #[test]
+    fn info_gives_way_but_errors_get_their_minimum_time() {
+        let start = Instant::now();
+        let mut toasts = Toasts::new();
+        toasts.push("Uploading", Severity::Info, start);
+        toasts.push("Upload failed", Severity::Error, after(start, 10));
+        assert_eq!(shown(&toasts), Some("Upload failed"));
+
+        toasts.push("Saved", Severity::Success, after(start, 20));
+        toasts.tick(after(start, 1000));
+        assert_eq!(shown(&toasts), Some("Upload failed"), "error still up");
+
+        toasts.tick(after(start, 3100));
+        assert_eq!(shown(&toasts), Some("Saved"));
+
+        toasts.tick(after(start, 8000));
+        assert_eq!(shown(&toasts), None, "lifetime over");
+    }
+
+    #[test]
+    fn repeats_are_not_queued_twice() {
+        let start = Instant::now();
+        let mut toasts = Toasts::new();
+        toasts.push("Offline", Severity::Error, start);
+        toasts.push("Offline", Severity::Error, after(start, 10));
+        toasts.push("Busy", Severity::Error, after(start, 20));
+        toasts.push("Busy", Severity::Error, after(start, 30));
+
+        toasts.dismiss(after(start, 40));
+        assert_eq!(shown(&toasts), Some("Busy"));
+        toasts.dismiss(after(start, 50));
+        assert_eq!(shown(&toasts), None);
+    }
+
+    #[test]
+    fn errors_are_kept_in_the_history() {
+        let start = Instant::now();
+        let mut toasts = Toasts::new();
+        toasts.push("Saved", Severity::Success, start);
+        toasts.push("First", Severity::Error, start);
+        toasts.push("Second", Severity::Error, start);
+
+        let texts: Vec<String> = toasts.history().into_iter().map(|e| e.text).collect();
+        assert_eq!(texts, vec!["First", "Second"]);
+        assert_eq!(toasts.unseen_errors(), 2);
+        toasts.mark_seen();
+        assert_eq!(toasts.unseen_errors(), 0);
+
+        let mut log = ErrorLog::new(toasts.history());
+        assert_eq!(
+            log.handle_input(KeyEvent::from(KeyCode::Esc)),
+            ErrorLogAction::Close
+        );
+    }
+}
@@ -0,0 +1,153 @@
+//! External editor support for composing long messages.
+//!
+//! The composer can hand its draft to `$VISUAL` / `$EDITOR`. While the editor
+//! runs, the TUI is suspended (raw mode off, primary screen restored) so the
+//! editor owns the terminal; it is resumed once the editor exits.
+
+use std::fs;
+use std::io::{self, Write};
+use std::path::{Path, PathBuf};
+use std::process::Command;
+
+/// Editor used when neither `$VISUAL` nor `$EDITOR` is set.
+const FALLBACK_EDITOR: &str = "vi";
+
+/// Resolves the editor command line from `$VISUAL` and `$EDITOR` values.
+///
+/// The first non-blank value wins, split on whitespace so settings such as
+/// `code --wait` work. Falls back to `vi`.
+#[must_use]
+pub fn resolve_editor(visual: Option<&str>, editor: Option<&str>) -> Vec<String> {
+    [visual, editor]
+        .into_iter()
+        .flatten()
+        .map(str::trim)
+        .find(|cmd| !cmd.is_empty())
+        .unwrap_or(FALLBACK_EDITOR)
+        .split_whitespace()
+        .map(str::to_string)
+        .collect()
+}
+
+/// Opens `initial` in the user's editor and returns the saved text.
+///
+/// Suspends the TUI for the duration of the editor session. A single trailing
+/// newline added by the editor is stripped.
+///
+/// # Errors
+///
+/// Returns an error if the temporary file cannot be written or read, the
+/// editor cannot be started, or it exits with a failure status.
+pub fn edit_text(initial: &str) -> io::Result<String> {
+    let runtime = crate::app::paths::runtime_dir()?;
+    let dir = crate::app::paths::create_private_dir(&runtime, "ithil-draft")?;
+    let result = edit_in(&dir, initial);
+    let _ = fs::remove_dir_all(&dir);
+    result
+}
+
+/// Runs the editor on a draft file in `dir`, a directory of its own.
+fn edit_in(dir: &Path, initial: &str) -> io::Result<String> {
+    let path = write_draft(dir, initial)?;
+
+    let visual = std::env::var("VISUAL").ok();
+    let editor = std::env::var("EDITOR").ok();
+    let argv = resolve_editor(visual.as_deref(), editor.as_deref());
+
+    suspend_terminal()?;
+    let status = Command::new(&argv[0]).args(&argv[1..]).arg(&path).status();
+    // Always try to restore the TUI, even if the editor failed to launch.
+    let resumed = resume_terminal();
+
+    let result = match status {
+        Ok(status) if status.success() => fs::read_to_string(&path),
+        Ok(status) => Err(io::Error::other(format!("editor exited with {status}"))),
+        Err(e) => Err(e),
+    };
+    resumed?;
+
+    result.map(|text| {
+        let text = text.strip_suffix('\n').unwrap_or(&text);
+        text.strip_suffix('\r').unwrap_or(text).to_string()
+    })
+}
+
+/// Writes the draft to a new file in `dir` only the current user can read,
+/// never through whatever may already be at its path.
+fn write_draft(dir: &Path, text: &str) -> io::Result<PathBuf> {
+    let path = dir.join("draft.md");
+    let mut options = fs::OpenOptions::new();
+    options.write(true).create_new(true);
+    #[cfg(unix)]
+    {
+        use std::os::unix::fs::OpenOptionsExt;
+        options.mode(0o600);
+    }
+    options.open(&path)?.write_all(text.as_bytes())?;
+    Ok(path)
+}
+
+/// Leaves the alternate screen and raw mode so the editor gets a normal tty.
+fn suspend_terminal() -> io::Result<()> {
+    crossterm::terminal::disable_raw_mode()?;
+    crossterm::execute!(
+        io::stdout(),
+        crossterm::terminal::LeaveAlternateScreen,
+        crossterm::event::DisableMouseCapture,
+        crossterm::event::DisableFocusChange
+    )
+}
+
+/// Re-enters the TUI terminal modes set up in `main`.
+fn resume_terminal() -> io::Result<()> {
+    crossterm::terminal::enable_raw_mode()?;
+    crossterm::execute!(
+        io::stdout(),
+        crossterm::terminal::EnterAlternateScreen,
+        crossterm::event::EnableMouseCapture,
+        crossterm::event::EnableFocusChange
+    )
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn prefers_visual_over_editor() {
+        assert_eq!(resolve_editor(Some("nvim"), Some("nano")), ["nvim"]);
+    }
+
+    #[test]
+    fn splits_arguments() {
+        assert_eq!(
+            resolve_editor(None, Some("code --wait")),
+            ["code", "--wait"]
+        );
+    }
+
+    #[test]
+    fn blank_values_fall_back_to_vi() {
+        assert_eq!(resolve_editor(Some("  "), None), [FALLBACK_EDITOR]);
+    }
+
+    #[test]
+    fn drafts_are_private_and_never_reuse_a_file() {
+        let dir = std::env::temp_dir().join(format!("ithil_draft_test_{}", std::process::id()));
+        fs::create_dir_all(&dir).unwrap();
+
+        let path = write_draft(&dir, "hello").unwrap();
+        assert_eq!(fs::read_to_string(&path).unwrap(), "hello");
+        #[cfg(unix)]
+        {
+            use std::os::unix::fs::PermissionsExt;
+            let mode = fs::metadata(&path).unwrap().permissions().mode();
+            assert_eq!(mode & 0o777, 0o600);
+        }
+        // Something already at the path is refused, not written through
+        assert!(write_draft(&dir, "again").is_err());
+        assert_eq!(fs::read_to_string(&path).unwrap(), "hello");
+
+        fs::remove_dir_all(&dir).unwrap();
+    }
+}
@@ -0,0 +1,111 @@
+//! Fetching the inbox's unread messages in the background (`Alt+I`).
+//!
+//! Each unread chat's newest messages are asked for in turn, and a flood
+//! wait can hold one request up for a while, so the fetch is a task of its
+//! own. The inbox opens at once with what is cached, and the app fills it in
+//! with each chat's messages as they arrive. Dropping a fetch stops it.
+
+use std::sync::Arc;
+
+use tokio::sync::mpsc;
+use tokio::task::JoinHandle;
+
+use crate::telegram::{TelegramApi, TelegramError};
+use crate::types::Message;
+
+/// Most chats fetched for one inbox; the rest show what is cached.
+pub const MAX_CHATS: usize = 50;
+
+/// A chat's newest messages, or why they couldn't be fetched.
+pub type Fetched = (i64, Result<Vec<Message>, TelegramError>);
+
+/// A fetch of the newest messages of several chats.
+#[derive(Debug)]
+pub struct InboxFetch {
+    /// Chats asked for
+    total: usize,
+    /// Chats reported so far
+    done: usize,
+    results: mpsc::UnboundedReceiver<Fetched>,
+    task: JoinHandle<()>,
+}
+
+impl InboxFetch {
+    /// Starts fetching each chat's newest messages, as many as its limit,
+    /// one chat at a time through `telegram`. Chats past [`MAX_CHATS`] are
+    /// left out.
+    ///
+    /// Must be called from within a Tokio runtime.
+    #[must_use]
+    pub fn start(telegram: Arc<dyn TelegramApi>, mut chats: Vec<(i64, usize)>) -> Self {
+        chats.truncate(MAX_CHATS);
+        let total = chats.len();
+        let (tx, results) = mpsc::unbounded_channel();
+        let task = tokio::spawn(async move {
+            for (chat_id, limit) in chats {
+                let messages = telegram.get_messages(chat_id, limit, None).await;
+                if tx.send((chat_id, messages)).is_err() {
+                    break;
+                }
+            }
+        });
+        Self {
+            total,
+            done: 0,
+            results,
+            task,
+        }
+    }
+
+    /// Returns the chats fetched since the last call, in order.
+    pub fn fetched(&mut self) -> Vec<Fetched> {
+        let fetched: Vec<Fetched> = std::iter::from_fn(|| self.results.try_recv().ok()).collect();
+        self.done += fetched.len();
+        fetched
+    }
+
+    /// Returns `true` once every chat has been reported.
+    #[must_use]
+    pub const fn is_done(&self) -> bool {
+        self.done >= self.total
+    }
+}
+
+impl Drop for InboxFetch {
+    fn drop(&mut self) {
+        self.task.abort();
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::cache::new_shared_cache;
+    use crate::telegram::fake::FakeTelegram;
+    use crate::types::Chat;
+
+    #[tokio::test]
+    async fn reports_each_chat_and_leaves_out_the_rest() {
+        let mut fake = FakeTelegram::new(new_shared_cache(100)).logged_in();
+        for id in 1..=60 {
+            let chat = Chat {
+                id,
+                ..Default::default()
+            };
+            fake = fake.with_chat(chat, Vec::new());
+        }
+        let chats = (1..=60).map(|id| (id, 20)).collect();
+        let mut fetch = InboxFetch::start(Arc::new(fake), chats);
+        assert!(!fetch.is_done());
+
+        let mut fetched = Vec::new();
+        for _ in 0..100 {
+            tokio::task::yield_now().await;
+            fetched.extend(fetch.fetched());
+        }
+        assert_eq!(fetched.len(), MAX_CHATS);
+        assert!(fetched.iter().all(|(_, messages)| messages.is_ok()));
+        assert_eq!(fetched.first().map(|(id, _)| *id), Some(1));
+        assert!(fetch.is_done());
+    }
+}
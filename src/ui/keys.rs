@@ -57,6 +57,44 @@ pub enum Action {
     ToggleSidebar,
     /// Open settings screen
     OpenSettings,
+    /// Open the chat quick-switcher
+    QuickSwitch,
+    /// Toggle stealth mode
+    ToggleStealth,
+    /// Lock the screen
+    Lock,
+    /// Jump to a date in the open conversation
+    JumpToDate,
+    /// Jump to the first unread message
+    JumpToUnread,
+    /// Jump to the next message mentioning the user
+    NextMention,
+    /// Return to the message selected before the last jump
+    JumpBack,
+    /// Redo a jump undone with `JumpBack`
+    JumpForward,
+    /// Jump to the first message of the previous day
+    PreviousDay,
+    /// Jump to the first message of the next day
+    NextDay,
+    /// Show reactions to the user's messages
+    ShowReactions,
+    /// Show unread messages from every chat
+    ShowInbox,
+    /// Show the messages bookmarked on this device
+    ShowBookmarks,
+    /// Show the messages pinned on this device in the open chat
+    ShowLocalPins,
+    /// Show the history of error notices
+    ShowErrors,
+    /// Show the menu of actions for the open chat
+    ChatActions,
+    /// Open the selected chat or message in the official Telegram app
+    OpenInTelegram,
+    /// Split the conversation view in two, or close the split
+    ToggleSplit,
+    /// Move focus to the other side of the split view
+    SwitchSplit,
 
     // =========================================================================
     // Navigation Actions
@@ -93,6 +131,8 @@ pub enum Action {
     ArchiveChat,
     /// Mark the selected chat as read
     MarkAsRead,
+    /// Mark every chat as read
+    MarkAllAsRead,
 
     // =========================================================================
     // Conversation Actions
@@ -111,12 +151,37 @@ pub enum Action {
     Delete,
     /// Forward the selected message
     Forward,
+    /// Copy the selected message's text to the clipboard
+    CopyMessage,
+    /// Report the selected message
+    ReportMessage,
+    /// React to the selected message
+    React,
+    /// Bookmark the selected message, or remove its bookmark
+    Bookmark,
+    /// Pin the selected message on this device only, or unpin it
+    LocalPin,
+    /// Open the message a forward was copied from, in its source chat
+    JumpToOriginal,
+    /// Select the message the selected one replies to
+    JumpToReply,
     /// Cancel the current action
     CancelAction,
     /// Open/view media (photo, video, document)
     OpenMedia,
+    /// Save a copy of the selected message's attachment to the downloads
+    /// directory
+    SaveMedia,
     /// Open the file picker to attach a file to the message
     AttachFile,
+    /// Switch the staged image between a compressed photo and a file
+    ToggleCompression,
+    /// Open the current channel's discussion group
+    OpenDiscussion,
+    /// Edit the previous (older) of my own messages
+    EditPrevious,
+    /// Edit the next (newer) of my own messages
+    EditNext,
 
     // =========================================================================
     // Input Actions
@@ -153,6 +218,25 @@ impl std::fmt::Display for Action {
             Self::FocusSidebar => write!(f, "Focus Sidebar"),
             Self::ToggleSidebar => write!(f, "Toggle Sidebar"),
             Self::OpenSettings => write!(f, "Open Settings"),
+            Self::QuickSwitch => write!(f, "Quick Switch"),
+            Self::ToggleStealth => write!(f, "Toggle Stealth"),
+            Self::Lock => write!(f, "Lock"),
+            Self::JumpToDate => write!(f, "Jump to Date"),
+            Self::JumpToUnread => write!(f, "Jump to Unread"),
+            Self::NextMention => write!(f, "Next Mention"),
+            Self::JumpBack => write!(f, "Jump Back"),
+            Self::JumpForward => write!(f, "Jump Forward"),
+            Self::PreviousDay => write!(f, "Previous Day"),
+            Self::NextDay => write!(f, "Next Day"),
+            Self::ShowReactions => write!(f, "Show Reactions"),
+            Self::ShowInbox => write!(f, "Show Inbox"),
+            Self::ShowBookmarks => write!(f, "Show Bookmarks"),
+            Self::ShowLocalPins => write!(f, "Show Local Pins"),
+            Self::ShowErrors => write!(f, "Show Errors"),
+            Self::ChatActions => write!(f, "Chat Actions"),
+            Self::OpenInTelegram => write!(f, "Open in Telegram"),
+            Self::ToggleSplit => write!(f, "Toggle Split"),
+            Self::SwitchSplit => write!(f, "Switch Split"),
             Self::Up => write!(f, "Up"),
             Self::Down => write!(f, "Down"),
             Self::Left => write!(f, "Left"),
@@ -167,6 +251,7 @@ impl std::fmt::Display for Action {
             Self::MuteChat => write!(f, "Mute Chat"),
             Self::ArchiveChat => write!(f, "Archive Chat"),
             Self::MarkAsRead => write!(f, "Mark As Read"),
+            Self::MarkAllAsRead => write!(f, "Mark All As Read"),
             Self::FocusInput => write!(f, "Focus Input"),
             Self::SendMessage => write!(f, "Send Message"),
             Self::NewLine => write!(f, "New Line"),
@@ -174,9 +259,21 @@ impl std::fmt::Display for Action {
             Self::Edit => write!(f, "Edit"),
             Self::Delete => write!(f, "Delete"),
             Self::Forward => write!(f, "Forward"),
+            Self::CopyMessage => write!(f, "Copy Message"),
+            Self::ReportMessage => write!(f, "Report Message"),
+            Self::React => write!(f, "React"),
+            Self::Bookmark => write!(f, "Bookmark"),
+            Self::LocalPin => write!(f, "Local Pin"),
+            Self::JumpToOriginal => write!(f, "Jump to Original"),
+            Self::JumpToReply => write!(f, "Jump to Reply"),
             Self::CancelAction => write!(f, "Cancel"),
             Self::OpenMedia => write!(f, "Open Media"),
+            Self::SaveMedia => write!(f, "Save Media"),
             Self::AttachFile => write!(f, "Attach File"),
+            Self::ToggleCompression => write!(f, "Toggle Compression"),
+            Self::OpenDiscussion => write!(f, "Open Discussion"),
+            Self::EditPrevious => write!(f, "Edit Previous"),
+            Self::EditNext => write!(f, "Edit Next"),
             Self::Backspace => write!(f, "Backspace"),
             Self::DeleteChar => write!(f, "Delete Char"),
             Self::ScrollUp => write!(f, "Scroll Up"),
@@ -186,6 +283,46 @@ impl std::fmt::Display for Action {
     }
 }
 
+impl Action {
+    /// Looks up a global action by its `snake_case` name, as used for
+    /// leader sequences in the config (e.g. `"mark_all_as_read"`).
+    ///
+    /// Only actions that make sense from any pane can be named.
+    #[must_use]
+    pub fn from_name(name: &str) -> Option<Self> {
+        Some(
+            match name.trim().to_lowercase().replace('-', "_").as_str() {
+                "quit" => Self::Quit,
+                "help" => Self::Help,
+                "next_pane" => Self::NextPane,
+                "previous_pane" => Self::PreviousPane,
+                "focus_chat_list" => Self::FocusChatList,
+                "focus_conversation" => Self::FocusConversation,
+                "focus_sidebar" => Self::FocusSidebar,
+                "focus_input" => Self::FocusInput,
+                "toggle_sidebar" => Self::ToggleSidebar,
+                "open_settings" => Self::OpenSettings,
+                "quick_switch" => Self::QuickSwitch,
+                "toggle_stealth" => Self::ToggleStealth,
+                "lock" => Self::Lock,
+                "jump_to_date" => Self::JumpToDate,
+                "show_reactions" => Self::ShowReactions,
+                "show_inbox" | "inbox" => Self::ShowInbox,
+                "show_bookmarks" | "bookmarks" => Self::ShowBookmarks,
+                "show_local_pins" | "local_pins" => Self::ShowLocalPins,
+                "show_errors" | "errors" => Self::ShowErrors,
+                "chat_actions" => Self::ChatActions,
+                "open_in_telegram" => Self::OpenInTelegram,
+                "toggle_split" => Self::ToggleSplit,
+                "switch_split" => Self::SwitchSplit,
+                "mark_as_read" => Self::MarkAsRead,
+                "mark_all_as_read" => Self::MarkAllAsRead,
+                _ => return None,
+            },
+        )
+    }
+}
+
 /// Key binding configuration.
 ///
 /// Maps [`KeyEvent`]s to [`Action`]s, supporting both standard and Vim-style
@@ -234,9 +371,14 @@ impl KeyMap {
         bindings.insert(key(KeyCode::Char('3'), ctrl()), Action::FocusSidebar);
         bindings.insert(key(KeyCode::Char('s'), ctrl()), Action::ToggleSidebar);
         bindings.insert(key(KeyCode::Char('t'), ctrl()), Action::AttachFile);
+        bindings.insert(key(KeyCode::Char('c'), alt()), Action::ToggleCompression);
         bindings.insert(key(KeyCode::Char(','), ctrl()), Action::OpenSettings);
         bindings.insert(key(KeyCode::Char('p'), ctrl()), Action::OpenSettings);
         bindings.insert(key(KeyCode::F(12), none()), Action::OpenSettings);
+        bindings.insert(key(KeyCode::Char('k'), ctrl()), Action::QuickSwitch);
+        bindings.insert(key(KeyCode::Char('S'), shift()), Action::ToggleStealth);
+        bindings.insert(key(KeyCode::Char('l'), ctrl()), Action::Lock);
+        bindings.insert(key(KeyCode::Char('g'), ctrl()), Action::JumpToDate);
 
         // =====================================================================
         // Arrow key navigation (both modes)
@@ -258,6 +400,39 @@ impl KeyMap {
         bindings.insert(key(KeyCode::Esc, none()), Action::CancelAction);
         bindings.insert(key(KeyCode::Backspace, none()), Action::Backspace);
         bindings.insert(key(KeyCode::Delete, none()), Action::DeleteChar);
+        bindings.insert(key(KeyCode::Up, ctrl()), Action::EditPrevious);
+        bindings.insert(key(KeyCode::Down, ctrl()), Action::EditNext);
+        bindings.insert(key(KeyCode::Char('u'), none()), Action::JumpToUnread);
+        bindings.insert(key(KeyCode::Char('@'), none()), Action::NextMention);
+        bindings.insert(key(KeyCode::Char('@'), shift()), Action::NextMention);
+        bindings.insert(key(KeyCode::Left, alt()), Action::JumpBack);
+        bindings.insert(key(KeyCode::Right, alt()), Action::JumpForward);
+        bindings.insert(key(KeyCode::Char('{'), none()), Action::PreviousDay);
+        bindings.insert(key(KeyCode::Char('{'), shift()), Action::PreviousDay);
+        bindings.insert(key(KeyCode::Char('}'), none()), Action::NextDay);
+        bindings.insert(key(KeyCode::Char('}'), shift()), Action::NextDay);
+        bindings.insert(key(KeyCode::Char('r'), alt()), Action::ShowReactions);
+        bindings.insert(key(KeyCode::Char('i'), alt()), Action::ShowInbox);
+        bindings.insert(key(KeyCode::Char('b'), alt()), Action::ShowBookmarks);
+        bindings.insert(key(KeyCode::Char('p'), alt()), Action::ShowLocalPins);
+        bindings.insert(key(KeyCode::Char('e'), alt()), Action::ShowErrors);
+        bindings.insert(key(KeyCode::Char('a'), alt()), Action::ChatActions);
+        bindings.insert(key(KeyCode::Char('o'), alt()), Action::OpenInTelegram);
+        bindings.insert(key(KeyCode::Char('v'), alt()), Action::ToggleSplit);
+        bindings.insert(key(KeyCode::Char('w'), alt()), Action::SwitchSplit);
+        bindings.insert(key(KeyCode::Char('s'), none()), Action::SaveMedia);
+        bindings.insert(key(KeyCode::Char('d'), none()), Action::OpenDiscussion);
+        bindings.insert(key(KeyCode::Char('!'), none()), Action::ReportMessage);
+        bindings.insert(key(KeyCode::Char('!'), shift()), Action::ReportMessage);
+        bindings.insert(key(KeyCode::Char('+'), none()), Action::React);
+        bindings.insert(key(KeyCode::Char('+'), shift()), Action::React);
+        bindings.insert(key(KeyCode::Char('b'), none()), Action::Bookmark);
+        bindings.insert(key(KeyCode::Char('P'), none()), Action::LocalPin);
+        bindings.insert(key(KeyCode::Char('P'), shift()), Action::LocalPin);
+        bindings.insert(key(KeyCode::Char('O'), none()), Action::JumpToOriginal);
+        bindings.insert(key(KeyCode::Char('O'), shift()), Action::JumpToOriginal);
+        bindings.insert(key(KeyCode::Char('R'), none()), Action::JumpToReply);
+        bindings.insert(key(KeyCode::Char('R'), shift()), Action::JumpToReply);
 
         // =====================================================================
         // Mode-specific bindings
@@ -293,7 +468,9 @@ impl KeyMap {
         bindings.insert(key(KeyCode::Char('e'), none()), Action::Edit);
         bindings.insert(key(KeyCode::Char('x'), none()), Action::Delete);
         bindings.insert(key(KeyCode::Char('f'), none()), Action::Forward);
+        bindings.insert(key(KeyCode::Char('y'), none()), Action::CopyMessage);
         bindings.insert(key(KeyCode::Char('o'), none()), Action::OpenMedia);
+        bindings.insert(key(KeyCode::Char('o'), ctrl()), Action::JumpBack);
     }
 
     /// Add standard key bindings.
@@ -305,6 +482,8 @@ impl KeyMap {
         bindings.insert(key(KeyCode::F(5), none()), Action::MarkAsRead);
         bindings.insert(key(KeyCode::F(2), none()), Action::PinChat);
         bindings.insert(key(KeyCode::F(3), none()), Action::MuteChat);
+        bindings.insert(key(KeyCode::F(4), none()), Action::Forward);
+        bindings.insert(key(KeyCode::Char('y'), ctrl()), Action::CopyMessage);
     }
 
     /// Get the action for a key event.
@@ -379,15 +558,49 @@ impl KeyMap {
                 ("e", "Edit"),
                 ("x", "Delete"),
                 ("f", "Forward"),
-                ("o", "Open media"),
+                ("y", "Copy message text"),
+                ("o", "Open media or poll"),
+                ("s", "Save attachment"),
                 ("Ctrl+T", "Attach file"),
+                ("Alt+C", "Image as photo or file (input)"),
+                ("↑ (empty)", "Edit last sent"),
+                ("Ctrl+↑/↓", "Cycle own edits"),
+                ("Ctrl+P/N", "Recall sent text (input)"),
+                ("Ctrl+E", "Compose in $EDITOR (input)"),
+                ("/help", "Slash commands (input)"),
                 ("p", "Pin/unpin"),
                 ("m", "Mute/unmute"),
+                ("d", "Channel discussion"),
                 ("Tab", "Next pane"),
                 ("Shift+Tab", "Previous pane"),
                 ("Ctrl+S", "Toggle sidebar / Save"),
+                ("Ctrl+K", "Jump to chat"),
+                ("Ctrl+G", "Jump to date"),
+                ("u", "First unread (conversation)"),
+                ("@", "Next mention of me"),
+                ("Ctrl+O/Alt+←", "Jump back"),
+                ("Alt+→", "Jump forward"),
+                ("Alt+R", "Reactions to my messages"),
+                ("Alt+I", "Unread inbox"),
+                ("Alt+B", "Bookmarks"),
+                ("Alt+P", "Local pins in this chat"),
+                ("Alt+E", "Error history"),
+                ("Alt+A", "Chat actions menu"),
+                ("Alt+O", "Open in Telegram"),
+                ("Alt+V", "Split/unsplit conversation"),
+                ("Alt+W", "Switch split side"),
+                ("!", "Report message"),
+                ("+", "React to message"),
+                ("b", "Bookmark message"),
+                ("P", "Pin message locally"),
+                ("O", "Original of a forward"),
+                ("R", "Replied message (back to return)"),
+                ("Ctrl+L", "Lock screen"),
                 ("Ctrl+P/F12", "Open settings"),
                 ("S", "Toggle stealth mode"),
+                ("v", "Reveal preview (stealth)"),
+                ("z/Z", "Collapse/expand chat section"),
+                ("Space", "Leader sequences"),
                 ("?", "Toggle help"),
                 ("Esc", "Back / Cancel"),
                 ("Ctrl+Q", "Quit"),
@@ -402,15 +615,49 @@ impl KeyMap {
                 ("Ctrl+R", "Reply"),
                 ("Ctrl+E", "Edit"),
                 ("Ctrl+O", "Open media"),
+                ("s", "Save attachment"),
+                ("Ctrl+Y", "Copy message text"),
                 ("Ctrl+T", "Attach file"),
+                ("Alt+C", "Image as photo or file (input)"),
+                ("↑ (empty)", "Edit last sent"),
+                ("Ctrl+↑/↓", "Cycle own edits"),
+                ("Ctrl+P/N", "Recall sent text (input)"),
+                ("Ctrl+E", "Compose in $EDITOR (input)"),
+                ("/help", "Slash commands (input)"),
                 ("F2", "Pin/unpin"),
                 ("F3", "Mute/unmute"),
+                ("d", "Channel discussion"),
+                ("F4", "Forward"),
                 ("F5", "Mark as read"),
                 ("Tab", "Next pane"),
                 ("Shift+Tab", "Previous pane"),
                 ("Ctrl+S", "Toggle sidebar / Save"),
+                ("Ctrl+K", "Jump to chat"),
+                ("Ctrl+G", "Jump to date"),
+                ("u", "First unread (conversation)"),
+                ("@", "Next mention of me"),
+                ("Alt+←/→", "Jump back/forward"),
+                ("Alt+R", "Reactions to my messages"),
+                ("Alt+I", "Unread inbox"),
+                ("Alt+B", "Bookmarks"),
+                ("Alt+P", "Local pins in this chat"),
+                ("Alt+E", "Error history"),
+                ("Alt+A", "Chat actions menu"),
+                ("Alt+O", "Open in Telegram"),
+                ("Alt+V", "Split/unsplit conversation"),
+                ("Alt+W", "Switch split side"),
+                ("!", "Report message"),
+                ("+", "React to message"),
+                ("b", "Bookmark message"),
+                ("P", "Pin message locally"),
+                ("O", "Original of a forward"),
+                ("R", "Replied message (back to return)"),
+                ("Ctrl+L", "Lock screen"),
                 ("Ctrl+P/F12", "Open settings"),
                 ("S", "Toggle stealth mode"),
+                ("v", "Reveal preview (stealth)"),
+                ("z/Z", "Collapse/expand chat section"),
+                ("Space", "Leader sequences"),
                 ("?", "Toggle help"),
                 ("Esc", "Back / Cancel"),
                 ("Ctrl+Q", "Quit"),
@@ -462,9 +709,8 @@ const fn shift() -> KeyModifiers {
     KeyModifiers::SHIFT
 }
 
-/// Alt modifier (unused but available for future use).
+/// Alt modifier.
 #[inline]
-#[allow(dead_code)]
 const fn alt() -> KeyModifiers {
     KeyModifiers::ALT
 }
@@ -571,6 +817,16 @@ mod tests {
         assert_eq!(format!("{}", Action::FocusInput), "Focus Input");
     }
 
+    #[test]
+    fn test_action_from_name() {
+        assert_eq!(
+            Action::from_name("mark_all_as_read"),
+            Some(Action::MarkAllAsRead)
+        );
+        assert_eq!(Action::from_name("Quick-Switch"), Some(Action::QuickSwitch));
+        assert_eq!(Action::from_name("reply"), None);
+    }
+
     #[test]
     fn test_default_keymap() {
         let keymap = KeyMap::default();
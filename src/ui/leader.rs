@@ -0,0 +1,319 @@
+//! Leader-key sequences for power users.
+//!
+//! Pressing the leader key (`Space` by default) starts a sequence of plain
+//! keys, like vim's `<leader>`: `Space m a` marks every chat read. The
+//! sequences live in the config under `ui.keyboard.leader_bindings`, each
+//! mapping space-separated keys to a global action name or a slash command:
+//!
+//! ```yaml
+//! ui:
+//!   keyboard:
+//!     leader: "space"
+//!     leader_bindings:
+//!       "m a": "mark_all_as_read"
+//!       "g s": "/goto Saved Messages"
+//! ```
+//!
+//! While a sequence is being typed, a hint popup lists the keys that can
+//! come next.
+//!
+//! # Example
+//!
+//! ```rust
+//! use std::collections::HashMap;
+//! use ithil::ui::keys::Action;
+//! use ithil::ui::leader::{LeaderCommand, LeaderKeys, LeaderStep};
+//!
+//! let bindings = HashMap::from([("m a".to_string(), "mark_all_as_read".to_string())]);
+//! let (leader, errors) = LeaderKeys::new("space", &bindings);
+//! assert!(errors.is_empty());
+//! assert_eq!(leader.step("m"), LeaderStep::Pending);
+//! assert_eq!(
+//!     leader.step("ma"),
+//!     LeaderStep::Run(LeaderCommand::Action(Action::MarkAllAsRead))
+//! );
+//! ```
+
+use std::collections::HashMap;
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+
+use super::keys::Action;
+
+/// What a leader sequence runs.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum LeaderCommand {
+    /// A global action, named in the config in `snake_case`
+    Action(Action),
+    /// A slash command, run as if typed into the input
+    Slash(String),
+}
+
+impl LeaderCommand {
+    /// Parses a binding's target: a slash command if it starts with `/`,
+    /// otherwise an action name.
+    ///
+    /// # Errors
+    ///
+    /// Returns a message if the action name is unknown.
+    pub fn parse(target: &str) -> Result<Self, String> {
+        let target = target.trim();
+        if target.starts_with('/') {
+            return Ok(Self::Slash(target.to_string()));
+        }
+        Action::from_name(target)
+            .map(Self::Action)
+            .ok_or_else(|| format!("unknown action \"{target}\""))
+    }
+
+    /// Returns a short description for the hint popup.
+    #[must_use]
+    pub fn description(&self) -> String {
+        match self {
+            Self::Action(action) => action.to_string(),
+            Self::Slash(command) => command.clone(),
+        }
+    }
+}
+
+/// Outcome of typing a key in a leader sequence.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum LeaderStep {
+    /// The keys so far start one or more sequences; wait for more
+    Pending,
+    /// The keys complete a sequence
+    Run(LeaderCommand),
+    /// No sequence starts with the keys
+    Unbound,
+}
+
+/// The leader key and its sequences.
+#[derive(Debug, Clone, Default)]
+pub struct LeaderKeys {
+    /// `None` when sequences are turned off
+    leader: Option<KeyEvent>,
+    /// Sequences as the keys typed after the leader, e.g. `"ma"`
+    bindings: Vec<(String, LeaderCommand)>,
+}
+
+impl LeaderKeys {
+    /// Builds the leader keys from the config's `leader` and
+    /// `leader_bindings`.
+    ///
+    /// Bindings that can't be understood are skipped and described in the
+    /// returned list, so one typo doesn't disable the rest. An empty
+    /// `leader` turns sequences off.
+    #[must_use]
+    pub fn new(leader: &str, bindings: &HashMap<String, String>) -> (Self, Vec<String>) {
+        let mut errors = Vec::new();
+        let leader = match parse_leader(leader) {
+            Ok(leader) => leader,
+            Err(e) => {
+                errors.push(e);
+                None
+            },
+        };
+
+        let mut parsed: Vec<(String, LeaderCommand)> = Vec::new();
+        for (keys, target) in bindings {
+            let sequence = match parse_sequence(keys) {
+                Ok(sequence) => sequence,
+                Err(e) => {
+                    errors.push(format!("leader binding \"{keys}\": {e}"));
+                    continue;
+                },
+            };
+            match LeaderCommand::parse(target) {
+                Ok(command) => parsed.push((sequence, command)),
+                Err(e) => errors.push(format!("leader binding \"{keys}\": {e}")),
+            }
+        }
+        parsed.sort_by(|a, b| a.0.cmp(&b.0));
+        errors.sort();
+
+        (
+            Self {
+                leader,
+                bindings: parsed,
+            },
+            errors,
+        )
+    }
+
+    /// Returns `true` if `key` is the leader key.
+    #[must_use]
+    pub fn is_leader(&self, key: &KeyEvent) -> bool {
+        self.leader
+            .is_some_and(|leader| leader.code == key.code && leader.modifiers == key.modifiers)
+            && !self.bindings.is_empty()
+    }
+
+    /// Looks up the keys typed after the leader.
+    #[must_use]
+    pub fn step(&self, typed: &str) -> LeaderStep {
+        if let Some((_, command)) = self.bindings.iter().find(|(keys, _)| keys == typed) {
+            return LeaderStep::Run(command.clone());
+        }
+        if self
+            .bindings
+            .iter()
+            .any(|(keys, _)| keys.starts_with(typed))
+        {
+            LeaderStep::Pending
+        } else {
+            LeaderStep::Unbound
+        }
+    }
+
+    /// Lists the keys that can follow `typed`, with what each does.
+    ///
+    /// A key that starts several sequences is described as a group, e.g.
+    /// `"+2 more"`.
+    #[must_use]
+    pub fn continuations(&self, typed: &str) -> Vec<(char, String)> {
+        let mut next: Vec<(char, String)> = Vec::new();
+        for (keys, command) in &self.bindings {
+            let Some(rest) = keys.strip_prefix(typed) else {
+                continue;
+            };
+            let mut chars = rest.chars();
+            let Some(key) = chars.next() else {
+                continue;
+            };
+            let is_group = chars.next().is_some();
+            match next.iter_mut().find(|(k, _)| *k == key) {
+                Some(entry) => entry.1 = String::new(),
+                None if is_group => next.push((key, String::new())),
+                None => next.push((key, command.description())),
+            }
+        }
+
+        // Groups are filled in once all their sequences are counted
+        for (key, description) in &mut next {
+            if description.is_empty() {
+                let prefix = format!("{typed}{key}");
+                let count = self
+                    .bindings
+                    .iter()
+                    .filter(|(keys, _)| keys.starts_with(&prefix))
+                    .count();
+                *description = format!("+{count} more");
+            }
+        }
+        next
+    }
+
+    /// Returns the leader key's label for the hint popup, e.g. `"Space"`.
+    #[must_use]
+    pub fn leader_label(&self) -> String {
+        match self.leader.map(|k| k.code) {
+            Some(KeyCode::Char(' ')) => "Space".to_string(),
+            Some(KeyCode::Char(c)) => c.to_string(),
+            _ => String::new(),
+        }
+    }
+}
+
+/// Parses the configured leader: `"space"`, a single character, or empty
+/// for none.
+fn parse_leader(leader: &str) -> Result<Option<KeyEvent>, String> {
+    let leader = leader.trim();
+    if leader.is_empty() {
+        return Ok(None);
+    }
+    if leader.eq_ignore_ascii_case("space") {
+        return Ok(Some(KeyEvent::new(KeyCode::Char(' '), KeyModifiers::NONE)));
+    }
+    let mut chars = leader.chars();
+    match (chars.next(), chars.next()) {
+        (Some(c), None) => {
+            let modifiers = if c.is_uppercase() {
+                KeyModifiers::SHIFT
+            } else {
+                KeyModifiers::NONE
+            };
+            Ok(Some(KeyEvent::new(KeyCode::Char(c), modifiers)))
+        },
+        _ => Err(format!(
+            "leader \"{leader}\" must be \"space\" or a single character"
+        )),
+    }
+}
+
+/// Parses space-separated single keys, e.g. `"m a"`, into `"ma"`.
+fn parse_sequence(keys: &str) -> Result<String, String> {
+    let mut sequence = String::new();
+    for token in keys.split_whitespace() {
+        let mut chars = token.chars();
+        match (chars.next(), chars.next()) {
+            (Some(c), None) => sequence.push(c),
+            _ => return Err(format!("\"{token}\" is not a single key")),
+        }
+    }
+    if sequence.is_empty() {
+        return Err("no keys".to_string());
+    }
+    Ok(sequence)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn leader(bindings: &[(&str, &str)]) -> (LeaderKeys, Vec<String>) {
+        let bindings = bindings
+            .iter()
+            .map(|(k, v)| ((*k).to_string(), (*v).to_string()))
+            .collect();
+        LeaderKeys::new("space", &bindings)
+    }
+
+    #[test]
+    fn sequences_run_after_their_last_key() {
+        let (keys, errors) = leader(&[("m a", "mark_all_as_read"), ("p", "/permissions")]);
+        assert!(errors.is_empty());
+        assert!(keys.is_leader(&KeyEvent::new(KeyCode::Char(' '), KeyModifiers::NONE)));
+
+        assert_eq!(keys.step("m"), LeaderStep::Pending);
+        assert_eq!(
+            keys.step("ma"),
+            LeaderStep::Run(LeaderCommand::Action(Action::MarkAllAsRead))
+        );
+        assert_eq!(
+            keys.step("p"),
+            LeaderStep::Run(LeaderCommand::Slash("/permissions".to_string()))
+        );
+        assert_eq!(keys.step("x"), LeaderStep::Unbound);
+    }
+
+    #[test]
+    fn continuations_group_shared_prefixes() {
+        let (keys, _) = leader(&[
+            ("m a", "mark_all_as_read"),
+            ("m r", "mark_as_read"),
+            ("l", "lock"),
+        ]);
+        assert_eq!(
+            keys.continuations(""),
+            vec![('l', "Lock".to_string()), ('m', "+2 more".to_string())]
+        );
+        assert_eq!(
+            keys.continuations("m"),
+            vec![
+                ('a', "Mark All As Read".to_string()),
+                ('r', "Mark As Read".to_string())
+            ]
+        );
+    }
+
+    #[test]
+    fn bad_bindings_are_reported_and_skipped() {
+        let (keys, errors) = leader(&[("ctrl+x", "lock"), ("q", "fly"), ("l", "lock")]);
+        assert_eq!(errors.len(), 2);
+        assert_eq!(keys.continuations(""), vec![('l', "Lock".to_string())]);
+
+        let (off, errors) = LeaderKeys::new("", &HashMap::new());
+        assert!(errors.is_empty());
+        assert!(!off.is_leader(&KeyEvent::new(KeyCode::Char(' '), KeyModifiers::NONE)));
+    }
+}
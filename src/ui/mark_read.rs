@@ -0,0 +1,114 @@
+//! Marking chats read in the background (`/readall`, the inbox's `r`).
+//!
+//! Telegram is asked in batches with pauses in between, and a flood wait
+//! can hold a run for up to half a minute, so the run is a task of its own.
+//! The app goes on drawing and handling keys meanwhile, and collects how far
+//! the run got, and how it ended, every tick. Dropping a run stops it.
+
+use std::sync::Arc;
+
+use tokio::sync::mpsc;
+use tokio::task::JoinHandle;
+
+use crate::telegram::{TelegramApi, TelegramError};
+
+/// What a run reports.
+#[derive(Debug)]
+pub enum MarkReadEvent {
+    /// This many chats are marked so far
+    Marked(usize),
+    /// The run ended, with how many chats were marked
+    Done(Result<usize, TelegramError>),
+}
+
+/// A run marking chats read.
+#[derive(Debug)]
+pub struct MarkRead {
+    chat_ids: Vec<i64>,
+    events: mpsc::UnboundedReceiver<MarkReadEvent>,
+    task: JoinHandle<()>,
+}
+
+impl MarkRead {
+    /// Starts marking `chat_ids` read through `telegram`.
+    ///
+    /// Must be called from within a Tokio runtime.
+    #[must_use]
+    pub fn start(telegram: Arc<dyn TelegramApi>, chat_ids: Vec<i64>) -> Self {
+        let (tx, events) = mpsc::unbounded_channel();
+        let ids = chat_ids.clone();
+        let task = tokio::spawn(async move {
+            let marked = tx.clone();
+            let progress = move |count| {
+                let _ = marked.send(MarkReadEvent::Marked(count));
+            };
+            let result = telegram.mark_chats_as_read(&ids, &progress).await;
+            let _ = tx.send(MarkReadEvent::Done(result));
+        });
+        Self {
+            chat_ids,
+            events,
+            task,
+        }
+    }
+
+    /// Returns the chats being marked.
+    #[must_use]
+    pub fn chat_ids(&self) -> &[i64] {
+        &self.chat_ids
+    }
+
+    /// Returns what the run reported since the last call, in order.
+    pub fn events(&mut self) -> Vec<MarkReadEvent> {
+        std::iter::from_fn(|| self.events.try_recv().ok()).collect()
+    }
+}
+
+impl Drop for MarkRead {
+    fn drop(&mut self) {
+        self.task.abort();
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::cache::new_shared_cache;
+    use crate::telegram::fake::{Call, FakeTelegram};
+
+    #[tokio::test]
+    async fn reports_each_chat_and_then_the_count() {
+        let telegram = Arc::new(FakeTelegram::new(new_shared_cache(100)).logged_in());
+        let mut run = MarkRead::start(telegram.clone(), vec![1, 2, 3]);
+        assert_eq!(run.chat_ids(), [1, 2, 3]);
+
+        let mut events = Vec::new();
+        for _ in 0..10 {
+            tokio::task::yield_now().await;
+            events.extend(run.events());
+        }
+        let marked: Vec<usize> = events
+            .iter()
+            .filter_map(|e| match e {
+                MarkReadEvent::Marked(n) => Some(*n),
+                MarkReadEvent::Done(_) => None,
+            })
+            .collect();
+        assert_eq!(marked, [1, 2, 3]);
+        assert!(matches!(events.last(), Some(MarkReadEvent::Done(Ok(3)))));
+        assert!(telegram.calls().contains(&Call::MarkAsRead(3)));
+    }
+
+    #[tokio::test]
+    async fn dropping_the_run_stops_it() {
+        let telegram = Arc::new(FakeTelegram::new(new_shared_cache(100)).logged_in());
+        drop(MarkRead::start(telegram.clone(), vec![1, 2, 3]));
+        for _ in 0..10 {
+            tokio::task::yield_now().await;
+        }
+        assert!(!telegram
+            .calls()
+            .iter()
+            .any(|call| matches!(call, Call::MarkAsRead(_))));
+    }
+}
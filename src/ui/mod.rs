@@ -6,9 +6,17 @@
 //! # Modules
 //!
 //! - [`app`]: Main application state machine and rendering
+//! - [`bulk_download`]: Saving a chat's attachments in bulk (`/download`)
 //! - [`components`]: Reusable UI components (input, auth, etc.)
+//! - [`editor`]: External `$EDITOR` support for the composer
+//! - [`inbox_fetch`]: Fetching the inbox's unread messages in the background
 //! - [`keys`]: Key bindings system with Vim/standard mode support
+//! - [`leader`]: Leader-key sequences defined in the config
+//! - [`mark_read`]: Marking chats read in the background
+//! - [`search`]: Search queries with `from:`/`in:`/`has:`/`before:` filters
+//! - [`send_queue`]: Sending in the background, in order per chat
 //! - [`styles`]: Theme-aware color palettes and pre-built styles
+//! - [`voice_queue`]: Playing a chat's voice messages in turn (`/listen`)
 //!
 //! # Quick Start
 //!
@@ -30,9 +38,17 @@
 //! ```
 
 pub mod app;
+pub mod bulk_download;
 pub mod components;
+pub mod editor;
+pub mod inbox_fetch;
 pub mod keys;
+pub mod leader;
+pub mod mark_read;
+pub mod search;
+pub mod send_queue;
 pub mod styles;
+pub mod voice_queue;
 
 pub use app::{App, AppAction, AppState, FocusedPane};
 pub use components::{AuthAction, AuthModel, InputComponent};
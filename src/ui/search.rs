@@ -0,0 +1,208 @@
+//! Search queries with filter operators (`/find`).
+//!
+//! Besides plain words, a query can narrow the results with operators:
+//!
+//! | Operator            | Keeps                                      |
+//! |---------------------|--------------------------------------------|
+//! | `from:alice`        | Messages whose sender's name contains it   |
+//! | `from:me`           | My own messages                            |
+//! | `in:work`           | Messages in the best-matching chat         |
+//! | `has:photo`         | Photos (also `media`, `video`, `file`, `voice`, `audio`, `link`, `pinned`) |
+//! | `before:2024-01-01` | Messages sent before the day               |
+//! | `after:2w`          | Messages sent on or after the day          |
+//!
+//! Values with spaces can be quoted: `from:"Alice Smith"`. Dates take the
+//! same forms as "jump to date". Anything else, including unknown
+//! `word:value` pairs such as links, is searched for as text.
+//!
+//! # Example
+//!
+//! ```rust
+//! use chrono::NaiveDate;
+//! use ithil::types::SearchFilter;
+//! use ithil::ui::search::SearchQuery;
+//!
+//! let today = NaiveDate::from_ymd_opt(2024, 3, 15).unwrap();
+//! let query = SearchQuery::parse("from:alice has:photo beach", today).unwrap();
+//! assert_eq!(query.text, "beach");
+//! assert_eq!(query.from.as_deref(), Some("alice"));
+//! assert_eq!(query.has, Some(SearchFilter::Photo));
+//! ```
+
+use chrono::{Local, NaiveDate};
+
+use crate::types::{Message, SearchFilter};
+use crate::utils::parse_date;
+
+/// A parsed search query.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct SearchQuery {
+    /// Words to search for, operators removed
+    pub text: String,
+    /// Sender name to match (`from:`); `"me"` means my own messages
+    pub from: Option<String>,
+    /// Chat name to search in (`in:`)
+    pub in_chat: Option<String>,
+    /// Kind of message (`has:`)
+    pub has: Option<SearchFilter>,
+    /// Only messages sent before this day (`before:`)
+    pub before: Option<NaiveDate>,
+    /// Only messages sent on or after this day (`after:`)
+    pub after: Option<NaiveDate>,
+}
+
+impl SearchQuery {
+    /// Parses a query, reading relative dates against `today`.
+    ///
+    /// # Errors
+    ///
+    /// Returns a message if an operator's value can't be understood, or if
+    /// the query is empty.
+    pub fn parse(input: &str, today: NaiveDate) -> Result<Self, String> {
+        let mut query = Self::default();
+        let mut words = Vec::new();
+
+        for token in split_quoted(input) {
+            let Some((key, value)) = token.split_once(':') else {
+                words.push(token);
+                continue;
+            };
+            let date = || {
+                parse_date(value, today).ok_or_else(|| format!("{key}: \"{value}\" is not a date"))
+            };
+            match key.to_lowercase().as_str() {
+                "from" if !value.is_empty() => query.from = Some(value.to_string()),
+                "in" if !value.is_empty() => query.in_chat = Some(value.to_string()),
+                "has" => {
+                    query.has = Some(SearchFilter::from_name(value).ok_or_else(|| {
+                        format!("has: \"{value}\" is not one of media, photo, video, file, voice, audio, link or pinned")
+                    })?);
+                },
+                "before" => query.before = Some(date()?),
+                "after" => query.after = Some(date()?),
+                _ => words.push(token),
+            }
+        }
+
+        query.text = words.join(" ");
+        if query == Self::default() {
+            return Err("Type something to search for".to_string());
+        }
+        Ok(query)
+    }
+
+    /// Returns `true` if the query only has words, so chats can match it
+    /// by name.
+    #[must_use]
+    pub fn is_plain(&self) -> bool {
+        self.from.is_none()
+            && self.in_chat.is_none()
+            && self.has.is_none()
+            && self.before.is_none()
+            && self.after.is_none()
+    }
+
+    /// Returns `true` if `message`, sent by `sender`, matches every part of
+    /// the query except `in:`, which picks where to search.
+    #[must_use]
+    pub fn matches(&self, message: &Message, sender: &str) -> bool {
+        let text = &self.text;
+        let day = message.date.with_timezone(&Local).date_naive();
+
+        (text.is_empty()
+            || contains_ignore_case(&message.content.text, text)
+            || contains_ignore_case(&message.content.caption, text))
+            && self.from.as_deref().map_or(true, |from| {
+                if from.eq_ignore_ascii_case("me") {
+                    message.is_outgoing
+                } else {
+                    contains_ignore_case(sender, from)
+                }
+            })
+            && self.has.map_or(true, |has| has.matches(message))
+            && self.before.map_or(true, |before| day < before)
+            && self.after.map_or(true, |after| day >= after)
+    }
+}
+
+/// Returns `true` if `haystack` contains `needle`, ignoring case.
+fn contains_ignore_case(haystack: &str, needle: &str) -> bool {
+    haystack.to_lowercase().contains(&needle.to_lowercase())
+}
+
+/// Splits on whitespace, keeping double-quoted runs together and dropping
+/// the quotes.
+fn split_quoted(input: &str) -> Vec<String> {
+    let mut tokens = Vec::new();
+    let mut current = String::new();
+    let mut quoted = false;
+    for c in input.chars() {
+        match c {
+            '"' => quoted = !quoted,
+            c if c.is_whitespace() && !quoted => {
+                if !current.is_empty() {
+                    tokens.push(std::mem::take(&mut current));
+                }
+            },
+            c => current.push(c),
+        }
+    }
+    if !current.is_empty() {
+        tokens.push(current);
+    }
+    tokens
+}
+
+#[cfg(test)]
+mod tests {
+    use chrono::{TimeZone, Utc};
+
+    use super::*;
+
+    fn today() -> NaiveDate {
+        NaiveDate::from_ymd_opt(2024, 3, 15).unwrap()
+    }
+
+    #[test]
+    fn operators_are_split_from_words() {
+        let query = SearchQuery::parse(
+            r#"in:"Work chat" from:"Alice Smith" before:2024-01-01 after:2023-12-01 report https://x.io"#,
+            today(),
+        )
+        .unwrap();
+        assert_eq!(query.text, "report https://x.io");
+        assert_eq!(query.in_chat.as_deref(), Some("Work chat"));
+        assert_eq!(query.from.as_deref(), Some("Alice Smith"));
+        assert_eq!(query.before, NaiveDate::from_ymd_opt(2024, 1, 1));
+        assert_eq!(query.after, NaiveDate::from_ymd_opt(2023, 12, 1));
+        assert!(!query.is_plain());
+    }
+
+    #[test]
+    fn bad_values_and_empty_queries_are_errors() {
+        assert!(SearchQuery::parse("has:hologram", today()).is_err());
+        assert!(SearchQuery::parse("before:someday", today()).is_err());
+        assert!(SearchQuery::parse("   ", today()).is_err());
+        assert!(SearchQuery::parse("has:link", today()).is_ok());
+    }
+
+    #[test]
+    fn matches_every_filter() {
+        let query = SearchQuery::parse("from:ali before:2024-03-01 lunch", today()).unwrap();
+        let mut message = Message {
+            date: Utc.with_ymd_and_hms(2024, 2, 20, 12, 0, 0).unwrap(),
+            ..Default::default()
+        };
+        message.content.text = "Lunch at noon?".to_string();
+
+        assert!(query.matches(&message, "Alice"));
+        assert!(!query.matches(&message, "Bob"));
+
+        message.date = Utc.with_ymd_and_hms(2024, 3, 5, 12, 0, 0).unwrap();
+        assert!(!query.matches(&message, "Alice"), "too late");
+
+        let mine = SearchQuery::parse("from:me", today()).unwrap();
+        message.is_outgoing = true;
+        assert!(mine.matches(&message, "Me"));
+    }
+}
@@ -0,0 +1,303 @@
+//! Sending messages and files in the background, in order per chat.
+//!
+//! Each chat sent to gets a lane: a task that takes that chat's sends one at
+//! a time, in the order they were made, so a quick second message never
+//! overtakes the first. Lanes run side by side, so a slow upload to one chat
+//! doesn't hold up another, and the app goes on handling keys while sends
+//! are in flight. The app collects what was sent, or what failed, every
+//! tick. Dropping the queue stops every lane, along with what it still had
+//! to send.
+
+use std::collections::HashMap;
+use std::path::PathBuf;
+use std::sync::Arc;
+
+use tokio::sync::mpsc;
+use tokio::task::JoinHandle;
+
+use crate::telegram::messages::MAX_ALBUM_SIZE;
+use crate::telegram::{TelegramApi, TelegramError};
+use crate::types::Message;
+use crate::utils::is_image;
+
+/// Something to send to a chat.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum Outgoing {
+    /// A text message, posted as `send_as` if set
+    Text {
+        text: String,
+        reply_to: Option<i64>,
+        send_as: Option<i64>,
+    },
+    /// Files with one caption, which goes with the first. Several images
+    /// going as photos are sent as albums of up to ten; otherwise each file
+    /// is sent in turn.
+    Files {
+        caption: String,
+        paths: Vec<PathBuf>,
+        as_photo: bool,
+        reply_to: Option<i64>,
+    },
+}
+
+impl Outgoing {
+    /// What is being sent, for error messages.
+    fn what(&self) -> &'static str {
+        match self {
+            Self::Text { .. } => "message",
+            Self::Files { paths, .. } if paths.len() == 1 => "file",
+            Self::Files { .. } => "files",
+        }
+    }
+}
+
+/// The outcome of one [`Outgoing`].
+#[derive(Debug)]
+pub struct Sent {
+    /// Chat it was sent to
+    pub chat_id: i64,
+    /// What was sent ("message", "file" or "files")
+    pub what: &'static str,
+    /// Messages Telegram accepted, even if a later file failed
+    pub messages: Vec<Message>,
+    /// Why the send stopped short, if it did
+    pub error: Option<TelegramError>,
+}
+
+/// A chat's lane: its queue, and the task sending from it.
+struct Lane {
+    queue: mpsc::UnboundedSender<Outgoing>,
+    task: JoinHandle<()>,
+}
+
+/// Sends to each chat in order, and to different chats at once.
+pub struct SendQueue {
+    telegram: Arc<dyn TelegramApi>,
+    lanes: HashMap<i64, Lane>,
+    done_tx: mpsc::UnboundedSender<Sent>,
+    done_rx: mpsc::UnboundedReceiver<Sent>,
+    /// Sends queued and not yet collected
+    pending: usize,
+}
+
+impl std::fmt::Debug for SendQueue {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        f.debug_struct("SendQueue")
+            .field("lanes", &self.lanes.len())
+            .field("pending", &self.pending)
+            .finish_non_exhaustive()
+    }
+}
+
+impl SendQueue {
+    /// Creates a queue sending through `telegram`.
+    #[must_use]
+    pub fn new(telegram: Arc<dyn TelegramApi>) -> Self {
+        let (done_tx, done_rx) = mpsc::unbounded_channel();
+        Self {
+            telegram,
+            lanes: HashMap::new(),
+            done_tx,
+            done_rx,
+            pending: 0,
+        }
+    }
+
+    /// Queues `outgoing` behind the chat's earlier sends.
+    ///
+    /// Must be called from within a Tokio runtime.
+    pub fn push(&mut self, chat_id: i64, outgoing: Outgoing) {
+        self.pending += 1;
+        let outgoing = match self
+            .lanes
+            .get(&chat_id)
+            .map(|lane| lane.queue.send(outgoing))
+        {
+            None => outgoing,
+            Some(Ok(())) => return,
+            // The lane's task is gone; start another
+            Some(Err(mpsc::error::SendError(outgoing))) => outgoing,
+        };
+        let (queue, rx) = mpsc::unbounded_channel();
+        let task = tokio::spawn(run_lane(
+            Arc::clone(&self.telegram),
+            chat_id,
+            rx,
+            self.done_tx.clone(),
+        ));
+        // Can't fail: the lane was just started
+        let _ = queue.send(outgoing);
+        self.lanes.insert(chat_id, Lane { queue, task });
+    }
+
+    /// Returns the sends that have finished since the last call, in the
+    /// order they finished.
+    pub fn finished(&mut self) -> Vec<Sent> {
+        let sent: Vec<Sent> = std::iter::from_fn(|| self.done_rx.try_recv().ok()).collect();
+        self.pending = self.pending.saturating_sub(sent.len());
+        sent
+    }
+
+    /// Returns how many sends are queued or in flight.
+    #[must_use]
+    pub const fn pending(&self) -> usize {
+        self.pending
+    }
+}
+
+impl Drop for SendQueue {
+    fn drop(&mut self) {
+        for lane in self.lanes.values() {
+            lane.task.abort();
+        }
+    }
+}
+
+/// Sends a chat's queue one at a time until the queue is dropped.
+async fn run_lane(
+    telegram: Arc<dyn TelegramApi>,
+    chat_id: i64,
+    mut queue: mpsc::UnboundedReceiver<Outgoing>,
+    done: mpsc::UnboundedSender<Sent>,
+) {
+    while let Some(outgoing) = queue.recv().await {
+        let sent = send(telegram.as_ref(), chat_id, &outgoing).await;
+        if done.send(sent).is_err() {
+            break;
+        }
+    }
+}
+
+async fn send(telegram: &dyn TelegramApi, chat_id: i64, outgoing: &Outgoing) -> Sent {
+    let mut sent = Sent {
+        chat_id,
+        what: outgoing.what(),
+        messages: Vec::new(),
+        error: None,
+    };
+    match outgoing {
+        Outgoing::Text {
+            text,
+            reply_to,
+            send_as,
+        } => match telegram
+            .send_message(chat_id, text, *reply_to, *send_as)
+            .await
+        {
+            Ok(message) => sent.messages.push(message),
+            Err(e) => sent.error = Some(e),
+        },
+        Outgoing::Files {
+            caption,
+            paths,
+            as_photo,
+            reply_to,
+        } => {
+            let album = paths.len() > 1 && *as_photo && paths.iter().all(|p| is_image(p));
+            let batch_size = if album { MAX_ALBUM_SIZE } else { 1 };
+            let mut caption = caption.as_str();
+            for batch in paths.chunks(batch_size) {
+                let result = if album {
+                    telegram
+                        .send_album(chat_id, caption, batch, *reply_to)
+                        .await
+                } else {
+                    telegram
+                        .send_file(chat_id, caption, &batch[0], *as_photo, *reply_to)
+                        .await
+                        .map(|message| vec![message])
+                };
+                match result {
+                    Ok(messages) => sent.messages.extend(messages),
+                    Err(e) => {
+                        sent.error = Some(e);
+                        break;
+                    },
+                }
+                caption = "";
+            }
+        },
+    }
+    sent
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::cache::new_shared_cache;
+    use crate::telegram::fake::{Call, FakeTelegram};
+    use crate::types::Chat;
+
+    fn text(text: &str) -> Outgoing {
+        Outgoing::Text {
+            text: text.to_string(),
+            reply_to: None,
+            send_as: None,
+        }
+    }
+
+    /// Lets the lanes run and collects what they finished.
+    async fn settle(queue: &mut SendQueue) -> Vec<Sent> {
+        for _ in 0..10 {
+            tokio::task::yield_now().await;
+        }
+        queue.finished()
+    }
+
+    #[tokio::test]
+    async fn chats_send_side_by_side_each_in_order() {
+        let chat = |id| Chat {
+            id,
+            ..Default::default()
+        };
+        let telegram = Arc::new(
+            FakeTelegram::new(new_shared_cache(100))
+                .logged_in()
+                .with_chat(chat(1), Vec::new())
+                .with_chat(chat(2), Vec::new()),
+        );
+        let sent_to = |chat_id| {
+            telegram
+                .calls()
+                .into_iter()
+                .filter_map(|call| match call {
+                    Call::SendMessage {
+                        chat_id: c, text, ..
+                    } if c == chat_id => Some(text),
+                    _ => None,
+                })
+                .collect::<Vec<_>>()
+        };
+        let mut queue = SendQueue::new(telegram.clone());
+
+        // The first chat's upload is slow; the second's message goes past it
+        let slow = telegram.hold_sends(1);
+        queue.push(1, text("first"));
+        queue.push(1, text("second"));
+        queue.push(2, text("hi"));
+        let done = settle(&mut queue).await;
+        assert_eq!(done.len(), 1);
+        assert_eq!(done[0].chat_id, 2);
+        assert_eq!(sent_to(2), ["hi"]);
+        assert!(sent_to(1).is_empty());
+        assert_eq!(queue.pending(), 2);
+
+        drop(slow);
+        let done = settle(&mut queue).await;
+        assert_eq!(done.len(), 2);
+        assert!(done.iter().all(|sent| sent.error.is_none()));
+        assert_eq!(sent_to(1), ["first", "second"]);
+        assert_eq!(queue.pending(), 0);
+
+        // Dropped, the queue sends nothing more
+        let slow = telegram.hold_sends(1);
+        queue.push(1, text("third"));
+        settle(&mut queue).await;
+        drop(queue);
+        drop(slow);
+        for _ in 0..10 {
+            tokio::task::yield_now().await;
+        }
+        assert_eq!(sent_to(1), ["first", "second"]);
+    }
+}
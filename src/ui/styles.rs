@@ -27,6 +27,29 @@ use std::sync::atomic::{AtomicU8, Ordering};
 // Theme enum and palette
 // =========================================================================
 
+/// Telegram's seven peer colors (red, orange, violet, green, cyan, blue,
+/// pink), as the official apps draw them.
+const PEER_COLORS: [Color; 7] = [
+    Color::Rgb(255, 132, 94),
+    Color::Rgb(254, 187, 91),
+    Color::Rgb(182, 148, 249),
+    Color::Rgb(154, 209, 100),
+    Color::Rgb(91, 203, 227),
+    Color::Rgb(92, 175, 250),
+    Color::Rgb(255, 138, 172),
+];
+
+/// The peer colors in the terminal's own palette, for the System theme.
+const PEER_COLORS_ANSI: [Color; 7] = [
+    Color::Red,
+    Color::Yellow,
+    Color::Magenta,
+    Color::Green,
+    Color::Cyan,
+    Color::Blue,
+    Color::LightMagenta,
+];
+
 /// Global theme index.
 static CURRENT_THEME: AtomicU8 = AtomicU8::new(0);
 
@@ -357,6 +380,18 @@ pub mod colors {
     pub fn decorative() -> Color {
         current_palette().decorative
     }
+
+    /// Returns the color Telegram's apps give the user or chat with this
+    /// (bare) ID: the seven peer colors in turn, by the ID's remainder.
+    #[must_use]
+    pub fn peer_color(id: i64) -> Color {
+        let index = usize::try_from(id.rem_euclid(7)).unwrap_or(0);
+        if super::Theme::current() == super::Theme::System {
+            super::PEER_COLORS_ANSI[index]
+        } else {
+            super::PEER_COLORS[index]
+        }
+    }
 }
 
 /// Pre-built styles for common UI elements.
@@ -444,6 +479,16 @@ impl Styles {
             .fg(colors::fg_bright())
     }
 
+    /// Initials avatar for the user or chat with this ID, in its peer
+    /// color.
+    #[must_use]
+    pub fn avatar(id: i64) -> Style {
+        Style::new()
+            .bg(colors::peer_color(id))
+            .fg(Color::White)
+            .add_modifier(Modifier::BOLD)
+    }
+
     /// Highlight style with bold modifier.
     #[must_use]
     pub fn highlight() -> Style {
@@ -508,6 +553,14 @@ impl Styles {
         Style::new().fg(colors::fg_primary())
     }
 
+    /// Style of a message matching a highlight word.
+    #[must_use]
+    pub fn message_highlight() -> Style {
+        Style::new()
+            .fg(colors::status_attention())
+            .add_modifier(Modifier::BOLD)
+    }
+
     /// System message style.
     #[must_use]
     pub fn message_system() -> Style {
@@ -595,6 +648,12 @@ impl Styles {
             .fg(colors::fg_bright())
             .add_modifier(Modifier::BOLD)
     }
+
+    /// QR code style: black on white in every theme, as scanners expect.
+    #[must_use]
+    pub const fn qr_code() -> Style {
+        Style::new().fg(Color::Black).bg(Color::White)
+    }
 }
 
 #[cfg(test)]
@@ -0,0 +1,264 @@
+//! Listening through a chat's voice messages in turn (`/listen`).
+//!
+//! `/listen` queues the open chat's unplayed voice messages, oldest first,
+//! and plays them one after another with the configured `voice_player`.
+//! Voice messages that arrive meanwhile join the end of the queue. Each is
+//! marked as played when it starts, as Telegram's own apps do, unless read
+//! receipts are off. `/listen next` skips ahead, `/listen stop` stops, and
+//! the status bar shows what's playing. The list and each download are
+//! fetched in the background, and the queue waits on them.
+
+use std::collections::VecDeque;
+use std::path::PathBuf;
+use std::process::Child;
+
+use tokio::sync::oneshot;
+
+use crate::telegram::TelegramError;
+use crate::types::{Message, MessageType};
+
+/// How far back `/listen` looks for unplayed voice messages.
+pub const LOOKBACK: usize = 100;
+
+/// What a queue waits on from the background.
+#[derive(Debug)]
+pub enum Fetched {
+    /// The chat's recent voice messages
+    Listed(Result<Vec<Message>, TelegramError>),
+    /// Where the current message was downloaded to
+    Downloaded(Result<PathBuf, TelegramError>),
+}
+
+/// A chat's unplayed voice messages, and the player for the current one.
+#[derive(Debug)]
+pub struct VoiceQueue {
+    chat_id: i64,
+    /// Not played yet, oldest first
+    queue: VecDeque<Message>,
+    current: Option<Message>,
+    player: Option<Child>,
+    /// The fetch being waited on, if any
+    waiting: Option<oneshot::Receiver<Fetched>>,
+    started: usize,
+    total: usize,
+}
+
+impl VoiceQueue {
+    /// Queues the unplayed voice messages among `messages` from a chat.
+    #[must_use]
+    pub fn new(chat_id: i64, messages: &[Message]) -> Self {
+        let mut queue = Self {
+            chat_id,
+            queue: VecDeque::new(),
+            current: None,
+            player: None,
+            waiting: None,
+            started: 0,
+            total: 0,
+        };
+        queue.add_listed(messages);
+        queue
+    }
+
+    /// Queues the unplayed voice messages among `messages` listed for the
+    /// chat, in order with any already queued.
+    pub fn add_listed(&mut self, messages: &[Message]) {
+        let current = self.current.as_ref().map(|m| m.id);
+        let mut queue: Vec<Message> = messages
+            .iter()
+            .filter(|m| m.chat_id == self.chat_id && is_unplayed_voice(m))
+            .filter(|m| Some(m.id) != current)
+            .cloned()
+            .chain(self.queue.drain(..))
+            .collect();
+        queue.sort_by_key(|m| m.id);
+        queue.dedup_by_key(|m| m.id);
+        self.total = self.started + queue.len();
+        self.queue = queue.into();
+    }
+
+    /// Returns the chat being listened to.
+    #[must_use]
+    pub const fn chat_id(&self) -> i64 {
+        self.chat_id
+    }
+
+    /// Returns `true` if nothing is left to play.
+    #[must_use]
+    pub fn is_empty(&self) -> bool {
+        self.queue.is_empty()
+    }
+
+    /// Adds a newly arrived message to the end, if it's an unplayed voice
+    /// message in this chat. Returns `true` if it was added.
+    pub fn offer(&mut self, message: &Message) -> bool {
+        let known = self.current.as_ref().map_or(false, |m| m.id == message.id)
+            || self.queue.iter().any(|m| m.id == message.id);
+        if message.chat_id != self.chat_id || known || !is_unplayed_voice(message) {
+            return false;
+        }
+        self.queue.push_back(message.clone());
+        self.total += 1;
+        true
+    }
+
+    /// Stops the current message and moves on to the next, returning it,
+    /// or `None` once all have been played.
+    pub fn start_next(&mut self) -> Option<Message> {
+        self.stop_player();
+        self.waiting = None;
+        self.current = self.queue.pop_front();
+        if self.current.is_some() {
+            self.started += 1;
+        }
+        self.current.clone()
+    }
+
+    /// Returns the message playing now.
+    #[must_use]
+    pub const fn current(&self) -> Option<&Message> {
+        self.current.as_ref()
+    }
+
+    /// Keeps the player for the current message, to be waited on or
+    /// stopped.
+    pub fn set_player(&mut self, player: Child) {
+        self.stop_player();
+        self.player = Some(player);
+    }
+
+    /// Waits on `fetch`, in place of whatever was waited on before.
+    pub fn wait_for(&mut self, fetch: oneshot::Receiver<Fetched>) {
+        self.waiting = Some(fetch);
+    }
+
+    /// Returns what was waited on, once it has arrived.
+    pub fn fetched(&mut self) -> Option<Fetched> {
+        let fetched = match self.waiting.as_mut()?.try_recv() {
+            Ok(fetched) => fetched,
+            Err(oneshot::error::TryRecvError::Empty) => return None,
+            Err(oneshot::error::TryRecvError::Closed) => {
+                let lost = Err(TelegramError::Internal("the fetch was dropped".to_string()));
+                match self.current {
+                    Some(_) => Fetched::Downloaded(lost),
+                    None => Fetched::Listed(lost),
+                }
+            },
+        };
+        self.waiting = None;
+        Some(fetched)
+    }
+
+    /// Returns `true` once the player for the current message has exited.
+    pub fn player_finished(&mut self) -> bool {
+        self.player
+            .as_mut()
+            .map_or(false, |player| !matches!(player.try_wait(), Ok(None)))
+    }
+
+    fn stop_player(&mut self) {
+        if let Some(mut player) = self.player.take() {
+            // It may already have exited
+            let _ = player.kill();
+            let _ = player.wait();
+        }
+    }
+
+    /// Returns which message is playing (counting from 1), and how many
+    /// there are in all.
+    #[must_use]
+    pub const fn progress(&self) -> (usize, usize) {
+        (self.started, self.total)
+    }
+}
+
+impl Drop for VoiceQueue {
+    fn drop(&mut self) {
+        self.stop_player();
+    }
+}
+
+/// Returns `true` for a voice message from someone else that hasn't been
+/// played yet.
+#[must_use]
+pub fn is_unplayed_voice(message: &Message) -> bool {
+    message.content.content_type == MessageType::Voice
+        && message.media_unread
+        && !message.is_outgoing
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::types::MessageContent;
+
+    fn voice(id: i64, unplayed: bool) -> Message {
+        Message {
+            id,
+            chat_id: 7,
+            media_unread: unplayed,
+            content: MessageContent {
+                content_type: MessageType::Voice,
+                ..MessageContent::default()
+            },
+            ..Message::default()
+        }
+    }
+
+    #[test]
+    fn plays_unplayed_voice_messages_oldest_first() {
+        let mut text = voice(4, true);
+        text.content.content_type = MessageType::Text;
+        let mut mine = voice(5, true);
+        mine.is_outgoing = true;
+        // Newest first, as Telegram lists them
+        let mut queue = VoiceQueue::new(
+            7,
+            &[voice(6, true), mine, text, voice(3, false), voice(2, true)],
+        );
+        assert_eq!(queue.progress(), (0, 2));
+
+        assert_eq!(queue.start_next().map(|m| m.id), Some(2));
+        assert_eq!(queue.current().map(|m| m.id), Some(2));
+        assert!(!queue.player_finished());
+
+        // Arrivals join the end, once
+        assert!(queue.offer(&voice(9, true)));
+        assert!(!queue.offer(&voice(9, true)));
+        assert!(!queue.offer(&voice(2, true)));
+        assert_eq!(queue.progress(), (1, 3));
+
+        assert_eq!(queue.start_next().map(|m| m.id), Some(6));
+        assert_eq!(queue.start_next().map(|m| m.id), Some(9));
+        assert!(queue.is_empty());
+        assert!(queue.start_next().is_none());
+        assert_eq!(queue.progress(), (3, 3));
+    }
+
+    #[test]
+    fn waits_for_the_list_and_keeps_arrivals_in_order() {
+        let mut queue = VoiceQueue::new(7, &[]);
+        let (tx, rx) = oneshot::channel();
+        queue.wait_for(rx);
+        assert!(queue.fetched().is_none());
+
+        // A message arriving while the list is fetched goes after older ones
+        assert!(queue.offer(&voice(9, true)));
+        tx.send(Fetched::Listed(Ok(vec![voice(9, true), voice(2, true)])))
+            .unwrap();
+        let Some(Fetched::Listed(Ok(listed))) = queue.fetched() else {
+            panic!("the list should have arrived");
+        };
+        queue.add_listed(&listed);
+        assert!(queue.fetched().is_none());
+        assert_eq!(queue.progress(), (0, 2));
+        assert_eq!(queue.start_next().map(|m| m.id), Some(2));
+
+        // Moving on stops waiting for the last message's download
+        let (tx, rx) = oneshot::channel();
+        queue.wait_for(rx);
+        assert_eq!(queue.start_next().map(|m| m.id), Some(9));
+        assert!(tx.send(Fetched::Downloaded(Ok(PathBuf::new()))).is_err());
+        assert!(queue.fetched().is_none());
+    }
+}
@@ -0,0 +1,175 @@
+//! Describing a local file before it's sent: its size, what kind of file
+//! it is, and an image's dimensions.
+//!
+//! The kind comes from the file's first bytes where they are telling, so
+//! a misnamed file is shown for what it is, and from the extension
+//! otherwise. Dimensions are read from PNG, GIF, JPEG and WebP headers
+//! without decoding the image.
+
+use std::fs::File;
+use std::io::Read;
+use std::path::Path;
+
+/// How much of the file is read to tell its kind and dimensions. JPEG
+/// metadata can put the size marker some way in.
+const HEAD_LEN: u64 = 64 * 1024;
+
+/// Leading bytes of common file kinds.
+const MAGIC: &[(&[u8], &str)] = &[
+    (b"\x89PNG\r\n\x1a\n", "PNG image"),
+    (b"\xff\xd8\xff", "JPEG image"),
+    (b"GIF8", "GIF image"),
+    (b"BM", "BMP image"),
+    (b"%PDF", "PDF document"),
+    (b"PK\x03\x04", "ZIP archive"),
+    (b"OggS", "Ogg audio"),
+    (b"ID3", "MP3 audio"),
+    (b"fLaC", "FLAC audio"),
+    (b"\x1a\x45\xdf\xa3", "Matroska video"),
+];
+
+/// What the composer shows about a staged attachment.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct FileInfo {
+    /// Size in bytes
+    pub size: u64,
+    /// Kind of file, such as "PNG image" or "PDF document"
+    pub kind: String,
+    /// Width and height in pixels, for images with a readable header
+    pub dimensions: Option<(u32, u32)>,
+}
+
+/// Describes the file at `path`, or returns `None` if it can't be read.
+#[must_use]
+pub fn file_info(path: &Path) -> Option<FileInfo> {
+    let file = File::open(path).ok()?;
+    let size = file.metadata().ok()?.len();
+    let mut head = Vec::new();
+    file.take(HEAD_LEN).read_to_end(&mut head).ok()?;
+    Some(FileInfo {
+        size,
+        kind: detect_kind(&head, path),
+        dimensions: image_dimensions(&head),
+    })
+}
+
+/// Names the kind of file from its first bytes, falling back to the
+/// extension.
+fn detect_kind(head: &[u8], path: &Path) -> String {
+    if let Some((_, kind)) = MAGIC.iter().find(|(m, _)| head.starts_with(m)) {
+        return (*kind).to_string();
+    }
+    if head.starts_with(b"RIFF") && tagged(head, 8, b"WEBP") {
+        return "WebP image".to_string();
+    }
+    if tagged(head, 4, b"ftyp") {
+        return "MP4 video".to_string();
+    }
+    path.extension()
+        .and_then(|e| e.to_str())
+        .filter(|e| !e.is_empty())
+        .map_or_else(
+            || "File".to_string(),
+            |e| format!("{} file", e.to_uppercase()),
+        )
+}
+
+/// Returns `true` if `tag` is found at `offset` in `head`.
+fn tagged(head: &[u8], offset: usize, tag: &[u8]) -> bool {
+    head.get(offset..)
+        .map_or(false, |rest| rest.starts_with(tag))
+}
+
+/// Reads an image's width and height from its header.
+fn image_dimensions(head: &[u8]) -> Option<(u32, u32)> {
+    let be16 = |at: usize| {
+        Some(u32::from(u16::from_be_bytes([
+            *head.get(at)?,
+            *head.get(at + 1)?,
+        ])))
+    };
+    let le16 = |at: usize| {
+        Some(u32::from(u16::from_le_bytes([
+            *head.get(at)?,
+            *head.get(at + 1)?,
+        ])))
+    };
+    let be32 = |at: usize| Some(u32::from_be_bytes(head.get(at..at + 4)?.try_into().ok()?));
+    let le24 = |at: usize| {
+        let b = head.get(at..at + 3)?;
+        Some(u32::from(b[0]) | (u32::from(b[1]) << 8) | (u32::from(b[2]) << 16))
+    };
+
+    if head.starts_with(b"\x89PNG\r\n\x1a\n") && tagged(head, 12, b"IHDR") {
+        return Some((be32(16)?, be32(20)?));
+    }
+    if head.starts_with(b"GIF8") {
+        return Some((le16(6)?, le16(8)?));
+    }
+    if head.starts_with(b"RIFF") && tagged(head, 8, b"WEBP") {
+        return match head.get(12..16)? {
+            b"VP8X" => Some((le24(24)? + 1, le24(27)? + 1)),
+            b"VP8 " => Some((le16(26)? & 0x3fff, le16(28)? & 0x3fff)),
+            b"VP8L" => {
+                let bits = u32::from_le_bytes(head.get(21..25)?.try_into().ok()?);
+                Some(((bits & 0x3fff) + 1, ((bits >> 14) & 0x3fff) + 1))
+            },
+            _ => None,
+        };
+    }
+    if head.starts_with(b"\xff\xd8") {
+        // Walk the segments to the first start-of-frame marker
+        let mut at = 2;
+        while *head.get(at)? == 0xff {
+            let marker = *head.get(at + 1)?;
+            let is_frame = matches!(marker, 0xc0..=0xcf) && !matches!(marker, 0xc4 | 0xc8 | 0xcc);
+            if is_frame {
+                return Some((be16(at + 7)?, be16(at + 5)?));
+            }
+            at += 2 + usize::try_from(be16(at + 2)?).ok()?;
+        }
+    }
+    None
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn kinds_come_from_content_before_the_extension() {
+        let png = b"\x89PNG\r\n\x1a\n\0\0\0\x0dIHDR\0\0\x02\x80\0\0\x01\xe0";
+        assert_eq!(detect_kind(png, Path::new("photo.jpg")), "PNG image");
+        assert_eq!(image_dimensions(png), Some((640, 480)));
+
+        let gif = b"GIF89a\x40\x01\xc8\x00";
+        assert_eq!(image_dimensions(gif), Some((320, 200)));
+
+        // APP0 segment, then a baseline frame of 1024x768
+        let jpeg = b"\xff\xd8\xff\xe0\x00\x04\x00\x00\xff\xc0\x00\x11\x08\x03\x00\x04\x00";
+        assert_eq!(detect_kind(jpeg, Path::new("x")), "JPEG image");
+        assert_eq!(image_dimensions(jpeg), Some((1024, 768)));
+
+        assert_eq!(detect_kind(b"plain", Path::new("notes.txt")), "TXT file");
+        assert_eq!(detect_kind(b"plain", Path::new("README")), "File");
+        assert_eq!(image_dimensions(b"plain"), None);
+        assert_eq!(image_dimensions(b"\xff\xd8\xff"), None);
+    }
+
+    #[test]
+    fn reads_a_file_from_disk() {
+        let path = std::env::temp_dir().join(format!("ithil_file_info_{}.gif", std::process::id()));
+        std::fs::write(&path, b"GIF87a\x10\x00\x20\x00rest").unwrap();
+        let info = file_info(&path).unwrap();
+        std::fs::remove_file(&path).unwrap();
+        assert_eq!(
+            info,
+            FileInfo {
+                size: 14,
+                kind: "GIF image".to_string(),
+                dimensions: Some((16, 32)),
+            }
+        );
+        assert!(file_info(&path).is_none());
+    }
+}
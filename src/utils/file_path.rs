@@ -0,0 +1,189 @@
+//! Spotting local file paths in composed text, and telling which files
+//! can go as photos.
+//!
+//! Pasting a file's path, or dropping the file on the terminal (which
+//! types its path), is a quick way to attach it. Terminals quote paths in
+//! different ways, so single and double quotes, backslash-escaped spaces
+//! and `file://` URIs are all understood.
+
+use std::path::{Path, PathBuf};
+
+/// Returns `true` when the file extension indicates an image that Telegram
+/// can receive as a compressed photo. Everything else is sent as a document.
+#[must_use]
+pub fn is_image(path: &Path) -> bool {
+    matches!(
+        path.extension()
+            .and_then(|e| e.to_str())
+            .map(str::to_ascii_lowercase)
+            .as_deref(),
+        Some("jpg" | "jpeg" | "png" | "webp" | "bmp")
+    )
+}
+
+/// Finds the path of an existing local file in `text`.
+///
+/// Returns the path and the rest of the text, to be used as a caption.
+/// Only absolute paths, `~/` paths and `file://` URIs count, so ordinary
+/// words never match files in the working directory.
+#[must_use]
+pub fn find_file_path(text: &str) -> Option<(PathBuf, String)> {
+    // A pasted path with unescaped spaces is only recognisable as a whole
+    if let Some(path) = existing_file(text.trim()) {
+        return Some((path, String::new()));
+    }
+
+    tokens(text).into_iter().find_map(|(start, end, token)| {
+        let path = existing_file(&token)?;
+        let before = text[..start].trim_end();
+        let after = text[end..].trim_start();
+        let separator = if before.is_empty() || after.is_empty() {
+            ""
+        } else {
+            " "
+        };
+        Some((path, format!("{before}{separator}{after}")))
+    })
+}
+
+/// Interprets a candidate as a path, if it names an existing file.
+fn existing_file(candidate: &str) -> Option<PathBuf> {
+    let path = if let Some(uri) = candidate.strip_prefix("file://") {
+        PathBuf::from(percent_decode(uri))
+    } else if let Some(rest) = candidate.strip_prefix("~/") {
+        dirs::home_dir()?.join(rest)
+    } else {
+        PathBuf::from(candidate)
+    };
+    (path.is_absolute() && path.is_file()).then_some(path)
+}
+
+/// Splits text into shell-like words, returning each word's byte range in
+/// `text` and its unquoted, unescaped value.
+fn tokens(text: &str) -> Vec<(usize, usize, String)> {
+    let mut tokens = Vec::new();
+    let mut current: Option<(usize, String)> = None;
+    let mut quote: Option<char> = None;
+    let mut chars = text.char_indices();
+
+    while let Some((i, c)) = chars.next() {
+        if quote.is_none() && c.is_whitespace() {
+            if let Some((start, word)) = current.take() {
+                tokens.push((start, i, word));
+            }
+            continue;
+        }
+
+        let word = &mut current.get_or_insert_with(|| (i, String::new())).1;
+        match (quote, c) {
+            (Some(q), c) if c == q => quote = None,
+            (None, '\'' | '"') => quote = Some(c),
+            (None, '\\') => {
+                if let Some((_, escaped)) = chars.next() {
+                    word.push(escaped);
+                }
+            },
+            (_, c) => word.push(c),
+        }
+    }
+    if let Some((start, word)) = current {
+        tokens.push((start, text.len(), word));
+    }
+    tokens
+}
+
+/// Decodes `%XX` escapes, as found in `file://` URIs.
+fn percent_decode(text: &str) -> String {
+    let bytes = text.as_bytes();
+    let mut decoded = Vec::with_capacity(bytes.len());
+    let mut i = 0;
+    while i < bytes.len() {
+        let escaped = (bytes[i] == b'%')
+            .then(|| text.get(i + 1..i + 3))
+            .flatten()
+            .and_then(|hex| u8::from_str_radix(hex, 16).ok());
+        if let Some(byte) = escaped {
+            decoded.push(byte);
+            i += 3;
+        } else {
+            decoded.push(bytes[i]);
+            i += 1;
+        }
+    }
+    String::from_utf8_lossy(&decoded).into_owned()
+}
+
+#[cfg(test)]
+mod tests {
+    use std::fs;
+
+    use super::*;
+
+    /// Creates `name` in a fresh temp directory and returns its path.
+    fn temp_file(test: &str, name: &str) -> PathBuf {
+        let dir =
+            std::env::temp_dir().join(format!("ithil_file_path_{test}_{}", std::process::id()));
+        fs::create_dir_all(&dir).unwrap();
+        let path = dir.join(name);
+        fs::write(&path, b"x").unwrap();
+        path
+    }
+
+    #[test]
+    fn path_with_caption_is_split_off() {
+        let path = temp_file("caption", "cat.png");
+        let text = format!("look at this {} so cute", path.display());
+        assert_eq!(
+            find_file_path(&text),
+            Some((path.clone(), "look at this so cute".to_string()))
+        );
+        fs::remove_dir_all(path.parent().unwrap()).unwrap();
+    }
+
+    #[test]
+    fn quoted_escaped_and_uri_paths_are_understood() {
+        let path = temp_file("quoting", "my cat.png");
+        let shown = path.display().to_string();
+
+        let quoted = format!("'{shown}' hi");
+        let escaped = format!("{} hi", shown.replace(' ', "\\ "));
+        let uri = format!("file://{} hi", shown.replace(' ', "%20"));
+        for text in [quoted, escaped, uri] {
+            assert_eq!(
+                find_file_path(&text),
+                Some((path.clone(), "hi".to_string())),
+                "{text}"
+            );
+        }
+        assert_eq!(find_file_path(&shown), Some((path.clone(), String::new())));
+        fs::remove_dir_all(path.parent().unwrap()).unwrap();
+    }
+
+    #[test]
+    fn plain_text_and_missing_files_are_ignored() {
+        assert_eq!(find_file_path("see you at 5/6"), None);
+        assert_eq!(find_file_path("/no/such/file.png please"), None);
+        assert_eq!(find_file_path("Cargo.toml"), None);
+    }
+
+    #[test]
+    fn images_classify_as_photo() {
+        for p in ["a.jpg", "a.jpeg", "a.PNG", "dir/sub/photo.WebP", "x.bmp"] {
+            assert!(is_image(Path::new(p)), "{p} should be an image");
+        }
+    }
+
+    #[test]
+    fn non_images_classify_as_document() {
+        for p in [
+            "a.gif",
+            "clip.mp4",
+            "notes.txt",
+            "report.pdf",
+            "noext",
+            "a.tar.gz",
+        ] {
+            assert!(!is_image(Path::new(p)), "{p} should NOT be an image");
+        }
+    }
+}
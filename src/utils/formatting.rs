@@ -117,6 +117,96 @@ pub fn word_wrap(text: &str, width: usize) -> String {
     lines.join("\n")
 }
 
+/// Returns the initials an avatar shows for `name`: the first letter or
+/// digit of each of its first two words, uppercased. A name with neither,
+/// such as a lone emoji, gives its first character.
+///
+/// # Examples
+///
+/// ```
+/// use ithil::utils::initials;
+///
+/// assert_eq!(initials("ada lovelace"), "AL");
+/// assert_eq!(initials("Rust (Off-topic) Chat"), "RO");
+/// assert_eq!(initials("🦀"), "🦀");
+/// ```
+#[must_use]
+pub fn initials(name: &str) -> String {
+    let letters: String = name
+        .split_whitespace()
+        .filter_map(|word| word.chars().find(|c| c.is_alphanumeric()))
+        .take(2)
+        .flat_map(char::to_uppercase)
+        .collect();
+    if letters.is_empty() {
+        name.trim()
+            .chars()
+            .next()
+            .map(String::from)
+            .unwrap_or_default()
+    } else {
+        letters
+    }
+}
+
+/// Wraps text into lines at most `width` columns wide, for messages.
+///
+/// Unlike [`word_wrap`], line breaks and spacing in the text are kept, and
+/// a word too long for one line (such as a URL) is split across lines
+/// rather than left sticking out. Widths are measured in terminal columns, so wide
+/// characters like emoji count as two.
+///
+/// # Examples
+///
+/// ```
+/// use ithil::utils::wrap_lines;
+///
+/// assert_eq!(wrap_lines("one two\n\nthree", 5), ["one", "two", "", "three"]);
+/// assert_eq!(wrap_lines("abcdefgh", 3), ["abc", "def", "gh"]);
+/// ```
+#[must_use]
+pub fn wrap_lines(text: &str, width: usize) -> Vec<String> {
+    if width == 0 {
+        return text.lines().map(ToString::to_string).collect();
+    }
+
+    let mut lines = Vec::new();
+    for paragraph in text.lines() {
+        let mut line = String::new();
+        let mut line_width = 0;
+        // Splitting on single spaces keeps runs of them, such as indents
+        for (i, word) in paragraph.split(' ').enumerate() {
+            let word_width = UnicodeWidthStr::width(word);
+            if i > 0 {
+                if line_width + 1 + word_width <= width {
+                    line.push(' ');
+                    line.push_str(word);
+                    line_width += 1 + word_width;
+                    continue;
+                }
+                // Spaces where the line breaks are dropped
+                if word.is_empty() {
+                    continue;
+                }
+                lines.push(line.trim_end().to_string());
+                line.clear();
+                line_width = 0;
+            }
+            for ch in word.chars() {
+                let ch_width = unicode_width::UnicodeWidthChar::width(ch).unwrap_or(0);
+                if line_width > 0 && line_width + ch_width > width {
+                    lines.push(std::mem::take(&mut line));
+                    line_width = 0;
+                }
+                line.push(ch);
+                line_width += ch_width;
+            }
+        }
+        lines.push(line);
+    }
+    lines
+}
+
 /// Formats a file size in bytes to a human-readable string.
 ///
 /// # Arguments
@@ -180,6 +270,113 @@ pub fn first_url(text: &str) -> Option<String> {
     })
 }
 
+/// Splits a text made only of emoji into its emoji, if there are at most
+/// `max` of them.
+///
+/// Skin tones, variation selectors, keycaps, flags, tag sequences and
+/// zero-width-joiner sequences (such as family emoji) each count as one
+/// emoji. Whitespace between them is ignored; anything else makes this
+/// return `None`.
+///
+/// # Examples
+///
+/// ```
+/// use ithil::utils::emoji_only;
+///
+/// assert_eq!(emoji_only("🎉 👍🏽", 3), Some(vec!["🎉", "👍🏽"]));
+/// assert_eq!(emoji_only("🎉 yay", 3), None);
+/// ```
+#[must_use]
+pub fn emoji_only(text: &str, max: usize) -> Option<Vec<&str>> {
+    let chars: Vec<(usize, char)> = text.char_indices().collect();
+    let mut emoji = Vec::new();
+    let mut i = 0;
+    while i < chars.len() {
+        let (start, c) = chars[i];
+        i += 1;
+        if c.is_whitespace() {
+            continue;
+        }
+
+        let keycap = matches!(c, '0'..='9' | '#' | '*');
+        if !keycap && !is_emoji_base(c) && !is_text_symbol(c) {
+            return None;
+        }
+        // A flag is a pair of regional indicators
+        if is_regional_indicator(c) {
+            if !chars
+                .get(i)
+                .is_some_and(|&(_, next)| is_regional_indicator(next))
+            {
+                return None;
+            }
+            i += 1;
+        }
+
+        // Digits and text symbols only count when drawn as emoji
+        let mut presented = !keycap && is_emoji_base(c);
+        while let Some(&(_, next)) = chars.get(i) {
+            match next {
+                '\u{FE0F}' if !keycap => presented = true,
+                '\u{20E3}' if keycap => presented = true,
+                '\u{FE0F}' | '\u{1F3FB}'..='\u{1F3FF}' | '\u{E0020}'..='\u{E007F}' => {},
+                '\u{200D}' if chars.get(i + 1).is_some_and(|&(_, c)| is_emoji_base(c)) => i += 1,
+                _ => break,
+            }
+            i += 1;
+        }
+        if !presented {
+            return None;
+        }
+
+        let end = chars.get(i).map_or(text.len(), |&(pos, _)| pos);
+        emoji.push(&text[start..end]);
+        if emoji.len() > max {
+            return None;
+        }
+    }
+    (!emoji.is_empty()).then_some(emoji)
+}
+
+/// Returns `true` for characters drawn as emoji by default.
+const fn is_emoji_base(c: char) -> bool {
+    matches!(
+        c,
+        '\u{1F000}'..='\u{1FAFF}'
+            | '\u{2600}'..='\u{27BF}'
+            | '\u{2300}'..='\u{23FF}'
+            | '\u{2B05}'..='\u{2B55}'
+    )
+}
+
+/// Returns `true` for symbols that are drawn as emoji only when followed by
+/// the emoji variation selector (U+FE0F), such as © and ™.
+const fn is_text_symbol(c: char) -> bool {
+    matches!(
+        c,
+        '\u{A9}'
+            | '\u{AE}'
+            | '\u{203C}'
+            | '\u{2049}'
+            | '\u{2122}'
+            | '\u{2139}'
+            | '\u{2194}'..='\u{21AA}'
+            | '\u{24C2}'
+            | '\u{25AA}'..='\u{25FE}'
+            | '\u{2934}'
+            | '\u{2935}'
+            | '\u{3030}'
+            | '\u{303D}'
+            | '\u{3297}'
+            | '\u{3299}'
+    )
+}
+
+/// Returns `true` for the letters that pair up into flags.
+const fn is_regional_indicator(c: char) -> bool {
+    matches!(c, '\u{1F1E6}'..='\u{1F1FF}')
+}
+
 /// Helper to format float sizes with minimal decimal places.
 fn format_float_size(value: f64, unit: &str) -> String {
     if (value - value.round()).abs() < 0.05 {
@@ -235,6 +432,31 @@ mod tests {
         }
     }
 
+    mod emoji_only_tests {
+        use super::*;
+
+        #[test]
+        fn sequences_count_as_one() {
+            // Family (ZWJ), flag, keycap, heart with variation selector
+            let text =
+                "\u{1F468}\u{200D}\u{1F469}\u{200D}\u{1F467} \u{1F1FA}\u{1F1E6} 1\u{FE0F}\u{20E3}";
+            assert_eq!(emoji_only(text, 3).map(|e| e.len()), Some(3));
+            assert_eq!(
+                emoji_only("\u{2764}\u{FE0F}", 3),
+                Some(vec!["\u{2764}\u{FE0F}"])
+            );
+        }
+
+        #[test]
+        fn text_and_too_many_are_refused() {
+            assert_eq!(emoji_only("", 3), None);
+            assert_eq!(emoji_only("1", 3), None);
+            assert_eq!(emoji_only("\u{A9} 2024", 3), None);
+            assert_eq!(emoji_only("\u{1F600}\u{1F600}\u{1F600}\u{1F600}", 3), None);
+            assert_eq!(emoji_only("ok \u{1F44D}", 3), None);
+        }
+    }
+
     mod truncate_tests {
         use super::*;
 
@@ -300,6 +522,63 @@ mod tests {
         }
     }
 
+    mod wrap_lines_tests {
+        use super::*;
+
+        #[test]
+        fn keeps_line_breaks_and_blank_lines() {
+            assert_eq!(wrap_lines("a\n\nb", 10), ["a", "", "b"]);
+            assert!(wrap_lines("", 10).is_empty());
+        }
+
+        #[test]
+        fn breaks_on_spaces_within_the_width() {
+            assert_eq!(
+                wrap_lines("The quick brown fox jumps", 10),
+                ["The quick", "brown fox", "jumps"]
+            );
+        }
+
+        #[test]
+        fn keeps_indents_and_runs_of_spaces() {
+            assert_eq!(wrap_lines("    let x  = 1;", 20), ["    let x  = 1;"]);
+            assert_eq!(wrap_lines("abc   def", 4), ["abc", "def"]);
+        }
+
+        #[test]
+        fn splits_words_longer_than_a_line() {
+            assert_eq!(
+                wrap_lines("see https://example.com/a/b", 10),
+                ["see", "https://ex", "ample.com/", "a/b"]
+            );
+        }
+
+        #[test]
+        fn counts_wide_characters_as_two_columns() {
+            assert_eq!(
+                wrap_lines("\u{1f600}\u{1f600}\u{1f600} ok", 5),
+                ["\u{1f600}\u{1f600}", "\u{1f600} ok"]
+            );
+        }
+    }
+
+    mod initials_tests {
+        use super::*;
+
+        #[test]
+        fn one_word_gives_one_letter() {
+            assert_eq!(initials("Alice"), "A");
+            assert_eq!(initials("  @bob "), "B");
+            assert_eq!(initials(""), "");
+        }
+
+        #[test]
+        fn only_the_first_two_words_count() {
+            assert_eq!(initials("the quick brown fox"), "TQ");
+            assert_eq!(initials("émile zola"), "ÉZ");
+        }
+    }
+
     mod file_size_tests {
         use super::*;
 
@@ -0,0 +1,204 @@
+//! Lists of keywords to look for in messages, for highlights and hooks.
+//!
+//! Each entry in a list is one of:
+//!
+//! - a word or phrase, which has to stand alone, so `deploy` doesn't match
+//!   "redeployed"
+//! - a word ending in `*`, which matches any ending, so `deploy*` does match
+//!   "deploying"
+//! - a regular expression between slashes, such as `/v\d+\.\d+ released/`
+//!
+//! All of them ignore case, and blank entries never match. Regular
+//! expressions are compiled once, when the config is read; one that doesn't
+//! compile never matches and is reported by [`Keywords::error`].
+
+use regex::{Regex, RegexBuilder};
+use serde::{Deserialize, Serialize};
+
+/// A list of keywords, ready to look for.
+///
+/// Reads from and writes to the config as a plain list of strings.
+///
+/// # Examples
+///
+/// ```
+/// use ithil::utils::Keywords;
+///
+/// let keywords = Keywords::new(["urgent", "deploy*", r"/build #\d+ failed/"]);
+/// assert_eq!(keywords.find("Deploying now"), Some("deploy*"));
+/// assert_eq!(keywords.find("Build #412 FAILED"), Some(r"/build #\d+ failed/"));
+/// assert_eq!(keywords.find("not urgently"), None);
+/// ```
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+#[serde(from = "Vec<String>", into = "Vec<String>")]
+pub struct Keywords {
+    entries: Vec<Keyword>,
+}
+
+#[derive(Debug, Clone)]
+struct Keyword {
+    /// The entry as written
+    source: String,
+    pattern: Pattern,
+}
+
+#[derive(Debug, Clone)]
+enum Pattern {
+    Blank,
+    /// Lowercase word or phrase, matching any ending if `open_ended`
+    Word {
+        word: String,
+        open_ended: bool,
+    },
+    Regex(Regex),
+    /// A regular expression that didn't compile, and why
+    Invalid(String),
+}
+
+impl Keyword {
+    fn new(source: String) -> Self {
+        let entry = source.trim();
+        let pattern = if let Some(expr) = entry
+            .strip_prefix('/')
+            .and_then(|rest| rest.strip_suffix('/'))
+            .filter(|expr| !expr.is_empty())
+        {
+            RegexBuilder::new(expr)
+                .case_insensitive(true)
+                .build()
+                .map_or_else(|e| Pattern::Invalid(e.to_string()), Pattern::Regex)
+        } else {
+            let (word, open_ended) = entry
+                .strip_suffix('*')
+                .map_or((entry, false), |word| (word, true));
+            let word = word.trim_end().to_lowercase();
+            if word.is_empty() {
+                Pattern::Blank
+            } else {
+                Pattern::Word { word, open_ended }
+            }
+        };
+        Self { source, pattern }
+    }
+
+    /// Returns `true` if the keyword is in `text`, which `lower` is the
+    /// lowercase of.
+    fn is_in(&self, text: &str, lower: &str) -> bool {
+        match &self.pattern {
+            Pattern::Blank | Pattern::Invalid(_) => false,
+            Pattern::Regex(regex) => regex.is_match(text),
+            Pattern::Word { word, open_ended } => {
+                lower.match_indices(word.as_str()).any(|(start, _)| {
+                    let end = start + word.len();
+                    let stands_alone = |c: Option<char>| !c.map_or(false, char::is_alphanumeric);
+                    stands_alone(lower[..start].chars().next_back())
+                        && (*open_ended || stands_alone(lower[end..].chars().next()))
+                })
+            },
+        }
+    }
+}
+
+impl Keywords {
+    /// Reads a list of keywords.
+    #[must_use]
+    pub fn new<I, S>(entries: I) -> Self
+    where
+        I: IntoIterator<Item = S>,
+        S: Into<String>,
+    {
+        Self {
+            entries: entries
+                .into_iter()
+                .map(|entry| Keyword::new(entry.into()))
+                .collect(),
+        }
+    }
+
+    /// Returns the first keyword found in `text`, trimmed, or `None`.
+    #[must_use]
+    pub fn find(&self, text: &str) -> Option<&str> {
+        let lower = text.to_lowercase();
+        self.entries
+            .iter()
+            .find(|keyword| keyword.is_in(text, &lower))
+            .map(|keyword| keyword.source.trim())
+    }
+
+    /// Returns `true` if every entry is blank, so nothing can match.
+    #[must_use]
+    pub fn is_blank(&self) -> bool {
+        self.entries
+            .iter()
+            .all(|keyword| matches!(keyword.pattern, Pattern::Blank))
+    }
+
+    /// Describes the first regular expression that didn't compile, if any.
+    #[must_use]
+    pub fn error(&self) -> Option<String> {
+        self.entries
+            .iter()
+            .find_map(|keyword| match &keyword.pattern {
+                Pattern::Invalid(e) => Some(format!("{}: {e}", keyword.source.trim())),
+                _ => None,
+            })
+    }
+}
+
+impl From<Vec<String>> for Keywords {
+    fn from(entries: Vec<String>) -> Self {
+        Self::new(entries)
+    }
+}
+
+impl From<Keywords> for Vec<String> {
+    fn from(keywords: Keywords) -> Self {
+        keywords
+            .entries
+            .into_iter()
+            .map(|keyword| keyword.source)
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn matches_whole_words_and_phrases_ignoring_case() {
+        let keywords = Keywords::new(["", "Ann", "on call"]);
+        assert_eq!(keywords.find("ping @ann!"), Some("Ann"));
+        assert_eq!(keywords.find("Who is ON CALL today?"), Some("on call"));
+        assert_eq!(keywords.find("planning"), None);
+        assert_eq!(keywords.find(""), None);
+    }
+
+    #[test]
+    fn a_trailing_star_matches_any_ending() {
+        let keywords = Keywords::new(["deploy*", "*"]);
+        assert_eq!(keywords.find("deployment failed"), Some("deploy*"));
+        assert_eq!(keywords.find("redeploy"), None);
+        assert!(!keywords.is_blank());
+        assert!(Keywords::new([" ", "*"]).is_blank());
+    }
+
+    #[test]
+    fn slashes_make_a_regular_expression() {
+        let keywords = Keywords::new([r"/^alert: \w+/", "/(/"]);
+        assert_eq!(keywords.find("ALERT: disk"), Some(r"/^alert: \w+/"));
+        assert_eq!(keywords.find("no alert: here"), None);
+        assert!(keywords.error().is_some_and(|e| e.starts_with("/(/: ")));
+        assert!(Keywords::new(["/a+/"]).error().is_none());
+    }
+
+    #[test]
+    fn reads_and_writes_a_plain_list() {
+        let keywords: Keywords = serde_yaml::from_str("[deploy*, /x+/]").unwrap();
+        assert_eq!(keywords.find("xxx"), Some("/x+/"));
+        assert_eq!(
+            Vec::<String>::from(keywords),
+            ["deploy*".to_string(), "/x+/".to_string()]
+        );
+    }
+}
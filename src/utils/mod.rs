@@ -3,10 +3,31 @@
 //! This module provides common utility functions for text formatting,
 //! time handling, and other helper operations.
 
+mod file_info;
+mod file_path;
 mod formatting;
+mod keywords;
 mod notify;
+mod passphrase;
+mod phone;
+mod presence;
+mod qr;
 mod time;
+mod title;
 
-pub use formatting::{first_url, format_file_size, truncate_string, word_wrap};
-pub use notify::{send_notification, should_notify};
-pub use time::{format_duration, format_relative_time, format_timestamp};
+pub use file_info::{file_info, FileInfo};
+pub use file_path::{find_file_path, is_image};
+pub use formatting::{
+    emoji_only, first_url, format_file_size, initials, truncate_string, word_wrap, wrap_lines,
+};
+pub use keywords::Keywords;
+pub use notify::{ring_bell, send_notification, should_alert, should_notify, should_ring};
+pub use passphrase::{from_hex, hash_passphrase, to_hex, verify_passphrase};
+pub use phone::{countries_for, normalize_phone, search_countries, Country, COUNTRIES};
+pub use presence::{should_be_online, ONLINE_REFRESH};
+pub use qr::QrCode;
+pub use time::{
+    format_absolute_time, format_auto_delete, format_compact_time, format_duration,
+    format_relative_time, format_timestamp, parse_date, parse_duration,
+};
+pub use title::{reset_terminal_title, set_terminal_title, window_title};
@@ -1,4 +1,13 @@
-//! Terminal-native desktop notifications via the OSC 9 escape sequence.
+//! Desktop notifications, and the terminal bell.
+//!
+//! Terminals known to understand the OSC 9 escape sequence get it directly;
+//! elsewhere (Terminal.app, Windows Terminal, most Linux terminals, tmux) the
+//! OS notification center is used through [`crate::platform`], with OSC 9 as
+//! the last resort.
+//!
+//! Where neither reaches the user, the bell (and the status bar flash drawn
+//! by the app) still works: every terminal understands BEL, and tmux and
+//! screen pass it on as an activity alert.
 
 use std::io::Write;
 
@@ -62,7 +71,39 @@ pub fn should_notify(
     chat_id: i64,
     chat_muted: bool,
 ) -> bool {
-    !focused && cfg.enabled && cfg.desktop && !chat_muted && !cfg.muted_chats.contains(&chat_id)
+    should_alert(focused, cfg) && !chat_muted && !cfg.muted_chats.contains(&chat_id)
+}
+
+/// Decide whether an incoming message matching a highlight word should
+/// raise a notification when highlights notify even in muted chats.
+#[must_use]
+pub const fn should_alert(focused: bool, cfg: &NotificationConfig) -> bool {
+    !focused && cfg.enabled && cfg.desktop
+}
+
+/// Decide whether an incoming message should ring the bell or flash the
+/// status bar. `viewing` is `true` while the chat is open in a focused
+/// terminal, where the message is seen anyway. Pure, like
+/// [`should_notify`].
+#[must_use]
+pub fn should_ring(
+    viewing: bool,
+    cfg: &NotificationConfig,
+    chat_id: i64,
+    chat_muted: bool,
+) -> bool {
+    cfg.enabled
+        && (cfg.bell || cfg.flash)
+        && !viewing
+        && !chat_muted
+        && !cfg.muted_chats.contains(&chat_id)
+        && (cfg.alert_chats.is_empty() || cfg.alert_chats.contains(&chat_id))
+}
+
+/// Returns `true` if the terminal is one known to turn OSC 9 into a desktop
+/// notification, judging by `$TERM_PROGRAM` and kitty's `$KITTY_WINDOW_ID`.
+fn supports_osc9(term_program: Option<&str>, in_kitty: bool) -> bool {
+    in_kitty || matches!(term_program, Some("iTerm.app" | "WezTerm" | "ghostty"))
 }
 
 /// Build the OSC 9 escape sequence for `text`, or `None` if the sanitized
@@ -79,24 +120,38 @@ fn osc9_sequence(text: &str, sound: bool) -> Option<String> {
     Some(seq)
 }
 
-/// Emit an OSC 9 desktop notification through the current terminal.
+/// Show a desktop notification for `text`.
 ///
-/// `text` is sanitized first. When `sound` is true a BEL is appended so
-/// terminals that map it to an alert will also chime. Best-effort: any
-/// I/O error is swallowed (a missed notification must never disrupt the UI).
-// Note: the sequence is written to stdout while the app is in the alternate
-// screen. The targeted terminals (iTerm2, kitty, WezTerm, Ghostty) honor OSC 9
-// there, but some emulators only act on the primary screen — a future
-// enhancement could write to /dev/tty instead.
+/// `text` is sanitized first. With OSC 9, `sound` appends a BEL so terminals
+/// that map it to an alert will also chime; system notifications use the
+/// default sound. Best-effort: any error is swallowed (a missed notification
+/// must never disrupt the UI).
+// Note: OSC 9 is written to stdout while the app is in the alternate screen.
+// The targeted terminals (iTerm2, kitty, WezTerm, Ghostty) honor it there.
 pub fn send_notification(text: &str, sound: bool) {
     let Some(seq) = osc9_sequence(text, sound) else {
         return;
     };
+    let term_program = std::env::var("TERM_PROGRAM").ok();
+    let in_kitty = std::env::var_os("KITTY_WINDOW_ID").is_some();
+    if !supports_osc9(term_program.as_deref(), in_kitty) {
+        match crate::platform::system_notification("Ithil", &sanitize(text), sound) {
+            Ok(()) => return,
+            Err(e) => tracing::debug!("System notification failed: {e}"),
+        }
+    }
     let mut stdout = std::io::stdout();
     let _ = stdout.write_all(seq.as_bytes());
     let _ = stdout.flush();
 }
 
+/// Ring the terminal bell. Best-effort, like [`send_notification`].
+pub fn ring_bell() {
+    let mut stdout = std::io::stdout();
+    let _ = stdout.write_all(b"\x07");
+    let _ = stdout.flush();
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -142,6 +197,7 @@ mod tests {
             sound: true,
             desktop,
             muted_chats: muted,
+            ..NotificationConfig::default()
         }
     }
 
@@ -175,6 +231,13 @@ mod tests {
         assert!(!should_notify(false, &cfg(true, true, vec![42]), 42, false));
     }
 
+    #[test]
+    fn alerts_ignore_mutes_but_not_focus_or_settings() {
+        assert!(should_alert(false, &cfg(true, true, vec![42])));
+        assert!(!should_alert(true, &cfg(true, true, vec![])));
+        assert!(!should_alert(false, &cfg(true, false, vec![])));
+    }
+
     #[test]
     fn osc9_none_when_empty_after_sanitize() {
         assert_eq!(osc9_sequence("\x1b\x07", false), None);
@@ -196,10 +259,39 @@ mod tests {
         );
     }
 
+    #[test]
+    fn osc9_only_for_known_terminals() {
+        assert!(supports_osc9(Some("iTerm.app"), false));
+        assert!(supports_osc9(Some("xterm-256color"), true));
+        assert!(!supports_osc9(Some("Apple_Terminal"), false));
+        assert!(!supports_osc9(Some("tmux"), false));
+        assert!(!supports_osc9(None, false));
+    }
+
     #[test]
     fn osc9_sanitizes_injection() {
         let s = osc9_sequence("a\x1b]9;evil\x07b", false).unwrap();
         // exactly one OSC opener (our own), none from the payload
         assert_eq!(s.matches("\x1b]9;").count(), 1);
     }
+
+    #[test]
+    fn rings_for_unseen_messages_in_chosen_unmuted_chats() {
+        let mut cfg = cfg(true, true, vec![7]);
+        assert!(!should_ring(false, &cfg, 42, false));
+
+        cfg.bell = true;
+        assert!(should_ring(false, &cfg, 42, false));
+        assert!(!should_ring(true, &cfg, 42, false));
+        assert!(!should_ring(false, &cfg, 42, true));
+        assert!(!should_ring(false, &cfg, 7, false));
+
+        // Once chats are chosen, only they ring
+        cfg.alert_chats = vec![99];
+        assert!(!should_ring(false, &cfg, 42, false));
+        assert!(should_ring(false, &cfg, 99, false));
+
+        cfg.enabled = false;
+        assert!(!should_ring(false, &cfg, 99, false));
+    }
 }
@@ -0,0 +1,131 @@
+//! Hashing and checking the local lock-screen passphrase.
+//!
+//! Passphrases are stored as PBKDF2-HMAC-SHA256 with a random salt, encoded
+//! as `pbkdf2-sha256$<iterations>$<salt hex>$<hash hex>` so the parameters
+//! can change without invalidating existing hashes.
+
+use sha2::Sha256;
+
+/// Scheme tag at the start of an encoded hash.
+const SCHEME: &str = "pbkdf2-sha256";
+
+/// PBKDF2 iterations for new hashes.
+const ITERATIONS: u32 = 100_000;
+
+/// Salt length in bytes.
+const SALT_LEN: usize = 16;
+
+/// Derived key length in bytes.
+const HASH_LEN: usize = 32;
+
+/// Hashes `passphrase` with a fresh random salt.
+///
+/// # Panics
+///
+/// Panics if the operating system's random number generator is unavailable.
+#[must_use]
+pub fn hash_passphrase(passphrase: &str) -> String {
+    let mut salt = [0u8; SALT_LEN];
+    getrandom::getrandom(&mut salt).expect("OS random number generator unavailable");
+    encode(passphrase, &salt, ITERATIONS)
+}
+
+/// Returns `true` if `passphrase` matches the encoded `stored` hash.
+///
+/// Malformed hashes never match.
+#[must_use]
+pub fn verify_passphrase(passphrase: &str, stored: &str) -> bool {
+    let mut parts = stored.split('$');
+    let (Some(SCHEME), Some(iterations), Some(salt), Some(hash), None) = (
+        parts.next(),
+        parts.next(),
+        parts.next(),
+        parts.next(),
+        parts.next(),
+    ) else {
+        return false;
+    };
+    let (Ok(iterations), Some(salt), Some(expected)) =
+        (iterations.parse::<u32>(), from_hex(salt), from_hex(hash))
+    else {
+        return false;
+    };
+    if iterations == 0 || expected.len() != HASH_LEN {
+        return false;
+    }
+
+    let actual = derive(passphrase, &salt, iterations);
+    // Constant-time comparison
+    actual
+        .iter()
+        .zip(&expected)
+        .fold(0u8, |diff, (a, b)| diff | (a ^ b))
+        == 0
+}
+
+fn derive(passphrase: &str, salt: &[u8], iterations: u32) -> [u8; HASH_LEN] {
+    let mut out = [0u8; HASH_LEN];
+    pbkdf2::pbkdf2_hmac::<Sha256>(passphrase.as_bytes(), salt, iterations, &mut out);
+    out
+}
+
+fn encode(passphrase: &str, salt: &[u8], iterations: u32) -> String {
+    let hash = derive(passphrase, salt, iterations);
+    format!("{SCHEME}${iterations}${}${}", to_hex(salt), to_hex(&hash))
+}
+
+/// Encodes bytes as lowercase hex.
+#[must_use]
+pub fn to_hex(bytes: &[u8]) -> String {
+    use std::fmt::Write as _;
+    bytes.iter().fold(String::new(), |mut s, b| {
+        let _ = write!(s, "{b:02x}");
+        s
+    })
+}
+
+/// Decodes hex, or returns `None` if `s` isn't hex.
+#[must_use]
+pub fn from_hex(s: &str) -> Option<Vec<u8>> {
+    if s.len() % 2 != 0 {
+        return None;
+    }
+    (0..s.len())
+        .step_by(2)
+        .map(|i| u8::from_str_radix(s.get(i..i + 2)?, 16).ok())
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn verifies_matching_passphrase_only() {
+        let stored = encode("correct horse", b"0123456789abcdef", 1000);
+        assert!(verify_passphrase("correct horse", &stored));
+        assert!(!verify_passphrase("wrong horse", &stored));
+    }
+
+    #[test]
+    fn random_salt_makes_hashes_differ() {
+        let a = hash_passphrase("same");
+        let b = hash_passphrase("same");
+        assert_ne!(a, b);
+        assert!(verify_passphrase("same", &a));
+    }
+
+    #[test]
+    fn rejects_malformed_hashes() {
+        assert!(!verify_passphrase("x", ""));
+        assert!(!verify_passphrase("x", "plaintext"));
+        assert!(!verify_passphrase("x", "pbkdf2-sha256$0$00$00"));
+        assert!(!verify_passphrase("x", "pbkdf2-sha256$10$zz$00"));
+    }
+
+    #[test]
+    fn hex_round_trips() {
+        assert_eq!(from_hex(&to_hex(&[0, 15, 255])), Some(vec![0, 15, 255]));
+        assert_eq!(from_hex("abc"), None);
+    }
+}
@@ -0,0 +1,301 @@
+//! Checking phone numbers typed at sign-in, and the country calling codes
+//! offered to help.
+//!
+//! Telegram wants numbers in E.164 form: a `+`, the country code and the
+//! national number, 15 digits at most. People type spaces, dashes, dots
+//! and brackets, or `00` for the `+`; [`normalize_phone`] accepts those and
+//! explains anything else before a request is sent.
+
+/// A country and its calling code.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct Country {
+    /// Country name in English
+    pub name: &'static str,
+    /// ISO 3166 two-letter code
+    pub iso: &'static str,
+    /// Calling code, without the `+`
+    pub code: &'static str,
+}
+
+const fn country(name: &'static str, iso: &'static str, code: &'static str) -> Country {
+    Country { name, iso, code }
+}
+
+/// Countries by name, with their calling codes.
+pub const COUNTRIES: &[Country] = &[
+    country("Afghanistan", "AF", "93"),
+    country("Albania", "AL", "355"),
+    country("Algeria", "DZ", "213"),
+    country("Andorra", "AD", "376"),
+    country("Angola", "AO", "244"),
+    country("Argentina", "AR", "54"),
+    country("Armenia", "AM", "374"),
+    country("Australia", "AU", "61"),
+    country("Austria", "AT", "43"),
+    country("Azerbaijan", "AZ", "994"),
+    country("Bahrain", "BH", "973"),
+    country("Bangladesh", "BD", "880"),
+    country("Belarus", "BY", "375"),
+    country("Belgium", "BE", "32"),
+    country("Bolivia", "BO", "591"),
+    country("Bosnia and Herzegovina", "BA", "387"),
+    country("Brazil", "BR", "55"),
+    country("Bulgaria", "BG", "359"),
+    country("Cambodia", "KH", "855"),
+    country("Cameroon", "CM", "237"),
+    country("Canada", "CA", "1"),
+    country("Chile", "CL", "56"),
+    country("China", "CN", "86"),
+    country("Colombia", "CO", "57"),
+    country("Costa Rica", "CR", "506"),
+    country("Croatia", "HR", "385"),
+    country("Cuba", "CU", "53"),
+    country("Cyprus", "CY", "357"),
+    country("Czechia", "CZ", "420"),
+    country("Denmark", "DK", "45"),
+    country("Ecuador", "EC", "593"),
+    country("Egypt", "EG", "20"),
+    country("El Salvador", "SV", "503"),
+    country("Estonia", "EE", "372"),
+    country("Ethiopia", "ET", "251"),
+    country("Finland", "FI", "358"),
+    country("France", "FR", "33"),
+    country("Georgia", "GE", "995"),
+    country("Germany", "DE", "49"),
+    country("Ghana", "GH", "233"),
+    country("Greece", "GR", "30"),
+    country("Guatemala", "GT", "502"),
+    country("Honduras", "HN", "504"),
+    country("Hong Kong", "HK", "852"),
+    country("Hungary", "HU", "36"),
+    country("Iceland", "IS", "354"),
+    country("India", "IN", "91"),
+    country("Indonesia", "ID", "62"),
+    country("Iran", "IR", "98"),
+    country("Iraq", "IQ", "964"),
+    country("Ireland", "IE", "353"),
+    country("Israel", "IL", "972"),
+    country("Italy", "IT", "39"),
+    country("Ivory Coast", "CI", "225"),
+    country("Japan", "JP", "81"),
+    country("Jordan", "JO", "962"),
+    country("Kazakhstan", "KZ", "7"),
+    country("Kenya", "KE", "254"),
+    country("Kuwait", "KW", "965"),
+    country("Kyrgyzstan", "KG", "996"),
+    country("Latvia", "LV", "371"),
+    country("Lebanon", "LB", "961"),
+    country("Libya", "LY", "218"),
+    country("Lithuania", "LT", "370"),
+    country("Luxembourg", "LU", "352"),
+    country("Malaysia", "MY", "60"),
+    country("Malta", "MT", "356"),
+    country("Mexico", "MX", "52"),
+    country("Moldova", "MD", "373"),
+    country("Mongolia", "MN", "976"),
+    country("Montenegro", "ME", "382"),
+    country("Morocco", "MA", "212"),
+    country("Myanmar", "MM", "95"),
+    country("Nepal", "NP", "977"),
+    country("Netherlands", "NL", "31"),
+    country("New Zealand", "NZ", "64"),
+    country("Nicaragua", "NI", "505"),
+    country("Nigeria", "NG", "234"),
+    country("North Macedonia", "MK", "389"),
+    country("Norway", "NO", "47"),
+    country("Oman", "OM", "968"),
+    country("Pakistan", "PK", "92"),
+    country("Panama", "PA", "507"),
+    country("Paraguay", "PY", "595"),
+    country("Peru", "PE", "51"),
+    country("Philippines", "PH", "63"),
+    country("Poland", "PL", "48"),
+    country("Portugal", "PT", "351"),
+    country("Qatar", "QA", "974"),
+    country("Romania", "RO", "40"),
+    country("Russia", "RU", "7"),
+    country("Saudi Arabia", "SA", "966"),
+    country("Senegal", "SN", "221"),
+    country("Serbia", "RS", "381"),
+    country("Singapore", "SG", "65"),
+    country("Slovakia", "SK", "421"),
+    country("Slovenia", "SI", "386"),
+    country("South Africa", "ZA", "27"),
+    country("South Korea", "KR", "82"),
+    country("Spain", "ES", "34"),
+    country("Sri Lanka", "LK", "94"),
+    country("Sudan", "SD", "249"),
+    country("Sweden", "SE", "46"),
+    country("Switzerland", "CH", "41"),
+    country("Syria", "SY", "963"),
+    country("Taiwan", "TW", "886"),
+    country("Tajikistan", "TJ", "992"),
+    country("Tanzania", "TZ", "255"),
+    country("Thailand", "TH", "66"),
+    country("Tunisia", "TN", "216"),
+    country("Turkey", "TR", "90"),
+    country("Turkmenistan", "TM", "993"),
+    country("Uganda", "UG", "256"),
+    country("Ukraine", "UA", "380"),
+    country("United Arab Emirates", "AE", "971"),
+    country("United Kingdom", "GB", "44"),
+    country("United States", "US", "1"),
+    country("Uruguay", "UY", "598"),
+    country("Uzbekistan", "UZ", "998"),
+    country("Venezuela", "VE", "58"),
+    country("Vietnam", "VN", "84"),
+    country("Yemen", "YE", "967"),
+    country("Zambia", "ZM", "260"),
+    country("Zimbabwe", "ZW", "263"),
+];
+
+/// Shortest number Telegram takes, in digits with the country code.
+const MIN_DIGITS: usize = 7;
+
+/// Longest number E.164 allows, in digits with the country code.
+const MAX_DIGITS: usize = 15;
+
+/// Returns the number typed in `input` in E.164 form, such as
+/// `+4915123456789`.
+///
+/// # Errors
+///
+/// Returns a message saying what is wrong: no country code, letters or
+/// other stray characters, or too few or too many digits.
+///
+/// # Example
+///
+/// ```rust
+/// use ithil::utils::normalize_phone;
+///
+/// assert_eq!(normalize_phone("+1 (555) 010-0199"), Ok("+15550100199".to_string()));
+/// assert_eq!(normalize_phone("0049 151 2345678"), Ok("+491512345678".to_string()));
+/// assert!(normalize_phone("555 0100").is_err());
+/// ```
+pub fn normalize_phone(input: &str) -> Result<String, String> {
+    let input = input.trim();
+    let Some(number) = input.strip_prefix('+').or_else(|| input.strip_prefix("00")) else {
+        return Err("Start with + and the country code, e.g. +44 20 7946 0958".to_string());
+    };
+    if let Some(c) = number
+        .chars()
+        .find(|&c| !c.is_ascii_digit() && !matches!(c, ' ' | '-' | '.' | '(' | ')'))
+    {
+        return Err(format!("\"{c}\" doesn't belong in a phone number"));
+    }
+    let digits: String = number.chars().filter(char::is_ascii_digit).collect();
+    if digits.starts_with('0') {
+        return Err("Country codes don't start with 0".to_string());
+    }
+    if digits.len() < MIN_DIGITS {
+        return Err("That number is too short".to_string());
+    }
+    if digits.len() > MAX_DIGITS {
+        return Err(format!(
+            "Phone numbers have at most {MAX_DIGITS} digits with the country code"
+        ));
+    }
+    Ok(format!("+{digits}"))
+}
+
+/// Returns the countries whose calling code starts the number typed in
+/// `input`: none if it doesn't start with `+` and a known code, and more
+/// than one where countries share a code (`+1`, `+7`).
+#[must_use]
+pub fn countries_for(input: &str) -> Vec<&'static Country> {
+    let Some(number) = input.trim().strip_prefix('+') else {
+        return Vec::new();
+    };
+    let digits: String = number.chars().filter(char::is_ascii_digit).collect();
+    let matching = COUNTRIES.iter().filter(|c| digits.starts_with(c.code));
+    let longest = matching.clone().map(|c| c.code.len()).max().unwrap_or(0);
+    matching.filter(|c| c.code.len() == longest).collect()
+}
+
+/// Returns the countries matching `query`: by name or ISO code, ignoring
+/// case, or by calling code for a query of digits (with or without `+`).
+#[must_use]
+pub fn search_countries(query: &str) -> Vec<&'static Country> {
+    let query = query.trim();
+    if query.is_empty() {
+        return COUNTRIES.iter().collect();
+    }
+    let digits = query.strip_prefix('+').unwrap_or(query);
+    if !digits.is_empty() && digits.chars().all(|c| c.is_ascii_digit()) {
+        return COUNTRIES
+            .iter()
+            .filter(|c| c.code.starts_with(digits))
+            .collect();
+    }
+    let query = query.to_lowercase();
+    COUNTRIES
+        .iter()
+        .filter(|c| c.name.to_lowercase().contains(&query) || c.iso.eq_ignore_ascii_case(&query))
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn numbers_are_cleaned_up_or_explained() {
+        assert_eq!(
+            normalize_phone(" +44 20 7946 0958 "),
+            Ok("+442079460958".to_string())
+        );
+        assert_eq!(
+            normalize_phone("+7.912.345.67.89"),
+            Ok("+79123456789".to_string())
+        );
+        assert!(normalize_phone("020 7946 0958").is_err());
+        assert!(normalize_phone("+44 20 79x6 0958").is_err());
+        assert!(normalize_phone("+044 20 7946 0958").is_err());
+        assert!(normalize_phone("+1 555").is_err());
+        assert!(normalize_phone("+1 2345 6789 0123 45").is_err());
+    }
+
+    #[test]
+    fn countries_are_found_by_number_name_or_code() {
+        let isos = |countries: Vec<&Country>| countries.iter().map(|c| c.iso).collect::<Vec<_>>();
+        assert_eq!(isos(countries_for("+44 20 7946")), ["GB"]);
+        // The longest code wins over a shorter one it starts with
+        assert_eq!(isos(countries_for("+380 44")), ["UA"]);
+        assert_eq!(isos(countries_for("+1 555")), ["CA", "US"]);
+        assert!(countries_for("44 20").is_empty());
+
+        let names = |query| {
+            search_countries(query)
+                .iter()
+                .map(|c| c.name)
+                .collect::<Vec<_>>()
+        };
+        assert_eq!(names("kingdom"), ["United Kingdom"]);
+        assert_eq!(
+            names("us"),
+            [
+                "Australia",
+                "Austria",
+                "Belarus",
+                "Cyprus",
+                "Russia",
+                "United States"
+            ]
+        );
+        assert_eq!(
+            names("+35"),
+            [
+                "Albania",
+                "Bulgaria",
+                "Cyprus",
+                "Finland",
+                "Iceland",
+                "Ireland",
+                "Luxembourg",
+                "Malta",
+                "Portugal",
+            ]
+        );
+        assert_eq!(search_countries("").len(), COUNTRIES.len());
+    }
+}
@@ -0,0 +1,50 @@
+//! Deciding when to report the user as online.
+
+use std::time::Duration;
+
+/// How often to re-send "online" while active, before Telegram expires it.
+pub const ONLINE_REFRESH: Duration = Duration::from_secs(120);
+
+/// Returns `true` if the user should currently be reported as online.
+///
+/// `show_online` is the privacy setting; when it is off ("appear offline")
+/// this is always `false`. Otherwise the terminal must be focused and the
+/// user must have been active within `idle_timeout` (`None` disables the
+/// idle check).
+#[must_use]
+pub fn should_be_online(
+    show_online: bool,
+    focused: bool,
+    idle: Duration,
+    idle_timeout: Option<Duration>,
+) -> bool {
+    show_online && focused && idle_timeout.map_or(true, |timeout| idle < timeout)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const MINUTE: Duration = Duration::from_secs(60);
+
+    #[test]
+    fn online_when_focused_and_active() {
+        assert!(should_be_online(true, true, MINUTE, Some(5 * MINUTE)));
+    }
+
+    #[test]
+    fn offline_when_unfocused_or_idle() {
+        assert!(!should_be_online(true, false, MINUTE, Some(5 * MINUTE)));
+        assert!(!should_be_online(true, true, 6 * MINUTE, Some(5 * MINUTE)));
+    }
+
+    #[test]
+    fn idle_check_can_be_disabled() {
+        assert!(should_be_online(true, true, 600 * MINUTE, None));
+    }
+
+    #[test]
+    fn appear_offline_never_reports_online() {
+        assert!(!should_be_online(false, true, Duration::ZERO, None));
+    }
+}
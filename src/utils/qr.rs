@@ -0,0 +1,554 @@
+//! QR code generation for showing links in the terminal.
+//!
+//! Text is encoded in byte mode at error correction level M, in the
+//! smallest version from 1 to 10 that holds it (up to 213 bytes, plenty for
+//! a link). Larger codes wouldn't fit most terminals anyway. The code can
+//! be drawn with Unicode half blocks, two rows per line, or in plain ASCII.
+//!
+//! # Example
+//!
+//! ```rust
+//! use ithil::utils::QrCode;
+//!
+//! let code = QrCode::encode("https://t.me/durov").unwrap();
+//! assert_eq!(code.size(), 25);
+//! assert!(code.is_dark(0, 0)); // Finder pattern corner
+//! ```
+
+/// Largest version produced.
+const MAX_VERSION: usize = 10;
+
+/// Error correction codewords per block at level M, by version.
+const ECC_PER_BLOCK: [usize; MAX_VERSION] = [10, 16, 26, 18, 24, 16, 18, 22, 22, 26];
+
+/// Error correction blocks at level M, by version.
+const BLOCKS: [usize; MAX_VERSION] = [1, 1, 1, 2, 2, 4, 4, 4, 5, 5];
+
+/// Format bits identifying level M.
+const LEVEL_M_BITS: u32 = 0;
+
+/// Light modules drawn around the code so scanners can find its edges.
+const QUIET_ZONE: usize = 2;
+
+/// A QR code, as a square grid of dark and light modules.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct QrCode {
+    size: usize,
+    modules: Vec<bool>,
+    /// Finder, timing, alignment and format modules, which masks skip
+    function: Vec<bool>,
+}
+
+impl QrCode {
+    /// Encodes `text`, or returns `None` if it is longer than 213 bytes.
+    #[must_use]
+    pub fn encode(text: &str) -> Option<Self> {
+        let data = text.as_bytes();
+        let version = (1..=MAX_VERSION).find(|&v| {
+            let count_bits = if v < 10 { 8 } else { 16 };
+            4 + count_bits + data.len() * 8 <= data_codewords(v) * 8
+        })?;
+
+        let mut code = Self {
+            size: version * 4 + 17,
+            modules: vec![false; (version * 4 + 17).pow(2)],
+            function: vec![false; (version * 4 + 17).pow(2)],
+        };
+        code.draw_function_patterns(version);
+        code.draw_codewords(&interleave(version, &data_bits(version, data)));
+
+        let mask = (0..8)
+            .min_by_key(|&mask| {
+                code.apply_mask(mask);
+                code.draw_format_bits(mask);
+                let penalty = code.penalty();
+                code.apply_mask(mask);
+                penalty
+            })
+            .unwrap_or(0);
+        code.apply_mask(mask);
+        code.draw_format_bits(mask);
+        Some(code)
+    }
+
+    /// Returns the number of modules along each side.
+    #[must_use]
+    pub const fn size(&self) -> usize {
+        self.size
+    }
+
+    /// Returns `true` if the module at column `x`, row `y` is dark.
+    /// Modules outside the code are light.
+    #[must_use]
+    pub fn is_dark(&self, x: usize, y: usize) -> bool {
+        x < self.size && y < self.size && self.modules[y * self.size + x]
+    }
+
+    /// Draws the code with half blocks, two module rows per line, with a
+    /// quiet zone around it. Dark modules are filled, so it should be shown
+    /// dark on light.
+    #[must_use]
+    pub fn to_unicode(&self) -> Vec<String> {
+        let span = self.size + 2 * QUIET_ZONE;
+        (0..span)
+            .step_by(2)
+            .map(|y| {
+                (0..span)
+                    .map(|x| match (self.shifted(x, y), self.shifted(x, y + 1)) {
+                        (true, true) => '\u{2588}',
+                        (true, false) => '\u{2580}',
+                        (false, true) => '\u{2584}',
+                        (false, false) => ' ',
+                    })
+                    .collect()
+            })
+            .collect()
+    }
+
+    /// Draws the code with `##` for each dark module, one module row per
+    /// line, with a quiet zone around it. Twice as tall as
+    /// [`Self::to_unicode`], but works in any font.
+    #[must_use]
+    pub fn to_ascii(&self) -> Vec<String> {
+        let span = self.size + 2 * QUIET_ZONE;
+        (0..span)
+            .map(|y| {
+                (0..span)
+                    .map(|x| if self.shifted(x, y) { "##" } else { "  " })
+                    .collect()
+            })
+            .collect()
+    }
+
+    /// Looks up a module counting from the top left of the quiet zone.
+    fn shifted(&self, x: usize, y: usize) -> bool {
+        x >= QUIET_ZONE && y >= QUIET_ZONE && self.is_dark(x - QUIET_ZONE, y - QUIET_ZONE)
+    }
+
+    fn set_function(&mut self, x: usize, y: usize, dark: bool) {
+        let i = y * self.size + x;
+        self.modules[i] = dark;
+        self.function[i] = true;
+    }
+
+    fn draw_function_patterns(&mut self, version: usize) {
+        let size = self.size;
+        for i in 0..size {
+            self.set_function(6, i, i % 2 == 0);
+            self.set_function(i, 6, i % 2 == 0);
+        }
+
+        for (x, y) in [(3, 3), (size - 4, 3), (3, size - 4)] {
+            self.draw_finder(x, y);
+        }
+
+        let positions = alignment_positions(version);
+        let last = positions.len().saturating_sub(1);
+        for (i, &x) in positions.iter().enumerate() {
+            for (j, &y) in positions.iter().enumerate() {
+                // The three corners already hold finder patterns
+                let corner = (i == 0 && (j == 0 || j == last)) || (i == last && j == 0);
+                if !corner {
+                    self.draw_alignment(x, y);
+                }
+            }
+        }
+
+        // Reserve the format areas; the real bits are drawn once the mask
+        // is chosen
+        self.draw_format_bits(0);
+        self.draw_version(version);
+    }
+
+    /// Draws a finder pattern and its separator around the center `(x, y)`.
+    fn draw_finder(&mut self, x: usize, y: usize) {
+        for dy in -4_isize..=4 {
+            for dx in -4_isize..=4 {
+                let (Some(xx), Some(yy)) = (x.checked_add_signed(dx), y.checked_add_signed(dy))
+                else {
+                    continue;
+                };
+                if xx < self.size && yy < self.size {
+                    let distance = dx.abs().max(dy.abs());
+                    self.set_function(xx, yy, distance != 2 && distance != 4);
+                }
+            }
+        }
+    }
+
+    /// Draws a 5x5 alignment pattern around the center `(x, y)`.
+    fn draw_alignment(&mut self, x: usize, y: usize) {
+        for dy in 0..5 {
+            for dx in 0..5 {
+                let distance = dx.abs_diff(2).max(dy.abs_diff(2));
+                self.set_function(x + dx - 2, y + dy - 2, distance != 1);
+            }
+        }
+    }
+
+    /// Draws both copies of the format bits for `mask`, and the dark module.
+    fn draw_format_bits(&mut self, mask: u32) {
+        let data = LEVEL_M_BITS << 3 | mask;
+        let mut rem = data;
+        for _ in 0..10 {
+            rem = (rem << 1) ^ ((rem >> 9) * 0x537);
+        }
+        let bits = (data << 10 | rem) ^ 0x5412;
+        let bit = |i: usize| (bits >> i) & 1 == 1;
+
+        let size = self.size;
+        for i in 0..=5 {
+            self.set_function(8, i, bit(i));
+        }
+        self.set_function(8, 7, bit(6));
+        self.set_function(8, 8, bit(7));
+        self.set_function(7, 8, bit(8));
+        for i in 9..15 {
+            self.set_function(14 - i, 8, bit(i));
+        }
+
+        for i in 0..8 {
+            self.set_function(size - 1 - i, 8, bit(i));
+        }
+        for i in 8..15 {
+            self.set_function(8, size - 15 + i, bit(i));
+        }
+        self.set_function(8, size - 8, true);
+    }
+
+    /// Draws the version blocks, which versions 7 and up carry.
+    fn draw_version(&mut self, version: usize) {
+        if version < 7 {
+            return;
+        }
+        let mut rem = version;
+        for _ in 0..12 {
+            rem = (rem << 1) ^ ((rem >> 11) * 0x1F25);
+        }
+        let bits = version << 12 | rem;
+        for i in 0..18 {
+            let dark = (bits >> i) & 1 == 1;
+            let a = self.size - 11 + i % 3;
+            let b = i / 3;
+            self.set_function(a, b, dark);
+            self.set_function(b, a, dark);
+        }
+    }
+
+    /// Places the codewords in the zigzag order, skipping function modules.
+    fn draw_codewords(&mut self, codewords: &[u8]) {
+        let size = self.size;
+        let total_bits = codewords.len() * 8;
+        let mut i = 0;
+        let mut right = size - 1;
+        loop {
+            // The vertical timing pattern shifts the columns left by one
+            if right == 6 {
+                right = 5;
+            }
+            let upward = (right + 1) & 2 == 0;
+            for vert in 0..size {
+                let y = if upward { size - 1 - vert } else { vert };
+                for x in [right, right - 1] {
+                    let index = y * size + x;
+                    if !self.function[index] && i < total_bits {
+                        self.modules[index] = (codewords[i / 8] >> (7 - i % 8)) & 1 == 1;
+                        i += 1;
+                    }
+                }
+            }
+            if right < 2 {
+                break;
+            }
+            right -= 2;
+        }
+    }
+
+    /// Flips the data modules picked out by `mask`. Applying it twice undoes
+    /// it.
+    fn apply_mask(&mut self, mask: u32) {
+        let size = self.size;
+        for y in 0..size {
+            for x in 0..size {
+                let flip = match mask {
+                    0 => (x + y) % 2 == 0,
+                    1 => y % 2 == 0,
+                    2 => x % 3 == 0,
+                    3 => (x + y) % 3 == 0,
+                    4 => (x / 3 + y / 2) % 2 == 0,
+                    5 => x * y % 2 + x * y % 3 == 0,
+                    6 => (x * y % 2 + x * y % 3) % 2 == 0,
+                    _ => ((x + y) % 2 + x * y % 3) % 2 == 0,
+                };
+                let index = y * size + x;
+                if flip && !self.function[index] {
+                    self.modules[index] = !self.modules[index];
+                }
+            }
+        }
+    }
+
+    /// Scores how hard the code is to scan; the mask with the lowest score
+    /// is used.
+    fn penalty(&self) -> usize {
+        let size = self.size;
+        let at = |x: usize, y: usize| self.modules[y * size + x];
+        let mut penalty = 0;
+
+        // Runs of five or more in a line, and finder-like patterns
+        let finder_like = [
+            true, false, true, true, true, false, true, false, false, false, false,
+        ];
+        for line in 0..size {
+            for horizontal in [true, false] {
+                let cells: Vec<bool> = (0..size)
+                    .map(|i| if horizontal { at(i, line) } else { at(line, i) })
+                    .collect();
+                let mut run = 1;
+                for i in 1..=size {
+                    if i < size && cells[i] == cells[i - 1] {
+                        run += 1;
+                    } else {
+                        if run >= 5 {
+                            penalty += run - 2;
+                        }
+                        run = 1;
+                    }
+                }
+                for window in cells.windows(finder_like.len()) {
+                    if window == finder_like || window.iter().rev().eq(finder_like.iter()) {
+                        penalty += 40;
+                    }
+                }
+            }
+        }
+
+        // 2x2 blocks of one color
+        for y in 0..size - 1 {
+            for x in 0..size - 1 {
+                let color = at(x, y);
+                if at(x + 1, y) == color && at(x, y + 1) == color && at(x + 1, y + 1) == color {
+                    penalty += 3;
+                }
+            }
+        }
+
+        // Imbalance between dark and light
+        let dark = self.modules.iter().filter(|&&m| m).count();
+        let percent = dark * 100 / self.modules.len();
+        penalty + percent.abs_diff(50) / 5 * 10
+    }
+}
+
+/// Returns the number of data codewords a version holds at level M.
+fn data_codewords(version: usize) -> usize {
+    raw_codewords(version) - ECC_PER_BLOCK[version - 1] * BLOCKS[version - 1]
+}
+
+/// Returns the number of codewords, data and error correction, a version
+/// holds.
+fn raw_codewords(version: usize) -> usize {
+    let mut modules = (16 * version + 128) * version + 64;
+    if version >= 2 {
+        let count = version / 7 + 2;
+        modules -= (25 * count - 10) * count - 55;
+        if version >= 7 {
+            modules -= 36;
+        }
+    }
+    modules / 8
+}
+
+/// Returns the centers of the alignment patterns along each axis.
+fn alignment_positions(version: usize) -> Vec<usize> {
+    if version == 1 {
+        return Vec::new();
+    }
+    let count = version / 7 + 2;
+    let step = (version * 4 + count * 2 + 1) / (count * 2 - 2) * 2;
+    let mut positions = vec![6];
+    let mut position = version * 4 + 10;
+    for _ in 0..count - 1 {
+        positions.insert(1, position);
+        position -= step;
+    }
+    positions
+}
+
+/// Builds the data codewords: mode, length, the bytes, then padding.
+fn data_bits(version: usize, data: &[u8]) -> Vec<u8> {
+    let capacity = data_codewords(version) * 8;
+    let mut bits = Vec::with_capacity(capacity);
+    push_bits(&mut bits, 0b0100, 4);
+    push_bits(&mut bits, data.len(), if version < 10 { 8 } else { 16 });
+    for &byte in data {
+        push_bits(&mut bits, usize::from(byte), 8);
+    }
+    let terminator = (capacity - bits.len()).min(4);
+    push_bits(&mut bits, 0, terminator);
+    let padding = (8 - bits.len() % 8) % 8;
+    push_bits(&mut bits, 0, padding);
+
+    let mut codewords: Vec<u8> = bits
+        .chunks(8)
+        .map(|byte| byte.iter().fold(0, |acc, &bit| acc << 1 | u8::from(bit)))
+        .collect();
+    for pad in [0xEC, 0x11].into_iter().cycle() {
+        if codewords.len() * 8 >= capacity {
+            break;
+        }
+        codewords.push(pad);
+    }
+    codewords
+}
+
+/// Appends the low `len` bits of `value`, most significant first.
+fn push_bits(bits: &mut Vec<bool>, value: usize, len: usize) {
+    for i in (0..len).rev() {
+        bits.push((value >> i) & 1 == 1);
+    }
+}
+
+/// Splits the data into blocks, adds each block's error correction, and
+/// interleaves the result.
+fn interleave(version: usize, data: &[u8]) -> Vec<u8> {
+    let blocks_count = BLOCKS[version - 1];
+    let ecc_len = ECC_PER_BLOCK[version - 1];
+    let raw = raw_codewords(version);
+    let short_blocks = blocks_count - raw % blocks_count;
+    let short_len = raw / blocks_count;
+    let divisor = rs_divisor(ecc_len);
+
+    let mut blocks = Vec::with_capacity(blocks_count);
+    let mut start = 0;
+    for i in 0..blocks_count {
+        let len = short_len - ecc_len + usize::from(i >= short_blocks);
+        let mut block = data[start..start + len].to_vec();
+        start += len;
+        let ecc = rs_remainder(&block, &divisor);
+        if i < short_blocks {
+            // Placeholder so every block is the same length
+            block.push(0);
+        }
+        block.extend(ecc);
+        blocks.push(block);
+    }
+
+    let mut result = Vec::with_capacity(raw);
+    for i in 0..=short_len {
+        for (j, block) in blocks.iter().enumerate() {
+            if i != short_len - ecc_len || j >= short_blocks {
+                result.push(block[i]);
+            }
+        }
+    }
+    result
+}
+
+/// Returns the Reed-Solomon generator polynomial of `degree`, highest
+/// coefficient first, leaving out the leading 1.
+fn rs_divisor(degree: usize) -> Vec<u8> {
+    let mut result = vec![0; degree];
+    result[degree - 1] = 1;
+    let mut root = 1;
+    for _ in 0..degree {
+        for j in 0..degree {
+            result[j] = gf_multiply(result[j], root);
+            if j + 1 < degree {
+                result[j] ^= result[j + 1];
+            }
+        }
+        root = gf_multiply(root, 0x02);
+    }
+    result
+}
+
+/// Returns the error correction codewords for `data`.
+fn rs_remainder(data: &[u8], divisor: &[u8]) -> Vec<u8> {
+    let mut result = vec![0; divisor.len()];
+    for &byte in data {
+        let factor = byte ^ result.remove(0);
+        result.push(0);
+        for (r, &d) in result.iter_mut().zip(divisor) {
+            *r ^= gf_multiply(d, factor);
+        }
+    }
+    result
+}
+
+/// Multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+fn gf_multiply(x: u8, y: u8) -> u8 {
+    let mut z: u8 = 0;
+    for i in (0..8).rev() {
+        z = (z << 1) ^ ((z >> 7) * 0x1D);
+        z ^= ((y >> i) & 1) * x;
+    }
+    z
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn picks_the_smallest_version_that_fits() {
+        assert_eq!(QrCode::encode("https://t.me/durov").unwrap().size(), 25);
+        let long = "x".repeat(100);
+        assert_eq!(QrCode::encode(&long).unwrap().size(), 41); // Version 6
+        assert!(QrCode::encode(&"x".repeat(214)).is_none());
+    }
+
+    #[test]
+    fn function_patterns_are_in_place() {
+        let code = QrCode::encode(&"x".repeat(150)).unwrap();
+        let size = code.size();
+        assert_eq!(size, 49); // Version 8 carries version blocks
+        for (x, y) in [(0, 0), (size - 7, 0), (0, size - 7)] {
+            assert!(code.is_dark(x, y) && code.is_dark(x + 6, y + 6));
+            assert!(!code.is_dark(x + 1, y + 1));
+            assert!(code.is_dark(x + 3, y + 3));
+        }
+        assert!(code.is_dark(8, size - 8), "dark module");
+        // Timing pattern alternates between the finders
+        assert!((8..size - 8).all(|i| code.is_dark(i, 6) == (i % 2 == 0)));
+    }
+
+    #[test]
+    fn format_bits_match_both_copies() {
+        let code = QrCode::encode("hello").unwrap();
+        let size = code.size();
+        let first: Vec<bool> = (0..=5)
+            .map(|i| code.is_dark(8, i))
+            .chain([code.is_dark(8, 7), code.is_dark(8, 8), code.is_dark(7, 8)])
+            .chain((9..15).map(|i| code.is_dark(14 - i, 8)))
+            .collect();
+        let second: Vec<bool> = (0..8)
+            .map(|i| code.is_dark(size - 1 - i, 8))
+            .chain((8..15).map(|i| code.is_dark(8, size - 15 + i)))
+            .collect();
+        assert_eq!(first, second);
+    }
+
+    #[test]
+    fn error_correction_matches_the_spec_example() {
+        // "01234567" at version 1-M (ISO/IEC 18004, annex I)
+        let data = [
+            0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11,
+            0xEC, 0x11,
+        ];
+        assert_eq!(
+            rs_remainder(&data, &rs_divisor(10)),
+            vec![0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55]
+        );
+    }
+
+    #[test]
+    fn renders_two_rows_per_line() {
+        let code = QrCode::encode("hi").unwrap();
+        let unicode = code.to_unicode();
+        let span = code.size() + 2 * QUIET_ZONE;
+        assert_eq!(unicode.len(), span.div_ceil(2));
+        assert!(unicode.iter().all(|line| line.chars().count() == span));
+        assert_eq!(code.to_ascii().len(), span);
+    }
+}
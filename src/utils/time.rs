@@ -3,7 +3,7 @@
 //! This module provides functions for formatting timestamps and durations
 //! in human-readable formats.
 
-use chrono::{DateTime, Duration, Local, Utc};
+use chrono::{DateTime, Datelike, Duration, Local, NaiveDate, Utc};
 
 /// Formats a timestamp for display.
 ///
@@ -145,6 +145,45 @@ pub fn format_absolute_time(time: DateTime<Utc>) -> String {
     local_time.format("%b %d, %Y %H:%M").to_string()
 }
 
+/// Formats a time in at most 8 columns, for the chat list.
+///
+/// Relative to `now`: "now" under a minute (or in the future), "5m" under
+/// an hour, "14:32" earlier today, the weekday ("Tue") within the past
+/// week, "3/2" earlier this year, and "3/2/23" before that.
+///
+/// # Examples
+///
+/// ```
+/// use chrono::{Duration, Local};
+/// use ithil::utils::format_compact_time;
+///
+/// let now = Local::now();
+/// let time = (now - Duration::minutes(5)).with_timezone(&chrono::Utc);
+/// assert_eq!(format_compact_time(time, now), "5m");
+/// ```
+#[must_use]
+pub fn format_compact_time(time: DateTime<Utc>, now: DateTime<Local>) -> String {
+    let local_time = time.with_timezone(&Local);
+    let diff = now.signed_duration_since(local_time);
+
+    if diff < Duration::minutes(1) {
+        return "now".to_string();
+    }
+    if diff < Duration::hours(1) {
+        return format!("{}m", diff.num_minutes());
+    }
+    if local_time.date_naive() == now.date_naive() {
+        return local_time.format("%H:%M").to_string();
+    }
+    if diff < Duration::days(6) {
+        return local_time.format("%a").to_string();
+    }
+    if local_time.year() == now.year() {
+        return local_time.format("%-m/%-d").to_string();
+    }
+    local_time.format("%-m/%-d/%y").to_string()
+}
+
 /// Formats a duration in a human-readable way.
 ///
 /// # Arguments
@@ -190,6 +229,121 @@ pub fn format_duration(duration: Duration) -> String {
     format!("{hours}h {minutes}m")
 }
 
+/// Formats an auto-delete timer given in seconds, e.g. `"1 week"`.
+///
+/// Telegram's "1 month" is 31 days. Zero means the timer is off.
+///
+/// # Examples
+///
+/// ```
+/// use ithil::utils::format_auto_delete;
+///
+/// assert_eq!(format_auto_delete(0), "Off");
+/// assert_eq!(format_auto_delete(86_400), "1 day");
+/// assert_eq!(format_auto_delete(2_678_400), "1 month");
+/// ```
+#[must_use]
+pub fn format_auto_delete(period: i32) -> String {
+    const UNITS: [(i32, &str); 5] = [
+        (31 * 86_400, "month"),
+        (7 * 86_400, "week"),
+        (86_400, "day"),
+        (3_600, "hour"),
+        (60, "minute"),
+    ];
+
+    if period <= 0 {
+        return "Off".to_string();
+    }
+    UNITS
+        .iter()
+        .find(|(unit, _)| period % unit == 0)
+        .map_or_else(
+            || format_duration(Duration::seconds(i64::from(period))),
+            |(unit, name)| {
+                let n = period / unit;
+                if n == 1 {
+                    format!("1 {name}")
+                } else {
+                    format!("{n} {name}s")
+                }
+            },
+        )
+}
+
+/// Parses a short duration such as `"30m"`, `"8h"`, `"2d"`, or `"1w"`.
+///
+/// A bare number is read as minutes. Returns `None` for anything else,
+/// including zero or negative amounts.
+///
+/// # Examples
+///
+/// ```
+/// use chrono::Duration;
+/// use ithil::utils::parse_duration;
+///
+/// assert_eq!(parse_duration("8h"), Some(Duration::hours(8)));
+/// assert_eq!(parse_duration("15"), Some(Duration::minutes(15)));
+/// assert_eq!(parse_duration("soon"), None);
+/// ```
+#[must_use]
+pub fn parse_duration(s: &str) -> Option<Duration> {
+    let s = s.trim().to_lowercase();
+    let split = s.find(|c: char| !c.is_ascii_digit()).unwrap_or(s.len());
+    let (amount, unit) = s.split_at(split);
+    let amount: i64 = amount.parse().ok().filter(|n| *n > 0)?;
+    match unit {
+        "" | "m" | "min" | "mins" => Some(Duration::minutes(amount)),
+        "s" | "sec" | "secs" => Some(Duration::seconds(amount)),
+        "h" | "hr" | "hrs" => Some(Duration::hours(amount)),
+        "d" | "day" | "days" => Some(Duration::days(amount)),
+        "w" | "wk" | "wks" => Some(Duration::weeks(amount)),
+        _ => None,
+    }
+}
+
+/// Parses a date typed by the user, relative to `today`.
+///
+/// Accepts `today`, `yesterday`, ISO dates (`2024-03-15`), month and day
+/// (`03-15`, taken as the most recent such day), and ages such as `3d` or
+/// `2w` (see [`parse_duration`]). Returns `None` for anything else, or for a
+/// date after `today`.
+///
+/// # Examples
+///
+/// ```
+/// use chrono::NaiveDate;
+/// use ithil::utils::parse_date;
+///
+/// let today = NaiveDate::from_ymd_opt(2024, 3, 15).unwrap();
+/// assert_eq!(parse_date("yesterday", today), today.pred_opt());
+/// assert_eq!(parse_date("2w", today), NaiveDate::from_ymd_opt(2024, 3, 1));
+/// assert_eq!(parse_date("12-25", today), NaiveDate::from_ymd_opt(2023, 12, 25));
+/// ```
+#[must_use]
+pub fn parse_date(s: &str, today: NaiveDate) -> Option<NaiveDate> {
+    let s = s.trim().to_lowercase();
+    let date = match s.as_str() {
+        "today" => Some(today),
+        "yesterday" => today.pred_opt(),
+        _ => NaiveDate::parse_from_str(&s, "%Y-%m-%d")
+            .ok()
+            .or_else(|| {
+                let (month, day) = s.split_once('-')?;
+                let (month, day) = (month.parse().ok()?, day.parse().ok()?);
+                NaiveDate::from_ymd_opt(today.year(), month, day)
+                    .filter(|d| *d <= today)
+                    .or_else(|| NaiveDate::from_ymd_opt(today.year() - 1, month, day))
+            })
+            .or_else(|| {
+                parse_duration(&s)
+                    .filter(|d| *d >= Duration::days(1))
+                    .and_then(|d| today.checked_sub_signed(Duration::days(d.num_days())))
+            }),
+    };
+    date.filter(|d| *d <= today)
+}
+
 /// Checks if a datetime is today.
 fn is_today<Tz: chrono::TimeZone>(time: &DateTime<Tz>, now: &DateTime<Local>) -> bool {
     let time_local = time.with_timezone(&Local);
@@ -288,4 +442,81 @@ mod tests {
         assert_eq!(format_duration(Duration::hours(2)), "2h");
         assert_eq!(format_duration(Duration::minutes(150)), "2h 30m");
     }
+
+    #[test]
+    fn format_auto_delete_picks_the_largest_whole_unit() {
+        assert_eq!(format_auto_delete(0), "Off");
+        assert_eq!(format_auto_delete(604_800), "1 week");
+        assert_eq!(format_auto_delete(2 * 86_400), "2 days");
+        assert_eq!(format_auto_delete(3 * 31 * 86_400), "3 months");
+        assert_eq!(format_auto_delete(90), "1m 30s");
+    }
+
+    #[test]
+    fn parse_duration_units() {
+        assert_eq!(parse_duration("30s"), Some(Duration::seconds(30)));
+        assert_eq!(parse_duration("45"), Some(Duration::minutes(45)));
+        assert_eq!(parse_duration("8H"), Some(Duration::hours(8)));
+        assert_eq!(parse_duration("2d"), Some(Duration::days(2)));
+        assert_eq!(parse_duration("1w"), Some(Duration::weeks(1)));
+    }
+
+    #[test]
+    fn parse_duration_rejects_garbage() {
+        assert_eq!(parse_duration(""), None);
+        assert_eq!(parse_duration("0h"), None);
+        assert_eq!(parse_duration("h"), None);
+        assert_eq!(parse_duration("3 years"), None);
+    }
+
+    #[test]
+    fn compact_time_shrinks_with_age() {
+        use chrono::TimeZone;
+
+        let now = Local.with_ymd_and_hms(2024, 3, 15, 12, 0, 0).unwrap();
+        let at = |y, m, d, h, min| {
+            Local
+                .with_ymd_and_hms(y, m, d, h, min, 0)
+                .unwrap()
+                .with_timezone(&Utc)
+        };
+
+        assert_eq!(format_compact_time(at(2024, 3, 15, 11, 59), now), "1m");
+        assert_eq!(format_compact_time(at(2024, 3, 15, 11, 55), now), "5m");
+        assert_eq!(
+            format_compact_time((now + Duration::seconds(5)).with_timezone(&Utc), now),
+            "now"
+        );
+        assert_eq!(format_compact_time(at(2024, 3, 15, 8, 5), now), "08:05");
+        assert_eq!(format_compact_time(at(2024, 3, 12, 18, 0), now), "Tue");
+        assert_eq!(format_compact_time(at(2024, 3, 2, 9, 0), now), "3/2");
+        assert_eq!(format_compact_time(at(2023, 12, 25, 9, 0), now), "12/25/23");
+    }
+
+    #[test]
+    fn parse_date_forms() {
+        let today = NaiveDate::from_ymd_opt(2024, 3, 15).unwrap();
+        assert_eq!(parse_date("Today", today), Some(today));
+        assert_eq!(
+            parse_date("2023-07-04", today),
+            NaiveDate::from_ymd_opt(2023, 7, 4)
+        );
+        assert_eq!(
+            parse_date("03-01", today),
+            NaiveDate::from_ymd_opt(2024, 3, 1)
+        );
+        assert_eq!(
+            parse_date("3d", today),
+            NaiveDate::from_ymd_opt(2024, 3, 12)
+        );
+    }
+
+    #[test]
+    fn parse_date_rejects_future_and_garbage() {
+        let today = NaiveDate::from_ymd_opt(2024, 3, 15).unwrap();
+        assert_eq!(parse_date("2024-04-01", today), None);
+        assert_eq!(parse_date("3h", today), None);
+        assert_eq!(parse_date("02-30", today), None);
+        assert_eq!(parse_date("someday", today), None);
+    }
 }
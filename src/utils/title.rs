@@ -0,0 +1,85 @@
+//! Terminal window title with an unread badge.
+//!
+//! Sets the title via OSC 2 (e.g. `Ithil (3 unread) — Alice`) and, on
+//! terminals that understand ConEmu-style progress (Windows Terminal, ConEmu,
+//! recent WezTerm and Ghostty), raises an OSC 9;4 indicator while there are
+//! unread messages. Terminals without progress support ignore the sequence.
+
+use std::io::Write;
+
+use super::notify::sanitize;
+
+/// Application name shown at the start of the title.
+const APP_NAME: &str = "Ithil";
+
+/// Builds the window title.
+///
+/// The unread count is only shown while the terminal is unfocused, so the
+/// badge clears as soon as the user looks at the window.
+#[must_use]
+pub fn window_title(unread: u32, chat_name: Option<&str>, focused: bool) -> String {
+    let mut title = APP_NAME.to_string();
+    if unread > 0 && !focused {
+        title.push_str(&format!(" ({unread} unread)"));
+    }
+    if let Some(name) = chat_name.map(sanitize).filter(|n| !n.is_empty()) {
+        title.push_str(" — ");
+        title.push_str(&name);
+    }
+    title
+}
+
+/// Builds the OSC 2 title sequence, followed by the OSC 9;4 progress state.
+///
+/// With `badge` set, progress is shown in the "paused" (attention) state at
+/// 100%; otherwise it is removed.
+fn title_sequence(title: &str, badge: bool) -> String {
+    let progress = if badge { "4;100" } else { "0;0" };
+    format!("\x1b]2;{}\x07\x1b]9;4;{progress}\x07", sanitize(title))
+}
+
+/// Writes the window title and progress badge to the terminal.
+///
+/// Best-effort: I/O errors are swallowed, since a stale title must never
+/// disrupt the UI.
+pub fn set_terminal_title(title: &str, badge: bool) {
+    let mut stdout = std::io::stdout();
+    let _ = stdout.write_all(title_sequence(title, badge).as_bytes());
+    let _ = stdout.flush();
+}
+
+/// Clears the title and progress badge, letting the terminal restore its own.
+pub fn reset_terminal_title() {
+    set_terminal_title("", false);
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn title_shows_unread_only_when_unfocused() {
+        assert_eq!(
+            window_title(3, Some("Alice"), false),
+            "Ithil (3 unread) — Alice"
+        );
+        assert_eq!(window_title(3, Some("Alice"), true), "Ithil — Alice");
+        assert_eq!(window_title(0, None, false), "Ithil");
+    }
+
+    #[test]
+    fn title_sanitizes_chat_name() {
+        let title = window_title(0, Some("evil\x1b]2;pwned\x07"), true);
+        assert!(!title.contains('\x1b'));
+        assert!(!title.contains('\x07'));
+    }
+
+    #[test]
+    fn sequence_sets_and_clears_badge() {
+        assert_eq!(
+            title_sequence("Ithil", true),
+            "\x1b]2;Ithil\x07\x1b]9;4;4;100\x07"
+        );
+        assert!(title_sequence("Ithil", false).ends_with("\x1b]9;4;0;0\x07"));
+    }
+}